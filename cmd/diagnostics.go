@@ -1,10 +1,14 @@
 package cmd
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -12,6 +16,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/spf13/cobra"
 
 	"github.com/SisyphusSQ/mongo-overview-tool/v2/internal/clioutput"
@@ -25,7 +30,7 @@ type diagnosticBaseConfig struct {
 	Timeout time.Duration
 }
 
-const maxCapacitySnapshotBytes = 32 << 20
+const maxLocalSnapshotBytes = 32 << 20
 
 var doctorConfig struct {
 	diagnosticBaseConfig
@@ -55,6 +60,37 @@ var hotspotConfig struct {
 	IncludeSystemDB bool
 }
 
+var latencyConfig struct {
+	diagnosticBaseConfig
+	Databases       string
+	Collections     string
+	IncludeSystemDB bool
+	Duration        time.Duration
+	P99Threshold    time.Duration
+	MaxCollections  int
+	Concurrency     int
+}
+
+var queryStatsConfig struct {
+	diagnosticBaseConfig
+	Databases       string
+	IncludeSystemDB bool
+	MaxShapes       int
+	Concurrency     int
+}
+
+var scatterGatherConfig struct {
+	diagnosticBaseConfig
+	Databases      string
+	Namespaces     string
+	Operations     string
+	Since          string
+	Until          string
+	MinExecutions  int64
+	BroadcastRatio float64
+	Concurrency    int
+}
+
 var indexAuditConfig struct {
 	diagnosticBaseConfig
 	Databases       string
@@ -65,6 +101,28 @@ var indexAuditConfig struct {
 	MinObservation  time.Duration
 	MaxCollections  int
 	Concurrency     int
+	EmitPlan        string
+	HidePeriod      time.Duration
+	Snapshot        string
+	UsageLedger     string
+	BatchSize       int
+	CursorFile      string
+}
+
+// indexAuditCursorFile 是 --cursor-file 保存的续跑位置；cursor 由 SDK 签名，计数只用于提示。
+// Batches 是旁路批次文件中已确认的行数，续跑时只合并这些行。
+type indexAuditCursorFile struct {
+	Cursor               string `json:"cursor"`
+	ProcessedCollections int    `json:"processedCollections"`
+	TotalCollections     int    `json:"totalCollections"`
+	Batches              int    `json:"batches"`
+}
+
+// indexAuditBatchLine 是批次文件中的一行，每批完成后追加一行，不重写已有内容。
+type indexAuditBatchLine struct {
+	Index   int                  `json:"index"`
+	Partial bool                 `json:"partial,omitempty"`
+	Result  mot.IndexAuditResult `json:"result"`
 }
 
 var capacityConfig struct {
@@ -78,6 +136,13 @@ var capacityConfig struct {
 	Concurrency     int
 }
 
+var capacityForecastConfig struct {
+	Format  string
+	Method  string
+	Quotas  string
+	Horizon time.Duration
+}
+
 var doctorCmd = &cobra.Command{
 	Use:   "doctor",
 	Short: "Run a read-only MongoDB health check",
@@ -148,6 +213,84 @@ var hotspotCmd = &cobra.Command{
 	},
 }
 
+var latencyCmd = &cobra.Command{
+	Use:   "latency",
+	Short: "Collect per-collection latency histograms from every data-bearing node",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if err := validateDiagnosticBase(latencyConfig.diagnosticBaseConfig); err != nil {
+			return err
+		}
+		if latencyConfig.Duration < 0 || latencyConfig.P99Threshold < 0 || latencyConfig.MaxCollections < 0 || latencyConfig.Concurrency < 0 {
+			return fmt.Errorf("duration, p99-threshold, max-collections and concurrency must not be negative")
+		}
+		ctx, cancel := diagnosticContext(cmd.Context(), latencyConfig.Timeout)
+		defer cancel()
+		client, err := diagnosticClient(ctx, &latencyConfig.BaseCfg)
+		if err != nil {
+			return err
+		}
+		defer closeSDKClient(client)
+		result, operationErr := client.CollectionLatency(ctx, mot.CollectionLatencyOptions{Databases: splitCSV(latencyConfig.Databases), Collections: splitCSV(latencyConfig.Collections), IncludeSystemDB: latencyConfig.IncludeSystemDB, Duration: latencyConfig.Duration, P99Threshold: latencyConfig.P99Threshold, MaxCollections: latencyConfig.MaxCollections, NodeConcurrency: latencyConfig.Concurrency, MaxTime: latencyConfig.Timeout})
+		return printDiagnosticAndError(cmd, result, latencyConfig.Format, operationErr)
+	},
+}
+
+var queryStatsCmd = &cobra.Command{
+	Use:   "query-stats",
+	Short: "Aggregate $queryStats query shapes from every mongod and mongos (MongoDB 7.1+)",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if err := validateDiagnosticBase(queryStatsConfig.diagnosticBaseConfig); err != nil {
+			return err
+		}
+		if queryStatsConfig.MaxShapes < 0 || queryStatsConfig.Concurrency < 0 {
+			return fmt.Errorf("max-shapes and concurrency must not be negative")
+		}
+		ctx, cancel := diagnosticContext(cmd.Context(), queryStatsConfig.Timeout)
+		defer cancel()
+		client, err := diagnosticClient(ctx, &queryStatsConfig.BaseCfg)
+		if err != nil {
+			return err
+		}
+		defer closeSDKClient(client)
+		result, operationErr := client.QueryStats(ctx, mot.QueryStatsOptions{Databases: splitCSV(queryStatsConfig.Databases), IncludeSystemDB: queryStatsConfig.IncludeSystemDB, MaxShapes: queryStatsConfig.MaxShapes, NodeConcurrency: queryStatsConfig.Concurrency})
+		return printDiagnosticAndError(cmd, result, queryStatsConfig.Format, operationErr)
+	},
+}
+
+var scatterGatherCmd = &cobra.Command{
+	Use:   "scatter-gather",
+	Short: "Detect query shapes that mongos broadcasts to every shard",
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if err := validateDiagnosticBase(scatterGatherConfig.diagnosticBaseConfig); err != nil {
+			return err
+		}
+		if scatterGatherConfig.MinExecutions < 0 || scatterGatherConfig.Concurrency < 0 {
+			return fmt.Errorf("min-executions and concurrency must not be negative")
+		}
+		if scatterGatherConfig.BroadcastRatio < 0 || scatterGatherConfig.BroadcastRatio > 1 {
+			return fmt.Errorf("broadcast-ratio must be between 0 and 1")
+		}
+		now := time.Now()
+		since, err := parseSlowlogTimeBound("--since", scatterGatherConfig.Since, now)
+		if err != nil {
+			return err
+		}
+		until, err := parseSlowlogTimeBound("--until", scatterGatherConfig.Until, now)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := diagnosticContext(cmd.Context(), scatterGatherConfig.Timeout)
+		defer cancel()
+		client, err := diagnosticClient(ctx, &scatterGatherConfig.BaseCfg)
+		if err != nil {
+			return err
+		}
+		defer closeSDKClient(client)
+		result, operationErr := client.ScatterGather(ctx, mot.ScatterGatherOptions{Databases: splitCSV(scatterGatherConfig.Databases), Namespaces: splitCSV(scatterGatherConfig.Namespaces), Operations: splitCSV(scatterGatherConfig.Operations), Since: since, Until: until, MinExecutions: scatterGatherConfig.MinExecutions, BroadcastRatio: scatterGatherConfig.BroadcastRatio, NodeConcurrency: scatterGatherConfig.Concurrency})
+		return printDiagnosticAndError(cmd, result, scatterGatherConfig.Format, operationErr)
+	},
+}
+
 var indexAuditCmd = &cobra.Command{
 	Use:   "index-audit",
 	Short: "Audit sharded index consistency, usage, definitions, and storage candidates",
@@ -168,18 +311,258 @@ var indexAuditCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		ctx, cancel := diagnosticContext(cmd.Context(), indexAuditConfig.Timeout)
+		planFormat, err := indexRemediationPlanFormat(indexAuditConfig.EmitPlan)
+		if err != nil {
+			return err
+		}
+		if indexAuditConfig.HidePeriod <= 0 {
+			return fmt.Errorf("hide-period must be positive")
+		}
+		if err := validateIndexAuditBatchCLI(); err != nil {
+			return err
+		}
+		timeout := indexAuditConfig.Timeout
+		if indexAuditConfig.BatchSize > 0 {
+			timeout = 0
+		}
+		ctx, cancel := diagnosticContext(cmd.Context(), timeout)
 		defer cancel()
 		client, err := diagnosticClient(ctx, &indexAuditConfig.BaseCfg)
 		if err != nil {
 			return err
 		}
 		defer closeSDKClient(client)
-		result, operationErr := client.IndexAudit(ctx, mot.IndexAuditOptions{Databases: splitCSV(indexAuditConfig.Databases), AllDatabases: indexAuditConfig.AllDatabases, Collections: splitCSV(indexAuditConfig.Collections), Checks: checks, IncludeSystemDB: indexAuditConfig.IncludeSystemDB, MinObservation: indexAuditConfig.MinObservation, MaxCollections: indexAuditConfig.MaxCollections, Concurrency: indexAuditConfig.Concurrency})
-		return printIndexAuditAndError(cmd, result, indexAuditConfig.Format, operationErr)
+		auditOpts := mot.IndexAuditOptions{Databases: splitCSV(indexAuditConfig.Databases), AllDatabases: indexAuditConfig.AllDatabases, Collections: splitCSV(indexAuditConfig.Collections), Checks: checks, IncludeSystemDB: indexAuditConfig.IncludeSystemDB, MinObservation: indexAuditConfig.MinObservation, MaxCollections: indexAuditConfig.MaxCollections, Concurrency: indexAuditConfig.Concurrency}
+		var ledgerPath string
+		if indexAuditConfig.UsageLedger != "" {
+			identity, identityErr := client.ClusterIdentity(ctx)
+			if identityErr != nil {
+				return safeDiagnosticCommandError(identityErr)
+			}
+			ledgerPath = indexUsageLedgerPath(indexAuditConfig.UsageLedger, identity)
+			ledger, readErr := readIndexUsageLedger(ledgerPath, identity)
+			if readErr != nil {
+				return readErr
+			}
+			auditOpts.UsageLedger = &ledger
+		}
+		if indexAuditConfig.BatchSize > 0 {
+			auditOpts.MaxCollections = indexAuditConfig.BatchSize
+			result, operationErr := runIndexAuditBatches(ctx, cmd, client, auditOpts, ledgerPath)
+			return finishIndexAudit(ctx, cmd, client, result, operationErr, planFormat)
+		}
+		result, operationErr := client.IndexAudit(ctx, auditOpts)
+		if result != nil && auditOpts.UsageLedger != nil && (operationErr == nil || errors.Is(operationErr, mot.ErrPartialResult)) {
+			auditOpts.UsageLedger.Record(result)
+			if writeErr := writeIndexUsageLedger(ledgerPath, *auditOpts.UsageLedger); writeErr != nil {
+				return writeErr
+			}
+		}
+		return finishIndexAudit(ctx, cmd, client, result, operationErr, planFormat)
 	},
 }
 
+// finishIndexAudit 在单次与分批审计之后统一生成修复计划、写入索引快照并输出结果。
+func finishIndexAudit(ctx context.Context, cmd *cobra.Command, client *mot.Client, result *mot.IndexAuditResult, operationErr error, planFormat string) error {
+	if result != nil && indexAuditConfig.EmitPlan != "" && (operationErr == nil || errors.Is(operationErr, mot.ErrPartialResult)) {
+		plan, planErr := client.IndexRemediationPlan(ctx, result, mot.IndexRemediationOptions{HidePeriod: indexAuditConfig.HidePeriod})
+		if planErr != nil {
+			return safeDiagnosticCommandError(planErr)
+		}
+		if writeErr := writeIndexRemediationPlan(indexAuditConfig.EmitPlan, planFormat, plan); writeErr != nil {
+			return writeErr
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "remediation plan with %d steps written to %s (not executed)\n", len(plan.Steps), indexAuditConfig.EmitPlan)
+	}
+	if result != nil && indexAuditConfig.Snapshot != "" && (operationErr == nil || errors.Is(operationErr, mot.ErrPartialResult)) {
+		snapshot, snapshotErr := client.IndexSnapshot(ctx, mot.IndexSnapshotOptions{Databases: splitCSV(indexAuditConfig.Databases), AllDatabases: indexAuditConfig.AllDatabases, Collections: splitCSV(indexAuditConfig.Collections), IncludeSystemDB: indexAuditConfig.IncludeSystemDB, MaxCollections: indexAuditConfig.MaxCollections})
+		if snapshot == nil {
+			return safeDiagnosticCommandError(snapshotErr)
+		}
+		if writeErr := writeLocalSnapshot(indexAuditConfig.Snapshot, "index", snapshot); writeErr != nil {
+			return writeErr
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "index snapshot with %d collections written to %s (%d incomplete)\n", len(snapshot.Collections), indexAuditConfig.Snapshot, len(snapshot.Incomplete))
+	}
+	return printIndexAuditAndError(cmd, result, indexAuditConfig.Format, operationErr)
+}
+
+// runIndexAuditBatches 以 --batch-size 为单位循环调用 IndexAuditBatch 并合并结果；每批结果追加到 --cursor-file 旁的批次文件，
+// 随后 cursor 文件只记录续跑位置与已确认批次数，续跑时逐行合并已确认批次再继续，全部完成后删除这两个文件。--timeout 作用于单个批次。
+func runIndexAuditBatches(ctx context.Context, cmd *cobra.Command, client *mot.Client, opts mot.IndexAuditOptions, ledgerPath string) (*mot.IndexAuditResult, error) {
+	saved, merged, restoredPartial, err := restoreIndexAuditBatches(indexAuditConfig.CursorFile)
+	if err != nil {
+		return nil, err
+	}
+	if saved.Cursor != "" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "resuming index audit from %s (%d/%d collections already processed)\n", indexAuditConfig.CursorFile, saved.ProcessedCollections, saved.TotalCollections)
+	}
+	var partialErrors []error
+	if restoredPartial {
+		partialErrors = append(partialErrors, fmt.Errorf("%w: batches restored from %s were incomplete", mot.ErrPartialResult, indexAuditBatchesPath(indexAuditConfig.CursorFile)))
+	}
+	for {
+		batchCtx, batchCancel := diagnosticContext(ctx, indexAuditConfig.Timeout)
+		batch, batchErr := client.IndexAuditBatch(batchCtx, mot.IndexAuditBatchOptions{Audit: opts, Cursor: saved.Cursor})
+		batchCancel()
+		if batch == nil {
+			if errors.Is(batchErr, mot.ErrIndexAuditScopeChanged) || errors.Is(batchErr, mot.ErrIndexAuditCursorInvalid) {
+				batchErr = fmt.Errorf("%w; remove %s to restart from the first batch", batchErr, indexAuditConfig.CursorFile)
+			}
+			if len(merged.Collections) == 0 {
+				return nil, batchErr
+			}
+			return merged, batchErr
+		}
+		if batchErr != nil {
+			partialErrors = append(partialErrors, batchErr)
+		}
+		if opts.UsageLedger != nil {
+			opts.UsageLedger.Record(&batch.IndexAuditResult)
+			if writeErr := writeIndexUsageLedger(ledgerPath, *opts.UsageLedger); writeErr != nil {
+				return nil, writeErr
+			}
+		}
+		merged.Merge(&batch.IndexAuditResult)
+		fmt.Fprintf(cmd.ErrOrStderr(), "index audit batch %d: %d collections in %d databases (%d/%d processed)\n",
+			batch.Batch.Index, batch.Batch.CollectionCount, batch.Batch.DatabaseCount, batch.Batch.ProcessedCollections, batch.Batch.TotalCollections)
+		if !batch.Batch.HasMore {
+			if removeErr := removeIndexAuditCursorFiles(indexAuditConfig.CursorFile); removeErr != nil {
+				return nil, removeErr
+			}
+			break
+		}
+		if saveErr := saveIndexAuditBatch(indexAuditConfig.CursorFile, &saved, batch, batchErr != nil); saveErr != nil {
+			return nil, saveErr
+		}
+	}
+	if len(partialErrors) > 0 {
+		return merged, errors.Join(partialErrors...)
+	}
+	return merged, nil
+}
+
+// indexAuditBatchesPath 返回与 cursor 文件同目录的批次结果文件。
+func indexAuditBatchesPath(cursorFile string) string {
+	return cursorFile + ".batches.jsonl"
+}
+
+// restoreIndexAuditBatches 读取续跑位置并合并已确认批次；从第一批开始时清理上次遗留的批次文件。
+func restoreIndexAuditBatches(cursorFile string) (indexAuditCursorFile, *mot.IndexAuditResult, bool, error) {
+	merged := &mot.IndexAuditResult{}
+	saved, err := readIndexAuditCursorFile(cursorFile)
+	if err != nil || cursorFile == "" {
+		return saved, merged, false, err
+	}
+	batchesPath := indexAuditBatchesPath(cursorFile)
+	if saved.Cursor == "" {
+		if removeErr := os.Remove(batchesPath); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+			return saved, nil, false, removeErr
+		}
+		return saved, merged, false, nil
+	}
+	partial, err := mergeIndexAuditBatchLines(batchesPath, saved.Batches, merged)
+	if err != nil {
+		return indexAuditCursorFile{}, nil, false, err
+	}
+	return saved, merged, partial, nil
+}
+
+// mergeIndexAuditBatchLines 按顺序合并前 count 行；cursor 写入前中断留下的多余行会被截掉，避免续跑后重复合并。
+func mergeIndexAuditBatchLines(path string, count int, merged *mot.IndexAuditResult) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, fmt.Errorf("index audit batch results %s are missing; remove the cursor file to restart from the first batch", path)
+		}
+		return false, err
+	}
+	reader := bufio.NewReader(file)
+	var offset int64
+	partial := false
+	for index := 0; index < count; index++ {
+		payload, readErr := reader.ReadBytes('\n')
+		if readErr != nil {
+			_ = file.Close()
+			if errors.Is(readErr, io.EOF) {
+				return false, fmt.Errorf("index audit batch results %s hold %d of %d batches; remove the cursor file to restart from the first batch", path, index, count)
+			}
+			return false, readErr
+		}
+		var line indexAuditBatchLine
+		if decodeErr := json.Unmarshal(payload, &line); decodeErr != nil {
+			_ = file.Close()
+			return false, fmt.Errorf("index audit batch results %s line %d: %w", path, index+1, decodeErr)
+		}
+		merged.Merge(&line.Result)
+		partial = partial || line.Partial
+		offset += int64(len(payload))
+	}
+	if err := file.Close(); err != nil {
+		return false, err
+	}
+	return partial, os.Truncate(path, offset)
+}
+
+// saveIndexAuditBatch 先追加本批结果再更新 cursor 文件，cursor 中的批次数只覆盖已落盘的行。
+func saveIndexAuditBatch(cursorFile string, saved *indexAuditCursorFile, batch *mot.IndexAuditBatchResult, partial bool) error {
+	saved.Cursor = batch.Batch.NextCursor
+	if cursorFile == "" {
+		return nil
+	}
+	line := indexAuditBatchLine{Index: batch.Batch.Index, Partial: partial, Result: batch.IndexAuditResult}
+	if err := appendLocalLine(indexAuditBatchesPath(cursorFile), line); err != nil {
+		return err
+	}
+	saved.ProcessedCollections, saved.TotalCollections = batch.Batch.ProcessedCollections, batch.Batch.TotalCollections
+	saved.Batches++
+	return writeLocalSnapshot(cursorFile, "index-audit-cursor", saved)
+}
+
+// removeIndexAuditCursorFiles 在全部批次完成后删除 cursor 与批次文件。
+func removeIndexAuditCursorFiles(cursorFile string) error {
+	if cursorFile == "" {
+		return nil
+	}
+	for _, path := range []string{cursorFile, indexAuditBatchesPath(cursorFile)} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateIndexAuditBatchCLI() error {
+	if indexAuditConfig.BatchSize < 0 || indexAuditConfig.BatchSize > 500 {
+		return fmt.Errorf("batch-size must be between 1 and 500")
+	}
+	if indexAuditConfig.BatchSize == 0 && indexAuditConfig.CursorFile != "" {
+		return fmt.Errorf("cursor-file requires batch-size")
+	}
+	if indexAuditConfig.BatchSize > 0 && indexAuditConfig.Snapshot != "" {
+		return fmt.Errorf("snapshot is not supported with batch-size")
+	}
+	return nil
+}
+
+// readIndexAuditCursorFile 读取续跑位置；未指定或文件不存在时从第一批开始，未记录已确认批次数的旧文件无法续跑。
+func readIndexAuditCursorFile(path string) (indexAuditCursorFile, error) {
+	var saved indexAuditCursorFile
+	if path == "" {
+		return saved, nil
+	}
+	if err := readLocalSnapshot(path, "index audit cursor", &saved); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return indexAuditCursorFile{}, nil
+		}
+		return indexAuditCursorFile{}, err
+	}
+	if saved.Cursor != "" && saved.Batches <= 0 {
+		return indexAuditCursorFile{}, fmt.Errorf("index audit cursor %s has no saved batch results; remove it to restart from the first batch", path)
+	}
+	return saved, nil
+}
+
 var capacityCmd = &cobra.Command{
 	Use:   "capacity",
 	Short: "Collect a stable, redacted MongoDB capacity snapshot",
@@ -235,6 +618,52 @@ var capacityDiffCmd = &cobra.Command{
 	},
 }
 
+var capacityForecastCmd = &cobra.Command{
+	Use:   "forecast <snapshot.json>...",
+	Short: "Forecast capacity exhaustion from a history of capacity snapshots offline",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := clioutput.ValidateFormat(capacityForecastConfig.Format); err != nil {
+			return err
+		}
+		quotas, err := parseCapacityQuotas(capacityForecastConfig.Quotas)
+		if err != nil {
+			return err
+		}
+		snapshots := make([]mot.CapacityResult, 0, len(args))
+		for _, path := range args {
+			snapshot, readErr := readCapacitySnapshot(path)
+			if readErr != nil {
+				return readErr
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+		result, err := mot.ForecastCapacity(snapshots, mot.CapacityForecastOptions{Method: mot.CapacityForecastMethod(capacityForecastConfig.Method), Quotas: quotas, Horizon: capacityForecastConfig.Horizon})
+		if err != nil {
+			return err
+		}
+		return clioutput.PrintDiagnosticResult(cmd.OutOrStdout(), result, capacityForecastConfig.Format)
+	},
+}
+
+// parseCapacityQuotas 解析 <database|namespace>=<size> 形式的 CSV 配额，size 支持 500GB、1.5TiB 等写法。
+func parseCapacityQuotas(value string) (map[string]int64, error) {
+	quotas := make(map[string]int64)
+	for _, part := range splitCSV(value) {
+		name, size, found := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid quota %q: want <database|namespace>=<size>", part)
+		}
+		parsed, err := humanize.ParseBytes(strings.TrimSpace(size))
+		if err != nil || parsed == 0 || parsed > math.MaxInt64 {
+			return nil, fmt.Errorf("invalid quota size %q for %s", size, name)
+		}
+		quotas[name] = int64(parsed)
+	}
+	return quotas, nil
+}
+
 func initDiagnostics() {
 	registerDiagnosticFlags(doctorCmd, &doctorConfig.diagnosticBaseConfig)
 	doctorCmd.Flags().StringVar(&doctorConfig.MinimumSeverity, "minimum-severity", "info", "Minimum finding severity: info|warning|critical")
@@ -258,15 +687,46 @@ func initDiagnostics() {
 	hotspotCmd.Flags().StringVar(&hotspotConfig.Databases, "database", "", "Filter by database names (CSV)")
 	hotspotCmd.Flags().BoolVar(&hotspotConfig.IncludeSystemDB, "include-system-db", false, "Include system databases")
 
+	registerDiagnosticFlags(latencyCmd, &latencyConfig.diagnosticBaseConfig)
+	latencyCmd.Flags().StringVar(&latencyConfig.Databases, "database", "", "Filter by database names (CSV); empty selects all non-system databases")
+	latencyCmd.Flags().StringVar(&latencyConfig.Collections, "collection", "", "Filter by collection names (CSV)")
+	latencyCmd.Flags().BoolVar(&latencyConfig.IncludeSystemDB, "include-system-db", false, "Include system databases")
+	latencyCmd.Flags().DurationVar(&latencyConfig.Duration, "duration", 0, "Interval between two snapshots; 0 reports cumulative histograms from a single snapshot")
+	latencyCmd.Flags().DurationVar(&latencyConfig.P99Threshold, "p99-threshold", 100*time.Millisecond, "Report namespaces whose approximate p99 latency reaches this threshold")
+	latencyCmd.Flags().IntVar(&latencyConfig.MaxCollections, "max-collections", 500, "Maximum number of collections")
+	latencyCmd.Flags().IntVar(&latencyConfig.Concurrency, "concurrency", 10, "Maximum number of concurrent node collectors")
+
+	registerDiagnosticFlags(queryStatsCmd, &queryStatsConfig.diagnosticBaseConfig)
+	queryStatsCmd.Flags().StringVar(&queryStatsConfig.Databases, "database", "", "Filter by database names (CSV); empty selects all non-system databases")
+	queryStatsCmd.Flags().BoolVar(&queryStatsConfig.IncludeSystemDB, "include-system-db", false, "Include system databases")
+	queryStatsCmd.Flags().IntVar(&queryStatsConfig.MaxShapes, "max-shapes", 50, "Maximum number of query shapes per node, ranked by total execution time")
+	queryStatsCmd.Flags().IntVar(&queryStatsConfig.Concurrency, "concurrency", 10, "Maximum number of concurrent node collectors")
+
+	registerDiagnosticFlags(scatterGatherCmd, &scatterGatherConfig.diagnosticBaseConfig)
+	scatterGatherCmd.Flags().StringVar(&scatterGatherConfig.Databases, "database", "", "Filter by database names (CSV)")
+	scatterGatherCmd.Flags().StringVar(&scatterGatherConfig.Namespaces, "ns", "", "Comma-separated namespaces (db.collection) to analyze")
+	scatterGatherCmd.Flags().StringVar(&scatterGatherConfig.Operations, "op", "", "Comma-separated profiler operations: command, getmore, insert, query, remove, update")
+	scatterGatherCmd.Flags().StringVar(&scatterGatherConfig.Since, "since", "", "Only analyze operations after this time, duration ago (e.g. 1h) or RFC3339")
+	scatterGatherCmd.Flags().StringVar(&scatterGatherConfig.Until, "until", "", "Only analyze operations before this time, duration ago (e.g. 10m) or RFC3339")
+	scatterGatherCmd.Flags().Int64Var(&scatterGatherConfig.MinExecutions, "min-executions", 5, "Minimum routed executions before a shape is reported")
+	scatterGatherCmd.Flags().Float64Var(&scatterGatherConfig.BroadcastRatio, "broadcast-ratio", 0.5, "Report shapes whose broadcast share of routed executions reaches this ratio")
+	scatterGatherCmd.Flags().IntVar(&scatterGatherConfig.Concurrency, "concurrency", 10, "Maximum number of concurrent mongos collectors")
+
 	registerDiagnosticFlags(indexAuditCmd, &indexAuditConfig.diagnosticBaseConfig)
 	indexAuditCmd.Flags().StringVar(&indexAuditConfig.Databases, "database", "", "Select databases (CSV); mutually exclusive with --all-databases")
 	indexAuditCmd.Flags().BoolVar(&indexAuditConfig.AllDatabases, "all-databases", false, "Audit all non-system databases")
 	indexAuditCmd.Flags().StringVar(&indexAuditConfig.Collections, "collection", "", "Filter by collection names (CSV)")
-	indexAuditCmd.Flags().StringVar(&indexAuditConfig.Checks, "checks", "", "Checks to run (CSV): unused,redundant,space,building,consistency")
+	indexAuditCmd.Flags().StringVar(&indexAuditConfig.Checks, "checks", "", "Checks to run (CSV): unused,redundant,space,building,consistency,member_consistency,shardkey,ttl,special (default: all but member_consistency, shardkey, ttl and special)")
 	indexAuditCmd.Flags().BoolVar(&indexAuditConfig.IncludeSystemDB, "include-system-db", false, "Include system databases")
 	indexAuditCmd.Flags().DurationVar(&indexAuditConfig.MinObservation, "min-observation", 7*24*time.Hour, "Minimum observation window for zero usage")
-	indexAuditCmd.Flags().IntVar(&indexAuditConfig.MaxCollections, "max-collections", 500, "Maximum number of collections")
+	indexAuditCmd.Flags().IntVar(&indexAuditConfig.MaxCollections, "max-collections", 500, "Maximum number of collections in single-shot mode (ignored with --batch-size)")
+	indexAuditCmd.Flags().IntVar(&indexAuditConfig.BatchSize, "batch-size", 0, "Audit all selected collections in batches of at most this many (1-500) and merge the results; 0 runs a single bounded audit")
+	indexAuditCmd.Flags().StringVar(&indexAuditConfig.CursorFile, "cursor-file", "", "Store the continuation cursor after each batch and resume from it on the next run; removed when the audit completes")
 	indexAuditCmd.Flags().IntVar(&indexAuditConfig.Concurrency, "concurrency", 10, "Maximum number of concurrent collection collectors")
+	indexAuditCmd.Flags().StringVar(&indexAuditConfig.EmitPlan, "emit-plan", "", "Write a reviewable remediation plan (.js mongosh script or .json) without executing it")
+	indexAuditCmd.Flags().DurationVar(&indexAuditConfig.HidePeriod, "hide-period", 7*24*time.Hour, "Observation period between hiding an unused index and dropping it")
+	indexAuditCmd.Flags().StringVar(&indexAuditConfig.UsageLedger, "usage-ledger", "", "Directory of per-cluster index usage ledgers; each run appends $indexStats counters so zero usage can be proven across restarts")
+	indexAuditCmd.Flags().StringVar(&indexAuditConfig.Snapshot, "snapshot", "", "Write canonical index definitions (fingerprints only) to a local JSON path for index diff")
 
 	registerDiagnosticFlags(capacityCmd, &capacityConfig.diagnosticBaseConfig)
 	capacityCmd.Flags().StringVar(&capacityConfig.Databases, "database", "", "Filter by database names (CSV); empty selects all non-system databases")
@@ -278,7 +738,12 @@ func initDiagnostics() {
 	capacityCmd.Flags().IntVar(&capacityConfig.Concurrency, "concurrency", 10, "Maximum number of concurrent collection collectors")
 	capacityDiffCmd.Flags().String("format", "table", "Output format: table|json")
 	capacityCmd.AddCommand(capacityDiffCmd)
-	rootCmd.AddCommand(doctorCmd, opsCmd, hotspotCmd, indexAuditCmd, capacityCmd)
+	capacityForecastCmd.Flags().StringVar(&capacityForecastConfig.Format, "format", "table", "Output format: table|json")
+	capacityForecastCmd.Flags().StringVar(&capacityForecastConfig.Method, "method", "linear", "Trend fitting method: linear (least squares) or robust (Theil-Sen, tolerant of outlier snapshots)")
+	capacityForecastCmd.Flags().StringVar(&capacityForecastConfig.Quotas, "quota", "", "Quotas (CSV) as <database|namespace>=<size>, e.g. app=500GB,app.events=100GiB")
+	capacityForecastCmd.Flags().DurationVar(&capacityForecastConfig.Horizon, "horizon", 90*24*time.Hour, "Report exhaustion findings projected within this window")
+	capacityCmd.AddCommand(capacityForecastCmd)
+	rootCmd.AddCommand(doctorCmd, opsCmd, hotspotCmd, latencyCmd, queryStatsCmd, scatterGatherCmd, indexAuditCmd, capacityCmd)
 }
 
 func registerDiagnosticFlags(command *cobra.Command, cfg *diagnosticBaseConfig) {
@@ -391,6 +856,8 @@ func safeDiagnosticCommandError(operationErr error) error {
 		return fmt.Errorf("%w: 部分 collector 未完成，已输出可用结果", mot.ErrPartialResult)
 	case errors.Is(operationErr, mot.ErrUnsupportedTopology):
 		return fmt.Errorf("%w", mot.ErrUnsupportedTopology)
+	case errors.Is(operationErr, mot.ErrDangerousOperation), errors.Is(operationErr, mot.ErrIndexAuditScopeChanged), errors.Is(operationErr, mot.ErrIndexAuditCursorInvalid):
+		return operationErr
 	default:
 		return errors.New("diagnostic command failed; 原始服务器错误已隐藏")
 	}
@@ -402,7 +869,7 @@ func parseIndexChecks(value string) ([]mot.IndexAuditCheck, error) {
 	for _, part := range parts {
 		check := mot.IndexAuditCheck(strings.ToLower(part))
 		switch check {
-		case mot.IndexCheckUnused, mot.IndexCheckRedundant, mot.IndexCheckSpace, mot.IndexCheckBuilding, mot.IndexCheckConsistency:
+		case mot.IndexCheckUnused, mot.IndexCheckRedundant, mot.IndexCheckSpace, mot.IndexCheckBuilding, mot.IndexCheckConsistency, mot.IndexCheckMemberConsistency, mot.IndexCheckShardKey, mot.IndexCheckTTL, mot.IndexCheckSpecial:
 			result = append(result, check)
 		default:
 			return nil, fmt.Errorf("unknown index audit check %q", part)
@@ -412,13 +879,72 @@ func parseIndexChecks(value string) ([]mot.IndexAuditCheck, error) {
 }
 
 func writeCapacitySnapshot(path string, result *mot.CapacityResult) error {
-	payload, err := json.MarshalIndent(result, "", "  ")
+	return writeLocalSnapshot(path, "capacity", result)
+}
+
+// indexUsageLedgerPath 按集群身份摘要的前 16 位选择 ledger 文件，同一目录可保存多个集群的 ledger。
+func indexUsageLedgerPath(directory string, identity mot.CapacityIdentity) string {
+	digest := identity.Digest
+	if len(digest) > 16 {
+		digest = digest[:16]
+	}
+	return filepath.Join(directory, "index-usage-"+digest+".json")
+}
+
+// readIndexUsageLedger 读取本地使用 ledger；文件不存在时返回绑定到当前集群的空 ledger。
+func readIndexUsageLedger(path string, identity mot.CapacityIdentity) (mot.IndexUsageLedger, error) {
+	var ledger mot.IndexUsageLedger
+	if err := readLocalSnapshot(path, "index usage ledger", &ledger); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return mot.NewIndexUsageLedger(identity), nil
+		}
+		return mot.IndexUsageLedger{}, err
+	}
+	return ledger, nil
+}
+
+func writeIndexUsageLedger(path string, ledger mot.IndexUsageLedger) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return writeLocalSnapshot(path, "index-usage", ledger)
+}
+
+// indexRemediationPlanFormat 按 --emit-plan 的扩展名选择输出格式。
+func indexRemediationPlanFormat(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".js":
+		return clioutput.PlanFormatJS, nil
+	case ".json":
+		return clioutput.PlanFormatJSON, nil
+	default:
+		return "", fmt.Errorf("emit-plan path must end with .js or .json")
+	}
+}
+
+func writeIndexRemediationPlan(path, format string, plan *mot.IndexRemediationPlan) error {
+	var buffer bytes.Buffer
+	if err := clioutput.WriteIndexRemediationPlan(&buffer, plan, format); err != nil {
+		return err
+	}
+	return writeLocalFile(path, "plan", buffer.Bytes())
+}
+
+// writeLocalSnapshot 以 0600 权限原子写入 JSON 快照，避免中断时留下半个文件。
+func writeLocalSnapshot(path, kind string, value any) error {
+	payload, err := json.MarshalIndent(value, "", "  ")
 	if err != nil {
 		return err
 	}
-	payload = append(payload, '\n')
+	return writeLocalFile(path, kind, append(payload, '\n'))
+}
+
+func writeLocalFile(path, kind string, payload []byte) error {
 	directory := filepath.Dir(path)
-	temporary, err := os.CreateTemp(directory, ".mot-capacity-*.tmp")
+	temporary, err := os.CreateTemp(directory, ".mot-"+kind+"-*.tmp")
 	if err != nil {
 		return err
 	}
@@ -442,21 +968,47 @@ func writeCapacitySnapshot(path string, result *mot.CapacityResult) error {
 	return os.Rename(temporaryPath, path)
 }
 
-func readCapacitySnapshot(path string) (mot.CapacityResult, error) {
-	info, err := os.Stat(path)
+// appendLocalLine 以 0600 权限向 JSON Lines 文件追加一行并落盘，已有内容不会被重写。
+func appendLocalLine(path string, value any) error {
+	payload, err := json.Marshal(value)
 	if err != nil {
-		return mot.CapacityResult{}, err
-	}
-	if info.Size() > maxCapacitySnapshotBytes {
-		return mot.CapacityResult{}, fmt.Errorf("capacity snapshot exceeds %d bytes", maxCapacitySnapshotBytes)
+		return err
 	}
-	payload, err := os.ReadFile(path)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
 	if err != nil {
-		return mot.CapacityResult{}, err
+		return err
+	}
+	if _, err := file.Write(append(payload, '\n')); err != nil {
+		_ = file.Close()
+		return err
 	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func readCapacitySnapshot(path string) (mot.CapacityResult, error) {
 	var result mot.CapacityResult
-	if err := json.Unmarshal(payload, &result); err != nil {
+	if err := readLocalSnapshot(path, "capacity", &result); err != nil {
 		return mot.CapacityResult{}, err
 	}
 	return result, nil
 }
+
+// readLocalSnapshot 读取大小受限的 JSON 快照；未知字段被忽略以兼容新版本写出的快照。
+func readLocalSnapshot(path, kind string, target any) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() > maxLocalSnapshotBytes {
+		return fmt.Errorf("%s snapshot exceeds %d bytes", kind, maxLocalSnapshotBytes)
+	}
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(payload, target)
+}
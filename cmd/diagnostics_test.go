@@ -10,6 +10,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 	"unicode"
 
 	"github.com/spf13/cobra"
@@ -32,6 +33,42 @@ func TestParseIndexChecksRejectsUnknownBeforeConnection(t *testing.T) {
 	if len(checks) != 2 || checks[0] != mot.IndexCheckUnused || checks[1] != mot.IndexCheckSpace {
 		t.Fatalf("checks = %#v", checks)
 	}
+	if checks, err := parseIndexChecks("MEMBER_CONSISTENCY"); err != nil || len(checks) != 1 || checks[0] != mot.IndexCheckMemberConsistency {
+		t.Fatalf("member_consistency checks = %#v, %v", checks, err)
+	}
+	if checks, err := parseIndexChecks("consistency,shardkey"); err != nil || len(checks) != 2 || checks[1] != mot.IndexCheckShardKey {
+		t.Fatalf("shardkey checks = %#v, %v", checks, err)
+	}
+	if checks, err := parseIndexChecks("ttl"); err != nil || len(checks) != 1 || checks[0] != mot.IndexCheckTTL {
+		t.Fatalf("ttl checks = %#v, %v", checks, err)
+	}
+	if checks, err := parseIndexChecks("special"); err != nil || len(checks) != 1 || checks[0] != mot.IndexCheckSpecial {
+		t.Fatalf("special checks = %#v, %v", checks, err)
+	}
+}
+
+func TestIndexRemediationPlanFormatFollowsExtension(t *testing.T) {
+	// 场景：--emit-plan 按扩展名选择 mongosh 脚本或 JSON，未知扩展名在建立连接前失败。
+	for path, want := range map[string]string{"": "", "plan.js": "js", "out/PLAN.JSON": "json"} {
+		got, err := indexRemediationPlanFormat(path)
+		if err != nil || got != want {
+			t.Fatalf("indexRemediationPlanFormat(%q) = %q, %v; want %q", path, got, err, want)
+		}
+	}
+	if _, err := indexRemediationPlanFormat("plan.txt"); err == nil {
+		t.Fatal("unknown plan extension was accepted")
+	}
+	path := t.TempDir() + "/plan.js"
+	if err := writeIndexRemediationPlan(path, "js", &mot.IndexRemediationPlan{SchemaVersion: 1}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("plan mode = %v, want 0600", info.Mode().Perm())
+	}
 }
 
 func TestReadCapacitySnapshotIgnoresCompatibleUnknownFields(t *testing.T) {
@@ -49,6 +86,28 @@ func TestReadCapacitySnapshotIgnoresCompatibleUnknownFields(t *testing.T) {
 	}
 }
 
+func TestIndexUsageLedgerFileKeyedByClusterDigest(t *testing.T) {
+	// 场景：使用 ledger 按集群摘要选择文件；文件不存在时得到绑定当前集群的空 ledger，目录不存在时写入会自动创建。
+	identity := mot.CapacityIdentity{TopologyType: mot.ClusterReplicaSet, Digest: "0123456789abcdef0123456789abcdef"}
+	directory := t.TempDir() + "/ledgers"
+	path := indexUsageLedgerPath(directory, identity)
+	if path != directory+"/index-usage-0123456789abcdef.json" {
+		t.Fatalf("ledger path = %q", path)
+	}
+	ledger, err := readIndexUsageLedger(path, identity)
+	if err != nil || ledger.ClusterDigest != identity.Digest || len(ledger.Observations) != 0 {
+		t.Fatalf("missing ledger = %#v, %v", ledger, err)
+	}
+	ledger.Observations = append(ledger.Observations, mot.IndexUsageObservation{Namespace: "app.orders", IndexName: "legacy_1", Host: "n1:27017"})
+	if err := writeIndexUsageLedger(path, ledger); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := readIndexUsageLedger(path, identity)
+	if err != nil || len(loaded.Observations) != 1 || loaded.Observations[0].IndexName != "legacy_1" {
+		t.Fatalf("loaded ledger = %#v, %v", loaded, err)
+	}
+}
+
 func TestDiagnosticCLIValidationRunsBeforeConnection(t *testing.T) {
 	// 场景：format、timeout、severity 和并发非法时，CLI 可在建立 MongoDB 连接前拒绝。
 	if err := validateDiagnosticBase(diagnosticBaseConfig{Format: "yaml"}); err == nil {
@@ -113,7 +172,7 @@ func TestIndexAuditPartialResultRendersAndReturnsSuccess(t *testing.T) {
 }
 
 func TestDiagnosticCommandFlagDefaultsAndIndexMutualExclusion(t *testing.T) {
-	// 场景：五个命令的关键默认值保持稳定，index database 选择严格互斥，且完整命令树的 help 只使用英文。
+	// 场景：六个命令的关键默认值保持稳定，index database 选择严格互斥，且完整命令树的 help 只使用英文。
 	initializeCommandsForTest.Do(initAll)
 	assertCommandTreeHelpUsesEnglish(t, rootCmd)
 	tests := []struct {
@@ -123,7 +182,10 @@ func TestDiagnosticCommandFlagDefaultsAndIndexMutualExclusion(t *testing.T) {
 		{doctorCmd, map[string]string{"format": "table", "timeout": "30s", "concurrency": "10", "oplog-window": "false"}},
 		{opsCmd, map[string]string{"format": "table", "min-duration": "2s", "limit": "100", "all-users": "true"}},
 		{hotspotCmd, map[string]string{"duration": "10s", "top": "10", "concurrency": "10"}},
-		{indexAuditCmd, map[string]string{"max-collections": "500", "concurrency": "10", "all-databases": "false"}},
+		{latencyCmd, map[string]string{"duration": "0s", "p99-threshold": "100ms", "max-collections": "500", "concurrency": "10"}},
+		{queryStatsCmd, map[string]string{"max-shapes": "50", "concurrency": "10", "include-system-db": "false"}},
+		{scatterGatherCmd, map[string]string{"min-executions": "5", "broadcast-ratio": "0.5", "concurrency": "10"}},
+		{indexAuditCmd, map[string]string{"max-collections": "500", "concurrency": "10", "all-databases": "false", "emit-plan": "", "hide-period": "168h0m0s"}},
 		{capacityCmd, map[string]string{"max-collections": "500", "concurrency": "10", "free-storage": "false"}},
 	}
 	for _, test := range tests {
@@ -151,7 +213,7 @@ func TestDiagnosticCommandFlagDefaultsAndIndexMutualExclusion(t *testing.T) {
 			t.Fatalf("invalid selection accepted: %#v", input)
 		}
 	}
-	if got := []string{doctorCmd.Name(), opsCmd.Name(), hotspotCmd.Name(), indexAuditCmd.Name(), capacityCmd.Name()}; !reflect.DeepEqual(got, []string{"doctor", "ops", "hotspot", "index-audit", "capacity"}) {
+	if got := []string{doctorCmd.Name(), opsCmd.Name(), hotspotCmd.Name(), latencyCmd.Name(), indexAuditCmd.Name(), capacityCmd.Name()}; !reflect.DeepEqual(got, []string{"doctor", "ops", "hotspot", "latency", "index-audit", "capacity"}) {
 		t.Fatalf("commands = %v", got)
 	}
 }
@@ -196,3 +258,151 @@ func TestDiagnosticClientAndTopLevelCleanupUseSuppressedErrors(t *testing.T) {
 		}
 	}
 }
+
+func TestIndexAuditBatchValidationAndCursorFile(t *testing.T) {
+	// 场景：batch-size 超出范围、cursor-file 未配合 batch-size、批量模式与 snapshot 同用时在连接前拒绝；cursor 文件不存在时从第一批开始，写入后可读回续跑位置。
+	saved := indexAuditConfig
+	t.Cleanup(func() { indexAuditConfig = saved })
+	for _, tt := range []struct {
+		batchSize  int
+		cursorFile string
+		snapshot   string
+	}{
+		{batchSize: -1},
+		{batchSize: 501},
+		{cursorFile: "cursor.json"},
+		{batchSize: 100, snapshot: "indexes.json"},
+	} {
+		indexAuditConfig.BatchSize, indexAuditConfig.CursorFile, indexAuditConfig.Snapshot = tt.batchSize, tt.cursorFile, tt.snapshot
+		if err := validateIndexAuditBatchCLI(); err == nil {
+			t.Fatalf("invalid batch options accepted: %#v", tt)
+		}
+	}
+	indexAuditConfig.BatchSize, indexAuditConfig.CursorFile, indexAuditConfig.Snapshot = 100, "cursor.json", ""
+	if err := validateIndexAuditBatchCLI(); err != nil {
+		t.Fatal(err)
+	}
+	path := t.TempDir() + "/cursor.json"
+	if cursor, err := readIndexAuditCursorFile(path); err != nil || cursor.Cursor != "" {
+		t.Fatalf("missing cursor file = %#v, %v", cursor, err)
+	}
+	if err := writeLocalSnapshot(path, "index-audit-cursor", indexAuditCursorFile{Cursor: "opaque", ProcessedCollections: 100, TotalCollections: 250}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readIndexAuditCursorFile(path); err == nil || !strings.Contains(err.Error(), "no saved batch results") {
+		t.Fatalf("cursor without results error = %v", err)
+	}
+	if err := writeLocalSnapshot(path, "index-audit-cursor", indexAuditCursorFile{Cursor: "opaque", ProcessedCollections: 100, TotalCollections: 250, Batches: 1}); err != nil {
+		t.Fatal(err)
+	}
+	cursor, err := readIndexAuditCursorFile(path)
+	if err != nil || cursor.Cursor != "opaque" || cursor.ProcessedCollections != 100 || cursor.Batches != 1 {
+		t.Fatalf("cursor file = %#v, %v", cursor, err)
+	}
+	if _, _, _, err := restoreIndexAuditBatches(path); err == nil || !strings.Contains(err.Error(), "are missing") {
+		t.Fatalf("missing batch results error = %v", err)
+	}
+	if err := safeDiagnosticCommandError(fmt.Errorf("%w; remove %s", mot.ErrIndexAuditScopeChanged, path)); !errors.Is(err, mot.ErrIndexAuditScopeChanged) || !strings.Contains(err.Error(), path) {
+		t.Fatalf("scope change error = %v", err)
+	}
+}
+
+func TestIndexAuditBatchesResumeAcrossSeveralBatches(t *testing.T) {
+	// 场景：cursor 文件只保存续跑位置与已确认批次数，每批结果逐行追加到批次文件；多次中断续跑后合并全部批次与 partial 标记，cursor 写入前中断留下的多余行被截掉，全部完成后两个文件都被删除。
+	path := t.TempDir() + "/cursor.json"
+	batch := func(index int, namespace string, hasMore bool) *mot.IndexAuditBatchResult {
+		return &mot.IndexAuditBatchResult{
+			IndexAuditResult: mot.IndexAuditResult{Collections: []mot.CollectionIndexAudit{{Namespace: namespace}}},
+			Batch:            mot.IndexAuditBatchMetadata{Index: index, ProcessedCollections: index, TotalCollections: 4, HasMore: hasMore, NextCursor: fmt.Sprintf("cursor-%d", index)},
+		}
+	}
+	saved, merged, partial, err := restoreIndexAuditBatches(path)
+	if err != nil || saved.Cursor != "" || len(merged.Collections) != 0 || partial {
+		t.Fatalf("fresh restore = %#v, %#v, %v, %v", saved, merged, partial, err)
+	}
+	if err := saveIndexAuditBatch(path, &saved, batch(1, "app.a", true), false); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveIndexAuditBatch(path, &saved, batch(2, "app.b", true), true); err != nil {
+		t.Fatal(err)
+	}
+	var cursorFile map[string]any
+	if err := readLocalSnapshot(path, "index audit cursor", &cursorFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cursorFile["result"]; ok || len(cursorFile) != 4 {
+		t.Fatalf("cursor file holds more than progress: %#v", cursorFile)
+	}
+
+	saved, merged, partial, err = restoreIndexAuditBatches(path)
+	if err != nil || saved.Cursor != "cursor-2" || saved.Batches != 2 || len(merged.Collections) != 2 || !partial {
+		t.Fatalf("first resume = %#v, %#v, %v, %v", saved, merged, partial, err)
+	}
+	if err := appendLocalLine(indexAuditBatchesPath(path), indexAuditBatchLine{Index: 3, Result: mot.IndexAuditResult{Collections: []mot.CollectionIndexAudit{{Namespace: "app.orphan"}}}}); err != nil {
+		t.Fatal(err)
+	}
+	saved, merged, _, err = restoreIndexAuditBatches(path)
+	if err != nil || len(merged.Collections) != 2 {
+		t.Fatalf("resume after orphan line = %#v, %v", merged, err)
+	}
+	if err := saveIndexAuditBatch(path, &saved, batch(3, "app.c", true), false); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, merged, partial, err = restoreIndexAuditBatches(path)
+	if err != nil || saved.Cursor != "cursor-3" || saved.Batches != 3 || !partial {
+		t.Fatalf("second resume = %#v, %v, %v", saved, partial, err)
+	}
+	final := batch(4, "app.d", false)
+	merged.Merge(&final.IndexAuditResult)
+	var namespaces []string
+	for _, collection := range merged.Collections {
+		namespaces = append(namespaces, collection.Namespace)
+	}
+	if strings.Join(namespaces, ",") != "app.a,app.b,app.c,app.d" {
+		t.Fatalf("merged namespaces = %v", namespaces)
+	}
+	if err := removeIndexAuditCursorFiles(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{path, indexAuditBatchesPath(path)} {
+		if _, err := os.Stat(file); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("%s not removed: %v", file, err)
+		}
+	}
+}
+
+func TestCapacityForecastReadsSnapshotHistoryOffline(t *testing.T) {
+	// 场景：配额按 <database|namespace>=<size> 解析，格式错误时在读取快照前拒绝；多个本地快照离线拟合后输出 JSON 预测与 finding。
+	if quotas, err := parseCapacityQuotas("app=1KB, app.events=2KiB"); err != nil || quotas["app"] != 1000 || quotas["app.events"] != 2048 {
+		t.Fatalf("quotas = %#v, %v", quotas, err)
+	}
+	for _, value := range []string{"app", "=1GB", "app=lots", "app=0"} {
+		if _, err := parseCapacityQuotas(value); err == nil {
+			t.Fatalf("invalid quota %q accepted", value)
+		}
+	}
+	saved := capacityForecastConfig
+	t.Cleanup(func() { capacityForecastConfig = saved })
+	capacityForecastConfig.Format, capacityForecastConfig.Method, capacityForecastConfig.Quotas, capacityForecastConfig.Horizon = "json", "linear", "app=1KB", 0
+	directory := t.TempDir()
+	var paths []string
+	for day := 0; day < 3; day++ {
+		total := int64(700 + 100*day)
+		snapshot := &mot.CapacityResult{SchemaVersion: 1, ClusterIdentity: mot.CapacityIdentity{TopologyType: mot.ClusterReplicaSet, Digest: "same"}, CollectedAt: time.Unix(int64(day)*24*3600, 0), Databases: []mot.DatabaseCapacity{{Name: "app", TotalSizeBytes: &total}}}
+		path := fmt.Sprintf("%s/capacity-%d.json", directory, day)
+		if err := writeCapacitySnapshot(path, snapshot); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	command := &cobra.Command{}
+	var output bytes.Buffer
+	command.SetOut(&output)
+	if err := capacityForecastCmd.RunE(command, paths); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output.String(), `"capacity.exhaustion_forecast"`) || !strings.Contains(output.String(), `"daysUntilLimit": 1`) {
+		t.Fatalf("forecast output = %s", output.String())
+	}
+}
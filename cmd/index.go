@@ -0,0 +1,303 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SisyphusSQ/mongo-overview-tool/v2/internal/clioutput"
+	"github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mot"
+	"github.com/SisyphusSQ/mongo-overview-tool/v2/vars"
+)
+
+const defaultIndexLedgerPath = "mot-index-ledger.json"
+
+var indexLifecycleConfig struct {
+	diagnosticBaseConfig
+	Namespace   string
+	IndexName   string
+	MaxUsageOps int64
+	DryRun      bool
+	Confirm     bool
+	Ledger      string
+	Concurrency int
+}
+
+var indexBuildsConfig struct {
+	diagnosticBaseConfig
+	Databases      string
+	Namespace      string
+	Concurrency    int
+	Watch          bool
+	Interval       time.Duration
+	StallThreshold time.Duration
+}
+
+var indexDiffConfig struct {
+	Format string
+	Remaps []string
+}
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Manage index lifecycle, watch index builds and compare index snapshots offline",
+}
+
+var indexHideCmd = &cobra.Command{
+	Use:     "hide",
+	Short:   "Hide an index with collMod (4.4+); requires --confirm or --dry-run",
+	Example: fmt.Sprintf("%s index hide --uri <mongodbUri> --ns app.orders --index legacy_1 --dry-run\n", vars.AppName),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runIndexVisibilityChange(cmd, true)
+	},
+}
+
+var indexUnhideCmd = &cobra.Command{
+	Use:     "unhide",
+	Short:   "Unhide an index with collMod (4.4+); requires --confirm or --dry-run",
+	Example: fmt.Sprintf("%s index unhide --uri <mongodbUri> --ns app.orders --index legacy_1 --confirm\n", vars.AppName),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runIndexVisibilityChange(cmd, false)
+	},
+}
+
+var indexStatusCmd = &cobra.Command{
+	Use:     "status",
+	Short:   "Show how long ledger-tracked indexes have been hidden and the slow queries seen since",
+	Example: fmt.Sprintf("%s index status --uri <mongodbUri> --ledger ./mot-index-ledger.json\n", vars.AppName),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if err := validateDiagnosticBase(indexLifecycleConfig.diagnosticBaseConfig); err != nil {
+			return err
+		}
+		if indexLifecycleConfig.Concurrency < 0 {
+			return fmt.Errorf("--concurrency must not be negative")
+		}
+		ledger, err := readIndexLifecycleLedger(indexLifecycleConfig.Ledger)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := diagnosticContext(cmd.Context(), indexLifecycleConfig.Timeout)
+		defer cancel()
+		client, err := diagnosticClient(ctx, &indexLifecycleConfig.BaseCfg)
+		if err != nil {
+			return err
+		}
+		defer closeSDKClient(client)
+		result, operationErr := client.IndexLifecycleStatus(ctx, mot.IndexLifecycleStatusOptions{Ledger: ledger, Namespaces: splitCSV(indexLifecycleConfig.Namespace), NodeConcurrency: indexLifecycleConfig.Concurrency})
+		if result == nil {
+			return safeDiagnosticCommandError(operationErr)
+		}
+		return printDiagnosticAndError(cmd, result, indexLifecycleConfig.Format, operationErr)
+	},
+}
+
+var indexBuildsCmd = &cobra.Command{
+	Use:     "builds",
+	Short:   "List active index builds on every member with phase, progress and elapsed time",
+	Example: fmt.Sprintf("%s index builds --uri <mongodbUri> --watch --interval 30s\n", vars.AppName),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if err := validateIndexBuildsCLI(); err != nil {
+			return err
+		}
+		return runIndexBuilds(cmd)
+	},
+}
+
+var indexDiffCmd = &cobra.Command{
+	Use:     "diff <source.json> <target.json>",
+	Short:   "Compare two index-audit snapshots offline, e.g. staging against production",
+	Example: fmt.Sprintf("%s index diff staging.json prod.json --remap 'app_staging.* -> app.*'\n", vars.AppName),
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := clioutput.ValidateFormat(indexDiffConfig.Format); err != nil {
+			return err
+		}
+		remaps, err := parseNamespaceRemaps(indexDiffConfig.Remaps)
+		if err != nil {
+			return err
+		}
+		source, err := readIndexSnapshot(args[0])
+		if err != nil {
+			return err
+		}
+		target, err := readIndexSnapshot(args[1])
+		if err != nil {
+			return err
+		}
+		result, err := mot.DiffIndexSnapshots(source, target, mot.IndexDiffOptions{Remaps: remaps})
+		if err != nil {
+			return err
+		}
+		return clioutput.PrintDiagnosticResult(cmd.OutOrStdout(), result, indexDiffConfig.Format)
+	},
+}
+
+func runIndexVisibilityChange(cmd *cobra.Command, hidden bool) error {
+	if err := validateDiagnosticBase(indexLifecycleConfig.diagnosticBaseConfig); err != nil {
+		return err
+	}
+	if err := validateIndexLifecycleCLI(); err != nil {
+		return err
+	}
+	ledger, err := readIndexLifecycleLedger(indexLifecycleConfig.Ledger)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := diagnosticContext(cmd.Context(), indexLifecycleConfig.Timeout)
+	defer cancel()
+	client, err := diagnosticClient(ctx, &indexLifecycleConfig.BaseCfg)
+	if err != nil {
+		return err
+	}
+	defer closeSDKClient(client)
+	opts := mot.IndexLifecycleOptions{Namespace: indexLifecycleConfig.Namespace, IndexName: indexLifecycleConfig.IndexName, MaxUsageOps: indexLifecycleConfig.MaxUsageOps, DryRun: indexLifecycleConfig.DryRun, NodeConcurrency: indexLifecycleConfig.Concurrency}
+	change := client.UnhideIndex
+	if hidden {
+		change = client.HideIndex
+	}
+	result, operationErr := change(ctx, opts)
+	if result == nil {
+		return safeDiagnosticCommandError(operationErr)
+	}
+	if indexLifecycleChanged(result) {
+		ledger.Record(result)
+		if writeErr := writeLocalSnapshot(indexLifecycleConfig.Ledger, "index-ledger", ledger); writeErr != nil {
+			return fmt.Errorf("index visibility changed but ledger write failed: %w", writeErr)
+		}
+	}
+	return printDiagnosticAndError(cmd, result, indexLifecycleConfig.Format, operationErr)
+}
+
+// runIndexBuilds 在 --watch 下按 --interval 重复采集，每轮把上一轮结果传给 SDK 估算速率与停滞；
+// --timeout 作用于单轮采集，构建全部结束或收到中断信号时退出。
+func runIndexBuilds(cmd *cobra.Command) error {
+	ctx, cancel := diagnosticContext(cmd.Context(), 0)
+	defer cancel()
+	client, err := diagnosticClient(ctx, &indexBuildsConfig.BaseCfg)
+	if err != nil {
+		return err
+	}
+	defer closeSDKClient(client)
+	opts := mot.IndexBuildsOptions{Databases: splitCSV(indexBuildsConfig.Databases), Namespaces: splitCSV(indexBuildsConfig.Namespace), NodeConcurrency: indexBuildsConfig.Concurrency, StallThreshold: indexBuildsConfig.StallThreshold}
+	for {
+		roundCtx, roundCancel := diagnosticContext(ctx, indexBuildsConfig.Timeout)
+		result, operationErr := client.IndexBuilds(roundCtx, opts)
+		roundCancel()
+		if result == nil {
+			return safeDiagnosticCommandError(operationErr)
+		}
+		if err := printDiagnosticAndError(cmd, result, indexBuildsConfig.Format, operationErr); err != nil {
+			return err
+		}
+		if !indexBuildsConfig.Watch || len(result.Builds) == 0 {
+			return nil
+		}
+		opts.Previous = result
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(indexBuildsConfig.Interval):
+		}
+	}
+}
+
+func validateIndexBuildsCLI() error {
+	if err := validateDiagnosticBase(indexBuildsConfig.diagnosticBaseConfig); err != nil {
+		return err
+	}
+	if indexBuildsConfig.Concurrency < 0 || indexBuildsConfig.StallThreshold < 0 {
+		return fmt.Errorf("--concurrency and --stall-threshold must not be negative")
+	}
+	if indexBuildsConfig.Watch && indexBuildsConfig.Interval <= 0 {
+		return fmt.Errorf("--interval must be positive in watch mode")
+	}
+	return nil
+}
+
+// validateIndexLifecycleCLI 在连接前拒绝不完整或未确认的变更；--dry-run 与 --confirm 必须且只能指定一个。
+func validateIndexLifecycleCLI() error {
+	if indexLifecycleConfig.Namespace == "" || indexLifecycleConfig.IndexName == "" {
+		return fmt.Errorf("--ns and --index are required")
+	}
+	if indexLifecycleConfig.DryRun == indexLifecycleConfig.Confirm {
+		return fmt.Errorf("index visibility changes require exactly one of --dry-run or --confirm")
+	}
+	if indexLifecycleConfig.MaxUsageOps < 0 || indexLifecycleConfig.Concurrency < 0 {
+		return fmt.Errorf("--max-usage-ops and --concurrency must not be negative")
+	}
+	return nil
+}
+
+func indexLifecycleChanged(result *mot.IndexLifecycleResult) bool {
+	for _, index := range result.Indexes {
+		if index.Changed {
+			return true
+		}
+	}
+	return false
+}
+
+// readIndexLifecycleLedger 读取本地 ledger；文件不存在时返回空 ledger。
+func readIndexLifecycleLedger(path string) (mot.IndexLifecycleLedger, error) {
+	var ledger mot.IndexLifecycleLedger
+	if err := readLocalSnapshot(path, "index ledger", &ledger); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return mot.IndexLifecycleLedger{}, nil
+		}
+		return mot.IndexLifecycleLedger{}, err
+	}
+	return ledger, nil
+}
+
+func parseNamespaceRemaps(values []string) ([]mot.NamespaceRemap, error) {
+	remaps := make([]mot.NamespaceRemap, 0, len(values))
+	for _, value := range values {
+		remap, err := mot.ParseNamespaceRemap(value)
+		if err != nil {
+			return nil, err
+		}
+		remaps = append(remaps, remap)
+	}
+	return remaps, nil
+}
+
+func readIndexSnapshot(path string) (mot.IndexSnapshot, error) {
+	var snapshot mot.IndexSnapshot
+	if err := readLocalSnapshot(path, "index", &snapshot); err != nil {
+		return mot.IndexSnapshot{}, err
+	}
+	return snapshot, nil
+}
+
+func initIndex() {
+	for _, command := range []*cobra.Command{indexHideCmd, indexUnhideCmd, indexStatusCmd} {
+		registerDiagnosticFlags(command, &indexLifecycleConfig.diagnosticBaseConfig)
+		command.Flags().StringVar(&indexLifecycleConfig.Ledger, "ledger", defaultIndexLedgerPath, "Local ledger file recording hidden indexes")
+		command.Flags().IntVar(&indexLifecycleConfig.Concurrency, "concurrency", 10, "Maximum number of concurrent member connections")
+	}
+	indexStatusCmd.Flags().StringVar(&indexLifecycleConfig.Namespace, "ns", "", "Filter by namespaces (CSV)")
+	for _, command := range []*cobra.Command{indexHideCmd, indexUnhideCmd} {
+		command.Flags().StringVar(&indexLifecycleConfig.Namespace, "ns", "", "Namespace of the index (<database>.<collection>)")
+		command.Flags().StringVar(&indexLifecycleConfig.IndexName, "index", "", "Index name")
+		command.Flags().BoolVar(&indexLifecycleConfig.DryRun, "dry-run", false, "Only run the safety checks and report the change")
+		command.Flags().BoolVar(&indexLifecycleConfig.Confirm, "confirm", false, "Confirm applying the change")
+	}
+	indexHideCmd.Flags().Int64Var(&indexLifecycleConfig.MaxUsageOps, "max-usage-ops", 0, "Refuse to hide when $indexStats ops summed over all members exceed this value")
+
+	registerDiagnosticFlags(indexBuildsCmd, &indexBuildsConfig.diagnosticBaseConfig)
+	indexBuildsCmd.Flags().StringVar(&indexBuildsConfig.Databases, "database", "", "Filter by database names (CSV)")
+	indexBuildsCmd.Flags().StringVar(&indexBuildsConfig.Namespace, "ns", "", "Filter by namespaces (CSV)")
+	indexBuildsCmd.Flags().IntVar(&indexBuildsConfig.Concurrency, "concurrency", 10, "Maximum number of concurrent member connections")
+	indexBuildsCmd.Flags().BoolVar(&indexBuildsConfig.Watch, "watch", false, "Keep polling until no build remains, estimating rate and remaining time")
+	indexBuildsCmd.Flags().DurationVar(&indexBuildsConfig.Interval, "interval", 10*time.Second, "Polling interval in watch mode")
+	indexBuildsCmd.Flags().DurationVar(&indexBuildsConfig.StallThreshold, "stall-threshold", 5*time.Minute, "Flag a build as stalled after this long without progress in watch mode")
+
+	indexDiffCmd.Flags().StringVar(&indexDiffConfig.Format, "format", "table", "Output format: table|json")
+	indexDiffCmd.Flags().StringArrayVar(&indexDiffConfig.Remaps, "remap", nil, "Map source namespaces before comparing, e.g. 'app_staging.* -> app.*' (repeatable; first match wins)")
+
+	indexCmd.AddCommand(indexHideCmd, indexUnhideCmd, indexStatusCmd, indexBuildsCmd, indexDiffCmd)
+	rootCmd.AddCommand(indexCmd)
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mot"
+)
+
+func TestValidateIndexLifecycleRequiresConfirmation(t *testing.T) {
+	// 场景：hide/unhide 必须指定 namespace 与索引名，并在 --dry-run 与 --confirm 中二选一，均在建立连接前校验。
+	initializeCommandsForTest.Do(initAll)
+	saved := indexLifecycleConfig
+	t.Cleanup(func() { indexLifecycleConfig = saved })
+	tests := []struct {
+		name      string
+		namespace string
+		index     string
+		dryRun    bool
+		confirm   bool
+		maxOps    int64
+		wantErr   bool
+	}{
+		{name: "missing index", namespace: "app.orders", confirm: true, wantErr: true},
+		{name: "unconfirmed", namespace: "app.orders", index: "legacy_1", wantErr: true},
+		{name: "dry-run and confirm", namespace: "app.orders", index: "legacy_1", dryRun: true, confirm: true, wantErr: true},
+		{name: "negative max ops", namespace: "app.orders", index: "legacy_1", confirm: true, maxOps: -1, wantErr: true},
+		{name: "dry-run", namespace: "app.orders", index: "legacy_1", dryRun: true},
+		{name: "confirmed", namespace: "app.orders", index: "legacy_1", confirm: true, maxOps: 10},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			indexLifecycleConfig.Namespace, indexLifecycleConfig.IndexName = test.namespace, test.index
+			indexLifecycleConfig.DryRun, indexLifecycleConfig.Confirm, indexLifecycleConfig.MaxUsageOps = test.dryRun, test.confirm, test.maxOps
+			if err := validateIndexLifecycleCLI(); (err != nil) != test.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+	if flag := indexHideCmd.Flags().Lookup("ledger"); flag == nil || flag.DefValue != defaultIndexLedgerPath {
+		t.Fatalf("--ledger flag = %v", flag)
+	}
+}
+
+func TestValidateIndexBuildsWatchInterval(t *testing.T) {
+	// 场景：--watch 需要正的 --interval，单次快照不检查 interval；负的停滞阈值在连接前拒绝。
+	initializeCommandsForTest.Do(initAll)
+	saved := indexBuildsConfig
+	t.Cleanup(func() { indexBuildsConfig = saved })
+	tests := []struct {
+		name     string
+		watch    bool
+		interval time.Duration
+		stall    time.Duration
+		wantErr  bool
+	}{
+		{name: "snapshot", interval: 0},
+		{name: "watch", watch: true, interval: 10 * time.Second},
+		{name: "watch without interval", watch: true, interval: 0, wantErr: true},
+		{name: "negative stall threshold", stall: -time.Second, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			indexBuildsConfig.Format, indexBuildsConfig.Watch, indexBuildsConfig.Interval, indexBuildsConfig.StallThreshold = "table", test.watch, test.interval, test.stall
+			if err := validateIndexBuildsCLI(); (err != nil) != test.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+	if command, _, err := rootCmd.Find([]string{"index", "builds"}); err != nil || command != indexBuildsCmd || command.Flags().Lookup("watch") == nil {
+		t.Fatalf("index builds command = %v, err = %v", command, err)
+	}
+}
+
+func TestIndexLifecycleLedgerRoundTrip(t *testing.T) {
+	// 测试 ledger 文件不存在时视为空，写入后可完整读回隐藏记录；拒绝原因只含 reason code，可原样输出。
+	path := t.TempDir() + "/ledger.json"
+	ledger, err := readIndexLifecycleLedger(path)
+	if err != nil || len(ledger.Entries) != 0 {
+		t.Fatalf("missing ledger = %#v, %v", ledger, err)
+	}
+	ledger.Record(&mot.IndexLifecycleResult{ClusterIdentity: mot.CapacityIdentity{Digest: "c1"}, Action: mot.IndexLifecycleHide, CheckedAt: time.Unix(100, 0).UTC(),
+		Indexes: []mot.IndexLifecycleState{{Namespace: "app.orders", IndexName: "legacy_1", Hidden: true, Changed: true}}})
+	if err := writeLocalSnapshot(path, "index-ledger", ledger); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := readIndexLifecycleLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Entries) != 1 || !loaded.Entries[0].Hidden || loaded.Entries[0].HiddenAt == nil || loaded.Entries[0].ClusterDigest != "c1" {
+		t.Fatalf("loaded ledger = %#v", loaded)
+	}
+	refusal := fmt.Errorf("%w: index hide refused (index_in_use)", mot.ErrDangerousOperation)
+	if got := safeDiagnosticCommandError(refusal); !errors.Is(got, mot.ErrDangerousOperation) || got.Error() != refusal.Error() {
+		t.Fatalf("refusal error = %v", got)
+	}
+}
+
+func TestIndexSnapshotRoundTripAndRemapParsing(t *testing.T) {
+	// 测试索引快照写入后可完整读回供 index diff 使用；非法映射规则在读取快照前失败。
+	initializeCommandsForTest.Do(initAll)
+	if flag := indexAuditCmd.Flags().Lookup("snapshot"); flag == nil || flag.DefValue != "" {
+		t.Fatalf("--snapshot flag = %v", flag)
+	}
+	path := t.TempDir() + "/idx.json"
+	snapshot := mot.IndexSnapshot{SchemaVersion: 1, ClusterType: mot.ClusterReplicaSet, CollectedAt: time.Unix(100, 0).UTC(), Collections: []mot.IndexSnapshotCollection{
+		{Namespace: "app.orders", Indexes: []mot.IndexSnapshotDefinition{{Name: "tenant_1", Key: []mot.IndexKeyField{{Field: "tenant", Order: "1"}}, FieldFingerprints: map[string]string{"key": "k"}}}},
+	}}
+	if err := writeLocalSnapshot(path, "index", snapshot); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := readIndexSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := mot.DiffIndexSnapshots(loaded, snapshot, mot.IndexDiffOptions{})
+	if err != nil || result.Identical != 1 || len(result.Items) != 0 {
+		t.Fatalf("diff of identical snapshots = %#v, %v", result, err)
+	}
+	if _, err := parseNamespaceRemaps([]string{"app_staging.* -> app.*", "broken"}); !errors.Is(err, mot.ErrInvalidOptions) {
+		t.Fatalf("parseNamespaceRemaps() error = %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mot"
+	"github.com/SisyphusSQ/mongo-overview-tool/v2/vars"
+)
+
+var profilerConfig struct {
+	diagnosticBaseConfig
+	Databases       string
+	AllDatabases    bool
+	IncludeSystemDB bool
+	Level           int
+	SlowMillis      int64
+	SampleRate      float64
+	DryRun          bool
+	Confirm         bool
+	Concurrency     int
+}
+
+var profilerCmd = &cobra.Command{
+	Use:   "profiler",
+	Short: "Inspect or change the database profiler on every replica set member",
+}
+
+var profilerStatusCmd = &cobra.Command{
+	Use:     "status",
+	Short:   "Show profiling level, slowms, sampleRate and filter for each database on every member",
+	Example: fmt.Sprintf("%s profiler status --uri <mongodbUri> --database app\n", vars.AppName),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if err := validateDiagnosticBase(profilerConfig.diagnosticBaseConfig); err != nil {
+			return err
+		}
+		if profilerConfig.Concurrency < 0 {
+			return fmt.Errorf("--concurrency must not be negative")
+		}
+		ctx, cancel := diagnosticContext(cmd.Context(), profilerConfig.Timeout)
+		defer cancel()
+		client, err := diagnosticClient(ctx, &profilerConfig.BaseCfg)
+		if err != nil {
+			return err
+		}
+		defer closeSDKClient(client)
+		result, operationErr := client.ProfilerStatus(ctx, profilerOptions())
+		if result == nil {
+			return safeDiagnosticCommandError(operationErr)
+		}
+		return printDiagnosticAndError(cmd, result, profilerConfig.Format, operationErr)
+	},
+}
+
+var profilerEnableCmd = &cobra.Command{
+	Use:     "enable",
+	Short:   "Enable the profiler on every replica set member or shard; requires --confirm or --dry-run",
+	Example: fmt.Sprintf("%s profiler enable --uri <mongodbUri> --database app --slowms 100 --dry-run\n", vars.AppName),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if profilerConfig.Level != 1 && profilerConfig.Level != 2 {
+			return fmt.Errorf("--level must be 1 or 2")
+		}
+		return runProfilerChange(cmd, profilerConfig.Level)
+	},
+}
+
+var profilerDisableCmd = &cobra.Command{
+	Use:     "disable",
+	Short:   "Disable the profiler on every replica set member or shard; requires --confirm or --dry-run",
+	Example: fmt.Sprintf("%s profiler disable --uri <mongodbUri> --all-databases --confirm\n", vars.AppName),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runProfilerChange(cmd, 0)
+	},
+}
+
+func runProfilerChange(cmd *cobra.Command, level int) error {
+	if err := validateDiagnosticBase(profilerConfig.diagnosticBaseConfig); err != nil {
+		return err
+	}
+	if err := validateProfilerChangeCLI(cmd); err != nil {
+		return err
+	}
+	opts := mot.ProfilerChangeOptions{ProfilerOptions: profilerOptions(), AllDatabases: profilerConfig.AllDatabases, Level: level, DryRun: profilerConfig.DryRun}
+	if cmd.Flags().Changed("slowms") {
+		opts.SlowMillis = &profilerConfig.SlowMillis
+	}
+	if cmd.Flags().Changed("sample-rate") {
+		opts.SampleRate = &profilerConfig.SampleRate
+	}
+	ctx, cancel := diagnosticContext(cmd.Context(), profilerConfig.Timeout)
+	defer cancel()
+	client, err := diagnosticClient(ctx, &profilerConfig.BaseCfg)
+	if err != nil {
+		return err
+	}
+	defer closeSDKClient(client)
+	result, operationErr := client.SetProfiler(ctx, opts)
+	if result == nil {
+		return safeDiagnosticCommandError(operationErr)
+	}
+	return printDiagnosticAndError(cmd, result, profilerConfig.Format, operationErr)
+}
+
+// validateProfilerChangeCLI 在连接前拒绝未确认的变更；--dry-run 与 --confirm 必须且只能指定一个。
+func validateProfilerChangeCLI(cmd *cobra.Command) error {
+	if err := validateIndexAuditSelection(profilerConfig.AllDatabases, profilerConfig.Databases); err != nil {
+		return err
+	}
+	if profilerConfig.DryRun == profilerConfig.Confirm {
+		return fmt.Errorf("profiler changes require exactly one of --dry-run or --confirm")
+	}
+	if profilerConfig.Concurrency < 0 {
+		return fmt.Errorf("--concurrency must not be negative")
+	}
+	if cmd.Flags().Changed("slowms") && profilerConfig.SlowMillis < 0 {
+		return fmt.Errorf("--slowms must not be negative")
+	}
+	if cmd.Flags().Changed("sample-rate") && (profilerConfig.SampleRate <= 0 || profilerConfig.SampleRate > 1) {
+		return fmt.Errorf("--sample-rate must be in (0, 1]")
+	}
+	return nil
+}
+
+func profilerOptions() mot.ProfilerOptions {
+	return mot.ProfilerOptions{Databases: splitCSV(profilerConfig.Databases), IncludeSystemDB: profilerConfig.IncludeSystemDB, NodeConcurrency: profilerConfig.Concurrency}
+}
+
+func initProfiler() {
+	for _, command := range []*cobra.Command{profilerStatusCmd, profilerEnableCmd, profilerDisableCmd} {
+		registerDiagnosticFlags(command, &profilerConfig.diagnosticBaseConfig)
+		command.Flags().StringVar(&profilerConfig.Databases, "database", "", "Select databases (CSV)")
+		command.Flags().BoolVar(&profilerConfig.IncludeSystemDB, "include-system-db", false, "Include system databases when no database is selected")
+		command.Flags().IntVar(&profilerConfig.Concurrency, "concurrency", 10, "Maximum number of concurrent member connections")
+	}
+	for _, command := range []*cobra.Command{profilerEnableCmd, profilerDisableCmd} {
+		command.Flags().BoolVar(&profilerConfig.AllDatabases, "all-databases", false, "Apply to all non-system databases on every member; mutually exclusive with --database")
+		command.Flags().BoolVar(&profilerConfig.DryRun, "dry-run", false, "Only report the change each member would receive")
+		command.Flags().BoolVar(&profilerConfig.Confirm, "confirm", false, "Confirm applying the change on every member")
+	}
+	profilerEnableCmd.Flags().IntVar(&profilerConfig.Level, "level", 1, "Profiling level: 1 records slow operations, 2 records all operations")
+	profilerEnableCmd.Flags().Int64Var(&profilerConfig.SlowMillis, "slowms", 100, "Slow operation threshold in milliseconds; process-wide, only applied when set")
+	profilerEnableCmd.Flags().Float64Var(&profilerConfig.SampleRate, "sample-rate", 1, "Fraction of slow operations to profile (3.6+); process-wide, only applied when set")
+
+	profilerCmd.AddCommand(profilerStatusCmd, profilerEnableCmd, profilerDisableCmd)
+	rootCmd.AddCommand(profilerCmd)
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestValidateProfilerChangeRequiresConfirmation(t *testing.T) {
+	// 场景：profiler 变更必须在 --dry-run 与 --confirm 中二选一，database 选择严格互斥，均在建立连接前校验。
+	initializeCommandsForTest.Do(initAll)
+	saved := profilerConfig
+	t.Cleanup(func() { profilerConfig = saved })
+	tests := []struct {
+		name         string
+		databases    string
+		allDatabases bool
+		dryRun       bool
+		confirm      bool
+		wantErr      bool
+	}{
+		{name: "unconfirmed", databases: "app", wantErr: true},
+		{name: "dry-run and confirm", databases: "app", dryRun: true, confirm: true, wantErr: true},
+		{name: "no database", confirm: true, wantErr: true},
+		{name: "database and all-databases", databases: "app", allDatabases: true, confirm: true, wantErr: true},
+		{name: "dry-run", databases: "app", dryRun: true},
+		{name: "confirmed all databases", allDatabases: true, confirm: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			profilerConfig.Databases, profilerConfig.AllDatabases = test.databases, test.allDatabases
+			profilerConfig.DryRun, profilerConfig.Confirm = test.dryRun, test.confirm
+			if err := validateProfilerChangeCLI(profilerDisableCmd); (err != nil) != test.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
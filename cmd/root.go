@@ -49,6 +49,8 @@ func initAll() {
 	initBulkDelete()
 	initBulkUpdate()
 	initDiagnostics()
+	initProfiler()
+	initIndex()
 }
 
 func Execute() {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -18,35 +19,59 @@ import (
 )
 
 var slowlogCfg config.SlowlogConfig
+var slowlogSortFields = []string{"cnt", "maxMills", "maxDocs", "p95", "totalMillis"}
 var slowlogFormat string
 
 var slowlogCmd = &cobra.Command{
 	Use:     "slowlog",
 	Short:   "Get MongoDB slow log",
 	Long:    `Get MongoDB slow log`,
-	Example: fmt.Sprintf("%s slowlog --uri <mongodbUri>\n", vars.AppName),
+	Example: fmt.Sprintf("%s slowlog --uri <mongodbUri>\n%s slowlog --from-log mongod.log,mongod.log.1.gz\n", vars.AppName, vars.AppName),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		start := time.Now()
+		if slowlogCfg.FromLog != "" {
+			return runSlowlogFromLog(cmd, start)
+		}
 		if err := config.BasePreCheck(&slowlogCfg.BaseCfg); err != nil {
 			return err
 		}
 
-		if !slices.Contains([]string{"cnt", "maxMills", "maxDocs"}, slowlogCfg.Sort) {
-			return fmt.Errorf("invalid sort field: %s, expect: cnt, maxMills, maxDocs", slowlogCfg.Sort)
+		if !slices.Contains(slowlogSortFields, slowlogCfg.Sort) {
+			return fmt.Errorf("invalid sort field: %s, expect: cnt, maxMills, maxDocs, p95, totalMillis", slowlogCfg.Sort)
+		}
+		if err := validateSlowlogFormat(slowlogCfg, slowlogFormat); err != nil {
+			return err
 		}
-		if err := clioutput.ValidateFormat(slowlogFormat); err != nil {
+		if err := validateSlowlogMerge(); err != nil {
 			return err
 		}
 
+		if err := validateSlowlogExplain(slowlogCfg); err != nil {
+			return err
+		}
+		if err := validateSlowlogPlanCache(slowlogCfg); err != nil {
+			return err
+		}
+		if err := validateSlowlogSnapshot(slowlogCfg); err != nil {
+			return err
+		}
+		if err := validateSlowlogHashFilters(slowlogCfg); err != nil {
+			return err
+		}
 		if slowlogCfg.QueryHash == "" {
 			slowlogCfg.Overview = true
-		} else {
+		} else if !slowlogCfg.PlanCache {
 			if slowlogFormat == clioutput.FormatJSON {
 				return fmt.Errorf("slowlog detail raw output does not support JSON format")
 			}
 			slowlogCfg.Detail = true
 		}
 
+		filter, err := slowlogFilterOptions(slowlogCfg.SlowlogFilterConfig, time.Now())
+		if err != nil {
+			return err
+		}
+
 		ctx := context.Background()
 		client, err := mot.NewClient(ctx, sdkOptionsFromBase(&slowlogCfg.BaseCfg))
 		if err != nil {
@@ -55,47 +80,445 @@ var slowlogCmd = &cobra.Command{
 		}
 		defer closeSDKClient(client)
 
-		if slowlogCfg.Overview {
-			result, operationErr := client.SlowlogSummary(ctx, mot.SlowlogOptions{
-				Databases: splitCSV(slowlogCfg.DB),
-				Sort:      mot.SlowlogSort(slowlogCfg.Sort),
-			})
-			var printErr error
-			if result != nil && slowlogFormat == clioutput.FormatJSON {
-				printErr = clioutput.PrintDiagnosticResult(os.Stdout, result, slowlogFormat)
-			} else if result != nil {
-				printErr = clioutput.PrintSlowlogSummary(os.Stdout, result, clioutput.SlowlogPrintOptions{URI: slowlogCfg.BuildUri})
+		if slowlogCfg.Overview && slowlogFormat == clioutput.FormatDigest {
+			filter.Databases = splitCSV(slowlogCfg.DB)
+			filter.GetLog = slowlogCfg.GetLog
+			result, operationErr := client.SlowlogDigest(ctx, slowlogDigestOptions(cmd, filter))
+			if err := printSlowlogDigest(result, operationErr); err != nil {
+				return err
 			}
-			if printErr != nil {
-				return printErr
+		} else if slowlogCfg.Overview {
+			filter.Databases = splitCSV(slowlogCfg.DB)
+			filter.Sort = mot.SlowlogSort(slowlogCfg.Sort)
+			filter.GetLog = slowlogCfg.GetLog
+			result, operationErr := client.SlowlogSummary(ctx, filter)
+			if err := writeSlowlogSnapshot(result, filter); err != nil {
+				return err
+			}
+			if err := printSlowlogSummary(result, operationErr, slowlogCfg.BuildUri); err != nil {
+				return err
+			}
+		} else if slowlogCfg.PlanCache {
+			filter.Databases = splitCSV(slowlogCfg.DB)
+			result, operationErr := client.PlanCache(ctx, mot.PlanCacheOptions{SlowlogOptions: filter, QueryHash: slowlogCfg.QueryHash})
+			if result == nil {
+				l.Logger.Errorf("PlanCache failed; detail suppressed")
+				return safeDiagnosticCommandError(operationErr)
+			}
+			if err = clioutput.PrintDiagnosticResult(os.Stdout, result, slowlogFormat); err != nil {
+				return err
 			}
 			if operationErr != nil {
-				l.Logger.Errorf("SlowlogSummary failed; detail suppressed")
+				l.Logger.Errorf("PlanCache failed; detail suppressed")
 				return safeDiagnosticCommandError(operationErr)
 			}
 		} else {
-			result, err := client.SlowlogDetail(ctx, slowlogCfg.DB, slowlogCfg.QueryHash)
-			if err != nil {
+			result, operationErr := client.SlowlogDetailWithOptions(ctx, slowlogCfg.DB, slowlogCfg.QueryHash, slowlogDetailOptions())
+			if result == nil {
 				l.Logger.Errorf("SlowlogDetail failed; detail suppressed")
-				return safeDiagnosticCommandError(err)
+				return safeDiagnosticCommandError(operationErr)
 			}
 			if err = clioutput.PrintSlowlogDetail(os.Stdout, result, clioutput.SlowlogPrintOptions{URI: slowlogCfg.BuildUri}); err != nil {
 				l.Logger.Errorf("PrintSlowlogDetail failed; detail suppressed")
 				return safeDiagnosticCommandError(err)
 			}
+			if operationErr != nil {
+				l.Logger.Errorf("SlowlogDetail explain failed; detail suppressed")
+				return safeDiagnosticCommandError(operationErr)
+			}
 		}
 		utils.PrintCost(start)
 		return nil
 	},
 }
 
+var slowlogDiffConfig struct {
+	Format       string
+	MinCount     int64
+	CountRatio   float64
+	LatencyRatio float64
+	DocsRatio    float64
+}
+
+var slowlogDiffCmd = &cobra.Command{
+	Use:     "diff <before.json> <after.json>",
+	Short:   "Compare two slowlog snapshots offline and report new, disappeared and regressed query shapes",
+	Example: fmt.Sprintf("%s slowlog diff ./slowlog-before.json ./slowlog-after.json --latency-ratio 2\n", vars.AppName),
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := clioutput.ValidateFormat(slowlogDiffConfig.Format); err != nil {
+			return err
+		}
+		before, err := readSlowlogSnapshot(args[0])
+		if err != nil {
+			return err
+		}
+		after, err := readSlowlogSnapshot(args[1])
+		if err != nil {
+			return err
+		}
+		result, err := mot.DiffSlowlog(before, after, mot.SlowlogDiffOptions{
+			MinCount:     slowlogDiffConfig.MinCount,
+			CountRatio:   slowlogDiffConfig.CountRatio,
+			LatencyRatio: slowlogDiffConfig.LatencyRatio,
+			DocsRatio:    slowlogDiffConfig.DocsRatio,
+		})
+		if err != nil {
+			return err
+		}
+		return clioutput.PrintDiagnosticResult(cmd.OutOrStdout(), result, slowlogDiffConfig.Format)
+	},
+}
+
+var slowlogAdviseConfig struct {
+	diagnosticBaseConfig
+	config.SlowlogFilterConfig
+	Databases         string
+	MaxCandidates     int
+	DocsExaminedRatio float64
+}
+
+var slowlogAdviseCmd = &cobra.Command{
+	Use:     "advise",
+	Short:   "Suggest indexes for slow COLLSCAN and in-memory SORT query shapes using the Equality-Sort-Range rule",
+	Example: fmt.Sprintf("%s slowlog advise --uri <mongodbUri> --db app --since 24h\n", vars.AppName),
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if err := validateDiagnosticBase(slowlogAdviseConfig.diagnosticBaseConfig); err != nil {
+			return err
+		}
+		if slowlogAdviseConfig.MaxCandidates < 0 {
+			return fmt.Errorf("--max-candidates must not be negative")
+		}
+		if slowlogAdviseConfig.DocsExaminedRatio < 0 {
+			return fmt.Errorf("--docs-examined-ratio must not be negative")
+		}
+		filter, err := slowlogFilterOptions(slowlogAdviseConfig.SlowlogFilterConfig, time.Now())
+		if err != nil {
+			return err
+		}
+		filter.Databases = splitCSV(slowlogAdviseConfig.Databases)
+		filter.Sort = mot.SlowlogSortTotal
+
+		ctx, cancel := diagnosticContext(cmd.Context(), slowlogAdviseConfig.Timeout)
+		defer cancel()
+		client, err := diagnosticClient(ctx, &slowlogAdviseConfig.BaseCfg)
+		if err != nil {
+			return err
+		}
+		defer closeSDKClient(client)
+		result, operationErr := client.SlowlogAdvise(ctx, mot.SlowlogAdviseOptions{SlowlogOptions: filter, MaxCandidates: slowlogAdviseConfig.MaxCandidates, DocsExaminedRatio: slowlogAdviseConfig.DocsExaminedRatio})
+		if result == nil {
+			return safeDiagnosticCommandError(operationErr)
+		}
+		return printDiagnosticAndError(cmd, result, slowlogAdviseConfig.Format, operationErr)
+	},
+}
+
+func validateSlowlogMerge() error {
+	if slowlogCfg.Merge != "" && slowlogCfg.Merge != string(mot.SlowlogMergeCluster) {
+		return fmt.Errorf("invalid merge mode: %s, expect: cluster", slowlogCfg.Merge)
+	}
+	if slowlogCfg.Merge != "" && slowlogCfg.QueryHash != "" {
+		return fmt.Errorf("--merge does not support --hash detail view")
+	}
+	return nil
+}
+
+func validateSlowlogExplain(cfg config.SlowlogConfig) error {
+	if cfg.Explain && cfg.QueryHash == "" {
+		return fmt.Errorf("--explain requires --hash")
+	}
+	if cfg.ExecutionStats && !cfg.Explain {
+		return fmt.Errorf("--execution-stats requires --explain")
+	}
+	return nil
+}
+
+func validateSlowlogPlanCache(cfg config.SlowlogConfig) error {
+	if cfg.PlanCache && cfg.QueryHash == "" {
+		return fmt.Errorf("--plan-cache requires --hash")
+	}
+	if cfg.PlanCache && cfg.Explain {
+		return fmt.Errorf("--plan-cache cannot be combined with --explain")
+	}
+	return nil
+}
+
+// validateSlowlogFormat 在 table/json 之外接受 digest；digest 只用于概览，且不与快照或合并视图组合。
+func validateSlowlogFormat(cfg config.SlowlogConfig, format string) error {
+	if format != clioutput.FormatDigest {
+		return clioutput.ValidateFormat(format)
+	}
+	if cfg.QueryHash != "" {
+		return fmt.Errorf("--format digest only applies to the slowlog summary, not --hash")
+	}
+	if cfg.Snapshot != "" {
+		return fmt.Errorf("--format digest cannot be combined with --snapshot")
+	}
+	if cfg.Merge != "" {
+		return fmt.Errorf("--format digest already merges query shapes across the cluster; drop --merge")
+	}
+	if cfg.MaxQueries < 0 {
+		return fmt.Errorf("--max-queries must not be negative")
+	}
+	return nil
+}
+
+// slowlogDigestOptions 默认按累计耗时排名；显式指定 --sort 时沿用该排序。
+func slowlogDigestOptions(cmd *cobra.Command, filter mot.SlowlogOptions) mot.SlowlogDigestOptions {
+	filter.Sort = mot.SlowlogSortTotal
+	if cmd.Flags().Changed("sort") {
+		filter.Sort = mot.SlowlogSort(slowlogCfg.Sort)
+	}
+	return mot.SlowlogDigestOptions{SlowlogOptions: filter, MaxQueries: slowlogCfg.MaxQueries}
+}
+
+func printSlowlogDigest(result *mot.SlowlogDigestResult, operationErr error) error {
+	if result == nil {
+		l.Logger.Errorf("SlowlogDigest failed; detail suppressed")
+		return safeDiagnosticCommandError(operationErr)
+	}
+	if err := clioutput.PrintSlowlogDigest(os.Stdout, result); err != nil {
+		return err
+	}
+	if operationErr != nil {
+		l.Logger.Errorf("SlowlogDigest failed; detail suppressed")
+		return safeDiagnosticCommandError(operationErr)
+	}
+	return nil
+}
+
+// validateSlowlogHashFilters 拒绝 --hash 模式下不会生效的过滤 flag：详情只读取该形状的最新 profile 样本，
+// plan cache 只按 --ns 缩小 namespace 范围。
+func validateSlowlogHashFilters(cfg config.SlowlogConfig) error {
+	if cfg.QueryHash == "" {
+		return nil
+	}
+	var ignored []string
+	for _, filter := range []struct {
+		flag string
+		set  bool
+	}{
+		{"--since", cfg.Since != ""},
+		{"--until", cfg.Until != ""},
+		{"--ns", cfg.Namespaces != "" && !cfg.PlanCache},
+		{"--op", cfg.Operations != ""},
+		{"--app", cfg.AppNames != ""},
+		{"--min-millis", cfg.MinMillis != 0},
+	} {
+		if filter.set {
+			ignored = append(ignored, filter.flag)
+		}
+	}
+	if len(ignored) == 0 {
+		return nil
+	}
+	if cfg.PlanCache {
+		return fmt.Errorf("%s cannot be combined with --hash --plan-cache; only --ns narrows plan cache namespaces", strings.Join(ignored, ", "))
+	}
+	return fmt.Errorf("%s only apply to the slowlog summary, not --hash", strings.Join(ignored, ", "))
+}
+
+func validateSlowlogSnapshot(cfg config.SlowlogConfig) error {
+	if cfg.Snapshot != "" && cfg.QueryHash != "" {
+		return fmt.Errorf("--snapshot only applies to the slowlog summary, not --hash")
+	}
+	return nil
+}
+
+// writeSlowlogSnapshot 在概览结果可用时写出本地快照；部分结果同样写出，collector 状态随快照保留。
+func writeSlowlogSnapshot(result *mot.SlowlogSummaryResult, filter mot.SlowlogOptions) error {
+	if result == nil || slowlogCfg.Snapshot == "" {
+		return nil
+	}
+	return writeLocalSnapshot(slowlogCfg.Snapshot, "slowlog", mot.NewSlowlogSnapshot(result, filter, time.Now()))
+}
+
+func readSlowlogSnapshot(path string) (mot.SlowlogSnapshot, error) {
+	var snapshot mot.SlowlogSnapshot
+	if err := readLocalSnapshot(path, "slowlog", &snapshot); err != nil {
+		return mot.SlowlogSnapshot{}, err
+	}
+	return snapshot, nil
+}
+
+func slowlogDetailOptions() mot.SlowlogDetailOptions {
+	switch {
+	case slowlogCfg.ExecutionStats:
+		return mot.SlowlogDetailOptions{Explain: mot.ExplainExecutionStats}
+	case slowlogCfg.Explain:
+		return mot.SlowlogDetailOptions{Explain: mot.ExplainQueryPlanner}
+	default:
+		return mot.SlowlogDetailOptions{}
+	}
+}
+
+// runSlowlogFromLog 离线解析 --from-log 指定的日志文件，复用 summary 输出路径。
+func runSlowlogFromLog(cmd *cobra.Command, start time.Time) error {
+	l.New(slowlogCfg.Debug)
+	if slowlogCfg.QueryHash != "" {
+		return fmt.Errorf("--from-log does not support --hash detail view")
+	}
+	if err := validateSlowlogExplain(slowlogCfg); err != nil {
+		return err
+	}
+	if err := validateSlowlogPlanCache(slowlogCfg); err != nil {
+		return err
+	}
+	if !slices.Contains(slowlogSortFields, slowlogCfg.Sort) {
+		return fmt.Errorf("invalid sort field: %s, expect: cnt, maxMills, maxDocs, p95, totalMillis", slowlogCfg.Sort)
+	}
+	if err := validateSlowlogFormat(slowlogCfg, slowlogFormat); err != nil {
+		return err
+	}
+	if err := validateSlowlogMerge(); err != nil {
+		return err
+	}
+	filter, err := slowlogFilterOptions(slowlogCfg.SlowlogFilterConfig, time.Now())
+	if err != nil {
+		return err
+	}
+	result, operationErr := mot.SlowlogSummaryFromLogs(context.Background(), mot.SlowlogLogOptions{
+		Paths:      splitCSV(slowlogCfg.FromLog),
+		Databases:  splitCSV(slowlogCfg.DB),
+		Sort:       mot.SlowlogSort(slowlogCfg.Sort),
+		Since:      filter.Since,
+		Until:      filter.Until,
+		Namespaces: filter.Namespaces,
+		Operations: filter.Operations,
+		AppNames:   filter.AppNames,
+		MinMillis:  filter.MinMillis,
+	})
+	if err := writeSlowlogSnapshot(result, filter); err != nil {
+		return err
+	}
+	if slowlogFormat == clioutput.FormatDigest {
+		var digest *mot.SlowlogDigestResult
+		if result != nil {
+			if digest, err = mot.BuildSlowlogDigest(result, slowlogDigestOptions(cmd, filter)); err != nil {
+				return err
+			}
+		}
+		if err := printSlowlogDigest(digest, operationErr); err != nil {
+			return err
+		}
+		utils.PrintCost(start)
+		return nil
+	}
+	if err := printSlowlogSummary(result, operationErr, ""); err != nil {
+		return err
+	}
+	utils.PrintCost(start)
+	return nil
+}
+
+// slowlogFilterOptions 把过滤 flag 转换为 SDK 选项；时间支持相对时长（如 1h）或 RFC3339。
+func slowlogFilterOptions(cfg config.SlowlogFilterConfig, now time.Time) (mot.SlowlogOptions, error) {
+	opts := mot.SlowlogOptions{
+		Namespaces: splitCSV(cfg.Namespaces),
+		Operations: splitCSV(cfg.Operations),
+		AppNames:   splitCSV(cfg.AppNames),
+		MinMillis:  cfg.MinMillis,
+	}
+	var err error
+	if opts.Since, err = parseSlowlogTimeBound("--since", cfg.Since, now); err != nil {
+		return mot.SlowlogOptions{}, err
+	}
+	if opts.Until, err = parseSlowlogTimeBound("--until", cfg.Until, now); err != nil {
+		return mot.SlowlogOptions{}, err
+	}
+	if opts.MinMillis < 0 {
+		return mot.SlowlogOptions{}, fmt.Errorf("--min-millis must not be negative")
+	}
+	if !opts.Since.IsZero() && !opts.Until.IsZero() && !opts.Until.After(opts.Since) {
+		return mot.SlowlogOptions{}, fmt.Errorf("--until must be after --since")
+	}
+	return opts, nil
+}
+
+func parseSlowlogTimeBound(flag, value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		if duration < 0 {
+			return time.Time{}, fmt.Errorf("%s duration must not be negative", flag)
+		}
+		return now.Add(-duration).UTC(), nil
+	}
+	bound, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: expect duration like 1h or RFC3339 time", flag)
+	}
+	return bound.UTC(), nil
+}
+
+func printSlowlogSummary(result *mot.SlowlogSummaryResult, operationErr error, uri string) error {
+	var printErr error
+	if result != nil && slowlogCfg.Merge == string(mot.SlowlogMergeCluster) {
+		merged, mergeErr := mot.MergeSlowlogSummary(result, mot.SlowlogSort(slowlogCfg.Sort))
+		if mergeErr != nil {
+			return mergeErr
+		}
+		if slowlogFormat == clioutput.FormatJSON {
+			printErr = clioutput.PrintDiagnosticResult(os.Stdout, merged, slowlogFormat)
+		} else {
+			printErr = clioutput.PrintMergedSlowlogSummary(os.Stdout, merged, clioutput.SlowlogPrintOptions{URI: uri})
+		}
+	} else if result != nil && slowlogFormat == clioutput.FormatJSON {
+		printErr = clioutput.PrintDiagnosticResult(os.Stdout, result, slowlogFormat)
+	} else if result != nil {
+		printErr = clioutput.PrintSlowlogSummary(os.Stdout, result, clioutput.SlowlogPrintOptions{URI: uri})
+	}
+	if printErr != nil {
+		return printErr
+	}
+	if operationErr != nil {
+		l.Logger.Errorf("SlowlogSummary failed; detail suppressed")
+		return safeDiagnosticCommandError(operationErr)
+	}
+	return nil
+}
+
 func initSlowlogCmd() {
 	registerBaseFlags(slowlogCmd, &slowlogCfg.BaseCfg)
 
 	slowlogCmd.Flags().StringVar(&slowlogCfg.QueryHash, "hash", "", "Query hash to filter slow log")
-	slowlogCmd.Flags().StringVar(&slowlogCfg.Sort, "sort", "cnt", "Sort field, default by cnt desc, list: cnt, maxMills, maxDocs")
+	slowlogCmd.Flags().StringVar(&slowlogCfg.Sort, "sort", "cnt", "Sort field, default by cnt desc, list: cnt, maxMills, maxDocs, p95, totalMillis")
 	slowlogCmd.Flags().StringVar(&slowlogCfg.DB, "db", "", "Database where slowlog in")
-	slowlogCmd.Flags().StringVar(&slowlogFormat, "format", "table", "Output format for slowlog summary: table|json")
+	slowlogCmd.Flags().StringVar(&slowlogCfg.FromLog, "from-log", "", "Comma-separated mongod log files (.gz supported) to summarize offline without connecting")
+	slowlogCmd.Flags().BoolVar(&slowlogCfg.GetLog, "getlog", false, "Also read recent slow operations from each member's in-memory getLog buffer for databases without profiler data")
+	slowlogCmd.Flags().BoolVar(&slowlogCfg.Explain, "explain", false, "With --hash, re-run the captured command shape with explain queryPlanner on the same node")
+	slowlogCmd.Flags().BoolVar(&slowlogCfg.ExecutionStats, "execution-stats", false, "With --explain, use executionStats verbosity; this executes the query")
+	slowlogCmd.Flags().BoolVar(&slowlogCfg.PlanCache, "plan-cache", false, "With --hash, inspect cached plans for the query shape on every data-bearing node via $planCacheStats (4.2+)")
+	registerSlowlogFilterFlags(slowlogCmd, &slowlogCfg.SlowlogFilterConfig)
+	slowlogCmd.Flags().StringVar(&slowlogCfg.Merge, "merge", "", "Merge the same query shape across hosts and shards: cluster")
+	slowlogCmd.Flags().StringVar(&slowlogFormat, "format", "table", "Output format for slowlog summary and plan cache: table|json; the summary also accepts digest")
+	slowlogCmd.Flags().IntVar(&slowlogCfg.MaxQueries, "max-queries", 20, "Maximum ranked query shapes in the --format digest report")
+	slowlogCmd.Flags().StringVar(&slowlogCfg.Snapshot, "snapshot", "", "Write the redacted summary as a JSON snapshot to a local path for slowlog diff")
+
+	registerDiagnosticFlags(slowlogAdviseCmd, &slowlogAdviseConfig.diagnosticBaseConfig)
+	slowlogAdviseCmd.Flags().StringVar(&slowlogAdviseConfig.Databases, "db", "", "Comma-separated databases whose profiler data is analyzed")
+	registerSlowlogFilterFlags(slowlogAdviseCmd, &slowlogAdviseConfig.SlowlogFilterConfig)
+	slowlogAdviseCmd.Flags().IntVar(&slowlogAdviseConfig.MaxCandidates, "max-candidates", 20, "Maximum problematic query shapes to analyze, ordered by total time")
+	slowlogAdviseCmd.Flags().Float64Var(&slowlogAdviseConfig.DocsExaminedRatio, "docs-examined-ratio", 100, "Treat a query shape as a candidate when docsExamined/nreturned reaches this ratio")
 
+	slowlogDiffCmd.Flags().StringVar(&slowlogDiffConfig.Format, "format", "table", "Output format: table|json")
+	slowlogDiffCmd.Flags().Int64Var(&slowlogDiffConfig.MinCount, "min-count", 5, "Ignore query shapes with fewer operations than this in the after snapshot")
+	slowlogDiffCmd.Flags().Float64Var(&slowlogDiffConfig.CountRatio, "count-ratio", 2, "Report a regression when the operation count grows by at least this factor")
+	slowlogDiffCmd.Flags().Float64Var(&slowlogDiffConfig.LatencyRatio, "latency-ratio", 1.5, "Report a regression when avg or p95 latency grows by at least this factor")
+	slowlogDiffCmd.Flags().Float64Var(&slowlogDiffConfig.DocsRatio, "docs-ratio", 2, "Report a regression when max docsExamined grows by at least this factor")
+
+	slowlogCmd.AddCommand(slowlogAdviseCmd, slowlogDiffCmd)
 	rootCmd.AddCommand(slowlogCmd)
 }
+
+// registerSlowlogFilterFlags 为 summary 与 advise 注册相同的时间窗和维度过滤 flag，取值绑定到各命令自己的 cfg。
+func registerSlowlogFilterFlags(command *cobra.Command, cfg *config.SlowlogFilterConfig) {
+	command.Flags().StringVar(&cfg.Since, "since", "", "Only aggregate operations after this time, duration ago (e.g. 1h) or RFC3339")
+	command.Flags().StringVar(&cfg.Until, "until", "", "Only aggregate operations before this time, duration ago (e.g. 10m) or RFC3339")
+	command.Flags().StringVar(&cfg.Namespaces, "ns", "", "Comma-separated namespaces (db.collection) to aggregate")
+	command.Flags().StringVar(&cfg.Operations, "op", "", "Comma-separated profiler operations: command, getmore, insert, query, remove, update")
+	command.Flags().StringVar(&cfg.AppNames, "app", "", "Comma-separated client appName values to aggregate")
+	command.Flags().Int64Var(&cfg.MinMillis, "min-millis", 0, "Only aggregate operations taking at least this many milliseconds")
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SisyphusSQ/mongo-overview-tool/v2/internal/config"
+	"github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mot"
+)
+
+func TestParseSlowlogTimeBound(t *testing.T) {
+	// 测试 --since/--until 同时支持相对时长与 RFC3339，非法输入返回错误。
+	now := time.Date(2026, 7, 20, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "empty"},
+		{name: "duration", value: "90m", want: now.Add(-90 * time.Minute)},
+		{name: "rfc3339", value: "2026-07-20T08:00:00+08:00", want: time.Date(2026, 7, 20, 0, 0, 0, 0, time.UTC)},
+		{name: "negative duration", value: "-1h", wantErr: true},
+		{name: "invalid", value: "yesterday", wantErr: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseSlowlogTimeBound("--since", tc.value, now)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !got.Equal(tc.want) {
+				t.Fatalf("bound = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateSlowlogExplainRequiresHash(t *testing.T) {
+	// 测试 --explain 必须指定 --hash，--execution-stats 必须显式叠加 --explain。
+	tests := []struct {
+		name    string
+		cfg     config.SlowlogConfig
+		wantErr bool
+	}{
+		{name: "summary"},
+		{name: "explain without hash", cfg: config.SlowlogConfig{Explain: true}, wantErr: true},
+		{name: "execution stats without explain", cfg: config.SlowlogConfig{QueryHash: "ABCD", ExecutionStats: true}, wantErr: true},
+		{name: "query planner", cfg: config.SlowlogConfig{QueryHash: "ABCD", Explain: true}},
+		{name: "execution stats", cfg: config.SlowlogConfig{QueryHash: "ABCD", Explain: true, ExecutionStats: true}},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if err := validateSlowlogExplain(tc.cfg); (err != nil) != tc.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSlowlogPlanCacheRequiresHash(t *testing.T) {
+	// 测试 --plan-cache 必须指定 --hash，且不能与 --explain 同时使用。
+	if err := validateSlowlogPlanCache(config.SlowlogConfig{PlanCache: true}); err == nil {
+		t.Fatal("--plan-cache without --hash was accepted")
+	}
+	if err := validateSlowlogPlanCache(config.SlowlogConfig{QueryHash: "ABCD", PlanCache: true, Explain: true}); err == nil {
+		t.Fatal("--plan-cache with --explain was accepted")
+	}
+	if err := validateSlowlogPlanCache(config.SlowlogConfig{QueryHash: "ABCD", PlanCache: true}); err != nil {
+		t.Fatalf("validateSlowlogPlanCache() error = %v", err)
+	}
+}
+
+func TestValidateSlowlogHashFiltersRejectsIgnoredFlags(t *testing.T) {
+	// 测试 --hash 详情拒绝所有过滤 flag，plan cache 只接受 --ns，概览模式不受影响。
+	if err := validateSlowlogHashFilters(config.SlowlogConfig{QueryHash: "ABCD", SlowlogFilterConfig: config.SlowlogFilterConfig{Operations: "query", MinMillis: 100}}); err == nil || !strings.Contains(err.Error(), "--op, --min-millis") {
+		t.Fatalf("detail filters error = %v", err)
+	}
+	if err := validateSlowlogHashFilters(config.SlowlogConfig{QueryHash: "ABCD", SlowlogFilterConfig: config.SlowlogFilterConfig{Namespaces: "app.orders"}}); err == nil {
+		t.Fatal("--ns with --hash detail was accepted")
+	}
+	if err := validateSlowlogHashFilters(config.SlowlogConfig{QueryHash: "ABCD", PlanCache: true, SlowlogFilterConfig: config.SlowlogFilterConfig{Namespaces: "app.orders"}}); err != nil {
+		t.Fatalf("plan cache --ns error = %v", err)
+	}
+	if err := validateSlowlogHashFilters(config.SlowlogConfig{QueryHash: "ABCD", PlanCache: true, SlowlogFilterConfig: config.SlowlogFilterConfig{Since: "1h"}}); err == nil {
+		t.Fatal("--since with --plan-cache was accepted")
+	}
+	if err := validateSlowlogHashFilters(config.SlowlogConfig{SlowlogFilterConfig: config.SlowlogFilterConfig{Since: "1h", AppNames: "api"}}); err != nil {
+		t.Fatalf("summary filters error = %v", err)
+	}
+}
+
+func TestSlowlogSnapshotRoundTrip(t *testing.T) {
+	// 测试 --snapshot 以 0600 权限写出带 schema 与时间窗的快照，slowlog diff 可原样读回；--hash 详情不支持快照。
+	if err := validateSlowlogSnapshot(config.SlowlogConfig{QueryHash: "ABCD", Snapshot: "out.json"}); err == nil {
+		t.Fatal("--snapshot with --hash was accepted")
+	}
+	path := t.TempDir() + "/slowlog.json"
+	previous := slowlogCfg.Snapshot
+	slowlogCfg.Snapshot = path
+	t.Cleanup(func() { slowlogCfg.Snapshot = previous })
+
+	since := time.Date(2026, 7, 20, 11, 0, 0, 0, time.UTC)
+	result := &mot.SlowlogSummaryResult{ClusterType: mot.ClusterReplicaSet, ReplicaSets: []mot.ReplicaSetSlowlogSummary{{Name: "rs0"}}}
+	if err := writeSlowlogSnapshot(result, mot.SlowlogOptions{Since: since}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("snapshot mode = %v, want 0600", info.Mode().Perm())
+	}
+	snapshot, err := readSlowlogSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.SchemaVersion != 1 || snapshot.Since == nil || !snapshot.Since.Equal(since) || snapshot.Until != nil || snapshot.Summary.ReplicaSets[0].Name != "rs0" {
+		t.Fatalf("snapshot = %#v", snapshot)
+	}
+}
+
+func TestValidateSlowlogFormatDigest(t *testing.T) {
+	// 测试 --format digest 只用于概览，不能与 --hash、--snapshot 或 --merge 组合。
+	tests := []struct {
+		name    string
+		cfg     config.SlowlogConfig
+		format  string
+		wantErr bool
+	}{
+		{name: "digest summary", format: "digest"},
+		{name: "table", format: "table"},
+		{name: "unknown", format: "yaml", wantErr: true},
+		{name: "digest hash", cfg: config.SlowlogConfig{QueryHash: "ABCD"}, format: "digest", wantErr: true},
+		{name: "digest snapshot", cfg: config.SlowlogConfig{Snapshot: "out.json"}, format: "digest", wantErr: true},
+		{name: "digest merge", cfg: config.SlowlogConfig{Merge: "cluster"}, format: "digest", wantErr: true},
+		{name: "negative max queries", cfg: config.SlowlogConfig{MaxQueries: -1}, format: "digest", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := validateSlowlogFormat(tc.cfg, tc.format); (err != nil) != tc.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestSlowlogAdviseFlagsDoNotShareSummaryConfig(t *testing.T) {
+	// 测试 advise 的 --db 与过滤 flag 写入自己的配置，不会改动 slowlog 概览命令的取值。
+	summary, advise := slowlogCfg, slowlogAdviseConfig
+	t.Cleanup(func() { slowlogCfg, slowlogAdviseConfig = summary, advise })
+
+	flags := slowlogAdviseCmd.Flags()
+	for name, value := range map[string]string{"db": "app", "since": "2h", "min-millis": "50", "docs-examined-ratio": "20"} {
+		if err := flags.Set(name, value); err != nil {
+			t.Fatalf("set --%s: %v", name, err)
+		}
+	}
+	if slowlogAdviseConfig.Databases != "app" || slowlogAdviseConfig.Since != "2h" || slowlogAdviseConfig.MinMillis != 50 || slowlogAdviseConfig.DocsExaminedRatio != 20 {
+		t.Fatalf("advise config = %#v", slowlogAdviseConfig)
+	}
+	if slowlogCfg.DB != summary.DB || slowlogCfg.Since != summary.Since || slowlogCfg.MinMillis != summary.MinMillis {
+		t.Fatalf("summary config changed: %#v", slowlogCfg)
+	}
+}
@@ -6,13 +6,15 @@ import (
 	"io"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mot"
 )
 
 const (
-	FormatTable = "table"
-	FormatJSON  = "json"
+	FormatTable  = "table"
+	FormatJSON   = "json"
+	FormatDigest = "digest" // 仅 slowlog 概览支持的 pt-query-digest 风格文本报告
 )
 
 func ValidateFormat(format string) error {
@@ -54,9 +56,45 @@ func PrintDiagnosticResult(w io.Writer, result any, format string) error {
 		}
 		printFindings(w, value.Findings)
 		printStatuses(w, value.CollectorStatuses)
+	case *mot.CollectionLatencyResult:
+		fmt.Fprintf(w, "MongoDB Collection Latency (%s, mode=%s, duration=%s)\n", value.ClusterType, value.Mode, durationText(value.EffectiveDuration))
+		fmt.Fprintln(w, "SHARD\tHOST\tNAMESPACE\tREAD_OPS\tREAD_P50/P95/P99_US\tWRITE_OPS\tWRITE_P50/P95/P99_US\tCMD_OPS\tCMD_P50/P95/P99_US")
+		for _, item := range value.Namespaces {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%d\t%s\t%d\t%s\n", item.Shard, item.Host, item.Namespace,
+				item.Reads.Ops, latencyPercentilesText(item.Reads), item.Writes.Ops, latencyPercentilesText(item.Writes),
+				item.Commands.Ops, latencyPercentilesText(item.Commands))
+		}
+		printFindings(w, value.Findings)
+		printStatuses(w, value.CollectorStatuses)
+	case *mot.QueryStatsResult:
+		fmt.Fprintf(w, "MongoDB Query Stats (%s)\n", value.ClusterType)
+		fmt.Fprintln(w, "ROLE\tREPLSET\tHOST\tNAMESPACE\tOP\tQUERY_SHAPE_HASH\tKEY_HASH\tCOUNT\tTOTAL\tSHARE\tAVG_MS\tMAX_MS\tMAX_DOCS_EXAMINED\tMAX_DOCS_RETURNED")
+		for _, node := range value.Nodes {
+			for _, item := range node.Items {
+				shapeHash := item.QueryShapeHash
+				if shapeHash == "" {
+					shapeHash = "-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%.2f\t%d\t%s\t%s\n", node.Role, node.ReplicaSet, node.Address, item.Namespace, item.Operation, shapeHash, item.KeyHash,
+					item.Count, millisText(item.TotalMillis), shareText(item.TotalMillis, node.TotalMillis), item.AvgMillis, item.MaxMillis, optionalInt(item.MaxDocsExamined), optionalInt(item.MaxDocsReturned))
+			}
+		}
+		printFindings(w, value.Findings)
+		printStatuses(w, value.CollectorStatuses)
+	case *mot.ScatterGatherResult:
+		fmt.Fprintf(w, "MongoDB Scatter-Gather (%s, routers=%d)\n", value.ClusterType, len(value.Routers))
+		fmt.Fprintln(w, "NAMESPACE\tOP\tQUERY_SHAPE\tPREDICATE_FIELDS\tSHARD_KEY\tSHARDS\tBROADCAST\tTARGETED\tUNKNOWN\tBROADCAST_RATIO\tQUERY_STATS_EXECS")
+		for _, item := range value.Shapes {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\t%s\n", item.Namespace, item.Operation, item.QueryShapeHash, strings.Join(item.PredicateFields, ","),
+				indexKeyText(item.ShardKey), item.ShardCount, item.Broadcast, item.Targeted, item.Unknown, optionalRatioText(item.BroadcastRatio), optionalInt(item.QueryStatsExecCount))
+		}
+		printFindings(w, value.Findings)
+		printStatuses(w, value.CollectorStatuses)
 	case *mot.IndexAuditResult:
 		fmt.Fprintln(w, "MongoDB Index Audit")
 		printIndexConsistency(w, value)
+		printIndexMemberDifferences(w, value)
+		printIndexTTL(w, value)
 		fmt.Fprintln(w, "NAMESPACE\tINDEX\tSHARD\tHOST\tOPS\tSINCE\tSIZE")
 		for _, collection := range value.Collections {
 			for _, index := range collection.Indexes {
@@ -65,6 +103,65 @@ func PrintDiagnosticResult(w io.Writer, result any, format string) error {
 		}
 		printFindings(w, value.Findings)
 		printStatuses(w, value.CollectorStatuses)
+	case *mot.SlowlogAdviseResult:
+		fmt.Fprintf(w, "MongoDB Slowlog Advise (%s)\n", value.ClusterType)
+		fmt.Fprintln(w, "NAMESPACE\tQUERY_HASH\tOP\tCOUNT\tREASONS\tPROPOSED_KEY\tSUPERSEDES")
+		for _, item := range value.Suggestions {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", item.Namespace, item.QueryHash, item.Operation, item.Count,
+				strings.Join(item.Reasons, ","), indexKeyText(item.ProposedKey), strings.Join(item.Supersedes, ","))
+		}
+		printFindings(w, value.Findings)
+		printStatuses(w, value.CollectorStatuses)
+	case *mot.PlanCacheResult:
+		fmt.Fprintf(w, "MongoDB Plan Cache (%s)\n", value.ClusterType)
+		fmt.Fprintln(w, "NAMESPACE\tQUERY_HASH\tSLOW_COUNT\tREPLSET\tHOST\tACTIVE\tWORKS\tCREATED\tPLAN")
+		for _, group := range value.Groups {
+			for _, entry := range group.Entries {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%t\t%s\t%s\t%s\n", group.Namespace, group.QueryHash, group.SlowlogCount,
+					entry.ReplicaSet, entry.Host, entry.IsActive, optionalInt(entry.Works), entry.TimeOfCreation.UTC().Format("2006-01-02T15:04:05Z"), entry.PlanSignature)
+			}
+		}
+		printFindings(w, value.Findings)
+		printStatuses(w, value.CollectorStatuses)
+	case *mot.ProfilerResult:
+		fmt.Fprintf(w, "MongoDB Profiler (%s, action=%s, dryRun=%t)\n", value.ClusterType, value.Action, value.DryRun)
+		fmt.Fprintln(w, "REPLSET\tHOST\tDATABASE\tLEVEL\tPREVIOUS\tSLOWMS\tSAMPLE_RATE\tFILTER")
+		for _, setting := range value.Settings {
+			previous := "-"
+			if setting.PreviousLevel != nil {
+				previous = fmt.Sprint(*setting.PreviousLevel)
+			}
+			sampleRate := "default"
+			if setting.SampleRate != nil {
+				sampleRate = fmt.Sprint(*setting.SampleRate)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%d\t%s\t%s\n", setting.ReplicaSet, setting.Host, setting.Database, setting.Level, previous, setting.SlowMillis, sampleRate, setting.Filter)
+		}
+		printFindings(w, value.Findings)
+		printStatuses(w, value.CollectorStatuses)
+	case *mot.IndexLifecycleResult:
+		fmt.Fprintf(w, "MongoDB Index Lifecycle (%s, action=%s, dryRun=%t)\n", value.ClusterType, value.Action, value.DryRun)
+		fmt.Fprintln(w, "NAMESPACE\tINDEX\tKEY\tHIDDEN\tCHANGED\tUSAGE_OPS\tREFUSED\tHIDDEN_FOR\tSLOW_SHAPES\tSLOW_EXECS\tCOLLSCANS")
+		for _, index := range value.Indexes {
+			hiddenFor := "-"
+			if index.HiddenAt != nil {
+				hiddenFor = durationText(index.HiddenFor)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%t\t%s\t%s\t%s\t%d\t%d\t%d\n", index.Namespace, index.IndexName, indexKeyText(index.Key), index.Hidden, index.Changed,
+				optionalInt(index.UsageOps), index.RefusedReason, hiddenFor, index.SlowQueryShapes, index.SlowQueryExecutions, index.CollectionScans)
+		}
+		printFindings(w, value.Findings)
+		printStatuses(w, value.CollectorStatuses)
+	case *mot.IndexBuildsResult:
+		fmt.Fprintf(w, "MongoDB Index Builds (%s, builds=%d, interval=%s)\n", value.ClusterType, len(value.Builds), durationText(value.Interval))
+		fmt.Fprintln(w, "NAMESPACE\tINDEXES\tSHARD\tHOST\tROLE\tPHASE\tDONE/TOTAL\tPROGRESS\tELAPSED\tRATE/S\tREMAINING\tSTALLED")
+		for _, build := range value.Builds {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", build.Namespace, strings.Join(build.Indexes, ","), build.Shard, build.Host,
+				indexBuildRole(build), textOrDash(build.Phase), indexBuildCountText(build), indexBuildProgressText(build),
+				durationText(build.Elapsed), indexBuildRateText(build), optionalDurationText(build.Remaining), optionalDurationText(build.StalledFor))
+		}
+		printFindings(w, value.Findings)
+		printStatuses(w, value.CollectorStatuses)
 	case *mot.CapacityResult:
 		fmt.Fprintf(w, "MongoDB Capacity (schema=%d, topology=%s)\n", value.SchemaVersion, value.ClusterIdentity.TopologyType)
 		fmt.Fprintln(w, "NAMESPACE\tCOUNT\tDATA\tSTORAGE\tINDEX\tFREE")
@@ -89,6 +186,58 @@ func PrintDiagnosticResult(w io.Writer, result any, format string) error {
 		for _, item := range value.Collections {
 			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", item.Namespace, item.State, optionalInt(item.Count.Delta), optionalBytes(item.Data.Delta), optionalBytes(item.Storage.Delta), optionalBytes(item.Index.Delta))
 		}
+	case *mot.CapacityForecastResult:
+		fmt.Fprintf(w, "MongoDB Capacity Forecast (method=%s, snapshots=%d, span=%s, horizon=%s)\n",
+			value.Method, value.Snapshots, durationText(value.LastCollectedAt.Sub(value.FirstCollectedAt)), durationText(value.Horizon))
+		fmt.Fprintln(w, "KIND\tNAME\tPOINTS\tLATEST\tGROWTH/DAY\tGROWTH/DAY_95%\tLIMIT\tDAYS_LEFT\tDAYS_LEFT_95%")
+		for _, group := range []struct {
+			kind   string
+			trends []mot.CapacityTrend
+		}{{"filesystem", value.Filesystems}, {"database", value.Databases}, {"collection", value.Collections}} {
+			for _, trend := range group.trends {
+				limit := "-"
+				if trend.LimitBytes != nil {
+					limit = fmt.Sprintf("%d (%s)", *trend.LimitBytes, trend.LimitSource)
+				}
+				name := trend.Name
+				if trend.LastSeenAt != nil {
+					name += " (last seen " + trend.LastSeenAt.UTC().Format("2006-01-02T15:04:05Z") + ")"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\n", group.kind, name, trend.Points, trend.LatestBytes,
+					forecastNumberText(trend.GrowthPerDay, "%.0f"), forecastRangeText(trend.GrowthPerDayLow, trend.GrowthPerDayHigh, "%.0f"),
+					limit, forecastNumberText(trend.DaysUntilLimit, "%.1f"), forecastRangeText(trend.DaysUntilLimitMin, trend.DaysUntilLimitMax, "%.1f"))
+			}
+		}
+		printFindings(w, value.Findings)
+	case *mot.SlowlogDiffResult:
+		fmt.Fprintf(w, "MongoDB Slowlog Diff (%s, added=%d, removed=%d, regressed=%d)\n", value.ClusterType, value.Added, value.Removed, value.Regressed)
+		fmt.Fprintln(w, "NAMESPACE\tOP\tQUERY_HASH\tPLAN\tSTATE\tCOUNT\tCOUNT_PER_HOUR\tAVG_MS\tP95_MS\tMAX_DOCS_EXAMINED\tREGRESSIONS")
+		for _, item := range value.Items {
+			regressions := "-"
+			if len(item.Regressions) > 0 {
+				regressions = strings.Join(item.Regressions, ",")
+			}
+			plan := item.PlanSummary
+			if item.PreviousPlanSummary != "" {
+				plan = item.PreviousPlanSummary + "->" + item.PlanSummary
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", item.Namespace, item.Operation, item.QueryHash, plan, item.State,
+				slowlogDeltaText(item.Count), slowlogRateText(item.CountPerHour), slowlogDeltaText(item.AvgMillis), slowlogDeltaText(item.P95Millis),
+				slowlogDeltaText(item.MaxDocsExamined), regressions)
+		}
+		printFindings(w, value.Findings)
+	case *mot.IndexDiffResult:
+		fmt.Fprintf(w, "MongoDB Index Diff (source=%s, target=%s, namespaces=%d, identical=%d, skipped=%d)\n",
+			value.SourceClusterType, value.TargetClusterType, value.Namespaces, value.Identical, len(value.Skipped))
+		fmt.Fprintln(w, "NAMESPACE\tSOURCE_NAMESPACE\tSTATE\tINDEX\tTARGET_INDEX\tKEY\tTARGET_KEY\tFIELDS")
+		for _, item := range value.Items {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", item.Namespace, item.SourceNamespace, item.State, item.IndexName, item.TargetIndexName,
+				indexKeyText(item.Key), indexKeyText(item.TargetKey), strings.Join(item.DifferingFields, ","))
+		}
+		if len(value.Skipped) > 0 {
+			fmt.Fprintf(w, "Skipped (incomplete snapshot): %s\n", strings.Join(value.Skipped, ","))
+		}
+		printFindings(w, value.Findings)
 	default:
 		return fmt.Errorf("unsupported diagnostic result %T", result)
 	}
@@ -133,6 +282,125 @@ func printIndexConsistency(w io.Writer, result *mot.IndexAuditResult) {
 	}
 }
 
+func printIndexMemberDifferences(w io.Writer, result *mot.IndexAuditResult) {
+	hasDifferences := false
+	for _, collection := range result.Collections {
+		if len(collection.MemberDifferences) > 0 {
+			hasDifferences = true
+			break
+		}
+	}
+	if !hasDifferences {
+		return
+	}
+	fmt.Fprintln(w, "Member Index Differences:")
+	fmt.Fprintln(w, "NAMESPACE\tREPLSET\tCODE\tINDEX\tMEMBERS\tKEY\tFINGERPRINT\tFIELDS")
+	for _, collection := range result.Collections {
+		for _, difference := range collection.MemberDifferences {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				collection.Namespace, difference.ReplicaSet, difference.Code, difference.IndexName, strings.Join(difference.Members, ","),
+				indexKeyText(difference.Key), difference.Fingerprint, strings.Join(difference.DifferingFields, ","))
+		}
+	}
+}
+
+func printIndexTTL(w io.Writer, result *mot.IndexAuditResult) {
+	hasTTL := false
+	for _, collection := range result.Collections {
+		if len(collection.TTLIndexes) > 0 {
+			hasTTL = true
+			break
+		}
+	}
+	if !hasTTL {
+		return
+	}
+	fmt.Fprintln(w, "TTL Indexes:")
+	fmt.Fprintln(w, "NAMESPACE\tINDEX\tKEY\tEXPIRE_AFTER_SECONDS\tCAPPED\tSAMPLED\tNON_DATE\tVALUE_TYPES")
+	for _, collection := range result.Collections {
+		for _, index := range collection.TTLIndexes {
+			expireAfter := optionalInt(index.ExpireAfterSeconds)
+			if index.InvalidExpireAfter {
+				expireAfter = "invalid"
+			}
+			types := make([]string, 0, len(index.ValueTypes))
+			for valueType, count := range index.ValueTypes {
+				types = append(types, fmt.Sprintf("%s=%d", valueType, count))
+			}
+			sort.Strings(types)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%d\t%d\t%s\n",
+				collection.Namespace, index.Name, indexKeyText(index.Key), expireAfter, index.Capped,
+				index.SampledDocuments, index.NonDateDocuments, strings.Join(types, ","))
+		}
+	}
+}
+
+func indexBuildRole(build mot.IndexBuild) string {
+	if build.Primary {
+		return "primary"
+	}
+	return "secondary"
+}
+
+func indexBuildCountText(build mot.IndexBuild) string {
+	if build.Done == nil && build.Total == nil {
+		return "-"
+	}
+	return optionalInt(build.Done) + "/" + optionalInt(build.Total)
+}
+
+func indexBuildProgressText(build mot.IndexBuild) string {
+	if build.Done == nil || build.Total == nil || *build.Total <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(*build.Done)*100/float64(*build.Total))
+}
+
+func indexBuildRateText(build mot.IndexBuild) string {
+	if build.RatePerSecond <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", build.RatePerSecond)
+}
+
+// optionalDurationText 用于只在 watch 比较后才有值的时长，0 表示未知。
+func optionalDurationText(duration time.Duration) string {
+	if duration <= 0 {
+		return "-"
+	}
+	return durationText(duration)
+}
+
+func forecastNumberText(value *float64, format string) string {
+	if value == nil {
+		return "-"
+	}
+	return fmt.Sprintf(format, *value)
+}
+
+// forecastRangeText 以 low..high 展示置信区间；上界缺失表示区间内可能不会耗尽，显示为 never。
+func forecastRangeText(low, high *float64, format string) string {
+	if low == nil && high == nil {
+		return "-"
+	}
+	upper := "never"
+	if high != nil {
+		upper = fmt.Sprintf(format, *high)
+	}
+	return forecastNumberText(low, format) + ".." + upper
+}
+
+func textOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func latencyPercentilesText(distribution mot.LatencyDistribution) string {
+	return optionalInt(distribution.P50Micros) + "/" + optionalInt(distribution.P95Micros) + "/" + optionalInt(distribution.P99Micros)
+}
+
 func indexKeyText(key []mot.IndexKeyField) string {
 	parts := make([]string, 0, len(key))
 	for _, field := range key {
@@ -184,6 +452,33 @@ func optionalBytes(value *int64) string {
 	return fmt.Sprintf("%d", *value)
 }
 
+// slowlogDeltaText 以 before->after 展示指标变化；缺失的一侧显示为 n/a，两侧都缺失时显示为 -。
+func slowlogDeltaText(delta mot.SlowlogDelta) string {
+	if delta.Before == nil && delta.After == nil {
+		return "-"
+	}
+	side := func(value *int64) string {
+		if value == nil {
+			return "n/a"
+		}
+		return fmt.Sprintf("%d", *value)
+	}
+	return side(delta.Before) + "->" + side(delta.After)
+}
+
+func slowlogRateText(rate *mot.SlowlogRateDelta) string {
+	if rate == nil || (rate.Before == nil && rate.After == nil) {
+		return "-"
+	}
+	side := func(value *float64) string {
+		if value == nil {
+			return "n/a"
+		}
+		return fmt.Sprintf("%.1f", *value)
+	}
+	return side(rate.Before) + "->" + side(rate.After)
+}
+
 func optionalInt(value *int64) string {
 	if value == nil {
 		return "unavailable"
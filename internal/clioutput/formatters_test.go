@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/fatih/color"
+	"go.mongodb.org/mongo-driver/bson"
 
 	"github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mot"
 )
@@ -99,15 +100,17 @@ func TestPrintDiagnosticResultGoldenAndRedactionBoundary(t *testing.T) {
 }
 
 func TestPrintAllDiagnosticCommandsGolden(t *testing.T) {
-	// 场景：ops、hotspot、index-audit、capacity 与 diff 的 table schema 保持稳定，并保留 collector scope。
+	// 场景：ops、hotspot、latency、index-audit、capacity 与 diff 的 table schema 保持稳定，并保留 collector scope。
 	withColorDisabled(t)
 	count, data, storage, indexSize, delta := int64(2), int64(100), int64(80), int64(20), int64(5)
+	p50, p95, p99 := int64(128), int64(2048), int64(131072)
 	results := []struct {
 		name  string
 		value any
 	}{
 		{"ops", &mot.CurrentOperationsResult{ClusterType: mot.ClusterReplicaSet, Visibility: "all_users", Source: "aggregation", Operations: []mot.CurrentOperation{{Host: "node", Namespace: "db.c", Operation: "query", RunningDuration: 3 * time.Second}}, CollectorStatuses: []mot.CollectorStatus{{Name: "current_operations", State: mot.CapabilitySupported, Scope: mot.FindingScope{Type: mot.ScopeCluster}}}}},
 		{"hotspot", &mot.HotspotResult{ClusterType: mot.ClusterSharded, EffectiveDuration: 2 * time.Second, Namespaces: []mot.NamespaceHotspot{{Shard: "s0", Host: "node", Namespace: "db.c", ReadPerSecond: 1.5, WritePerSecond: 0.5, TotalTimeMicros: 40}}, CollectorStatuses: []mot.CollectorStatus{{Name: "hotspot", State: mot.CapabilitySupported, Scope: mot.FindingScope{Type: mot.ScopeNode, Shard: "s0", Node: "node"}}}}},
+		{"latency", &mot.CollectionLatencyResult{ClusterType: mot.ClusterSharded, Mode: mot.CollectionLatencySampled, EffectiveDuration: 2 * time.Second, Namespaces: []mot.NamespaceLatency{{Shard: "s0", Host: "node", Namespace: "db.c", Reads: mot.LatencyDistribution{Ops: 10, P50Micros: &p50, P95Micros: &p95, P99Micros: &p99}}}, Findings: []mot.DiagnosticFinding{{Code: "latency.p99_high", Severity: mot.SeverityWarning, Scope: mot.FindingScope{Type: mot.ScopeNamespace, Shard: "s0", Node: "node", Namespace: "db.c"}, Summary: "namespace 读操作 p99 延迟超过阈值"}}, CollectorStatuses: []mot.CollectorStatus{{Name: "collection_latency", State: mot.CapabilitySupported, Scope: mot.FindingScope{Type: mot.ScopeNode, Shard: "s0", Node: "node"}}}}},
 		{"index", &mot.IndexAuditResult{Collections: []mot.CollectionIndexAudit{{Namespace: "db.c", Indexes: []mot.IndexObservation{{Name: "a_1", Shard: "s0", Host: "node", Ops: 0, Since: time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC), SizeBytes: &indexSize}}}}, CollectorStatuses: []mot.CollectorStatus{{Name: "index_usage", State: mot.CapabilitySupported, Scope: mot.FindingScope{Type: mot.ScopeNamespace, Namespace: "db.c"}}}}},
 		{"capacity", &mot.CapacityResult{SchemaVersion: 1, ClusterIdentity: mot.CapacityIdentity{TopologyType: mot.ClusterReplicaSet, Digest: "digest"}, Databases: []mot.DatabaseCapacity{{Name: "db", Collections: []mot.CollectionCapacity{{Namespace: "db.c", Count: &count, DataSizeBytes: &data, StorageSizeBytes: &storage, IndexSizeBytes: &indexSize}}}}, CollectorStatuses: []mot.CollectorStatus{{Name: "collection_capacity", State: mot.CapabilitySupported, Scope: mot.FindingScope{Type: mot.ScopeNamespace, Namespace: "db.c"}}}}},
 		{"diff", &mot.CapacityDiffResult{Duration: 24 * time.Hour, Collections: []mot.CollectionCapacityDiff{{Namespace: "db.c", State: "existing", Count: mot.CapacityDelta{Delta: &delta}}}}},
@@ -256,6 +259,401 @@ func TestPrintSlowlogSummaryFixture(t *testing.T) {
 	}
 }
 
+func TestPrintMergedSlowlogSummaryFixture(t *testing.T) {
+	// 测试集群级合并视图输出排序方式、累计耗时与贡献分片。
+	withColorDisabled(t)
+	p95 := int64(120)
+	share := 0.75
+	result := &mot.MergedSlowlogSummary{
+		Merge: mot.SlowlogMergeCluster, Sort: mot.SlowlogSortTotal, Total: 9, TotalMillis: 800,
+		Items: []mot.MergedSlowlogItem{{
+			SlowlogSummaryItem: mot.SlowlogSummaryItem{Namespace: "app.orders", QueryHash: "ABCD1234", Operation: "query", Count: 9, TotalMillis: 600, P95Millis: &p95, TimeShare: &share},
+			ReplicaSets:        []string{"shard-a", "shard-b"},
+			Hosts:              []string{"a1:27017", "b1:27017"},
+		}},
+	}
+
+	var output bytes.Buffer
+	if err := PrintMergedSlowlogSummary(&output, result, SlowlogPrintOptions{}); err != nil {
+		t.Fatalf("PrintMergedSlowlogSummary failed: %v", err)
+	}
+	for _, value := range []string{"Merged: cluster, Sort: totalMillis", "TotalMills: 800", "app.orders", "75.0%", "shard-a,shard-b", "a1:27017,b1:27017"} {
+		if !strings.Contains(output.String(), value) {
+			t.Fatalf("merged slowlog output omitted %q:\n%s", value, output.String())
+		}
+	}
+}
+
+func TestPrintSlowlogAdviseFixture(t *testing.T) {
+	// 测试索引建议表格输出候选 key、触发原因和可替代索引。
+	result := &mot.SlowlogAdviseResult{
+		ClusterType: mot.ClusterReplicaSet,
+		Suggestions: []mot.IndexSuggestion{{
+			Namespace: "app.orders", QueryHash: "ABCD1234", Operation: "query", Count: 7,
+			Reasons:     []string{"collection_scan", "blocking_sort"},
+			ProposedKey: []mot.IndexKeyField{{Field: "status", Order: "1"}, {Field: "createdAt", Order: "-1"}},
+			Supersedes:  []string{"status_1"},
+		}},
+	}
+
+	var output bytes.Buffer
+	if err := PrintDiagnosticResult(&output, result, FormatTable); err != nil {
+		t.Fatalf("PrintDiagnosticResult failed: %v", err)
+	}
+	for _, value := range []string{"MongoDB Slowlog Advise (repl)", "app.orders", "collection_scan,blocking_sort", "status:1,createdAt:-1", "status_1"} {
+		if !strings.Contains(output.String(), value) {
+			t.Fatalf("slowlog advise output omitted %q:\n%s", value, output.String())
+		}
+	}
+}
+
+func TestPrintSlowlogDetailExplainFixture(t *testing.T) {
+	// 测试 slowlog 详情在 explain 存在时输出计划树、索引边界类别和 executionStats。
+	withColorDisabled(t)
+	ratio := 250.0
+	result := &mot.SlowlogDetailResult{
+		Namespace: "app.orders",
+		Slowlog:   bson.M{"op": "query"},
+		Explain: &mot.SlowlogExplain{
+			Verbosity: mot.ExplainExecutionStats,
+			WinningPlan: mot.ExplainPlan{
+				Stages:       []string{"SORT", "FETCH", "IXSCAN"},
+				InMemorySort: true,
+				IndexScans: []mot.ExplainIndexScan{{
+					IndexName: "status_1",
+					Key:       []mot.IndexKeyField{{Field: "status", Order: "1"}},
+					Bounds:    []mot.ExplainIndexBound{{Field: "status", Intervals: 1, Kind: "point"}},
+				}},
+			},
+			RejectedPlans:  []mot.ExplainPlan{{Stages: []string{"COLLSCAN"}, CollectionScan: true}},
+			ExecutionStats: &mot.ExplainStats{NReturned: 2, TotalDocsExamined: 500, FetchRatio: &ratio},
+		},
+	}
+
+	var output bytes.Buffer
+	if err := PrintSlowlogDetail(&output, result, SlowlogPrintOptions{}); err != nil {
+		t.Fatalf("PrintSlowlogDetail failed: %v", err)
+	}
+	for _, value := range []string{"explain (executionStats)", "winning: SORT > FETCH > IXSCAN", "status:point(1)", "rejected#1: COLLSCAN", "fetchRatio=250.00"} {
+		if !strings.Contains(output.String(), value) {
+			t.Fatalf("slowlog detail output omitted %q:\n%s", value, output.String())
+		}
+	}
+}
+
+func TestPrintPlanCacheFixture(t *testing.T) {
+	// 测试 plan cache 表格按节点输出缓存计划签名、isActive 与 works。
+	works := int64(12)
+	result := &mot.PlanCacheResult{
+		ClusterType: mot.ClusterSharded,
+		Groups: []mot.PlanCacheGroup{{
+			Namespace: "app.orders", QueryHash: "ABCD1234", SlowlogCount: 3, DistinctPlans: 1,
+			Entries: []mot.PlanCacheEntry{{
+				ReplicaSet: "shard01", Host: "n1:27017", IsActive: true, Works: &works,
+				TimeOfCreation: time.Date(2026, 7, 20, 8, 0, 0, 0, time.UTC), PlanSignature: "FETCH>IXSCAN {status:1}",
+			}},
+		}},
+	}
+
+	var output bytes.Buffer
+	if err := PrintDiagnosticResult(&output, result, FormatTable); err != nil {
+		t.Fatalf("PrintDiagnosticResult failed: %v", err)
+	}
+	for _, value := range []string{"MongoDB Plan Cache (sharding)", "app.orders", "shard01", "n1:27017", "true", "2026-07-20T08:00:00Z", "FETCH>IXSCAN {status:1}"} {
+		if !strings.Contains(output.String(), value) {
+			t.Fatalf("plan cache output omitted %q:\n%s", value, output.String())
+		}
+	}
+}
+
+func TestPrintProfilerFixture(t *testing.T) {
+	// 测试 profiler 表格输出每个成员的 level、修改前 level 与 slowms。
+	previous := 0
+	result := &mot.ProfilerResult{
+		ClusterType: mot.ClusterReplicaSet, Action: mot.ProfilerActionEnable,
+		Settings:          []mot.ProfilerSetting{{ReplicaSet: "rs0", Host: "n1:27017", Database: "app", Level: 1, PreviousLevel: &previous, SlowMillis: 100}},
+		CollectorStatuses: []mot.CollectorStatus{{Name: "profiler_change", State: mot.CapabilitySupported, Scope: mot.FindingScope{Type: mot.ScopeNode, Node: "n1:27017", Database: "app"}, ReasonCode: "applied"}},
+	}
+
+	var output bytes.Buffer
+	if err := PrintDiagnosticResult(&output, result, FormatTable); err != nil {
+		t.Fatalf("PrintDiagnosticResult failed: %v", err)
+	}
+	for _, value := range []string{"MongoDB Profiler (repl, action=enable, dryRun=false)", "rs0\tn1:27017\tapp\t1\t0\t100\tdefault", "applied"} {
+		if !strings.Contains(output.String(), value) {
+			t.Fatalf("profiler output omitted %q:\n%s", value, output.String())
+		}
+	}
+}
+
+func TestPrintSlowlogDiffFixture(t *testing.T) {
+	// 测试 slowlog diff 表格以 before->after 展示指标与每小时次数变化，缺失一侧显示为 n/a，主计划变化以箭头展示。
+	before, after, delta := int64(10), int64(30), int64(20)
+	ratio, rateBefore, rateAfter := 3.0, 2.5, 7.5
+	result := &mot.SlowlogDiffResult{
+		ClusterType: mot.ClusterReplicaSet, Added: 1, Regressed: 1,
+		Items: []mot.SlowlogShapeDiff{
+			{Namespace: "app.orders", Operation: "query", QueryHash: "ABCD", PlanSummary: "COLLSCAN", PreviousPlanSummary: "IXSCAN { a: 1 }", State: "existing", Count: mot.SlowlogDelta{Before: &before, After: &after, Delta: &delta, Ratio: &ratio}, CountPerHour: &mot.SlowlogRateDelta{Before: &rateBefore, After: &rateAfter, Ratio: &ratio}, Regressions: []string{"count", "planSummary"}},
+			{Namespace: "app.users", Operation: "query", QueryHash: "EF01", State: "added", Count: mot.SlowlogDelta{After: &after}},
+		},
+		Findings: []mot.DiagnosticFinding{{Code: "slowlog.regression", Severity: mot.SeverityWarning, Scope: mot.FindingScope{Type: mot.ScopeNamespace, Namespace: "app.orders"}}},
+	}
+
+	var output bytes.Buffer
+	if err := PrintDiagnosticResult(&output, result, FormatTable); err != nil {
+		t.Fatalf("PrintDiagnosticResult failed: %v", err)
+	}
+	for _, value := range []string{"MongoDB Slowlog Diff (repl, added=1, removed=0, regressed=1)", "app.orders\tquery\tABCD\tIXSCAN { a: 1 }->COLLSCAN\texisting\t10->30\t2.5->7.5\t-\t-\t-\tcount,planSummary\n", "app.users\tquery\tEF01\t\tadded\tn/a->30\t-\t-", "slowlog.regression"} {
+		if !strings.Contains(output.String(), value) {
+			t.Fatalf("slowlog diff output omitted %q:\n%s", value, output.String())
+		}
+	}
+}
+
+func TestPrintSlowlogDigestFixture(t *testing.T) {
+	// 测试 digest 报告输出全局节点分布、每个查询形状的耗时占比、按 bucket 次数绘制的延迟分布与脱敏样本命令。
+	share, p95 := 0.75, int64(400)
+	docsRatio := 120.0
+	moment := time.Date(2026, 7, 20, 8, 0, 0, 0, time.UTC)
+	result := &mot.SlowlogDigestResult{
+		ClusterType: mot.ClusterReplicaSet, FirstTime: moment, LastTime: moment, Total: 12, TotalMillis: 4000, UniqueShapes: 3,
+		Hosts: []mot.SlowlogDigestHost{{ReplicaSet: "rs0", Host: "n1:27017", Count: 12, TotalMillis: 4000}},
+		Queries: []mot.SlowlogDigestQuery{{
+			Rank: 1,
+			MergedSlowlogItem: mot.MergedSlowlogItem{
+				SlowlogSummaryItem: mot.SlowlogSummaryItem{Namespace: "app.orders", Operation: "query", QueryHash: "ABCD", Count: 10, TotalMillis: 3000, AvgMillis: 300, MinMillis: 10, MaxMillis: 800, P95Millis: &p95, TimeShare: &share, PlanSummary: "COLLSCAN", AppNames: []string{"api"}, WorstDocsToReturned: &docsRatio, CollectionScanCount: 10},
+				ReplicaSets:        []string{"rs0"}, Hosts: []string{"n1:27017"},
+			},
+			LatencyHistogram: []mot.SlowlogLatencyBucket{
+				{LowerMillis: 0, UpperMillis: 1}, {LowerMillis: 1, UpperMillis: 10}, {LowerMillis: 10, UpperMillis: 100, Count: 2},
+				{LowerMillis: 100, UpperMillis: 1000, Count: 8}, {LowerMillis: 1000, UpperMillis: 10000}, {LowerMillis: 10000},
+			},
+			ExampleCommand: `{"find":"orders","filter":{"status":"?"}}`,
+		}},
+	}
+
+	var output bytes.Buffer
+	if err := PrintSlowlogDigest(&output, result); err != nil {
+		t.Fatalf("PrintSlowlogDigest failed: %v", err)
+	}
+	for _, value := range []string{
+		"# Slowlog digest (repl)", "# Overall: 12 slow ops, 3 unique shapes, total 4.00s", "100.0%",
+		"# Query 1: 75.0% of response time, 10 calls, ID ABCD", "# Plan:        COLLSCAN", "docs/returned 120.00",
+		"# Percentiles: p50 n/a, p95 400ms, p99 n/a\n",
+		"#   <1ms          0  \n", "#   10ms          2  ##########\n", "#   100ms         8  ########################################\n", "#   10s+          0  \n",
+		"# Example (redacted):\n#   {\"find\":\"orders\",\"filter\":{\"status\":\"?\"}}",
+	} {
+		if !strings.Contains(output.String(), value) {
+			t.Fatalf("digest output omitted %q:\n%s", value, output.String())
+		}
+	}
+
+	result.Queries[0].LatencyHistogram = nil
+	output.Reset()
+	if err := PrintSlowlogDigest(&output, result); err != nil {
+		t.Fatalf("PrintSlowlogDigest failed: %v", err)
+	}
+	if !strings.Contains(output.String(), "# Latency distribution: unavailable") {
+		t.Fatalf("digest without buckets did not report an unavailable distribution:\n%s", output.String())
+	}
+}
+
+func TestPrintQueryStatsFixture(t *testing.T) {
+	// 测试 query stats 表格按节点列出查询形状，缺少 8.0+ 指标时显示为 unavailable。
+	returned := int64(3)
+	result := &mot.QueryStatsResult{
+		ClusterType: mot.ClusterSharded,
+		Nodes: []mot.QueryStatsNode{
+			{Role: "mongos", Address: "router:27017", Total: 4, TotalMillis: 2000, Items: []mot.SlowlogSummaryItem{{Namespace: "app.orders", Operation: "find", KeyHash: "KEY1", Count: 4, TotalMillis: 1500, AvgMillis: 375, MaxMillis: 900, MaxDocsReturned: &returned}}},
+		},
+		CollectorStatuses: []mot.CollectorStatus{{Name: "query_stats", State: mot.CapabilityUnsupported, Scope: mot.FindingScope{Type: mot.ScopeNode, Node: "n1:27018"}, ReasonCode: "server_unsupported"}},
+	}
+
+	var output bytes.Buffer
+	if err := PrintDiagnosticResult(&output, result, FormatTable); err != nil {
+		t.Fatalf("PrintDiagnosticResult failed: %v", err)
+	}
+	for _, value := range []string{"MongoDB Query Stats (sharding)", "mongos\t\trouter:27017\tapp.orders\tfind\t-\tKEY1\t4\t1.50s\t75.0%\t375.00\t900\tunavailable\t3\n", "query_stats"} {
+		if !strings.Contains(output.String(), value) {
+			t.Fatalf("query stats output omitted %q:\n%s", value, output.String())
+		}
+	}
+}
+
+func TestPrintScatterGatherFixture(t *testing.T) {
+	// 测试 scatter-gather 表格列出过滤字段、分片键与广播/定向次数，缺少 $queryStats 时显示为 unavailable。
+	ratio := 0.75
+	result := &mot.ScatterGatherResult{
+		ClusterType: mot.ClusterSharded, Routers: []string{"router1:27017", "router2:27017"},
+		Shapes:   []mot.ScatterGatherShape{{Namespace: "app.orders", Operation: "query", PredicateFields: []string{"status"}, ShardKey: []mot.IndexKeyField{{Field: "tenant", Order: "1"}}, ShardCount: 3, Executions: 9, Broadcast: 6, Targeted: 2, Unknown: 1, BroadcastRatio: &ratio}},
+		Findings: []mot.DiagnosticFinding{{Code: "sharding.scatter_gather_query", Severity: mot.SeverityWarning, Scope: mot.FindingScope{Type: mot.ScopeNamespace, Namespace: "app.orders"}}},
+	}
+
+	var output bytes.Buffer
+	if err := PrintDiagnosticResult(&output, result, FormatTable); err != nil {
+		t.Fatalf("PrintDiagnosticResult failed: %v", err)
+	}
+	for _, value := range []string{"MongoDB Scatter-Gather (sharding, routers=2)", "app.orders\tquery\t\tstatus\ttenant:1\t3\t6\t2\t1\t0.75\tunavailable\n", "sharding.scatter_gather_query"} {
+		if !strings.Contains(output.String(), value) {
+			t.Fatalf("scatter-gather output omitted %q:\n%s", value, output.String())
+		}
+	}
+}
+
+func TestPrintIndexLifecycleFixture(t *testing.T) {
+	// 测试索引生命周期表格列出隐藏状态、拒绝原因、隐藏时长与隐藏后出现的慢查询计数。
+	hiddenAt := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	ops := int64(12)
+	result := &mot.IndexLifecycleResult{
+		ClusterType: mot.ClusterReplicaSet, Action: mot.IndexLifecycleStatus,
+		Indexes: []mot.IndexLifecycleState{
+			{Namespace: "app.orders", IndexName: "legacy_1", Key: []mot.IndexKeyField{{Field: "legacy", Order: "1"}}, Hidden: true, HiddenAt: &hiddenAt, HiddenFor: 49 * time.Hour, SlowQueryShapes: 2, SlowQueryExecutions: 7, CollectionScans: 3},
+			{Namespace: "app.users", IndexName: "email_1", UsageOps: &ops, RefusedReason: "index_in_use"},
+		},
+		Findings: []mot.DiagnosticFinding{{Code: "index.hidden_slow_queries", Severity: mot.SeverityWarning, Scope: mot.FindingScope{Type: mot.ScopeNamespace, Namespace: "app.orders"}}},
+	}
+
+	var output bytes.Buffer
+	if err := PrintDiagnosticResult(&output, result, FormatTable); err != nil {
+		t.Fatalf("PrintDiagnosticResult failed: %v", err)
+	}
+	for _, value := range []string{"MongoDB Index Lifecycle (repl, action=status, dryRun=false)", "app.orders\tlegacy_1\tlegacy:1\ttrue\tfalse\tunavailable\t\t49h0m0s\t2\t7\t3\n", "app.users\temail_1\t\tfalse\tfalse\t12\tindex_in_use\t-\t0\t0\t0\n", "index.hidden_slow_queries"} {
+		if !strings.Contains(output.String(), value) {
+			t.Fatalf("index lifecycle output omitted %q:\n%s", value, output.String())
+		}
+	}
+}
+
+func TestPrintIndexDiffFixture(t *testing.T) {
+	// 测试索引快照对比表格列出映射前的 namespace、差异状态与不同的选项，未完整读取的 namespace 单独列出。
+	result := &mot.IndexDiffResult{
+		SourceClusterType: mot.ClusterReplicaSet, TargetClusterType: mot.ClusterSharded, Namespaces: 3, Identical: 1, Skipped: []string{"app.events"},
+		Items: []mot.IndexDiffItem{
+			{Namespace: "app.orders", SourceNamespace: "app_staging.orders", State: "options_mismatch", IndexName: "tenant_1", Key: []mot.IndexKeyField{{Field: "tenant", Order: "1"}}, TargetKey: []mot.IndexKeyField{{Field: "tenant", Order: "1"}}, DifferingFields: []string{"unique"}},
+			{Namespace: "app.users", State: "only_in_target", IndexName: "email_1", Key: []mot.IndexKeyField{{Field: "email", Order: "1"}}},
+		},
+		Findings: []mot.DiagnosticFinding{{Code: "index.diff_definition_mismatch", Severity: mot.SeverityWarning, Scope: mot.FindingScope{Type: mot.ScopeNamespace, Namespace: "app.orders"}}},
+	}
+
+	var output bytes.Buffer
+	if err := PrintDiagnosticResult(&output, result, FormatTable); err != nil {
+		t.Fatalf("PrintDiagnosticResult failed: %v", err)
+	}
+	for _, value := range []string{"MongoDB Index Diff (source=repl, target=sharding, namespaces=3, identical=1, skipped=1)", "app.orders\tapp_staging.orders\toptions_mismatch\ttenant_1\t\ttenant:1\ttenant:1\tunique\n", "app.users\t\tonly_in_target\temail_1\t\temail:1\t\t\n", "Skipped (incomplete snapshot): app.events", "index.diff_definition_mismatch"} {
+		if !strings.Contains(output.String(), value) {
+			t.Fatalf("index diff output omitted %q:\n%s", value, output.String())
+		}
+	}
+}
+
+func TestPrintIndexTTLFixture(t *testing.T) {
+	// 测试 TTL 表格列出每个 TTL 索引的 expireAfterSeconds、capped 标记与字段类型抽样，非法值显示为 invalid。
+	expireAfter := int64(3600)
+	result := &mot.IndexAuditResult{
+		Collections: []mot.CollectionIndexAudit{{
+			Namespace: "app.sessions", Indexes: []mot.IndexObservation{},
+			TTLIndexes: []mot.IndexTTLObservation{
+				{Name: "createdAt_1", Key: []mot.IndexKeyField{{Field: "createdAt", Order: "1"}}, ExpireAfterSeconds: &expireAfter, SampledDocuments: 10, NonDateDocuments: 2, ValueTypes: map[string]int64{"string": 2, "date": 8}},
+				{Name: "tenant_1_expireAt_1", Key: []mot.IndexKeyField{{Field: "tenant", Order: "1"}, {Field: "expireAt", Order: "1"}}, InvalidExpireAfter: true, Capped: true},
+			},
+		}},
+		Findings: []mot.DiagnosticFinding{{Code: "index.ttl_non_date_values", Severity: mot.SeverityWarning, Scope: mot.FindingScope{Type: mot.ScopeNamespace, Namespace: "app.sessions"}}},
+	}
+
+	var output bytes.Buffer
+	if err := PrintDiagnosticResult(&output, result, FormatTable); err != nil {
+		t.Fatalf("PrintDiagnosticResult failed: %v", err)
+	}
+	for _, value := range []string{"TTL Indexes:", "app.sessions\tcreatedAt_1\tcreatedAt:1\t3600\tfalse\t10\t2\tdate=8,string=2\n", "app.sessions\ttenant_1_expireAt_1\ttenant:1,expireAt:1\tinvalid\ttrue\t0\t0\t\n", "index.ttl_non_date_values"} {
+		if !strings.Contains(output.String(), value) {
+			t.Fatalf("ttl output omitted %q:\n%s", value, output.String())
+		}
+	}
+}
+
+func TestPrintIndexBuildsFixture(t *testing.T) {
+	// 测试索引构建表格按成员列出阶段、进度百分比与 watch 估算值，未比较前速率与剩余时间显示为 -。
+	done, total := int64(250), int64(1000)
+	result := &mot.IndexBuildsResult{
+		ClusterType: mot.ClusterReplicaSet, Interval: 30 * time.Second,
+		Builds: []mot.IndexBuild{
+			{Namespace: "app.orders", Indexes: []string{"tenant_1"}, Host: "n1:27017", Primary: true, Phase: "scanning collection", Done: &done, Total: &total, Elapsed: 90 * time.Second, RatePerSecond: 12.5, Remaining: time.Minute},
+			{Namespace: "app.orders", Indexes: []string{"tenant_1"}, Host: "n2:27017", Elapsed: 80 * time.Second, StalledFor: 6 * time.Minute},
+		},
+		Findings: []mot.DiagnosticFinding{{Code: "index.build_stalled", Severity: mot.SeverityWarning, Scope: mot.FindingScope{Type: mot.ScopeNamespace, Node: "n2:27017", Namespace: "app.orders"}}},
+	}
+
+	var output bytes.Buffer
+	if err := PrintDiagnosticResult(&output, result, FormatTable); err != nil {
+		t.Fatalf("PrintDiagnosticResult failed: %v", err)
+	}
+	for _, value := range []string{
+		"MongoDB Index Builds (repl, builds=2, interval=30s)",
+		"app.orders\ttenant_1\t\tn1:27017\tprimary\tscanning collection\t250/1000\t25.0%\t1m30s\t12.5\t1m0s\t-\n",
+		"app.orders\ttenant_1\t\tn2:27017\tsecondary\t-\t-\t-\t1m20s\t-\t-\t6m0s\n",
+		"index.build_stalled",
+	} {
+		if !strings.Contains(output.String(), value) {
+			t.Fatalf("index builds output omitted %q:\n%s", value, output.String())
+		}
+	}
+}
+
+func TestPrintCapacityForecastFixture(t *testing.T) {
+	// 测试容量预测表格按文件系统、数据库、集合列出趋势与置信区间，斜率下界不为正时最晚耗尽显示为 never，无上限的行显示为 -，最新快照中已不存在的对象在名称后标注最后出现时间。
+	growth, low, high := 100.0, -20.0, 180.0
+	days, earliest := 60.0, 33.3
+	limit := int64(10000)
+	lastSeen := time.Unix(2*24*3600, 0)
+	result := &mot.CapacityForecastResult{
+		Method: mot.CapacityForecastRobust, Snapshots: 4, FirstCollectedAt: time.Unix(0, 0), LastCollectedAt: time.Unix(3*24*3600, 0), Horizon: 90 * 24 * time.Hour,
+		Filesystems: []mot.CapacityTrend{{Name: "shard-a/n1:27017", Points: 4, LatestBytes: 4000, GrowthPerDay: &growth, GrowthPerDayLow: &low, GrowthPerDayHigh: &high, LimitBytes: &limit, LimitSource: "filesystem", DaysUntilLimit: &days, DaysUntilLimitMin: &earliest}},
+		Collections: []mot.CapacityTrend{{Name: "app.events", Points: 2, LatestBytes: 130}, {Name: "app.dropped", Points: 3, LatestBytes: 90, LastSeenAt: &lastSeen}},
+		Findings:    []mot.DiagnosticFinding{{Code: "capacity.exhaustion_forecast", Severity: mot.SeverityWarning, Scope: mot.FindingScope{Type: mot.ScopeNode, Node: "n1:27017"}}},
+	}
+
+	var output bytes.Buffer
+	if err := PrintDiagnosticResult(&output, result, FormatTable); err != nil {
+		t.Fatalf("PrintDiagnosticResult failed: %v", err)
+	}
+	for _, value := range []string{
+		"MongoDB Capacity Forecast (method=robust, snapshots=4, span=72h0m0s, horizon=2160h0m0s)",
+		"filesystem\tshard-a/n1:27017\t4\t4000\t100\t-20..180\t10000 (filesystem)\t60.0\t33.3..never\n",
+		"collection\tapp.events\t2\t130\t-\t-\t-\t-\t-\n",
+		"collection\tapp.dropped (last seen 1970-01-03T00:00:00Z)\t3\t90\t-\t-\t-\t-\t-\n",
+		"capacity.exhaustion_forecast",
+	} {
+		if !strings.Contains(output.String(), value) {
+			t.Fatalf("capacity forecast output omitted %q:\n%s", value, output.String())
+		}
+	}
+}
+
+func TestWriteIndexRemediationPlanScriptFixture(t *testing.T) {
+	// 测试修复脚本逐步注明来源 finding 与回滚命令，hide 步骤可直接执行，drop 步骤保持注释。
+	notBefore := time.Date(2026, 10, 8, 0, 0, 0, 0, time.UTC)
+	plan := &mot.IndexRemediationPlan{
+		SchemaVersion: 1, GeneratedAt: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), HidePeriod: 7 * 24 * time.Hour,
+		Steps: []mot.IndexRemediationStep{
+			{Order: 1, Phase: mot.IndexRemediationHide, Namespace: "app.orders", IndexName: "legacy_1", FindingCode: "index.unused_candidate", FindingSummary: "unused", Command: "HIDE", Rollback: "UNHIDE"},
+			{Order: 2, Phase: mot.IndexRemediationDrop, Namespace: "app.orders", IndexName: "legacy_1", FindingCode: "index.unused_candidate", Command: "DROP", Rollback: "CREATE", NotBefore: &notBefore},
+		},
+	}
+
+	var output bytes.Buffer
+	if err := WriteIndexRemediationPlan(&output, plan, PlanFormatJS); err != nil {
+		t.Fatalf("WriteIndexRemediationPlan failed: %v", err)
+	}
+	for _, value := range []string{"// [1] hide app.orders legacy_1\n// finding: index.unused_candidate - unused\n// rollback: UNHIDE\nHIDE\n", "// notBefore: 2026-10-08T00:00:00Z\n// rollback: CREATE\n// DROP\n"} {
+		if !strings.Contains(output.String(), value) {
+			t.Fatalf("remediation script omitted %q:\n%s", value, output.String())
+		}
+	}
+	if err := WriteIndexRemediationPlan(&output, plan, "yaml"); err == nil {
+		t.Fatal("unknown plan format was accepted")
+	}
+}
+
 func TestBulkObserverDryRunFixture(t *testing.T) {
 	// 测试 bulk observer 的 dry-run summary 和完成提示。
 	withColorDisabled(t)
@@ -0,0 +1,69 @@
+package clioutput
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mot"
+)
+
+const (
+	PlanFormatJS   = "js"
+	PlanFormatJSON = "json"
+)
+
+// WriteIndexRemediationPlan 输出修复计划；js 格式为可审阅的 mongosh 脚本，dropIndexes 一律注释掉，需人工确认观察期后再启用。
+func WriteIndexRemediationPlan(w io.Writer, plan *mot.IndexRemediationPlan, format string) error {
+	switch format {
+	case PlanFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(plan)
+	case PlanFormatJS:
+		writeIndexRemediationScript(w, plan)
+		return nil
+	default:
+		return fmt.Errorf("plan format must be js or json")
+	}
+}
+
+func writeIndexRemediationScript(w io.Writer, plan *mot.IndexRemediationPlan) {
+	fmt.Fprintln(w, "// mot index-audit remediation plan")
+	fmt.Fprintf(w, "// generatedAt: %s, hidePeriod: %s\n", plan.GeneratedAt.UTC().Format(time.RFC3339), durationText(plan.HidePeriod))
+	fmt.Fprintln(w, "// 本脚本仅供审阅，不会被 mot 执行；dropIndexes 步骤默认注释，需确认观察期结束后手动启用。")
+	if len(plan.Steps) == 0 {
+		fmt.Fprintln(w, "// 无需修复的索引。")
+		return
+	}
+	for _, step := range plan.Steps {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "// [%d] %s %s %s\n", step.Order, step.Phase, step.Namespace, step.IndexName)
+		fmt.Fprintf(w, "// finding: %s", step.FindingCode)
+		if step.FindingSummary != "" {
+			fmt.Fprintf(w, " - %s", step.FindingSummary)
+		}
+		fmt.Fprintln(w)
+		if len(step.Shards) > 0 {
+			fmt.Fprintf(w, "// shards: %s\n", strings.Join(step.Shards, ","))
+		}
+		if step.NotBefore != nil {
+			fmt.Fprintf(w, "// notBefore: %s\n", step.NotBefore.UTC().Format(time.RFC3339))
+		}
+		if step.RequiresReview {
+			fmt.Fprintln(w, "// requiresReview: true")
+		}
+		for _, note := range step.Notes {
+			fmt.Fprintf(w, "// note: %s\n", note)
+		}
+		fmt.Fprintf(w, "// rollback: %s\n", step.Rollback)
+		if step.Phase == mot.IndexRemediationDrop || step.RequiresReview {
+			fmt.Fprintf(w, "// %s\n", step.Command)
+			continue
+		}
+		fmt.Fprintln(w, step.Command)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"go.mongodb.org/mongo-driver/bson"
@@ -25,7 +26,11 @@ func PrintSlowlogSummary(w io.Writer, result *mot.SlowlogSummaryResult, opts Slo
 		fmt.Fprintf(w, "\nReplSet: %s\n", color.GreenString(repl.Name))
 		fmt.Fprintln(w, "====================================")
 		for _, host := range repl.Hosts {
-			fmt.Fprintf(w, "Host: %s, State: %s\n", color.GreenString(host.Address), color.GreenString(host.State))
+			address := host.Address
+			if address == "" {
+				address = "-"
+			}
+			fmt.Fprintf(w, "Host: %s, State: %s\n", color.GreenString(address), color.GreenString(host.State))
 			for _, db := range host.Databases {
 				printSlowlogDatabase(w, db)
 			}
@@ -36,6 +41,60 @@ func PrintSlowlogSummary(w io.Writer, result *mot.SlowlogSummaryResult, opts Slo
 	return nil
 }
 
+// PrintMergedSlowlogSummary 输出跨 host/shard 合并后的集群级 top-N。
+func PrintMergedSlowlogSummary(w io.Writer, result *mot.MergedSlowlogSummary, opts SlowlogPrintOptions) error {
+	if result == nil {
+		return nil
+	}
+	PrintAhead(w, opts.URI)
+	width := 4
+	hashWidth := 12
+	for _, item := range result.Items {
+		if len(item.Namespace)+2 > width {
+			width = len(item.Namespace) + 2
+		}
+		if len(item.QueryHash)+2 > hashWidth {
+			hashWidth = len(item.QueryHash) + 2
+		}
+	}
+	fmt.Fprintf(w, "\nMerged: %s, Sort: %s\n", color.GreenString(string(result.Merge)), color.GreenString(string(result.Sort)))
+	fmt.Fprintf(w, "Total: %s TotalMills: %s\n\n", color.HiRedString("%d", result.Total), color.HiRedString("%d", result.TotalMillis))
+	fmt.Fprint(w, color.CyanString("%-*s%-*s%-10s%-8s%-10s%-10s%-12s%-12s%-16s%-18s%-18s\n",
+		width, "ns", hashWidth, "queryHash", "op", "count", "maxMills", "avgMills", "p95Mills", "time%", "plan", "shards", "hosts"))
+	fmt.Fprintf(w, "%-*s%-*s%-10s%-8s%-10s%-10s%-12s%-12s%-16s%-18s%-18s\n",
+		width, "--", hashWidth, "---------", "--", "-----", "--------", "--------", "--------", "-----", "----", "------", "-----")
+	hostMax := false
+	for _, item := range result.Items {
+		p95 := optionalInt(item.P95Millis)
+		if item.PercentileSource == mot.SlowlogPercentileHostMax {
+			p95 = "<=" + p95
+			hostMax = true
+		}
+		fmt.Fprintf(w, "%-*s%-*s%-10s%-8d%-10d%-10.1f%-12s%-12s%-16s%-18s%-18s\n",
+			width,
+			item.Namespace,
+			hashWidth,
+			item.QueryHash,
+			item.Operation,
+			item.Count,
+			item.MaxMillis,
+			item.AvgMillis,
+			p95,
+			optionalPercentText(item.TimeShare),
+			item.PlanSummary,
+			strings.Join(item.ReplicaSets, ","),
+			strings.Join(item.Hosts, ","),
+		)
+	}
+	if hostMax {
+		fmt.Fprintln(w, "\np95 marked <= is the highest per-host p95: some hosts only report server-side $percentile, so no cluster-wide histogram is available")
+	}
+	fmt.Fprintln(w)
+	printFindings(w, result.Findings)
+	printStatuses(w, result.CollectorStatuses)
+	return nil
+}
+
 func PrintSlowlogDetail(w io.Writer, result *mot.SlowlogDetailResult, opts SlowlogPrintOptions) error {
 	if result == nil {
 		return nil
@@ -58,9 +117,38 @@ func PrintSlowlogDetail(w io.Writer, result *mot.SlowlogDetailResult, opts Slowl
 		return err
 	}
 	fmt.Fprintln(w, string(payload))
+	if result.Explain != nil {
+		printSlowlogExplain(w, result.Explain)
+	}
 	return nil
 }
 
+func printSlowlogExplain(w io.Writer, explain *mot.SlowlogExplain) {
+	fmt.Fprintln(w)
+	color.New(color.FgGreen).Fprintf(w, "explain (%s):\n", explain.Verbosity)
+	fmt.Fprintln(w, "--------")
+	printExplainPlan(w, "winning", explain.WinningPlan)
+	for i, plan := range explain.RejectedPlans {
+		printExplainPlan(w, fmt.Sprintf("rejected#%d", i+1), plan)
+	}
+	if stats := explain.ExecutionStats; stats != nil {
+		fmt.Fprintf(w, "executionStats: nReturned=%d keysExamined=%d docsExamined=%d fetchRatio=%s timeMillis=%d\n",
+			stats.NReturned, stats.TotalKeysExamined, stats.TotalDocsExamined, optionalRatioText(stats.FetchRatio), stats.ExecutionTimeMillis)
+	}
+	printFindings(w, explain.Findings)
+}
+
+func printExplainPlan(w io.Writer, label string, plan mot.ExplainPlan) {
+	fmt.Fprintf(w, "%s: %s (inMemorySort=%t, collscan=%t)\n", label, strings.Join(plan.Stages, " > "), plan.InMemorySort, plan.CollectionScan)
+	for _, scan := range plan.IndexScans {
+		bounds := make([]string, 0, len(scan.Bounds))
+		for _, bound := range scan.Bounds {
+			bounds = append(bounds, fmt.Sprintf("%s:%s(%d)", bound.Field, bound.Kind, bound.Intervals))
+		}
+		fmt.Fprintf(w, "  index %s {%s} bounds: %s\n", scan.IndexName, indexKeyText(scan.Key), strings.Join(bounds, ", "))
+	}
+}
+
 func printSlowlogDatabase(w io.Writer, db mot.DatabaseSlowlogSummary) {
 	width := 4
 	hashWidth := 12
@@ -77,12 +165,12 @@ func printSlowlogDatabase(w io.Writer, db mot.DatabaseSlowlogSummary) {
 		color.HiRedString("%d", db.Total),
 		color.GreenString(timeutil.FormatLayoutString(db.FirstTime)),
 		color.GreenString(timeutil.FormatLayoutString(db.LastTime)))
-	fmt.Fprint(w, color.CyanString("%-*s%-*s%-10s%-6s%-10s%-10s%-10s%-16s%-12s%-12s%-6s%-9s%-18s%-22s%-22s\n",
-		width, "ns", hashWidth, "queryHash", "op", "count", "maxMills", "minMills", "maxDocs", "plan", "docs/ret", "keys/ret", "err", "collscan", "apps", "firstTs", "lastTs"))
-	fmt.Fprintf(w, "%-*s%-*s%-10s%-6s%-10s%-10s%-10s%-16s%-12s%-12s%-6s%-9s%-18s%-22s%-22s\n",
-		width, "--", hashWidth, "---------", "--", "-----", "--------", "--------", "-------", "----", "--------", "--------", "---", "--------", "----", "-------", "------")
+	fmt.Fprint(w, color.CyanString("%-*s%-*s%-10s%-6s%-10s%-10s%-10s%-12s%-12s%-12s%-10s%-16s%-12s%-12s%-6s%-9s%-18s%-22s%-22s\n",
+		width, "ns", hashWidth, "queryHash", "op", "count", "maxMills", "minMills", "avgMills", "p95Mills", "p99Mills", "time%", "maxDocs", "plan", "docs/ret", "keys/ret", "err", "collscan", "apps", "firstTs", "lastTs"))
+	fmt.Fprintf(w, "%-*s%-*s%-10s%-6s%-10s%-10s%-10s%-12s%-12s%-12s%-10s%-16s%-12s%-12s%-6s%-9s%-18s%-22s%-22s\n",
+		width, "--", hashWidth, "---------", "--", "-----", "--------", "--------", "--------", "--------", "--------", "-----", "-------", "----", "--------", "--------", "---", "--------", "----", "-------", "------")
 	for _, item := range db.Items {
-		fmt.Fprintf(w, "%-*s%-*s%-10s%-6d%-10d%-10d%-10d%-16s%-12s%-12s%-6d%-9d%-18s%-22s%-22s\n",
+		fmt.Fprintf(w, "%-*s%-*s%-10s%-6d%-10d%-10d%-10.1f%-12s%-12s%-12s%-10d%-16s%-12s%-12s%-6d%-9d%-18s%-22s%-22s\n",
 			width,
 			item.Namespace,
 			hashWidth,
@@ -91,6 +179,10 @@ func printSlowlogDatabase(w io.Writer, db mot.DatabaseSlowlogSummary) {
 			item.Count,
 			item.MaxMillis,
 			item.MinMillis,
+			item.AvgMillis,
+			optionalInt(item.P95Millis),
+			optionalInt(item.P99Millis),
+			optionalPercentText(item.TimeShare),
 			item.MaxDocs,
 			item.PlanSummary,
 			optionalRatioText(item.WorstDocsToReturned),
@@ -105,6 +197,120 @@ func printSlowlogDatabase(w io.Writer, db mot.DatabaseSlowlogSummary) {
 	fmt.Fprintln(w)
 }
 
+const slowlogDigestBarWidth = 40
+
+// PrintSlowlogDigest 输出 pt-query-digest 风格的文本报告：全局时间窗与节点分布，随后每个排名查询形状一个块。
+func PrintSlowlogDigest(w io.Writer, result *mot.SlowlogDigestResult) error {
+	if result == nil {
+		return nil
+	}
+	fmt.Fprintf(w, "# Slowlog digest (%s)\n", result.ClusterType)
+	fmt.Fprintf(w, "# Window: %s ~ %s\n", optionalTimeText(result.Since), optionalTimeText(result.Until))
+	fmt.Fprintf(w, "# Observed: [%s]~[%s]\n", timeutil.FormatLayoutString(result.FirstTime), timeutil.FormatLayoutString(result.LastTime))
+	fmt.Fprintf(w, "# Overall: %d slow ops, %d unique shapes, total %s\n", result.Total, result.UniqueShapes, millisText(result.TotalMillis))
+	fmt.Fprintln(w, "# Hosts:")
+	for _, host := range result.Hosts {
+		fmt.Fprintf(w, "#   %-16s %-24s %8d ops %12s %6s\n", host.ReplicaSet, host.Host, host.Count, millisText(host.TotalMillis), shareText(host.TotalMillis, result.TotalMillis))
+	}
+	for _, query := range result.Queries {
+		fmt.Fprintln(w, "#")
+		fmt.Fprintf(w, "# Query %d: %s of response time, %d calls, ID %s\n", query.Rank, optionalPercentText(query.TimeShare), query.Count, query.QueryHash)
+		fmt.Fprintf(w, "# Namespace:   %s\n", query.Namespace)
+		fmt.Fprintf(w, "# Operation:   %s\n", query.Operation)
+		fmt.Fprintf(w, "# Plan:        %s\n", query.PlanSummary)
+		fmt.Fprintf(w, "# Apps:        %s\n", strings.Join(query.AppNames, ","))
+		fmt.Fprintf(w, "# ReplSets:    %s\n", strings.Join(query.ReplicaSets, ","))
+		fmt.Fprintf(w, "# Hosts:       %s\n", strings.Join(query.Hosts, ","))
+		fmt.Fprintf(w, "# Time range:  [%s]~[%s]\n", timeutil.FormatLayoutString(query.FirstTime), timeutil.FormatLayoutString(query.LastTime))
+		fmt.Fprintf(w, "# Exec time:   total %s, avg %.1fms, min %dms, max %dms\n", millisText(query.TotalMillis), query.AvgMillis, query.MinMillis, query.MaxMillis)
+		fmt.Fprintf(w, "# Examined:    maxDocs %s, maxKeys %s, maxReturned %s, docs/returned %s, keys/returned %s\n",
+			optionalInt(query.MaxDocsExamined), optionalInt(query.MaxKeysExamined), optionalInt(query.MaxDocsReturned),
+			optionalRatioText(query.WorstDocsToReturned), optionalRatioText(query.WorstKeysToReturned))
+		fmt.Fprintf(w, "# Errors:      %d, collscan %d, in-memory sort %d\n", query.ErrorCount, query.CollectionScanCount, query.SortStageCount)
+		fmt.Fprintf(w, "# Percentiles: p50 %s, p95 %s, p99 %s\n", optionalMillisText(query.P50Millis), optionalMillisText(query.P95Millis), optionalMillisText(query.P99Millis))
+		printSlowlogLatencyHistogram(w, query.LatencyHistogram)
+		if query.ExampleCommand != "" {
+			fmt.Fprintln(w, "# Example (redacted):")
+			fmt.Fprintf(w, "#   %s\n", query.ExampleCommand)
+		}
+	}
+	fmt.Fprintln(w)
+	printFindings(w, result.Findings)
+	printStatuses(w, result.CollectorStatuses)
+	return nil
+}
+
+// printSlowlogLatencyHistogram 按十倍程区间输出次数柱状图；来源没有 bucket 时说明分布不可用。
+func printSlowlogLatencyHistogram(w io.Writer, buckets []mot.SlowlogLatencyBucket) {
+	if len(buckets) == 0 {
+		fmt.Fprintln(w, "# Latency distribution: unavailable (no histogram buckets for this source)")
+		return
+	}
+	fmt.Fprintln(w, "# Latency distribution:")
+	var maximum int64
+	for _, bucket := range buckets {
+		maximum = max(maximum, bucket.Count)
+	}
+	for _, bucket := range buckets {
+		fmt.Fprintf(w, "#   %-6s %8d  %s\n", latencyBucketLabel(bucket), bucket.Count, latencyBar(bucket.Count, maximum))
+	}
+}
+
+func latencyBucketLabel(bucket mot.SlowlogLatencyBucket) string {
+	label := fmt.Sprintf("%dms", bucket.LowerMillis)
+	switch {
+	case bucket.LowerMillis == 0 && bucket.UpperMillis > 0:
+		return fmt.Sprintf("<%dms", bucket.UpperMillis)
+	case bucket.LowerMillis >= 1000 && bucket.LowerMillis%1000 == 0:
+		label = fmt.Sprintf("%ds", bucket.LowerMillis/1000)
+	}
+	if bucket.UpperMillis == 0 {
+		label += "+"
+	}
+	return label
+}
+
+func optionalMillisText(value *int64) string {
+	if value == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%dms", *value)
+}
+
+// latencyBar 按最大值线性缩放柱长，非零值至少显示一格。
+func latencyBar(value, maximum int64) string {
+	if value <= 0 || maximum <= 0 {
+		return ""
+	}
+	width := int((value*slowlogDigestBarWidth + maximum - 1) / maximum)
+	return strings.Repeat("#", min(max(width, 1), slowlogDigestBarWidth))
+}
+
+func millisText(value int64) string {
+	return fmt.Sprintf("%.2fs", float64(value)/1000)
+}
+
+func shareText(part, total int64) string {
+	if total <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(part)/float64(total)*100)
+}
+
+func optionalTimeText(value *time.Time) string {
+	if value == nil {
+		return "-"
+	}
+	return timeutil.FormatLayoutString(*value)
+}
+
+func optionalPercentText(value *float64) string {
+	if value == nil {
+		return "unavailable"
+	}
+	return fmt.Sprintf("%.1f%%", *value*100)
+}
+
 func optionalRatioText(value *float64) string {
 	if value == nil {
 		return "unavailable"
@@ -43,6 +43,16 @@ type StatsConfig struct {
 	Collection string
 }
 
+// SlowlogFilterConfig 是 slowlog 概览与 advise 共用的时间窗和维度过滤；每个命令各自持有一份。
+type SlowlogFilterConfig struct {
+	Since      string // 相对时长（如 1h）或 RFC3339 时间，作为时间窗下界
+	Until      string // 相对时长或 RFC3339 时间，作为时间窗上界（不含）
+	Namespaces string // 逗号分隔的 db.collection
+	Operations string // 逗号分隔的 profiler op
+	AppNames   string // 逗号分隔的 appName
+	MinMillis  int64
+}
+
 type SlowlogConfig struct {
 	BaseCfg
 
@@ -51,6 +61,18 @@ type SlowlogConfig struct {
 	DB        string
 	Sort      string
 	QueryHash string
+	FromLog   string // 逗号分隔的 mongod 日志文件，设置后离线解析且不连接 MongoDB
+	GetLog    bool   // 额外读取各成员 getLog 内存缓冲
+
+	SlowlogFilterConfig
+	Merge string // 结果合并粒度，cluster 表示跨 host/shard 折叠同一查询形状
+
+	Explain        bool // 详情模式下在同一节点重放 queryPlanner explain
+	ExecutionStats bool // explain 使用 executionStats，会真实执行查询
+	PlanCache      bool // 详情模式下改为检查各数据节点 $planCacheStats 中该查询形状的缓存计划
+
+	Snapshot   string // 概览模式下把脱敏的聚合结果写入该本地路径，供 slowlog diff 离线比较
+	MaxQueries int    // --format digest 报告中的查询形状数量上限
 }
 
 type BulkConfig struct {
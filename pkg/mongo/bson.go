@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
 )
 
 // ---------- 预编译正则，避免每次调用重复编译 ----------
@@ -301,3 +302,18 @@ func ParseBsonDoc(jsonStr string) (bson.D, error) {
 	}
 	return result, nil
 }
+
+// DecodeOrderedM 把 raw 文档解码为顶层 bson.M，嵌套文档保留为 bson.D。
+// sort、索引 key 等字段的语义依赖 key 顺序，默认的嵌套 bson.M 会丢失该顺序。
+func DecodeOrderedM(raw bson.Raw) (bson.M, error) {
+	decoder, err := bson.NewDecoder(bsonrw.NewBSONDocumentReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	decoder.DefaultDocumentD()
+	var result bson.M
+	if err := decoder.Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -151,84 +151,15 @@ func (c *Conn) DBStatus(ctx context.Context, db string) (result DBStats, err err
 }
 
 func (c *Conn) GetSlowLogView(ctx context.Context, db, sort string) (result []*SlowlogView, err error) {
-	errorCondition := bson.D{{Key: "$or", Value: bson.A{
-		bson.D{{Key: "$ne", Value: bson.A{"$errCode", nil}}},
-		bson.D{{Key: "$ne", Value: bson.A{"$errName", nil}}},
-	}}}
-	errorCountExpression := bson.D{
-		{Key: "$sum", Value: bson.D{
-			{Key: "$cond", Value: bson.A{errorCondition, int64(1), int64(0)}},
-		}},
-	}
-	collectionScanCondition := bson.D{{Key: "$eq", Value: bson.A{"$planSummary", "COLLSCAN"}}}
-	collectionScanExpression := bson.D{
-		{Key: "$sum", Value: bson.D{
-			{Key: "$cond", Value: bson.A{collectionScanCondition, int64(1), int64(0)}},
-		}},
-	}
-	agg := bson.A{
-		bson.D{
-			{Key: "$group",
-				Value: bson.D{
-					{Key: "_id",
-						Value: bson.D{
-							{Key: "ns", Value: "$ns"},
-							{Key: "queryHash", Value: "$queryHash"},
-							{Key: "op", Value: "$op"},
-							{Key: "planSummary", Value: "$planSummary"},
-						},
-					},
-					{Key: "ns", Value: bson.D{{Key: "$first", Value: "$ns"}}},
-					{Key: "op", Value: bson.D{{Key: "$first", Value: "$op"}}},
-					{Key: "queryHash", Value: bson.D{{Key: "$first", Value: "$queryHash"}}},
-					{Key: "planSummary", Value: bson.D{{Key: "$first", Value: "$planSummary"}}},
-					{Key: "cnt", Value: bson.D{{Key: "$sum", Value: 1}}},
-					{Key: "maxMills", Value: bson.D{{Key: "$max", Value: "$millis"}}},
-					{Key: "minMills", Value: bson.D{{Key: "$min", Value: "$millis"}}},
-					{Key: "maxDocs", Value: bson.D{{Key: "$max", Value: "$docsExamined"}}},
-					{Key: "maxKeysExamined", Value: bson.D{{Key: "$max", Value: "$keysExamined"}}},
-					{Key: "maxDocsExamined", Value: bson.D{{Key: "$max", Value: "$docsExamined"}}},
-					{Key: "maxDocsReturned", Value: bson.D{{Key: "$max", Value: "$nreturned"}}},
-					{Key: "maxPlanningMicros", Value: bson.D{{Key: "$max", Value: "$planningTimeMicros"}}},
-					{Key: "maxCpuNanos", Value: bson.D{{Key: "$max", Value: "$cpuNanos"}}},
-					{Key: "appNames", Value: bson.D{{Key: "$addToSet", Value: "$appName"}}},
-					{Key: "errorCount", Value: errorCountExpression},
-					{Key: "collectionScanCount", Value: collectionScanExpression},
-					{Key: "maxTs", Value: bson.D{{Key: "$max", Value: "$ts"}}},
-					{Key: "minTs", Value: bson.D{{Key: "$min", Value: "$ts"}}},
-				},
-			},
-		},
-		bson.D{{Key: "$sort", Value: bson.D{{Key: sort, Value: -1}}}},
-		bson.D{
-			{Key: "$project",
-				Value: bson.D{
-					{Key: "_id", Value: 0},
-					{Key: "ns", Value: 1},
-					{Key: "op", Value: 1},
-					{Key: "queryHash", Value: 1},
-					{Key: "planSummary", Value: 1},
-					{Key: "cnt", Value: 1},
-					{Key: "maxMills", Value: 1},
-					{Key: "minMills", Value: 1},
-					{Key: "maxDocs", Value: 1},
-					{Key: "maxKeysExamined", Value: 1},
-					{Key: "maxDocsExamined", Value: 1},
-					{Key: "maxDocsReturned", Value: 1},
-					{Key: "maxPlanningMicros", Value: 1},
-					{Key: "maxCpuNanos", Value: 1},
-					{Key: "appNames", Value: 1},
-					{Key: "errorCount", Value: 1},
-					{Key: "collectionScanCount", Value: 1},
-					{Key: "maxTs", Value: 1},
-					{Key: "minTs", Value: 1},
-				},
-			},
-		},
-	}
+	return c.GetSlowLogViewFiltered(ctx, db, sort, SlowlogFilter{}, false)
+}
 
+// GetSlowLogViewFiltered 在 $group 之前下推 $match，只聚合时间窗与维度过滤后的 profile 记录。
+// 服务端总是先按耗时分桶再二次 $group，返回有界 histogram 供跨节点合并与 digest 分布使用；nativePercentile 为 true 时（7.0+）
+// 另以 $setWindowFields + $percentile 计算 p50/p95/p99，否则由客户端基于 histogram 估算。
+func (c *Conn) GetSlowLogViewFiltered(ctx context.Context, db, sort string, filter SlowlogFilter, nativePercentile bool) (result []*SlowlogView, err error) {
 	cur, err := c.Client.Database(db).Collection("system.profile").
-		Aggregate(ctx, agg)
+		Aggregate(ctx, slowlogViewPipeline(sort, filter, nativePercentile))
 	if err != nil {
 		return nil, err
 	}
@@ -241,17 +172,139 @@ func (c *Conn) GetSlowLogView(ctx context.Context, db, sort string) (result []*S
 		if r.QueryHash == "" {
 			r.QueryHash = legacySlowlogID(r.Ns, r.Op, r.PlanSummary)
 		}
+		finalizeSlowlogView(r)
 	}
+	sortSlowlogViews(result, sort)
 	return
 }
 
-func (c *Conn) GetSlowDetail(ctx context.Context, db, hash string) (result bson.M, err error) {
+type slowlogAccumulator struct {
+	name     string
+	operator string
+	value    any
+}
+
+func slowlogViewPipeline(sort string, filter SlowlogFilter, nativePercentile bool) bson.A {
+	errorCondition := bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "$ne", Value: bson.A{"$errCode", nil}}},
+		bson.D{{Key: "$ne", Value: bson.A{"$errName", nil}}},
+	}}}
+	collectionScanCondition := bson.D{{Key: "$eq", Value: bson.A{"$planSummary", "COLLSCAN"}}}
+	sortStageCondition := bson.D{{Key: "$eq", Value: bson.A{"$hasSortStage", true}}}
+	accumulators := []slowlogAccumulator{
+		{name: "ns", operator: "$first", value: "$ns"},
+		{name: "op", operator: "$first", value: "$op"},
+		{name: "queryHash", operator: "$first", value: "$queryHash"},
+		{name: "planSummary", operator: "$first", value: "$planSummary"},
+		{name: "cnt", operator: "$sum", value: 1},
+		{name: "maxMills", operator: "$max", value: "$millis"},
+		{name: "minMills", operator: "$min", value: "$millis"},
+		{name: "totalMillis", operator: "$sum", value: "$millis"},
+		{name: "maxDocs", operator: "$max", value: "$docsExamined"},
+		{name: "maxKeysExamined", operator: "$max", value: "$keysExamined"},
+		{name: "maxDocsExamined", operator: "$max", value: "$docsExamined"},
+		{name: "maxDocsReturned", operator: "$max", value: "$nreturned"},
+		{name: "maxPlanningMicros", operator: "$max", value: "$planningTimeMicros"},
+		{name: "maxCpuNanos", operator: "$max", value: "$cpuNanos"},
+		{name: "appNames", operator: "$addToSet", value: "$appName"},
+		{name: "errorCount", operator: "$sum", value: bson.D{{Key: "$cond", Value: bson.A{errorCondition, int64(1), int64(0)}}}},
+		{name: "collectionScanCount", operator: "$sum", value: bson.D{{Key: "$cond", Value: bson.A{collectionScanCondition, int64(1), int64(0)}}}},
+		{name: "sortStageCount", operator: "$sum", value: bson.D{{Key: "$cond", Value: bson.A{sortStageCondition, int64(1), int64(0)}}}},
+		{name: "maxTs", operator: "$max", value: "$ts"},
+		{name: "minTs", operator: "$min", value: "$ts"},
+	}
+	groupKey := bson.D{
+		{Key: "ns", Value: "$ns"},
+		{Key: "queryHash", Value: "$queryHash"},
+		{Key: "op", Value: "$op"},
+		{Key: "planSummary", Value: "$planSummary"},
+	}
+
+	agg := bson.A{}
+	if match := filter.matchStage(); len(match) > 0 {
+		agg = append(agg, bson.D{{Key: "$match", Value: match}})
+	}
+	bucketKey := append(bson.D{}, groupKey...)
+	bucketKey = append(bucketKey, bson.E{Key: "bucket", Value: slowlogMillisBucketExpression()})
+	buckets := bson.D{{Key: "_id", Value: bucketKey}}
+	merged := bson.D{{Key: "_id", Value: bson.D{
+		{Key: "ns", Value: "$_id.ns"},
+		{Key: "queryHash", Value: "$_id.queryHash"},
+		{Key: "op", Value: "$_id.op"},
+		{Key: "planSummary", Value: "$_id.planSummary"},
+	}}}
+	for _, accumulator := range accumulators {
+		buckets = append(buckets, bson.E{Key: accumulator.name, Value: bson.D{{Key: accumulator.operator, Value: accumulator.value}}})
+		switch accumulator.operator {
+		case "$addToSet":
+			merged = append(merged, bson.E{Key: "appNameSets", Value: bson.D{{Key: "$push", Value: "$" + accumulator.name}}})
+		case "$first", "$max", "$min":
+			merged = append(merged, bson.E{Key: accumulator.name, Value: bson.D{{Key: accumulator.operator, Value: "$" + accumulator.name}}})
+		default:
+			merged = append(merged, bson.E{Key: accumulator.name, Value: bson.D{{Key: "$sum", Value: "$" + accumulator.name}}})
+		}
+	}
+	if nativePercentile {
+		// $percentile 作为窗口函数按形状分区计算，分桶 $group 照常执行，7.0+ 同时得到原生分位与可合并的 histogram。
+		agg = append(agg, bson.D{{Key: "$setWindowFields", Value: bson.D{
+			{Key: "partitionBy", Value: groupKey},
+			{Key: "output", Value: bson.D{{Key: "millisPercentiles", Value: bson.D{{Key: "$percentile", Value: bson.D{
+				{Key: "input", Value: "$millis"},
+				{Key: "p", Value: bson.A{0.5, 0.95, 0.99}},
+				{Key: "method", Value: "approximate"},
+			}}}}}},
+		}}})
+		buckets = append(buckets, bson.E{Key: "millisPercentiles", Value: bson.D{{Key: "$first", Value: "$millisPercentiles"}}})
+		merged = append(merged, bson.E{Key: "millisPercentiles", Value: bson.D{{Key: "$first", Value: "$millisPercentiles"}}})
+	}
+	merged = append(merged, bson.E{Key: "millisHistogram", Value: bson.D{{Key: "$push", Value: bson.D{
+		{Key: "bucket", Value: "$_id.bucket"},
+		{Key: "count", Value: "$cnt"},
+	}}}})
+	agg = append(agg, bson.D{{Key: "$group", Value: buckets}}, bson.D{{Key: "$group", Value: merged}})
+
+	serverSort := sort
+	if sort == "p95" {
+		serverSort = "maxMills"
+	}
+	project := bson.D{{Key: "_id", Value: 0}}
+	for _, accumulator := range accumulators {
+		project = append(project, bson.E{Key: accumulator.name, Value: 1})
+	}
+	project = append(project,
+		bson.E{Key: "appNameSets", Value: 1},
+		bson.E{Key: "millisHistogram", Value: 1},
+		bson.E{Key: "millisPercentiles", Value: 1},
+	)
+	return append(agg,
+		bson.D{{Key: "$sort", Value: bson.D{{Key: serverSort, Value: -1}}}},
+		bson.D{{Key: "$project", Value: project}},
+	)
+}
+
+func (c *Conn) GetSlowDetail(ctx context.Context, db, hash string) (bson.M, error) {
+	raw, err := c.GetSlowDetailRaw(ctx, db, hash)
+	if err != nil {
+		return nil, err
+	}
+	var result bson.M
+	if err := bson.Unmarshal(raw, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetSlowDetailRaw 返回未解码的 profile 文档，调用方需要保留嵌套字段顺序时自行用 DecodeOrderedM 解码。
+func (c *Conn) GetSlowDetailRaw(ctx context.Context, db, hash string) (bson.Raw, error) {
 	profile := c.Client.Database(db).Collection("system.profile")
-	err = profile.
+	raw, err := profile.
 		FindOne(ctx, bson.M{"queryHash": hash}, options.FindOne().SetSort(bson.M{"ts": -1})).
-		Decode(&result)
-	if err == nil || !errors.Is(err, mongo.ErrNoDocuments) || !strings.HasPrefix(hash, legacySlowlogPrefix) {
-		return result, err
+		DecodeBytes()
+	if err == nil {
+		return raw, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) || !strings.HasPrefix(hash, legacySlowlogPrefix) {
+		return nil, err
 	}
 
 	cur, err := profile.Find(ctx, bson.D{{Key: "queryHash", Value: nil}}, options.Find().SetSort(bson.D{{Key: "ts", Value: -1}}))
@@ -261,11 +314,11 @@ func (c *Conn) GetSlowDetail(ctx context.Context, db, hash string) (result bson.
 	defer closeMongoCursor(ctx, cur)
 	for cur.Next(ctx) {
 		var candidate bson.M
-		if err := cur.Decode(&candidate); err != nil {
+		if err := bson.Unmarshal(cur.Current, &candidate); err != nil {
 			return nil, err
 		}
 		if legacySlowlogDocumentID(candidate) == hash {
-			return candidate, nil
+			return append(bson.Raw(nil), cur.Current...), nil
 		}
 	}
 	if err := cur.Err(); err != nil {
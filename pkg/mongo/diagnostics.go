@@ -59,22 +59,23 @@ func (c *Conn) OplogWindow(ctx context.Context, maxTime time.Duration) (OplogWin
 
 // CurrentOperationSnapshot 是从 $currentOp 投影得到的安全字段集合。
 type CurrentOperationSnapshot struct {
-	Host                  string `bson:"host" json:"host,omitempty"`
-	Shard                 string `bson:"shard" json:"shard,omitempty"`
-	Namespace             string `bson:"ns" json:"namespace,omitempty"`
-	Operation             string `bson:"op" json:"operation,omitempty"`
-	AppName               string `bson:"appName" json:"appName,omitempty"`
-	QueryHash             string `bson:"queryHash" json:"queryHash,omitempty"`
-	PlanSummary           string `bson:"planSummary" json:"planSummary,omitempty"`
-	SecondsRunning        *int64 `bson:"secsRunning" json:"secondsRunning,omitempty"`
-	WaitingForLock        bool   `bson:"waitingForLock" json:"waitingForLock"`
-	WaitingForFlowControl bool   `bson:"waitingForFlowControl" json:"waitingForFlowControl"`
-	KillPending           bool   `bson:"killPending" json:"killPending"`
-	TransactionActive     bool   `bson:"transactionActive" json:"transactionActive"`
-	TransactionMicros     *int64 `bson:"transactionMicros" json:"transactionMicros,omitempty"`
-	Message               string `bson:"message" json:"message,omitempty"`
-	ProgressDone          *int64 `bson:"progressDone" json:"progressDone,omitempty"`
-	ProgressTotal         *int64 `bson:"progressTotal" json:"progressTotal,omitempty"`
+	Host                  string   `bson:"host" json:"host,omitempty"`
+	Shard                 string   `bson:"shard" json:"shard,omitempty"`
+	Namespace             string   `bson:"ns" json:"namespace,omitempty"`
+	Operation             string   `bson:"op" json:"operation,omitempty"`
+	AppName               string   `bson:"appName" json:"appName,omitempty"`
+	QueryHash             string   `bson:"queryHash" json:"queryHash,omitempty"`
+	PlanSummary           string   `bson:"planSummary" json:"planSummary,omitempty"`
+	SecondsRunning        *int64   `bson:"secsRunning" json:"secondsRunning,omitempty"`
+	WaitingForLock        bool     `bson:"waitingForLock" json:"waitingForLock"`
+	WaitingForFlowControl bool     `bson:"waitingForFlowControl" json:"waitingForFlowControl"`
+	KillPending           bool     `bson:"killPending" json:"killPending"`
+	TransactionActive     bool     `bson:"transactionActive" json:"transactionActive"`
+	TransactionMicros     *int64   `bson:"transactionMicros" json:"transactionMicros,omitempty"`
+	Message               string   `bson:"message" json:"message,omitempty"`
+	ProgressDone          *int64   `bson:"progressDone" json:"progressDone,omitempty"`
+	ProgressTotal         *int64   `bson:"progressTotal" json:"progressTotal,omitempty"`
+	IndexNames            []string `bson:"indexNames" json:"indexNames,omitempty"`
 }
 
 // CurrentOperations 优先使用 $currentOp aggregation 并在服务端完成过滤与投影。
@@ -194,3 +194,103 @@ func TestDecodeServerStatusVersionFixturesPreserveOptionalMetrics(t *testing.T)
 		})
 	}
 }
+
+func TestDecodeCollectionLatencySnapshotKeepsOnlyCounters(t *testing.T) {
+	// 场景：latencyStats 只解码累计延迟、ops 与 histogram，bucket 按下界升序且数值类型兼容。
+	payload, err := bson.Marshal(bson.D{
+		{Key: "ns", Value: "db.c"},
+		{Key: "latencyStats", Value: bson.D{
+			{Key: "reads", Value: bson.D{
+				{Key: "histogram", Value: bson.A{
+					bson.D{{Key: "micros", Value: int64(1024)}, {Key: "count", Value: int32(2)}},
+					bson.D{{Key: "micros", Value: int64(64)}, {Key: "count", Value: int64(5)}},
+				}},
+				{Key: "latency", Value: int64(3000)},
+				{Key: "ops", Value: int64(7)},
+			}},
+			{Key: "writes", Value: bson.D{{Key: "latency", Value: int64(0)}, {Key: "ops", Value: int64(0)}}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := decodeCollectionLatencySnapshot(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Namespace != "db.c" || snapshot.Reads.Ops != 7 || snapshot.Reads.LatencyMicros != 3000 {
+		t.Fatalf("snapshot = %#v", snapshot)
+	}
+	if len(snapshot.Reads.Histogram) != 2 || snapshot.Reads.Histogram[0].LowerBoundMicros != 64 || snapshot.Reads.Histogram[1].Count != 2 {
+		t.Fatalf("histogram = %#v", snapshot.Reads.Histogram)
+	}
+	if snapshot.Commands.Ops != 0 || len(snapshot.Commands.Histogram) != 0 {
+		t.Fatalf("missing commands = %#v, want zero value", snapshot.Commands)
+	}
+}
+
+func TestDecodeTTLMonitorStatusAndParameters(t *testing.T) {
+	// 场景：serverStatus 的 uptime 为 double、ttl 计数为 long；旧版本 repl.ismaster 与新版本 isWritablePrimary 都能识别 primary。
+	payload, err := bson.Marshal(bson.D{
+		{Key: "uptime", Value: float64(7200)},
+		{Key: "repl", Value: bson.D{{Key: "ismaster", Value: true}}},
+		{Key: "metrics", Value: bson.D{{Key: "ttl", Value: bson.D{{Key: "passes", Value: int64(3)}, {Key: "deletedDocuments", Value: int64(42)}}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := decodeTTLMonitorStatus(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !snapshot.Primary || snapshot.UptimeSeconds == nil || *snapshot.UptimeSeconds != 7200 || snapshot.Passes == nil || *snapshot.Passes != 3 || *snapshot.DeletedDocuments != 42 {
+		t.Fatalf("snapshot = %#v", snapshot)
+	}
+	parameters, err := bson.Marshal(bson.D{{Key: "ttlMonitorEnabled", Value: false}, {Key: "ttlMonitorSleepSecs", Value: int32(120)}, {Key: "ok", Value: float64(1)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	applyTTLMonitorParameters(&snapshot, parameters)
+	if snapshot.Enabled == nil || *snapshot.Enabled || snapshot.SleepSeconds == nil || *snapshot.SleepSeconds != 120 {
+		t.Fatalf("parameters = %#v", snapshot)
+	}
+	pipeline := fieldTypePipeline("expireAt", 1000)
+	payload, err = bson.MarshalExtJSON(bson.D{{Key: "pipeline", Value: pipeline}}, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, required := range []string{`{"$isArray":"$expireAt"}`, `"$anyElementTrue"`, `"arrayWithDate"`, `{"$type":"$expireAt"}`} {
+		if len(pipeline) != 2 || !strings.Contains(string(payload), required) {
+			t.Fatalf("pipeline missing %q: %s", required, payload)
+		}
+	}
+}
+
+func TestIndexBuildOperationsProjectOnlyNamesAndProgress(t *testing.T) {
+	// 场景：索引构建 pipeline 只投影 command 中的集合名与索引名，不投影 key 或构建选项；旧版本 <db>.$cmd 还原为目标集合后再按 namespace 过滤。
+	pipeline := buildIndexBuildOperationsPipeline(CurrentOperationsQuery{AllUsers: true, Databases: []string{"app"}})
+	payload, err := bson.MarshalExtJSON(bson.D{{Key: "pipeline", Value: pipeline}}, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(payload)
+	for _, forbidden := range []string{"command.indexes.key", "partialFilterExpression", "client", "effectiveUsers"} {
+		if strings.Contains(text, forbidden) {
+			t.Fatalf("pipeline contains forbidden field %q: %s", forbidden, text)
+		}
+	}
+	for _, required := range []string{"command.createIndexes", "^Index Build", "$command.indexes.name", "$progress.done"} {
+		if !strings.Contains(text, required) {
+			t.Fatalf("pipeline missing %q: %s", required, text)
+		}
+	}
+
+	operations := []indexBuildOperation{
+		{CurrentOperationSnapshot: CurrentOperationSnapshot{Namespace: "app.$cmd", IndexNames: []string{"tenant_1"}}, CreateIndexes: "orders"},
+		{CurrentOperationSnapshot: CurrentOperationSnapshot{Namespace: "app.events", Message: "Index Build: scanning collection"}},
+	}
+	got := filterIndexBuildOperations(operations, CurrentOperationsQuery{Namespaces: []string{"app.orders"}})
+	if len(got) != 1 || got[0].Namespace != "app.orders" || got[0].IndexNames[0] != "tenant_1" {
+		t.Fatalf("operations = %#v", got)
+	}
+}
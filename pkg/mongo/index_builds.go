@@ -0,0 +1,131 @@
+package mongo
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	drivermongo "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indexBuildOperation 额外保留 createIndexes 目标集合，用于修正旧版本 ns 为 <db>.$cmd 的情况。
+type indexBuildOperation struct {
+	CurrentOperationSnapshot `bson:",inline"`
+	CreateIndexes            string `bson:"createIndexes"`
+}
+
+// IndexBuildOperations 通过 $currentOp 读取正在进行的索引构建，只投影进度字段与索引名，不包含 key 或构建选项。
+func (c *Conn) IndexBuildOperations(ctx context.Context, query CurrentOperationsQuery) ([]CurrentOperationSnapshot, error) {
+	aggregateOptions := options.Aggregate()
+	if query.MaxTime > 0 {
+		aggregateOptions.SetMaxTime(query.MaxTime)
+	}
+	cursor, err := c.Client.Database("admin").Aggregate(ctx, buildIndexBuildOperationsPipeline(query), aggregateOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer closeMongoCursor(ctx, cursor)
+	var operations []indexBuildOperation
+	if err := cursor.All(ctx, &operations); err != nil {
+		return nil, err
+	}
+	return filterIndexBuildOperations(operations, query), nil
+}
+
+// IndexBuildOperationsCommand 是 3.6 之前的 currentOp 命令 fallback，过滤条件与 aggregation 相同。
+func (c *Conn) IndexBuildOperationsCommand(ctx context.Context, query CurrentOperationsQuery) ([]CurrentOperationSnapshot, error) {
+	command := bson.D{{Key: "currentOp", Value: 1}, {Key: "$all", Value: query.AllUsers}, {Key: "$or", Value: indexBuildOperationConditions()}}
+	if query.MaxTime > 0 {
+		command = append(command, bson.E{Key: "maxTimeMS", Value: query.MaxTime.Milliseconds()})
+	}
+	var response struct {
+		Operations []struct {
+			Host           string `bson:"host"`
+			Shard          string `bson:"shard"`
+			Namespace      string `bson:"ns"`
+			Operation      string `bson:"op"`
+			SecondsRunning *int64 `bson:"secs_running"`
+			Message        string `bson:"msg"`
+			Progress       struct {
+				Done  *int64 `bson:"done"`
+				Total *int64 `bson:"total"`
+			} `bson:"progress"`
+			Command struct {
+				CreateIndexes string `bson:"createIndexes"`
+				Indexes       []struct {
+					Name string `bson:"name"`
+				} `bson:"indexes"`
+			} `bson:"command"`
+		} `bson:"inprog"`
+	}
+	if err := c.Client.Database("admin").RunCommand(ctx, command).Decode(&response); err != nil {
+		return nil, err
+	}
+	operations := make([]indexBuildOperation, 0, len(response.Operations))
+	for _, raw := range response.Operations {
+		operation := indexBuildOperation{CreateIndexes: raw.Command.CreateIndexes}
+		operation.CurrentOperationSnapshot = CurrentOperationSnapshot{
+			Host: raw.Host, Shard: raw.Shard, Namespace: raw.Namespace, Operation: raw.Operation,
+			SecondsRunning: raw.SecondsRunning, Message: raw.Message,
+			ProgressDone: raw.Progress.Done, ProgressTotal: raw.Progress.Total,
+		}
+		for _, index := range raw.Command.Indexes {
+			operation.IndexNames = append(operation.IndexNames, index.Name)
+		}
+		operations = append(operations, operation)
+	}
+	return filterIndexBuildOperations(operations, query), nil
+}
+
+func indexBuildOperationConditions() bson.A {
+	return bson.A{
+		bson.D{{Key: "command.createIndexes", Value: bson.D{{Key: "$exists", Value: true}}}},
+		bson.D{{Key: "msg", Value: bson.D{{Key: "$regex", Value: "^Index Build"}}}},
+	}
+}
+
+func buildIndexBuildOperationsPipeline(query CurrentOperationsQuery) drivermongo.Pipeline {
+	match := bson.D{{Key: "$or", Value: indexBuildOperationConditions()}}
+	if len(query.Databases) > 0 {
+		databasePatterns := make([]string, 0, len(query.Databases))
+		for _, database := range query.Databases {
+			databasePatterns = append(databasePatterns, regexp.QuoteMeta(database))
+		}
+		match = append(match, bson.E{Key: "ns", Value: bson.D{{Key: "$regex", Value: "^(?:" + strings.Join(databasePatterns, "|") + `)\.`}}})
+	}
+	return drivermongo.Pipeline{
+		bson.D{{Key: "$currentOp", Value: bson.D{{Key: "allUsers", Value: query.AllUsers}, {Key: "idleConnections", Value: false}, {Key: "localOps", Value: false}}}},
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "host", Value: 1},
+			{Key: "shard", Value: 1},
+			{Key: "ns", Value: 1},
+			{Key: "op", Value: 1},
+			{Key: "secsRunning", Value: "$secs_running"},
+			{Key: "message", Value: "$msg"},
+			{Key: "progressDone", Value: "$progress.done"},
+			{Key: "progressTotal", Value: "$progress.total"},
+			{Key: "indexNames", Value: "$command.indexes.name"},
+			{Key: "createIndexes", Value: "$command.createIndexes"},
+		}}},
+	}
+}
+
+// filterIndexBuildOperations 把 <db>.$cmd 还原为目标集合，再按 namespace 过滤；namespace 过滤放在客户端，因为旧版本无法在服务端匹配。
+func filterIndexBuildOperations(operations []indexBuildOperation, query CurrentOperationsQuery) []CurrentOperationSnapshot {
+	result := make([]CurrentOperationSnapshot, 0, len(operations))
+	for _, operation := range operations {
+		snapshot := operation.CurrentOperationSnapshot
+		if database, collection, found := strings.Cut(snapshot.Namespace, "."); found && collection == "$cmd" && operation.CreateIndexes != "" {
+			snapshot.Namespace = database + "." + operation.CreateIndexes
+		}
+		if !currentOperationNamespaceAllowed(snapshot.Namespace, query) {
+			continue
+		}
+		result = append(result, snapshot)
+	}
+	return result
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
 	"sort"
 	"strings"
 	"time"
@@ -36,6 +37,25 @@ type CanonicalIndexDefinition struct {
 	FieldFingerprints   map[string]string
 	Building            bool
 	Shard               string
+	// 以下属性供分片键检查判断索引能否支撑分片键或保证全局唯一；Collated 表示定义了非 simple collation。
+	Unique   bool
+	Hidden   bool
+	Sparse   bool
+	Partial  bool
+	Collated bool
+	// ExpireAfterSeconds 是 TTL 索引的过期秒数；值不是整数时为 nil 且 InvalidExpireAfterSeconds 为 true。
+	ExpireAfterSeconds        *int64
+	InvalidExpireAfterSeconds bool
+	// 以下属性供特殊索引重叠检查使用：TwoDSphereVersion 是 2dsphereIndexVersion，
+	// WildcardProjection 是 wildcardProjection 展开后的字段路径及是否包含，只含字段名。
+	TwoDSphereVersion  *int64
+	WildcardProjection map[string]bool
+}
+
+// FieldCardinalitySample 是对单个字段抽样后的去重计数，只包含数量，不包含字段值。
+type FieldCardinalitySample struct {
+	Sampled  int64
+	Distinct int64
 }
 
 // IndexRoutingSnapshot 是从 config routing metadata 独立建立的 expected-shard 基线。
@@ -43,6 +63,7 @@ type IndexRoutingSnapshot struct {
 	Namespace      string
 	Sharded        bool
 	ExpectedShards []string
+	ShardKey       bson.D // 分片键定义，只含字段名与方向
 }
 
 // MetadataConsistencyRequest 限定 official command 的 database/collection scope 和成本边界。
@@ -126,7 +147,7 @@ func (c *Conn) IndexRouting(ctx context.Context, database, collection string, ma
 		return IndexRoutingSnapshot{}, fmt.Errorf("MongoDB connection is required")
 	}
 	namespace := database + "." + collection
-	findOneOptions := options.FindOne().SetProjection(bson.D{{Key: "_id", Value: 1}, {Key: "uuid", Value: 1}, {Key: "dropped", Value: 1}})
+	findOneOptions := options.FindOne().SetProjection(bson.D{{Key: "_id", Value: 1}, {Key: "uuid", Value: 1}, {Key: "dropped", Value: 1}, {Key: "key", Value: 1}})
 	if maxTime > 0 {
 		findOneOptions.SetMaxTime(maxTime)
 	}
@@ -171,10 +192,13 @@ func indexRoutingSnapshot(namespace string, metadata bson.D, chunks []bson.Raw)
 		return result, nil
 	}
 	for _, element := range metadata {
-		if element.Key == "dropped" {
+		switch element.Key {
+		case "dropped":
 			if dropped, ok := element.Value.(bool); ok && dropped {
-				return result, nil
+				return IndexRoutingSnapshot{Namespace: namespace}, nil
 			}
+		case "key":
+			result.ShardKey, _ = element.Value.(bson.D)
 		}
 	}
 	result.Sharded = true
@@ -255,6 +279,43 @@ func (c *Conn) IndexConsistencyStats(ctx context.Context, database, collection s
 	return result, nil
 }
 
+// SampleFieldCardinality 通过 $sample 抽取至多 sampleSize 个文档并统计字段的去重值数量，字段缺失的文档计为同一个值。
+func (c *Conn) SampleFieldCardinality(ctx context.Context, database, collection, field string, sampleSize int, maxTime time.Duration) (FieldCardinalitySample, error) {
+	aggregateOptions := options.Aggregate()
+	if maxTime > 0 {
+		aggregateOptions.SetMaxTime(maxTime)
+	}
+	cursor, err := c.Client.Database(database).Collection(collection).Aggregate(ctx, fieldCardinalityPipeline(field, sampleSize), aggregateOptions)
+	if err != nil {
+		return FieldCardinalitySample{}, err
+	}
+	defer closeMongoCursor(ctx, cursor)
+	var result FieldCardinalitySample
+	if cursor.Next(ctx) {
+		var row struct {
+			Sampled  int64 `bson:"sampled"`
+			Distinct int64 `bson:"distinct"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return FieldCardinalitySample{}, fmt.Errorf("decode field cardinality sample: %w", err)
+		}
+		result = FieldCardinalitySample{Sampled: row.Sampled, Distinct: row.Distinct}
+	}
+	return result, cursor.Err()
+}
+
+func fieldCardinalityPipeline(field string, sampleSize int) []bson.D {
+	return []bson.D{
+		{{Key: "$sample", Value: bson.D{{Key: "size", Value: sampleSize}}}},
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$" + field}, {Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "sampled", Value: bson.D{{Key: "$sum", Value: "$count"}}},
+			{Key: "distinct", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}
+}
+
 // CheckMetadataIndexConsistency 执行 7.x official command 并完整消费 command cursor。
 func (c *Conn) CheckMetadataIndexConsistency(ctx context.Context, request MetadataConsistencyRequest) ([]MetadataIndexInconsistency, error) {
 	if c == nil || c.Client == nil {
@@ -482,6 +543,7 @@ func canonicalIndexDefinition(spec bson.D, shard string, building bool) (Canonic
 			}
 			semantic = append(semantic, element)
 		default:
+			applyCanonicalIndexProperty(&result, element)
 			semantic = append(semantic, element)
 		}
 	}
@@ -505,6 +567,83 @@ func canonicalIndexDefinition(spec bson.D, shard string, building bool) (Canonic
 	return result, nil
 }
 
+func applyCanonicalIndexProperty(result *CanonicalIndexDefinition, element bson.E) {
+	switch element.Key {
+	case "unique":
+		result.Unique = indexOptionEnabled(element.Value)
+	case "hidden":
+		result.Hidden = indexOptionEnabled(element.Value)
+	case "sparse":
+		result.Sparse = indexOptionEnabled(element.Value)
+	case "partialFilterExpression":
+		result.Partial = true
+	case "collation":
+		result.Collated = true
+	case "expireAfterSeconds":
+		if seconds, ok := indexOptionInteger(element.Value); ok {
+			result.ExpireAfterSeconds = &seconds
+		} else {
+			result.InvalidExpireAfterSeconds = true
+		}
+	case "2dsphereIndexVersion":
+		if version, ok := indexOptionInteger(element.Value); ok {
+			result.TwoDSphereVersion = &version
+		}
+	case "wildcardProjection":
+		if projection, err := indexKeyDocument(element.Value); err == nil {
+			result.WildcardProjection = make(map[string]bool, len(projection))
+			flattenWildcardProjection(result.WildcardProjection, "", projection)
+		}
+	}
+}
+
+// flattenWildcardProjection 把嵌套写法 {a: {b: 1}} 展开为 a.b。
+func flattenWildcardProjection(result map[string]bool, prefix string, projection bson.D) {
+	for _, element := range projection {
+		path := element.Key
+		if prefix != "" {
+			path = prefix + "." + element.Key
+		}
+		if nested, err := indexKeyDocument(element.Value); err == nil {
+			flattenWildcardProjection(result, path, nested)
+			continue
+		}
+		result[path] = indexOptionEnabled(element.Value)
+	}
+}
+
+func indexOptionInteger(value any) (int64, bool) {
+	switch typed := value.(type) {
+	case int32:
+		return int64(typed), true
+	case int64:
+		return typed, true
+	case float64:
+		if typed != math.Trunc(typed) || math.Abs(typed) > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(typed), true
+	default:
+		return 0, false
+	}
+}
+
+// indexOptionEnabled 兼容旧版本以数字保存的布尔型索引选项。
+func indexOptionEnabled(value any) bool {
+	switch typed := value.(type) {
+	case bool:
+		return typed
+	case int32:
+		return typed != 0
+	case int64:
+		return typed != 0
+	case float64:
+		return typed != 0
+	default:
+		return false
+	}
+}
+
 func fingerprintBSON(document bson.D) (string, error) {
 	payload, err := bson.MarshalExtJSON(document, true, false)
 	if err != nil {
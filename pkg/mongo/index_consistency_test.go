@@ -137,6 +137,63 @@ func TestCanonicalIndexDefinitionPersistentOptionsAffectFingerprint(t *testing.T
 	}
 }
 
+func TestCanonicalIndexDefinitionExposesShardKeyProperties(t *testing.T) {
+	// 场景：分片键与 TTL 检查依赖 unique/hidden/sparse/partial/collation/expireAfterSeconds 属性，旧版本以数字保存的 unique 也要识别。
+	spec := bson.D{
+		{Key: "v", Value: int32(1)},
+		{Key: "key", Value: bson.D{{Key: "tenant", Value: int32(1)}}},
+		{Key: "name", Value: "tenant_1"},
+		{Key: "unique", Value: float64(1)},
+		{Key: "hidden", Value: true},
+		{Key: "sparse", Value: false},
+		{Key: "partialFilterExpression", Value: bson.D{{Key: "state", Value: "fixture-private"}}},
+		{Key: "collation", Value: bson.D{{Key: "locale", Value: "en"}}},
+	}
+	definition, err := canonicalIndexDefinition(spec, "shard-a", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !definition.Unique || !definition.Hidden || definition.Sparse || !definition.Partial || !definition.Collated {
+		t.Fatalf("definition properties = %#v", definition)
+	}
+	for name, value := range map[string]any{"int": int32(3600), "integral double": float64(0), "fraction": 1.5, "string": "3600"} {
+		ttl := append(bson.D(nil), spec[:3]...)
+		ttl = append(ttl, bson.E{Key: "expireAfterSeconds", Value: value})
+		definition, err := canonicalIndexDefinition(ttl, "shard-a", false)
+		valid := name == "int" || name == "integral double"
+		if err != nil || (definition.ExpireAfterSeconds != nil) != valid || definition.InvalidExpireAfterSeconds == valid {
+			t.Fatalf("%s expireAfterSeconds = %#v, %v", name, definition, err)
+		}
+	}
+	pipeline := fieldCardinalityPipeline("customer.region", 1000)
+	if len(pipeline) != 3 || pipeline[0][0].Key != "$sample" || pipeline[1][0].Value.(bson.D)[0].Value != "$customer.region" {
+		t.Fatalf("pipeline = %#v", pipeline)
+	}
+}
+
+func TestCanonicalIndexDefinitionExposesSpecialIndexProperties(t *testing.T) {
+	// 场景：特殊索引检查需要 2dsphereIndexVersion 与展开后的 wildcardProjection 字段路径，嵌套写法与数字形式的包含标记都要识别。
+	geo, err := canonicalIndexDefinition(bson.D{
+		{Key: "key", Value: bson.D{{Key: "location", Value: "2dsphere"}}},
+		{Key: "name", Value: "location_2dsphere"},
+		{Key: "2dsphereIndexVersion", Value: int32(3)},
+	}, "", false)
+	if err != nil || geo.TwoDSphereVersion == nil || *geo.TwoDSphereVersion != 3 {
+		t.Fatalf("2dsphere definition = %#v, %v", geo, err)
+	}
+	wildcard, err := canonicalIndexDefinition(bson.D{
+		{Key: "key", Value: bson.D{{Key: "$**", Value: int32(1)}}},
+		{Key: "name", Value: "$**_1"},
+		{Key: "wildcardProjection", Value: bson.D{{Key: "profile", Value: bson.D{{Key: "email", Value: int32(1)}}}, {Key: "status", Value: true}, {Key: "_id", Value: int32(0)}}},
+	}, "", false)
+	if err != nil || !reflect.DeepEqual(wildcard.WildcardProjection, map[string]bool{"profile.email": true, "status": true, "_id": false}) {
+		t.Fatalf("wildcard definition = %#v, %v", wildcard, err)
+	}
+	if wildcard.FieldFingerprints["wildcardProjection"] == "" {
+		t.Fatalf("wildcard projection fingerprint missing: %#v", wildcard.FieldFingerprints)
+	}
+}
+
 func TestRoutingChunkFilterSupportsLegacyNamespaceAndUUID(t *testing.T) {
 	// 场景：3.4 风格 routing 只按 ns 查询，带 uuid 的新 schema 同时保留 ns fallback，内部 schema 不外泄。
 	legacy, err := routingChunkFilter("app.orders", bson.D{{Key: "_id", Value: "app.orders"}})
@@ -334,7 +391,7 @@ func TestIndexRoutingSnapshotBuildsIndependentExpectedShardSet(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !snapshot.Sharded || !reflect.DeepEqual(snapshot.ExpectedShards, []string{"shard-a", "shard-b"}) {
+	if !snapshot.Sharded || !reflect.DeepEqual(snapshot.ExpectedShards, []string{"shard-a", "shard-b"}) || !reflect.DeepEqual(snapshot.ShardKey, bson.D{{Key: "tenant", Value: 1}}) {
 		t.Fatalf("snapshot = %#v", snapshot)
 	}
 
@@ -0,0 +1,16 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SetIndexHidden 通过 collMod 修改索引的 hidden 属性（4.4+）；经 mongos 执行时作用于所有持有该集合的 shard。
+func (c *Conn) SetIndexHidden(ctx context.Context, database, collection, name string, hidden bool) error {
+	command := bson.D{
+		{Key: "collMod", Value: collection},
+		{Key: "index", Value: bson.D{{Key: "name", Value: name}, {Key: "hidden", Value: hidden}}},
+	}
+	return c.Client.Database(database).RunCommand(ctx, command).Err()
+}
@@ -0,0 +1,133 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FieldTypeArrayWithDate 是元素中至少含一个 date 的数组；不含 date 的数组（包括空数组）仍计为 "array"。
+const FieldTypeArrayWithDate = "arrayWithDate"
+
+// FieldTypeSample 是对单个字段抽样后按 BSON 类型名统计的文档数，只包含类型名与数量，不包含字段值。
+// 数组按元素类型区分为 FieldTypeArrayWithDate 与 "array"，因为 TTL monitor 只按数组中的 date 元素判断过期。
+type FieldTypeSample struct {
+	Sampled int64
+	Types   map[string]int64
+}
+
+// TTLMonitorSnapshot 是单个 mongod 上 TTL monitor 的累计计数与配置；getParameter 不可用时配置字段为 nil。
+// Primary 取自 serverStatus.repl，只有 primary 会执行 TTL 删除。
+type TTLMonitorSnapshot struct {
+	Primary          bool
+	UptimeSeconds    *int64
+	Passes           *int64
+	DeletedDocuments *int64
+	Enabled          *bool
+	SleepSeconds     *int64
+}
+
+// SampleFieldTypes 通过 $sample 抽取至多 sampleSize 个文档，并按字段的 $type 结果分组计数；字段缺失计为 "missing"，
+// 数组再检查元素中是否存在 date。
+func (c *Conn) SampleFieldTypes(ctx context.Context, database, collection, field string, sampleSize int, maxTime time.Duration) (FieldTypeSample, error) {
+	aggregateOptions := options.Aggregate()
+	if maxTime > 0 {
+		aggregateOptions.SetMaxTime(maxTime)
+	}
+	cursor, err := c.Client.Database(database).Collection(collection).Aggregate(ctx, fieldTypePipeline(field, sampleSize), aggregateOptions)
+	if err != nil {
+		return FieldTypeSample{}, err
+	}
+	defer closeMongoCursor(ctx, cursor)
+	result := FieldTypeSample{Types: make(map[string]int64)}
+	for cursor.Next(ctx) {
+		var row struct {
+			Type  string `bson:"_id"`
+			Count int64  `bson:"count"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return FieldTypeSample{}, fmt.Errorf("decode field type sample: %w", err)
+		}
+		result.Types[row.Type] += row.Count
+		result.Sampled += row.Count
+	}
+	return result, cursor.Err()
+}
+
+func fieldTypePipeline(field string, sampleSize int) []bson.D {
+	return []bson.D{
+		{{Key: "$sample", Value: bson.D{{Key: "size", Value: sampleSize}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: fieldTypeExpression("$" + field)},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}
+}
+
+// fieldTypeExpression 返回字段的 $type；数组只要有一个元素为 date 即返回 FieldTypeArrayWithDate。
+func fieldTypeExpression(path string) bson.D {
+	elementIsDate := bson.D{{Key: "$map", Value: bson.D{
+		{Key: "input", Value: path},
+		{Key: "as", Value: "value"},
+		{Key: "in", Value: bson.D{{Key: "$eq", Value: bson.A{bson.D{{Key: "$type", Value: "$$value"}}, "date"}}}},
+	}}}
+	return bson.D{{Key: "$cond", Value: bson.A{
+		bson.D{{Key: "$isArray", Value: path}},
+		bson.D{{Key: "$cond", Value: bson.A{bson.D{{Key: "$anyElementTrue", Value: bson.A{elementIsDate}}}, FieldTypeArrayWithDate, "array"}}},
+		bson.D{{Key: "$type", Value: path}},
+	}}}
+}
+
+// TTLMonitorStatus 读取 serverStatus.metrics.ttl 与 uptime；ttlMonitorEnabled/ttlMonitorSleepSecs 读取失败时只保留计数。
+func (c *Conn) TTLMonitorStatus(ctx context.Context, maxTime time.Duration) (TTLMonitorSnapshot, error) {
+	command := bson.D{{Key: "serverStatus", Value: 1}}
+	if millis := maxTime.Milliseconds(); millis > 0 {
+		command = append(command, bson.E{Key: "maxTimeMS", Value: millis})
+	}
+	var raw bson.Raw
+	if err := c.Client.Database("admin").RunCommand(ctx, command).Decode(&raw); err != nil {
+		return TTLMonitorSnapshot{}, err
+	}
+	result, err := decodeTTLMonitorStatus(raw)
+	if err != nil {
+		return TTLMonitorSnapshot{}, err
+	}
+	var parameters bson.Raw
+	parameterCommand := bson.D{{Key: "getParameter", Value: 1}, {Key: "ttlMonitorEnabled", Value: 1}, {Key: "ttlMonitorSleepSecs", Value: 1}}
+	if err := c.Client.Database("admin").RunCommand(ctx, parameterCommand).Decode(&parameters); err == nil {
+		applyTTLMonitorParameters(&result, parameters)
+	}
+	return result, nil
+}
+
+func decodeTTLMonitorStatus(raw bson.Raw) (TTLMonitorSnapshot, error) {
+	var status struct {
+		Uptime *int64 `bson:"uptime"`
+		Repl   struct {
+			IsMaster          bool `bson:"ismaster"`
+			IsWritablePrimary bool `bson:"isWritablePrimary"`
+		} `bson:"repl"`
+		Metrics struct {
+			TTL struct {
+				Passes           *int64 `bson:"passes"`
+				DeletedDocuments *int64 `bson:"deletedDocuments"`
+			} `bson:"ttl"`
+		} `bson:"metrics"`
+	}
+	if err := bson.Unmarshal(raw, &status); err != nil {
+		return TTLMonitorSnapshot{}, fmt.Errorf("decode ttl monitor status: %w", err)
+	}
+	return TTLMonitorSnapshot{Primary: status.Repl.IsMaster || status.Repl.IsWritablePrimary, UptimeSeconds: status.Uptime, Passes: status.Metrics.TTL.Passes, DeletedDocuments: status.Metrics.TTL.DeletedDocuments}, nil
+}
+
+func applyTTLMonitorParameters(result *TTLMonitorSnapshot, raw bson.Raw) {
+	if value, ok := raw.Lookup("ttlMonitorEnabled").BooleanOK(); ok {
+		result.Enabled = &value
+	}
+	if value, ok := raw.Lookup("ttlMonitorSleepSecs").AsInt64OK(); ok {
+		result.SleepSeconds = &value
+	}
+}
@@ -0,0 +1,120 @@
+package mongo
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LatencyHistogramBucket 是 latencyStats histogram 的单个 bucket；LowerBoundMicros 为 bucket 下界（服务端字段 micros）。
+type LatencyHistogramBucket struct {
+	LowerBoundMicros int64 `json:"lowerBoundMicros"`
+	Count            int64 `json:"count"`
+}
+
+// LatencyOperationSnapshot 是单类操作自进程启动以来的累计延迟。
+type LatencyOperationSnapshot struct {
+	LatencyMicros int64                    `json:"latencyMicros"`
+	Ops           int64                    `json:"ops"`
+	Histogram     []LatencyHistogramBucket `json:"histogram,omitempty"`
+}
+
+// CollectionLatencySnapshot 只保留 $collStats latencyStats 的计数字段。
+type CollectionLatencySnapshot struct {
+	Namespace string                   `json:"namespace"`
+	Reads     LatencyOperationSnapshot `json:"reads"`
+	Writes    LatencyOperationSnapshot `json:"writes"`
+	Commands  LatencyOperationSnapshot `json:"commands"`
+}
+
+// CollectionLatencyStats 执行只读 $collStats latencyStats；调用方必须直连 mongod 数据节点。
+func (c *Conn) CollectionLatencyStats(ctx context.Context, database, collection string, maxTime time.Duration) (CollectionLatencySnapshot, error) {
+	pipeline := []bson.D{
+		{{Key: "$collStats", Value: bson.D{{Key: "latencyStats", Value: bson.D{{Key: "histograms", Value: true}}}}}},
+		{{Key: "$project", Value: bson.D{{Key: "_id", Value: 0}, {Key: "ns", Value: 1}, {Key: "latencyStats", Value: 1}}}},
+	}
+	aggregateOptions := options.Aggregate()
+	if maxTime > 0 {
+		aggregateOptions.SetMaxTime(maxTime)
+	}
+	cursor, err := c.Client.Database(database).Collection(collection).Aggregate(ctx, pipeline, aggregateOptions)
+	if err != nil {
+		return CollectionLatencySnapshot{}, err
+	}
+	defer closeMongoCursor(ctx, cursor)
+	result := CollectionLatencySnapshot{Namespace: database + "." + collection}
+	for cursor.Next(ctx) {
+		snapshot, decodeErr := decodeCollectionLatencySnapshot(cursor.Current)
+		if decodeErr != nil {
+			return CollectionLatencySnapshot{}, decodeErr
+		}
+		mergeLatencyOperation(&result.Reads, snapshot.Reads)
+		mergeLatencyOperation(&result.Writes, snapshot.Writes)
+		mergeLatencyOperation(&result.Commands, snapshot.Commands)
+	}
+	if err := cursor.Err(); err != nil {
+		return CollectionLatencySnapshot{}, err
+	}
+	return result, nil
+}
+
+func decodeCollectionLatencySnapshot(raw bson.Raw) (CollectionLatencySnapshot, error) {
+	var response struct {
+		Namespace    string `bson:"ns"`
+		LatencyStats bson.M `bson:"latencyStats"`
+	}
+	if err := bson.Unmarshal(raw, &response); err != nil {
+		return CollectionLatencySnapshot{}, err
+	}
+	return CollectionLatencySnapshot{
+		Namespace: response.Namespace,
+		Reads:     decodeLatencyOperation(response.LatencyStats["reads"]),
+		Writes:    decodeLatencyOperation(response.LatencyStats["writes"]),
+		Commands:  decodeLatencyOperation(response.LatencyStats["commands"]),
+	}, nil
+}
+
+func decodeLatencyOperation(value any) LatencyOperationSnapshot {
+	document, ok := value.(bson.M)
+	if !ok {
+		return LatencyOperationSnapshot{}
+	}
+	result := LatencyOperationSnapshot{LatencyMicros: diagnosticInt64(document["latency"]), Ops: diagnosticInt64(document["ops"])}
+	buckets, _ := document["histogram"].(bson.A)
+	for _, item := range buckets {
+		bucket, ok := item.(bson.M)
+		if !ok {
+			continue
+		}
+		result.Histogram = append(result.Histogram, LatencyHistogramBucket{LowerBoundMicros: diagnosticInt64(bucket["micros"]), Count: diagnosticInt64(bucket["count"])})
+	}
+	sort.SliceStable(result.Histogram, func(i, j int) bool {
+		return result.Histogram[i].LowerBoundMicros < result.Histogram[j].LowerBoundMicros
+	})
+	return result
+}
+
+func mergeLatencyOperation(target *LatencyOperationSnapshot, source LatencyOperationSnapshot) {
+	target.LatencyMicros += source.LatencyMicros
+	target.Ops += source.Ops
+	if len(source.Histogram) == 0 {
+		return
+	}
+	counts := make(map[int64]int64, len(target.Histogram)+len(source.Histogram))
+	for _, bucket := range target.Histogram {
+		counts[bucket.LowerBoundMicros] += bucket.Count
+	}
+	for _, bucket := range source.Histogram {
+		counts[bucket.LowerBoundMicros] += bucket.Count
+	}
+	target.Histogram = target.Histogram[:0]
+	for micros, count := range counts {
+		target.Histogram = append(target.Histogram, LatencyHistogramBucket{LowerBoundMicros: micros, Count: count})
+	}
+	sort.SliceStable(target.Histogram, func(i, j int) bool {
+		return target.Histogram[i].LowerBoundMicros < target.Histogram[j].LowerBoundMicros
+	})
+}
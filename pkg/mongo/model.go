@@ -250,8 +250,25 @@ type SlowlogView struct {
 	AppNames            []string  `json:"appNames,omitempty" bson:"appNames"`
 	ErrorCount          int64     `json:"errorCount" bson:"errorCount"`
 	CollectionScanCount int64     `json:"collectionScanCount" bson:"collectionScanCount"`
+	SortStageCount      int64     `json:"sortStageCount" bson:"sortStageCount"`
 	MaxTs               time.Time `json:"maxTs" bson:"maxTs"`
 	MinTs               time.Time `json:"minTs" bson:"minTs"`
+	TotalMillis         int64     `json:"totalMillis" bson:"totalMillis"`
+	P50Mills            *int64    `json:"p50Mills,omitempty" bson:"-"`
+	P95Mills            *int64    `json:"p95Mills,omitempty" bson:"-"`
+	P99Mills            *int64    `json:"p99Mills,omitempty" bson:"-"`
+
+	// 以下为服务端聚合的中间结果，由 finalizeSlowlogView 转换后清空；MillisHistogram 保留用于跨 host 合并，
+	// 7.0+ 同时返回 $percentile 结果，单节点 percentile 优先取原生值。
+	MillisHistogram   []SlowlogMillisBucket `json:"-" bson:"millisHistogram,omitempty"`
+	MillisPercentiles []float64             `json:"-" bson:"millisPercentiles,omitempty"`
+	AppNameSets       [][]string            `json:"-" bson:"appNameSets,omitempty"`
 
 	DB string `json:"-" bson:"-"`
 }
+
+// SlowlogMillisBucket 是按 1/4 倍频程划分的耗时 bucket 计数。
+type SlowlogMillisBucket struct {
+	Bucket int64 `bson:"bucket"`
+	Count  int64 `bson:"count"`
+}
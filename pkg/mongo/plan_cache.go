@@ -0,0 +1,53 @@
+package mongo
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PlanCacheStats 在集合上执行只读 $planCacheStats，只返回 queryHash 或 planCacheKey 命中 keys 的条目；
+// createdFromQuery 等包含查询取值的字段在服务端被投影剔除。调用方必须直连 mongod 数据节点。
+func (c *Conn) PlanCacheStats(ctx context.Context, database, collection string, keys []string, maxTime time.Duration) ([]bson.M, error) {
+	pipeline := []bson.D{
+		{{Key: "$planCacheStats", Value: bson.D{}}},
+		{{Key: "$match", Value: bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "queryHash", Value: bson.D{{Key: "$in", Value: keys}}}},
+			bson.D{{Key: "planCacheShapeHash", Value: bson.D{{Key: "$in", Value: keys}}}},
+			bson.D{{Key: "planCacheKey", Value: bson.D{{Key: "$in", Value: keys}}}},
+		}}}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0}, {Key: "queryHash", Value: 1}, {Key: "planCacheShapeHash", Value: 1}, {Key: "planCacheKey", Value: 1},
+			{Key: "isActive", Value: 1}, {Key: "works", Value: 1}, {Key: "timeOfCreation", Value: 1}, {Key: "cachedPlan", Value: 1},
+		}}},
+	}
+	aggregateOptions := options.Aggregate()
+	if maxTime > 0 {
+		aggregateOptions.SetMaxTime(maxTime)
+	}
+	cursor, err := c.Client.Database(database).Collection(collection).Aggregate(ctx, pipeline, aggregateOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer closeMongoCursor(ctx, cursor)
+	var entries []bson.M
+	for cursor.Next(ctx) {
+		entry, decodeErr := DecodeOrderedM(cursor.Current)
+		if decodeErr != nil {
+			return nil, decodeErr
+		}
+		entries = append(entries, entry)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// IsLegacySlowlogID 判断标识是否为低版本 profiler 缺少 queryHash 时生成的兼容标识。
+func IsLegacySlowlogID(id string) bool {
+	return strings.HasPrefix(id, legacySlowlogPrefix)
+}
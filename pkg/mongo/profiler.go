@@ -0,0 +1,54 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ProfilerSetting 是 profile 命令返回的设置；修改时返回的是修改前的值。Filter 仅 5.0+ 且已设置时存在。
+type ProfilerSetting struct {
+	Level      int32
+	SlowMS     int64
+	SampleRate *float64
+	Filter     string
+}
+
+// ProfilerStatus 执行 {profile: -1} 读取 database 的 profiler 设置；调用方必须直连 mongod 数据节点。
+func (c *Conn) ProfilerStatus(ctx context.Context, database string) (ProfilerSetting, error) {
+	return c.runProfile(ctx, database, bson.D{{Key: "profile", Value: int32(-1)}})
+}
+
+// SetProfiler 修改 database 的 profiling level；slowMS、sampleRate 为 nil 时保持不变。
+// slowms 与 sampleRate 是 mongod 进程级设置，对该节点所有 database 生效。
+func (c *Conn) SetProfiler(ctx context.Context, database string, level int32, slowMS *int64, sampleRate *float64) (ProfilerSetting, error) {
+	command := bson.D{{Key: "profile", Value: level}}
+	if slowMS != nil {
+		command = append(command, bson.E{Key: "slowms", Value: *slowMS})
+	}
+	if sampleRate != nil {
+		command = append(command, bson.E{Key: "sampleRate", Value: *sampleRate})
+	}
+	return c.runProfile(ctx, database, command)
+}
+
+func (c *Conn) runProfile(ctx context.Context, database string, command bson.D) (ProfilerSetting, error) {
+	var response struct {
+		Was        int32    `bson:"was"`
+		SlowMS     int64    `bson:"slowms"`
+		SampleRate *float64 `bson:"sampleRate"`
+		Filter     bson.Raw `bson:"filter"`
+	}
+	if err := c.Client.Database(database).RunCommand(ctx, command).Decode(&response); err != nil {
+		return ProfilerSetting{}, err
+	}
+	setting := ProfilerSetting{Level: response.Was, SlowMS: response.SlowMS, SampleRate: response.SampleRate}
+	if len(response.Filter) > 0 {
+		filter, err := bson.MarshalExtJSON(response.Filter, false, false)
+		if err != nil {
+			return ProfilerSetting{}, err
+		}
+		setting.Filter = string(filter)
+	}
+	return setting, nil
+}
@@ -0,0 +1,149 @@
+package mongo
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// QueryStatsEntry 是 $queryStats 中单个查询形状的指标；Database/Collection 为 HMAC 变换后的标识，
+// 查询形状中的字面量已由服务端替换为类型占位符，此处不保留 queryShape 的过滤条件。
+type QueryStatsEntry struct {
+	KeyHash         string
+	QueryShapeHash  string // 8.0+
+	Database        string
+	Collection      string
+	Command         string
+	ExecCount       int64
+	TotalExecMicros int64
+	MaxExecMicros   int64
+	MinExecMicros   int64
+	MaxDocsReturned *int64
+	DocsExamined    *int64 // 8.0+ 累计值
+	MaxDocsExamined *int64 // 8.0+
+	MaxKeysExamined *int64 // 8.0+
+	FirstSeen       time.Time
+	LatestSeen      time.Time
+}
+
+// QueryStatsIdentifier 按 transformIdentifiers 的 hmac-sha-256 算法变换标识符，用于把结果中的库名、集合名还原。
+func QueryStatsIdentifier(key []byte, identifier string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(identifier))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// QueryStats 在 admin 上执行 $queryStats（7.1+），库名、集合名与字段名以 hmacKey 做 HMAC 变换；
+// 只投影 namespace、命令名与 metrics，client 元数据与查询形状不离开服务端。
+func (c *Conn) QueryStats(ctx context.Context, hmacKey []byte, maxTime time.Duration) ([]QueryStatsEntry, error) {
+	pipeline := []bson.D{
+		{{Key: "$queryStats", Value: bson.D{{Key: "transformIdentifiers", Value: bson.D{
+			{Key: "algorithm", Value: "hmac-sha-256"},
+			{Key: "hmacKey", Value: primitive.Binary{Subtype: 8, Data: hmacKey}},
+		}}}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "keyHash", Value: 1},
+			{Key: "queryShapeHash", Value: 1},
+			{Key: "key.queryShape.cmdNs", Value: 1},
+			{Key: "key.queryShape.command", Value: 1},
+			{Key: "metrics", Value: 1},
+		}}},
+	}
+	aggregateOptions := options.Aggregate()
+	if maxTime > 0 {
+		aggregateOptions.SetMaxTime(maxTime)
+	}
+	cursor, err := c.Client.Database("admin").Aggregate(ctx, pipeline, aggregateOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer closeMongoCursor(ctx, cursor)
+	var entries []QueryStatsEntry
+	for cursor.Next(ctx) {
+		var document struct {
+			KeyHash        string `bson:"keyHash"`
+			QueryShapeHash string `bson:"queryShapeHash"`
+			Key            struct {
+				QueryShape struct {
+					CmdNs struct {
+						DB   string `bson:"db"`
+						Coll string `bson:"coll"`
+					} `bson:"cmdNs"`
+					Command string `bson:"command"`
+				} `bson:"queryShape"`
+			} `bson:"key"`
+			Metrics bson.M `bson:"metrics"`
+		}
+		if err := cursor.Decode(&document); err != nil {
+			return nil, err
+		}
+		entry := QueryStatsEntry{
+			KeyHash: document.KeyHash, QueryShapeHash: document.QueryShapeHash,
+			Database: document.Key.QueryShape.CmdNs.DB, Collection: document.Key.QueryShape.CmdNs.Coll,
+			Command:   document.Key.QueryShape.Command,
+			ExecCount: diagnosticInt64(document.Metrics["execCount"]),
+		}
+		execMicros := queryStatsMetric(document.Metrics, "totalExecMicros")
+		entry.TotalExecMicros = diagnosticInt64(execMicros["sum"])
+		entry.MaxExecMicros = diagnosticInt64(execMicros["max"])
+		entry.MinExecMicros = diagnosticInt64(execMicros["min"])
+		entry.MaxDocsReturned = diagnosticNestedInt64(queryStatsMetric(document.Metrics, "docsReturned"), "max")
+		docsExamined := queryStatsMetric(document.Metrics, "docsExamined")
+		entry.DocsExamined = diagnosticNestedInt64(docsExamined, "sum")
+		entry.MaxDocsExamined = diagnosticNestedInt64(docsExamined, "max")
+		entry.MaxKeysExamined = diagnosticNestedInt64(queryStatsMetric(document.Metrics, "keysExamined"), "max")
+		entry.FirstSeen = queryStatsTime(document.Metrics["firstSeenTimestamp"])
+		entry.LatestSeen = queryStatsTime(document.Metrics["latestSeenTimestamp"])
+		entries = append(entries, entry)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ActiveMongos 读取 config.mongos 中 since 之后仍有心跳的 mongos 地址；调用方必须连接 mongos。
+func (c *Conn) ActiveMongos(ctx context.Context, since time.Time) ([]string, error) {
+	cursor, err := c.Client.Database("config").Collection("mongos").Find(ctx,
+		bson.D{{Key: "ping", Value: bson.D{{Key: "$gte", Value: since}}}},
+		options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}}).SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer closeMongoCursor(ctx, cursor)
+	var addresses []string
+	for cursor.Next(ctx) {
+		var document struct {
+			ID string `bson:"_id"`
+		}
+		if err := cursor.Decode(&document); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, document.ID)
+	}
+	return addresses, cursor.Err()
+}
+
+func queryStatsMetric(metrics bson.M, key string) bson.M {
+	switch value := metrics[key].(type) {
+	case bson.M:
+		return value
+	case bson.D:
+		return value.Map()
+	}
+	return nil
+}
+
+func queryStatsTime(value any) time.Time {
+	if dateTime, ok := value.(primitive.DateTime); ok {
+		return dateTime.Time().UTC()
+	}
+	return time.Time{}
+}
@@ -0,0 +1,41 @@
+package mongo
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestQueryStatsIdentifierMatchesServerTransform(t *testing.T) {
+	// 场景：hmac-sha-256 变换结果为 base64 编码的 HMAC 摘要，本地还原库名、集合名依赖与服务端一致的编码。
+	if got := QueryStatsIdentifier([]byte("Jefe"), "what do ya want for nothing?"); got != "W9zBRr9gdU5qBCQmCJV1x1oAPwidJzmDnexYuWTsOEM=" {
+		t.Fatalf("identifier = %q", got)
+	}
+	if QueryStatsIdentifier([]byte("k1"), "orders") == QueryStatsIdentifier([]byte("k2"), "orders") {
+		t.Fatal("identifier ignored hmac key")
+	}
+}
+
+func TestQueryStatsMetricAcceptsOrderedAndUnorderedDocuments(t *testing.T) {
+	// 场景：metrics 子文档可能解码为 bson.M 或 bson.D，缺失的 8.0+ 指标保持 nil 而不是 0。
+	seen := time.Date(2026, 7, 20, 8, 0, 0, 0, time.UTC)
+	metrics := bson.M{
+		"totalExecMicros":     bson.D{{Key: "sum", Value: int64(1500)}, {Key: "max", Value: int32(900)}},
+		"docsReturned":        bson.M{"max": int64(3)},
+		"latestSeenTimestamp": primitive.NewDateTimeFromTime(seen),
+	}
+	if got := diagnosticInt64(queryStatsMetric(metrics, "totalExecMicros")["max"]); got != 900 {
+		t.Fatalf("max exec micros = %d", got)
+	}
+	if got := diagnosticNestedInt64(queryStatsMetric(metrics, "docsReturned"), "max"); got == nil || *got != 3 {
+		t.Fatalf("max docs returned = %v", got)
+	}
+	if got := diagnosticNestedInt64(queryStatsMetric(metrics, "docsExamined"), "max"); got != nil {
+		t.Fatalf("missing docsExamined = %v", *got)
+	}
+	if !queryStatsTime(metrics["latestSeenTimestamp"]).Equal(seen) || !queryStatsTime(metrics["firstSeenTimestamp"]).IsZero() {
+		t.Fatal("seen timestamps were not decoded as UTC or zero")
+	}
+}
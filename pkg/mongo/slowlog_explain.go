@@ -0,0 +1,120 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrSlowlogNotExplainable 表示 profile 记录中没有可以 explain 的命令形状。
+var ErrSlowlogNotExplainable = errors.New("slowlog operation is not explainable")
+
+var explainableCommands = map[string]struct{}{
+	"find": {}, "aggregate": {}, "count": {}, "distinct": {},
+	"findAndModify": {}, "findandmodify": {}, "update": {}, "delete": {},
+}
+
+// slowlogCommandDroppedFields 是会话、事务与 API 版本等只能出现在顶层命令上的字段，以及 mongos 转发到 shard 时附加、
+// 只在 shard 侧 profile 中出现的内部参数；直接在 shard 成员上重放会触发 StaleConfig 或被拒绝为内部参数。
+var slowlogCommandDroppedFields = map[string]struct{}{
+	"lsid": {}, "txnNumber": {}, "txnRetryCounter": {}, "autocommit": {}, "startTransaction": {},
+	"readConcern": {}, "writeConcern": {}, "apiVersion": {}, "apiStrict": {}, "apiDeprecationErrors": {},
+	"shardVersion": {}, "databaseVersion": {}, "clientOperationKey": {}, "maxTimeMSOpOnly": {}, "mayBypassWriteBlocking": {},
+	"runtimeConstants": {}, "fromMongos": {}, "fromRouter": {}, "needsMerge": {},
+}
+
+// SlowlogCommandDroppedField 判断 profile 命令中的字段是否应在重放或输出样本前剔除；"$" 开头的字段（$db、$clusterTime 等）一律剔除。
+func SlowlogCommandDroppedField(key string) bool {
+	_, dropped := slowlogCommandDroppedFields[key]
+	return dropped || strings.HasPrefix(key, "$")
+}
+
+// SlowlogExplainCommand 从 profile 记录重建可 explain 的命令：3.6+ 使用 command（getmore 使用
+// originatingCommand），update/remove 的单条语句包装回 update/delete 命令；3.4 使用 query/updateobj。
+func SlowlogExplainCommand(profile bson.M) (bson.D, error) {
+	ns, _ := profile["ns"].(string)
+	_, collection, found := strings.Cut(ns, ".")
+	if !found || collection == "" {
+		return nil, ErrSlowlogNotExplainable
+	}
+	op, _ := profile["op"].(string)
+	command := orderedDocument(profile["originatingCommand"])
+	if command == nil {
+		command = orderedDocument(profile["command"])
+	}
+	if command == nil {
+		query := orderedDocument(profile["query"])
+		switch {
+		case query == nil:
+			return nil, ErrSlowlogNotExplainable
+		case op == "update":
+			statement := bson.D{{Key: "q", Value: query}, {Key: "u", Value: orderedDocument(profile["updateobj"])}}
+			return bson.D{{Key: "update", Value: collection}, {Key: "updates", Value: bson.A{statement}}}, nil
+		case op == "remove":
+			statement := bson.D{{Key: "q", Value: query}, {Key: "limit", Value: 0}}
+			return bson.D{{Key: "delete", Value: collection}, {Key: "deletes", Value: bson.A{statement}}}, nil
+		}
+		command = query
+	}
+	if len(command) == 0 {
+		return nil, ErrSlowlogNotExplainable
+	}
+	if command[0].Key == "q" {
+		statement := explainCommandFields(command)
+		switch op {
+		case "update":
+			return bson.D{{Key: "update", Value: collection}, {Key: "updates", Value: bson.A{statement}}}, nil
+		case "remove":
+			return bson.D{{Key: "delete", Value: collection}, {Key: "deletes", Value: bson.A{statement}}}, nil
+		}
+		return nil, ErrSlowlogNotExplainable
+	}
+	if _, ok := explainableCommands[command[0].Key]; !ok {
+		return nil, ErrSlowlogNotExplainable
+	}
+	result := explainCommandFields(command)
+	if result[0].Key == "aggregate" && !hasElement(result, "cursor") {
+		result = append(result, bson.E{Key: "cursor", Value: bson.D{}})
+	}
+	return result, nil
+}
+
+// Explain 在 db 上以指定 verbosity 执行 explain；嵌套文档保留字段顺序以便解析 plan 树。
+func (c *Conn) Explain(ctx context.Context, db string, command bson.D, verbosity string) (bson.M, error) {
+	raw, err := c.Client.Database(db).RunCommand(ctx, bson.D{
+		{Key: "explain", Value: command},
+		{Key: "verbosity", Value: verbosity},
+	}).DecodeBytes()
+	if err != nil {
+		return nil, err
+	}
+	return DecodeOrderedM(raw)
+}
+
+func explainCommandFields(command bson.D) bson.D {
+	result := make(bson.D, 0, len(command))
+	for _, element := range command {
+		if SlowlogCommandDroppedField(element.Key) {
+			continue
+		}
+		result = append(result, element)
+	}
+	return result
+}
+
+// orderedDocument 只接受 DecodeOrderedM 产生的 bson.D；命令名必须是首个字段，无序的 bson.M 无法还原。
+func orderedDocument(value any) bson.D {
+	document, _ := value.(bson.D)
+	return document
+}
+
+func hasElement(document bson.D, key string) bool {
+	for _, element := range document {
+		if element.Key == key {
+			return true
+		}
+	}
+	return false
+}
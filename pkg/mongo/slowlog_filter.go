@@ -0,0 +1,66 @@
+package mongo
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SlowlogFilter 描述慢日志聚合前的时间窗与维度过滤；零值表示不过滤。
+type SlowlogFilter struct {
+	Since      time.Time
+	Until      time.Time
+	Namespaces []string
+	Operations []string
+	AppNames   []string
+	MinMillis  int64
+}
+
+// matchStage 生成 system.profile 的 $match 条件，字段均可命中 profile 的标准字段。
+func (f SlowlogFilter) matchStage() bson.D {
+	match := bson.D{}
+	timeRange := bson.D{}
+	if !f.Since.IsZero() {
+		timeRange = append(timeRange, bson.E{Key: "$gte", Value: f.Since})
+	}
+	if !f.Until.IsZero() {
+		timeRange = append(timeRange, bson.E{Key: "$lt", Value: f.Until})
+	}
+	if len(timeRange) > 0 {
+		match = append(match, bson.E{Key: "ts", Value: timeRange})
+	}
+	if len(f.Namespaces) > 0 {
+		match = append(match, bson.E{Key: "ns", Value: bson.D{{Key: "$in", Value: f.Namespaces}}})
+	}
+	if len(f.Operations) > 0 {
+		match = append(match, bson.E{Key: "op", Value: bson.D{{Key: "$in", Value: f.Operations}}})
+	}
+	if len(f.AppNames) > 0 {
+		match = append(match, bson.E{Key: "appName", Value: bson.D{{Key: "$in", Value: f.AppNames}}})
+	}
+	if f.MinMillis > 0 {
+		match = append(match, bson.E{Key: "millis", Value: bson.D{{Key: "$gte", Value: f.MinMillis}}})
+	}
+	return match
+}
+
+// Matches 在日志解析路径上复现 matchStage 的语义；时间窗过滤时缺失时间戳的记录不计入。
+func (f SlowlogFilter) Matches(entry SlowlogEntry) bool {
+	if !f.Since.IsZero() && (entry.Ts.IsZero() || entry.Ts.Before(f.Since)) {
+		return false
+	}
+	if !f.Until.IsZero() && (entry.Ts.IsZero() || !entry.Ts.Before(f.Until)) {
+		return false
+	}
+	if len(f.Namespaces) > 0 && !slices.Contains(f.Namespaces, entry.Ns) {
+		return false
+	}
+	if len(f.Operations) > 0 && !slices.Contains(f.Operations, entry.Op) {
+		return false
+	}
+	if len(f.AppNames) > 0 && !slices.Contains(f.AppNames, entry.AppName) {
+		return false
+	}
+	return f.MinMillis <= 0 || entry.Millis >= f.MinMillis
+}
@@ -0,0 +1,492 @@
+package mongo
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	slowQueryLogID       = 51803
+	startupLogID         = 4615611
+	maxSlowlogLogLineLen = 64 << 20
+	legacyLogTimeLayout  = "2006-01-02T15:04:05.000Z0700"
+)
+
+var (
+	legacySlowlogDuration    = regexp.MustCompile(` (\d+)ms$`)
+	legacySlowlogCounter     = regexp.MustCompile(` (keysExamined|docsExamined|nreturned|planningTimeMicros|cpuNanos|errCode|nShards):(-?\d+)`)
+	legacySlowlogQueryHash   = regexp.MustCompile(` queryHash:([0-9A-Fa-f]+)`)
+	legacySlowlogPlanSummary = regexp.MustCompile(` planSummary: (.+?)(?: [A-Za-z]+:[^ ]| \d+ms$)`)
+	legacySlowlogAppName     = regexp.MustCompile(`(?:^| )appName: "((?:[^"\\]|\\.)*)"`)
+	legacySlowlogCommand     = regexp.MustCompile(`(?:^| )command: ([A-Za-z]+) \{`)
+)
+
+// SlowlogEntry 是单条慢操作记录中可聚合的字段；不保留 command、filter 等原文。
+type SlowlogEntry struct {
+	Ns             string
+	Op             string
+	QueryHash      string
+	PlanSummary    string
+	AppName        string
+	Millis         int64
+	KeysExamined   *int64
+	DocsExamined   *int64
+	DocsReturned   *int64
+	PlanningMicros *int64
+	CPUNanos       *int64
+	Failed         bool
+	SortStage      bool
+	Ts             time.Time
+
+	// 以下字段只出现在 mongos 日志中：NShards 为本次执行路由到的分片数，QueryShapeHash 为 8.0+ 查询形状标识，
+	// PredicateFields 为过滤条件的顶层字段名（不含取值）。
+	NShards         *int64
+	QueryShapeHash  string
+	PredicateFields []string
+}
+
+// SlowlogLogStats 记录一次日志流解析的行数与命中的慢查询条数。
+type SlowlogLogStats struct {
+	Lines   int64
+	Entries int64
+	// Host 取自启动日志中的 host:port；轮转归档通常不含启动行，此时为空。
+	Host string
+}
+
+type slowlogGroupKey struct {
+	ns          string
+	queryHash   string
+	op          string
+	planSummary string
+}
+
+// SlowlogAggregator 在客户端复现 GetSlowLogView 的 $group 语义，按 database 归档。
+type SlowlogAggregator struct {
+	groups     map[string]map[slowlogGroupKey]*SlowlogView
+	apps       map[slowlogGroupKey]map[string]struct{}
+	histograms map[slowlogGroupKey]map[int64]int64
+}
+
+func NewSlowlogAggregator() *SlowlogAggregator {
+	return &SlowlogAggregator{
+		groups:     make(map[string]map[slowlogGroupKey]*SlowlogView),
+		apps:       make(map[slowlogGroupKey]map[string]struct{}),
+		histograms: make(map[slowlogGroupKey]map[int64]int64),
+	}
+}
+
+// Add 合并一条慢操作记录；namespace 无法解析出 database 时忽略。
+func (a *SlowlogAggregator) Add(entry SlowlogEntry) {
+	db, _, found := strings.Cut(entry.Ns, ".")
+	if !found || db == "" {
+		return
+	}
+	key := slowlogGroupKey{ns: entry.Ns, queryHash: entry.QueryHash, op: entry.Op, planSummary: entry.PlanSummary}
+	if a.groups[db] == nil {
+		a.groups[db] = make(map[slowlogGroupKey]*SlowlogView)
+	}
+	view := a.groups[db][key]
+	if view == nil {
+		view = &SlowlogView{Ns: entry.Ns, Op: entry.Op, QueryHash: entry.QueryHash, PlanSummary: entry.PlanSummary, MinMills: entry.Millis, MinTs: entry.Ts, MaxTs: entry.Ts, DB: db}
+		a.groups[db][key] = view
+		a.apps[key] = make(map[string]struct{})
+		a.histograms[key] = make(map[int64]int64)
+	}
+	view.Cnt++
+	view.TotalMillis += entry.Millis
+	a.histograms[key][slowlogMillisBucket(entry.Millis)]++
+	view.MaxMills = max(view.MaxMills, entry.Millis)
+	view.MinMills = min(view.MinMills, entry.Millis)
+	if entry.DocsExamined != nil {
+		view.MaxDocs = max(view.MaxDocs, *entry.DocsExamined)
+	}
+	view.MaxKeysExamined = maxOptionalInt64(view.MaxKeysExamined, entry.KeysExamined)
+	view.MaxDocsExamined = maxOptionalInt64(view.MaxDocsExamined, entry.DocsExamined)
+	view.MaxDocsReturned = maxOptionalInt64(view.MaxDocsReturned, entry.DocsReturned)
+	view.MaxPlanningMicros = maxOptionalInt64(view.MaxPlanningMicros, entry.PlanningMicros)
+	view.MaxCPUNanos = maxOptionalInt64(view.MaxCPUNanos, entry.CPUNanos)
+	if entry.Failed {
+		view.ErrorCount++
+	}
+	if entry.PlanSummary == "COLLSCAN" {
+		view.CollectionScanCount++
+	}
+	if entry.SortStage {
+		view.SortStageCount++
+	}
+	if entry.Ts.Before(view.MinTs) {
+		view.MinTs = entry.Ts
+	}
+	if entry.Ts.After(view.MaxTs) {
+		view.MaxTs = entry.Ts
+	}
+	if _, exists := a.apps[key][entry.AppName]; !exists {
+		a.apps[key][entry.AppName] = struct{}{}
+		view.AppNames = append(view.AppNames, entry.AppName)
+	}
+}
+
+// Databases 返回已观察到慢操作的 database，按名称排序。
+func (a *SlowlogAggregator) Databases() []string {
+	result := make([]string, 0, len(a.groups))
+	for db := range a.groups {
+		result = append(result, db)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Views 按 sort 字段降序返回单个 database 的聚合视图，缺失 queryHash 时使用 legacy 标识，
+// percentile 与 profiler 回退路径使用相同的 histogram 估算。
+func (a *SlowlogAggregator) Views(db, sortField string) []*SlowlogView {
+	result := make([]*SlowlogView, 0, len(a.groups[db]))
+	for key, view := range a.groups[db] {
+		copyOfView := *view
+		copyOfView.AppNames = append([]string(nil), view.AppNames...)
+		if copyOfView.QueryHash == "" {
+			copyOfView.QueryHash = legacySlowlogID(copyOfView.Ns, copyOfView.Op, copyOfView.PlanSummary)
+		}
+		for bucket, count := range a.histograms[key] {
+			copyOfView.MillisHistogram = append(copyOfView.MillisHistogram, SlowlogMillisBucket{Bucket: bucket, Count: count})
+		}
+		finalizeSlowlogView(&copyOfView)
+		result = append(result, &copyOfView)
+	}
+	sortSlowlogViews(result, sortField)
+	return result
+}
+
+func maxOptionalInt64(current, value *int64) *int64 {
+	if value == nil {
+		return current
+	}
+	if current == nil || *value > *current {
+		next := *value
+		return &next
+	}
+	return current
+}
+
+// ReadSlowlogLog 流式读取 mongod 日志（自动识别 gzip），逐条回调慢查询记录。
+func ReadSlowlogLog(ctx context.Context, r io.Reader, add func(SlowlogEntry)) (SlowlogLogStats, error) {
+	var stats SlowlogLogStats
+	buffered := bufio.NewReader(r)
+	var source io.Reader = buffered
+	if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, gzipErr := gzip.NewReader(buffered)
+		if gzipErr != nil {
+			return stats, gzipErr
+		}
+		defer func() { _ = gzipReader.Close() }()
+		source = gzipReader
+	}
+	scanner := bufio.NewScanner(source)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSlowlogLogLineLen)
+	for scanner.Scan() {
+		stats.Lines++
+		if stats.Lines%4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return stats, err
+			}
+		}
+		entry, ok := ParseSlowlogLogLine(scanner.Bytes())
+		if !ok {
+			if stats.Host == "" {
+				stats.Host = parseSlowlogLogStartupHost(scanner.Bytes())
+			}
+			continue
+		}
+		stats.Entries++
+		add(entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return stats, err
+	}
+	return stats, ctx.Err()
+}
+
+// GetLog 执行只读 getLog，返回内存日志缓冲中的原始行；调用方必须直连 mongod 数据节点。
+func (c *Conn) GetLog(ctx context.Context, name string) ([]string, error) {
+	var response struct {
+		Log []string `bson:"log"`
+	}
+	if err := c.Client.Database("admin").RunCommand(ctx, bson.D{{Key: "getLog", Value: name}}).Decode(&response); err != nil {
+		return nil, err
+	}
+	return response.Log, nil
+}
+
+// ParseSlowlogLogLine 解析单行 mongod 日志：4.4+ 结构化日志的 "Slow query"（id 51803），
+// 或 3.4–4.2 文本日志中以耗时结尾的慢操作行。
+func ParseSlowlogLogLine(line []byte) (SlowlogEntry, bool) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return SlowlogEntry{}, false
+	}
+	if line[0] != '{' {
+		return parseLegacySlowlogLine(string(line))
+	}
+	if !bytes.Contains(line, []byte("51803")) {
+		return SlowlogEntry{}, false
+	}
+	var document struct {
+		T struct {
+			Date string `json:"$date"`
+		} `json:"t"`
+		ID   int `json:"id"`
+		Attr struct {
+			Type               string          `json:"type"`
+			Ns                 string          `json:"ns"`
+			AppName            string          `json:"appName"`
+			Command            json.RawMessage `json:"command"`
+			PlanSummary        string          `json:"planSummary"`
+			KeysExamined       *int64          `json:"keysExamined"`
+			DocsExamined       *int64          `json:"docsExamined"`
+			NReturned          *int64          `json:"nreturned"`
+			QueryHash          string          `json:"queryHash"`
+			DurationMillis     int64           `json:"durationMillis"`
+			PlanningTimeMicros *int64          `json:"planningTimeMicros"`
+			CPUNanos           *int64          `json:"cpuNanos"`
+			ErrCode            *int64          `json:"errCode"`
+			ErrName            string          `json:"errName"`
+			HasSortStage       bool            `json:"hasSortStage"`
+			NShards            *int64          `json:"nShards"`
+			QueryShapeHash     string          `json:"queryShapeHash"`
+		} `json:"attr"`
+	}
+	if err := json.Unmarshal(line, &document); err != nil || document.ID != slowQueryLogID || document.Attr.Ns == "" {
+		return SlowlogEntry{}, false
+	}
+	ts, _ := time.Parse(time.RFC3339Nano, document.T.Date)
+	return SlowlogEntry{
+		Ns: document.Attr.Ns, Op: slowlogLogOperation(document.Attr.Type, firstJSONKey(document.Attr.Command)),
+		QueryHash: document.Attr.QueryHash, PlanSummary: document.Attr.PlanSummary, AppName: document.Attr.AppName,
+		Millis: document.Attr.DurationMillis, KeysExamined: document.Attr.KeysExamined, DocsExamined: document.Attr.DocsExamined,
+		DocsReturned: document.Attr.NReturned, PlanningMicros: document.Attr.PlanningTimeMicros, CPUNanos: document.Attr.CPUNanos,
+		Failed: document.Attr.ErrCode != nil || document.Attr.ErrName != "", SortStage: document.Attr.HasSortStage, Ts: ts.UTC(),
+		NShards: document.Attr.NShards, QueryShapeHash: document.Attr.QueryShapeHash, PredicateFields: slowlogPredicateFields(document.Attr.Command),
+	}, true
+}
+
+// parseSlowlogLogStartupHost 从 "MongoDB starting"（结构化日志 id 4615611）启动行提取 host:port。
+func parseSlowlogLogStartupHost(line []byte) string {
+	line = bytes.TrimSpace(line)
+	if !bytes.Contains(line, []byte("MongoDB starting")) {
+		return ""
+	}
+	if len(line) > 0 && line[0] == '{' {
+		var document struct {
+			ID   int `json:"id"`
+			Attr struct {
+				Host string `json:"host"`
+				Port int    `json:"port"`
+			} `json:"attr"`
+		}
+		if err := json.Unmarshal(line, &document); err != nil || document.ID != startupLogID {
+			return ""
+		}
+		return slowlogLogHostAddress(document.Attr.Host, strconv.Itoa(document.Attr.Port))
+	}
+	var host, port string
+	for _, field := range strings.Fields(string(line)) {
+		if value, ok := strings.CutPrefix(field, "host="); ok {
+			host = value
+		}
+		if value, ok := strings.CutPrefix(field, "port="); ok {
+			port = value
+		}
+	}
+	return slowlogLogHostAddress(host, port)
+}
+
+func slowlogLogHostAddress(host, port string) string {
+	if host == "" || port == "" || port == "0" {
+		return host
+	}
+	return host + ":" + port
+}
+
+// parseLegacySlowlogLine 解析 "<ts> <severity> <component> [<ctx>] <op> <ns> ... <n>ms" 文本行。
+func parseLegacySlowlogLine(line string) (SlowlogEntry, bool) {
+	contextEnd := strings.Index(line, "] ")
+	if contextEnd < 0 {
+		return SlowlogEntry{}, false
+	}
+	header := strings.Fields(line[:contextEnd+1])
+	if len(header) < 4 {
+		return SlowlogEntry{}, false
+	}
+	switch header[2] {
+	case "COMMAND", "WRITE", "QUERY":
+	default:
+		return SlowlogEntry{}, false
+	}
+	message := line[contextEnd+2:]
+	duration := legacySlowlogDuration.FindStringSubmatch(message)
+	parts := strings.SplitN(message, " ", 3)
+	if duration == nil || len(parts) < 3 || !strings.Contains(parts[1], ".") {
+		return SlowlogEntry{}, false
+	}
+	logType, rest := parts[0], parts[2]
+	commandName := ""
+	switch logType {
+	case "command":
+		if match := legacySlowlogCommand.FindStringSubmatch(rest); match != nil {
+			commandName = match[1]
+		}
+		logType = ""
+	case "query", "getmore", "insert", "update", "remove":
+	default:
+		return SlowlogEntry{}, false
+	}
+	millis, _ := strconv.ParseInt(duration[1], 10, 64)
+	ts, _ := time.Parse(legacyLogTimeLayout, header[0])
+	entry := SlowlogEntry{Ns: parts[1], Op: slowlogLogOperation(logType, commandName), Millis: millis, Ts: ts.UTC()}
+	if match := legacySlowlogQueryHash.FindStringSubmatch(rest); match != nil {
+		entry.QueryHash = match[1]
+	}
+	if match := legacySlowlogPlanSummary.FindStringSubmatch(rest); match != nil {
+		entry.PlanSummary = strings.TrimSpace(match[1])
+	}
+	if match := legacySlowlogAppName.FindStringSubmatch(rest); match != nil {
+		entry.AppName = match[1]
+	}
+	for _, match := range legacySlowlogCounter.FindAllStringSubmatch(rest, -1) {
+		value, err := strconv.ParseInt(match[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch match[1] {
+		case "keysExamined":
+			entry.KeysExamined = &value
+		case "docsExamined":
+			entry.DocsExamined = &value
+		case "nreturned":
+			entry.DocsReturned = &value
+		case "planningTimeMicros":
+			entry.PlanningMicros = &value
+		case "cpuNanos":
+			entry.CPUNanos = &value
+		case "errCode":
+			entry.Failed = true
+		case "nShards":
+			entry.NShards = &value
+		}
+	}
+	if strings.Contains(rest, " exception: ") {
+		entry.Failed = true
+	}
+	entry.SortStage = strings.Contains(rest, " hasSortStage:1")
+	return entry, true
+}
+
+// slowlogLogOperation 把日志中的 type/command 名映射为 system.profile 的 op 取值。
+func slowlogLogOperation(logType, commandName string) string {
+	switch logType {
+	case "query", "getmore", "insert", "update", "remove":
+		return logType
+	}
+	switch commandName {
+	case "find":
+		return "query"
+	case "getMore":
+		return "getmore"
+	case "insert":
+		return "insert"
+	case "update":
+		return "update"
+	case "delete":
+		return "remove"
+	default:
+		return "command"
+	}
+}
+
+// slowlogPredicateFields 取出命令过滤条件的顶层字段名并排序去重：find 的 filter，count/distinct/findAndModify 的 query，
+// update/delete 的首个语句，aggregate 的首个 $match；$and 的子条件展开一层，其余操作符忽略。
+func slowlogPredicateFields(raw json.RawMessage) []string {
+	var command map[string]json.RawMessage
+	if len(raw) == 0 || json.Unmarshal(raw, &command) != nil {
+		return nil
+	}
+	var predicate json.RawMessage
+	switch {
+	case command["filter"] != nil:
+		predicate = command["filter"]
+	case command["query"] != nil:
+		predicate = command["query"]
+	case command["updates"] != nil || command["deletes"] != nil:
+		statements := command["updates"]
+		if statements == nil {
+			statements = command["deletes"]
+		}
+		var items []map[string]json.RawMessage
+		if json.Unmarshal(statements, &items) == nil && len(items) > 0 {
+			predicate = items[0]["q"]
+		}
+	case command["pipeline"] != nil:
+		var stages []map[string]json.RawMessage
+		if json.Unmarshal(command["pipeline"], &stages) == nil && len(stages) > 0 {
+			predicate = stages[0]["$match"]
+		}
+	}
+	fields := make(map[string]struct{})
+	collectSlowlogPredicateFields(predicate, fields, 0)
+	if len(fields) == 0 {
+		return nil
+	}
+	result := make([]string, 0, len(fields))
+	for field := range fields {
+		result = append(result, field)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func collectSlowlogPredicateFields(raw json.RawMessage, fields map[string]struct{}, depth int) {
+	var predicate map[string]json.RawMessage
+	if len(raw) == 0 || depth > 1 || json.Unmarshal(raw, &predicate) != nil {
+		return
+	}
+	for key, value := range predicate {
+		if key == "$and" {
+			var clauses []json.RawMessage
+			if json.Unmarshal(value, &clauses) == nil {
+				for _, clause := range clauses {
+					collectSlowlogPredicateFields(clause, fields, depth+1)
+				}
+			}
+			continue
+		}
+		if !strings.HasPrefix(key, "$") {
+			fields[key] = struct{}{}
+		}
+	}
+}
+
+func firstJSONKey(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return ""
+	}
+	token, err := decoder.Token()
+	if err != nil {
+		return ""
+	}
+	key, _ := token.(string)
+	return key
+}
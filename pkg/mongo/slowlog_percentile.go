@@ -0,0 +1,129 @@
+package mongo
+
+import (
+	"math"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// slowlogBucketsPerOctave 决定耗时 histogram 的精度：每个 bucket 上下界相差约 19%，
+// 1 小时以内的耗时最多落在约 90 个 bucket 中。
+const slowlogBucketsPerOctave = 4
+
+var slowlogPercentiles = []float64{0.50, 0.95, 0.99}
+
+// slowlogMillisBucketExpression 是 slowlogMillisBucket 的服务端等价表达式，3.4 即可执行。
+func slowlogMillisBucketExpression() bson.D {
+	return bson.D{{Key: "$floor", Value: bson.D{{Key: "$multiply", Value: bson.A{
+		bson.D{{Key: "$log", Value: bson.A{bson.D{{Key: "$add", Value: bson.A{bson.D{{Key: "$ifNull", Value: bson.A{"$millis", 0}}}, 1}}}, 2}}},
+		slowlogBucketsPerOctave,
+	}}}}}
+}
+
+func slowlogMillisBucket(millis int64) int64 {
+	if millis < 0 {
+		millis = 0
+	}
+	return int64(math.Floor(math.Log2(float64(millis+1)) * slowlogBucketsPerOctave))
+}
+
+// SlowlogBucketEstimate 取 bucket 的几何中点作为估算值（毫秒）。
+func SlowlogBucketEstimate(bucket int64) int64 {
+	return int64(math.Round(math.Pow(2, (float64(bucket)+0.5)/slowlogBucketsPerOctave) - 1))
+}
+
+// SlowlogHistogramPercentile 按 rank 在已排序的 bucket 中找到目标 bucket，并把估算值限制在观察到的 min/max 之间。
+func SlowlogHistogramPercentile(buckets []SlowlogMillisBucket, q float64, minMillis, maxMillis int64) *int64 {
+	var total int64
+	for _, bucket := range buckets {
+		total += bucket.Count
+	}
+	if total <= 0 {
+		return nil
+	}
+	rank := int64(math.Ceil(q * float64(total)))
+	if rank < 1 {
+		rank = 1
+	}
+	var cumulative int64
+	for _, bucket := range buckets {
+		cumulative += bucket.Count
+		if cumulative >= rank {
+			value := min(max(SlowlogBucketEstimate(bucket.Bucket), minMillis), maxMillis)
+			return &value
+		}
+	}
+	return nil
+}
+
+// finalizeSlowlogView 把服务端中间结果转换为 percentile 与去重后的 appName；
+// histogram 排序后保留，供跨 host 合并时重新计算 percentile。
+func finalizeSlowlogView(view *SlowlogView) {
+	targets := []**int64{&view.P50Mills, &view.P95Mills, &view.P99Mills}
+	sort.SliceStable(view.MillisHistogram, func(i, j int) bool { return view.MillisHistogram[i].Bucket < view.MillisHistogram[j].Bucket })
+	switch {
+	case len(view.MillisPercentiles) == len(slowlogPercentiles):
+		for i, value := range view.MillisPercentiles {
+			rounded := int64(math.Round(value))
+			*targets[i] = &rounded
+		}
+	case len(view.MillisHistogram) > 0:
+		for i, q := range slowlogPercentiles {
+			*targets[i] = SlowlogHistogramPercentile(view.MillisHistogram, q, view.MinMills, view.MaxMills)
+		}
+	}
+	if len(view.AppNameSets) > 0 {
+		seen := make(map[string]struct{}, len(view.AppNames))
+		for _, appName := range view.AppNames {
+			seen[appName] = struct{}{}
+		}
+		for _, appNames := range view.AppNameSets {
+			for _, appName := range appNames {
+				if _, exists := seen[appName]; !exists {
+					seen[appName] = struct{}{}
+					view.AppNames = append(view.AppNames, appName)
+				}
+			}
+		}
+	}
+	view.MillisPercentiles, view.AppNameSets = nil, nil
+}
+
+// sortSlowlogViews 按 sort 字段降序排列，并以 ns/queryHash/op/planSummary 稳定打破平局。
+func sortSlowlogViews(views []*SlowlogView, sortField string) {
+	sort.SliceStable(views, func(i, j int) bool {
+		left, right := slowlogSortValue(views[i], sortField), slowlogSortValue(views[j], sortField)
+		if left != right {
+			return left > right
+		}
+		if views[i].Ns != views[j].Ns {
+			return views[i].Ns < views[j].Ns
+		}
+		if views[i].QueryHash != views[j].QueryHash {
+			return views[i].QueryHash < views[j].QueryHash
+		}
+		if views[i].Op != views[j].Op {
+			return views[i].Op < views[j].Op
+		}
+		return views[i].PlanSummary < views[j].PlanSummary
+	})
+}
+
+func slowlogSortValue(view *SlowlogView, sortField string) int64 {
+	switch sortField {
+	case "maxMills":
+		return view.MaxMills
+	case "maxDocs":
+		return view.MaxDocs
+	case "totalMillis":
+		return view.TotalMillis
+	case "p95":
+		if view.P95Mills == nil {
+			return 0
+		}
+		return *view.P95Mills
+	default:
+		return view.Cnt
+	}
+}
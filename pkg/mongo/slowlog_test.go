@@ -1,10 +1,16 @@
 package mongo
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestLegacySlowlogID(t *testing.T) {
@@ -34,3 +40,348 @@ func TestLegacySlowlogDocumentID(t *testing.T) {
 		t.Fatalf("legacySlowlogDocumentID() = %q, want %q", got, want)
 	}
 }
+
+func TestParseSlowlogLogLineExtractsAggregatableFields(t *testing.T) {
+	// 测试结构化日志的 Slow query 行映射为 profiler op，且非 51803 行被忽略。
+	line := `{"t":{"$date":"2026-07-14T08:00:01.500+08:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn7","msg":"Slow query","attr":{"type":"command","ns":"app.orders","appName":"api","command":{"find":"orders","filter":{"status":"new"}},"planSummary":"COLLSCAN","keysExamined":0,"docsExamined":5000,"nreturned":0,"queryHash":"ABCD1234","planningTimeMicros":800,"durationMillis":120}}`
+	entry, ok := ParseSlowlogLogLine([]byte(line))
+	if !ok {
+		t.Fatalf("ParseSlowlogLogLine() ok = false")
+	}
+	if entry.Ns != "app.orders" || entry.Op != "query" || entry.QueryHash != "ABCD1234" || entry.Millis != 120 || entry.AppName != "api" {
+		t.Fatalf("entry = %#v", entry)
+	}
+	if entry.DocsExamined == nil || *entry.DocsExamined != 5000 || entry.DocsReturned == nil || *entry.DocsReturned != 0 || entry.CPUNanos != nil {
+		t.Fatalf("entry counters = %#v", entry)
+	}
+	if !entry.Ts.Equal(time.Date(2026, 7, 14, 0, 0, 1, 500000000, time.UTC)) || entry.Failed {
+		t.Fatalf("entry ts/failed = %v/%v", entry.Ts, entry.Failed)
+	}
+	for _, ignored := range []string{
+		`{"t":{"$date":"2026-07-14T08:00:01.500+08:00"},"id":51800,"attr":{"ns":"app.orders"}}`,
+		`not a json line 51803`,
+		``,
+	} {
+		if _, ok := ParseSlowlogLogLine([]byte(ignored)); ok {
+			t.Fatalf("line %q parsed as slow query", ignored)
+		}
+	}
+}
+
+func TestReadSlowlogLogAggregatesGzipLikeProfilerGroup(t *testing.T) {
+	// 测试 gzip 归档按 ns/queryHash/op/planSummary 聚合，缺失 queryHash 时回退 legacy 标识。
+	lines := []string{
+		`{"t":{"$date":"2026-07-14T00:00:01Z"},"id":51803,"attr":{"type":"remove","ns":"app.orders","planSummary":"COLLSCAN","docsExamined":10,"durationMillis":50,"errName":"WriteConflict","errCode":112}}`,
+		`{"t":{"$date":"2026-07-14T00:00:03Z"},"id":51803,"attr":{"type":"remove","ns":"app.orders","planSummary":"COLLSCAN","docsExamined":30,"hasSortStage":true,"durationMillis":20}}`,
+		`{"t":{"$date":"2026-07-14T00:00:02Z"},"id":51803,"attr":{"type":"command","ns":"app.users","command":{"aggregate":"users"},"queryHash":"FFFF0000","durationMillis":500}}`,
+		`{"t":{"$date":"2026-07-14T00:00:02Z"},"id":22943,"attr":{"remote":"127.0.0.1:5000"}}`,
+	}
+	var payload bytes.Buffer
+	writer := gzip.NewWriter(&payload)
+	_, _ = writer.Write([]byte(strings.Join(lines, "\n")))
+	_ = writer.Close()
+
+	aggregator := NewSlowlogAggregator()
+	stats, err := ReadSlowlogLog(context.Background(), &payload, aggregator.Add)
+	if err != nil || stats.Lines != 4 || stats.Entries != 3 {
+		t.Fatalf("stats = %#v, err = %v", stats, err)
+	}
+	views := aggregator.Views("app", "maxMills")
+	if len(views) != 2 || views[0].QueryHash != "FFFF0000" || views[0].Op != "command" {
+		t.Fatalf("views = %#v", views)
+	}
+	removes := views[1]
+	if removes.QueryHash != legacySlowlogID("app.orders", "remove", "COLLSCAN") || removes.Cnt != 2 || removes.MaxMills != 50 || removes.MinMills != 20 || removes.MaxDocs != 30 {
+		t.Fatalf("remove view = %#v", removes)
+	}
+	if removes.ErrorCount != 1 || removes.CollectionScanCount != 2 || removes.SortStageCount != 1 || removes.MaxTs.Sub(removes.MinTs) != 2*time.Second {
+		t.Fatalf("remove view counters = %#v", removes)
+	}
+}
+
+func TestParseSlowlogLogLineSupportsLegacyTextFormat(t *testing.T) {
+	// 测试 3.4–4.2 文本日志按组件、op、namespace 和计数字段解析，忽略非慢操作行。
+	find := `2020-03-01T10:00:00.250+0800 I COMMAND  [conn12] command app.orders appName: "api" command: find { find: "orders", filter: { docsExamined: 1 } } planSummary: IXSCAN { status: 1 } keysExamined:200 docsExamined:200 cursorExhausted:1 numYields:1 nreturned:2 queryHash:5F5F1234 hasSortStage:1 reslen:300 protocol:op_msg 150ms`
+	entry, ok := ParseSlowlogLogLine([]byte(find))
+	if !ok {
+		t.Fatalf("legacy find line not parsed")
+	}
+	if entry.Ns != "app.orders" || entry.Op != "query" || entry.AppName != "api" || entry.QueryHash != "5F5F1234" || entry.PlanSummary != "IXSCAN { status: 1 }" || entry.Millis != 150 {
+		t.Fatalf("entry = %#v", entry)
+	}
+	if entry.KeysExamined == nil || *entry.KeysExamined != 200 || entry.DocsReturned == nil || *entry.DocsReturned != 2 || !entry.SortStage {
+		t.Fatalf("entry counters = %#v", entry)
+	}
+	if !entry.Ts.Equal(time.Date(2020, 3, 1, 2, 0, 0, 250000000, time.UTC)) {
+		t.Fatalf("entry ts = %v", entry.Ts)
+	}
+
+	remove := `2018-05-01T00:00:00.000Z I WRITE    [conn3] remove app.events command: { q: { ts: { $lt: 1 } }, limit: 0 } planSummary: COLLSCAN keysExamined:0 docsExamined:9000 ndeleted:0 exception: write conflict code:112 numYields:70 105ms`
+	entry, ok = ParseSlowlogLogLine([]byte(remove))
+	if !ok || entry.Op != "remove" || entry.PlanSummary != "COLLSCAN" || !entry.Failed || entry.DocsExamined == nil || *entry.DocsExamined != 9000 {
+		t.Fatalf("legacy remove entry = %#v, ok = %v", entry, ok)
+	}
+
+	for _, ignored := range []string{
+		`2020-03-01T10:00:00.250+0800 I NETWORK  [listener] connection accepted from 127.0.0.1:5000 #1 (1 connection now open)`,
+		`2020-03-01T10:00:00.250+0800 I COMMAND  [conn1] CMD: drop app.tmp`,
+	} {
+		if _, ok := ParseSlowlogLogLine([]byte(ignored)); ok {
+			t.Fatalf("line %q parsed as slow query", ignored)
+		}
+	}
+}
+
+func TestParseSlowlogLogStartupHost(t *testing.T) {
+	// 测试从结构化与文本格式的启动行提取 host:port，其他行返回空。
+	for line, want := range map[string]string{
+		`{"t":{"$date":"2026-07-14T00:00:00Z"},"s":"I","c":"CONTROL","id":4615611,"ctx":"initandlisten","msg":"MongoDB starting","attr":{"pid":1,"port":27017,"dbPath":"/data/db","host":"db-1"}}`: "db-1:27017",
+		`2020-03-01T10:00:00.000+0800 I CONTROL  [initandlisten] MongoDB starting : pid=1 port=27018 dbpath=/data/db 64-bit host=db-2`:                                                             "db-2:27018",
+		`2020-03-01T10:00:00.250+0800 I NETWORK  [listener] connection accepted from 127.0.0.1:5000 #1 (1 connection now open)`:                                                                    "",
+	} {
+		if got := parseSlowlogLogStartupHost([]byte(line)); got != want {
+			t.Fatalf("parseSlowlogLogStartupHost(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestParseSlowlogLogLineExtractsMongosRouting(t *testing.T) {
+	// 测试 mongos 慢日志解析出路由分片数、8.0 queryShapeHash 与过滤条件字段名，不保留字段取值。
+	line := `{"t":{"$date":"2026-07-14T08:00:01.500Z"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn7","msg":"Slow query","attr":{"type":"command","ns":"app.orders","command":{"find":"orders","filter":{"status":"new","$and":[{"createdAt":{"$gt":1}},{"$or":[{"a":1}]}]}},"nShards":3,"queryShapeHash":"F00D","durationMillis":120}}`
+	entry, ok := ParseSlowlogLogLine([]byte(line))
+	if !ok || entry.NShards == nil || *entry.NShards != 3 || entry.QueryShapeHash != "F00D" {
+		t.Fatalf("entry = %#v, ok = %v", entry, ok)
+	}
+	if strings.Join(entry.PredicateFields, ",") != "createdAt,status" {
+		t.Fatalf("predicate fields = %v", entry.PredicateFields)
+	}
+	update := `{"t":{"$date":"2026-07-14T08:00:01.500Z"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn7","msg":"Slow query","attr":{"type":"command","ns":"app.orders","command":{"update":"orders","updates":[{"q":{"tenant":"t1"},"u":{"$set":{"x":1}}}]},"nShards":1,"durationMillis":120}}`
+	if entry, ok = ParseSlowlogLogLine([]byte(update)); !ok || strings.Join(entry.PredicateFields, ",") != "tenant" {
+		t.Fatalf("update entry = %#v", entry)
+	}
+	legacy := `2020-03-01T10:00:00.250+0800 I COMMAND  [conn12] command app.orders command: find { find: "orders", filter: { status: "new" } } nShards:4 cursorExhausted:1 numYields:0 nreturned:2 reslen:300 protocol:op_msg 150ms`
+	if entry, ok = ParseSlowlogLogLine([]byte(legacy)); !ok || entry.NShards == nil || *entry.NShards != 4 || entry.PredicateFields != nil {
+		t.Fatalf("legacy entry = %#v, ok = %v", entry, ok)
+	}
+}
+
+func TestSlowlogFilterMatchStageAndEntryMatch(t *testing.T) {
+	// 测试过滤条件生成 $group 之前的 $match，并与日志路径的逐条过滤保持一致。
+	since := time.Date(2026, 7, 14, 0, 0, 0, 0, time.UTC)
+	filter := SlowlogFilter{Since: since, Until: since.Add(time.Hour), Namespaces: []string{"app.orders"}, Operations: []string{"query"}, AppNames: []string{"api"}, MinMillis: 100}
+	match := filter.matchStage()
+	keys := make([]string, 0, len(match))
+	for _, element := range match {
+		keys = append(keys, element.Key)
+	}
+	if strings.Join(keys, ",") != "ts,ns,op,appName,millis" {
+		t.Fatalf("match keys = %v", keys)
+	}
+	if len(SlowlogFilter{}.matchStage()) != 0 {
+		t.Fatalf("zero filter produced $match")
+	}
+
+	entry := SlowlogEntry{Ns: "app.orders", Op: "query", AppName: "api", Millis: 150, Ts: since.Add(time.Minute)}
+	if !filter.Matches(entry) {
+		t.Fatalf("entry %#v should match", entry)
+	}
+	for _, rejected := range []SlowlogEntry{
+		{Ns: "app.orders", Op: "query", AppName: "api", Millis: 150, Ts: since.Add(time.Hour)},
+		{Ns: "app.orders", Op: "query", AppName: "api", Millis: 150},
+		{Ns: "app.users", Op: "query", AppName: "api", Millis: 150, Ts: since},
+		{Ns: "app.orders", Op: "update", AppName: "api", Millis: 150, Ts: since},
+		{Ns: "app.orders", Op: "query", AppName: "batch", Millis: 150, Ts: since},
+		{Ns: "app.orders", Op: "query", AppName: "api", Millis: 99, Ts: since},
+	} {
+		if filter.Matches(rejected) {
+			t.Fatalf("entry %#v should not match", rejected)
+		}
+	}
+}
+
+func TestSlowlogViewPipelineChoosesPercentileStrategy(t *testing.T) {
+	// 测试所有版本都分桶后二次 $group 返回有界 histogram；7.0+ 额外在分组前以 $setWindowFields + $percentile 计算原生分位并随分组带出。
+	stageNames := func(pipeline bson.A) []string {
+		names := make([]string, 0, len(pipeline))
+		for _, stage := range pipeline {
+			names = append(names, stage.(bson.D)[0].Key)
+		}
+		return names
+	}
+	native := slowlogViewPipeline("p95", SlowlogFilter{MinMillis: 10}, true)
+	if got := strings.Join(stageNames(native), ","); got != "$match,$setWindowFields,$group,$group,$sort,$project" {
+		t.Fatalf("native stages = %s", got)
+	}
+	window := native[1].(bson.D)[0].Value.(bson.D).Map()["output"].(bson.D)
+	if percentile := window.Map()["millisPercentiles"]; percentile == nil {
+		t.Fatalf("native window missing $percentile: %v", window)
+	}
+	merged := native[3].(bson.D)[0].Value.(bson.D).Map()
+	if merged["millisPercentiles"] == nil || merged["millisHistogram"] == nil {
+		t.Fatalf("native merge group = %v, want percentiles and histogram", merged)
+	}
+	fallback := slowlogViewPipeline("totalMillis", SlowlogFilter{}, false)
+	if got := strings.Join(stageNames(fallback), ","); got != "$group,$group,$sort,$project" {
+		t.Fatalf("fallback stages = %s", got)
+	}
+	if sortKey := fallback[2].(bson.D)[0].Value.(bson.D)[0].Key; sortKey != "totalMillis" {
+		t.Fatalf("fallback sort key = %s", sortKey)
+	}
+}
+
+func TestFinalizeSlowlogViewPrefersNativePercentilesAndKeepsHistogram(t *testing.T) {
+	// 测试 7.0+ 同时返回 $percentile 与 bucket 时，单节点 percentile 取原生值，histogram 排序后保留供合并与 digest 使用。
+	view := &SlowlogView{
+		MinMills: 3, MaxMills: 900, MillisPercentiles: []float64{12.4, 480.6, 899.5},
+		MillisHistogram: []SlowlogMillisBucket{{Bucket: slowlogMillisBucket(800), Count: 1}, {Bucket: slowlogMillisBucket(10), Count: 9}},
+	}
+	finalizeSlowlogView(view)
+	if view.P50Mills == nil || *view.P50Mills != 12 || *view.P95Mills != 481 || *view.P99Mills != 900 {
+		t.Fatalf("percentiles = %v/%v/%v", view.P50Mills, view.P95Mills, view.P99Mills)
+	}
+	if len(view.MillisHistogram) != 2 || view.MillisHistogram[0].Bucket > view.MillisHistogram[1].Bucket || view.MillisPercentiles != nil {
+		t.Fatalf("histogram = %#v, percentiles = %v", view.MillisHistogram, view.MillisPercentiles)
+	}
+}
+
+func TestFinalizeSlowlogViewEstimatesPercentilesFromHistogram(t *testing.T) {
+	// 测试 histogram 回退按 rank 取 bucket 几何中点，并限制在观察到的 min/max 之间，同时合并分桶 appName 并保留 histogram。
+	view := &SlowlogView{MinMills: 10, MaxMills: 5000, AppNames: []string{"api"}, AppNameSets: [][]string{{"api", "batch"}, {"batch"}}}
+	for i := 0; i < 90; i++ {
+		view.MillisHistogram = append(view.MillisHistogram, SlowlogMillisBucket{Bucket: slowlogMillisBucket(12), Count: 1})
+	}
+	view.MillisHistogram = append(view.MillisHistogram,
+		SlowlogMillisBucket{Bucket: slowlogMillisBucket(900), Count: 9},
+		SlowlogMillisBucket{Bucket: slowlogMillisBucket(5000), Count: 1},
+	)
+	finalizeSlowlogView(view)
+	if view.P50Mills == nil || *view.P50Mills < 10 || *view.P50Mills > 14 {
+		t.Fatalf("p50 = %v, want about 12", view.P50Mills)
+	}
+	if view.P95Mills == nil || *view.P95Mills < 800 || *view.P95Mills > 1000 {
+		t.Fatalf("p95 = %v, want about 900", view.P95Mills)
+	}
+	if view.P99Mills == nil || *view.P99Mills < 800 || *view.P99Mills > 1000 {
+		t.Fatalf("p99 = %v, want about 900", view.P99Mills)
+	}
+	if strings.Join(view.AppNames, ",") != "api,batch" || len(view.MillisHistogram) != 92 || view.AppNameSets != nil {
+		t.Fatalf("finalized view = %#v", view)
+	}
+
+	native := &SlowlogView{MillisPercentiles: []float64{1.4, 20.5, 99.6}}
+	finalizeSlowlogView(native)
+	if *native.P50Mills != 1 || *native.P95Mills != 21 || *native.P99Mills != 100 {
+		t.Fatalf("native percentiles = %d/%d/%d", *native.P50Mills, *native.P95Mills, *native.P99Mills)
+	}
+}
+
+func TestSlowlogAggregatorSortsByP95AndTotalMillis(t *testing.T) {
+	// 测试日志聚合累计 totalMillis 并支持 p95/totalMillis 排序：稳定慢的查询排在单次离群值之前。
+	aggregator := NewSlowlogAggregator()
+	for i := 0; i < 20; i++ {
+		aggregator.Add(SlowlogEntry{Ns: "app.steady", Op: "query", QueryHash: "A", Millis: 300})
+		aggregator.Add(SlowlogEntry{Ns: "app.outlier", Op: "query", QueryHash: "B", Millis: 5})
+	}
+	aggregator.Add(SlowlogEntry{Ns: "app.outlier", Op: "query", QueryHash: "B", Millis: 60000})
+
+	byP95 := aggregator.Views("app", "p95")
+	if byP95[0].Ns != "app.steady" || byP95[0].TotalMillis != 6000 {
+		t.Fatalf("p95 order = %s/%d", byP95[0].Ns, byP95[0].TotalMillis)
+	}
+	byTotal := aggregator.Views("app", "totalMillis")
+	if byTotal[0].Ns != "app.outlier" || byTotal[0].TotalMillis != 60100 {
+		t.Fatalf("totalMillis order = %s/%d", byTotal[0].Ns, byTotal[0].TotalMillis)
+	}
+}
+
+func TestDecodeOrderedMKeepsNestedKeyOrder(t *testing.T) {
+	// 测试 slowlog 详情解码后嵌套的 sort 与索引 key 保持原始字段顺序。
+	raw, err := bson.Marshal(bson.D{
+		{Key: "ns", Value: "app.orders"},
+		{Key: "command", Value: bson.D{{Key: "sort", Value: bson.D{{Key: "z", Value: int32(1)}, {Key: "a", Value: int32(-1)}}}}},
+	})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	document, err := DecodeOrderedM(raw)
+	if err != nil {
+		t.Fatalf("DecodeOrderedM() error = %v", err)
+	}
+	command, ok := document["command"].(bson.D)
+	if !ok || document["ns"] != "app.orders" {
+		t.Fatalf("document = %#v", document)
+	}
+	sortSpec, ok := command[0].Value.(bson.D)
+	if !ok || len(sortSpec) != 2 || sortSpec[0].Key != "z" || sortSpec[1].Key != "a" {
+		t.Fatalf("sort = %#v", command[0].Value)
+	}
+}
+
+func TestSlowlogExplainCommandDropsShardInternalArguments(t *testing.T) {
+	// 测试 shard 侧 profile 记录的 mongos 转发参数（shardVersion、databaseVersion 等）在重放前剔除，只保留用户命令字段。
+	profile := bson.M{
+		"ns": "app.orders", "op": "query",
+		"command": bson.D{
+			{Key: "find", Value: "orders"}, {Key: "filter", Value: bson.D{{Key: "status", Value: "A"}}}, {Key: "limit", Value: int64(10)},
+			{Key: "shardVersion", Value: bson.A{primitive.Timestamp{T: 1, I: 2}, primitive.NewObjectID()}},
+			{Key: "databaseVersion", Value: bson.D{{Key: "uuid", Value: "u"}, {Key: "lastMod", Value: int32(1)}}},
+			{Key: "clientOperationKey", Value: "key"}, {Key: "maxTimeMSOpOnly", Value: int64(30000)},
+			{Key: "mayBypassWriteBlocking", Value: false}, {Key: "readConcern", Value: bson.D{{Key: "level", Value: "local"}}},
+			{Key: "$clusterTime", Value: bson.D{}}, {Key: "$db", Value: "app"},
+		},
+	}
+	command, err := SlowlogExplainCommand(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, element := range command {
+		keys = append(keys, element.Key)
+	}
+	if strings.Join(keys, ",") != "find,filter,limit" {
+		t.Fatalf("command keys = %v", keys)
+	}
+}
+
+func TestSlowlogExplainCommandRebuildsCommandShapes(t *testing.T) {
+	// 测试从 profile 重建 explain 命令：剔除会话字段、包装单条 update 语句、兼容 3.4 并拒绝 insert。
+	find, err := SlowlogExplainCommand(bson.M{
+		"ns": "app.orders", "op": "query",
+		"command": bson.D{{Key: "find", Value: "orders"}, {Key: "filter", Value: bson.D{{Key: "status", Value: "A"}}}, {Key: "lsid", Value: bson.D{}}, {Key: "$db", Value: "app"}},
+	})
+	if err != nil || len(find) != 2 || find[0].Key != "find" || find[1].Key != "filter" {
+		t.Fatalf("find = %#v, err = %v", find, err)
+	}
+
+	update, err := SlowlogExplainCommand(bson.M{
+		"ns": "app.orders", "op": "update",
+		"command": bson.D{{Key: "q", Value: bson.D{{Key: "status", Value: "A"}}}, {Key: "u", Value: bson.D{{Key: "$set", Value: bson.D{}}}}, {Key: "multi", Value: true}},
+	})
+	if err != nil || update[0].Key != "update" || update[0].Value != "orders" || update[1].Key != "updates" {
+		t.Fatalf("update = %#v, err = %v", update, err)
+	}
+
+	legacy, err := SlowlogExplainCommand(bson.M{
+		"ns": "app.orders", "op": "remove",
+		"query": bson.D{{Key: "status", Value: "A"}},
+	})
+	if err != nil || legacy[0].Key != "delete" || legacy[1].Key != "deletes" {
+		t.Fatalf("legacy = %#v, err = %v", legacy, err)
+	}
+
+	aggregate, err := SlowlogExplainCommand(bson.M{
+		"ns": "app.orders", "op": "command",
+		"command": bson.D{{Key: "aggregate", Value: "orders"}, {Key: "pipeline", Value: bson.A{}}},
+	})
+	if err != nil || !hasElement(aggregate, "cursor") {
+		t.Fatalf("aggregate = %#v, err = %v", aggregate, err)
+	}
+
+	_, err = SlowlogExplainCommand(bson.M{
+		"ns": "app.orders", "op": "insert",
+		"command": bson.D{{Key: "insert", Value: "orders"}},
+	})
+	if !errors.Is(err, ErrSlowlogNotExplainable) {
+		t.Fatalf("insert error = %v, want ErrSlowlogNotExplainable", err)
+	}
+}
@@ -523,6 +523,19 @@ func normalizeCapacityOptions(opts CapacityOptions) (CapacityOptions, error) {
 	return opts, nil
 }
 
+// ClusterIdentity 返回当前集群的脱敏身份摘要，供调用方为本地快照与 ledger 选择文件。
+func (c *Client) ClusterIdentity(ctx context.Context) (identity CapacityIdentity, err error) {
+	if err := c.requireConn(); err != nil {
+		return CapacityIdentity{}, err
+	}
+	defer func() { err = mapContextError(err) }()
+	cluster, err := c.detectCluster(ctx)
+	if err != nil {
+		return CapacityIdentity{}, err
+	}
+	return c.capacityIdentity(ctx, cluster.Type)
+}
+
 func (c *Client) capacityIdentity(ctx context.Context, clusterType pkgmongo.ClusterType) (CapacityIdentity, error) {
 	inputs := []string{string(clusterType)}
 	switch clusterType {
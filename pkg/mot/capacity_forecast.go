@@ -0,0 +1,375 @@
+package mot
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+type CapacityForecastMethod string
+
+const (
+	// CapacityForecastLinear 使用最小二乘线性回归，置信区间取斜率的 95% t 区间。
+	CapacityForecastLinear CapacityForecastMethod = "linear"
+	// CapacityForecastRobust 使用 Theil-Sen 回归（两两斜率的中位数），对个别异常快照不敏感，置信区间取 Sen 秩区间。
+	CapacityForecastRobust CapacityForecastMethod = "robust"
+)
+
+const (
+	// minCapacityForecastSnapshots 是拟合趋势并给出置信区间所需的最少快照数。
+	minCapacityForecastSnapshots   = 3
+	defaultCapacityForecastHorizon = 90 * 24 * time.Hour
+	// capacityForecastCriticalDays 是预计耗尽天数不超过该值时 finding 升级为 critical 的阈值。
+	capacityForecastCriticalDays = 30
+)
+
+type CapacityForecastOptions struct {
+	Method CapacityForecastMethod
+	// Quotas 按数据库名或 namespace 配置字节配额，分别与数据库 totalSize 及集合 storageSize+indexSize 比较。
+	Quotas map[string]int64
+	// Horizon 是报告 capacity.exhaustion_forecast 的预测窗口，默认 90 天。
+	Horizon time.Duration
+}
+
+// CapacityTrend 是单个数据库、集合或文件系统在快照序列上的增长趋势；少于 3 个数据点时不拟合。
+// 最新快照中已不存在的对象（如已删除的集合）只给出 LastSeenAt，不拟合也不预测。
+type CapacityTrend struct {
+	Name              string     `json:"name"`
+	Shard             string     `json:"shard,omitempty"`
+	Host              string     `json:"host,omitempty"`
+	Points            int        `json:"points"`
+	LatestBytes       int64      `json:"latestBytes"`
+	LastSeenAt        *time.Time `json:"lastSeenAt,omitempty"`
+	GrowthPerDay      *float64   `json:"growthPerDay,omitempty"`
+	GrowthPerDayLow   *float64   `json:"growthPerDayLow,omitempty"`
+	GrowthPerDayHigh  *float64   `json:"growthPerDayHigh,omitempty"`
+	LimitBytes        *int64     `json:"limitBytes,omitempty"`
+	LimitSource       string     `json:"limitSource,omitempty"`
+	DaysUntilLimit    *float64   `json:"daysUntilLimit,omitempty"`
+	DaysUntilLimitMin *float64   `json:"daysUntilLimitMin,omitempty"`
+	DaysUntilLimitMax *float64   `json:"daysUntilLimitMax,omitempty"`
+}
+
+type CapacityForecastResult struct {
+	SchemaVersion    int                    `json:"schemaVersion"`
+	ClusterIdentity  CapacityIdentity       `json:"clusterIdentity"`
+	Method           CapacityForecastMethod `json:"method"`
+	Snapshots        int                    `json:"snapshots"`
+	FirstCollectedAt time.Time              `json:"firstCollectedAt"`
+	LastCollectedAt  time.Time              `json:"lastCollectedAt"`
+	Horizon          time.Duration          `json:"horizon"`
+	Filesystems      []CapacityTrend        `json:"filesystems"`
+	Databases        []CapacityTrend        `json:"databases"`
+	Collections      []CapacityTrend        `json:"collections"`
+	Findings         []DiagnosticFinding    `json:"findings"`
+}
+
+type capacityPoint struct {
+	days  float64
+	bytes int64
+}
+
+// capacitySeries 是同一对象按时间排序的观测值；limit 取最新快照中的文件系统总量，lastSeen 是最后一次出现的采集时间。
+type capacitySeries struct {
+	trend    CapacityTrend
+	points   []capacityPoint
+	limit    *int64
+	lastSeen time.Time
+}
+
+// ForecastCapacity 纯离线地在同一集群的多个兼容快照上拟合增长趋势，预测文件系统或配额的耗尽时间。
+func ForecastCapacity(snapshots []CapacityResult, opts CapacityForecastOptions) (*CapacityForecastResult, error) {
+	if opts.Method == "" {
+		opts.Method = CapacityForecastLinear
+	}
+	if opts.Method != CapacityForecastLinear && opts.Method != CapacityForecastRobust {
+		return nil, invalidOptions("unknown capacity forecast method %q", opts.Method)
+	}
+	if opts.Horizon < 0 {
+		return nil, invalidOptions("forecast horizon must not be negative")
+	}
+	if opts.Horizon == 0 {
+		opts.Horizon = defaultCapacityForecastHorizon
+	}
+	for name, quota := range opts.Quotas {
+		if quota <= 0 {
+			return nil, invalidOptions("quota for %q must be positive", name)
+		}
+	}
+	if len(snapshots) < minCapacityForecastSnapshots {
+		return nil, invalidOptions("capacity forecast requires at least %d snapshots", minCapacityForecastSnapshots)
+	}
+	ordered := append([]CapacityResult(nil), snapshots...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].CollectedAt.Before(ordered[j].CollectedAt) })
+	identity := ordered[0].ClusterIdentity
+	for i, snapshot := range ordered {
+		if snapshot.SchemaVersion != capacitySchemaVersion {
+			return nil, invalidOptions("unsupported capacity schema version")
+		}
+		if identity.Digest == "" || snapshot.ClusterIdentity != identity {
+			return nil, invalidOptions("capacity snapshots belong to different clusters")
+		}
+		if i > 0 && !snapshot.CollectedAt.After(ordered[i-1].CollectedAt) {
+			return nil, invalidOptions("capacity snapshots must have distinct collection times")
+		}
+	}
+	first := ordered[0].CollectedAt
+	filesystems := make(map[string]*capacitySeries)
+	databases := make(map[string]*capacitySeries)
+	collections := make(map[string]*capacitySeries)
+	for _, snapshot := range ordered {
+		days := snapshot.CollectedAt.Sub(first).Hours() / 24
+		seenFilesystems := make(map[string]bool)
+		for _, database := range snapshot.Databases {
+			for _, filesystem := range capacityFilesystems(database) {
+				key := capacityFilesystemName(filesystem)
+				if seenFilesystems[key] || filesystem.FSUsedSizeBytes == nil {
+					continue
+				}
+				seenFilesystems[key] = true
+				series := capacitySeriesFor(filesystems, key, CapacityTrend{Name: key, Shard: filesystem.Shard, Host: filesystem.Host})
+				series.points = append(series.points, capacityPoint{days: days, bytes: *filesystem.FSUsedSizeBytes})
+				series.limit, series.lastSeen = filesystem.FSTotalSizeBytes, snapshot.CollectedAt
+			}
+			if total, ok := databaseCapacityTotal(database); ok {
+				series := capacitySeriesFor(databases, database.Name, CapacityTrend{Name: database.Name})
+				series.points = append(series.points, capacityPoint{days: days, bytes: total})
+				series.lastSeen = snapshot.CollectedAt
+			}
+			for _, collection := range database.Collections {
+				if collection.StorageSizeBytes == nil || collection.IndexSizeBytes == nil {
+					continue
+				}
+				series := capacitySeriesFor(collections, collection.Namespace, CapacityTrend{Name: collection.Namespace})
+				series.points = append(series.points, capacityPoint{days: days, bytes: *collection.StorageSizeBytes + *collection.IndexSizeBytes})
+				series.lastSeen = snapshot.CollectedAt
+			}
+		}
+	}
+	result := &CapacityForecastResult{
+		SchemaVersion: capacitySchemaVersion, ClusterIdentity: identity, Method: opts.Method, Snapshots: len(ordered),
+		FirstCollectedAt: first, LastCollectedAt: ordered[len(ordered)-1].CollectedAt, Horizon: opts.Horizon,
+		Filesystems: []CapacityTrend{}, Databases: []CapacityTrend{}, Collections: []CapacityTrend{}, Findings: []DiagnosticFinding{},
+	}
+	for _, series := range sortedCapacitySeries(filesystems) {
+		trend := forecastCapacitySeries(series, series.limit, "filesystem", opts.Method, result.LastCollectedAt)
+		result.Filesystems = append(result.Filesystems, trend)
+		result.Findings = appendCapacityForecastFinding(result.Findings, FindingScope{Type: ScopeNode, Shard: trend.Shard, Node: trend.Host}, trend, result, opts.Horizon)
+	}
+	for _, series := range sortedCapacitySeries(databases) {
+		trend := forecastCapacitySeries(series, capacityQuota(opts.Quotas, series.trend.Name), "quota", opts.Method, result.LastCollectedAt)
+		result.Databases = append(result.Databases, trend)
+		result.Findings = appendCapacityForecastFinding(result.Findings, FindingScope{Type: ScopeDatabase, Database: trend.Name}, trend, result, opts.Horizon)
+	}
+	for _, series := range sortedCapacitySeries(collections) {
+		trend := forecastCapacitySeries(series, capacityQuota(opts.Quotas, series.trend.Name), "quota", opts.Method, result.LastCollectedAt)
+		result.Collections = append(result.Collections, trend)
+		result.Findings = appendCapacityForecastFinding(result.Findings, FindingScope{Type: ScopeNamespace, Namespace: trend.Name}, trend, result, opts.Horizon)
+	}
+	sanitizeAndSortFindings(result.Findings)
+	return result, nil
+}
+
+// capacityFilesystems 优先使用各成员的文件系统容量，旧快照只有数据库级 fsUsedSize/fsTotalSize 时按单个文件系统处理。
+func capacityFilesystems(database DatabaseCapacity) []FilesystemCapacity {
+	if len(database.Filesystems) > 0 {
+		return database.Filesystems
+	}
+	return []FilesystemCapacity{{FSUsedSizeBytes: database.FSUsedSizeBytes, FSTotalSizeBytes: database.FSTotalSizeBytes}}
+}
+
+// capacityFilesystemName 以 shard/host 标识文件系统，数据库级汇总值统一记为 "dbStats"。
+func capacityFilesystemName(filesystem FilesystemCapacity) string {
+	switch {
+	case filesystem.Shard != "" && filesystem.Host != "":
+		return filesystem.Shard + "/" + filesystem.Host
+	case filesystem.Host != "":
+		return filesystem.Host
+	case filesystem.Shard != "":
+		return filesystem.Shard
+	default:
+		return "dbStats"
+	}
+}
+
+func databaseCapacityTotal(database DatabaseCapacity) (int64, bool) {
+	if database.TotalSizeBytes != nil {
+		return *database.TotalSizeBytes, true
+	}
+	if database.StorageSizeBytes != nil && database.IndexSizeBytes != nil {
+		return *database.StorageSizeBytes + *database.IndexSizeBytes, true
+	}
+	return 0, false
+}
+
+func capacitySeriesFor(values map[string]*capacitySeries, key string, trend CapacityTrend) *capacitySeries {
+	series, ok := values[key]
+	if !ok {
+		series = &capacitySeries{trend: trend}
+		values[key] = series
+	}
+	return series
+}
+
+func sortedCapacitySeries(values map[string]*capacitySeries) []*capacitySeries {
+	result := make([]*capacitySeries, 0, len(values))
+	for _, key := range sortedUnionKeys(values, nil) {
+		result = append(result, values[key])
+	}
+	return result
+}
+
+func capacityQuota(quotas map[string]int64, name string) *int64 {
+	if quota, ok := quotas[name]; ok {
+		return &quota
+	}
+	return nil
+}
+
+// forecastCapacitySeries 拟合增长斜率并以最新观测值为起点推算到达上限的天数；
+// 斜率区间下界不为正时区间上界（最晚耗尽时间）为空，表示在置信区间内可能不会耗尽。
+// 未出现在最新快照（latest）中的对象只记录 LastSeenAt，避免已删除的集合继续产生耗尽预测。
+func forecastCapacitySeries(series *capacitySeries, limit *int64, limitSource string, method CapacityForecastMethod, latest time.Time) CapacityTrend {
+	trend := series.trend
+	trend.Points = len(series.points)
+	trend.LatestBytes = series.points[len(series.points)-1].bytes
+	if limit != nil {
+		trend.LimitBytes = limit
+		trend.LimitSource = limitSource
+	}
+	if series.lastSeen.Before(latest) {
+		lastSeen := series.lastSeen
+		trend.LastSeenAt = &lastSeen
+		return trend
+	}
+	if len(series.points) < minCapacityForecastSnapshots {
+		return trend
+	}
+	slope, low, high := fitCapacityTrend(series.points, method)
+	trend.GrowthPerDay, trend.GrowthPerDayLow, trend.GrowthPerDayHigh = &slope, &low, &high
+	if limit == nil {
+		return trend
+	}
+	remaining := float64(*limit - trend.LatestBytes)
+	trend.DaysUntilLimit = daysUntilCapacityLimit(remaining, slope)
+	trend.DaysUntilLimitMin = daysUntilCapacityLimit(remaining, high)
+	trend.DaysUntilLimitMax = daysUntilCapacityLimit(remaining, low)
+	return trend
+}
+
+func daysUntilCapacityLimit(remaining, perDay float64) *float64 {
+	if remaining <= 0 {
+		days := 0.0
+		return &days
+	}
+	if perDay <= 0 {
+		return nil
+	}
+	days := remaining / perDay
+	return &days
+}
+
+func appendCapacityForecastFinding(findings []DiagnosticFinding, scope FindingScope, trend CapacityTrend, result *CapacityForecastResult, horizon time.Duration) []DiagnosticFinding {
+	if trend.DaysUntilLimit == nil || *trend.DaysUntilLimit > horizon.Hours()/24 {
+		return findings
+	}
+	severity := SeverityWarning
+	if *trend.DaysUntilLimit <= capacityForecastCriticalDays {
+		severity = SeverityCritical
+	}
+	evidence := map[string]any{
+		"name": trend.Name, "limitSource": trend.LimitSource, "limitBytes": *trend.LimitBytes, "latestBytes": trend.LatestBytes,
+		"growthBytesPerDay": roundCapacityForecast(*trend.GrowthPerDay), "growthBytesPerDayLow": roundCapacityForecast(*trend.GrowthPerDayLow),
+		"growthBytesPerDayHigh": roundCapacityForecast(*trend.GrowthPerDayHigh), "daysUntilLimit": roundCapacityForecast(*trend.DaysUntilLimit),
+		"method": string(result.Method), "snapshots": trend.Points, "confidence": 0.95,
+	}
+	if trend.DaysUntilLimitMin != nil {
+		evidence["daysUntilLimitMin"] = roundCapacityForecast(*trend.DaysUntilLimitMin)
+	}
+	if trend.DaysUntilLimitMax != nil {
+		evidence["daysUntilLimitMax"] = roundCapacityForecast(*trend.DaysUntilLimitMax)
+	}
+	return append(findings, DiagnosticFinding{
+		Code: "capacity.exhaustion_forecast", Severity: severity, Scope: scope,
+		Summary:        fmt.Sprintf("按当前增长趋势预计 %.0f 天内达到%s上限", math.Ceil(*trend.DaysUntilLimit), capacityLimitLabel(trend.LimitSource)),
+		Evidence:       evidence,
+		Recommendation: "结合置信区间安排扩容、清理过期数据或调整配额；快照间隔不均或数据迁移期间的趋势需人工复核",
+	})
+}
+
+func capacityLimitLabel(source string) string {
+	if source == "filesystem" {
+		return "文件系统容量"
+	}
+	return "配额"
+}
+
+func roundCapacityForecast(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// fitCapacityTrend 返回每天增长字节数及其 95% 置信区间。
+func fitCapacityTrend(points []capacityPoint, method CapacityForecastMethod) (float64, float64, float64) {
+	if method == CapacityForecastRobust {
+		return theilSenSlope(points)
+	}
+	return leastSquaresSlope(points)
+}
+
+func leastSquaresSlope(points []capacityPoint) (float64, float64, float64) {
+	n := float64(len(points))
+	var meanX, meanY float64
+	for _, point := range points {
+		meanX += point.days
+		meanY += float64(point.bytes)
+	}
+	meanX /= n
+	meanY /= n
+	var sxx, sxy float64
+	for _, point := range points {
+		sxx += (point.days - meanX) * (point.days - meanX)
+		sxy += (point.days - meanX) * (float64(point.bytes) - meanY)
+	}
+	slope := sxy / sxx
+	intercept := meanY - slope*meanX
+	var residuals float64
+	for _, point := range points {
+		residual := float64(point.bytes) - (intercept + slope*point.days)
+		residuals += residual * residual
+	}
+	margin := studentT95(len(points)-2) * math.Sqrt(residuals/(n-2)/sxx)
+	return slope, slope - margin, slope + margin
+}
+
+// theilSenSlope 取所有点对斜率的中位数，区间按 Kendall 统计量的正态近似选取两两斜率的秩（Hollander & Wolfe）：
+// 下界为第 (N-C)/2 个、上界为第 (N+C)/2+1 个斜率（1 起算），换算为 0 起算的下标与 scipy.stats.theilslopes 一致。
+func theilSenSlope(points []capacityPoint) (float64, float64, float64) {
+	var slopes []float64
+	for i := range points {
+		for j := i + 1; j < len(points); j++ {
+			slopes = append(slopes, float64(points[j].bytes-points[i].bytes)/(points[j].days-points[i].days))
+		}
+	}
+	sort.Float64s(slopes)
+	count := len(slopes)
+	median := slopes[count/2]
+	if count%2 == 0 {
+		median = (slopes[count/2-1] + slopes[count/2]) / 2
+	}
+	n := float64(len(points))
+	spread := 1.96 * math.Sqrt(n*(n-1)*(2*n+5)/18)
+	lower := int(math.Round((float64(count)-spread)/2)) - 1
+	upper := int(math.Round((float64(count) + spread) / 2))
+	return median, slopes[max(lower, 0)], slopes[min(upper, count-1)]
+}
+
+// studentT95 返回双侧 95% 的 t 分位数，自由度超过 30 时使用正态近似。
+func studentT95(degrees int) float64 {
+	table := []float64{12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228, 2.201, 2.179, 2.160, 2.145, 2.131,
+		2.120, 2.110, 2.101, 2.093, 2.086, 2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042}
+	if degrees >= 1 && degrees <= len(table) {
+		return table[degrees-1]
+	}
+	return 1.96
+}
@@ -0,0 +1,152 @@
+package mot
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func forecastSnapshot(identity CapacityIdentity, day int, fsUsed, dbTotal, collectionStorage int64) CapacityResult {
+	fsTotal, indexSize := int64(10000), int64(0)
+	return CapacityResult{
+		SchemaVersion: capacitySchemaVersion, ClusterIdentity: identity, CollectedAt: time.Unix(int64(day)*24*3600, 0),
+		Databases: []DatabaseCapacity{{
+			Name: "app", TotalSizeBytes: &dbTotal,
+			Filesystems: []FilesystemCapacity{{Shard: "shard-a", Host: "n1:27017", FSUsedSizeBytes: &fsUsed, FSTotalSizeBytes: &fsTotal}},
+			Collections: []CollectionCapacity{{Namespace: "app.events", StorageSizeBytes: &collectionStorage, IndexSizeBytes: &indexSize}},
+		}},
+	}
+}
+
+func TestForecastCapacityProjectsFilesystemAndQuotaExhaustion(t *testing.T) {
+	// 场景：文件系统每天增长 100 字节，剩余 6000 字节时预计 60 天耗尽并报告带置信区间的 warning；数据库配额 20 天内耗尽报告 critical，
+	// 未配置配额的集合只输出趋势；快照乱序输入时按采集时间排序。
+	identity := CapacityIdentity{TopologyType: ClusterReplicaSet, Digest: "same"}
+	snapshots := []CapacityResult{
+		forecastSnapshot(identity, 3, 4000, 800, 130),
+		forecastSnapshot(identity, 0, 3700, 500, 100),
+		forecastSnapshot(identity, 1, 3800, 600, 110),
+		forecastSnapshot(identity, 2, 3900, 700, 120),
+	}
+	result, err := ForecastCapacity(snapshots, CapacityForecastOptions{Quotas: map[string]int64{"app": 2800}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Snapshots != 4 || result.Method != CapacityForecastLinear || len(result.Filesystems) != 1 || len(result.Databases) != 1 || len(result.Collections) != 1 {
+		t.Fatalf("result = %#v", result)
+	}
+	filesystem := result.Filesystems[0]
+	if filesystem.Name != "shard-a/n1:27017" || *filesystem.GrowthPerDay != 100 || *filesystem.DaysUntilLimit != 60 || *filesystem.DaysUntilLimitMin != 60 || filesystem.LimitSource != "filesystem" {
+		t.Fatalf("filesystem trend = %#v", filesystem)
+	}
+	if database := result.Databases[0]; database.DaysUntilLimit == nil || *database.DaysUntilLimit != 20 || database.LimitSource != "quota" {
+		t.Fatalf("database trend = %#v", database)
+	}
+	if collection := result.Collections[0]; *collection.GrowthPerDay != 10 || collection.LimitBytes != nil || collection.DaysUntilLimit != nil {
+		t.Fatalf("collection trend = %#v", collection)
+	}
+	if len(result.Findings) != 2 || result.Findings[0].Severity != SeverityCritical || result.Findings[0].Scope.Database != "app" || result.Findings[1].Code != "capacity.exhaustion_forecast" || result.Findings[1].Scope.Node != "n1:27017" {
+		t.Fatalf("findings = %#v", result.Findings)
+	}
+	if evidence := result.Findings[1].Evidence; evidence["daysUntilLimit"] != 60.0 || evidence["confidence"] != 0.95 || evidence["limitBytes"] != int64(10000) {
+		t.Fatalf("evidence = %#v", evidence)
+	}
+	if short, err := ForecastCapacity(snapshots, CapacityForecastOptions{Horizon: 30 * 24 * time.Hour}); err != nil || len(short.Findings) != 0 {
+		t.Fatalf("findings outside horizon = %#v, %v", short, err)
+	}
+}
+
+func TestForecastCapacityRobustIgnoresOutlierAndRejectsMixedClusters(t *testing.T) {
+	// 场景：robust 使用 Theil-Sen 斜率，单个异常快照不改变中位斜率而最小二乘会被拉偏；不同集群、重复采集时间和不足 3 个快照都拒绝。
+	identity := CapacityIdentity{TopologyType: ClusterReplicaSet, Digest: "same"}
+	var snapshots []CapacityResult
+	for day := 0; day < 6; day++ {
+		used := int64(1000 + 50*day)
+		if day == 3 {
+			used += 2000
+		}
+		snapshots = append(snapshots, forecastSnapshot(identity, day, used, 100, 100))
+	}
+	robust, err := ForecastCapacity(snapshots, CapacityForecastOptions{Method: CapacityForecastRobust})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slope := *robust.Filesystems[0].GrowthPerDay; slope != 50 {
+		t.Fatalf("robust slope = %f", slope)
+	}
+	if low, high := *robust.Filesystems[0].GrowthPerDayLow, *robust.Filesystems[0].GrowthPerDayHigh; low > 50 || high < 50 {
+		t.Fatalf("robust band = [%f, %f]", low, high)
+	}
+	linear, err := ForecastCapacity(snapshots, CapacityForecastOptions{})
+	if err != nil || math.Abs(*linear.Filesystems[0].GrowthPerDay-50) < 1 {
+		t.Fatalf("linear trend = %#v, %v", linear, err)
+	}
+
+	other := forecastSnapshot(CapacityIdentity{TopologyType: ClusterReplicaSet, Digest: "other"}, 9, 1, 1, 1)
+	duplicate := snapshots[1]
+	for name, input := range map[string][]CapacityResult{
+		"mixed":     append(append([]CapacityResult(nil), snapshots...), other),
+		"duplicate": append(append([]CapacityResult(nil), snapshots...), duplicate),
+		"too few":   snapshots[:2],
+	} {
+		if _, err := ForecastCapacity(input, CapacityForecastOptions{}); !errors.Is(err, ErrInvalidOptions) {
+			t.Fatalf("%s error = %v", name, err)
+		}
+	}
+	if _, err := ForecastCapacity(snapshots, CapacityForecastOptions{Method: "quadratic"}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("unknown method error = %v", err)
+	}
+}
+
+func TestTheilSenSlopeMatchesReferenceInterval(t *testing.T) {
+	// 场景：y=x³ 的两两斜率互不相同（少量重复），置信区间的秩与 scipy.stats.theilslopes 在 95% 下的结果一致：
+	// n=10 时 45 个斜率取下标 11 与 33，n=5 时 10 个斜率取下标 0 与 9。
+	for _, tt := range []struct {
+		n                 int
+		median, low, high float64
+	}{
+		{n: 10, median: 67, low: 36, high: 109},
+		{n: 5, median: 14.5, low: 1, high: 37},
+	} {
+		var points []capacityPoint
+		for i := 0; i < tt.n; i++ {
+			points = append(points, capacityPoint{days: float64(i), bytes: int64(i * i * i)})
+		}
+		median, low, high := theilSenSlope(points)
+		if median != tt.median || low != tt.low || high != tt.high {
+			t.Fatalf("n=%d theil-sen = %v [%v, %v], want %v [%v, %v]", tt.n, median, low, high, tt.median, tt.low, tt.high)
+		}
+	}
+}
+
+func TestForecastCapacitySkipsSeriesMissingFromLatestSnapshot(t *testing.T) {
+	// 场景：集合在最后一个快照前被删除，只输出最后出现时间，不拟合趋势也不产生耗尽预测；仍存在的集合照常预测。
+	identity := CapacityIdentity{TopologyType: ClusterReplicaSet, Digest: "same"}
+	var snapshots []CapacityResult
+	for day := 0; day < 4; day++ {
+		snapshot := forecastSnapshot(identity, day, 1000, 100, int64(100+10*day))
+		if day < 3 {
+			dropped, index := int64(500+100*day), int64(0)
+			snapshot.Databases[0].Collections = append(snapshot.Databases[0].Collections, CollectionCapacity{Namespace: "app.dropped", StorageSizeBytes: &dropped, IndexSizeBytes: &index})
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	result, err := ForecastCapacity(snapshots, CapacityForecastOptions{Quotas: map[string]int64{"app.dropped": 800}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Collections) != 2 || result.Collections[0].Name != "app.dropped" {
+		t.Fatalf("collections = %#v", result.Collections)
+	}
+	dropped := result.Collections[0]
+	if dropped.LastSeenAt == nil || !dropped.LastSeenAt.Equal(snapshots[2].CollectedAt) || dropped.GrowthPerDay != nil || dropped.DaysUntilLimit != nil {
+		t.Fatalf("dropped trend = %#v", dropped)
+	}
+	if events := result.Collections[1]; events.LastSeenAt != nil || events.GrowthPerDay == nil || *events.GrowthPerDay != 10 {
+		t.Fatalf("events trend = %#v", events)
+	}
+	if hasFindingCode(result.Findings, "capacity.exhaustion_forecast") {
+		t.Fatalf("findings = %#v, want no forecast for dropped collection", result.Findings)
+	}
+}
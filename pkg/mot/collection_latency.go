@@ -0,0 +1,465 @@
+package mot
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+const (
+	defaultLatencyP99Threshold = 100 * time.Millisecond
+	defaultLatencyMaxTime      = 5 * time.Second
+)
+
+type CollectionLatencyMode string
+
+const (
+	CollectionLatencyCumulative CollectionLatencyMode = "cumulative"
+	CollectionLatencySampled    CollectionLatencyMode = "sampled"
+)
+
+type CollectionLatencyOptions struct {
+	Databases       []string
+	Collections     []string
+	IncludeSystemDB bool
+	// Duration 为 0 时只采集一次自进程启动以来的累计分布；大于 0 时按两次快照差分。
+	Duration        time.Duration
+	P99Threshold    time.Duration
+	MaxCollections  int
+	NodeConcurrency int
+	// MaxTime 是单个 $collStats 的 maxTimeMS，为 0 时使用 5s。
+	MaxTime time.Duration
+}
+
+// LatencyHistogramBucket 直接复用 pkg/mongo 的 bucket 定义，避免两套同名类型互相转换。
+type LatencyHistogramBucket = pkgmongo.LatencyHistogramBucket
+
+// LatencyDistribution 的 percentile 取命中 bucket 的下界，是保守近似值。
+type LatencyDistribution struct {
+	Ops           int64                    `json:"ops"`
+	TotalMicros   int64                    `json:"totalMicros"`
+	AverageMicros *float64                 `json:"averageMicros,omitempty"`
+	P50Micros     *int64                   `json:"p50Micros,omitempty"`
+	P95Micros     *int64                   `json:"p95Micros,omitempty"`
+	P99Micros     *int64                   `json:"p99Micros,omitempty"`
+	Histogram     []LatencyHistogramBucket `json:"histogram,omitempty"`
+}
+
+type NamespaceLatency struct {
+	Shard     string              `json:"shard,omitempty"`
+	Host      string              `json:"host"`
+	Namespace string              `json:"namespace"`
+	Reads     LatencyDistribution `json:"reads"`
+	Writes    LatencyDistribution `json:"writes"`
+	Commands  LatencyDistribution `json:"commands"`
+}
+
+type CollectionLatencyResult struct {
+	ClusterType       ClusterType           `json:"clusterType"`
+	Mode              CollectionLatencyMode `json:"mode"`
+	StartedAt         time.Time             `json:"startedAt"`
+	FinishedAt        time.Time             `json:"finishedAt"`
+	EffectiveDuration time.Duration         `json:"effectiveDuration"`
+	Namespaces        []NamespaceLatency    `json:"namespaces"`
+	Findings          []DiagnosticFinding   `json:"findings"`
+	CollectorStatuses []CollectorStatus     `json:"collectorStatuses"`
+}
+
+type collectionLatencyNodeSnapshot struct {
+	Identity    string
+	Shard       string
+	Address     string
+	CollectedAt time.Time
+	Namespaces  map[string]pkgmongo.CollectionLatencySnapshot
+}
+
+type collectionLatencySnapshot struct {
+	CollectedAt time.Time
+	Nodes       []collectionLatencyNodeSnapshot
+}
+
+// CollectionLatency 在每个 mongod 数据节点上按集合采集 latencyStats histogram。
+func (c *Client) CollectionLatency(ctx context.Context, opts CollectionLatencyOptions) (result *CollectionLatencyResult, err error) {
+	if c != nil && c.session == nil {
+		return withEphemeralCollectorSession(ctx, c, func(session *CollectorSession) (*CollectionLatencyResult, error) {
+			return session.CollectionLatency(ctx, opts)
+		})
+	}
+	opts, err = normalizeCollectionLatencyOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.requireMemberConnectionURI(); err != nil {
+		return nil, err
+	}
+	defer func() { err = mapContextError(err) }()
+
+	cluster, err := c.detectCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clusterType := convertClusterType(cluster.Type)
+	if gate, allowed := diagnosticCapabilityGate("collection_latency", clusterType, cluster.MaxWireVersion, true); !allowed {
+		return &CollectionLatencyResult{ClusterType: clusterType, CollectorStatuses: []CollectorStatus{gate}}, nil
+	}
+	refs, err := c.indexCollectionRefs(ctx, IndexAuditOptions{Databases: opts.Databases, AllDatabases: len(opts.Databases) == 0, Collections: opts.Collections, IncludeSystemDB: opts.IncludeSystemDB, MaxCollections: opts.MaxCollections})
+	if err != nil {
+		return nil, err
+	}
+	refs = latencyCollectionRefs(refs)
+	if len(refs) == 0 {
+		return nil, invalidOptions("no collections selected")
+	}
+	targets, targetStatuses, targetErrors := c.discoverHotspotTargets(ctx, cluster.Type)
+	if len(targets) == 0 {
+		return &CollectionLatencyResult{ClusterType: clusterType, CollectorStatuses: targetStatuses}, errors.Join(targetErrors...)
+	}
+	first, firstStatuses, firstErrors := c.collectCollectionLatencySnapshot(ctx, targets, refs, opts)
+	collectorStatuses := append(targetStatuses, firstStatuses...)
+	collectorErrors := append(targetErrors, firstErrors...)
+	var resultValue CollectionLatencyResult
+	if opts.Duration == 0 {
+		resultValue = summarizeCollectionLatency(first, opts)
+	} else {
+		if len(first.Nodes) == 0 {
+			return &CollectionLatencyResult{ClusterType: clusterType, Mode: CollectionLatencySampled, StartedAt: first.CollectedAt, CollectorStatuses: collectorStatuses}, errors.Join(collectorErrors...)
+		}
+		if waitErr := waitForHotspotSample(ctx, opts.Duration); waitErr != nil {
+			partial := &CollectionLatencyResult{ClusterType: clusterType, Mode: CollectionLatencySampled, StartedAt: first.CollectedAt, CollectorStatuses: collectorStatuses}
+			sortCollectorStatuses(partial.CollectorStatuses)
+			return partial, newDiagnosticPartialError("collection-latency", partial, waitErr)
+		}
+		second, secondStatuses, secondErrors := c.collectCollectionLatencySnapshot(ctx, targets, refs, opts)
+		collectorStatuses = append(collectorStatuses, secondStatuses...)
+		collectorErrors = append(collectorErrors, secondErrors...)
+		resultValue = diffCollectionLatency(first, second, opts)
+	}
+	resultValue.ClusterType = clusterType
+	resultValue.CollectorStatuses = append(resultValue.CollectorStatuses, collectorStatuses...)
+	sortCollectorStatuses(resultValue.CollectorStatuses)
+	result = &resultValue
+	if len(collectorErrors) > 0 {
+		return result, newDiagnosticPartialError("collection-latency", result, errors.Join(collectorErrors...))
+	}
+	return result, nil
+}
+
+func latencyCollectionRefs(refs []indexCollectionRef) []indexCollectionRef {
+	result := make([]indexCollectionRef, 0, len(refs))
+	for _, ref := range refs {
+		if ref.Type == "" || ref.Type == "collection" {
+			result = append(result, ref)
+		}
+	}
+	return result
+}
+
+func (c *Client) collectCollectionLatencySnapshot(ctx context.Context, targets []hotspotTarget, refs []indexCollectionRef, opts CollectionLatencyOptions) (collectionLatencySnapshot, []CollectorStatus, []error) {
+	snapshot := collectionLatencySnapshot{CollectedAt: time.Now().UTC()}
+	var statuses []CollectorStatus
+	var collectorErrors []error
+	var mu sync.Mutex
+	group, groupCtx := errgroup.WithContext(ctx)
+	limit := semaphore.NewWeighted(int64(opts.NodeConcurrency))
+	for _, target := range targets {
+		if acquireErr := acquireDiagnosticSlot(groupCtx, limit); acquireErr != nil {
+			mu.Lock()
+			collectorErrors = append(collectorErrors, acquireErr)
+			mu.Unlock()
+			break
+		}
+		target := target
+		group.Go(func() error {
+			defer limit.Release(1)
+			release, acquireErr := c.acquireRemoteSlot(groupCtx)
+			if acquireErr != nil {
+				mu.Lock()
+				collectorErrors = append(collectorErrors, acquireErr)
+				mu.Unlock()
+				return nil
+			}
+			defer release()
+			scope := FindingScope{Type: ScopeNode, ReplicaSet: target.ReplicaSet, Shard: target.Shard, Node: target.Address}
+			conn, connectErr := c.connectAddress(groupCtx, target.Address, derivedConnectionOptions{Direct: boolPointer(true)})
+			if connectErr != nil {
+				mu.Lock()
+				collectorErrors = append(collectorErrors, connectErr)
+				statuses = append(statuses, failedCollectorStatus("collection_latency", scope, connectErr))
+				mu.Unlock()
+				return nil
+			}
+			defer c.closeDerivedConnection(groupCtx, conn)
+			node := collectionLatencyNodeSnapshot{
+				Identity: target.ReplicaSet + "/" + target.Address, Shard: target.Shard, Address: target.Address,
+				Namespaces: make(map[string]pkgmongo.CollectionLatencySnapshot, len(refs)),
+			}
+			var nodeStatuses []CollectorStatus
+			var nodeErrors []error
+			for _, ref := range refs {
+				namespace := ref.Database + "." + ref.Collection
+				stats, statsErr := conn.CollectionLatencyStats(groupCtx, ref.Database, ref.Collection, opts.MaxTime)
+				if isNamespaceNotFoundError(statsErr) {
+					// 未分片集合只存在于 primary shard，其余 shard 上视为不存在而非采集失败。
+					continue
+				}
+				if statsErr != nil {
+					namespaceScope := FindingScope{Type: ScopeNamespace, ReplicaSet: target.ReplicaSet, Shard: target.Shard, Node: target.Address, Database: ref.Database, Namespace: namespace}
+					if !isUnauthorizedError(statsErr) && !isUnsupportedDiagnosticError(statsErr) {
+						nodeErrors = append(nodeErrors, statsErr)
+					}
+					nodeStatuses = append(nodeStatuses, failedCollectorStatus("collection_latency", namespaceScope, statsErr))
+					if cancelErr := contextError(groupCtx); cancelErr != nil {
+						break
+					}
+					continue
+				}
+				node.Namespaces[namespace] = stats
+			}
+			node.CollectedAt = time.Now().UTC()
+			mu.Lock()
+			defer mu.Unlock()
+			statuses = append(statuses, nodeStatuses...)
+			collectorErrors = append(collectorErrors, nodeErrors...)
+			if len(node.Namespaces) == 0 {
+				return nil
+			}
+			snapshot.Nodes = append(snapshot.Nodes, node)
+			statuses = append(statuses, CollectorStatus{Name: "collection_latency", State: CapabilitySupported, Scope: scope})
+			return nil
+		})
+	}
+	_ = group.Wait()
+	snapshot.CollectedAt = time.Now().UTC()
+	sort.SliceStable(snapshot.Nodes, func(i, j int) bool { return snapshot.Nodes[i].Identity < snapshot.Nodes[j].Identity })
+	sortCollectorStatuses(statuses)
+	return snapshot, statuses, collectorErrors
+}
+
+func normalizeCollectionLatencyOptions(opts CollectionLatencyOptions) (CollectionLatencyOptions, error) {
+	if opts.Duration < 0 {
+		return CollectionLatencyOptions{}, invalidOptions("duration must not be negative")
+	}
+	if opts.P99Threshold < 0 {
+		return CollectionLatencyOptions{}, invalidOptions("p99 threshold must not be negative")
+	}
+	if opts.MaxCollections < 0 || opts.NodeConcurrency < 0 {
+		return CollectionLatencyOptions{}, invalidOptions("max collections and node concurrency must not be negative")
+	}
+	if opts.MaxTime < 0 {
+		return CollectionLatencyOptions{}, invalidOptions("max time must not be negative")
+	}
+	if opts.P99Threshold == 0 {
+		opts.P99Threshold = defaultLatencyP99Threshold
+	}
+	if opts.MaxCollections == 0 {
+		opts.MaxCollections = defaultMaxCollections
+	}
+	if opts.NodeConcurrency == 0 {
+		opts.NodeConcurrency = defaultOverviewNodeConcurrency
+	}
+	if opts.MaxTime == 0 {
+		opts.MaxTime = defaultLatencyMaxTime
+	}
+	return opts, nil
+}
+
+// summarizeCollectionLatency 把单次快照视为自进程启动以来的累计分布。
+func summarizeCollectionLatency(snapshot collectionLatencySnapshot, opts CollectionLatencyOptions) CollectionLatencyResult {
+	result := CollectionLatencyResult{Mode: CollectionLatencyCumulative, StartedAt: snapshot.CollectedAt, FinishedAt: snapshot.CollectedAt}
+	for _, node := range snapshot.Nodes {
+		for namespace, stats := range node.Namespaces {
+			if stats.Reads.Ops == 0 && stats.Writes.Ops == 0 && stats.Commands.Ops == 0 {
+				continue
+			}
+			result.Namespaces = append(result.Namespaces, NamespaceLatency{
+				Shard: node.Shard, Host: node.Address, Namespace: namespace,
+				Reads: latencyDistribution(stats.Reads), Writes: latencyDistribution(stats.Writes), Commands: latencyDistribution(stats.Commands),
+			})
+		}
+	}
+	finishCollectionLatency(&result, opts)
+	return result
+}
+
+// diffCollectionLatency 只比较两次快照都存在的 namespace；任一累计计数下降都视为 reset。
+func diffCollectionLatency(first, second collectionLatencySnapshot, opts CollectionLatencyOptions) CollectionLatencyResult {
+	result := CollectionLatencyResult{
+		Mode: CollectionLatencySampled, StartedAt: first.CollectedAt, FinishedAt: second.CollectedAt,
+		EffectiveDuration: second.CollectedAt.Sub(first.CollectedAt),
+	}
+	firstByIdentity := make(map[string]collectionLatencyNodeSnapshot, len(first.Nodes))
+	secondByIdentity := make(map[string]collectionLatencyNodeSnapshot, len(second.Nodes))
+	for _, node := range first.Nodes {
+		firstByIdentity[node.Identity] = node
+	}
+	for _, node := range second.Nodes {
+		secondByIdentity[node.Identity] = node
+		previous, ok := firstByIdentity[node.Identity]
+		if !ok {
+			continue
+		}
+		for namespace, current := range node.Namespaces {
+			before, existed := previous.Namespaces[namespace]
+			if !existed {
+				continue
+			}
+			reads, readsOK := latencyOperationDelta(before.Reads, current.Reads)
+			writes, writesOK := latencyOperationDelta(before.Writes, current.Writes)
+			commands, commandsOK := latencyOperationDelta(before.Commands, current.Commands)
+			if !readsOK || !writesOK || !commandsOK {
+				result.Findings = append(result.Findings, DiagnosticFinding{Code: "latency.namespace_counter_reset", Severity: SeverityInfo, Scope: FindingScope{Type: ScopeNamespace, Shard: node.Shard, Node: node.Address, Namespace: namespace}, Summary: "namespace 延迟累计计数器在采样窗口内重置，未计算该项分布"})
+				continue
+			}
+			if reads.Ops == 0 && writes.Ops == 0 && commands.Ops == 0 {
+				continue
+			}
+			result.Namespaces = append(result.Namespaces, NamespaceLatency{
+				Shard: node.Shard, Host: node.Address, Namespace: namespace,
+				Reads: latencyDistribution(reads), Writes: latencyDistribution(writes), Commands: latencyDistribution(commands),
+			})
+		}
+	}
+	for identity, node := range firstByIdentity {
+		if _, ok := secondByIdentity[identity]; ok {
+			continue
+		}
+		result.CollectorStatuses = append(result.CollectorStatuses, CollectorStatus{
+			Name: "collection_latency", State: CapabilityFailed,
+			Scope:      FindingScope{Type: ScopeNode, Shard: node.Shard, Node: node.Address},
+			ReasonCode: "node_unreachable", Message: "第二快照未返回该节点",
+		})
+	}
+	finishCollectionLatency(&result, opts)
+	return result
+}
+
+func finishCollectionLatency(result *CollectionLatencyResult, opts CollectionLatencyOptions) {
+	thresholdMicros := opts.P99Threshold.Microseconds()
+	for _, item := range result.Namespaces {
+		scope := FindingScope{Type: ScopeNamespace, Shard: item.Shard, Node: item.Host, Namespace: item.Namespace}
+		for _, operation := range []struct {
+			name         string
+			summary      string
+			distribution LatencyDistribution
+		}{
+			{"reads", "namespace 读操作 p99 延迟超过阈值", item.Reads},
+			{"writes", "namespace 写操作 p99 延迟超过阈值", item.Writes},
+			{"commands", "namespace 命令 p99 延迟超过阈值", item.Commands},
+		} {
+			p99 := operation.distribution.P99Micros
+			if thresholdMicros <= 0 || p99 == nil || *p99 < thresholdMicros {
+				continue
+			}
+			result.Findings = append(result.Findings, DiagnosticFinding{
+				Code: "latency.p99_high", Severity: SeverityWarning, Scope: scope, Summary: operation.summary,
+				Evidence:       map[string]any{"operationType": operation.name, "p99Micros": *p99, "thresholdMicros": thresholdMicros, "ops": operation.distribution.Ops, "mode": string(result.Mode)},
+				Recommendation: "结合 slowlog 与执行计划定位该 namespace 的高延迟查询形状",
+			})
+		}
+	}
+	sort.SliceStable(result.Namespaces, func(i, j int) bool {
+		left, right := result.Namespaces[i], result.Namespaces[j]
+		if worstLatencyP99(left) != worstLatencyP99(right) {
+			return worstLatencyP99(left) > worstLatencyP99(right)
+		}
+		if left.Namespace != right.Namespace {
+			return left.Namespace < right.Namespace
+		}
+		if left.Shard != right.Shard {
+			return left.Shard < right.Shard
+		}
+		return left.Host < right.Host
+	})
+	sanitizeAndSortFindings(result.Findings)
+	sortCollectorStatuses(result.CollectorStatuses)
+}
+
+func worstLatencyP99(item NamespaceLatency) int64 {
+	var worst int64
+	for _, value := range []*int64{item.Reads.P99Micros, item.Writes.P99Micros, item.Commands.P99Micros} {
+		if value != nil && *value > worst {
+			worst = *value
+		}
+	}
+	return worst
+}
+
+func latencyOperationDelta(before, after pkgmongo.LatencyOperationSnapshot) (pkgmongo.LatencyOperationSnapshot, bool) {
+	if after.Ops < before.Ops || after.LatencyMicros < before.LatencyMicros {
+		return pkgmongo.LatencyOperationSnapshot{}, false
+	}
+	previous := make(map[int64]int64, len(before.Histogram))
+	for _, bucket := range before.Histogram {
+		previous[bucket.LowerBoundMicros] = bucket.Count
+	}
+	delta := pkgmongo.LatencyOperationSnapshot{Ops: after.Ops - before.Ops, LatencyMicros: after.LatencyMicros - before.LatencyMicros}
+	for _, bucket := range after.Histogram {
+		count := bucket.Count - previous[bucket.LowerBoundMicros]
+		if count < 0 {
+			return pkgmongo.LatencyOperationSnapshot{}, false
+		}
+		delete(previous, bucket.LowerBoundMicros)
+		if count > 0 {
+			delta.Histogram = append(delta.Histogram, LatencyHistogramBucket{LowerBoundMicros: bucket.LowerBoundMicros, Count: count})
+		}
+	}
+	for _, count := range previous {
+		if count > 0 {
+			return pkgmongo.LatencyOperationSnapshot{}, false
+		}
+	}
+	return delta, true
+}
+
+func latencyDistribution(stats pkgmongo.LatencyOperationSnapshot) LatencyDistribution {
+	result := LatencyDistribution{Ops: stats.Ops, TotalMicros: stats.LatencyMicros}
+	if stats.Ops > 0 {
+		average := float64(stats.LatencyMicros) / float64(stats.Ops)
+		result.AverageMicros = &average
+	}
+	var total int64
+	for _, bucket := range stats.Histogram {
+		if bucket.Count <= 0 {
+			continue
+		}
+		total += bucket.Count
+		result.Histogram = append(result.Histogram, bucket)
+	}
+	histogram := result.Histogram
+	sort.SliceStable(histogram, func(i, j int) bool { return histogram[i].LowerBoundMicros < histogram[j].LowerBoundMicros })
+	result.P50Micros = histogramPercentile(result.Histogram, total, 0.50)
+	result.P95Micros = histogramPercentile(result.Histogram, total, 0.95)
+	result.P99Micros = histogramPercentile(result.Histogram, total, 0.99)
+	return result
+}
+
+func histogramPercentile(histogram []LatencyHistogramBucket, total int64, percentile float64) *int64 {
+	if total <= 0 {
+		return nil
+	}
+	rank := int64(math.Ceil(float64(total) * percentile))
+	if rank < 1 {
+		rank = 1
+	}
+	var cumulative int64
+	for _, bucket := range histogram {
+		cumulative += bucket.Count
+		if cumulative >= rank {
+			value := bucket.LowerBoundMicros
+			return &value
+		}
+	}
+	value := histogram[len(histogram)-1].LowerBoundMicros
+	return &value
+}
@@ -0,0 +1,84 @@
+package mot
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+func TestSummarizeCollectionLatencyReportsPercentilesAndThreshold(t *testing.T) {
+	// 场景：单快照模式按 histogram 下界估算 percentile，p99 达到阈值时输出 warning。
+	snapshot := collectionLatencySnapshot{
+		CollectedAt: time.Date(2026, 7, 14, 0, 0, 0, 0, time.UTC),
+		Nodes: []collectionLatencyNodeSnapshot{{
+			Identity: "rs0/n1", Address: "n1",
+			Namespaces: map[string]pkgmongo.CollectionLatencySnapshot{
+				"db.slow": {Reads: pkgmongo.LatencyOperationSnapshot{Ops: 100, LatencyMicros: 500000, Histogram: []pkgmongo.LatencyHistogramBucket{
+					{LowerBoundMicros: 128, Count: 90}, {LowerBoundMicros: 1024, Count: 8}, {LowerBoundMicros: 262144, Count: 2},
+				}}},
+				"db.idle": {},
+			},
+		}},
+	}
+
+	result := summarizeCollectionLatency(snapshot, CollectionLatencyOptions{P99Threshold: 200 * time.Millisecond})
+	if result.Mode != CollectionLatencyCumulative || len(result.Namespaces) != 1 {
+		t.Fatalf("result = %#v, want one cumulative namespace", result)
+	}
+	reads := result.Namespaces[0].Reads
+	if reads.P50Micros == nil || *reads.P50Micros != 128 || reads.P95Micros == nil || *reads.P95Micros != 1024 || reads.P99Micros == nil || *reads.P99Micros != 262144 {
+		t.Fatalf("percentiles = %v/%v/%v", reads.P50Micros, reads.P95Micros, reads.P99Micros)
+	}
+	if reads.AverageMicros == nil || *reads.AverageMicros != 5000 {
+		t.Fatalf("average = %v, want 5000", reads.AverageMicros)
+	}
+	assertFindingCode(t, result.Findings, "latency.p99_high", SeverityWarning)
+	if result.Namespaces[0].Writes.P99Micros != nil {
+		t.Fatalf("empty writes p99 = %v, want unavailable", *result.Namespaces[0].Writes.P99Micros)
+	}
+}
+
+func TestDiffCollectionLatencyUsesBucketDeltasAndRejectsReset(t *testing.T) {
+	// 场景：双快照只统计窗口内新增 bucket；累计计数下降时标记 reset，不生成伪分布。
+	start := time.Date(2026, 7, 14, 0, 0, 0, 0, time.UTC)
+	first := collectionLatencySnapshot{CollectedAt: start, Nodes: []collectionLatencyNodeSnapshot{{
+		Identity: "rs0/n1", Address: "n1",
+		Namespaces: map[string]pkgmongo.CollectionLatencySnapshot{
+			"db.c":     {Writes: pkgmongo.LatencyOperationSnapshot{Ops: 1000, LatencyMicros: 100000, Histogram: []pkgmongo.LatencyHistogramBucket{{LowerBoundMicros: 64, Count: 1000}}}},
+			"db.reset": {Reads: pkgmongo.LatencyOperationSnapshot{Ops: 50, LatencyMicros: 500, Histogram: []pkgmongo.LatencyHistogramBucket{{LowerBoundMicros: 8, Count: 50}}}},
+		},
+	}}}
+	second := collectionLatencySnapshot{CollectedAt: start.Add(10 * time.Second), Nodes: []collectionLatencyNodeSnapshot{{
+		Identity: "rs0/n1", Address: "n1",
+		Namespaces: map[string]pkgmongo.CollectionLatencySnapshot{
+			"db.c":     {Writes: pkgmongo.LatencyOperationSnapshot{Ops: 1010, LatencyMicros: 1100000, Histogram: []pkgmongo.LatencyHistogramBucket{{LowerBoundMicros: 64, Count: 1000}, {LowerBoundMicros: 131072, Count: 10}}}},
+			"db.reset": {Reads: pkgmongo.LatencyOperationSnapshot{Ops: 2, LatencyMicros: 20, Histogram: []pkgmongo.LatencyHistogramBucket{{LowerBoundMicros: 8, Count: 2}}}},
+		},
+	}}}
+
+	result := diffCollectionLatency(first, second, CollectionLatencyOptions{P99Threshold: 100 * time.Millisecond})
+	if result.EffectiveDuration != 10*time.Second || len(result.Namespaces) != 1 {
+		t.Fatalf("result = %#v", result)
+	}
+	writes := result.Namespaces[0].Writes
+	if writes.Ops != 10 || writes.P50Micros == nil || *writes.P50Micros != 131072 {
+		t.Fatalf("writes delta = %#v", writes)
+	}
+	assertFindingCode(t, result.Findings, "latency.p99_high", SeverityWarning)
+	assertFindingCode(t, result.Findings, "latency.namespace_counter_reset", SeverityInfo)
+}
+
+func TestNormalizeCollectionLatencyOptionsRejectsNegativeValues(t *testing.T) {
+	// 场景：负 duration/阈值在发起远程采集前返回 ErrInvalidOptions，零值使用默认阈值。
+	for _, opts := range []CollectionLatencyOptions{{Duration: -time.Second}, {P99Threshold: -time.Millisecond}, {NodeConcurrency: -1}, {MaxTime: -time.Second}} {
+		if _, err := normalizeCollectionLatencyOptions(opts); !errors.Is(err, ErrInvalidOptions) {
+			t.Fatalf("options %#v error = %v, want ErrInvalidOptions", opts, err)
+		}
+	}
+	opts, err := normalizeCollectionLatencyOptions(CollectionLatencyOptions{})
+	if err != nil || opts.P99Threshold != defaultLatencyP99Threshold || opts.NodeConcurrency != defaultOverviewNodeConcurrency || opts.MaxTime != defaultLatencyMaxTime {
+		t.Fatalf("defaults = %#v, err = %v", opts, err)
+	}
+}
@@ -81,18 +81,35 @@ type DiagnosticCapability struct {
 func DiagnosticCapabilities() []DiagnosticCapability {
 	capabilities := []DiagnosticCapability{
 		{Name: "collection_capacity", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "collStats", Cost: CapabilityCostBounded},
+		{Name: "collection_latency", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "collStats", Cost: CapabilityCostBounded},
 		{Name: "current_operations", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "inprog", Cost: CapabilityCostLow, SensitiveFields: []string{"command", "client", "user", "session"}},
 		{Name: "database_capacity", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "dbStats", Cost: CapabilityCostBounded},
 		{Name: "free_storage", MinimumVersion: "3.6", MinimumWireVersion: 6, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "collStats", Cost: CapabilityCostExpensiveOptIn},
 		{Name: "hotspot_snapshot", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "top", Cost: CapabilityCostBounded},
+		{Name: "index_builds", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "inprog", Cost: CapabilityCostLow, SensitiveFields: []string{"command", "index key", "derived connection"}},
 		{Name: "index_consistency_direct", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterSharded}, Privilege: "listShards, find config metadata, listIndexes", Cost: CapabilityCostBounded, SensitiveFields: []string{"raw index spec", "partialFilterExpression", "derived connection"}},
 		{Name: "index_consistency_index_stats", MinimumVersion: "4.2.4", MinimumWireVersion: 8, Topologies: []ClusterType{ClusterSharded}, Privilege: "indexStats", Cost: CapabilityCostBounded, SensitiveFields: []string{"raw index spec", "partialFilterExpression"}},
 		{Name: "index_consistency_metadata_check", MinimumVersion: "7.0", MinimumWireVersion: 21, Topologies: []ClusterType{ClusterSharded}, Privilege: "checkMetadataConsistency", Cost: CapabilityCostBounded, SensitiveFields: []string{"raw inconsistency", "shard key values"}},
 		{Name: "index_consistency_visibility", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterSharded}, Privilege: "collStats", Cost: CapabilityCostBounded},
+		{Name: "index_lifecycle", MinimumVersion: "4.4", MinimumWireVersion: 9, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "collMod, indexStats", Cost: CapabilityCostLow},
+		{Name: "index_member_consistency", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "listIndexes", Cost: CapabilityCostBounded, SensitiveFields: []string{"raw index spec", "partialFilterExpression", "derived connection"}},
+		{Name: "index_shard_key", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterSharded}, Privilege: "find config metadata, indexStats, listIndexes, aggregate $sample", Cost: CapabilityCostBounded, SensitiveFields: []string{"raw index spec", "shard key values"}},
+		{Name: "index_snapshot", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "listCollections, listIndexes", Cost: CapabilityCostBounded, SensitiveFields: []string{"raw index spec", "partialFilterExpression"}},
+		{Name: "index_special", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "listIndexes, find config metadata", Cost: CapabilityCostBounded, SensitiveFields: []string{"raw index spec", "wildcardProjection", "weights"}},
+		{Name: "index_ttl", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "listIndexes, aggregate $sample", Cost: CapabilityCostBounded, SensitiveFields: []string{"raw index spec", "document values"}},
+		{Name: "index_ttl_monitor", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "serverStatus, getParameter", Cost: CapabilityCostLow, SensitiveFields: []string{"derived connection"}},
 		{Name: "index_usage", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "indexStats", Cost: CapabilityCostBounded},
 		{Name: "oplog_window", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "find local.oplog.rs", Cost: CapabilityCostLow},
+		{Name: "plan_cache", MinimumVersion: "4.2", MinimumWireVersion: 8, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "planCacheRead", Cost: CapabilityCostBounded, SensitiveFields: []string{"createdFromQuery"}},
+		{Name: "profiler_change", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "enableProfiler", Cost: CapabilityCostLow},
+		{Name: "profiler_status", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "enableProfiler", Cost: CapabilityCostLow},
+		{Name: "query_stats", MinimumVersion: "7.1", MinimumWireVersion: 22, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "queryStatsReadTransformed", Cost: CapabilityCostBounded, SensitiveFields: []string{"queryShape", "client"}},
 		{Name: "replica_status", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "replSetGetStatus", Cost: CapabilityCostLow},
+		{Name: "scatter_gather", MinimumVersion: "3.6", MinimumWireVersion: 6, Topologies: []ClusterType{ClusterSharded}, Privilege: "getLog, find config metadata", Cost: CapabilityCostBounded, SensitiveFields: []string{"command", "filter", "client", "user", "session"}},
 		{Name: "server_status", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "serverStatus", Cost: CapabilityCostLow},
+		{Name: "slowlog_advise", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "find system.profile, listIndexes", Cost: CapabilityCostBounded, SensitiveFields: []string{"command", "filter", "pipeline"}},
+		{Name: "slowlog_digest", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "find system.profile", Cost: CapabilityCostBounded, SensitiveFields: []string{"command", "filter", "pipeline"}},
+		{Name: "slowlog_getlog", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "getLog", Cost: CapabilityCostLow, SensitiveFields: []string{"command", "filter", "client", "user", "session"}},
 		{Name: "slowlog_insight", MinimumVersion: "3.4", MinimumWireVersion: 5, Topologies: []ClusterType{ClusterReplicaSet, ClusterSharded}, Privilege: "find system.profile", Cost: CapabilityCostBounded, SensitiveFields: []string{"command", "filter", "client", "user", "session"}},
 	}
 	sort.SliceStable(capabilities, func(i, j int) bool { return capabilities[i].Name < capabilities[j].Name })
@@ -281,7 +298,7 @@ func isUnsupportedDiagnosticError(err error) bool {
 	var commandError drivermongo.CommandError
 	if errors.As(err, &commandError) {
 		switch commandError.Code {
-		case 9, 59, 115, 16436, 168, 40324:
+		case 9, 59, 115, 16436, 168, 224, 40324:
 			return true
 		}
 	}
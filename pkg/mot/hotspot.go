@@ -86,6 +86,7 @@ type hotspotTarget struct {
 	ReplicaSet string
 	Shard      string
 	Address    string
+	Primary    bool
 }
 
 // Hotspot 采集 mongod 数据节点的两次累计快照，并按实际间隔计算热点 rate。
@@ -162,7 +163,7 @@ func (c *Client) discoverHotspotTargets(ctx context.Context, clusterType pkgmong
 			if member.Health != 1 || (member.State != pkgmongo.StatePrimary && member.State != pkgmongo.StateSecondary) {
 				continue
 			}
-			targets = append(targets, hotspotTarget{ReplicaSet: inventory.Name, Shard: shard, Address: member.Name})
+			targets = append(targets, hotspotTarget{ReplicaSet: inventory.Name, Shard: shard, Address: member.Name, Primary: member.State == pkgmongo.StatePrimary})
 		}
 	}
 	switch clusterType {
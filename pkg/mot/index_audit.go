@@ -27,6 +27,14 @@ const (
 	IndexCheckSpace       IndexAuditCheck = "space"
 	IndexCheckBuilding    IndexAuditCheck = "building"
 	IndexCheckConsistency IndexAuditCheck = "consistency"
+	// IndexCheckMemberConsistency 比较同一副本集成员之间的索引定义，需显式指定，不在默认检查中。
+	IndexCheckMemberConsistency IndexAuditCheck = "member_consistency"
+	// IndexCheckShardKey 检查分片集合的分片键索引与唯一索引约束，需要 mongos 且需显式指定。
+	IndexCheckShardKey IndexAuditCheck = "shardkey"
+	// IndexCheckTTL 列出 TTL 索引、抽样字段类型并检查 TTL monitor，需显式指定。
+	IndexCheckTTL IndexAuditCheck = "ttl"
+	// IndexCheckSpecial 检查 text、wildcard、2dsphere 与 hashed 索引之间的重叠，需显式指定。
+	IndexCheckSpecial IndexAuditCheck = "special"
 )
 
 type IndexAuditOptions struct {
@@ -38,6 +46,8 @@ type IndexAuditOptions struct {
 	MinObservation  time.Duration
 	MaxCollections  int
 	Concurrency     int
+	// UsageLedger 提供历史 $indexStats 观测，使零使用判定可以跨越重启与索引重建；nil 时只使用本次计数。
+	UsageLedger *IndexUsageLedger
 }
 
 type IndexKeyField struct {
@@ -76,12 +86,19 @@ type CollectionIndexAudit struct {
 	Coverage            IndexConsistencyCoverage     `json:"coverage,omitempty"`
 	Fallback            *IndexConsistencyFallback    `json:"fallback,omitempty"`
 	Differences         []IndexConsistencyDifference `json:"differences,omitempty"`
+	MemberDifferences   []IndexMemberDifference      `json:"memberDifferences,omitempty"`
 	ConsistencyStatuses []CollectorStatus            `json:"consistencyStatuses,omitempty"`
 	DataSizeBytes       *int64                       `json:"dataSizeBytes,omitempty"`
 	IndexSizeBytes      *int64                       `json:"indexSizeBytes,omitempty"`
 	IndexToDataRatio    *float64                     `json:"indexToDataRatio,omitempty"`
 	Indexes             []IndexObservation           `json:"indexes"`
 	Findings            []DiagnosticFinding          `json:"findings"`
+	ShardKey            []IndexKeyField              `json:"shardKey,omitempty"`
+	TTLIndexes          []IndexTTLObservation        `json:"ttlIndexes,omitempty"`
+
+	// routing 与 shardDefinitions 是一致性检查已采集的路由和各 shard 索引定义，分片键检查直接复用。
+	routing          *pkgmongo.IndexRoutingSnapshot
+	shardDefinitions map[string][]pkgmongo.CanonicalIndexDefinition
 }
 
 type IndexAuditResult struct {
@@ -96,11 +113,15 @@ type indexCollectionRef struct {
 	Database   string
 	Collection string
 	Type       string
+	Capped     bool
 }
 
 type indexCollectionMetadata struct {
-	Name string `bson:"name"`
-	Type string `bson:"type"`
+	Name    string `bson:"name"`
+	Type    string `bson:"type"`
+	Options struct {
+		Capped bool `bson:"capped"`
+	} `bson:"options"`
 }
 
 type indexAuditTargetCollection struct {
@@ -136,6 +157,9 @@ func (c *Client) IndexAudit(ctx context.Context, opts IndexAuditOptions) (result
 	if err := validateIndexConsistencyTopology(cluster.Type, consistencyRequested); err != nil {
 		return nil, err
 	}
+	if err := validateIndexShardKeyTopology(cluster.Type, opts.Checks); err != nil {
+		return nil, err
+	}
 	if generalRequested {
 		if gate, allowed := diagnosticCapabilityGate("index_usage", convertClusterType(cluster.Type), cluster.MaxWireVersion, true); !allowed {
 			result.CollectorStatuses = []CollectorStatus{gate}
@@ -164,6 +188,11 @@ func (c *Client) collectIndexAuditRefs(
 	consistencyRequested bool,
 	generalRequested bool,
 ) (*IndexAuditResult, error) {
+	if generalRequested {
+		if err := c.validateIndexUsageLedger(ctx, clusterType, opts.UsageLedger); err != nil {
+			return nil, err
+		}
+	}
 	collectionsByNamespace := make(map[string]CollectionIndexAudit, len(refs))
 	var collectorErrors []error
 	if consistencyRequested {
@@ -177,7 +206,38 @@ func (c *Client) collectIndexAuditRefs(
 		result.CollectorStatuses = append(result.CollectorStatuses, statuses...)
 		collectorErrors = append(collectorErrors, consistencyErrors...)
 	}
-	if generalRequested {
+	if includesIndexCheck(opts.Checks, IndexCheckShardKey) {
+		collections, statuses, shardKeyErrors := collectIndexShardKeyAudit(ctx, refs, opts, clientIndexConsistencySource{client: c}, collectionsByNamespace)
+		for _, collection := range collections {
+			collectionsByNamespace[collection.Namespace] = collection
+		}
+		result.CollectorStatuses = append(result.CollectorStatuses, statuses...)
+		collectorErrors = append(collectorErrors, shardKeyErrors...)
+	}
+	if includesIndexCheck(opts.Checks, IndexCheckTTL) {
+		collections, statuses, ttlErrors := collectIndexTTLAudit(ctx, refs, opts, clientIndexTTLSource{client: c}, collectionsByNamespace)
+		for _, collection := range collections {
+			collectionsByNamespace[collection.Namespace] = collection
+		}
+		result.CollectorStatuses = append(result.CollectorStatuses, statuses...)
+		collectorErrors = append(collectorErrors, ttlErrors...)
+		if len(collections) > 0 {
+			findings, monitorStatuses, monitorErrors := c.collectTTLMonitorFindings(ctx, clusterType, semaphore.NewWeighted(int64(opts.Concurrency)))
+			result.Findings = append(result.Findings, findings...)
+			result.CollectorStatuses = append(result.CollectorStatuses, monitorStatuses...)
+			collectorErrors = append(collectorErrors, monitorErrors...)
+		}
+	}
+	if includesIndexCheck(opts.Checks, IndexCheckSpecial) {
+		collections, statuses, specialErrors := collectIndexSpecialAudit(ctx, refs, opts, clusterType == pkgmongo.ClusterShard, clientIndexSpecialSource{client: c}, collectionsByNamespace)
+		for _, collection := range collections {
+			collectionsByNamespace[collection.Namespace] = collection
+		}
+		result.CollectorStatuses = append(result.CollectorStatuses, statuses...)
+		collectorErrors = append(collectorErrors, specialErrors...)
+	}
+	memberRequested := includesIndexCheck(opts.Checks, IndexCheckMemberConsistency)
+	if generalRequested || memberRequested {
 		targets, targetStatuses, discoveryErrors := c.discoverHotspotTargets(ctx, clusterType)
 		result.CollectorStatuses = append(result.CollectorStatuses, targetStatuses...)
 		collectorErrors = append(collectorErrors, discoveryErrors...)
@@ -190,7 +250,19 @@ func (c *Client) collectIndexAuditRefs(
 			}
 			ref := ref
 			group.Go(func() error {
-				collection, statuses, collectErrors := c.collectIndexAuditCollection(groupCtx, ref, targets, opts, result.CollectedAt, clusterType == pkgmongo.ClusterRepl, capabilityLimit)
+				collection := CollectionIndexAudit{Namespace: ref.Database + "." + ref.Collection, Indexes: []IndexObservation{}, Findings: []DiagnosticFinding{}}
+				var statuses []CollectorStatus
+				var collectErrors []error
+				if generalRequested {
+					collection, statuses, collectErrors = c.collectIndexAuditCollection(groupCtx, ref, targets, opts, result.CollectedAt, clusterType == pkgmongo.ClusterRepl, capabilityLimit)
+				}
+				if memberRequested {
+					differences, memberStatuses, memberErrors := c.collectIndexMemberConsistency(groupCtx, ref, targets, capabilityLimit)
+					collection.MemberDifferences = differences
+					collection.Findings = append(collection.Findings, indexMemberConsistencyFindings(collection.Namespace, ref.Database, differences)...)
+					statuses = append(statuses, memberStatuses...)
+					collectErrors = append(collectErrors, memberErrors...)
+				}
 				mu.Lock()
 				collectionsByNamespace[collection.Namespace] = mergeIndexAuditCollections(collectionsByNamespace[collection.Namespace], collection)
 				result.CollectorStatuses = append(result.CollectorStatuses, statuses...)
@@ -226,9 +298,16 @@ func validateIndexConsistencyTopology(clusterType pkgmongo.ClusterType, requeste
 	return nil
 }
 
+func validateIndexShardKeyTopology(clusterType pkgmongo.ClusterType, checks []IndexAuditCheck) error {
+	if includesIndexCheck(checks, IndexCheckShardKey) && clusterType != pkgmongo.ClusterShard {
+		return fmt.Errorf("%w: index shardkey check requires a mongos connection", ErrUnsupportedTopology)
+	}
+	return nil
+}
+
 func includesGeneralIndexCheck(checks []IndexAuditCheck) bool {
 	for _, check := range checks {
-		if check != IndexCheckConsistency {
+		if check != IndexCheckConsistency && check != IndexCheckMemberConsistency && check != IndexCheckShardKey && check != IndexCheckTTL && check != IndexCheckSpecial {
 			return true
 		}
 	}
@@ -242,6 +321,7 @@ func mergeIndexAuditCollections(consistency, general CollectionIndexAudit) Colle
 	consistency.DataSizeBytes = general.DataSizeBytes
 	consistency.IndexSizeBytes = general.IndexSizeBytes
 	consistency.IndexToDataRatio = general.IndexToDataRatio
+	consistency.MemberDifferences = general.MemberDifferences
 	consistency.Indexes = append(consistency.Indexes, general.Indexes...)
 	consistency.Findings = append(consistency.Findings, general.Findings...)
 	sanitizeAndSortFindings(consistency.Findings)
@@ -272,7 +352,7 @@ func normalizeIndexAuditOptions(opts IndexAuditOptions) (IndexAuditOptions, erro
 	}
 	for _, check := range opts.Checks {
 		switch check {
-		case IndexCheckUnused, IndexCheckRedundant, IndexCheckSpace, IndexCheckBuilding, IndexCheckConsistency:
+		case IndexCheckUnused, IndexCheckRedundant, IndexCheckSpace, IndexCheckBuilding, IndexCheckConsistency, IndexCheckMemberConsistency, IndexCheckShardKey, IndexCheckTTL, IndexCheckSpecial:
 		default:
 			return IndexAuditOptions{}, invalidOptions("unknown index audit check %q", check)
 		}
@@ -314,7 +394,7 @@ func selectIndexCollectionRefs(database string, metadata []indexCollectionMetada
 		if len(collections) > 0 && !stringIncluded(collections, item.Name) {
 			continue
 		}
-		refs = append(refs, indexCollectionRef{Database: database, Collection: item.Name, Type: item.Type})
+		refs = append(refs, indexCollectionRef{Database: database, Collection: item.Name, Type: item.Type, Capped: item.Options.Capped})
 	}
 	sort.SliceStable(refs, func(i, j int) bool {
 		return refs[i].Database+"."+refs[i].Collection < refs[j].Database+"."+refs[j].Collection
@@ -432,13 +512,18 @@ func evaluateIndexAuditCollection(collection CollectionIndexAudit, expectedNodes
 				continue
 			}
 			complete, unused := canDetermineOwnership && len(observations) == expectedNodes && expectedNodes > 0, true
+			var ledgerPeriods int
+			var unobservedGap time.Duration
 			for _, observation := range observations {
 				if observation.Ops != 0 {
 					unused = false
 				}
-				if observation.Since.IsZero() || now.Sub(observation.Since) < opts.MinObservation || observation.Building {
+				coverage := opts.UsageLedger.coverage(collection.Namespace, observation, now)
+				if observation.Since.IsZero() || coverage.Observed < opts.MinObservation || observation.Building {
 					complete = false
 				}
+				ledgerPeriods = max(ledgerPeriods, coverage.Periods)
+				unobservedGap = max(unobservedGap, coverage.Gap)
 			}
 			code, severity, summary := "index.unused_candidate", SeverityWarning, "索引在完整观察窗口内未记录使用，需结合查询模式人工复核"
 			if !complete {
@@ -449,6 +534,10 @@ func evaluateIndexAuditCollection(collection CollectionIndexAudit, expectedNodes
 				if !canDetermineOwnership {
 					evidence["ownershipCoverage"] = "unknown"
 				}
+				if opts.UsageLedger != nil && ledgerPeriods > 1 {
+					evidence["counterPeriods"] = ledgerPeriods
+					evidence["unobservedGapSeconds"] = int64(unobservedGap / time.Second)
+				}
 				if len(observations) > 0 {
 					evidence["unique"] = observations[0].Unique
 					evidence["sparse"] = observations[0].Sparse
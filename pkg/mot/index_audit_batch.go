@@ -99,6 +99,9 @@ func (c *Client) indexAuditBatch(ctx context.Context, batchOpts IndexAuditBatchO
 	if err := validateIndexConsistencyTopology(cluster.Type, consistencyRequested); err != nil {
 		return nil, err
 	}
+	if err := validateIndexShardKeyTopology(cluster.Type, opts.Checks); err != nil {
+		return nil, err
+	}
 	if generalRequested {
 		if gate, allowed := diagnosticCapabilityGate("index_usage", convertClusterType(cluster.Type), cluster.MaxWireVersion, true); !allowed {
 			result.CollectorStatuses = []CollectorStatus{gate}
@@ -169,6 +172,50 @@ func (c *Client) indexAuditBatch(ctx context.Context, batchOpts IndexAuditBatchO
 	return nil, collectErr
 }
 
+type indexAuditFindingKey struct {
+	code  string
+	scope FindingScope
+}
+
+// Merge 把另一批次的结果并入 r：collection 与 namespace 级 finding 按批次互不重叠直接追加，
+// 每批都会重复产生的节点/集群级 finding 按 code+scope 去重、collector status 按内容去重，consistency summary 重新汇总。
+func (r *IndexAuditResult) Merge(other *IndexAuditResult) {
+	if r == nil || other == nil {
+		return
+	}
+	if r.CollectedAt.IsZero() {
+		r.CollectedAt = other.CollectedAt
+	}
+	r.Collections = append(r.Collections, other.Collections...)
+	sort.SliceStable(r.Collections, func(i, j int) bool { return r.Collections[i].Namespace < r.Collections[j].Namespace })
+	// 节点级 finding 每批都会重新评估，evidence 中的计数可能变化，因此按 code+scope 去重并保留最新一批的结果。
+	seenFindings := make(map[indexAuditFindingKey]int, len(r.Findings))
+	findings := make([]DiagnosticFinding, 0, len(r.Findings)+len(other.Findings))
+	for _, finding := range append(append([]DiagnosticFinding(nil), r.Findings...), other.Findings...) {
+		key := indexAuditFindingKey{code: finding.Code, scope: finding.Scope}
+		if index, ok := seenFindings[key]; ok {
+			findings[index] = finding
+			continue
+		}
+		seenFindings[key] = len(findings)
+		findings = append(findings, finding)
+	}
+	r.Findings = findings
+	seenStatuses := make(map[CollectorStatus]bool, len(r.CollectorStatuses))
+	statuses := make([]CollectorStatus, 0, len(r.CollectorStatuses)+len(other.CollectorStatuses))
+	for _, status := range append(append([]CollectorStatus(nil), r.CollectorStatuses...), other.CollectorStatuses...) {
+		if seenStatuses[status] {
+			continue
+		}
+		seenStatuses[status] = true
+		statuses = append(statuses, status)
+	}
+	r.CollectorStatuses = statuses
+	sanitizeAndSortFindings(r.Findings)
+	sortCollectorStatuses(r.CollectorStatuses)
+	r.ConsistencySummary = summarizeIndexConsistency(r.Collections)
+}
+
 func indexAuditBatchScopeHash(opts IndexAuditOptions) (string, error) {
 	scope := indexAuditBatchScope{
 		Databases:       append([]string(nil), opts.Databases...),
@@ -315,3 +315,36 @@ func (s *mutableIndexAuditCatalogSource) ListCollections(context.Context, *pkgmo
 	s.collectionLoads++
 	return append([]indexCollectionMetadata(nil), s.collections...), nil
 }
+
+func TestIndexAuditResultMergeDeduplicatesRepeatedFindings(t *testing.T) {
+	// 场景：合并两个批次时 collection 按 namespace 排序追加，每批重复产生的节点级 finding 按 code+scope 只保留最新一份（evidence 可能随批次变化），collector status 只保留一份，consistency summary 按合并后的集合重新计算。
+	nodeFinding := DiagnosticFinding{Code: "index.ttl_monitor_inactive", Severity: SeverityWarning, Scope: FindingScope{Type: ScopeNode, Node: "n1:27017"}, Summary: "ttl monitor inactive"}
+	status := CollectorStatus{Name: "index_ttl", State: CapabilitySupported}
+	merged := &IndexAuditResult{}
+	merged.Merge(&IndexAuditResult{
+		Collections:       []CollectionIndexAudit{{Namespace: "app.users", State: IndexConsistencyConsistent}},
+		Findings:          []DiagnosticFinding{nodeFinding},
+		CollectorStatuses: []CollectorStatus{status},
+	})
+	laterFinding := nodeFinding
+	laterFinding.Evidence = map[string]any{"passes": int64(12)}
+	merged.Merge(&IndexAuditResult{
+		Collections:       []CollectionIndexAudit{{Namespace: "app.orders", State: IndexConsistencyInconsistent}},
+		Findings:          []DiagnosticFinding{laterFinding, {Code: "index.inconsistent", Severity: SeverityWarning, Scope: FindingScope{Type: ScopeNamespace, Namespace: "app.orders"}, Summary: "inconsistent"}},
+		CollectorStatuses: []CollectorStatus{status},
+	})
+	if len(merged.Collections) != 2 || merged.Collections[0].Namespace != "app.orders" {
+		t.Fatalf("collections = %#v", merged.Collections)
+	}
+	if len(merged.Findings) != 2 || len(merged.CollectorStatuses) != 1 {
+		t.Fatalf("findings = %#v statuses = %#v", merged.Findings, merged.CollectorStatuses)
+	}
+	for _, finding := range merged.Findings {
+		if finding.Code == nodeFinding.Code && finding.Evidence["passes"] != int64(12) {
+			t.Fatalf("node finding = %#v, want the latest batch evidence", finding)
+		}
+	}
+	if merged.ConsistencySummary != (IndexConsistencySummary{Consistent: 1, Inconsistent: 1}) {
+		t.Fatalf("summary = %#v", merged.ConsistencySummary)
+	}
+}
@@ -0,0 +1,311 @@
+package mot
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+const defaultIndexBuildStallThreshold = 5 * time.Minute
+
+var indexBuildProgressSuffix = regexp.MustCompile(`:?\s*\d+/\d+(\s+\d+%)?$`)
+
+// IndexBuildsOptions 描述一次索引构建快照；Previous 为上一轮结果时按两次采样估算速率、剩余时间与停滞时长。
+type IndexBuildsOptions struct {
+	Databases       []string
+	Namespaces      []string
+	NodeConcurrency int
+	StallThreshold  time.Duration
+	Previous        *IndexBuildsResult
+}
+
+// IndexBuildsResult 是各数据成员上正在进行的索引构建；Interval 只在与上一轮比较时给出。
+type IndexBuildsResult struct {
+	ClusterType       ClusterType         `json:"clusterType"`
+	CollectedAt       time.Time           `json:"collectedAt"`
+	Interval          time.Duration       `json:"interval,omitempty"`
+	Builds            []IndexBuild        `json:"builds"`
+	Findings          []DiagnosticFinding `json:"findings,omitempty"`
+	CollectorStatuses []CollectorStatus   `json:"collectorStatuses,omitempty"`
+}
+
+// IndexBuild 是单个成员上某个集合的构建进度；同一成员同一集合的 createIndexes 命令与构建线程合并为一行。
+// Done/Total 属于当前 Phase，阶段切换后重新计数，Remaining 只估算当前阶段。
+type IndexBuild struct {
+	Namespace     string        `json:"namespace"`
+	Indexes       []string      `json:"indexes,omitempty"`
+	ReplicaSet    string        `json:"replicaSet,omitempty"`
+	Shard         string        `json:"shard,omitempty"`
+	Host          string        `json:"host"`
+	Primary       bool          `json:"primary"`
+	Phase         string        `json:"phase,omitempty"`
+	Done          *int64        `json:"done,omitempty"`
+	Total         *int64        `json:"total,omitempty"`
+	Elapsed       time.Duration `json:"elapsed"`
+	RatePerSecond float64       `json:"ratePerSecond,omitempty"`
+	Remaining     time.Duration `json:"remaining,omitempty"`
+	StalledFor    time.Duration `json:"stalledFor,omitempty"`
+}
+
+// IndexBuilds 直连每个健康的 PRIMARY/SECONDARY 成员，通过 $currentOp 读取正在进行的索引构建。
+func (c *Client) IndexBuilds(ctx context.Context, opts IndexBuildsOptions) (result *IndexBuildsResult, err error) {
+	if c != nil && c.session == nil {
+		return withEphemeralCollectorSession(ctx, c, func(session *CollectorSession) (*IndexBuildsResult, error) {
+			return session.IndexBuilds(ctx, opts)
+		})
+	}
+	opts, err = normalizeIndexBuildsOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.requireMemberConnectionURI(); err != nil {
+		return nil, err
+	}
+	defer func() { err = mapContextError(err) }()
+
+	cluster, err := c.detectCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result = &IndexBuildsResult{ClusterType: convertClusterType(cluster.Type), CollectedAt: time.Now().UTC()}
+	if gate, allowed := diagnosticCapabilityGate("index_builds", result.ClusterType, cluster.MaxWireVersion, true); !allowed {
+		result.CollectorStatuses = []CollectorStatus{gate}
+		return result, nil
+	}
+	targets, statuses, collectorErrors := c.discoverHotspotTargets(ctx, cluster.Type)
+	result.CollectorStatuses = statuses
+	query := pkgmongo.CurrentOperationsQuery{AllUsers: true, Databases: opts.Databases, Namespaces: opts.Namespaces, MaxTime: 5 * time.Second}
+
+	var mu sync.Mutex
+	group, groupCtx := errgroup.WithContext(ctx)
+	limit := semaphore.NewWeighted(int64(opts.NodeConcurrency))
+	for _, target := range targets {
+		if acquireErr := acquireDiagnosticSlot(groupCtx, limit); acquireErr != nil {
+			mu.Lock()
+			collectorErrors = append(collectorErrors, acquireErr)
+			mu.Unlock()
+			break
+		}
+		target := target
+		group.Go(func() error {
+			defer limit.Release(1)
+			scope := FindingScope{Type: ScopeNode, ReplicaSet: target.ReplicaSet, Shard: target.Shard, Node: target.Address}
+			operations, collectErr := c.indexBuildNodeOperations(groupCtx, target.Address, query, cluster.MaxWireVersion)
+			mu.Lock()
+			defer mu.Unlock()
+			if collectErr != nil {
+				if !isUnauthorizedError(collectErr) {
+					collectorErrors = append(collectorErrors, collectErr)
+				}
+				result.CollectorStatuses = append(result.CollectorStatuses, failedCollectorStatus("index_builds", scope, collectErr))
+				return nil
+			}
+			result.CollectorStatuses = append(result.CollectorStatuses, CollectorStatus{Name: "index_builds", State: CapabilitySupported, Scope: scope})
+			result.Builds = append(result.Builds, mergeIndexBuildOperations(target, operations)...)
+			return nil
+		})
+	}
+	_ = group.Wait()
+	sortIndexBuilds(result.Builds)
+	applyIndexBuildProgress(result, opts.Previous)
+	result.Findings = evaluateIndexBuilds(result.Builds, opts.StallThreshold)
+	sanitizeAndSortFindings(result.Findings)
+	sortCollectorStatuses(result.CollectorStatuses)
+	if len(collectorErrors) > 0 {
+		return result, newDiagnosticPartialError("index-builds", result, errors.Join(collectorErrors...))
+	}
+	return result, nil
+}
+
+func (c *Client) indexBuildNodeOperations(ctx context.Context, address string, query pkgmongo.CurrentOperationsQuery, maxWireVersion int) ([]pkgmongo.CurrentOperationSnapshot, error) {
+	release, err := c.acquireRemoteSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	conn, err := c.connectAddress(ctx, address, derivedConnectionOptions{Direct: boolPointer(true)})
+	if err != nil {
+		return nil, err
+	}
+	defer c.closeDerivedConnection(ctx, conn)
+	operations, _, err := collectCurrentOperationsForWireVersion(
+		maxWireVersion,
+		query,
+		func(query pkgmongo.CurrentOperationsQuery) ([]pkgmongo.CurrentOperationSnapshot, error) {
+			return conn.IndexBuildOperations(ctx, query)
+		},
+		func(query pkgmongo.CurrentOperationsQuery) ([]pkgmongo.CurrentOperationSnapshot, error) {
+			return conn.IndexBuildOperationsCommand(ctx, query)
+		},
+	)
+	return operations, err
+}
+
+func normalizeIndexBuildsOptions(opts IndexBuildsOptions) (IndexBuildsOptions, error) {
+	if opts.NodeConcurrency < 0 {
+		return IndexBuildsOptions{}, invalidOptions("node concurrency must not be negative")
+	}
+	if opts.StallThreshold < 0 {
+		return IndexBuildsOptions{}, invalidOptions("stall threshold must not be negative")
+	}
+	if opts.NodeConcurrency == 0 {
+		opts.NodeConcurrency = defaultOverviewNodeConcurrency
+	}
+	if opts.StallThreshold == 0 {
+		opts.StallThreshold = defaultIndexBuildStallThreshold
+	}
+	return opts, nil
+}
+
+// mergeIndexBuildOperations 按集合合并同一成员上的 operation：进度取自带 progress 的构建线程，运行时长取最大值。
+func mergeIndexBuildOperations(target hotspotTarget, operations []pkgmongo.CurrentOperationSnapshot) []IndexBuild {
+	byNamespace := make(map[string]*IndexBuild)
+	var namespaces []string
+	for _, operation := range operations {
+		build, ok := byNamespace[operation.Namespace]
+		if !ok {
+			build = &IndexBuild{Namespace: operation.Namespace, ReplicaSet: target.ReplicaSet, Shard: target.Shard, Host: target.Address, Primary: target.Primary}
+			byNamespace[operation.Namespace] = build
+			namespaces = append(namespaces, operation.Namespace)
+		}
+		for _, name := range operation.IndexNames {
+			if name != "" && !stringIncluded(build.Indexes, name) {
+				build.Indexes = append(build.Indexes, name)
+			}
+		}
+		if operation.SecondsRunning != nil {
+			if elapsed := time.Duration(*operation.SecondsRunning) * time.Second; elapsed > build.Elapsed {
+				build.Elapsed = elapsed
+			}
+		}
+		if build.Total == nil && (operation.ProgressTotal != nil || build.Phase == "") {
+			build.Phase = indexBuildPhase(operation.Message)
+			build.Done, build.Total = operation.ProgressDone, operation.ProgressTotal
+		}
+	}
+	result := make([]IndexBuild, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		build := byNamespace[namespace]
+		sort.Strings(build.Indexes)
+		result = append(result, *build)
+	}
+	return result
+}
+
+// indexBuildPhase 去掉 msg 中的 done/total 计数；4.4+ 的 msg 会重复阶段前缀，只保留最后一段。
+func indexBuildPhase(message string) string {
+	message = indexBuildProgressSuffix.ReplaceAllString(safeOperationMessage(message), "")
+	if index := strings.LastIndex(message, "Index Build"); index >= 0 {
+		message = message[index+len("Index Build"):]
+	}
+	return strings.Trim(message, " :()")
+}
+
+func sortIndexBuilds(builds []IndexBuild) {
+	sort.SliceStable(builds, func(i, j int) bool {
+		left, right := builds[i], builds[j]
+		if left.Namespace != right.Namespace {
+			return left.Namespace < right.Namespace
+		}
+		if left.Shard != right.Shard {
+			return left.Shard < right.Shard
+		}
+		if left.Primary != right.Primary {
+			return left.Primary
+		}
+		return left.Host < right.Host
+	})
+}
+
+// applyIndexBuildProgress 与上一轮同一成员同一集合的构建比较；阶段切换视为有进展，done 未增加时累加停滞时长。
+func applyIndexBuildProgress(current, previous *IndexBuildsResult) {
+	if previous == nil {
+		return
+	}
+	interval := current.CollectedAt.Sub(previous.CollectedAt)
+	if interval <= 0 {
+		return
+	}
+	current.Interval = interval
+	before := make(map[string]IndexBuild, len(previous.Builds))
+	for _, build := range previous.Builds {
+		before[build.Host+"\x00"+build.Namespace] = build
+	}
+	for i := range current.Builds {
+		build := &current.Builds[i]
+		prior, ok := before[build.Host+"\x00"+build.Namespace]
+		if !ok || prior.Phase != build.Phase || prior.Done == nil || build.Done == nil {
+			continue
+		}
+		progressed := *build.Done - *prior.Done
+		if progressed <= 0 {
+			build.StalledFor = prior.StalledFor + interval
+			continue
+		}
+		build.RatePerSecond = float64(progressed) / interval.Seconds()
+		if build.Total != nil && *build.Total > *build.Done {
+			build.Remaining = time.Duration(float64(*build.Total-*build.Done) / build.RatePerSecond * float64(time.Second)).Round(time.Second)
+		}
+	}
+}
+
+// evaluateIndexBuilds 只依据与上一轮的比较结果报告：停滞超过阈值的构建，以及 primary 有进展而同一副本集 secondary 停滞的构建。
+func evaluateIndexBuilds(builds []IndexBuild, stallThreshold time.Duration) []DiagnosticFinding {
+	findings := make([]DiagnosticFinding, 0)
+	progressingPrimaries := make(map[string]IndexBuild)
+	for _, build := range builds {
+		if build.Primary && build.RatePerSecond > 0 {
+			progressingPrimaries[build.ReplicaSet+"\x00"+build.Namespace] = build
+		}
+	}
+	for _, build := range builds {
+		if build.StalledFor <= 0 {
+			continue
+		}
+		database, _, _ := strings.Cut(build.Namespace, ".")
+		scope := FindingScope{Type: ScopeNamespace, ReplicaSet: build.ReplicaSet, Shard: build.Shard, Node: build.Host, Database: database, Namespace: build.Namespace}
+		if build.StalledFor >= stallThreshold {
+			findings = append(findings, DiagnosticFinding{
+				Code: "index.build_stalled", Severity: SeverityWarning, Scope: scope,
+				Summary:        "索引构建在观察期内没有进展",
+				Evidence:       indexBuildEvidence(build),
+				Recommendation: "检查该成员的磁盘、锁等待与 commit quorum 状态；必要时在业务低峰期重新发起构建",
+			})
+		}
+		if primary, ok := progressingPrimaries[build.ReplicaSet+"\x00"+build.Namespace]; ok && !build.Primary {
+			evidence := indexBuildEvidence(build)
+			evidence["primary"] = primary.Host
+			evidence["primaryPhase"] = primary.Phase
+			findings = append(findings, DiagnosticFinding{
+				Code: "index.build_lagging_on_secondary", Severity: SeverityWarning, Scope: scope,
+				Summary:        "primary 上的索引构建有进展，但 secondary 上停滞",
+				Evidence:       evidence,
+				Recommendation: "检查 secondary 的复制延迟与资源使用；secondary 构建未完成前 commit quorum 可能一直阻塞",
+			})
+		}
+	}
+	return findings
+}
+
+func indexBuildEvidence(build IndexBuild) map[string]any {
+	evidence := map[string]any{"indexes": build.Indexes, "phase": build.Phase, "stalledSeconds": build.StalledFor.Seconds(), "elapsedSeconds": build.Elapsed.Seconds()}
+	if build.Done != nil {
+		evidence["done"] = *build.Done
+	}
+	if build.Total != nil {
+		evidence["total"] = *build.Total
+	}
+	return evidence
+}
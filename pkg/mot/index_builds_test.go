@@ -0,0 +1,77 @@
+package mot
+
+import (
+	"testing"
+	"time"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+func TestMergeIndexBuildOperationsPerNamespace(t *testing.T) {
+	// 场景：同一成员同一集合的 createIndexes 命令与构建线程合并为一行，进度取自带 progress 的线程，msg 中重复的阶段前缀与计数被去掉。
+	int64Pointer := func(value int64) *int64 { return &value }
+	target := hotspotTarget{ReplicaSet: "rs0", Address: "n1:27017", Primary: true}
+	builds := mergeIndexBuildOperations(target, []pkgmongo.CurrentOperationSnapshot{
+		{Namespace: "app.orders", IndexNames: []string{"tenant_1"}, SecondsRunning: int64Pointer(120)},
+		{Namespace: "app.orders", IndexNames: []string{"tenant_1", "status_1"}, SecondsRunning: int64Pointer(118), Message: "Index Build: scanning collection Index Build: scanning collection: 500/1000 50%", ProgressDone: int64Pointer(500), ProgressTotal: int64Pointer(1000)},
+		{Namespace: "app.events", Message: "Index Build (background) Index Build (background): 10/40 25%", ProgressDone: int64Pointer(10), ProgressTotal: int64Pointer(40)},
+	})
+	if len(builds) != 2 {
+		t.Fatalf("builds = %#v", builds)
+	}
+	orders := builds[0]
+	if orders.Phase != "scanning collection" || *orders.Done != 500 || *orders.Total != 1000 || orders.Elapsed != 120*time.Second || !orders.Primary || len(orders.Indexes) != 2 || orders.Indexes[0] != "status_1" {
+		t.Fatalf("orders = %#v", orders)
+	}
+	if builds[1].Phase != "background" || builds[1].Host != "n1:27017" {
+		t.Fatalf("events = %#v", builds[1])
+	}
+	if got := indexBuildPhase("Index Build: draining writes received during build"); got != "draining writes received during build" {
+		t.Fatalf("phase = %q", got)
+	}
+}
+
+func TestApplyIndexBuildProgressEstimatesAndFlags(t *testing.T) {
+	// 场景：与上一轮比较估算速率与当前阶段剩余时间；done 未增加时累加停滞时长，超过阈值报告停滞，primary 有进展而 secondary 停滞时单独报告；阶段切换不计为停滞。
+	int64Pointer := func(value int64) *int64 { return &value }
+	collectedAt := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	previous := &IndexBuildsResult{CollectedAt: collectedAt, Builds: []IndexBuild{
+		{Namespace: "app.orders", ReplicaSet: "rs0", Host: "n1:27017", Primary: true, Phase: "scanning collection", Done: int64Pointer(100), Total: int64Pointer(1000)},
+		{Namespace: "app.orders", ReplicaSet: "rs0", Host: "n2:27017", Phase: "scanning collection", Done: int64Pointer(50), Total: int64Pointer(1000), StalledFor: 4 * time.Minute},
+		{Namespace: "app.orders", ReplicaSet: "rs0", Host: "n3:27017", Phase: "scanning collection", Done: int64Pointer(900), Total: int64Pointer(1000)},
+	}}
+	current := &IndexBuildsResult{CollectedAt: collectedAt.Add(time.Minute), Builds: []IndexBuild{
+		{Namespace: "app.orders", ReplicaSet: "rs0", Host: "n1:27017", Primary: true, Phase: "scanning collection", Done: int64Pointer(400), Total: int64Pointer(1000)},
+		{Namespace: "app.orders", ReplicaSet: "rs0", Host: "n2:27017", Phase: "scanning collection", Done: int64Pointer(50), Total: int64Pointer(1000)},
+		{Namespace: "app.orders", ReplicaSet: "rs0", Host: "n3:27017", Phase: "inserting keys from external sorter into index", Done: int64Pointer(0), Total: int64Pointer(2000)},
+	}}
+	applyIndexBuildProgress(current, previous)
+	primary, lagging, switched := current.Builds[0], current.Builds[1], current.Builds[2]
+	if current.Interval != time.Minute || primary.RatePerSecond != 5 || primary.Remaining != 2*time.Minute || primary.StalledFor != 0 {
+		t.Fatalf("primary = %#v interval = %s", primary, current.Interval)
+	}
+	if lagging.StalledFor != 5*time.Minute || lagging.RatePerSecond != 0 || switched.StalledFor != 0 {
+		t.Fatalf("lagging = %#v switched = %#v", lagging, switched)
+	}
+	findings := evaluateIndexBuilds(current.Builds, defaultIndexBuildStallThreshold)
+	if len(findings) != 2 || !hasFindingCode(findings, "index.build_stalled") || !hasFindingCode(findings, "index.build_lagging_on_secondary") {
+		t.Fatalf("findings = %#v", findings)
+	}
+	for _, finding := range findings {
+		if finding.Scope.Node != "n2:27017" || finding.Scope.Namespace != "app.orders" {
+			t.Fatalf("finding scope = %#v", finding.Scope)
+		}
+		if _, ok := finding.Evidence["primary"]; ok != (finding.Code == "index.build_lagging_on_secondary") {
+			t.Fatalf("finding evidence = %#v", finding.Evidence)
+		}
+	}
+	if got := evaluateIndexBuilds(current.Builds, 10*time.Minute); len(got) != 1 || got[0].Code != "index.build_lagging_on_secondary" {
+		t.Fatalf("findings below threshold = %#v", got)
+	}
+	if opts, err := normalizeIndexBuildsOptions(IndexBuildsOptions{}); err != nil || opts.StallThreshold != defaultIndexBuildStallThreshold || opts.NodeConcurrency != defaultOverviewNodeConcurrency {
+		t.Fatalf("opts = %#v, err = %v", opts, err)
+	}
+	if _, err := normalizeIndexBuildsOptions(IndexBuildsOptions{StallThreshold: -time.Second}); err == nil {
+		t.Fatal("negative stall threshold accepted")
+	}
+}
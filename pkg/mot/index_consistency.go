@@ -89,6 +89,8 @@ type indexConsistencyEvaluation struct {
 	ObservedShards []string
 	Differences    []IndexConsistencyDifference
 	Findings       []DiagnosticFinding
+	// Definitions 是 legacy 策略读取到的各 shard 索引定义，供分片键检查复用；official 策略不读取定义。
+	Definitions map[string][]pkgmongo.CanonicalIndexDefinition
 }
 
 type indexShardTarget struct {
@@ -278,6 +280,7 @@ func auditIndexConsistencyCollection(ctx context.Context, ref indexCollectionRef
 		return result, result.ConsistencyStatuses, consistencyCollectorErrors(routingOutcome.err)
 	}
 	routing := routingOutcome.snapshot
+	result.routing = &routing
 	result.Sharded = routing.Sharded
 	result.ExpectedShards = append([]string(nil), routing.ExpectedShards...)
 	if !routing.Sharded {
@@ -421,6 +424,7 @@ func collectLegacyIndexConsistency(ctx context.Context, ref indexCollectionRef,
 		if complete {
 			statuses = append(statuses, consistencyStatus(namespace, strategy, CapabilitySupported, "complete"))
 			evaluation, confirmationErrors, attempted := confirmLegacyDifferences(ctx, ref, expected, targets, collectionBuilds, observations, source)
+			evaluation.Definitions = observations
 			collectorErrors = append(collectorErrors, consistencyCollectorErrors(confirmationErrors...)...)
 			if attempted {
 				if len(confirmationErrors) > 0 {
@@ -451,6 +455,7 @@ func collectLegacyIndexConsistency(ctx context.Context, ref indexCollectionRef,
 	first, directErrors := collectDirectDefinitions(ctx, ref, expected, targets, source, nil)
 	markBuildingDefinitions(first, collectionBuilds)
 	evaluation, confirmationErrors, _ := confirmLegacyDifferences(ctx, ref, expected, targets, collectionBuilds, first, source)
+	evaluation.Definitions = first
 	allDirectErrors := append(directErrors, confirmationErrors...)
 	collectorErrors = append(collectorErrors, consistencyCollectorErrors(allDirectErrors...)...)
 	if len(allDirectErrors) > 0 {
@@ -598,6 +603,7 @@ func mergeConsistencyEvaluation(result CollectionIndexAudit, strategy IndexConsi
 	result.Differences = append([]IndexConsistencyDifference(nil), evaluation.Differences...)
 	result.Findings = append(result.Findings, evaluation.Findings...)
 	result.ConsistencyStatuses = append([]CollectorStatus(nil), statuses...)
+	result.shardDefinitions = evaluation.Definitions
 	sanitizeAndSortFindings(result.Findings)
 	sortCollectorStatuses(result.ConsistencyStatuses)
 	return result
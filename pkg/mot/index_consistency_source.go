@@ -105,3 +105,12 @@ func (s clientIndexConsistencySource) Direct(ctx context.Context, ref indexColle
 	defer s.client.closeDerivedConnection(ctx, conn)
 	return conn.ListIndexDefinitions(ctx, ref.Database, ref.Collection, indexConsistencyCollectorTimeout)
 }
+
+func (s clientIndexConsistencySource) SampleCardinality(ctx context.Context, ref indexCollectionRef, field string) (pkgmongo.FieldCardinalitySample, error) {
+	release, err := s.client.acquireRemoteSlot(ctx)
+	if err != nil {
+		return pkgmongo.FieldCardinalitySample{}, err
+	}
+	defer release()
+	return s.client.conn.SampleFieldCardinality(ctx, ref.Database, ref.Collection, field, shardKeyCardinalitySampleSize, indexConsistencyCollectorTimeout)
+}
@@ -0,0 +1,578 @@
+package mot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+const indexLifecycleLedgerSchemaVersion = 1
+
+type IndexLifecycleAction string
+
+const (
+	IndexLifecycleHide   IndexLifecycleAction = "hide"
+	IndexLifecycleUnhide IndexLifecycleAction = "unhide"
+	IndexLifecycleStatus IndexLifecycleAction = "status"
+)
+
+// IndexLifecycleOptions 描述隐藏或恢复单个索引；MaxUsageOps 是 hide 允许的 $indexStats 累计 ops 上限（所有数据成员求和）。
+type IndexLifecycleOptions struct {
+	Namespace       string
+	IndexName       string
+	MaxUsageOps     int64
+	DryRun          bool
+	NodeConcurrency int
+}
+
+// IndexLifecycleStatusOptions 以本地 ledger 为准列出当前集群上经 mot 隐藏的索引；Namespaces 为空时不过滤。
+type IndexLifecycleStatusOptions struct {
+	Ledger          IndexLifecycleLedger
+	Namespaces      []string
+	NodeConcurrency int
+}
+
+// IndexLifecycleResult 是 hide/unhide/status 的结构化结果；ClusterIdentity 用于把 ledger 条目绑定到集群。
+type IndexLifecycleResult struct {
+	ClusterType       ClusterType           `json:"clusterType"`
+	ClusterIdentity   CapacityIdentity      `json:"clusterIdentity"`
+	Action            IndexLifecycleAction  `json:"action"`
+	DryRun            bool                  `json:"dryRun,omitempty"`
+	CheckedAt         time.Time             `json:"checkedAt"`
+	Indexes           []IndexLifecycleState `json:"indexes"`
+	Findings          []DiagnosticFinding   `json:"findings,omitempty"`
+	CollectorStatuses []CollectorStatus     `json:"collectorStatuses,omitempty"`
+}
+
+// IndexLifecycleState 是单个索引的可见性状态；慢查询计数只统计隐藏之后出现在同一集合上的查询形状。
+type IndexLifecycleState struct {
+	Namespace           string          `json:"namespace"`
+	IndexName           string          `json:"indexName"`
+	Key                 []IndexKeyField `json:"key,omitempty"`
+	Hidden              bool            `json:"hidden"`
+	Changed             bool            `json:"changed,omitempty"`
+	UsageOps            *int64          `json:"usageOps,omitempty"`
+	RefusedReason       string          `json:"refusedReason,omitempty"`
+	HiddenAt            *time.Time      `json:"hiddenAt,omitempty"`
+	HiddenFor           time.Duration   `json:"hiddenFor,omitempty"`
+	SlowQueryShapes     int64           `json:"slowQueryShapes,omitempty"`
+	SlowQueryExecutions int64           `json:"slowQueryExecutions,omitempty"`
+	CollectionScans     int64           `json:"collectionScans,omitempty"`
+}
+
+// IndexLifecycleLedger 是 CLI 保存在本地的隐藏记录，只包含 namespace、索引名与 key 形状。
+type IndexLifecycleLedger struct {
+	SchemaVersion int                         `json:"schemaVersion"`
+	Entries       []IndexLifecycleLedgerEntry `json:"entries"`
+}
+
+type IndexLifecycleLedgerEntry struct {
+	ClusterDigest  string          `json:"clusterDigest"`
+	Namespace      string          `json:"namespace"`
+	IndexName      string          `json:"indexName"`
+	Key            []IndexKeyField `json:"key,omitempty"`
+	Hidden         bool            `json:"hidden"`
+	HiddenAt       *time.Time      `json:"hiddenAt,omitempty"`
+	UnhiddenAt     *time.Time      `json:"unhiddenAt,omitempty"`
+	UsageOpsAtHide *int64          `json:"usageOpsAtHide,omitempty"`
+}
+
+// HideIndex 以 collMod 隐藏索引。_id_、分片键索引、$indexStats ops 超过上限或使用情况不完整时拒绝执行，
+// 返回的错误匹配 ErrDangerousOperation，拒绝原因记录在 IndexLifecycleState.RefusedReason。
+func (c *Client) HideIndex(ctx context.Context, opts IndexLifecycleOptions) (*IndexLifecycleResult, error) {
+	return c.setIndexVisibility(ctx, opts, true)
+}
+
+// UnhideIndex 以 collMod 恢复被隐藏的索引，用于隐藏后出现回归时立即回滚。
+func (c *Client) UnhideIndex(ctx context.Context, opts IndexLifecycleOptions) (*IndexLifecycleResult, error) {
+	return c.setIndexVisibility(ctx, opts, false)
+}
+
+func (c *Client) setIndexVisibility(ctx context.Context, opts IndexLifecycleOptions, hidden bool) (result *IndexLifecycleResult, err error) {
+	database, collection, err := validateIndexLifecycleOptions(&opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.requireMemberConnectionURI(); err != nil {
+		return nil, err
+	}
+	defer func() { err = mapContextError(err) }()
+
+	cluster, err := c.detectCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := c.capacityIdentity(ctx, cluster.Type)
+	if err != nil {
+		return nil, err
+	}
+	result = &IndexLifecycleResult{ClusterType: convertClusterType(cluster.Type), ClusterIdentity: identity, Action: IndexLifecycleUnhide, DryRun: opts.DryRun, CheckedAt: time.Now().UTC()}
+	if hidden {
+		result.Action = IndexLifecycleHide
+	}
+	state := IndexLifecycleState{Namespace: opts.Namespace, IndexName: opts.IndexName}
+	if gate, allowed := diagnosticCapabilityGate("index_lifecycle", result.ClusterType, cluster.MaxWireVersion, true); !allowed {
+		result.CollectorStatuses = []CollectorStatus{gate}
+		return refuseIndexLifecycle(result, state, gate.ReasonCode)
+	}
+	if hidden && opts.IndexName == "_id_" {
+		return refuseIndexLifecycle(result, state, "id_index")
+	}
+
+	usage, statuses, usageErrors := c.indexLifecycleUsage(ctx, cluster.Type, database, collection, opts)
+	result.CollectorStatuses = append(result.CollectorStatuses, statuses...)
+	sortCollectorStatuses(result.CollectorStatuses)
+	if !usage.found {
+		if len(usageErrors) > 0 {
+			return result, newDiagnosticPartialError("index-"+string(result.Action), result, errors.Join(usageErrors...))
+		}
+		return refuseIndexLifecycle(result, state, "index_not_found")
+	}
+	state.Key, state.Hidden = usage.key, usage.allHidden
+	ops := usage.ops
+	state.UsageOps = &ops
+	if hidden {
+		if len(usageErrors) > 0 {
+			return refuseIndexLifecycle(result, state, "usage_inconclusive")
+		}
+		if usage.ops > opts.MaxUsageOps {
+			return refuseIndexLifecycle(result, state, "index_in_use")
+		}
+		if cluster.Type == pkgmongo.ClusterShard {
+			routing, routingErr := c.conn.IndexRouting(ctx, database, collection, 5*time.Second)
+			if routingErr != nil {
+				result.CollectorStatuses = append(result.CollectorStatuses, failedCollectorStatus("index_lifecycle", FindingScope{Type: ScopeNamespace, Database: database, Namespace: opts.Namespace}, routingErr))
+				sortCollectorStatuses(result.CollectorStatuses)
+				return refuseIndexLifecycle(result, state, "routing_unavailable")
+			}
+			if routing.Sharded && indexKeyHasPrefix(state.Key, indexKeyFromRouting(routing)) {
+				return refuseIndexLifecycle(result, state, "shard_key_index")
+			}
+		}
+	}
+
+	status := CollectorStatus{Name: "index_lifecycle", State: CapabilitySupported, Scope: FindingScope{Type: ScopeNamespace, Database: database, Namespace: opts.Namespace}}
+	needsChange := !usage.allHidden
+	if !hidden {
+		needsChange = usage.anyHidden
+	}
+	switch {
+	case !needsChange:
+		status.ReasonCode, status.Message = "unchanged", fmt.Sprintf("索引 hidden 已为 %t", hidden)
+	case opts.DryRun:
+		status.ReasonCode, status.Message = "dry_run", fmt.Sprintf("将索引 hidden 改为 %t", hidden)
+	default:
+		if setErr := c.conn.SetIndexHidden(ctx, database, collection, opts.IndexName, hidden); setErr != nil {
+			result.Indexes = []IndexLifecycleState{state}
+			result.CollectorStatuses = append(result.CollectorStatuses, failedCollectorStatus("index_lifecycle", status.Scope, setErr))
+			sortCollectorStatuses(result.CollectorStatuses)
+			return result, setErr
+		}
+		state.Hidden, state.Changed = hidden, true
+		status.ReasonCode, status.Message = "applied", fmt.Sprintf("索引 hidden 已改为 %t", hidden)
+	}
+	result.Indexes = []IndexLifecycleState{state}
+	result.CollectorStatuses = append(result.CollectorStatuses, status)
+	sortCollectorStatuses(result.CollectorStatuses)
+	if len(usageErrors) > 0 {
+		return result, newDiagnosticPartialError("index-"+string(result.Action), result, errors.Join(usageErrors...))
+	}
+	return result, nil
+}
+
+// IndexLifecycleStatus 读取 ledger 中仍处于隐藏状态的索引的当前可见性，并统计隐藏之后同一集合上出现的慢查询形状。
+func (c *Client) IndexLifecycleStatus(ctx context.Context, opts IndexLifecycleStatusOptions) (result *IndexLifecycleResult, err error) {
+	if c != nil && c.session == nil {
+		return withEphemeralCollectorSession(ctx, c, func(session *CollectorSession) (*IndexLifecycleResult, error) {
+			return session.IndexLifecycleStatus(ctx, opts)
+		})
+	}
+	if opts.NodeConcurrency < 0 {
+		return nil, invalidOptions("node concurrency must not be negative")
+	}
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.requireMemberConnectionURI(); err != nil {
+		return nil, err
+	}
+	defer func() { err = mapContextError(err) }()
+
+	cluster, err := c.detectCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := c.capacityIdentity(ctx, cluster.Type)
+	if err != nil {
+		return nil, err
+	}
+	result = &IndexLifecycleResult{ClusterType: convertClusterType(cluster.Type), ClusterIdentity: identity, Action: IndexLifecycleStatus, CheckedAt: time.Now().UTC()}
+	if gate, allowed := diagnosticCapabilityGate("index_lifecycle", result.ClusterType, cluster.MaxWireVersion, true); !allowed {
+		result.CollectorStatuses = []CollectorStatus{gate}
+		return result, nil
+	}
+	entries := opts.Ledger.hiddenEntries(identity.Digest, opts.Namespaces)
+	if len(entries) == 0 {
+		return result, nil
+	}
+
+	var collectorErrors []error
+	slowlogGroups := make(map[time.Time]*indexLifecycleSlowlogGroup)
+	for _, entry := range entries {
+		database, collection, _ := strings.Cut(entry.Namespace, ".")
+		scope := FindingScope{Type: ScopeNamespace, Database: database, Namespace: entry.Namespace}
+		state := IndexLifecycleState{Namespace: entry.Namespace, IndexName: entry.IndexName, Key: entry.Key, HiddenAt: entry.HiddenAt}
+		if entry.HiddenAt != nil {
+			state.HiddenFor = result.CheckedAt.Sub(*entry.HiddenAt)
+			group := slowlogGroups[entry.HiddenAt.UTC()]
+			if group == nil {
+				group = &indexLifecycleSlowlogGroup{}
+				slowlogGroups[entry.HiddenAt.UTC()] = group
+			}
+			if !stringIncluded(group.databases, database) {
+				group.databases = append(group.databases, database)
+			}
+			if !stringIncluded(group.namespaces, entry.Namespace) {
+				group.namespaces = append(group.namespaces, entry.Namespace)
+			}
+		}
+		stats, statsErr := c.conn.IndexStats(ctx, database, collection, 5*time.Second)
+		if statsErr != nil {
+			collectorErrors = append(collectorErrors, statsErr)
+			result.CollectorStatuses = append(result.CollectorStatuses, failedCollectorStatus("index_lifecycle", scope, statsErr))
+			result.Indexes = append(result.Indexes, state)
+			continue
+		}
+		found, allHidden := false, true
+		for _, stat := range stats {
+			if stat.Name == entry.IndexName {
+				found = true
+				allHidden = allHidden && stat.Hidden
+			}
+		}
+		state.Hidden = found && allHidden
+		if !state.Hidden {
+			result.Findings = append(result.Findings, DiagnosticFinding{
+				Code: "index.lifecycle_drift", Severity: SeverityWarning, Scope: scope,
+				Summary:        "ledger 记录为隐藏的索引在服务器上已可见或已不存在",
+				Evidence:       map[string]any{"indexName": entry.IndexName, "found": found},
+				Recommendation: "确认是否有人手动恢复或删除了该索引；如需继续观察，重新执行 mot index hide",
+			})
+		}
+		result.Indexes = append(result.Indexes, state)
+	}
+
+	// 每个隐藏时间单独读取一次 slowlog，保证统计的执行次数都发生在对应索引隐藏之后。
+	hiddenTimes := make([]time.Time, 0, len(slowlogGroups))
+	for hiddenAt := range slowlogGroups {
+		hiddenTimes = append(hiddenTimes, hiddenAt)
+	}
+	sort.Slice(hiddenTimes, func(i, j int) bool { return hiddenTimes[i].Before(hiddenTimes[j]) })
+	for _, hiddenAt := range hiddenTimes {
+		group := slowlogGroups[hiddenAt]
+		slowlog, slowlogErr := c.SlowlogSummary(ctx, SlowlogOptions{Databases: group.databases, Namespaces: group.namespaces, Since: hiddenAt, Concurrency: opts.NodeConcurrency})
+		if slowlog != nil {
+			for _, status := range slowlog.CollectorStatuses {
+				if !slices.Contains(result.CollectorStatuses, status) {
+					result.CollectorStatuses = append(result.CollectorStatuses, status)
+				}
+			}
+			applyIndexLifecycleSlowQueries(result.Indexes, hiddenAt, slowlog)
+		}
+		if slowlogErr != nil {
+			collectorErrors = append(collectorErrors, slowlogErr)
+			if contextError(ctx) != nil {
+				break
+			}
+		}
+	}
+	for _, state := range result.Indexes {
+		if state.Hidden && state.SlowQueryShapes > 0 {
+			result.Findings = append(result.Findings, indexLifecycleSlowQueryFinding(state))
+		}
+	}
+	sort.SliceStable(result.Indexes, func(i, j int) bool {
+		if result.Indexes[i].Namespace != result.Indexes[j].Namespace {
+			return result.Indexes[i].Namespace < result.Indexes[j].Namespace
+		}
+		return result.Indexes[i].IndexName < result.Indexes[j].IndexName
+	})
+	sanitizeAndSortFindings(result.Findings)
+	sortCollectorStatuses(result.CollectorStatuses)
+	if len(collectorErrors) > 0 {
+		return result, newDiagnosticPartialError("index-status", result, errors.Join(collectorErrors...))
+	}
+	return result, nil
+}
+
+// Record 把实际执行的 hide/unhide 写入 ledger；dry-run、被拒绝与未发生变化的结果不记录。
+func (l *IndexLifecycleLedger) Record(result *IndexLifecycleResult) {
+	if l == nil || result == nil || result.DryRun {
+		return
+	}
+	l.SchemaVersion = indexLifecycleLedgerSchemaVersion
+	for _, state := range result.Indexes {
+		if !state.Changed {
+			continue
+		}
+		at := result.CheckedAt
+		index := -1
+		for i, entry := range l.Entries {
+			if entry.ClusterDigest == result.ClusterIdentity.Digest && entry.Namespace == state.Namespace && entry.IndexName == state.IndexName {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			l.Entries = append(l.Entries, IndexLifecycleLedgerEntry{ClusterDigest: result.ClusterIdentity.Digest, Namespace: state.Namespace, IndexName: state.IndexName})
+			index = len(l.Entries) - 1
+		}
+		entry := &l.Entries[index]
+		entry.Key, entry.Hidden = state.Key, state.Hidden
+		if state.Hidden {
+			entry.HiddenAt, entry.UnhiddenAt, entry.UsageOpsAtHide = &at, nil, state.UsageOps
+		} else {
+			entry.UnhiddenAt = &at
+		}
+	}
+	sort.SliceStable(l.Entries, func(i, j int) bool {
+		if l.Entries[i].Namespace != l.Entries[j].Namespace {
+			return l.Entries[i].Namespace < l.Entries[j].Namespace
+		}
+		return l.Entries[i].IndexName < l.Entries[j].IndexName
+	})
+}
+
+func (l IndexLifecycleLedger) hiddenEntries(clusterDigest string, namespaces []string) []IndexLifecycleLedgerEntry {
+	var result []IndexLifecycleLedgerEntry
+	for _, entry := range l.Entries {
+		if !entry.Hidden || entry.ClusterDigest != clusterDigest {
+			continue
+		}
+		if len(namespaces) > 0 && !stringIncluded(namespaces, entry.Namespace) {
+			continue
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+func validateIndexLifecycleOptions(opts *IndexLifecycleOptions) (string, string, error) {
+	database, collection, found := strings.Cut(opts.Namespace, ".")
+	if !found || database == "" || collection == "" {
+		return "", "", invalidOptions("namespace must be <database>.<collection>")
+	}
+	if strings.TrimSpace(opts.IndexName) == "" {
+		return "", "", invalidOptions("index name is required")
+	}
+	if opts.MaxUsageOps < 0 || opts.NodeConcurrency < 0 {
+		return "", "", invalidOptions("max usage ops and node concurrency must not be negative")
+	}
+	if opts.NodeConcurrency == 0 {
+		opts.NodeConcurrency = defaultOverviewNodeConcurrency
+	}
+	return database, collection, nil
+}
+
+// refuseIndexLifecycle 只在错误中携带 reason code，不包含 namespace 或索引名。
+func refuseIndexLifecycle(result *IndexLifecycleResult, state IndexLifecycleState, reason string) (*IndexLifecycleResult, error) {
+	state.RefusedReason = reason
+	result.Indexes = []IndexLifecycleState{state}
+	return result, fmt.Errorf("%w: index %s refused (%s)", ErrDangerousOperation, result.Action, reason)
+}
+
+type indexLifecycleUsage struct {
+	found     bool
+	key       []IndexKeyField
+	ops       int64
+	allHidden bool
+	anyHidden bool
+}
+
+// indexLifecycleUsage 在每个数据成员上读取 $indexStats；ops 按成员累加，secondary 上的读取同样计入。
+func (c *Client) indexLifecycleUsage(ctx context.Context, clusterType pkgmongo.ClusterType, database, collection string, opts IndexLifecycleOptions) (indexLifecycleUsage, []CollectorStatus, []error) {
+	targets, statuses, collectorErrors := c.discoverHotspotTargets(ctx, clusterType)
+	usage := indexLifecycleUsage{allHidden: true}
+	var mu sync.Mutex
+	group, groupCtx := errgroup.WithContext(ctx)
+	limit := semaphore.NewWeighted(int64(opts.NodeConcurrency))
+	for _, target := range targets {
+		if acquireErr := acquireDiagnosticSlot(groupCtx, limit); acquireErr != nil {
+			mu.Lock()
+			collectorErrors = append(collectorErrors, acquireErr)
+			mu.Unlock()
+			break
+		}
+		target := target
+		group.Go(func() error {
+			defer limit.Release(1)
+			scope := FindingScope{Type: ScopeNode, ReplicaSet: target.ReplicaSet, Shard: target.Shard, Node: target.Address, Database: database}
+			stats, statsErr := c.indexLifecycleNodeStats(groupCtx, target.Address, database, collection)
+			mu.Lock()
+			defer mu.Unlock()
+			if statsErr != nil {
+				collectorErrors = append(collectorErrors, statsErr)
+				statuses = append(statuses, failedCollectorStatus("index_usage", scope, statsErr))
+				return nil
+			}
+			statuses = append(statuses, CollectorStatus{Name: "index_usage", State: CapabilitySupported, Scope: scope})
+			for _, stat := range stats {
+				if stat.Name != opts.IndexName {
+					continue
+				}
+				observation := indexObservationFromMongo(stat, target.Shard)
+				if !usage.found {
+					usage.key = observation.Key
+				}
+				usage.found = true
+				usage.ops += stat.Ops
+				usage.allHidden = usage.allHidden && stat.Hidden
+				usage.anyHidden = usage.anyHidden || stat.Hidden
+			}
+			return nil
+		})
+	}
+	_ = group.Wait()
+	if !usage.found {
+		usage.allHidden = false
+	}
+	return usage, statuses, collectorErrors
+}
+
+func (c *Client) indexLifecycleNodeStats(ctx context.Context, address, database, collection string) ([]pkgmongo.IndexStatSnapshot, error) {
+	release, err := c.acquireRemoteSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	conn, err := c.connectAddress(ctx, address, derivedConnectionOptions{Direct: boolPointer(true)})
+	if err != nil {
+		return nil, err
+	}
+	defer c.closeDerivedConnection(ctx, conn)
+	return conn.IndexStats(ctx, database, collection, 5*time.Second)
+}
+
+func indexKeyFromRouting(routing pkgmongo.IndexRoutingSnapshot) []IndexKeyField {
+	key := make([]IndexKeyField, 0, len(routing.ShardKey))
+	for _, field := range routing.ShardKey {
+		key = append(key, IndexKeyField{Field: field.Key, Order: fmt.Sprint(field.Value)})
+	}
+	return key
+}
+
+// indexKeyHasPrefix 判断索引是否可支撑分片键：key 以分片键的全部字段与方向开头。
+func indexKeyHasPrefix(key, prefix []IndexKeyField) bool {
+	if len(prefix) == 0 || len(key) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if key[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// indexLifecycleSlowlogGroup 是隐藏时间相同的索引所在的库与集合，共用一次 slowlog 读取。
+type indexLifecycleSlowlogGroup struct {
+	databases  []string
+	namespaces []string
+}
+
+// applyIndexLifecycleSlowQueries 把自 hiddenAt 起读取的 slowlog 归到在该时刻隐藏的索引上；
+// 只统计计划可能受该索引影响的形状：COLLSCAN，或使用了与该索引前导字段相同的其他索引。
+func applyIndexLifecycleSlowQueries(states []IndexLifecycleState, hiddenAt time.Time, slowlog *SlowlogSummaryResult) {
+	for i := range states {
+		state := &states[i]
+		if state.HiddenAt == nil || !state.HiddenAt.Equal(hiddenAt) {
+			continue
+		}
+		for _, replicaSet := range slowlog.ReplicaSets {
+			for _, host := range replicaSet.Hosts {
+				for _, database := range host.Databases {
+					for _, item := range database.Items {
+						if item.Namespace != state.Namespace || item.LastTime.Before(*state.HiddenAt) || !slowlogPlanCouldUseIndex(item.PlanSummary, state.Key) {
+							continue
+						}
+						state.SlowQueryShapes++
+						state.SlowQueryExecutions += item.Count
+						state.CollectionScans += item.CollectionScanCount
+					}
+				}
+			}
+		}
+	}
+}
+
+// slowlogPlanCouldUseIndex 按 planSummary 判断查询是否可能使用 key：集合扫描总是可能；索引扫描只有在
+// 扫描的 key pattern 与 key 前导字段相同时才视为可能。ledger 没有记录 key 时只认集合扫描。
+func slowlogPlanCouldUseIndex(planSummary string, key []IndexKeyField) bool {
+	if slowlogPlanScansCollection(planSummary) {
+		return true
+	}
+	if len(key) == 0 {
+		return false
+	}
+	for _, fields := range planSummaryKeyPatterns(planSummary) {
+		if len(fields) > 0 && fields[0] == key[0].Field {
+			return true
+		}
+	}
+	return false
+}
+
+// planSummaryKeyPatterns 解析 "IXSCAN { a: 1, b: -1 }, IXSCAN { c: 1 }" 形式的 planSummary，返回每个 key pattern 的字段名。
+func planSummaryKeyPatterns(planSummary string) [][]string {
+	var patterns [][]string
+	for rest := planSummary; ; {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			return patterns
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return patterns
+		}
+		var fields []string
+		for _, part := range strings.Split(rest[start+1:start+end], ",") {
+			field, _, _ := strings.Cut(strings.TrimSpace(part), ":")
+			if field = strings.Trim(strings.TrimSpace(field), `"`); field != "" {
+				fields = append(fields, field)
+			}
+		}
+		patterns = append(patterns, fields)
+		rest = rest[start+end+1:]
+	}
+}
+
+func indexLifecycleSlowQueryFinding(state IndexLifecycleState) DiagnosticFinding {
+	database, _, _ := strings.Cut(state.Namespace, ".")
+	severity := SeverityInfo
+	if state.CollectionScans > 0 {
+		severity = SeverityWarning
+	}
+	return DiagnosticFinding{
+		Code: "index.hidden_slow_queries", Severity: severity,
+		Scope:   FindingScope{Type: ScopeNamespace, Database: database, Namespace: state.Namespace},
+		Summary: "索引隐藏后该集合出现了可能依赖该索引的慢查询，删除前需确认是否由隐藏引起",
+		Evidence: map[string]any{
+			"indexName": state.IndexName, "hiddenFor": state.HiddenFor.String(), "slowQueryShapes": state.SlowQueryShapes,
+			"slowQueryExecutions": state.SlowQueryExecutions, "collectionScans": state.CollectionScans,
+		},
+		Recommendation: "使用 mot slowlog --ns 查看对应查询形状；若计划依赖该索引，执行 mot index unhide 恢复",
+	}
+}
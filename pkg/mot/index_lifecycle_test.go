@@ -0,0 +1,116 @@
+package mot
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIndexLifecycleLedgerRecordsOnlyAppliedChanges(t *testing.T) {
+	// 场景：dry-run 与未变化的结果不写入 ledger；hide 记录隐藏时间，unhide 在同一条目上记录恢复时间。
+	ops := int64(0)
+	hiddenAt := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	hide := &IndexLifecycleResult{ClusterIdentity: CapacityIdentity{Digest: "c1"}, Action: IndexLifecycleHide, CheckedAt: hiddenAt,
+		Indexes: []IndexLifecycleState{{Namespace: "app.orders", IndexName: "legacy_1", Hidden: true, Changed: true, UsageOps: &ops}}}
+	var ledger IndexLifecycleLedger
+	ledger.Record(&IndexLifecycleResult{DryRun: true, Indexes: []IndexLifecycleState{{Namespace: "app.orders", IndexName: "x_1", Changed: true}}})
+	ledger.Record(&IndexLifecycleResult{Indexes: []IndexLifecycleState{{Namespace: "app.orders", IndexName: "y_1", Hidden: true}}})
+	ledger.Record(hide)
+	if len(ledger.Entries) != 1 || ledger.SchemaVersion != indexLifecycleLedgerSchemaVersion {
+		t.Fatalf("ledger = %#v", ledger)
+	}
+	if entry := ledger.Entries[0]; !entry.Hidden || entry.HiddenAt == nil || !entry.HiddenAt.Equal(hiddenAt) || entry.ClusterDigest != "c1" || entry.UsageOpsAtHide == nil {
+		t.Fatalf("entry = %#v", entry)
+	}
+	if got := ledger.hiddenEntries("c1", []string{"app.orders"}); len(got) != 1 {
+		t.Fatalf("hidden entries = %#v", got)
+	}
+	if got := ledger.hiddenEntries("other", nil); len(got) != 0 {
+		t.Fatalf("entries of another cluster = %#v", got)
+	}
+
+	ledger.Record(&IndexLifecycleResult{ClusterIdentity: CapacityIdentity{Digest: "c1"}, Action: IndexLifecycleUnhide, CheckedAt: hiddenAt.Add(time.Hour),
+		Indexes: []IndexLifecycleState{{Namespace: "app.orders", IndexName: "legacy_1", Changed: true}}})
+	if entry := ledger.Entries[0]; len(ledger.Entries) != 1 || entry.Hidden || entry.UnhiddenAt == nil || entry.HiddenAt == nil {
+		t.Fatalf("entry after unhide = %#v", entry)
+	}
+	if got := ledger.hiddenEntries("c1", nil); len(got) != 0 {
+		t.Fatalf("unhidden entry still listed: %#v", got)
+	}
+}
+
+func TestIndexLifecycleGuards(t *testing.T) {
+	// 场景：namespace 与索引名必须完整；4.4 以下拒绝隐藏；支撑分片键的索引按字段与方向前缀识别；拒绝错误只带 reason code。
+	for _, opts := range []IndexLifecycleOptions{{IndexName: "a_1"}, {Namespace: "app", IndexName: "a_1"}, {Namespace: "app.orders"}, {Namespace: "app.orders", IndexName: "a_1", MaxUsageOps: -1}} {
+		if _, _, err := validateIndexLifecycleOptions(&opts); !errors.Is(err, ErrInvalidOptions) {
+			t.Fatalf("options %#v error = %v", opts, err)
+		}
+	}
+	if status, allowed := diagnosticCapabilityGate("index_lifecycle", ClusterReplicaSet, 8, true); allowed || status.ReasonCode != "unsupported_version" {
+		t.Fatalf("4.2 gate = %#v allowed=%t", status, allowed)
+	}
+	shardKey := []IndexKeyField{{Field: "tenant", Order: "1"}}
+	if !indexKeyHasPrefix([]IndexKeyField{{Field: "tenant", Order: "1"}, {Field: "createdAt", Order: "-1"}}, shardKey) {
+		t.Fatal("compound index with shard key prefix was not detected")
+	}
+	if indexKeyHasPrefix([]IndexKeyField{{Field: "tenant", Order: "hashed"}}, shardKey) || indexKeyHasPrefix([]IndexKeyField{{Field: "createdAt", Order: "1"}}, shardKey) {
+		t.Fatal("unrelated index treated as shard key index")
+	}
+	result, err := refuseIndexLifecycle(&IndexLifecycleResult{Action: IndexLifecycleHide}, IndexLifecycleState{Namespace: "secret.orders", IndexName: "legacy_1"}, "index_in_use")
+	if !errors.Is(err, ErrDangerousOperation) || result.Indexes[0].RefusedReason != "index_in_use" {
+		t.Fatalf("refusal = %#v, %v", result, err)
+	}
+	if msg := err.Error(); msg != "dangerous operation: index hide refused (index_in_use)" {
+		t.Fatalf("refusal message = %q", msg)
+	}
+}
+
+func TestApplyIndexLifecycleSlowQueriesCountsShapesAfterHide(t *testing.T) {
+	// 场景：只统计同一 namespace 上最后出现时间晚于该索引隐藏时间、计划可能使用该索引的慢查询形状，COLLSCAN 使 finding 升级为 warning。
+	hiddenAt := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	laterHiddenAt := hiddenAt.Add(24 * time.Hour)
+	key := []IndexKeyField{{Field: "status", Order: "1"}, {Field: "createdAt", Order: "-1"}}
+	states := []IndexLifecycleState{
+		{Namespace: "app.orders", IndexName: "legacy_1", Key: key, Hidden: true, HiddenAt: &hiddenAt, HiddenFor: 48 * time.Hour},
+		{Namespace: "app.orders", IndexName: "later_1", Key: key, Hidden: true, HiddenAt: &laterHiddenAt, HiddenFor: 24 * time.Hour},
+	}
+	slowlog := &SlowlogSummaryResult{ReplicaSets: []ReplicaSetSlowlogSummary{{Hosts: []HostSlowlogSummary{{Databases: []DatabaseSlowlogSummary{{Items: []SlowlogSummaryItem{
+		{Namespace: "app.orders", PlanSummary: "COLLSCAN", Count: 4, CollectionScanCount: 3, LastTime: hiddenAt.Add(time.Hour)},
+		{Namespace: "app.orders", PlanSummary: "IXSCAN { status: 1 }", Count: 5, LastTime: hiddenAt.Add(2 * time.Hour)},
+		{Namespace: "app.orders", PlanSummary: "IXSCAN { userId: 1 }", Count: 6, LastTime: hiddenAt.Add(2 * time.Hour)},
+		{Namespace: "app.orders", PlanSummary: "COLLSCAN", Count: 9, LastTime: hiddenAt.Add(-time.Hour)},
+		{Namespace: "app.users", PlanSummary: "COLLSCAN", Count: 2, LastTime: hiddenAt.Add(time.Hour)},
+	}}}}}}}}
+	applyIndexLifecycleSlowQueries(states, hiddenAt, slowlog)
+	if states[0].SlowQueryShapes != 2 || states[0].SlowQueryExecutions != 9 || states[0].CollectionScans != 3 {
+		t.Fatalf("state = %#v", states[0])
+	}
+	if states[1].SlowQueryShapes != 0 {
+		t.Fatalf("index hidden at a different time was attributed: %#v", states[1])
+	}
+	finding := indexLifecycleSlowQueryFinding(states[0])
+	if finding.Code != "index.hidden_slow_queries" || finding.Severity != SeverityWarning || finding.Scope.Namespace != "app.orders" {
+		t.Fatalf("finding = %#v", finding)
+	}
+}
+
+func TestSlowlogPlanCouldUseIndexMatchesLeadingField(t *testing.T) {
+	// 场景：集合扫描总是可能受隐藏索引影响；索引扫描只有前导字段相同才算，缺少 key 的 ledger 记录只认集合扫描。
+	key := []IndexKeyField{{Field: "status", Order: "1"}}
+	for _, tc := range []struct {
+		plan string
+		key  []IndexKeyField
+		want bool
+	}{
+		{plan: "COLLSCAN", key: key, want: true},
+		{plan: "IXSCAN { userId: 1 }, IXSCAN { status: 1, createdAt: -1 }", key: key, want: true},
+		{plan: "IXSCAN { userId: 1, status: 1 }", key: key, want: false},
+		{plan: "IDHACK", key: key, want: false},
+		{plan: "IXSCAN { status: 1 }", key: nil, want: false},
+		{plan: "COLLSCAN", key: nil, want: true},
+	} {
+		if got := slowlogPlanCouldUseIndex(tc.plan, tc.key); got != tc.want {
+			t.Fatalf("slowlogPlanCouldUseIndex(%q, %v) = %v, want %v", tc.plan, tc.key, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package mot
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	drivermongo "go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/sync/semaphore"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+// IndexMemberDifference 是同一副本集（或同一 shard）成员之间的索引定义差异；Members 为缺失或参与比较的成员地址。
+type IndexMemberDifference struct {
+	Code            string          `json:"code"`
+	ReplicaSet      string          `json:"replicaSet"`
+	Shard           string          `json:"shard,omitempty"`
+	IndexName       string          `json:"indexName,omitempty"`
+	Members         []string        `json:"members,omitempty"`
+	Key             []IndexKeyField `json:"key,omitempty"`
+	Fingerprint     string          `json:"fingerprint,omitempty"`
+	DifferingFields []string        `json:"differingFields,omitempty"`
+}
+
+// memberDifferenceCodes 把跨 shard 比较的 code 映射为成员级 code，比较逻辑与 direct listIndexes 策略共用。
+var memberDifferenceCodes = map[string]string{
+	"index.missing_on_shard": "index.missing_on_member",
+	"index.name_mismatch":    "index.member_name_mismatch",
+	"index.spec_mismatch":    "index.member_spec_mismatch",
+}
+
+type indexMemberGroup struct {
+	replicaSet string
+	shard      string
+	targets    []hotspotTarget
+}
+
+// collectIndexMemberConsistency 直连同一副本集的每个数据成员执行 listIndexes，用 canonical fingerprint 比较成员之间的定义。
+// 无法读取的成员只记录状态，不参与比较；少于两个可比较成员时跳过该副本集。
+func (c *Client) collectIndexMemberConsistency(ctx context.Context, ref indexCollectionRef, targets []hotspotTarget, capabilityLimit *semaphore.Weighted) ([]IndexMemberDifference, []CollectorStatus, []error) {
+	var differences []IndexMemberDifference
+	var statuses []CollectorStatus
+	var collectorErrors []error
+	namespace := ref.Database + "." + ref.Collection
+	for _, group := range indexMemberGroups(targets) {
+		if len(group.targets) < 2 {
+			continue
+		}
+		observations := make(map[string][]pkgmongo.CanonicalIndexDefinition, len(group.targets))
+		var mu sync.Mutex
+		results := collectIndexAuditTargets(ctx, group.targets, func(ctx context.Context, target hotspotTarget) indexAuditTargetCollection {
+			scope := FindingScope{Type: ScopeNamespace, ReplicaSet: target.ReplicaSet, Shard: target.Shard, Node: target.Address, Database: ref.Database, Namespace: namespace}
+			release, acquireErr := c.acquireCapabilityRemoteSlot(ctx, capabilityLimit)
+			if acquireErr != nil {
+				return indexAuditTargetCollection{errors: []error{acquireErr}}
+			}
+			defer release()
+			definitions, listErr := c.memberIndexDefinitions(ctx, target.Address, ref)
+			if listErr != nil {
+				item := indexAuditTargetCollection{statuses: []CollectorStatus{failedCollectorStatus("index_member_consistency", scope, listErr)}}
+				if !isUnauthorizedError(listErr) {
+					item.errors = []error{listErr}
+				}
+				return item
+			}
+			mu.Lock()
+			observations[target.Address] = definitions
+			mu.Unlock()
+			return indexAuditTargetCollection{statuses: []CollectorStatus{{Name: "index_member_consistency", State: CapabilitySupported, Scope: scope}}}
+		})
+		for _, item := range results {
+			statuses = append(statuses, item.statuses...)
+			collectorErrors = append(collectorErrors, item.errors...)
+		}
+		differences = append(differences, compareIndexMemberDefinitions(group, observations)...)
+	}
+	return differences, statuses, collectorErrors
+}
+
+// compareIndexMemberDefinitions 比较同一副本集内已读取成员的索引定义；构建中的索引不参与比较，少于两个成员时无从比较。
+func compareIndexMemberDefinitions(group indexMemberGroup, observations map[string][]pkgmongo.CanonicalIndexDefinition) []IndexMemberDifference {
+	if len(observations) < 2 {
+		return nil
+	}
+	members := make([]string, 0, len(observations))
+	for member := range observations {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	buildingNames, buildingSemantics := buildingIndexKeys(observations)
+	var differences []IndexMemberDifference
+	for _, difference := range compareLegacyDefinitions(members, observations, buildingNames, buildingSemantics) {
+		differences = append(differences, IndexMemberDifference{
+			Code: memberDifferenceCodes[difference.Code], ReplicaSet: group.replicaSet, Shard: group.shard,
+			IndexName: difference.IndexName, Members: difference.Shards, Key: difference.Key,
+			Fingerprint: difference.Fingerprint, DifferingFields: difference.DifferingFields,
+		})
+	}
+	return differences
+}
+
+// memberIndexDefinitions 读取单个成员上的索引定义；成员上不存在该集合时视为没有任何索引。
+func (c *Client) memberIndexDefinitions(ctx context.Context, address string, ref indexCollectionRef) ([]pkgmongo.CanonicalIndexDefinition, error) {
+	conn, err := c.connectAddress(ctx, address, derivedConnectionOptions{Direct: boolPointer(true)})
+	if err != nil {
+		return nil, err
+	}
+	defer c.closeDerivedConnection(ctx, conn)
+	definitions, err := conn.ListIndexDefinitions(ctx, ref.Database, ref.Collection, indexConsistencyCollectorTimeout)
+	if isNamespaceNotFoundError(err) {
+		return []pkgmongo.CanonicalIndexDefinition{}, nil
+	}
+	return definitions, err
+}
+
+func indexMemberGroups(targets []hotspotTarget) []indexMemberGroup {
+	byReplicaSet := make(map[string]*indexMemberGroup)
+	var order []string
+	for _, target := range targets {
+		key := target.Shard + "\x00" + target.ReplicaSet
+		group, ok := byReplicaSet[key]
+		if !ok {
+			group = &indexMemberGroup{replicaSet: target.ReplicaSet, shard: target.Shard}
+			byReplicaSet[key] = group
+			order = append(order, key)
+		}
+		group.targets = append(group.targets, target)
+	}
+	sort.Strings(order)
+	result := make([]indexMemberGroup, 0, len(order))
+	for _, key := range order {
+		result = append(result, *byReplicaSet[key])
+	}
+	return result
+}
+
+func indexMemberConsistencyFindings(namespace, database string, differences []IndexMemberDifference) []DiagnosticFinding {
+	summaries := map[string]string{
+		"index.missing_on_member":    "索引在同一副本集的部分成员上缺失",
+		"index.member_name_mismatch": "语义相同的索引在同一副本集的成员上名称不一致",
+		"index.member_spec_mismatch": "同名索引的定义在同一副本集的成员之间不一致",
+	}
+	findings := make([]DiagnosticFinding, 0, len(differences))
+	for _, difference := range differences {
+		findings = append(findings, DiagnosticFinding{
+			Code: difference.Code, Severity: SeverityWarning,
+			Scope:   FindingScope{Type: ScopeNamespace, ReplicaSet: difference.ReplicaSet, Shard: difference.Shard, Database: database, Namespace: namespace},
+			Summary: summaries[difference.Code],
+			Evidence: map[string]any{
+				"indexName": difference.IndexName, "members": difference.Members,
+				"fingerprint": difference.Fingerprint, "differingFields": difference.DifferingFields,
+			},
+			Recommendation: "确认各成员的索引构建是否完成；对缺失或定义不同的成员按滚动方式重建索引，恢复自旧备份的成员需重新同步",
+		})
+	}
+	return findings
+}
+
+func isNamespaceNotFoundError(err error) bool {
+	var commandError drivermongo.CommandError
+	return errors.As(err, &commandError) && commandError.Code == 26
+}
@@ -0,0 +1,67 @@
+package mot
+
+import (
+	"testing"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+func TestCompareIndexMemberDefinitionsReportsPerMember(t *testing.T) {
+	// 场景：同一副本集内缺失、定义不同的索引按成员报告；构建中的索引和单成员副本集不参与比较。
+	tenant := consistencyDefinition("tenant_1", "semantic-a", false, map[string]string{"key": "key-a", "unique": "false"})
+	tenantUnique := consistencyDefinition("tenant_1", "semantic-b", false, map[string]string{"key": "key-a", "unique": "true"})
+	created := consistencyDefinition("createdAt_1", "semantic-c", false, map[string]string{"key": "key-c"})
+	building := consistencyDefinition("status_1", "semantic-d", true, map[string]string{"key": "key-d"})
+	group := indexMemberGroup{replicaSet: "rs0", shard: "shard-a"}
+	differences := compareIndexMemberDefinitions(group, map[string][]pkgmongo.CanonicalIndexDefinition{
+		"n1:27017": {tenant, created, building},
+		"n2:27017": {tenantUnique, created},
+		"n3:27017": {tenant},
+	})
+	codes := make(map[string]IndexMemberDifference, len(differences))
+	for _, difference := range differences {
+		codes[difference.Code] = difference
+		if difference.ReplicaSet != "rs0" || difference.Shard != "shard-a" {
+			t.Fatalf("difference scope = %#v", difference)
+		}
+	}
+	if len(differences) != 2 {
+		t.Fatalf("differences = %#v", differences)
+	}
+	if missing := codes["index.missing_on_member"]; missing.IndexName != "createdAt_1" || len(missing.Members) != 1 || missing.Members[0] != "n3:27017" {
+		t.Fatalf("missing difference = %#v", missing)
+	}
+	if spec := codes["index.member_spec_mismatch"]; spec.IndexName != "tenant_1" || len(spec.DifferingFields) == 0 {
+		t.Fatalf("spec difference = %#v", spec)
+	}
+	if got := compareIndexMemberDefinitions(group, map[string][]pkgmongo.CanonicalIndexDefinition{"n1:27017": {tenant}}); len(got) != 0 {
+		t.Fatalf("single member differences = %#v", got)
+	}
+
+	findings := indexMemberConsistencyFindings("app.orders", "app", differences)
+	if len(findings) != 2 || findings[0].Scope.ReplicaSet != "rs0" || findings[0].Scope.Namespace != "app.orders" || findings[0].Summary == "" {
+		t.Fatalf("findings = %#v", findings)
+	}
+}
+
+func TestIndexMemberGroupsAndChecks(t *testing.T) {
+	// 场景：成员按 shard 与副本集分组且顺序稳定；member_consistency 需显式指定，既不要求 mongos 也不触发通用使用率采集。
+	groups := indexMemberGroups([]hotspotTarget{
+		{ReplicaSet: "rs1", Shard: "shard-b", Address: "b1"},
+		{ReplicaSet: "rs0", Shard: "shard-a", Address: "a1"},
+		{ReplicaSet: "rs1", Shard: "shard-b", Address: "b2"},
+	})
+	if len(groups) != 2 || groups[0].replicaSet != "rs0" || len(groups[1].targets) != 2 {
+		t.Fatalf("groups = %#v", groups)
+	}
+	if includesGeneralIndexCheck([]IndexAuditCheck{IndexCheckMemberConsistency}) {
+		t.Fatal("member_consistency treated as general index check")
+	}
+	opts, err := normalizeIndexAuditOptions(IndexAuditOptions{AllDatabases: true})
+	if err != nil || includesIndexCheck(opts.Checks, IndexCheckMemberConsistency) {
+		t.Fatalf("default checks = %#v, %v", opts.Checks, err)
+	}
+	if _, err := normalizeIndexAuditOptions(IndexAuditOptions{AllDatabases: true, Checks: []IndexAuditCheck{IndexCheckMemberConsistency}}); err != nil {
+		t.Fatal(err)
+	}
+}
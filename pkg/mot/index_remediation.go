@@ -0,0 +1,291 @@
+package mot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	indexRemediationSchemaVersion = 1
+	defaultIndexHidePeriod        = 7 * 24 * time.Hour
+	// hideIndexMinimumWireVersion 对应 MongoDB 4.4，collMod 自该版本起支持 index.hidden。
+	hideIndexMinimumWireVersion = 9
+)
+
+type IndexRemediationPhase string
+
+const (
+	IndexRemediationCreate IndexRemediationPhase = "create"
+	IndexRemediationHide   IndexRemediationPhase = "hide"
+	IndexRemediationDrop   IndexRemediationPhase = "drop"
+)
+
+// IndexRemediationOptions 控制修复计划的生成；HidePeriod 为隐藏索引到允许删除之间的观察期，默认 7 天。
+type IndexRemediationOptions struct {
+	HidePeriod     time.Duration
+	GeneratedAt    time.Time
+	MaxWireVersion int // 为 0 时视为未知，仍生成 hideIndex 步骤
+}
+
+// IndexRemediationPlan 是根据 index-audit 结果生成、未经执行的修复计划；每一步附带来源 finding 与回滚命令。
+type IndexRemediationPlan struct {
+	SchemaVersion int                    `json:"schemaVersion"`
+	GeneratedAt   time.Time              `json:"generatedAt"`
+	HidePeriod    time.Duration          `json:"hidePeriod"`
+	Steps         []IndexRemediationStep `json:"steps"`
+}
+
+// IndexRemediationStep 的 Command 与 Rollback 均为 mongosh 语句；RequiresReview 表示索引定义中有审计结果未保留的选项，
+// 执行前必须人工补全或确认。
+type IndexRemediationStep struct {
+	Order          int                   `json:"order"`
+	Phase          IndexRemediationPhase `json:"phase"`
+	Namespace      string                `json:"namespace"`
+	IndexName      string                `json:"indexName"`
+	Shards         []string              `json:"shards,omitempty"`
+	FindingCode    string                `json:"findingCode"`
+	FindingSummary string                `json:"findingSummary,omitempty"`
+	Command        string                `json:"command"`
+	Rollback       string                `json:"rollback"`
+	NotBefore      *time.Time            `json:"notBefore,omitempty"`
+	RequiresReview bool                  `json:"requiresReview,omitempty"`
+	Notes          []string              `json:"notes,omitempty"`
+}
+
+// IndexRemediationPlan 读取集群 wire version 后生成修复计划；只执行 hello，不创建、隐藏或删除任何索引。
+func (c *Client) IndexRemediationPlan(ctx context.Context, result *IndexAuditResult, opts IndexRemediationOptions) (plan *IndexRemediationPlan, err error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+	defer func() { err = mapContextError(err) }()
+	if opts.MaxWireVersion == 0 {
+		cluster, err := c.detectCluster(ctx)
+		if err != nil {
+			return nil, err
+		}
+		opts.MaxWireVersion = cluster.MaxWireVersion
+	}
+	return BuildIndexRemediationPlan(result, opts)
+}
+
+// BuildIndexRemediationPlan 从 index-audit 结果生成修复计划，不连接 MongoDB：
+// 缺失于部分 shard 的索引生成 createIndexes，长期未使用的索引先 hideIndex（4.4+），观察期后再 dropIndex；
+// 已知分片键时跳过以分片键为前缀的索引。
+func BuildIndexRemediationPlan(result *IndexAuditResult, opts IndexRemediationOptions) (*IndexRemediationPlan, error) {
+	if result == nil {
+		return nil, invalidOptions("index audit result is required")
+	}
+	if opts.HidePeriod < 0 {
+		return nil, invalidOptions("hide period must not be negative")
+	}
+	if opts.HidePeriod == 0 {
+		opts.HidePeriod = defaultIndexHidePeriod
+	}
+	if opts.GeneratedAt.IsZero() {
+		opts.GeneratedAt = time.Now()
+	}
+	plan := &IndexRemediationPlan{SchemaVersion: indexRemediationSchemaVersion, GeneratedAt: opts.GeneratedAt.UTC(), HidePeriod: opts.HidePeriod}
+	hideSupported := opts.MaxWireVersion == 0 || opts.MaxWireVersion >= hideIndexMinimumWireVersion
+	for _, collection := range result.Collections {
+		database, name, found := strings.Cut(collection.Namespace, ".")
+		if !found {
+			continue
+		}
+		for _, difference := range collection.Differences {
+			if difference.Code != "index.missing_on_shard" || difference.IndexName == "" {
+				continue
+			}
+			plan.Steps = append(plan.Steps, createIndexRemediationStep(database, name, collection, difference))
+		}
+		for _, finding := range collection.Findings {
+			if finding.Code != "index.unused_candidate" {
+				continue
+			}
+			indexName, _ := finding.Evidence["indexName"].(string)
+			if indexName == "" || indexName == "_id_" {
+				continue
+			}
+			// 以分片键为前缀的索引支撑分片键，mot index hide 会以 shard_key_index 拒绝，计划中同样不生成隐藏与删除步骤。
+			if observation, observed := indexObservationByName(collection.Indexes, indexName); observed && indexKeyHasPrefix(observation.Key, collection.ShardKey) {
+				continue
+			}
+			plan.Steps = append(plan.Steps, unusedIndexRemediationSteps(database, name, collection, indexName, finding, hideSupported, plan.GeneratedAt.Add(opts.HidePeriod))...)
+		}
+	}
+	sort.SliceStable(plan.Steps, func(i, j int) bool {
+		left, right := plan.Steps[i], plan.Steps[j]
+		if indexRemediationPhaseRank(left.Phase) != indexRemediationPhaseRank(right.Phase) {
+			return indexRemediationPhaseRank(left.Phase) < indexRemediationPhaseRank(right.Phase)
+		}
+		if left.Namespace != right.Namespace {
+			return left.Namespace < right.Namespace
+		}
+		return left.IndexName < right.IndexName
+	})
+	for i := range plan.Steps {
+		plan.Steps[i].Order = i + 1
+	}
+	return plan, nil
+}
+
+func createIndexRemediationStep(database, collectionName string, collection CollectionIndexAudit, difference IndexConsistencyDifference) IndexRemediationStep {
+	step := IndexRemediationStep{
+		Phase: IndexRemediationCreate, Namespace: collection.Namespace, IndexName: difference.IndexName,
+		Shards: append([]string(nil), difference.Shards...), FindingCode: difference.Code,
+		FindingSummary: indexConsistencyFindingSummary(collection.Findings, difference.Code),
+	}
+	key := difference.Key
+	observation, observed := indexObservationByName(collection.Indexes, difference.IndexName)
+	if len(key) == 0 && observed {
+		key = observation.Key
+	}
+	if len(key) == 0 {
+		step.RequiresReview = true
+		step.Notes = append(step.Notes, "审计结果未包含索引 key，需从已有该索引的 shard 上 getIndexes() 取得完整定义")
+	}
+	var specNotes []string
+	step.Command, specNotes = createIndexCommand(database, collectionName, difference.IndexName, key, observation, observed)
+	if len(specNotes) > 0 {
+		step.RequiresReview = true
+		step.Notes = append(step.Notes, specNotes...)
+	}
+	step.Rollback = dropIndexCommand(database, collectionName, difference.IndexName)
+	step.Notes = append(step.Notes, "通过 mongos 执行时只会在缺失的 shard 上新建索引；回滚需直连本步骤列出的 shard PRIMARY 执行，经 mongos 执行会删除所有 shard 上的同名索引")
+	return step
+}
+
+func unusedIndexRemediationSteps(database, collectionName string, collection CollectionIndexAudit, indexName string, finding DiagnosticFinding, hideSupported bool, notBefore time.Time) []IndexRemediationStep {
+	observation, observed := indexObservationByName(collection.Indexes, indexName)
+	recreate, specNotes := createIndexCommand(database, collectionName, indexName, observation.Key, observation, observed)
+	drop := IndexRemediationStep{
+		Phase: IndexRemediationDrop, Namespace: collection.Namespace, IndexName: indexName,
+		FindingCode: finding.Code, FindingSummary: finding.Summary,
+		Command: dropIndexCommand(database, collectionName, indexName), Rollback: recreate,
+	}
+	if !observed || len(observation.Key) == 0 {
+		drop.RequiresReview = true
+		drop.Notes = append(drop.Notes, "审计结果未包含索引定义，回滚前需先保存 getIndexes() 输出")
+	}
+	if len(specNotes) > 0 {
+		drop.RequiresReview = true
+		drop.Notes = append(drop.Notes, specNotes...)
+	}
+	if observation.Unique {
+		drop.RequiresReview = true
+		drop.Notes = append(drop.Notes, "唯一索引同时承担约束，删除后将不再拒绝重复值")
+	}
+	if observation.ExpireAfterSeconds != nil {
+		drop.RequiresReview = true
+		drop.Notes = append(drop.Notes, "TTL 索引删除后过期文档不再自动清理")
+	}
+	if !hideSupported {
+		drop.RequiresReview = true
+		drop.Notes = append(drop.Notes, "MongoDB 4.4 以下不支持隐藏索引，无法先隐藏观察，删除前需充分复核慢日志")
+		return []IndexRemediationStep{drop}
+	}
+	notBeforeUTC := notBefore.UTC()
+	drop.NotBefore = &notBeforeUTC
+	drop.Notes = append(drop.Notes, "仅在隐藏观察期结束且未发现相关慢查询回归后执行")
+	if observation.Hidden {
+		drop.Notes = append(drop.Notes, "索引在审计时已处于隐藏状态")
+		return []IndexRemediationStep{drop}
+	}
+	hide := IndexRemediationStep{
+		Phase: IndexRemediationHide, Namespace: collection.Namespace, IndexName: indexName,
+		FindingCode: finding.Code, FindingSummary: finding.Summary,
+		Command:  hideIndexCommand(database, collectionName, indexName, true),
+		Rollback: hideIndexCommand(database, collectionName, indexName, false),
+		Notes:    []string{"隐藏后索引仍随写入维护，查询规划器不再使用；出现回归时执行回滚命令即可立即恢复"},
+	}
+	return []IndexRemediationStep{hide, drop}
+}
+
+func indexObservationByName(observations []IndexObservation, name string) (IndexObservation, bool) {
+	for _, observation := range observations {
+		if observation.Name == name {
+			return observation, true
+		}
+	}
+	return IndexObservation{}, false
+}
+
+func indexConsistencyFindingSummary(findings []DiagnosticFinding, code string) string {
+	for _, finding := range findings {
+		if finding.Code == code {
+			return finding.Summary
+		}
+	}
+	return ""
+}
+
+// createIndexCommand 用审计结果保留的 key 与选项重建 createIndexes；partial、collation、wildcardProjection
+// 与特殊索引的附加参数只保留了指纹，返回需要人工补全的说明。
+func createIndexCommand(database, collection, name string, key []IndexKeyField, observation IndexObservation, observed bool) (string, []string) {
+	fields := make([]string, 0, len(key))
+	for _, field := range key {
+		fields = append(fields, mongoshString(field.Field)+": "+mongoshIndexOrder(field.Order))
+	}
+	spec := []string{"key: {" + strings.Join(fields, ", ") + "}", "name: " + mongoshString(name)}
+	var notes []string
+	if observed {
+		if observation.Unique {
+			spec = append(spec, "unique: true")
+		}
+		if observation.Sparse {
+			spec = append(spec, "sparse: true")
+		}
+		if observation.ExpireAfterSeconds != nil {
+			spec = append(spec, "expireAfterSeconds: "+strconv.FormatInt(*observation.ExpireAfterSeconds, 10))
+		}
+		if observation.Partial {
+			notes = append(notes, "索引包含 partialFilterExpression，需补全原始过滤条件")
+		}
+		if observation.CollationFingerprint != "" {
+			notes = append(notes, "索引指定了 collation，需补全原始 collation")
+		}
+		if observation.WildcardProjection {
+			notes = append(notes, "索引包含 wildcardProjection，需补全原始投影")
+		}
+		if observation.SpecialType != "" {
+			notes = append(notes, fmt.Sprintf("%s 索引可能带有附加参数（如 weights、2dsphereIndexVersion），需按原定义补全", observation.SpecialType))
+		}
+	}
+	return fmt.Sprintf("db.getSiblingDB(%s).runCommand({createIndexes: %s, indexes: [{%s}]})", mongoshString(database), mongoshString(collection), strings.Join(spec, ", ")), notes
+}
+
+func dropIndexCommand(database, collection, name string) string {
+	return fmt.Sprintf("db.getSiblingDB(%s).runCommand({dropIndexes: %s, index: %s})", mongoshString(database), mongoshString(collection), mongoshString(name))
+}
+
+func hideIndexCommand(database, collection, name string, hidden bool) string {
+	return fmt.Sprintf("db.getSiblingDB(%s).runCommand({collMod: %s, index: {name: %s, hidden: %t}})", mongoshString(database), mongoshString(collection), mongoshString(name), hidden)
+}
+
+func mongoshString(value string) string {
+	payload, _ := json.Marshal(value)
+	return string(payload)
+}
+
+// mongoshIndexOrder 数值方向原样输出，hashed、text、2dsphere 等类型输出为字符串。
+func mongoshIndexOrder(order string) string {
+	if _, err := strconv.ParseFloat(order, 64); err == nil {
+		return order
+	}
+	return mongoshString(order)
+}
+
+func indexRemediationPhaseRank(phase IndexRemediationPhase) int {
+	switch phase {
+	case IndexRemediationCreate:
+		return 0
+	case IndexRemediationHide:
+		return 1
+	default:
+		return 2
+	}
+}
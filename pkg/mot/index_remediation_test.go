@@ -0,0 +1,105 @@
+package mot
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildIndexRemediationPlanOrdersCreateHideDrop(t *testing.T) {
+	// 场景：缺失 shard 的索引先 createIndexes，未使用索引先 hideIndex，dropIndexes 排在最后且带观察期。
+	generatedAt := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	result := &IndexAuditResult{Collections: []CollectionIndexAudit{{
+		Namespace: "app.orders",
+		Differences: []IndexConsistencyDifference{{
+			Code: "index.missing_on_shard", IndexName: "status_1", Shards: []string{"rs1"},
+			Key: []IndexKeyField{{Field: "status", Order: "1"}},
+		}},
+		Indexes: []IndexObservation{
+			{Name: "status_1", Key: []IndexKeyField{{Field: "status", Order: "1"}}},
+			{Name: "legacy_1", Key: []IndexKeyField{{Field: "legacy", Order: "-1"}}, Sparse: true},
+		},
+		Findings: []DiagnosticFinding{
+			{Code: "index.missing_on_shard", Summary: "索引在部分 expected shards 上缺失"},
+			{Code: "index.unused_candidate", Summary: "unused", Evidence: map[string]any{"indexName": "legacy_1"}},
+			{Code: "index.unused_candidate", Evidence: map[string]any{"indexName": "_id_"}},
+		},
+	}}}
+	plan, err := BuildIndexRemediationPlan(result, IndexRemediationOptions{GeneratedAt: generatedAt, MaxWireVersion: 17})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Steps) != 3 {
+		t.Fatalf("steps = %#v", plan.Steps)
+	}
+	create, hide, drop := plan.Steps[0], plan.Steps[1], plan.Steps[2]
+	if create.Phase != IndexRemediationCreate || create.Order != 1 || create.FindingSummary == "" || len(create.Shards) != 1 {
+		t.Fatalf("create = %#v", create)
+	}
+	if create.Command != `db.getSiblingDB("app").runCommand({createIndexes: "orders", indexes: [{key: {"status": 1}, name: "status_1"}]})` {
+		t.Fatalf("create command = %s", create.Command)
+	}
+	if hide.Phase != IndexRemediationHide || !strings.Contains(hide.Command, "hidden: true") || !strings.Contains(hide.Rollback, "hidden: false") {
+		t.Fatalf("hide = %#v", hide)
+	}
+	if drop.Phase != IndexRemediationDrop || drop.NotBefore == nil || !drop.NotBefore.Equal(generatedAt.Add(defaultIndexHidePeriod)) {
+		t.Fatalf("drop = %#v", drop)
+	}
+	if !strings.Contains(drop.Command, `dropIndexes: "orders", index: "legacy_1"`) || !strings.Contains(drop.Rollback, `{"legacy": -1}`) || !strings.Contains(drop.Rollback, "sparse: true") {
+		t.Fatalf("drop command = %s rollback = %s", drop.Command, drop.Rollback)
+	}
+}
+
+func TestBuildIndexRemediationPlanSkipsShardKeyIndexes(t *testing.T) {
+	// 场景：已知分片键时，以分片键为前缀的未使用索引不生成隐藏或删除步骤（mot index hide 会拒绝），其他未使用索引照常生成。
+	result := &IndexAuditResult{Collections: []CollectionIndexAudit{{
+		Namespace: "app.orders",
+		ShardKey:  []IndexKeyField{{Field: "tenant", Order: "1"}},
+		Indexes: []IndexObservation{
+			{Name: "tenant_1_createdAt_1", Key: []IndexKeyField{{Field: "tenant", Order: "1"}, {Field: "createdAt", Order: "1"}}},
+			{Name: "legacy_1", Key: []IndexKeyField{{Field: "legacy", Order: "1"}}},
+		},
+		Findings: []DiagnosticFinding{
+			{Code: "index.unused_candidate", Evidence: map[string]any{"indexName": "tenant_1_createdAt_1"}},
+			{Code: "index.unused_candidate", Evidence: map[string]any{"indexName": "legacy_1"}},
+		},
+	}}}
+	plan, err := BuildIndexRemediationPlan(result, IndexRemediationOptions{MaxWireVersion: 17})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Steps) != 2 {
+		t.Fatalf("steps = %#v", plan.Steps)
+	}
+	for _, step := range plan.Steps {
+		if step.IndexName != "legacy_1" {
+			t.Fatalf("shard key index step = %#v", step)
+		}
+	}
+}
+
+func TestBuildIndexRemediationPlanWithoutHideSupportRequiresReview(t *testing.T) {
+	// 场景：4.4 以下无法隐藏索引，只输出需要人工复核的 dropIndexes，且不伪造观察期。
+	result := &IndexAuditResult{Collections: []CollectionIndexAudit{{
+		Namespace: "app.users",
+		Indexes:   []IndexObservation{{Name: "email_1", Key: []IndexKeyField{{Field: "email", Order: "1"}}, Unique: true, Partial: true}},
+		Findings:  []DiagnosticFinding{{Code: "index.unused_candidate", Evidence: map[string]any{"indexName": "email_1"}}},
+	}}}
+	plan, err := BuildIndexRemediationPlan(result, IndexRemediationOptions{MaxWireVersion: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Steps) != 1 {
+		t.Fatalf("steps = %#v", plan.Steps)
+	}
+	drop := plan.Steps[0]
+	if drop.Phase != IndexRemediationDrop || drop.NotBefore != nil || !drop.RequiresReview || len(drop.Notes) < 3 {
+		t.Fatalf("drop = %#v", drop)
+	}
+	if _, err := BuildIndexRemediationPlan(result, IndexRemediationOptions{HidePeriod: -time.Hour}); err == nil {
+		t.Fatal("negative hide period was accepted")
+	}
+	if _, err := BuildIndexRemediationPlan(nil, IndexRemediationOptions{}); err == nil {
+		t.Fatal("nil result was accepted")
+	}
+}
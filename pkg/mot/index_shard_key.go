@@ -0,0 +1,271 @@
+package mot
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+const (
+	// shardKeyCardinalitySampleSize 是 hashed 分片键字段基数抽样的文档数；抽样不足该数量的小集合不做判断。
+	shardKeyCardinalitySampleSize = 1000
+	// shardKeyLowCardinalityDistinct 是抽样中去重值少于该数量即视为低基数的阈值。
+	shardKeyLowCardinalityDistinct = 100
+)
+
+// indexShardKeySource 在一致性采集源之上增加字段基数抽样，测试可替换为 fake。
+type indexShardKeySource interface {
+	indexConsistencySource
+	SampleCardinality(context.Context, indexCollectionRef, string) (pkgmongo.FieldCardinalitySample, error)
+}
+
+// collectIndexShardKeyAudit 检查分片集合的分片键索引。一致性检查已采集的路由与各 shard 定义直接复用，
+// 缺失时再按 $indexStats、direct listIndexes 的顺序补采；未分片集合不产生结果。
+func collectIndexShardKeyAudit(ctx context.Context, refs []indexCollectionRef, opts IndexAuditOptions, source indexShardKeySource, collected map[string]CollectionIndexAudit) ([]CollectionIndexAudit, []CollectorStatus, []error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultOverviewNodeConcurrency
+	}
+	var pending []indexCollectionRef
+	for _, ref := range refs {
+		if ref.Type == "collection" && collected[ref.Database+"."+ref.Collection].routing == nil {
+			pending = append(pending, ref)
+		}
+	}
+	routings := collectIndexRoutingOutcomes(ctx, pending, concurrency, source)
+	for _, ref := range refs {
+		namespace := ref.Database + "." + ref.Collection
+		if routing := collected[namespace].routing; ref.Type == "collection" && routing != nil {
+			routings[namespace] = indexRoutingOutcome{snapshot: *routing}
+		}
+	}
+
+	var targets map[string]indexShardTarget
+	var targetErr error
+	var targetsOnce sync.Once
+	loadTargets := func() (map[string]indexShardTarget, error) {
+		targetsOnce.Do(func() { targets, targetErr = source.Shards(ctx) })
+		return targets, targetErr
+	}
+
+	limit := semaphore.NewWeighted(int64(concurrency))
+	group, groupCtx := errgroup.WithContext(ctx)
+	var mu sync.Mutex
+	var collections []CollectionIndexAudit
+	var statuses []CollectorStatus
+	var collectorErrors []error
+	for _, ref := range refs {
+		outcome, exists := routings[ref.Database+"."+ref.Collection]
+		if !exists || (outcome.err == nil && !outcome.snapshot.Sharded) {
+			continue
+		}
+		if acquireErr := acquireDiagnosticSlot(groupCtx, limit); acquireErr != nil {
+			mu.Lock()
+			collectorErrors = append(collectorErrors, acquireErr)
+			mu.Unlock()
+			break
+		}
+		ref := ref
+		collection := collected[ref.Database+"."+ref.Collection]
+		group.Go(func() error {
+			defer limit.Release(1)
+			collection, itemStatuses, itemErrors := auditIndexShardKeyCollection(groupCtx, ref, outcome, collection, loadTargets, source)
+			mu.Lock()
+			collections = append(collections, collection)
+			statuses = append(statuses, itemStatuses...)
+			collectorErrors = append(collectorErrors, itemErrors...)
+			mu.Unlock()
+			return nil
+		})
+	}
+	_ = group.Wait()
+	sort.SliceStable(collections, func(i, j int) bool { return collections[i].Namespace < collections[j].Namespace })
+	sortCollectorStatuses(statuses)
+	return collections, statuses, collectorErrors
+}
+
+func auditIndexShardKeyCollection(
+	ctx context.Context,
+	ref indexCollectionRef,
+	outcome indexRoutingOutcome,
+	collection CollectionIndexAudit,
+	loadTargets func() (map[string]indexShardTarget, error),
+	source indexShardKeySource,
+) (CollectionIndexAudit, []CollectorStatus, []error) {
+	namespace := ref.Database + "." + ref.Collection
+	scope := FindingScope{Type: ScopeNamespace, Database: ref.Database, Namespace: namespace}
+	if collection.Namespace == "" {
+		collection = CollectionIndexAudit{Namespace: namespace, Indexes: []IndexObservation{}, Findings: []DiagnosticFinding{}}
+	}
+	if outcome.err != nil {
+		return collection, []CollectorStatus{failedCollectorStatus("index_shard_key", scope, outcome.err)}, consistencyCollectorErrors(outcome.err)
+	}
+	routing := outcome.snapshot
+	collection.routing = &routing
+	collection.Sharded = true
+	collection.ExpectedShards = append([]string(nil), routing.ExpectedShards...)
+	collection.ShardKey = indexKeyFromRouting(routing)
+
+	definitions, collectErr := shardKeyDefinitions(ctx, ref, routing.ExpectedShards, collection.shardDefinitions, loadTargets, source)
+	var statuses []CollectorStatus
+	var collectorErrors []error
+	if collectErr != nil {
+		statuses = append(statuses, failedCollectorStatus("index_shard_key", scope, collectErr))
+		collectorErrors = append(collectorErrors, consistencyCollectorErrors(collectErr)...)
+	} else {
+		statuses = append(statuses, CollectorStatus{Name: "index_shard_key", State: CapabilitySupported, Scope: scope, ReasonCode: "complete"})
+	}
+	findings := evaluateShardKeyIndexes(namespace, ref.Database, collection.ShardKey, routing.ExpectedShards, definitions)
+
+	if field, hashed := hashedShardKeyField(collection.ShardKey); hashed {
+		sample, sampleErr := source.SampleCardinality(ctx, ref, field)
+		if sampleErr != nil {
+			statuses = append(statuses, failedCollectorStatus("index_shard_key_cardinality", scope, sampleErr))
+			collectorErrors = append(collectorErrors, consistencyCollectorErrors(sampleErr)...)
+		} else {
+			statuses = append(statuses, CollectorStatus{Name: "index_shard_key_cardinality", State: CapabilitySupported, Scope: scope, ReasonCode: "complete"})
+			findings = append(findings, hashedShardKeyCardinalityFindings(namespace, ref.Database, field, sample)...)
+		}
+	}
+	collection.Findings = append(collection.Findings, findings...)
+	sanitizeAndSortFindings(collection.Findings)
+	return collection, statuses, collectorErrors
+}
+
+// shardKeyDefinitions 返回各 expected shard 的索引定义；已复用的定义覆盖全部 shard 时不再发起采集。
+// $indexStats 在 4.2.4 以下缺少 shard 字段，此时与其他失败一样退回 direct listIndexes。
+func shardKeyDefinitions(
+	ctx context.Context,
+	ref indexCollectionRef,
+	expected []string,
+	reused map[string][]pkgmongo.CanonicalIndexDefinition,
+	loadTargets func() (map[string]indexShardTarget, error),
+	source indexShardKeySource,
+) (map[string][]pkgmongo.CanonicalIndexDefinition, error) {
+	if len(expected) > 0 && len(observedExpectedShards(expected, reused)) == len(expected) {
+		return reused, nil
+	}
+	visibility, visibilityErr := source.Visibility(ctx, ref)
+	if visibilityErr != nil {
+		return nil, visibilityErr
+	}
+	stats, statsErr := source.Stats(ctx, ref)
+	observations := definitionsByShard(stats)
+	if statsErr != nil || len(observedExpectedShards(expected, observations)) != len(expected) {
+		targets, err := loadTargets()
+		if err != nil {
+			return nil, err
+		}
+		var directErrors []error
+		observations, directErrors = collectDirectDefinitions(ctx, ref, expected, targets, source, nil)
+		if len(directErrors) > 0 {
+			markBuildingDefinitions(observations, visibility.IndexBuilds)
+			return observations, errors.Join(directErrors...)
+		}
+	}
+	markBuildingDefinitions(observations, visibility.IndexBuilds)
+	return observations, nil
+}
+
+// evaluateShardKeyIndexes 按 shard 判断是否存在可支撑分片键的索引（以分片键为前缀，且非 partial、sparse 或非 simple collation），
+// 以及支撑索引是否全部隐藏或仍在构建；唯一索引不以分片键为前缀时只能在单个 shard 内保证唯一。未读取到定义的 shard 不参与判断。
+func evaluateShardKeyIndexes(namespace, database string, shardKey []IndexKeyField, expected []string, definitions map[string][]pkgmongo.CanonicalIndexDefinition) []DiagnosticFinding {
+	if len(shardKey) == 0 {
+		return nil
+	}
+	var missing, hidden, building []string
+	uniqueShards := make(map[string][]string)
+	uniqueKeys := make(map[string][]IndexKeyField)
+	for _, shard := range observedExpectedShards(expected, definitions) {
+		supported, visible, ready := false, false, false
+		for _, definition := range definitions[shard] {
+			key := publicIndexKey(definition.Key)
+			if indexKeyHasPrefix(key, shardKey) {
+				if definition.Partial || definition.Sparse || definition.Collated {
+					continue
+				}
+				supported = true
+				visible = visible || !definition.Hidden
+				ready = ready || (!definition.Hidden && !definition.Building)
+				continue
+			}
+			if definition.Unique && definition.Name != "_id_" {
+				uniqueShards[definition.Name] = append(uniqueShards[definition.Name], shard)
+				uniqueKeys[definition.Name] = key
+			}
+		}
+		switch {
+		case !supported:
+			missing = append(missing, shard)
+		case !visible:
+			hidden = append(hidden, shard)
+		case !ready:
+			building = append(building, shard)
+		}
+	}
+	scope := FindingScope{Type: ScopeNamespace, Database: database, Namespace: namespace}
+	var findings []DiagnosticFinding
+	if len(missing) > 0 {
+		findings = append(findings, DiagnosticFinding{
+			Code: "index.shard_key_index_missing", Severity: SeverityCritical, Scope: scope,
+			Summary:        "部分 shard 上没有可支撑分片键的索引",
+			Evidence:       map[string]any{"shardKey": shardKey, "shards": missing},
+			Recommendation: "在缺失的 shard 上重建以分片键为前缀的索引；在恢复前 chunk 拆分与迁移会失败",
+		})
+	}
+	if len(hidden) > 0 {
+		findings = append(findings, DiagnosticFinding{
+			Code: "index.shard_key_index_hidden", Severity: SeverityWarning, Scope: scope,
+			Summary:        "可支撑分片键的索引在部分 shard 上全部处于隐藏状态",
+			Evidence:       map[string]any{"shardKey": shardKey, "shards": hidden},
+			Recommendation: "取消隐藏分片键索引，避免 chunk 迁移与按分片键路由的查询退化",
+		})
+	}
+	if len(building) > 0 {
+		findings = append(findings, DiagnosticFinding{
+			Code: "index.shard_key_index_building", Severity: SeverityInfo, Scope: scope,
+			Summary:        "可支撑分片键的索引在部分 shard 上仍在构建",
+			Evidence:       map[string]any{"shardKey": shardKey, "shards": building},
+			Recommendation: "等待索引构建完成后再执行 chunk 迁移或 balancer 相关操作",
+		})
+	}
+	for _, name := range sortedUnionKeys(uniqueShards, nil) {
+		findings = append(findings, DiagnosticFinding{
+			Code: "index.unique_without_shard_key_prefix", Severity: SeverityWarning, Scope: scope,
+			Summary:        "唯一索引不以分片键为前缀，只能在单个 shard 内保证唯一",
+			Evidence:       map[string]any{"indexName": name, "key": uniqueKeys[name], "shardKey": shardKey, "shards": uniqueShards[name]},
+			Recommendation: "改为以分片键为前缀的唯一索引，或在应用层保证全局唯一",
+		})
+	}
+	return findings
+}
+
+// hashedShardKeyField 返回分片键中的 hashed 字段；一个分片键最多包含一个 hashed 字段。
+func hashedShardKeyField(shardKey []IndexKeyField) (string, bool) {
+	for _, field := range shardKey {
+		if field.Order == "hashed" {
+			return field.Field, true
+		}
+	}
+	return "", false
+}
+
+// hashedShardKeyCardinalityFindings 只在抽样达到上限时判断基数，小集合的抽样不足以说明字段的取值分布。
+func hashedShardKeyCardinalityFindings(namespace, database, field string, sample pkgmongo.FieldCardinalitySample) []DiagnosticFinding {
+	if sample.Sampled < shardKeyCardinalitySampleSize || sample.Distinct >= shardKeyLowCardinalityDistinct {
+		return nil
+	}
+	return []DiagnosticFinding{{
+		Code: "index.hashed_shard_key_low_cardinality", Severity: SeverityWarning,
+		Scope:          FindingScope{Type: ScopeNamespace, Database: database, Namespace: namespace},
+		Summary:        "hashed 分片键字段基数过低，相同取值的文档只能落在同一个 chunk",
+		Evidence:       map[string]any{"field": field, "sampledDocuments": sample.Sampled, "distinctValues": sample.Distinct},
+		Recommendation: "选择基数更高的字段作为分片键，或使用包含高基数字段的复合分片键，避免出现 jumbo chunk",
+	}}
+}
@@ -0,0 +1,163 @@
+package mot
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+type fakeIndexShardKeySource struct {
+	*fakeIndexConsistencySource
+	samples     map[string]pkgmongo.FieldCardinalitySample
+	sampleCalls []string
+}
+
+func (f *fakeIndexShardKeySource) SampleCardinality(_ context.Context, ref indexCollectionRef, field string) (pkgmongo.FieldCardinalitySample, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := ref.Database + "." + ref.Collection + ":" + field
+	f.sampleCalls = append(f.sampleCalls, key)
+	return f.samples[key], nil
+}
+
+func shardKeyDefinition(name string, key ...string) pkgmongo.CanonicalIndexDefinition {
+	definition := pkgmongo.CanonicalIndexDefinition{Name: name, SemanticFingerprint: name, FieldFingerprints: map[string]string{"key": name}}
+	for i := 0; i+1 < len(key); i += 2 {
+		definition.Key = append(definition.Key, pkgmongo.IndexKeySnapshot{Field: key[i], Order: key[i+1]})
+	}
+	return definition
+}
+
+func TestEvaluateShardKeyIndexesPerShard(t *testing.T) {
+	// 场景：按 shard 判断分片键索引缺失、全部隐藏或仍在构建；partial 前缀索引不能支撑分片键，不以分片键为前缀的唯一索引单独报告。
+	shardKey := []IndexKeyField{{Field: "tenant", Order: "1"}}
+	supporting := shardKeyDefinition("tenant_1_createdAt_1", "tenant", "1", "createdAt", "1")
+	partial := shardKeyDefinition("tenant_1", "tenant", "1")
+	partial.Partial = true
+	hidden := supporting
+	hidden.Hidden = true
+	building := supporting
+	building.Building = true
+	email := shardKeyDefinition("email_1", "email", "1")
+	email.Unique = true
+	id := shardKeyDefinition("_id_", "_id", "1")
+	id.Unique = true
+	findings := evaluateShardKeyIndexes("app.orders", "app", shardKey, []string{"shard-a", "shard-b", "shard-c", "shard-d", "shard-e"}, map[string][]pkgmongo.CanonicalIndexDefinition{
+		"shard-a": {id, supporting, email},
+		"shard-b": {id, partial},
+		"shard-c": {id, hidden},
+		"shard-d": {id, building, email},
+	})
+	byCode := make(map[string]DiagnosticFinding, len(findings))
+	for _, finding := range findings {
+		byCode[finding.Code] = finding
+	}
+	if len(findings) != 4 {
+		t.Fatalf("findings = %#v", findings)
+	}
+	if missing := byCode["index.shard_key_index_missing"]; missing.Severity != SeverityCritical || len(missing.Evidence["shards"].([]string)) != 1 || missing.Evidence["shards"].([]string)[0] != "shard-b" {
+		t.Fatalf("missing finding = %#v", missing)
+	}
+	if got := byCode["index.shard_key_index_hidden"].Evidence["shards"].([]string); len(got) != 1 || got[0] != "shard-c" {
+		t.Fatalf("hidden shards = %v", got)
+	}
+	if got := byCode["index.shard_key_index_building"].Evidence["shards"].([]string); len(got) != 1 || got[0] != "shard-d" {
+		t.Fatalf("building shards = %v", got)
+	}
+	if unique := byCode["index.unique_without_shard_key_prefix"]; unique.Evidence["indexName"] != "email_1" || len(unique.Evidence["shards"].([]string)) != 2 {
+		t.Fatalf("unique finding = %#v", unique)
+	}
+
+	compoundUnique := shardKeyDefinition("tenant_1_email_1", "tenant", "1", "email", "1")
+	compoundUnique.Unique = true
+	if got := evaluateShardKeyIndexes("app.orders", "app", shardKey, []string{"shard-a"}, map[string][]pkgmongo.CanonicalIndexDefinition{"shard-a": {compoundUnique}}); len(got) != 0 {
+		t.Fatalf("prefixed unique index findings = %#v", got)
+	}
+}
+
+func TestHashedShardKeyCardinalityNeedsFullSample(t *testing.T) {
+	// 场景：只有抽样达到上限且去重值低于阈值时才报告低基数，小集合抽样不足不下结论。
+	if field, hashed := hashedShardKeyField([]IndexKeyField{{Field: "region", Order: "1"}, {Field: "userId", Order: "hashed"}}); !hashed || field != "userId" {
+		t.Fatalf("hashed field = %q, %t", field, hashed)
+	}
+	low := hashedShardKeyCardinalityFindings("app.events", "app", "status", pkgmongo.FieldCardinalitySample{Sampled: shardKeyCardinalitySampleSize, Distinct: 3})
+	if len(low) != 1 || low[0].Code != "index.hashed_shard_key_low_cardinality" || low[0].Evidence["distinctValues"] != int64(3) {
+		t.Fatalf("low cardinality findings = %#v", low)
+	}
+	if got := hashedShardKeyCardinalityFindings("app.events", "app", "status", pkgmongo.FieldCardinalitySample{Sampled: 40, Distinct: 3}); len(got) != 0 {
+		t.Fatalf("small sample findings = %#v", got)
+	}
+	if got := hashedShardKeyCardinalityFindings("app.events", "app", "userId", pkgmongo.FieldCardinalitySample{Sampled: shardKeyCardinalitySampleSize, Distinct: 990}); len(got) != 0 {
+		t.Fatalf("high cardinality findings = %#v", got)
+	}
+}
+
+func TestCollectIndexShardKeyAuditReusesConsistencyDefinitions(t *testing.T) {
+	// 场景：一致性检查已采集的路由与各 shard 定义直接复用，不再读取路由或索引；未复用的 hashed 分片集合补采定义并抽样基数，未分片集合不产生结果。
+	consistency := completeFakeConsistencySource("4.0.28")
+	consistency.routing["app.events"] = pkgmongo.IndexRoutingSnapshot{
+		Namespace: "app.events", Sharded: true, ExpectedShards: []string{"shard-a", "shard-b"}, ShardKey: bson.D{{Key: "status", Value: "hashed"}},
+	}
+	consistency.routing["app.local"] = pkgmongo.IndexRoutingSnapshot{Namespace: "app.local"}
+	consistency.direct["app.events:shard-a"] = []pkgmongo.CanonicalIndexDefinition{shardKeyDefinition("status_hashed", "status", "hashed")}
+	consistency.direct["app.events:shard-b"] = []pkgmongo.CanonicalIndexDefinition{shardKeyDefinition("status_1", "status", "1")}
+	source := &fakeIndexShardKeySource{
+		fakeIndexConsistencySource: consistency,
+		samples:                    map[string]pkgmongo.FieldCardinalitySample{"app.events:status": {Sampled: shardKeyCardinalitySampleSize, Distinct: 4}},
+	}
+	ordersRouting := pkgmongo.IndexRoutingSnapshot{Namespace: "app.orders", Sharded: true, ExpectedShards: []string{"shard-a", "shard-b"}, ShardKey: bson.D{{Key: "tenant", Value: int32(1)}}}
+	collected := map[string]CollectionIndexAudit{
+		"app.orders": {
+			Namespace: "app.orders", Sharded: true, State: IndexConsistencyConsistent, routing: &ordersRouting,
+			shardDefinitions: map[string][]pkgmongo.CanonicalIndexDefinition{
+				"shard-a": {shardKeyDefinition("tenant_1", "tenant", "1")},
+				"shard-b": {shardKeyDefinition("tenant_1", "tenant", "1")},
+			},
+		},
+	}
+	refs := []indexCollectionRef{
+		{Database: "app", Collection: "events", Type: "collection"},
+		{Database: "app", Collection: "local", Type: "collection"},
+		{Database: "app", Collection: "orders", Type: "collection"},
+	}
+
+	collections, statuses, collectorErrors := collectIndexShardKeyAudit(context.Background(), refs, IndexAuditOptions{Concurrency: 2}, source, collected)
+	if len(collectorErrors) != 0 || len(collections) != 2 {
+		t.Fatalf("collections=%#v errors=%v", collections, collectorErrors)
+	}
+	byNamespace := indexCollectionsByNamespace(collections)
+	orders := byNamespace["app.orders"]
+	if orders.State != IndexConsistencyConsistent || len(orders.ShardKey) != 1 || orders.ShardKey[0].Order != "1" || len(orders.Findings) != 0 {
+		t.Fatalf("orders = %#v", orders)
+	}
+	events := byNamespace["app.events"]
+	if !hasFindingCode(events.Findings, "index.shard_key_index_missing") || !hasFindingCode(events.Findings, "index.hashed_shard_key_low_cardinality") {
+		t.Fatalf("events findings = %#v", events.Findings)
+	}
+	if source.routingCalls != 2 || len(source.directCalls) != 2 || len(source.statsCalls) != 1 || len(source.sampleCalls) != 1 {
+		t.Fatalf("routing=%d direct=%v stats=%v samples=%v", source.routingCalls, source.directCalls, source.statsCalls, source.sampleCalls)
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("statuses = %#v", statuses)
+	}
+}
+
+func TestIndexShardKeyCheckRequiresMongos(t *testing.T) {
+	// 场景：shardkey 需显式指定且要求 mongos，不触发通用使用率采集。
+	if includesGeneralIndexCheck([]IndexAuditCheck{IndexCheckShardKey}) {
+		t.Fatal("shardkey treated as general index check")
+	}
+	opts, err := normalizeIndexAuditOptions(IndexAuditOptions{AllDatabases: true, Checks: []IndexAuditCheck{IndexCheckShardKey}})
+	if err != nil || len(opts.Checks) != 1 {
+		t.Fatalf("opts=%#v err=%v", opts, err)
+	}
+	if err := validateIndexShardKeyTopology(pkgmongo.ClusterRepl, opts.Checks); err == nil {
+		t.Fatal("shardkey accepted a replica set connection")
+	}
+	if err := validateIndexShardKeyTopology(pkgmongo.ClusterShard, opts.Checks); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,437 @@
+package mot
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+const indexSnapshotSchemaVersion = 1
+
+// indexDiffIgnoredFields 不参与跨集群比较：name 单独比较；key 按规范化后的字段与方向比较；
+// v 由服务端版本决定，background 只影响构建方式，二者都不代表业务意图。3.x 的 ns 在生成快照时已剔除。
+var indexDiffIgnoredFields = []string{"name", "key", "v", "background"}
+
+// IndexSnapshotOptions 选择写入索引快照的范围，语义与 IndexAuditOptions 相同。
+type IndexSnapshotOptions struct {
+	Databases       []string
+	AllDatabases    bool
+	Collections     []string
+	IncludeSystemDB bool
+	MaxCollections  int
+}
+
+// IndexSnapshot 是可离线比较的索引定义快照；只保存键模式与各选项的 fingerprint，不含原始 partialFilterExpression 等定义。
+type IndexSnapshot struct {
+	SchemaVersion     int                       `json:"schemaVersion"`
+	CollectedAt       time.Time                 `json:"collectedAt"`
+	ClusterType       ClusterType               `json:"clusterType"`
+	Collections       []IndexSnapshotCollection `json:"collections"`
+	Incomplete        []string                  `json:"incomplete,omitempty"`
+	CollectorStatuses []CollectorStatus         `json:"collectorStatuses,omitempty"`
+}
+
+type IndexSnapshotCollection struct {
+	Namespace string                    `json:"namespace"`
+	Indexes   []IndexSnapshotDefinition `json:"indexes"`
+}
+
+// IndexSnapshotDefinition 对应 pkg/mongo.CanonicalIndexDefinition 的可公开部分。
+type IndexSnapshotDefinition struct {
+	Name                string            `json:"name"`
+	Key                 []IndexKeyField   `json:"key"`
+	SemanticFingerprint string            `json:"semanticFingerprint"`
+	FullFingerprint     string            `json:"fullFingerprint"`
+	FieldFingerprints   map[string]string `json:"fieldFingerprints"`
+	Building            bool              `json:"building,omitempty"`
+}
+
+// NamespaceRemap 把源快照的 namespace 映射到目标快照；From 与 To 可各含一个 *，匹配部分原样代入 To。
+type NamespaceRemap struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// IndexDiffOptions 设置离线比较时对源快照应用的 namespace 映射，按顺序取第一条匹配的规则。
+type IndexDiffOptions struct {
+	Remaps []NamespaceRemap
+}
+
+// IndexDiffResult 是两个索引快照的比较结果；Source 为应用映射的一侧。
+type IndexDiffResult struct {
+	SchemaVersion     int                 `json:"schemaVersion"`
+	SourceClusterType ClusterType         `json:"sourceClusterType"`
+	TargetClusterType ClusterType         `json:"targetClusterType"`
+	SourceCollectedAt time.Time           `json:"sourceCollectedAt"`
+	TargetCollectedAt time.Time           `json:"targetCollectedAt"`
+	Namespaces        int                 `json:"namespaces"`
+	Identical         int                 `json:"identical"`
+	Skipped           []string            `json:"skipped,omitempty"`
+	Items             []IndexDiffItem     `json:"items"`
+	Findings          []DiagnosticFinding `json:"findings,omitempty"`
+}
+
+// IndexDiffItem 的 State 为 namespace_only_in_source、namespace_only_in_target、only_in_source、only_in_target、
+// name_mismatch、key_mismatch 或 options_mismatch。
+type IndexDiffItem struct {
+	Namespace       string          `json:"namespace"`
+	SourceNamespace string          `json:"sourceNamespace,omitempty"`
+	State           string          `json:"state"`
+	IndexName       string          `json:"indexName,omitempty"`
+	TargetIndexName string          `json:"targetIndexName,omitempty"`
+	Key             []IndexKeyField `json:"key,omitempty"`
+	TargetKey       []IndexKeyField `json:"targetKey,omitempty"`
+	DifferingFields []string        `json:"differingFields,omitempty"`
+}
+
+// IndexSnapshot 通过当前连接读取所选集合的索引定义并计算 canonical fingerprint，供离线 DiffIndexSnapshots 使用。
+func (c *Client) IndexSnapshot(ctx context.Context, opts IndexSnapshotOptions) (result *IndexSnapshot, err error) {
+	if c != nil && c.session == nil {
+		return withEphemeralCollectorSession(ctx, c, func(session *CollectorSession) (*IndexSnapshot, error) {
+			return session.IndexSnapshot(ctx, opts)
+		})
+	}
+	auditOpts, err := normalizeIndexAuditOptions(IndexAuditOptions{
+		Databases: opts.Databases, AllDatabases: opts.AllDatabases, Collections: opts.Collections,
+		IncludeSystemDB: opts.IncludeSystemDB, MaxCollections: opts.MaxCollections,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.requireConn(); err != nil {
+		return nil, err
+	}
+	defer func() { err = mapContextError(err) }()
+	cluster, err := c.detectCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result = &IndexSnapshot{SchemaVersion: indexSnapshotSchemaVersion, CollectedAt: time.Now().UTC(), ClusterType: convertClusterType(cluster.Type), Collections: []IndexSnapshotCollection{}}
+	if gate, allowed := diagnosticCapabilityGate("index_snapshot", result.ClusterType, cluster.MaxWireVersion, true); !allowed {
+		result.CollectorStatuses = []CollectorStatus{gate}
+		return result, nil
+	}
+	refs, err := c.indexCollectionRefs(ctx, auditOpts)
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) == 0 {
+		return nil, invalidOptions("no collections selected")
+	}
+	if len(refs) > auditOpts.MaxCollections {
+		return nil, collectionLimitExceeded(auditOpts.MaxCollections, len(refs))
+	}
+
+	var collectorErrors []error
+	for _, ref := range refs {
+		if ref.Type != "collection" {
+			continue
+		}
+		namespace := ref.Database + "." + ref.Collection
+		scope := FindingScope{Type: ScopeNamespace, Database: ref.Database, Namespace: namespace}
+		definitions, listErr := c.conn.ListIndexDefinitions(ctx, ref.Database, ref.Collection, indexConsistencyCollectorTimeout)
+		if isNamespaceNotFoundError(listErr) {
+			continue
+		}
+		if listErr != nil {
+			if ctxErr := contextError(ctx); ctxErr != nil {
+				return nil, ctxErr
+			}
+			result.Incomplete = append(result.Incomplete, namespace)
+			result.CollectorStatuses = append(result.CollectorStatuses, failedCollectorStatus("index_snapshot", scope, listErr))
+			if !isUnauthorizedError(listErr) {
+				collectorErrors = append(collectorErrors, listErr)
+			}
+			continue
+		}
+		result.Collections = append(result.Collections, IndexSnapshotCollection{Namespace: namespace, Indexes: indexSnapshotDefinitions(definitions)})
+	}
+	sort.SliceStable(result.Collections, func(i, j int) bool { return result.Collections[i].Namespace < result.Collections[j].Namespace })
+	sort.Strings(result.Incomplete)
+	sortCollectorStatuses(result.CollectorStatuses)
+	if len(collectorErrors) > 0 {
+		return result, newDiagnosticPartialError("index-snapshot", result, errors.Join(collectorErrors...))
+	}
+	return result, nil
+}
+
+func indexSnapshotDefinitions(definitions []pkgmongo.CanonicalIndexDefinition) []IndexSnapshotDefinition {
+	result := make([]IndexSnapshotDefinition, 0, len(definitions))
+	for _, definition := range definitions {
+		fields := make(map[string]string, len(definition.FieldFingerprints))
+		for field, fingerprint := range definition.FieldFingerprints {
+			fields[field] = fingerprint
+		}
+		result = append(result, IndexSnapshotDefinition{
+			Name: definition.Name, Key: publicIndexKey(definition.Key), SemanticFingerprint: definition.SemanticFingerprint,
+			FullFingerprint: definition.FullFingerprint, FieldFingerprints: fields, Building: definition.Building,
+		})
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// ParseNamespaceRemap 解析 "app_staging.* -> app.*" 形式的映射规则。
+func ParseNamespaceRemap(value string) (NamespaceRemap, error) {
+	from, to, ok := strings.Cut(value, "->")
+	remap := NamespaceRemap{From: strings.TrimSpace(from), To: strings.TrimSpace(to)}
+	if !ok || remap.From == "" || remap.To == "" {
+		return NamespaceRemap{}, invalidOptions("namespace remap %q must look like <from> -> <to>", value)
+	}
+	if err := validateNamespaceRemap(remap); err != nil {
+		return NamespaceRemap{}, err
+	}
+	return remap, nil
+}
+
+func validateNamespaceRemap(remap NamespaceRemap) error {
+	fromWildcards, toWildcards := strings.Count(remap.From, "*"), strings.Count(remap.To, "*")
+	if fromWildcards > 1 || toWildcards > fromWildcards {
+		return invalidOptions("namespace remap %s -> %s supports at most one * and the target may only use * when the source does", remap.From, remap.To)
+	}
+	return nil
+}
+
+// apply 返回映射后的 namespace；未匹配时 ok 为 false。
+func (r NamespaceRemap) apply(namespace string) (string, bool) {
+	prefix, suffix, wildcard := strings.Cut(r.From, "*")
+	if !wildcard {
+		return r.To, namespace == r.From
+	}
+	if len(namespace) < len(prefix)+len(suffix) || !strings.HasPrefix(namespace, prefix) || !strings.HasSuffix(namespace, suffix) {
+		return "", false
+	}
+	matched := namespace[len(prefix) : len(namespace)-len(suffix)]
+	return strings.Replace(r.To, "*", matched, 1), true
+}
+
+// DiffIndexSnapshots 离线比较两个索引快照：source 先按映射规则改写 namespace，再逐个 namespace 比较索引名、键模式与选项。
+// 任一侧未能完整读取的 namespace 只列入 Skipped，不会被报告为缺失。不连接 MongoDB。
+func DiffIndexSnapshots(source, target IndexSnapshot, opts IndexDiffOptions) (*IndexDiffResult, error) {
+	if source.SchemaVersion != indexSnapshotSchemaVersion || target.SchemaVersion != indexSnapshotSchemaVersion {
+		return nil, invalidOptions("unsupported index snapshot schema version")
+	}
+	for _, remap := range opts.Remaps {
+		if err := validateNamespaceRemap(remap); err != nil {
+			return nil, err
+		}
+	}
+	sourceCollections, sourceNames, err := remappedSnapshotCollections(source, opts.Remaps)
+	if err != nil {
+		return nil, err
+	}
+	targetCollections := make(map[string]IndexSnapshotCollection, len(target.Collections))
+	for _, collection := range target.Collections {
+		targetCollections[collection.Namespace] = collection
+	}
+	skipped := make(map[string]struct{})
+	for _, namespace := range source.Incomplete {
+		mapped, _ := remapNamespace(namespace, opts.Remaps)
+		skipped[mapped] = struct{}{}
+	}
+	for _, namespace := range target.Incomplete {
+		skipped[namespace] = struct{}{}
+	}
+
+	result := &IndexDiffResult{
+		SchemaVersion: indexSnapshotSchemaVersion, SourceClusterType: source.ClusterType, TargetClusterType: target.ClusterType,
+		SourceCollectedAt: source.CollectedAt, TargetCollectedAt: target.CollectedAt, Items: []IndexDiffItem{},
+	}
+	for _, namespace := range sortedUnionKeys(sourceCollections, targetCollections) {
+		if _, incomplete := skipped[namespace]; incomplete {
+			result.Skipped = append(result.Skipped, namespace)
+			continue
+		}
+		result.Namespaces++
+		sourceCollection, inSource := sourceCollections[namespace]
+		targetCollection, inTarget := targetCollections[namespace]
+		sourceNamespace := sourceNames[namespace]
+		if sourceNamespace == namespace {
+			sourceNamespace = ""
+		}
+		var items []IndexDiffItem
+		switch {
+		case !inTarget:
+			items = []IndexDiffItem{{Namespace: namespace, State: "namespace_only_in_source"}}
+		case !inSource:
+			items = []IndexDiffItem{{Namespace: namespace, State: "namespace_only_in_target"}}
+		default:
+			items = diffIndexSnapshotCollection(namespace, sourceCollection.Indexes, targetCollection.Indexes)
+		}
+		if len(items) == 0 {
+			result.Identical++
+			continue
+		}
+		for i := range items {
+			items[i].SourceNamespace = sourceNamespace
+		}
+		result.Items = append(result.Items, items...)
+	}
+	sort.Strings(result.Skipped)
+	result.Findings = indexDiffFindings(result.Items)
+	sanitizeAndSortFindings(result.Findings)
+	return result, nil
+}
+
+func remappedSnapshotCollections(snapshot IndexSnapshot, remaps []NamespaceRemap) (map[string]IndexSnapshotCollection, map[string]string, error) {
+	collections := make(map[string]IndexSnapshotCollection, len(snapshot.Collections))
+	names := make(map[string]string, len(snapshot.Collections))
+	for _, collection := range snapshot.Collections {
+		namespace, _ := remapNamespace(collection.Namespace, remaps)
+		if previous, exists := names[namespace]; exists {
+			return nil, nil, invalidOptions("namespace remap maps both %s and %s to %s", previous, collection.Namespace, namespace)
+		}
+		names[namespace] = collection.Namespace
+		collections[namespace] = collection
+	}
+	return collections, names, nil
+}
+
+func remapNamespace(namespace string, remaps []NamespaceRemap) (string, bool) {
+	for _, remap := range remaps {
+		if mapped, ok := remap.apply(namespace); ok {
+			return mapped, true
+		}
+	}
+	return namespace, false
+}
+
+// diffIndexSnapshotCollection 先按索引名配对；只存在于一侧的索引若在另一侧有语义相同的定义则视为改名。
+func diffIndexSnapshotCollection(namespace string, source, target []IndexSnapshotDefinition) []IndexDiffItem {
+	sourceByName := indexSnapshotByName(source)
+	targetByName := indexSnapshotByName(target)
+	var items []IndexDiffItem
+	var sourceOnly, targetOnly []IndexSnapshotDefinition
+	for _, name := range sortedUnionKeys(sourceByName, targetByName) {
+		sourceIndex, inSource := sourceByName[name]
+		targetIndex, inTarget := targetByName[name]
+		switch {
+		case !inTarget:
+			sourceOnly = append(sourceOnly, sourceIndex)
+		case !inSource:
+			targetOnly = append(targetOnly, targetIndex)
+		default:
+			if item, differs := compareIndexSnapshotDefinitions(namespace, sourceIndex, targetIndex); differs {
+				items = append(items, item)
+			}
+		}
+	}
+	matchedTarget := make(map[string]struct{})
+	for _, sourceIndex := range sourceOnly {
+		renamed := false
+		for _, targetIndex := range targetOnly {
+			if _, matched := matchedTarget[targetIndex.Name]; matched {
+				continue
+			}
+			if len(indexSnapshotDifferingFields(sourceIndex, targetIndex)) == 0 {
+				matchedTarget[targetIndex.Name] = struct{}{}
+				items = append(items, IndexDiffItem{Namespace: namespace, State: "name_mismatch", IndexName: sourceIndex.Name, TargetIndexName: targetIndex.Name, Key: sourceIndex.Key})
+				renamed = true
+				break
+			}
+		}
+		if !renamed {
+			items = append(items, IndexDiffItem{Namespace: namespace, State: "only_in_source", IndexName: sourceIndex.Name, Key: sourceIndex.Key})
+		}
+	}
+	for _, targetIndex := range targetOnly {
+		if _, matched := matchedTarget[targetIndex.Name]; !matched {
+			items = append(items, IndexDiffItem{Namespace: namespace, State: "only_in_target", IndexName: targetIndex.Name, Key: targetIndex.Key})
+		}
+	}
+	return items
+}
+
+func compareIndexSnapshotDefinitions(namespace string, source, target IndexSnapshotDefinition) (IndexDiffItem, bool) {
+	fields := indexSnapshotDifferingFields(source, target)
+	if len(fields) == 0 {
+		return IndexDiffItem{}, false
+	}
+	state := "options_mismatch"
+	if stringIncluded(fields, "key") {
+		state = "key_mismatch"
+	}
+	return IndexDiffItem{Namespace: namespace, State: state, IndexName: source.Name, Key: source.Key, TargetKey: target.Key, DifferingFields: fields}, true
+}
+
+// indexSnapshotDifferingFields 用规范化的 Key 判断 key 差异，避免 1 与 1.0 这类仅 BSON 数值类型不同的键模式被误报；
+// 其余选项按字段指纹比较。
+func indexSnapshotDifferingFields(source, target IndexSnapshotDefinition) []string {
+	var fields []string
+	if !slices.Equal(source.Key, target.Key) {
+		fields = append(fields, "key")
+	}
+	for _, field := range sortedUnionKeys(source.FieldFingerprints, target.FieldFingerprints) {
+		if stringIncluded(indexDiffIgnoredFields, field) {
+			continue
+		}
+		sourceValue, inSource := source.FieldFingerprints[field]
+		targetValue, inTarget := target.FieldFingerprints[field]
+		if inSource != inTarget || sourceValue != targetValue {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func indexSnapshotByName(definitions []IndexSnapshotDefinition) map[string]IndexSnapshotDefinition {
+	result := make(map[string]IndexSnapshotDefinition, len(definitions))
+	for _, definition := range definitions {
+		result[definition.Name] = definition
+	}
+	return result
+}
+
+func indexDiffFindings(items []IndexDiffItem) []DiagnosticFinding {
+	type findingTemplate struct {
+		code           string
+		severity       Severity
+		summary        string
+		recommendation string
+	}
+	templates := map[string]findingTemplate{
+		"namespace_only_in_source": {"index.diff_namespace_missing", SeverityInfo, "集合只存在于 source 快照", "确认目标环境是否应创建该集合，或补充 namespace 映射规则"},
+		"namespace_only_in_target": {"index.diff_namespace_missing", SeverityInfo, "集合只存在于 target 快照", "确认源环境是否缺少该集合，或补充 namespace 映射规则"},
+		"only_in_source":           {"index.diff_missing_index", SeverityWarning, "索引只存在于 source 快照", "迁移或发布前在目标环境创建该索引，或确认其已被有意删除"},
+		"only_in_target":           {"index.diff_missing_index", SeverityWarning, "索引只存在于 target 快照", "确认源环境是否缺少该索引，或目标环境的索引是否仍被使用"},
+		"name_mismatch":            {"index.diff_name_mismatch", SeverityInfo, "语义相同的索引在两个快照中名称不同", "统一索引名，避免按名称执行的 hint、隐藏与删除在两个环境中行为不同"},
+		"key_mismatch":             {"index.diff_definition_mismatch", SeverityWarning, "同名索引的键模式在两个快照中不同", "按目标环境的查询模式确认应保留的键模式，并重建不一致的一侧"},
+		"options_mismatch":         {"index.diff_definition_mismatch", SeverityWarning, "同名索引的选项在两个快照中不同", "核对 differingFields 中的选项，确认 unique、TTL、partial 等语义在两个环境中一致"},
+	}
+	findings := make([]DiagnosticFinding, 0, len(items))
+	for _, item := range items {
+		template, ok := templates[item.State]
+		if !ok {
+			continue
+		}
+		database, _, _ := strings.Cut(item.Namespace, ".")
+		evidence := map[string]any{"state": item.State}
+		if item.IndexName != "" {
+			evidence["indexName"] = item.IndexName
+		}
+		if item.TargetIndexName != "" {
+			evidence["targetIndexName"] = item.TargetIndexName
+		}
+		if item.SourceNamespace != "" {
+			evidence["sourceNamespace"] = item.SourceNamespace
+		}
+		if len(item.DifferingFields) > 0 {
+			evidence["differingFields"] = item.DifferingFields
+		}
+		findings = append(findings, DiagnosticFinding{
+			Code: template.code, Severity: template.severity,
+			Scope:   FindingScope{Type: ScopeNamespace, Database: database, Namespace: item.Namespace},
+			Summary: template.summary, Evidence: evidence, Recommendation: template.recommendation,
+		})
+	}
+	return findings
+}
@@ -0,0 +1,109 @@
+package mot
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// indexSnapshotTestDefinition 以 "field:order" 描述规范化键模式，fields 中的 key 指纹可与之独立设置。
+func indexSnapshotTestDefinition(name, key string, fields map[string]string) IndexSnapshotDefinition {
+	fields["name"] = "name-" + name
+	field, order, _ := strings.Cut(key, ":")
+	return IndexSnapshotDefinition{Name: name, Key: []IndexKeyField{{Field: field, Order: order}}, FieldFingerprints: fields}
+}
+
+func TestDiffIndexSnapshotsAppliesRemapAndClassifiesDifferences(t *testing.T) {
+	// 场景：source 按映射规则改写 namespace 后逐个比较；改名、键模式与选项差异分别分类，v 不参与比较，未完整读取的 namespace 只列入 skipped。
+	source := IndexSnapshot{SchemaVersion: indexSnapshotSchemaVersion, ClusterType: ClusterReplicaSet, CollectedAt: time.Unix(10, 0), Incomplete: []string{"app_staging.events"}, Collections: []IndexSnapshotCollection{
+		{Namespace: "app_staging.orders", Indexes: []IndexSnapshotDefinition{
+			indexSnapshotTestDefinition("_id_", "_id:1", map[string]string{"key": "k-id", "v": "v1"}),
+			indexSnapshotTestDefinition("tenant_1", "tenant:1", map[string]string{"key": "k-tenant", "unique": "true"}),
+			indexSnapshotTestDefinition("status_1", "status:1", map[string]string{"key": "k-status"}),
+			indexSnapshotTestDefinition("legacy_1", "legacy:1", map[string]string{"key": "k-legacy"}),
+			indexSnapshotTestDefinition("created_1", "created:1", map[string]string{"key": "k-created"}),
+		}},
+		{Namespace: "app_staging.users", Indexes: []IndexSnapshotDefinition{indexSnapshotTestDefinition("_id_", "_id:1", map[string]string{"key": "k-id"})}},
+	}}
+	target := IndexSnapshot{SchemaVersion: indexSnapshotSchemaVersion, ClusterType: ClusterSharded, CollectedAt: time.Unix(20, 0), Collections: []IndexSnapshotCollection{
+		{Namespace: "app.orders", Indexes: []IndexSnapshotDefinition{
+			indexSnapshotTestDefinition("_id_", "_id:1", map[string]string{"key": "k-id", "v": "v2"}),
+			indexSnapshotTestDefinition("tenant_1", "tenant:1", map[string]string{"key": "k-tenant"}),
+			indexSnapshotTestDefinition("status_1", "status:-1", map[string]string{"key": "k-status-desc"}),
+			indexSnapshotTestDefinition("legacy_renamed", "legacy:1", map[string]string{"key": "k-legacy"}),
+		}},
+		{Namespace: "app.users", Indexes: []IndexSnapshotDefinition{indexSnapshotTestDefinition("_id_", "_id:1", map[string]string{"key": "k-id"})}},
+		{Namespace: "app.events", Indexes: []IndexSnapshotDefinition{}},
+		{Namespace: "app.audit", Indexes: []IndexSnapshotDefinition{}},
+	}}
+	remap, err := ParseNamespaceRemap("app_staging.* -> app.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := DiffIndexSnapshots(source, target, IndexDiffOptions{Remaps: []NamespaceRemap{remap}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Namespaces != 3 || result.Identical != 1 || len(result.Skipped) != 1 || result.Skipped[0] != "app.events" {
+		t.Fatalf("result = %#v", result)
+	}
+	states := make(map[string]IndexDiffItem)
+	for _, item := range result.Items {
+		states[item.State] = item
+	}
+	if len(result.Items) != 5 || states["namespace_only_in_target"].Namespace != "app.audit" {
+		t.Fatalf("items = %#v", result.Items)
+	}
+	if item := states["options_mismatch"]; item.IndexName != "tenant_1" || item.SourceNamespace != "app_staging.orders" || len(item.DifferingFields) != 1 || item.DifferingFields[0] != "unique" {
+		t.Fatalf("options mismatch = %#v", item)
+	}
+	if item := states["key_mismatch"]; item.IndexName != "status_1" {
+		t.Fatalf("key mismatch = %#v", item)
+	}
+	if item := states["name_mismatch"]; item.IndexName != "legacy_1" || item.TargetIndexName != "legacy_renamed" {
+		t.Fatalf("name mismatch = %#v", item)
+	}
+	if item := states["only_in_source"]; item.IndexName != "created_1" {
+		t.Fatalf("only in source = %#v", item)
+	}
+	assertFindingCode(t, result.Findings, "index.diff_missing_index", SeverityWarning)
+	assertFindingCode(t, result.Findings, "index.diff_name_mismatch", SeverityInfo)
+}
+
+func TestNamespaceRemapValidation(t *testing.T) {
+	// 场景：映射规则格式错误、通配符不匹配或多个 namespace 映射到同一目标时拒绝比较。
+	for _, value := range []string{"app.*", "app.* ->", "app.*.* -> x.*", "app.orders -> x.*"} {
+		if _, err := ParseNamespaceRemap(value); !errors.Is(err, ErrInvalidOptions) {
+			t.Fatalf("ParseNamespaceRemap(%q) error = %v", value, err)
+		}
+	}
+	if mapped, ok := (NamespaceRemap{From: "app_staging.orders", To: "app.orders"}).apply("app_staging.orders"); !ok || mapped != "app.orders" {
+		t.Fatalf("exact remap = %q, %t", mapped, ok)
+	}
+	if _, ok := (NamespaceRemap{From: "app_staging.*", To: "app.*"}).apply("other.orders"); ok {
+		t.Fatal("remap matched an unrelated namespace")
+	}
+	snapshot := IndexSnapshot{SchemaVersion: indexSnapshotSchemaVersion, Collections: []IndexSnapshotCollection{{Namespace: "a.orders"}, {Namespace: "b.orders"}}}
+	remaps := []NamespaceRemap{{From: "a.*", To: "c.*"}, {From: "b.*", To: "c.*"}}
+	if _, err := DiffIndexSnapshots(snapshot, snapshot, IndexDiffOptions{Remaps: remaps}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("colliding remap error = %v", err)
+	}
+	if _, err := DiffIndexSnapshots(IndexSnapshot{}, snapshot, IndexDiffOptions{}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("schema version error = %v", err)
+	}
+}
+
+func TestIndexSnapshotDifferingFieldsUsesNormalizedKey(t *testing.T) {
+	// 场景：键模式只有 BSON 数值类型不同（1 与 1.0）时 key 指纹不同但规范化 Key 相同，不报 key 差异；v 与 background 不参与比较。
+	source := indexSnapshotTestDefinition("status_1", "status:1", map[string]string{"key": "k-int32", "v": "v1", "background": "true"})
+	target := indexSnapshotTestDefinition("status_1", "status:1", map[string]string{"key": "k-double", "v": "v2"})
+	if fields := indexSnapshotDifferingFields(source, target); len(fields) != 0 {
+		t.Fatalf("differing fields = %v, want none", fields)
+	}
+	target = indexSnapshotTestDefinition("status_1", "status:-1", map[string]string{"key": "k-int32", "unique": "true"})
+	if fields := indexSnapshotDifferingFields(source, target); !reflect.DeepEqual(fields, []string{"key", "unique"}) {
+		t.Fatalf("differing fields = %v, want [key unique]", fields)
+	}
+}
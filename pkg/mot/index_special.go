@@ -0,0 +1,337 @@
+package mot
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+// indexSpecialSource 读取集合的索引定义与分片路由，测试可替换为 fake。
+type indexSpecialSource interface {
+	Definitions(context.Context, indexCollectionRef) ([]pkgmongo.CanonicalIndexDefinition, error)
+	Routing(context.Context, indexCollectionRef) (pkgmongo.IndexRoutingSnapshot, error)
+}
+
+type clientIndexSpecialSource struct {
+	client *Client
+}
+
+func (s clientIndexSpecialSource) Definitions(ctx context.Context, ref indexCollectionRef) ([]pkgmongo.CanonicalIndexDefinition, error) {
+	return clientIndexTTLSource(s).Definitions(ctx, ref)
+}
+
+func (s clientIndexSpecialSource) Routing(ctx context.Context, ref indexCollectionRef) (pkgmongo.IndexRoutingSnapshot, error) {
+	return clientIndexConsistencySource(s).Routing(ctx, ref)
+}
+
+// collectIndexSpecialAudit 检查 text、wildcard、2dsphere 与 hashed 索引之间的重叠；普通前缀冗余仍由 redundant 检查负责。
+// 一致性检查已采集的各 shard 定义与路由直接复用，只返回产生 finding 或读取失败的集合。
+func collectIndexSpecialAudit(ctx context.Context, refs []indexCollectionRef, opts IndexAuditOptions, sharded bool, source indexSpecialSource, collected map[string]CollectionIndexAudit) ([]CollectionIndexAudit, []CollectorStatus, []error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultOverviewNodeConcurrency
+	}
+	limit := semaphore.NewWeighted(int64(concurrency))
+	group, groupCtx := errgroup.WithContext(ctx)
+	var mu sync.Mutex
+	var collections []CollectionIndexAudit
+	var statuses []CollectorStatus
+	var collectorErrors []error
+	for _, ref := range refs {
+		if ref.Type != "collection" {
+			continue
+		}
+		if acquireErr := acquireDiagnosticSlot(groupCtx, limit); acquireErr != nil {
+			mu.Lock()
+			collectorErrors = append(collectorErrors, acquireErr)
+			mu.Unlock()
+			break
+		}
+		ref := ref
+		collection := collected[ref.Database+"."+ref.Collection]
+		group.Go(func() error {
+			defer limit.Release(1)
+			collection, itemStatuses, itemErrors, found := auditIndexSpecialCollection(groupCtx, ref, collection, sharded, source)
+			mu.Lock()
+			if found {
+				collections = append(collections, collection)
+			}
+			statuses = append(statuses, itemStatuses...)
+			collectorErrors = append(collectorErrors, itemErrors...)
+			mu.Unlock()
+			return nil
+		})
+	}
+	_ = group.Wait()
+	sort.SliceStable(collections, func(i, j int) bool { return collections[i].Namespace < collections[j].Namespace })
+	sortCollectorStatuses(statuses)
+	return collections, statuses, collectorErrors
+}
+
+func auditIndexSpecialCollection(ctx context.Context, ref indexCollectionRef, collection CollectionIndexAudit, sharded bool, source indexSpecialSource) (CollectionIndexAudit, []CollectorStatus, []error, bool) {
+	namespace := ref.Database + "." + ref.Collection
+	scope := FindingScope{Type: ScopeNamespace, Database: ref.Database, Namespace: namespace}
+	definitions, err := source.Definitions(ctx, ref)
+	if err != nil {
+		var collectorErrors []error
+		if !isUnauthorizedError(err) {
+			collectorErrors = []error{err}
+		}
+		return collection, []CollectorStatus{failedCollectorStatus("index_special", scope, err)}, collectorErrors, false
+	}
+	var statuses []CollectorStatus
+	var collectorErrors []error
+	// hashed 索引可能支撑 hashed 分片键，分片键未知时不做 hashed 判断。
+	var shardKey []IndexKeyField
+	shardKeyKnown := !sharded
+	if sharded {
+		if collection.routing != nil {
+			shardKey, shardKeyKnown = indexKeyFromRouting(*collection.routing), true
+		} else if routing, routingErr := source.Routing(ctx, ref); routingErr != nil {
+			statuses = append(statuses, failedCollectorStatus("index_special", scope, routingErr))
+			if !isUnauthorizedError(routingErr) {
+				collectorErrors = append(collectorErrors, routingErr)
+			}
+		} else {
+			shardKey, shardKeyKnown = indexKeyFromRouting(routing), true
+		}
+	}
+	if len(statuses) == 0 {
+		statuses = append(statuses, CollectorStatus{Name: "index_special", State: CapabilitySupported, Scope: scope, ReasonCode: "complete"})
+	}
+	findings := indexSpecialFindings(scope, definitions, collection.shardDefinitions, shardKey, shardKeyKnown)
+	if len(findings) == 0 {
+		return collection, statuses, collectorErrors, false
+	}
+	if collection.Namespace == "" {
+		collection = CollectionIndexAudit{Namespace: namespace, Indexes: []IndexObservation{}, Findings: []DiagnosticFinding{}}
+	}
+	collection.Findings = append(collection.Findings, findings...)
+	sanitizeAndSortFindings(collection.Findings)
+	return collection, statuses, collectorErrors, true
+}
+
+// indexSpecialFindings 在同一集合的索引定义之间查找四类特殊索引重叠，evidence 只包含索引名、字段名与 fingerprint。
+// 四类检查都只看 specialIndexEligible 的索引，成对比较的三类还要求 partial/collation 相同。
+func indexSpecialFindings(scope FindingScope, definitions []pkgmongo.CanonicalIndexDefinition, shardDefinitions map[string][]pkgmongo.CanonicalIndexDefinition, shardKey []IndexKeyField, hashedCheck bool) []DiagnosticFinding {
+	sorted := make([]pkgmongo.CanonicalIndexDefinition, 0, len(definitions))
+	for _, definition := range definitions {
+		if specialIndexEligible(definition) {
+			sorted = append(sorted, definition)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	var findings []DiagnosticFinding
+	if finding, conflict := textIndexConflict(scope, sorted, shardDefinitions); conflict {
+		findings = append(findings, finding)
+	}
+	for _, wildcard := range sorted {
+		if _, ok := wildcardIndexPath(wildcard); !ok {
+			continue
+		}
+		for _, single := range sorted {
+			if _, singleWildcard := wildcardIndexPath(single); singleWildcard || len(single.Key) != 1 || !rangeIndexOrder(single.Key[0].Order) || single.Name == "_id_" || single.Unique || single.ExpireAfterSeconds != nil ||
+				!sameIndexOptions(wildcard, single) || !wildcardCoversField(wildcard, single.Key[0].Field) {
+				continue
+			}
+			evidence := specialIndexEvidence(single, wildcard, "wildcardIndexName", "wildcardFingerprint")
+			evidence["field"] = single.Key[0].Field
+			if fingerprint := wildcard.FieldFingerprints["wildcardProjection"]; fingerprint != "" {
+				evidence["wildcardProjectionFingerprint"] = fingerprint
+			}
+			findings = append(findings, DiagnosticFinding{
+				Code: "index.special_wildcard_overlap", Severity: SeverityInfo, Scope: scope,
+				Summary:        "wildcard 索引的投影覆盖了单字段索引的字段，单字段索引可能冗余",
+				Evidence:       evidence,
+				Recommendation: "确认查询不依赖唯一约束、{$exists: false}、整数组等值匹配等 wildcard 索引不支持的形态后，先隐藏再删除单字段索引",
+			})
+		}
+	}
+	for i, first := range sorted {
+		for _, second := range sorted[i+1:] {
+			for _, field := range twoDSphereFields(first) {
+				if !stringIncluded(twoDSphereFields(second), field) || equalOptionalInt64(first.TwoDSphereVersion, second.TwoDSphereVersion) || !sameIndexOptions(first, second) {
+					continue
+				}
+				evidence := specialIndexEvidence(first, second, "otherIndexName", "otherIndexFingerprint")
+				evidence["field"] = field
+				if first.TwoDSphereVersion != nil {
+					evidence["version"] = *first.TwoDSphereVersion
+				}
+				if second.TwoDSphereVersion != nil {
+					evidence["otherVersion"] = *second.TwoDSphereVersion
+				}
+				findings = append(findings, DiagnosticFinding{
+					Code: "index.special_2dsphere_version_duplicate", Severity: SeverityInfo, Scope: scope,
+					Summary:        "同一字段存在多个 2dsphereIndexVersion 不同的 2dsphere 索引，旧版本索引通常是升级遗留",
+					Evidence:       evidence,
+					Recommendation: "确认查询已由新版本索引支撑后，先隐藏再删除旧版本的 2dsphere 索引",
+				})
+			}
+		}
+	}
+	if hashedCheck {
+		for _, hashed := range sorted {
+			if len(hashed.Key) != 1 || hashed.Key[0].Order != "hashed" {
+				continue
+			}
+			field := hashed.Key[0].Field
+			if len(shardKey) > 0 && shardKey[0] == (IndexKeyField{Field: field, Order: "hashed"}) {
+				continue
+			}
+			for _, ranged := range sorted {
+				if !rangeIndexOrder(ranged.Key[0].Order) || ranged.Key[0].Field != field || !sameIndexOptions(hashed, ranged) {
+					continue
+				}
+				evidence := specialIndexEvidence(hashed, ranged, "rangeIndexName", "rangeIndexFingerprint")
+				evidence["field"] = field
+				findings = append(findings, DiagnosticFinding{
+					Code: "index.special_hashed_duplicate", Severity: SeverityInfo, Scope: scope,
+					Summary:        "hashed 索引的字段已是另一范围索引的首字段，等值查询可由范围索引支撑",
+					Evidence:       evidence,
+					Recommendation: "确认该 hashed 索引不用于 hashed 分片键、zone 或计划中的重新分片后，先隐藏再删除",
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// textIndexConflict 汇总本次 listIndexes 与各 shard 上的 text 索引定义；同一 mongod 只允许一个 text 索引，
+// 出现多个名称或 weights 不同的定义说明曾尝试通过新的 weights 再建一个 text 索引且只在部分 shard 成功；各 shard 定义同样跳过构建中与隐藏的索引。
+func textIndexConflict(scope FindingScope, definitions []pkgmongo.CanonicalIndexDefinition, shardDefinitions map[string][]pkgmongo.CanonicalIndexDefinition) (DiagnosticFinding, bool) {
+	distinct := make(map[string]bool)
+	var names, weights, shards []string
+	add := func(definition pkgmongo.CanonicalIndexDefinition, shard string) {
+		if !textIndex(definition) || !specialIndexEligible(definition) {
+			return
+		}
+		distinct[definition.Name+"\x00"+definition.SemanticFingerprint] = true
+		if !stringIncluded(names, definition.Name) {
+			names = append(names, definition.Name)
+		}
+		if fingerprint := definition.FieldFingerprints["weights"]; fingerprint != "" && !stringIncluded(weights, fingerprint) {
+			weights = append(weights, fingerprint)
+		}
+		if shard != "" && !stringIncluded(shards, shard) {
+			shards = append(shards, shard)
+		}
+	}
+	for _, definition := range definitions {
+		add(definition, definition.Shard)
+	}
+	for shard, values := range shardDefinitions {
+		for _, definition := range values {
+			add(definition, shard)
+		}
+	}
+	if len(distinct) < 2 {
+		return DiagnosticFinding{}, false
+	}
+	sort.Strings(names)
+	sort.Strings(weights)
+	sort.Strings(shards)
+	evidence := map[string]any{"indexNames": names, "weightsFingerprints": weights}
+	if len(shards) > 0 {
+		evidence["shards"] = shards
+	}
+	return DiagnosticFinding{
+		Code: "index.special_text_conflict", Severity: SeverityWarning, Scope: scope,
+		Summary:        "集合上存在多个名称或 weights 不同的 text 索引定义，每个集合只能有一个 text 索引",
+		Evidence:       evidence,
+		Recommendation: "保留一个 text 索引并把所需字段合并到它的 weights 中，删除各 shard 上多余的 text 索引定义",
+	}, true
+}
+
+// specialIndexEligible 排除构建中与已隐藏的索引：前者定义尚未生效，后者已在下线流程中，不再作为重叠的任何一方报告。
+func specialIndexEligible(definition pkgmongo.CanonicalIndexDefinition) bool {
+	return !definition.Building && !definition.Hidden
+}
+
+func specialIndexEvidence(index, other pkgmongo.CanonicalIndexDefinition, otherNameKey, otherFingerprintKey string) map[string]any {
+	return map[string]any{
+		"indexName": index.Name, "indexFingerprint": index.SemanticFingerprint,
+		otherNameKey: other.Name, otherFingerprintKey: other.SemanticFingerprint,
+	}
+}
+
+func textIndex(definition pkgmongo.CanonicalIndexDefinition) bool {
+	for _, field := range definition.Key {
+		if field.Order == "text" {
+			return true
+		}
+	}
+	return false
+}
+
+func twoDSphereFields(definition pkgmongo.CanonicalIndexDefinition) []string {
+	var fields []string
+	for _, field := range definition.Key {
+		if field.Order == "2dsphere" {
+			fields = append(fields, field.Field)
+		}
+	}
+	return fields
+}
+
+// wildcardIndexPath 返回单字段 wildcard 索引的路径前缀，"$**" 返回空字符串；复合 wildcard 索引不参与判断。
+func wildcardIndexPath(definition pkgmongo.CanonicalIndexDefinition) (string, bool) {
+	if len(definition.Key) != 1 {
+		return "", false
+	}
+	field := definition.Key[0].Field
+	if field == "$**" {
+		return "", true
+	}
+	if path, found := strings.CutSuffix(field, ".$**"); found {
+		return path, true
+	}
+	return "", false
+}
+
+// wildcardCoversField 按 wildcard 路径前缀或 wildcardProjection 的包含/排除规则判断字段是否被索引；_id 只在投影显式包含时覆盖。
+func wildcardCoversField(wildcard pkgmongo.CanonicalIndexDefinition, field string) bool {
+	path, ok := wildcardIndexPath(wildcard)
+	if !ok {
+		return false
+	}
+	if path != "" {
+		return fieldHasPathPrefix(field, path)
+	}
+	if fieldHasPathPrefix(field, "_id") {
+		return wildcard.WildcardProjection["_id"]
+	}
+	inclusion := false
+	for projected, included := range wildcard.WildcardProjection {
+		if projected != "_id" && included {
+			inclusion = true
+		}
+		if projected != "_id" && fieldHasPathPrefix(field, projected) {
+			return included
+		}
+	}
+	return !inclusion
+}
+
+func fieldHasPathPrefix(field, path string) bool {
+	return field == path || strings.HasPrefix(field, path+".")
+}
+
+// rangeIndexOrder 判断 key 方向是否为普通升降序（1/-1），排除 text、hashed、2dsphere 等特殊类型。
+func rangeIndexOrder(order string) bool {
+	_, err := strconv.ParseFloat(order, 64)
+	return err == nil
+}
+
+// sameIndexOptions 比较影响可替代性的 partialFilterExpression 与 collation fingerprint。
+func sameIndexOptions(a, b pkgmongo.CanonicalIndexDefinition) bool {
+	return a.FieldFingerprints["partialFilterExpression"] == b.FieldFingerprints["partialFilterExpression"] &&
+		a.FieldFingerprints["collation"] == b.FieldFingerprints["collation"]
+}
@@ -0,0 +1,151 @@
+package mot
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+type fakeIndexSpecialSource struct {
+	definitions  map[string][]pkgmongo.CanonicalIndexDefinition
+	routing      map[string]pkgmongo.IndexRoutingSnapshot
+	routingErr   error
+	routingCalls int
+}
+
+func (f *fakeIndexSpecialSource) Definitions(_ context.Context, ref indexCollectionRef) ([]pkgmongo.CanonicalIndexDefinition, error) {
+	return f.definitions[ref.Database+"."+ref.Collection], nil
+}
+
+func (f *fakeIndexSpecialSource) Routing(_ context.Context, ref indexCollectionRef) (pkgmongo.IndexRoutingSnapshot, error) {
+	f.routingCalls++
+	return f.routing[ref.Database+"."+ref.Collection], f.routingErr
+}
+
+func TestIndexSpecialFindingsDetectOverlaps(t *testing.T) {
+	// 场景：wildcard 投影覆盖单字段索引、同字段不同版本的 2dsphere、hashed 与范围索引首字段重复、各 shard 上 weights 不同的 text 索引各自报告；
+	// 投影排除的字段、唯一索引、collation 不同以及支撑 hashed 分片键的索引不报告。
+	scope := FindingScope{Type: ScopeNamespace, Database: "app", Namespace: "app.users"}
+	wildcard := shardKeyDefinition("$**_1", "$**", "1")
+	wildcard.WildcardProjection = map[string]bool{"secret": false}
+	wildcard.FieldFingerprints["wildcardProjection"] = "projection-fp"
+	uniqueEmail := shardKeyDefinition("email_1", "email", "1")
+	uniqueEmail.Unique = true
+	excluded := shardKeyDefinition("secret_1", "secret", "1")
+	collated := shardKeyDefinition("nickname_1", "nickname", "1")
+	collated.FieldFingerprints["collation"] = "collation-fp"
+	geoV2, geoV3 := shardKeyDefinition("location_2dsphere", "location", "2dsphere"), shardKeyDefinition("location_2dsphere_v3", "location", "2dsphere")
+	version2, version3 := int64(2), int64(3)
+	geoV2.TwoDSphereVersion, geoV3.TwoDSphereVersion = &version2, &version3
+	definitions := []pkgmongo.CanonicalIndexDefinition{
+		shardKeyDefinition("_id_", "_id", "1"), wildcard, shardKeyDefinition("status_1", "status", "1"), uniqueEmail, excluded, collated,
+		geoV2, geoV3, shardKeyDefinition("tenant_hashed", "tenant", "hashed"), shardKeyDefinition("tenant_1_createdAt_1", "tenant", "1", "createdAt", "1"),
+		shardKeyDefinition("title_text", "_fts", "text", "_ftsx", "1"),
+	}
+	secondText := shardKeyDefinition("body_text", "_fts", "text", "_ftsx", "1")
+	secondText.FieldFingerprints["weights"] = "weights-body"
+	shardDefinitions := map[string][]pkgmongo.CanonicalIndexDefinition{"shard-b": {secondText}}
+
+	findings := indexSpecialFindings(scope, definitions, shardDefinitions, nil, true)
+	byCode := make(map[string][]DiagnosticFinding)
+	for _, finding := range findings {
+		byCode[finding.Code] = append(byCode[finding.Code], finding)
+	}
+	if overlaps := byCode["index.special_wildcard_overlap"]; len(overlaps) != 1 || overlaps[0].Evidence["indexName"] != "status_1" || overlaps[0].Evidence["wildcardProjectionFingerprint"] != "projection-fp" {
+		t.Fatalf("wildcard findings = %#v", overlaps)
+	}
+	if geo := byCode["index.special_2dsphere_version_duplicate"]; len(geo) != 1 || geo[0].Evidence["version"] != int64(2) || geo[0].Evidence["otherVersion"] != int64(3) {
+		t.Fatalf("2dsphere findings = %#v", geo)
+	}
+	if hashed := byCode["index.special_hashed_duplicate"]; len(hashed) != 1 || hashed[0].Evidence["rangeIndexName"] != "tenant_1_createdAt_1" || hashed[0].Evidence["rangeIndexFingerprint"] != "tenant_1_createdAt_1" {
+		t.Fatalf("hashed findings = %#v", hashed)
+	}
+	if text := byCode["index.special_text_conflict"]; len(text) != 1 || len(text[0].Evidence["indexNames"].([]string)) != 2 || text[0].Evidence["shards"].([]string)[0] != "shard-b" {
+		t.Fatalf("text findings = %#v", text)
+	}
+	hashedShardKey := []IndexKeyField{{Field: "tenant", Order: "hashed"}}
+	if hasFindingCode(indexSpecialFindings(scope, definitions, nil, hashedShardKey, true), "index.special_hashed_duplicate") {
+		t.Fatal("hashed shard key index reported as duplicate")
+	}
+	if hasFindingCode(indexSpecialFindings(scope, definitions, nil, nil, false), "index.special_hashed_duplicate") || hasFindingCode(indexSpecialFindings(scope, definitions, nil, nil, true), "index.special_text_conflict") {
+		t.Fatal("hashed check ran without shard key or single text index reported")
+	}
+
+	pathWildcard := shardKeyDefinition("profile.$**_1", "profile.$**", "1")
+	inclusion := shardKeyDefinition("$**_1", "$**", "1")
+	inclusion.WildcardProjection = map[string]bool{"profile": true, "_id": false}
+	for _, tt := range []struct {
+		wildcard pkgmongo.CanonicalIndexDefinition
+		field    string
+		want     bool
+	}{
+		{pathWildcard, "profile.email", true},
+		{pathWildcard, "profileId", false},
+		{inclusion, "profile.email", true},
+		{inclusion, "status", false},
+		{wildcard, "_id", false},
+	} {
+		if got := wildcardCoversField(tt.wildcard, tt.field); got != tt.want {
+			t.Fatalf("wildcardCoversField(%s, %s) = %t", tt.wildcard.Name, tt.field, got)
+		}
+	}
+}
+
+func TestIndexSpecialFindingsSkipBuildingHiddenAndDifferentOptions(t *testing.T) {
+	// 场景：四类检查统一跳过构建中与隐藏的索引，2dsphere 也要求 partial/collation 相同；
+	// 隐藏的 hashed/wildcard、构建中或各 shard 上隐藏的 text 索引以及 collation 不同的 2dsphere 都不报告。
+	scope := FindingScope{Type: ScopeNamespace, Database: "app", Namespace: "app.users"}
+	hiddenHashed := shardKeyDefinition("tenant_hashed", "tenant", "hashed")
+	hiddenHashed.Hidden = true
+	hiddenWildcard := shardKeyDefinition("$**_1", "$**", "1")
+	hiddenWildcard.Hidden = true
+	geoV2, geoV3 := shardKeyDefinition("location_2dsphere", "location", "2dsphere"), shardKeyDefinition("location_2dsphere_v3", "location", "2dsphere")
+	version2, version3 := int64(2), int64(3)
+	geoV2.TwoDSphereVersion, geoV3.TwoDSphereVersion = &version2, &version3
+	geoV3.FieldFingerprints["collation"] = "collation-fp"
+	buildingText := shardKeyDefinition("body_text", "_fts", "text", "_ftsx", "1")
+	buildingText.Building = true
+	buildingText.FieldFingerprints["weights"] = "weights-body"
+	hiddenShardText := shardKeyDefinition("summary_text", "_fts", "text", "_ftsx", "1")
+	hiddenShardText.Hidden = true
+	hiddenShardText.FieldFingerprints["weights"] = "weights-summary"
+	definitions := []pkgmongo.CanonicalIndexDefinition{
+		hiddenHashed, hiddenWildcard, shardKeyDefinition("tenant_1", "tenant", "1"), geoV2, geoV3,
+		shardKeyDefinition("title_text", "_fts", "text", "_ftsx", "1"), buildingText,
+	}
+	shardDefinitions := map[string][]pkgmongo.CanonicalIndexDefinition{"shard-b": {hiddenShardText}}
+
+	if findings := indexSpecialFindings(scope, definitions, shardDefinitions, nil, true); len(findings) != 0 {
+		t.Fatalf("findings = %#v, want none", findings)
+	}
+}
+
+func TestCollectIndexSpecialAuditReusesRoutingAndSkipsHashedWithoutShardKey(t *testing.T) {
+	// 场景：分片集群上已采集的路由直接复用，缺失时补读；路由读取失败时记录 collector status 并跳过 hashed 判断，其余判断照常进行。
+	definitions := []pkgmongo.CanonicalIndexDefinition{
+		shardKeyDefinition("tenant_hashed", "tenant", "hashed"),
+		shardKeyDefinition("tenant_1", "tenant", "1"),
+	}
+	source := &fakeIndexSpecialSource{definitions: map[string][]pkgmongo.CanonicalIndexDefinition{"app.orders": definitions, "app.events": definitions}}
+	refs := []indexCollectionRef{{Database: "app", Collection: "events", Type: "collection"}, {Database: "app", Collection: "orders", Type: "collection"}}
+	collected := map[string]CollectionIndexAudit{"app.events": {Namespace: "app.events", Indexes: []IndexObservation{}, Findings: []DiagnosticFinding{}, routing: &pkgmongo.IndexRoutingSnapshot{Namespace: "app.events"}}}
+	source.routingErr = errors.New("config read failed")
+	collections, statuses, collectorErrors := collectIndexSpecialAudit(context.Background(), refs, IndexAuditOptions{Concurrency: 1}, true, source, collected)
+	if source.routingCalls != 1 || len(collectorErrors) != 1 || len(collections) != 1 || collections[0].Namespace != "app.events" {
+		t.Fatalf("collections=%#v statuses=%#v errors=%v calls=%d", collections, statuses, collectorErrors, source.routingCalls)
+	}
+	if !hasFindingCode(collections[0].Findings, "index.special_hashed_duplicate") {
+		t.Fatalf("events findings = %#v", collections[0].Findings)
+	}
+	failed := 0
+	for _, status := range statuses {
+		if status.Name == "index_special" && status.State != CapabilitySupported {
+			failed++
+		}
+	}
+	if failed != 1 || len(statuses) != 2 {
+		t.Fatalf("statuses = %#v", statuses)
+	}
+}
@@ -0,0 +1,295 @@
+package mot
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+const (
+	// ttlTypeSampleSize 是 TTL 字段类型抽样的文档数上限。
+	ttlTypeSampleSize = 1000
+	// maxTTLExpireAfterSeconds 是 MongoDB 接受的 expireAfterSeconds 上限（2^31-1）。
+	maxTTLExpireAfterSeconds = 2147483647
+	// defaultTTLMonitorSleepSeconds 是 ttlMonitorSleepSecs 的默认值，getParameter 不可用时按此估算应有的 pass 数。
+	defaultTTLMonitorSleepSeconds = 60
+)
+
+// ttlDateTypes 是 TTL monitor 能够处理或会静默跳过的字段类型；其余类型（包括不含 date 元素的数组）的文档永远不会过期。
+var ttlDateTypes = map[string]struct{}{"date": {}, "missing": {}, "null": {}, pkgmongo.FieldTypeArrayWithDate: {}}
+
+// IndexTTLObservation 是一个 TTL 索引的定义与字段类型抽样；ValueTypes 只包含 BSON 类型名与文档数，不包含字段值。
+type IndexTTLObservation struct {
+	Name               string           `json:"name"`
+	Key                []IndexKeyField  `json:"key"`
+	ExpireAfterSeconds *int64           `json:"expireAfterSeconds,omitempty"`
+	InvalidExpireAfter bool             `json:"invalidExpireAfter,omitempty"`
+	Capped             bool             `json:"capped,omitempty"`
+	SampledDocuments   int64            `json:"sampledDocuments"`
+	NonDateDocuments   int64            `json:"nonDateDocuments"`
+	ValueTypes         map[string]int64 `json:"valueTypes,omitempty"`
+}
+
+// indexTTLSource 读取集合的索引定义并抽样字段类型，测试可替换为 fake。
+type indexTTLSource interface {
+	Definitions(context.Context, indexCollectionRef) ([]pkgmongo.CanonicalIndexDefinition, error)
+	SampleTypes(context.Context, indexCollectionRef, string) (pkgmongo.FieldTypeSample, error)
+}
+
+type clientIndexTTLSource struct {
+	client *Client
+}
+
+func (s clientIndexTTLSource) Definitions(ctx context.Context, ref indexCollectionRef) ([]pkgmongo.CanonicalIndexDefinition, error) {
+	release, err := s.client.acquireRemoteSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return s.client.conn.ListIndexDefinitions(ctx, ref.Database, ref.Collection, indexConsistencyCollectorTimeout)
+}
+
+func (s clientIndexTTLSource) SampleTypes(ctx context.Context, ref indexCollectionRef, field string) (pkgmongo.FieldTypeSample, error) {
+	release, err := s.client.acquireRemoteSlot(ctx)
+	if err != nil {
+		return pkgmongo.FieldTypeSample{}, err
+	}
+	defer release()
+	return s.client.conn.SampleFieldTypes(ctx, ref.Database, ref.Collection, field, ttlTypeSampleSize, indexConsistencyCollectorTimeout)
+}
+
+// collectIndexTTLAudit 列出所选集合的 TTL 索引并抽样单字段 TTL 的字段类型；只返回含 TTL 索引或读取失败的集合。
+func collectIndexTTLAudit(ctx context.Context, refs []indexCollectionRef, opts IndexAuditOptions, source indexTTLSource, collected map[string]CollectionIndexAudit) ([]CollectionIndexAudit, []CollectorStatus, []error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultOverviewNodeConcurrency
+	}
+	limit := semaphore.NewWeighted(int64(concurrency))
+	group, groupCtx := errgroup.WithContext(ctx)
+	var mu sync.Mutex
+	var collections []CollectionIndexAudit
+	var statuses []CollectorStatus
+	var collectorErrors []error
+	for _, ref := range refs {
+		if ref.Type != "collection" {
+			continue
+		}
+		if acquireErr := acquireDiagnosticSlot(groupCtx, limit); acquireErr != nil {
+			mu.Lock()
+			collectorErrors = append(collectorErrors, acquireErr)
+			mu.Unlock()
+			break
+		}
+		ref := ref
+		collection := collected[ref.Database+"."+ref.Collection]
+		group.Go(func() error {
+			defer limit.Release(1)
+			collection, itemStatuses, itemErrors, found := auditIndexTTLCollection(groupCtx, ref, collection, source)
+			mu.Lock()
+			if found {
+				collections = append(collections, collection)
+			}
+			statuses = append(statuses, itemStatuses...)
+			collectorErrors = append(collectorErrors, itemErrors...)
+			mu.Unlock()
+			return nil
+		})
+	}
+	_ = group.Wait()
+	sort.SliceStable(collections, func(i, j int) bool { return collections[i].Namespace < collections[j].Namespace })
+	sortCollectorStatuses(statuses)
+	return collections, statuses, collectorErrors
+}
+
+func auditIndexTTLCollection(ctx context.Context, ref indexCollectionRef, collection CollectionIndexAudit, source indexTTLSource) (CollectionIndexAudit, []CollectorStatus, []error, bool) {
+	namespace := ref.Database + "." + ref.Collection
+	scope := FindingScope{Type: ScopeNamespace, Database: ref.Database, Namespace: namespace}
+	definitions, err := source.Definitions(ctx, ref)
+	if err != nil {
+		var collectorErrors []error
+		if !isUnauthorizedError(err) {
+			collectorErrors = []error{err}
+		}
+		return collection, []CollectorStatus{failedCollectorStatus("index_ttl", scope, err)}, collectorErrors, false
+	}
+	var observations []IndexTTLObservation
+	var statuses []CollectorStatus
+	var collectorErrors []error
+	for _, definition := range definitions {
+		if definition.ExpireAfterSeconds == nil && !definition.InvalidExpireAfterSeconds {
+			continue
+		}
+		observation := IndexTTLObservation{
+			Name: definition.Name, Key: publicIndexKey(definition.Key), ExpireAfterSeconds: definition.ExpireAfterSeconds,
+			InvalidExpireAfter: definition.InvalidExpireAfterSeconds, Capped: ref.Capped,
+		}
+		if len(observation.Key) == 1 {
+			sample, sampleErr := source.SampleTypes(ctx, ref, observation.Key[0].Field)
+			if sampleErr != nil {
+				statuses = append(statuses, failedCollectorStatus("index_ttl", scope, sampleErr))
+				if !isUnauthorizedError(sampleErr) {
+					collectorErrors = append(collectorErrors, sampleErr)
+				}
+			} else {
+				applyTTLTypeSample(&observation, sample)
+			}
+		}
+		observations = append(observations, observation)
+	}
+	if len(collectorErrors) == 0 && len(statuses) == 0 {
+		statuses = append(statuses, CollectorStatus{Name: "index_ttl", State: CapabilitySupported, Scope: scope, ReasonCode: "complete"})
+	}
+	if len(observations) == 0 {
+		return collection, statuses, collectorErrors, false
+	}
+	if collection.Namespace == "" {
+		collection = CollectionIndexAudit{Namespace: namespace, Indexes: []IndexObservation{}, Findings: []DiagnosticFinding{}}
+	}
+	collection.TTLIndexes = observations
+	collection.Findings = append(collection.Findings, indexTTLFindings(namespace, ref.Database, observations)...)
+	sanitizeAndSortFindings(collection.Findings)
+	return collection, statuses, collectorErrors, true
+}
+
+func applyTTLTypeSample(observation *IndexTTLObservation, sample pkgmongo.FieldTypeSample) {
+	observation.SampledDocuments = sample.Sampled
+	observation.ValueTypes = make(map[string]int64, len(sample.Types))
+	for valueType, count := range sample.Types {
+		observation.ValueTypes[valueType] = count
+		if _, ok := ttlDateTypes[valueType]; !ok {
+			observation.NonDateDocuments += count
+		}
+	}
+}
+
+// indexTTLFindings 报告 TTL monitor 不会生效的索引：非日期字段值、非法 expireAfterSeconds、复合索引与 capped 集合。
+func indexTTLFindings(namespace, database string, observations []IndexTTLObservation) []DiagnosticFinding {
+	scope := FindingScope{Type: ScopeNamespace, Database: database, Namespace: namespace}
+	var findings []DiagnosticFinding
+	for _, observation := range observations {
+		if observation.NonDateDocuments > 0 {
+			findings = append(findings, DiagnosticFinding{
+				Code: "index.ttl_non_date_values", Severity: SeverityWarning, Scope: scope,
+				Summary: "TTL 索引字段存在非日期类型的值，这些文档不会过期",
+				Evidence: map[string]any{
+					"indexName": observation.Name, "field": observation.Key[0].Field, "sampledDocuments": observation.SampledDocuments,
+					"nonDateDocuments": observation.NonDateDocuments, "valueTypes": observation.ValueTypes,
+				},
+				Recommendation: "把该字段统一写为 Date 类型并修正存量文档，否则需要另行清理这些文档",
+			})
+		}
+		if observation.InvalidExpireAfter || (observation.ExpireAfterSeconds != nil && (*observation.ExpireAfterSeconds < 0 || *observation.ExpireAfterSeconds > maxTTLExpireAfterSeconds)) {
+			evidence := map[string]any{"indexName": observation.Name}
+			if observation.ExpireAfterSeconds != nil {
+				evidence["expireAfterSeconds"] = *observation.ExpireAfterSeconds
+			}
+			findings = append(findings, DiagnosticFinding{
+				Code: "index.ttl_invalid_expire_after", Severity: SeverityWarning, Scope: scope,
+				Summary:        "TTL 索引的 expireAfterSeconds 不是 0 到 2147483647 之间的整数，TTL monitor 不会按预期删除文档",
+				Evidence:       evidence,
+				Recommendation: "使用 collMod 把 expireAfterSeconds 修正为有效的整数秒数",
+			})
+		}
+		if len(observation.Key) > 1 {
+			findings = append(findings, DiagnosticFinding{
+				Code: "index.ttl_compound", Severity: SeverityWarning, Scope: scope,
+				Summary:        "expireAfterSeconds 定义在复合索引上，TTL monitor 会忽略该索引",
+				Evidence:       map[string]any{"indexName": observation.Name, "key": observation.Key},
+				Recommendation: "在日期字段上单独建立 TTL 索引，再评估是否仍需要该复合索引",
+			})
+		}
+		if observation.Capped {
+			findings = append(findings, DiagnosticFinding{
+				Code: "index.ttl_on_capped", Severity: SeverityWarning, Scope: scope,
+				Summary:        "TTL 索引位于 capped 集合上，TTL monitor 不会从 capped 集合删除文档",
+				Evidence:       map[string]any{"indexName": observation.Name},
+				Recommendation: "依靠 capped 集合的大小上限淘汰数据，或改用普通集合加 TTL 索引",
+			})
+		}
+	}
+	return findings
+}
+
+// collectTTLMonitorFindings 直连每个健康的 PRIMARY/SECONDARY 成员读取 serverStatus.metrics.ttl，检查 TTL monitor 是否停用或明显落后。
+func (c *Client) collectTTLMonitorFindings(ctx context.Context, clusterType pkgmongo.ClusterType, capabilityLimit *semaphore.Weighted) ([]DiagnosticFinding, []CollectorStatus, []error) {
+	targets, statuses, collectorErrors := c.discoverHotspotTargets(ctx, clusterType)
+	var findings []DiagnosticFinding
+	var mu sync.Mutex
+	results := collectIndexAuditTargets(ctx, targets, func(ctx context.Context, target hotspotTarget) indexAuditTargetCollection {
+		scope := FindingScope{Type: ScopeNode, ReplicaSet: target.ReplicaSet, Shard: target.Shard, Node: target.Address}
+		release, acquireErr := c.acquireCapabilityRemoteSlot(ctx, capabilityLimit)
+		if acquireErr != nil {
+			return indexAuditTargetCollection{errors: []error{acquireErr}}
+		}
+		defer release()
+		snapshot, err := c.ttlMonitorStatus(ctx, target.Address)
+		if err != nil {
+			item := indexAuditTargetCollection{statuses: []CollectorStatus{failedCollectorStatus("index_ttl_monitor", scope, err)}}
+			if !isUnauthorizedError(err) {
+				item.errors = []error{err}
+			}
+			return item
+		}
+		if finding, inactive := evaluateTTLMonitor(scope, snapshot); inactive {
+			mu.Lock()
+			findings = append(findings, finding)
+			mu.Unlock()
+		}
+		return indexAuditTargetCollection{statuses: []CollectorStatus{{Name: "index_ttl_monitor", State: CapabilitySupported, Scope: scope}}}
+	})
+	for _, item := range results {
+		statuses = append(statuses, item.statuses...)
+		collectorErrors = append(collectorErrors, item.errors...)
+	}
+	return findings, statuses, collectorErrors
+}
+
+func (c *Client) ttlMonitorStatus(ctx context.Context, address string) (pkgmongo.TTLMonitorSnapshot, error) {
+	conn, err := c.connectAddress(ctx, address, derivedConnectionOptions{Direct: boolPointer(true)})
+	if err != nil {
+		return pkgmongo.TTLMonitorSnapshot{}, err
+	}
+	defer c.closeDerivedConnection(ctx, conn)
+	return conn.TTLMonitorStatus(ctx, indexConsistencyCollectorTimeout)
+}
+
+// evaluateTTLMonitor 在 ttlMonitorEnabled=false，或 primary 上的 pass 数不足按 uptime 与 ttlMonitorSleepSecs 估算值的一半时判定 TTL monitor 不活跃；
+// secondary 不执行 TTL 删除，运行时间不足三个周期的成员也不做落后判断。
+func evaluateTTLMonitor(scope FindingScope, snapshot pkgmongo.TTLMonitorSnapshot) (DiagnosticFinding, bool) {
+	sleepSeconds := int64(defaultTTLMonitorSleepSeconds)
+	if snapshot.SleepSeconds != nil && *snapshot.SleepSeconds > 0 {
+		sleepSeconds = *snapshot.SleepSeconds
+	}
+	evidence := map[string]any{"sleepSeconds": sleepSeconds}
+	for name, value := range map[string]*int64{"passes": snapshot.Passes, "deletedDocuments": snapshot.DeletedDocuments, "uptimeSeconds": snapshot.UptimeSeconds} {
+		if value != nil {
+			evidence[name] = *value
+		}
+	}
+	reason := ""
+	switch {
+	case snapshot.Enabled != nil && !*snapshot.Enabled:
+		reason = "disabled"
+	case snapshot.Primary && snapshot.Passes != nil && snapshot.UptimeSeconds != nil:
+		expected := *snapshot.UptimeSeconds / sleepSeconds
+		evidence["expectedPasses"] = expected
+		if expected >= 3 && *snapshot.Passes*2 < expected {
+			reason = "lagging"
+		}
+	}
+	if reason == "" {
+		return DiagnosticFinding{}, false
+	}
+	evidence["reason"] = reason
+	return DiagnosticFinding{
+		Code: "index.ttl_monitor_inactive", Severity: SeverityWarning, Scope: scope,
+		Summary:        "TTL monitor 已停用或运行次数明显少于预期，TTL 索引的过期文档可能没有被删除",
+		Evidence:       evidence,
+		Recommendation: "确认 ttlMonitorEnabled 未被关闭，并排查长时间持锁、复制延迟或节点负载导致的 TTL 删除积压",
+	}, true
+}
@@ -0,0 +1,117 @@
+package mot
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+type fakeIndexTTLSource struct {
+	mu          sync.Mutex
+	definitions map[string][]pkgmongo.CanonicalIndexDefinition
+	samples     map[string]pkgmongo.FieldTypeSample
+	sampleCalls []string
+}
+
+func (f *fakeIndexTTLSource) Definitions(_ context.Context, ref indexCollectionRef) ([]pkgmongo.CanonicalIndexDefinition, error) {
+	return f.definitions[ref.Database+"."+ref.Collection], nil
+}
+
+func (f *fakeIndexTTLSource) SampleTypes(_ context.Context, ref indexCollectionRef, field string) (pkgmongo.FieldTypeSample, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := ref.Database + "." + ref.Collection + ":" + field
+	f.sampleCalls = append(f.sampleCalls, key)
+	return f.samples[key], nil
+}
+
+func ttlDefinition(name string, expireAfter int64, key ...string) pkgmongo.CanonicalIndexDefinition {
+	definition := shardKeyDefinition(name, key...)
+	definition.ExpireAfterSeconds = &expireAfter
+	return definition
+}
+
+func TestCollectIndexTTLAuditFlagsIneffectiveIndexes(t *testing.T) {
+	// 场景：列出 TTL 索引并抽样单字段 TTL 的字段类型；非日期值（含不含 date 元素的数组）、非法 expireAfterSeconds、复合索引与 capped 集合各自报告，不含 TTL 的集合不产生结果。
+	invalid := ttlDefinition("expireAt_1", 0, "expireAt", "1")
+	invalid.ExpireAfterSeconds, invalid.InvalidExpireAfterSeconds = nil, true
+	source := &fakeIndexTTLSource{
+		definitions: map[string][]pkgmongo.CanonicalIndexDefinition{
+			"app.sessions": {shardKeyDefinition("_id_", "_id", "1"), ttlDefinition("createdAt_1", 3600, "createdAt", "1"), ttlDefinition("tenant_1_createdAt_1", 60, "tenant", "1", "createdAt", "1")},
+			"app.events":   {invalid},
+			"app.logs":     {ttlDefinition("ts_1", -1, "ts", "1")},
+			"app.orders":   {shardKeyDefinition("_id_", "_id", "1")},
+		},
+		samples: map[string]pkgmongo.FieldTypeSample{
+			"app.sessions:createdAt": {Sampled: 10, Types: map[string]int64{"date": 5, pkgmongo.FieldTypeArrayWithDate: 2, "array": 1, "string": 1, "missing": 1}},
+			"app.logs:ts":            {Sampled: 5, Types: map[string]int64{"date": 5}},
+		},
+	}
+	refs := []indexCollectionRef{
+		{Database: "app", Collection: "events", Type: "collection"},
+		{Database: "app", Collection: "logs", Type: "collection", Capped: true},
+		{Database: "app", Collection: "orders", Type: "collection"},
+		{Database: "app", Collection: "orders_view", Type: "view"},
+		{Database: "app", Collection: "sessions", Type: "collection"},
+	}
+	collections, statuses, collectorErrors := collectIndexTTLAudit(context.Background(), refs, IndexAuditOptions{Concurrency: 2}, source, map[string]CollectionIndexAudit{})
+	if len(collectorErrors) != 0 || len(collections) != 3 || len(statuses) != 4 {
+		t.Fatalf("collections=%#v statuses=%#v errors=%v", collections, statuses, collectorErrors)
+	}
+	byNamespace := indexCollectionsByNamespace(collections)
+	sessions := byNamespace["app.sessions"]
+	if len(sessions.TTLIndexes) != 2 || sessions.TTLIndexes[0].NonDateDocuments != 2 || sessions.TTLIndexes[1].SampledDocuments != 0 {
+		t.Fatalf("sessions ttl = %#v", sessions.TTLIndexes)
+	}
+	if !hasFindingCode(sessions.Findings, "index.ttl_non_date_values") || !hasFindingCode(sessions.Findings, "index.ttl_compound") {
+		t.Fatalf("sessions findings = %#v", sessions.Findings)
+	}
+	if !hasFindingCode(byNamespace["app.events"].Findings, "index.ttl_invalid_expire_after") {
+		t.Fatalf("events findings = %#v", byNamespace["app.events"].Findings)
+	}
+	logs := byNamespace["app.logs"]
+	if !hasFindingCode(logs.Findings, "index.ttl_on_capped") || !hasFindingCode(logs.Findings, "index.ttl_invalid_expire_after") || hasFindingCode(logs.Findings, "index.ttl_non_date_values") {
+		t.Fatalf("logs findings = %#v", logs.Findings)
+	}
+	if len(source.sampleCalls) != 3 {
+		t.Fatalf("sample calls = %v", source.sampleCalls)
+	}
+	payload, err := json.Marshal(collections)
+	if err != nil || !strings.Contains(string(payload), `"valueTypes":{"array":1,"arrayWithDate":2,"date":5,"missing":1,"string":1}`) {
+		t.Fatalf("payload = %s, err = %v", payload, err)
+	}
+}
+
+func TestEvaluateTTLMonitorInactivity(t *testing.T) {
+	// 场景：ttlMonitorEnabled=false 在任何成员上都报告；primary 的 pass 数少于按 uptime 估算值的一半才算落后，secondary 与刚启动的成员不判断落后。
+	int64Pointer := func(value int64) *int64 { return &value }
+	disabled := false
+	scope := FindingScope{Type: ScopeNode, Node: "n1:27017"}
+	tests := []struct {
+		name       string
+		snapshot   pkgmongo.TTLMonitorSnapshot
+		wantReason string
+	}{
+		{name: "disabled secondary", snapshot: pkgmongo.TTLMonitorSnapshot{Enabled: &disabled}, wantReason: "disabled"},
+		{name: "lagging primary", snapshot: pkgmongo.TTLMonitorSnapshot{Primary: true, UptimeSeconds: int64Pointer(3600), Passes: int64Pointer(10)}, wantReason: "lagging"},
+		{name: "healthy primary", snapshot: pkgmongo.TTLMonitorSnapshot{Primary: true, UptimeSeconds: int64Pointer(3600), Passes: int64Pointer(59)}},
+		{name: "custom sleep", snapshot: pkgmongo.TTLMonitorSnapshot{Primary: true, UptimeSeconds: int64Pointer(3600), Passes: int64Pointer(10), SleepSeconds: int64Pointer(300)}},
+		{name: "lagging secondary", snapshot: pkgmongo.TTLMonitorSnapshot{UptimeSeconds: int64Pointer(3600), Passes: int64Pointer(0)}},
+		{name: "recent restart", snapshot: pkgmongo.TTLMonitorSnapshot{Primary: true, UptimeSeconds: int64Pointer(120), Passes: int64Pointer(0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finding, inactive := evaluateTTLMonitor(scope, tt.snapshot)
+			if inactive != (tt.wantReason != "") || (inactive && (finding.Code != "index.ttl_monitor_inactive" || finding.Evidence["reason"] != tt.wantReason || finding.Scope.Node != "n1:27017")) {
+				t.Fatalf("finding = %#v, inactive = %t", finding, inactive)
+			}
+		})
+	}
+	if includesGeneralIndexCheck([]IndexAuditCheck{IndexCheckTTL}) {
+		t.Fatal("ttl treated as general index check")
+	}
+}
@@ -0,0 +1,138 @@
+package mot
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+const (
+	indexUsageLedgerSchemaVersion = 1
+	// indexUsageLedgerRetention 限制 ledger 中保留的计数周期，避免文件随运行次数无限增长。
+	indexUsageLedgerRetention = 180 * 24 * time.Hour
+)
+
+// IndexUsageLedger 在本地累积每个成员、每个索引的 $indexStats 观测，用于跨越重启与重建造成的计数清零。
+// 同一计数周期（相同 Since）只保留最新一次观测。
+type IndexUsageLedger struct {
+	SchemaVersion int                     `json:"schemaVersion"`
+	ClusterDigest string                  `json:"clusterDigest"`
+	Observations  []IndexUsageObservation `json:"observations"`
+}
+
+// IndexUsageObservation 表示在 ObservedAt 时刻读取到的计数：自 Since 起该成员上的索引共被使用 Ops 次。
+type IndexUsageObservation struct {
+	Namespace  string    `json:"namespace"`
+	IndexName  string    `json:"indexName"`
+	Host       string    `json:"host"`
+	Since      time.Time `json:"since"`
+	Ops        int64     `json:"ops"`
+	ObservedAt time.Time `json:"observedAt"`
+}
+
+// indexUsageCoverage 是单个成员上可证明零使用的累计观测时长，以及各计数周期之间无法观测的空档。
+type indexUsageCoverage struct {
+	Observed time.Duration
+	Gap      time.Duration
+	Periods  int
+}
+
+// NewIndexUsageLedger 创建绑定到集群身份摘要的空 ledger。
+func NewIndexUsageLedger(identity CapacityIdentity) IndexUsageLedger {
+	return IndexUsageLedger{SchemaVersion: indexUsageLedgerSchemaVersion, ClusterDigest: identity.Digest, Observations: []IndexUsageObservation{}}
+}
+
+// Record 把一次 index-audit 的使用计数追加到 ledger；同一计数周期只保留最新观测，超过保留期的周期被清理。
+func (l *IndexUsageLedger) Record(result *IndexAuditResult) {
+	if result == nil {
+		return
+	}
+	l.SchemaVersion = indexUsageLedgerSchemaVersion
+	byPeriod := make(map[string]int, len(l.Observations))
+	for i, observation := range l.Observations {
+		byPeriod[observation.periodKey()] = i
+	}
+	for _, collection := range result.Collections {
+		for _, index := range collection.Indexes {
+			if index.Host == "" || index.Since.IsZero() || index.Building {
+				continue
+			}
+			observation := IndexUsageObservation{Namespace: collection.Namespace, IndexName: index.Name, Host: index.Host, Since: index.Since.UTC(), Ops: index.Ops, ObservedAt: result.CollectedAt.UTC()}
+			if i, exists := byPeriod[observation.periodKey()]; exists {
+				if observation.ObservedAt.After(l.Observations[i].ObservedAt) {
+					l.Observations[i] = observation
+				}
+				continue
+			}
+			byPeriod[observation.periodKey()] = len(l.Observations)
+			l.Observations = append(l.Observations, observation)
+		}
+	}
+	retained := l.Observations[:0]
+	for _, observation := range l.Observations {
+		if result.CollectedAt.Sub(observation.ObservedAt) <= indexUsageLedgerRetention {
+			retained = append(retained, observation)
+		}
+	}
+	l.Observations = retained
+	sort.SliceStable(l.Observations, func(i, j int) bool { return l.Observations[i].periodKey() < l.Observations[j].periodKey() })
+}
+
+func (o IndexUsageObservation) periodKey() string {
+	return strings.Join([]string{o.Namespace, o.IndexName, o.Host, o.Since.UTC().Format(time.RFC3339Nano)}, "\x00")
+}
+
+// coverage 返回当前计数周期的观测时长；当前周期零使用时再向前回溯：每个周期取最后一次观测，连续零使用的周期累计为观测时长，
+// 遇到有使用的周期即停止；相邻周期之间（上次观测到计数清零）的时间无法观测，计入 Gap 而非观测时长。
+func (l *IndexUsageLedger) coverage(namespace string, current IndexObservation, now time.Time) indexUsageCoverage {
+	if current.Since.IsZero() {
+		return indexUsageCoverage{}
+	}
+	result := indexUsageCoverage{Observed: now.Sub(current.Since), Periods: 1}
+	if l == nil || current.Ops != 0 {
+		return result
+	}
+	var history []IndexUsageObservation
+	for _, observation := range l.Observations {
+		if observation.Namespace == namespace && observation.IndexName == current.Name && observation.Host == current.Host && observation.Since.Before(current.Since) {
+			history = append(history, observation)
+		}
+	}
+	sort.SliceStable(history, func(i, j int) bool { return history[i].Since.After(history[j].Since) })
+	boundary := current.Since
+	for _, observation := range history {
+		if observation.Ops != 0 {
+			break
+		}
+		end := observation.ObservedAt
+		if end.After(boundary) {
+			end = boundary
+		}
+		result.Observed += end.Sub(observation.Since)
+		result.Gap += boundary.Sub(end)
+		result.Periods++
+		boundary = observation.Since
+	}
+	return result
+}
+
+// validateIndexUsageLedger 拒绝属于其他集群的 ledger，避免把不同集群的计数拼接成零使用证据。
+func (c *Client) validateIndexUsageLedger(ctx context.Context, clusterType pkgmongo.ClusterType, ledger *IndexUsageLedger) error {
+	if ledger == nil {
+		return nil
+	}
+	if ledger.SchemaVersion != indexUsageLedgerSchemaVersion {
+		return invalidOptions("unsupported index usage ledger schema version")
+	}
+	identity, err := c.capacityIdentity(ctx, clusterType)
+	if err != nil {
+		return err
+	}
+	if ledger.ClusterDigest != identity.Digest {
+		return invalidOptions("index usage ledger belongs to another cluster")
+	}
+	return nil
+}
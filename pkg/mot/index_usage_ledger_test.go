@@ -0,0 +1,56 @@
+package mot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIndexUsageLedgerProvesZeroUsageAcrossRestarts(t *testing.T) {
+	// 场景：当前计数周期不足观测窗口时，ledger 中此前连续零使用的周期可以补足；周期之间的空档不计入观测时长，
+	// 有使用的周期截断回溯。
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	opts := IndexAuditOptions{Checks: []IndexAuditCheck{IndexCheckUnused}, MinObservation: 7 * day}
+	collection := CollectionIndexAudit{Namespace: "app.orders", Indexes: []IndexObservation{{Name: "legacy_1", Host: "n1:27017", Since: now.Add(-2 * day)}}}
+	assertFindingCode(t, evaluateIndexAuditCollection(collection, 1, opts, now), "index.usage_inconclusive", SeverityInfo)
+
+	ledger := NewIndexUsageLedger(CapacityIdentity{Digest: "c1"})
+	ledger.Record(&IndexAuditResult{CollectedAt: now.Add(-3 * day), Collections: []CollectionIndexAudit{{Namespace: "app.orders", Indexes: []IndexObservation{{Name: "legacy_1", Host: "n1:27017", Since: now.Add(-8 * day)}}}}})
+	ledger.Record(&IndexAuditResult{CollectedAt: now.Add(-4 * day), Collections: []CollectionIndexAudit{{Namespace: "app.orders", Indexes: []IndexObservation{{Name: "legacy_1", Host: "n1:27017", Since: now.Add(-8 * day)}}}}})
+	if len(ledger.Observations) != 1 || !ledger.Observations[0].ObservedAt.Equal(now.Add(-3*day)) {
+		t.Fatalf("ledger kept more than the latest observation per period: %#v", ledger.Observations)
+	}
+	opts.UsageLedger = &ledger
+	coverage := ledger.coverage("app.orders", collection.Indexes[0], now)
+	if coverage.Observed != 7*day || coverage.Gap != day || coverage.Periods != 2 {
+		t.Fatalf("coverage = %#v", coverage)
+	}
+	findings := evaluateIndexAuditCollection(collection, 1, opts, now)
+	assertFindingCode(t, findings, "index.unused_candidate", SeverityWarning)
+	if findings[0].Evidence["counterPeriods"] != 2 {
+		t.Fatalf("evidence = %#v", findings[0].Evidence)
+	}
+
+	ledger.Record(&IndexAuditResult{CollectedAt: now.Add(-10 * day), Collections: []CollectionIndexAudit{{Namespace: "app.orders", Indexes: []IndexObservation{{Name: "legacy_1", Host: "n1:27017", Since: now.Add(-20 * day), Ops: 3}}}}})
+	if got := ledger.coverage("app.orders", collection.Indexes[0], now); got.Periods != 2 {
+		t.Fatalf("period with usage extended coverage: %#v", got)
+	}
+	if got := ledger.coverage("app.orders", IndexObservation{Name: "legacy_1", Host: "n2:27017", Since: now.Add(-2 * day)}, now); got.Periods != 1 {
+		t.Fatalf("another host reused the ledger: %#v", got)
+	}
+}
+
+func TestIndexUsageLedgerRecordDropsExpiredPeriods(t *testing.T) {
+	// 场景：超过保留期的计数周期被清理，构建中或缺少 since 的观测不写入 ledger。
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	ledger := NewIndexUsageLedger(CapacityIdentity{Digest: "c1"})
+	ledger.Observations = append(ledger.Observations, IndexUsageObservation{Namespace: "app.orders", IndexName: "old_1", Host: "n1", Since: now.Add(-400 * 24 * time.Hour), ObservedAt: now.Add(-200 * 24 * time.Hour)})
+	ledger.Record(&IndexAuditResult{CollectedAt: now, Collections: []CollectionIndexAudit{{Namespace: "app.orders", Indexes: []IndexObservation{
+		{Name: "a_1", Host: "n1", Since: now.Add(-time.Hour)},
+		{Name: "b_1", Host: "n1", Since: now.Add(-time.Hour), Building: true},
+		{Name: "c_1", Host: "n1"},
+	}}}})
+	if len(ledger.Observations) != 1 || ledger.Observations[0].IndexName != "a_1" || ledger.SchemaVersion != indexUsageLedgerSchemaVersion {
+		t.Fatalf("ledger = %#v", ledger)
+	}
+}
@@ -0,0 +1,366 @@
+package mot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+const defaultPlanCacheGroups = 20
+
+type planCacheNodeEntry struct {
+	namespace string
+	entry     PlanCacheEntry
+}
+
+// PlanCache 在每个 mongod 数据节点上按 namespace 执行 $planCacheStats，并按 queryHash/planCacheKey
+// 关联到 slowlog 查询形状；同一形状在不同分片或成员上缓存了不同计划时输出 finding。
+func (c *Client) PlanCache(ctx context.Context, opts PlanCacheOptions) (result *PlanCacheResult, err error) {
+	if c != nil && c.session == nil {
+		return withEphemeralCollectorSession(ctx, c, func(session *CollectorSession) (*PlanCacheResult, error) {
+			return session.PlanCache(ctx, opts)
+		})
+	}
+	opts, err = normalizePlanCacheOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.requireMemberConnectionURI(); err != nil {
+		return nil, err
+	}
+	defer func() { err = mapContextError(err) }()
+
+	cluster, err := c.detectCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result = &PlanCacheResult{ClusterType: convertClusterType(cluster.Type)}
+	if gate, allowed := diagnosticCapabilityGate("plan_cache", result.ClusterType, cluster.MaxWireVersion, true); !allowed {
+		result.CollectorStatuses = []CollectorStatus{gate}
+		return result, nil
+	}
+
+	var collectorErrors []error
+	summary, err := c.session.SlowlogSummary(ctx, opts.SlowlogOptions)
+	if err != nil {
+		if summary == nil || !errors.Is(err, ErrPartialResult) {
+			return nil, err
+		}
+		collectorErrors = append(collectorErrors, err)
+	}
+	result.CollectorStatuses = append(result.CollectorStatuses, summary.CollectorStatuses...)
+	merged, err := MergeSlowlogSummary(summary, SlowlogSortTotal)
+	if err != nil {
+		return nil, err
+	}
+	result.Groups = planCacheGroups(merged.Items, opts)
+	if len(result.Groups) == 0 {
+		result.CollectorStatuses = append(result.CollectorStatuses, CollectorStatus{Name: "plan_cache", State: CapabilitySkipped, Scope: FindingScope{Type: ScopeCluster}, ReasonCode: "no_slowlog_group", Message: "没有可关联 plan cache 的 slowlog 查询形状"})
+		sortCollectorStatuses(result.CollectorStatuses)
+		if len(collectorErrors) > 0 {
+			return result, newDiagnosticPartialError("plan-cache", result, errors.Join(collectorErrors...))
+		}
+		return result, nil
+	}
+
+	targets, targetStatuses, targetErrors := c.discoverHotspotTargets(ctx, cluster.Type)
+	result.CollectorStatuses = append(result.CollectorStatuses, targetStatuses...)
+	collectorErrors = append(collectorErrors, targetErrors...)
+	statuses, entryErrors := c.collectPlanCacheEntries(ctx, targets, result.Groups, opts.NodeConcurrency)
+	result.CollectorStatuses = append(result.CollectorStatuses, statuses...)
+	collectorErrors = append(collectorErrors, entryErrors...)
+
+	for i := range result.Groups {
+		group := &result.Groups[i]
+		finishPlanCacheGroup(group)
+		if len(group.Entries) == 0 {
+			result.CollectorStatuses = append(result.CollectorStatuses, CollectorStatus{Name: "plan_cache", State: CapabilitySkipped, Scope: FindingScope{Type: ScopeNamespace, Namespace: group.Namespace}, ReasonCode: "not_cached", Message: fmt.Sprintf("各数据节点 plan cache 中没有 %s", group.QueryHash)})
+			continue
+		}
+		if finding := evaluatePlanCacheGroup(*group); finding != nil {
+			result.Findings = append(result.Findings, *finding)
+		}
+	}
+	sanitizeAndSortFindings(result.Findings)
+	sortCollectorStatuses(result.CollectorStatuses)
+	if len(collectorErrors) > 0 {
+		return result, newDiagnosticPartialError("plan-cache", result, errors.Join(collectorErrors...))
+	}
+	return result, nil
+}
+
+func normalizePlanCacheOptions(opts PlanCacheOptions) (PlanCacheOptions, error) {
+	if opts.MaxGroups < 0 || opts.NodeConcurrency < 0 {
+		return PlanCacheOptions{}, invalidOptions("max groups and node concurrency must not be negative")
+	}
+	if pkgmongo.IsLegacySlowlogID(opts.QueryHash) {
+		return PlanCacheOptions{}, invalidOptions("legacy slowlog id has no plan cache entry")
+	}
+	if opts.MaxGroups == 0 {
+		opts.MaxGroups = defaultPlanCacheGroups
+	}
+	if opts.NodeConcurrency == 0 {
+		opts.NodeConcurrency = defaultOverviewNodeConcurrency
+	}
+	return opts, nil
+}
+
+// planCacheGroups 选出要检查的查询形状；指定 QueryHash 但 slowlog 中没有该形状时，按 Namespaces 逐个检查。
+func planCacheGroups(items []MergedSlowlogItem, opts PlanCacheOptions) []PlanCacheGroup {
+	var groups []PlanCacheGroup
+	seen := make(map[string]struct{})
+	for _, item := range items {
+		if item.QueryHash == "" || pkgmongo.IsLegacySlowlogID(item.QueryHash) || !strings.Contains(item.Namespace, ".") {
+			continue
+		}
+		if opts.QueryHash != "" && item.QueryHash != opts.QueryHash {
+			continue
+		}
+		key := item.Namespace + "\x00" + item.QueryHash
+		if _, exists := seen[key]; exists {
+			continue
+		}
+		if opts.QueryHash == "" && len(groups) >= opts.MaxGroups {
+			break
+		}
+		seen[key] = struct{}{}
+		groups = append(groups, PlanCacheGroup{
+			Namespace: item.Namespace, QueryHash: item.QueryHash, Operation: item.Operation,
+			SlowlogCount: item.Count, SlowlogTotalMillis: item.TotalMillis,
+		})
+	}
+	if len(groups) == 0 && opts.QueryHash != "" {
+		for _, namespace := range opts.Namespaces {
+			if strings.Contains(namespace, ".") {
+				groups = append(groups, PlanCacheGroup{Namespace: namespace, QueryHash: opts.QueryHash})
+			}
+		}
+	}
+	return groups
+}
+
+func (c *Client) collectPlanCacheEntries(ctx context.Context, targets []hotspotTarget, groups []PlanCacheGroup, nodeConcurrency int) ([]CollectorStatus, []error) {
+	keysByNamespace := make(map[string][]string)
+	for _, group := range groups {
+		keysByNamespace[group.Namespace] = append(keysByNamespace[group.Namespace], group.QueryHash)
+	}
+	namespaces := make([]string, 0, len(keysByNamespace))
+	for namespace := range keysByNamespace {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	var statuses []CollectorStatus
+	var collectorErrors []error
+	var mu sync.Mutex
+	group, groupCtx := errgroup.WithContext(ctx)
+	limit := semaphore.NewWeighted(int64(nodeConcurrency))
+	for _, target := range targets {
+		if acquireErr := acquireDiagnosticSlot(groupCtx, limit); acquireErr != nil {
+			mu.Lock()
+			collectorErrors = append(collectorErrors, acquireErr)
+			mu.Unlock()
+			break
+		}
+		target := target
+		group.Go(func() error {
+			defer limit.Release(1)
+			release, acquireErr := c.acquireRemoteSlot(groupCtx)
+			if acquireErr != nil {
+				mu.Lock()
+				collectorErrors = append(collectorErrors, acquireErr)
+				mu.Unlock()
+				return nil
+			}
+			defer release()
+			scope := FindingScope{Type: ScopeNode, ReplicaSet: target.ReplicaSet, Shard: target.Shard, Node: target.Address}
+			conn, connectErr := c.connectAddress(groupCtx, target.Address, derivedConnectionOptions{Direct: boolPointer(true)})
+			if connectErr != nil {
+				mu.Lock()
+				collectorErrors = append(collectorErrors, connectErr)
+				statuses = append(statuses, failedCollectorStatus("plan_cache", scope, connectErr))
+				mu.Unlock()
+				return nil
+			}
+			defer c.closeDerivedConnection(groupCtx, conn)
+			var nodeEntries []planCacheNodeEntry
+			var nodeStatuses []CollectorStatus
+			var nodeErrors []error
+			for _, namespace := range namespaces {
+				database, collection, _ := strings.Cut(namespace, ".")
+				documents, statsErr := conn.PlanCacheStats(groupCtx, database, collection, keysByNamespace[namespace], 5*time.Second)
+				if statsErr != nil {
+					namespaceScope := FindingScope{Type: ScopeNamespace, ReplicaSet: target.ReplicaSet, Shard: target.Shard, Node: target.Address, Database: database, Namespace: namespace}
+					if !isUnauthorizedError(statsErr) && !isUnsupportedDiagnosticError(statsErr) {
+						nodeErrors = append(nodeErrors, statsErr)
+					}
+					nodeStatuses = append(nodeStatuses, failedCollectorStatus("plan_cache", namespaceScope, statsErr))
+					if cancelErr := contextError(groupCtx); cancelErr != nil {
+						break
+					}
+					continue
+				}
+				for _, document := range documents {
+					entry := planCacheEntryFromDocument(document)
+					entry.ReplicaSet, entry.Shard, entry.Host = target.ReplicaSet, target.Shard, target.Address
+					nodeEntries = append(nodeEntries, planCacheNodeEntry{namespace: namespace, entry: entry})
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			statuses = append(statuses, nodeStatuses...)
+			collectorErrors = append(collectorErrors, nodeErrors...)
+			for _, item := range nodeEntries {
+				attachPlanCacheEntry(groups, item.namespace, item.entry)
+			}
+			if len(nodeStatuses) < len(namespaces) {
+				statuses = append(statuses, CollectorStatus{Name: "plan_cache", State: CapabilitySupported, Scope: scope})
+			}
+			return nil
+		})
+	}
+	_ = group.Wait()
+	sortCollectorStatuses(statuses)
+	return statuses, collectorErrors
+}
+
+// attachPlanCacheEntry 把条目挂到 queryHash 或 planCacheKey 相同的查询形状上。
+func attachPlanCacheEntry(groups []PlanCacheGroup, namespace string, entry PlanCacheEntry) {
+	for i := range groups {
+		if groups[i].Namespace != namespace {
+			continue
+		}
+		if groups[i].QueryHash == entry.QueryHash || groups[i].QueryHash == entry.PlanCacheKey {
+			groups[i].Entries = append(groups[i].Entries, entry)
+			return
+		}
+	}
+}
+
+// planCacheEntryFromDocument 兼容 classic plan 树与 7.0+ SBE 的 cachedPlan；8.0 的 planCacheShapeHash 视同 queryHash。
+func planCacheEntryFromDocument(document bson.M) PlanCacheEntry {
+	entry := PlanCacheEntry{}
+	entry.QueryHash, _ = document["queryHash"].(string)
+	if entry.QueryHash == "" {
+		entry.QueryHash, _ = document["planCacheShapeHash"].(string)
+	}
+	entry.PlanCacheKey, _ = document["planCacheKey"].(string)
+	entry.IsActive, _ = document["isActive"].(bool)
+	if works, ok := document["works"]; ok {
+		value := explainInt64(works)
+		entry.Works = &value
+	}
+	if created, ok := document["timeOfCreation"].(primitive.DateTime); ok {
+		entry.TimeOfCreation = created.Time().UTC()
+	}
+	cachedPlan := documentElements(document["cachedPlan"])
+	entry.CachedPlan = summarizeExplainPlan(explainPlanRoot(cachedPlan))
+	entry.PlanSignature = planCacheSignature(entry.CachedPlan, cachedPlan)
+	return entry
+}
+
+// planCacheSignature 由 stage 链与索引 key 组成；SBE 计划没有 stage 树时对 stages 文本取摘要。
+func planCacheSignature(plan ExplainPlan, cachedPlan bson.D) string {
+	if len(plan.Stages) == 0 {
+		if stages, ok := elementValue(cachedPlan, "stages").(string); ok && stages != "" {
+			digest := fnv.New64a()
+			_, _ = digest.Write([]byte(stages))
+			return fmt.Sprintf("sbe:%016x", digest.Sum64())
+		}
+		return ""
+	}
+	signature := strings.Join(plan.Stages, ">")
+	for _, scan := range plan.IndexScans {
+		fields := make([]string, 0, len(scan.Key))
+		for _, field := range scan.Key {
+			fields = append(fields, field.Field+":"+field.Order)
+		}
+		signature += " {" + strings.Join(fields, ",") + "}"
+	}
+	return signature
+}
+
+func finishPlanCacheGroup(group *PlanCacheGroup) {
+	sort.SliceStable(group.Entries, func(i, j int) bool {
+		if group.Entries[i].ReplicaSet != group.Entries[j].ReplicaSet {
+			return group.Entries[i].ReplicaSet < group.Entries[j].ReplicaSet
+		}
+		return group.Entries[i].Host < group.Entries[j].Host
+	})
+	plans := make(map[string]struct{})
+	for _, entry := range group.Entries {
+		if entry.PlanSignature != "" {
+			plans[entry.PlanSignature] = struct{}{}
+		}
+	}
+	group.DistinctPlans = len(plans)
+}
+
+// evaluatePlanCacheGroup 区分同一副本集成员之间与不同分片之间的计划分歧。
+func evaluatePlanCacheGroup(group PlanCacheGroup) *DiagnosticFinding {
+	if group.DistinctPlans < 2 {
+		return nil
+	}
+	plansByReplicaSet := make(map[string]map[string]struct{})
+	for _, entry := range group.Entries {
+		if entry.PlanSignature == "" {
+			continue
+		}
+		if plansByReplicaSet[entry.ReplicaSet] == nil {
+			plansByReplicaSet[entry.ReplicaSet] = make(map[string]struct{})
+		}
+		plansByReplicaSet[entry.ReplicaSet][entry.PlanSignature] = struct{}{}
+	}
+	acrossMembers := false
+	var reference map[string]struct{}
+	acrossShards := false
+	replicaSets := make([]string, 0, len(plansByReplicaSet))
+	for replicaSet := range plansByReplicaSet {
+		replicaSets = append(replicaSets, replicaSet)
+	}
+	sort.Strings(replicaSets)
+	for _, replicaSet := range replicaSets {
+		plans := plansByReplicaSet[replicaSet]
+		if len(plans) > 1 {
+			acrossMembers = true
+		}
+		if reference == nil {
+			reference = plans
+			continue
+		}
+		if len(plans) != len(reference) {
+			acrossShards = true
+			continue
+		}
+		for plan := range plans {
+			if _, ok := reference[plan]; !ok {
+				acrossShards = true
+			}
+		}
+	}
+	return &DiagnosticFinding{
+		Code: "query.plan_cache_divergent", Severity: SeverityWarning,
+		Scope:   FindingScope{Type: ScopeNamespace, Namespace: group.Namespace},
+		Summary: "同一查询形状在不同节点上缓存了不同的执行计划",
+		Evidence: map[string]any{
+			"queryHash": group.QueryHash, "distinctPlans": group.DistinctPlans, "entries": len(group.Entries),
+			"acrossShards": acrossShards, "acrossMembers": acrossMembers,
+		},
+		Recommendation: "对比各节点的 cachedPlan，确认数据分布或索引差异；必要时使用 planCacheClear 让查询重新选择计划",
+	}
+}
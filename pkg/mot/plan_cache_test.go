@@ -0,0 +1,93 @@
+package mot
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func planCacheTestDocument(queryHash, index string) bson.M {
+	return bson.M{
+		"queryHash":      queryHash,
+		"planCacheKey":   "KEY" + queryHash,
+		"isActive":       true,
+		"works":          int64(12),
+		"timeOfCreation": primitive.NewDateTimeFromTime(time.Date(2026, 7, 20, 8, 0, 0, 0, time.UTC)),
+		"cachedPlan": bson.D{{Key: "stage", Value: "FETCH"}, {Key: "inputStage", Value: bson.D{
+			{Key: "stage", Value: "IXSCAN"},
+			{Key: "indexName", Value: index + "_1"},
+			{Key: "keyPattern", Value: bson.D{{Key: index, Value: int32(1)}}},
+		}}},
+	}
+}
+
+func TestPlanCacheEntryFromDocumentSummarizesCachedPlan(t *testing.T) {
+	// 场景：classic cachedPlan 转为 stage 链与索引签名，8.0 的 planCacheShapeHash 视同 queryHash。
+	entry := planCacheEntryFromDocument(planCacheTestDocument("ABCD", "status"))
+	if entry.QueryHash != "ABCD" || entry.PlanCacheKey != "KEYABCD" || !entry.IsActive || entry.Works == nil || *entry.Works != 12 {
+		t.Fatalf("entry = %#v", entry)
+	}
+	if entry.PlanSignature != "FETCH>IXSCAN {status:1}" || !entry.TimeOfCreation.Equal(time.Date(2026, 7, 20, 8, 0, 0, 0, time.UTC)) {
+		t.Fatalf("signature = %q, created = %v", entry.PlanSignature, entry.TimeOfCreation)
+	}
+
+	sbe := planCacheEntryFromDocument(bson.M{"planCacheShapeHash": "EF01", "cachedPlan": bson.D{{Key: "slots", Value: "$$RESULT=s1"}, {Key: "stages", Value: "[1] scan s1"}}})
+	if sbe.QueryHash != "EF01" || len(sbe.PlanSignature) != len("sbe:")+16 {
+		t.Fatalf("sbe entry = %#v", sbe)
+	}
+}
+
+func TestEvaluatePlanCacheGroupFlagsDivergentPlans(t *testing.T) {
+	// 场景：同一查询形状在两个分片上缓存不同计划时输出 warning，并区分分片间与成员间分歧。
+	groups := []PlanCacheGroup{{Namespace: "app.orders", QueryHash: "ABCD"}}
+	for _, item := range []struct {
+		replicaSet, host, index string
+	}{
+		{replicaSet: "shard01", host: "n1", index: "status"},
+		{replicaSet: "shard01", host: "n2", index: "status"},
+		{replicaSet: "shard02", host: "n3", index: "createdAt"},
+	} {
+		entry := planCacheEntryFromDocument(planCacheTestDocument("ABCD", item.index))
+		entry.ReplicaSet, entry.Host = item.replicaSet, item.host
+		attachPlanCacheEntry(groups, "app.orders", entry)
+	}
+	attachPlanCacheEntry(groups, "app.users", planCacheEntryFromDocument(planCacheTestDocument("ABCD", "name")))
+
+	finishPlanCacheGroup(&groups[0])
+	if len(groups[0].Entries) != 3 || groups[0].DistinctPlans != 2 {
+		t.Fatalf("group = %#v", groups[0])
+	}
+	finding := evaluatePlanCacheGroup(groups[0])
+	if finding == nil || finding.Code != "query.plan_cache_divergent" || finding.Evidence["acrossShards"] != true || finding.Evidence["acrossMembers"] != false {
+		t.Fatalf("finding = %#v", finding)
+	}
+
+	same := PlanCacheGroup{Entries: groups[0].Entries[:2]}
+	finishPlanCacheGroup(&same)
+	if evaluatePlanCacheGroup(same) != nil {
+		t.Fatalf("identical cached plans produced a finding")
+	}
+}
+
+func TestPlanCacheGroupsSelectsSlowlogShapes(t *testing.T) {
+	// 场景：只关联带 queryHash 的 slowlog 形状；指定 hash 但无 slowlog 聚合组时按 namespace 检查。
+	items := []MergedSlowlogItem{
+		{SlowlogSummaryItem: SlowlogSummaryItem{Namespace: "app.orders", QueryHash: "ABCD", Count: 3, TotalMillis: 900}},
+		{SlowlogSummaryItem: SlowlogSummaryItem{Namespace: "app.orders", QueryHash: "legacy:0011"}},
+		{SlowlogSummaryItem: SlowlogSummaryItem{Namespace: "app.users", QueryHash: "EF01"}},
+	}
+	groups := planCacheGroups(items, PlanCacheOptions{MaxGroups: 1})
+	if len(groups) != 1 || groups[0].QueryHash != "ABCD" || groups[0].SlowlogTotalMillis != 900 {
+		t.Fatalf("groups = %#v", groups)
+	}
+	groups = planCacheGroups(items, PlanCacheOptions{QueryHash: "9999", SlowlogOptions: SlowlogOptions{Namespaces: []string{"app.events"}}})
+	if len(groups) != 1 || groups[0].Namespace != "app.events" || groups[0].SlowlogCount != 0 {
+		t.Fatalf("fallback groups = %#v", groups)
+	}
+	if _, err := normalizePlanCacheOptions(PlanCacheOptions{QueryHash: "legacy:0011"}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("legacy hash error = %v, want ErrInvalidOptions", err)
+	}
+}
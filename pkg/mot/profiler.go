@@ -0,0 +1,310 @@
+package mot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+// ProfilerStatus 读取每个 PRIMARY/SECONDARY 成员上各 database 的 profiling level、slowms、sampleRate 与 filter，
+// 并标记同一 database 在成员之间不一致的设置。
+func (c *Client) ProfilerStatus(ctx context.Context, opts ProfilerOptions) (result *ProfilerResult, err error) {
+	if c != nil && c.session == nil {
+		return withEphemeralCollectorSession(ctx, c, func(session *CollectorSession) (*ProfilerResult, error) {
+			return session.ProfilerStatus(ctx, opts)
+		})
+	}
+	opts, err = normalizeProfilerOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.requireMemberConnectionURI(); err != nil {
+		return nil, err
+	}
+	defer func() { err = mapContextError(err) }()
+
+	cluster, err := c.detectCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result = &ProfilerResult{ClusterType: convertClusterType(cluster.Type), Action: ProfilerActionStatus}
+	if gate, allowed := diagnosticCapabilityGate("profiler_status", result.ClusterType, cluster.MaxWireVersion, true); !allowed {
+		result.CollectorStatuses = []CollectorStatus{gate}
+		return result, nil
+	}
+	return c.runProfiler(ctx, cluster.Type, result, opts, nil)
+}
+
+// SetProfiler 在每个 PRIMARY/SECONDARY 成员上修改选定 database 的 profiler。profiler 设置不随复制同步，
+// 因此逐成员执行；DryRun 时只读取当前设置并在 CollectorStatus 中报告将要做的修改。
+func (c *Client) SetProfiler(ctx context.Context, opts ProfilerChangeOptions) (result *ProfilerResult, err error) {
+	if err := validateProfilerChange(opts); err != nil {
+		return nil, err
+	}
+	opts.ProfilerOptions, err = normalizeProfilerOptions(opts.ProfilerOptions)
+	if err != nil {
+		return nil, err
+	}
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.requireMemberConnectionURI(); err != nil {
+		return nil, err
+	}
+	defer func() { err = mapContextError(err) }()
+
+	cluster, err := c.detectCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result = &ProfilerResult{ClusterType: convertClusterType(cluster.Type), Action: ProfilerActionEnable, DryRun: opts.DryRun}
+	if opts.Level == 0 {
+		result.Action = ProfilerActionDisable
+	}
+	if gate, allowed := diagnosticCapabilityGate("profiler_change", result.ClusterType, cluster.MaxWireVersion, true); !allowed {
+		result.CollectorStatuses = []CollectorStatus{gate}
+		return result, nil
+	}
+	return c.runProfiler(ctx, cluster.Type, result, opts.ProfilerOptions, &opts)
+}
+
+func normalizeProfilerOptions(opts ProfilerOptions) (ProfilerOptions, error) {
+	if opts.NodeConcurrency < 0 {
+		return ProfilerOptions{}, invalidOptions("node concurrency must not be negative")
+	}
+	if opts.NodeConcurrency == 0 {
+		opts.NodeConcurrency = defaultOverviewNodeConcurrency
+	}
+	return opts, nil
+}
+
+func validateProfilerChange(opts ProfilerChangeOptions) error {
+	if opts.Level < 0 || opts.Level > 2 {
+		return invalidOptions("profiling level must be 0, 1 or 2")
+	}
+	if opts.AllDatabases == (len(opts.Databases) > 0) {
+		return invalidOptions("databases and all databases must be specified exclusively")
+	}
+	if opts.SlowMillis != nil && *opts.SlowMillis < 0 {
+		return invalidOptions("slow millis must not be negative")
+	}
+	if opts.SampleRate != nil && (*opts.SampleRate <= 0 || *opts.SampleRate > 1) {
+		return invalidOptions("sample rate must be in (0, 1]")
+	}
+	return nil
+}
+
+func (c *Client) runProfiler(ctx context.Context, clusterType pkgmongo.ClusterType, result *ProfilerResult, opts ProfilerOptions, change *ProfilerChangeOptions) (*ProfilerResult, error) {
+	statusName, operation := "profiler_status", "profiler-status"
+	if change != nil {
+		statusName, operation = "profiler_change", "profiler-change"
+	}
+	targets, targetStatuses, collectorErrors := c.discoverHotspotTargets(ctx, clusterType)
+	result.CollectorStatuses = append(result.CollectorStatuses, targetStatuses...)
+
+	var mu sync.Mutex
+	group, groupCtx := errgroup.WithContext(ctx)
+	limit := semaphore.NewWeighted(int64(opts.NodeConcurrency))
+	for _, target := range targets {
+		if acquireErr := acquireDiagnosticSlot(groupCtx, limit); acquireErr != nil {
+			mu.Lock()
+			collectorErrors = append(collectorErrors, acquireErr)
+			mu.Unlock()
+			break
+		}
+		target := target
+		group.Go(func() error {
+			defer limit.Release(1)
+			settings, statuses, nodeErrors := c.profilerNode(groupCtx, target, statusName, opts, change)
+			mu.Lock()
+			defer mu.Unlock()
+			result.Settings = append(result.Settings, settings...)
+			result.CollectorStatuses = append(result.CollectorStatuses, statuses...)
+			collectorErrors = append(collectorErrors, nodeErrors...)
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	sort.SliceStable(result.Settings, func(i, j int) bool {
+		left, right := result.Settings[i], result.Settings[j]
+		if left.ReplicaSet != right.ReplicaSet {
+			return left.ReplicaSet < right.ReplicaSet
+		}
+		if left.Host != right.Host {
+			return left.Host < right.Host
+		}
+		return left.Database < right.Database
+	})
+	result.Findings = evaluateProfilerSettings(result.Settings)
+	sanitizeAndSortFindings(result.Findings)
+	sortCollectorStatuses(result.CollectorStatuses)
+	if len(collectorErrors) > 0 {
+		return result, newDiagnosticPartialError(operation, result, errors.Join(collectorErrors...))
+	}
+	return result, nil
+}
+
+// profilerNode 在单个成员上读取（并按需修改）各 database 的设置；修改类失败总是计入错误。
+func (c *Client) profilerNode(ctx context.Context, target hotspotTarget, statusName string, opts ProfilerOptions, change *ProfilerChangeOptions) ([]ProfilerSetting, []CollectorStatus, []error) {
+	scope := FindingScope{Type: ScopeNode, ReplicaSet: target.ReplicaSet, Shard: target.Shard, Node: target.Address}
+	release, err := c.acquireRemoteSlot(ctx)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	defer release()
+	conn, err := c.connectAddress(ctx, target.Address, derivedConnectionOptions{Direct: boolPointer(true)})
+	if err != nil {
+		return nil, []CollectorStatus{failedCollectorStatus(statusName, scope, err)}, []error{err}
+	}
+	defer c.closeDerivedConnection(ctx, conn)
+	databases := opts.Databases
+	if len(databases) == 0 {
+		names, listErr := conn.Client.ListDatabaseNames(ctx, bson.D{})
+		if listErr != nil {
+			return nil, []CollectorStatus{failedCollectorStatus(statusName, scope, listErr)}, []error{listErr}
+		}
+		for _, name := range names {
+			if opts.IncludeSystemDB || !isSystemDatabase(name) {
+				databases = append(databases, name)
+			}
+		}
+	}
+
+	var settings []ProfilerSetting
+	var statuses []CollectorStatus
+	var nodeErrors []error
+	for _, database := range databases {
+		databaseScope := scope
+		databaseScope.Database = database
+		current, statusErr := conn.ProfilerStatus(ctx, database)
+		if statusErr != nil {
+			if change != nil || (!isUnauthorizedError(statusErr) && !isUnsupportedDiagnosticError(statusErr)) {
+				nodeErrors = append(nodeErrors, statusErr)
+			}
+			statuses = append(statuses, failedCollectorStatus(statusName, databaseScope, statusErr))
+			if cancelErr := contextError(ctx); cancelErr != nil {
+				break
+			}
+			continue
+		}
+		setting := profilerSetting(target, database, current)
+		if change != nil {
+			status := CollectorStatus{Name: statusName, State: CapabilitySupported, Scope: databaseScope}
+			switch {
+			case !profilerNeedsChange(current, *change):
+				status.ReasonCode, status.Message = "unchanged", fmt.Sprintf("level 已为 %d", current.Level)
+			case change.DryRun:
+				status.ReasonCode, status.Message = "dry_run", fmt.Sprintf("将 level 从 %d 改为 %d", current.Level, change.Level)
+			default:
+				previous, setErr := conn.SetProfiler(ctx, database, int32(change.Level), change.SlowMillis, change.SampleRate)
+				if setErr != nil {
+					nodeErrors = append(nodeErrors, setErr)
+					statuses = append(statuses, failedCollectorStatus(statusName, databaseScope, setErr))
+					settings = append(settings, setting)
+					continue
+				}
+				previousLevel := int(previous.Level)
+				setting.PreviousLevel = &previousLevel
+				setting.Level = change.Level
+				if change.SlowMillis != nil {
+					setting.SlowMillis = *change.SlowMillis
+				}
+				if change.SampleRate != nil {
+					setting.SampleRate = change.SampleRate
+				}
+				status.ReasonCode, status.Message = "applied", fmt.Sprintf("level 已从 %d 改为 %d", previous.Level, change.Level)
+			}
+			statuses = append(statuses, status)
+		}
+		settings = append(settings, setting)
+	}
+	if change == nil && len(settings) > 0 {
+		statuses = append(statuses, CollectorStatus{Name: statusName, State: CapabilitySupported, Scope: scope})
+	}
+	return settings, statuses, nodeErrors
+}
+
+func profilerSetting(target hotspotTarget, database string, current pkgmongo.ProfilerSetting) ProfilerSetting {
+	return ProfilerSetting{
+		ReplicaSet: target.ReplicaSet, Shard: target.Shard, Host: target.Address, Database: database,
+		Level: int(current.Level), SlowMillis: current.SlowMS, SampleRate: current.SampleRate, Filter: current.Filter,
+	}
+}
+
+func profilerNeedsChange(current pkgmongo.ProfilerSetting, change ProfilerChangeOptions) bool {
+	if int(current.Level) != change.Level {
+		return true
+	}
+	if change.SlowMillis != nil && *change.SlowMillis != current.SlowMS {
+		return true
+	}
+	return change.SampleRate != nil && (current.SampleRate == nil || *current.SampleRate != *change.SampleRate)
+}
+
+// evaluateProfilerSettings 按 database 比较所有成员的 level、slowms、sampleRate 与 filter。
+func evaluateProfilerSettings(settings []ProfilerSetting) []DiagnosticFinding {
+	byDatabase := make(map[string][]ProfilerSetting)
+	for _, setting := range settings {
+		byDatabase[setting.Database] = append(byDatabase[setting.Database], setting)
+	}
+	databases := make([]string, 0, len(byDatabase))
+	for database := range byDatabase {
+		databases = append(databases, database)
+	}
+	sort.Strings(databases)
+
+	findings := make([]DiagnosticFinding, 0)
+	for _, database := range databases {
+		variants := make(map[string]struct{})
+		levels := make(map[string]struct{})
+		slowMillis := make(map[string]struct{})
+		sampleRates := make(map[string]struct{})
+		for _, setting := range byDatabase[database] {
+			rate := "default"
+			if setting.SampleRate != nil {
+				rate = strconv.FormatFloat(*setting.SampleRate, 'g', -1, 64)
+			}
+			variants[fmt.Sprintf("%d|%d|%s|%s", setting.Level, setting.SlowMillis, rate, setting.Filter)] = struct{}{}
+			levels[strconv.Itoa(setting.Level)] = struct{}{}
+			slowMillis[strconv.FormatInt(setting.SlowMillis, 10)] = struct{}{}
+			sampleRates[rate] = struct{}{}
+		}
+		if len(variants) < 2 {
+			continue
+		}
+		findings = append(findings, DiagnosticFinding{
+			Code: "profiler.inconsistent_settings", Severity: SeverityWarning,
+			Scope:   FindingScope{Type: ScopeDatabase, Database: database},
+			Summary: "同一 database 在各成员上的 profiler 设置不一致",
+			Evidence: map[string]any{
+				"levels": joinedProfilerValues(levels), "slowMillis": joinedProfilerValues(slowMillis), "sampleRates": joinedProfilerValues(sampleRates),
+				"nodes": len(byDatabase[database]), "variants": len(variants),
+			},
+			Recommendation: "使用 mot profiler enable/disable 统一各成员设置；slowms 与 sampleRate 为节点级设置",
+		})
+	}
+	return findings
+}
+
+func joinedProfilerValues(values map[string]struct{}) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
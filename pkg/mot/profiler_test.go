@@ -0,0 +1,54 @@
+package mot
+
+import (
+	"errors"
+	"testing"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+func TestEvaluateProfilerSettingsFlagsInconsistentMembers(t *testing.T) {
+	// 场景：同一 database 在成员间 level 或 slowms 不同时输出 warning，设置一致的 database 不输出。
+	rate := 0.5
+	settings := []ProfilerSetting{
+		{ReplicaSet: "rs0", Host: "n1", Database: "app", Level: 1, SlowMillis: 100},
+		{ReplicaSet: "rs0", Host: "n2", Database: "app", Level: 0, SlowMillis: 200},
+		{ReplicaSet: "rs0", Host: "n1", Database: "logs", Level: 1, SlowMillis: 100, SampleRate: &rate},
+		{ReplicaSet: "rs0", Host: "n2", Database: "logs", Level: 1, SlowMillis: 100, SampleRate: &rate},
+	}
+	findings := evaluateProfilerSettings(settings)
+	if len(findings) != 1 || findings[0].Code != "profiler.inconsistent_settings" || findings[0].Scope.Database != "app" {
+		t.Fatalf("findings = %#v", findings)
+	}
+	if findings[0].Evidence["levels"] != "0,1" || findings[0].Evidence["slowMillis"] != "100,200" || findings[0].Evidence["nodes"] != 2 {
+		t.Fatalf("evidence = %#v", findings[0].Evidence)
+	}
+}
+
+func TestProfilerChangeOptionsValidationAndNoop(t *testing.T) {
+	// 场景：非法 level、sampleRate 与 database 选择返回 ErrInvalidOptions；与当前设置相同的成员不重复修改。
+	slow := int64(100)
+	rate := 1.5
+	for _, opts := range []ProfilerChangeOptions{
+		{Level: 3, AllDatabases: true},
+		{Level: 1},
+		{Level: 1, AllDatabases: true, ProfilerOptions: ProfilerOptions{Databases: []string{"app"}}},
+		{Level: 1, AllDatabases: true, SampleRate: &rate},
+	} {
+		if err := validateProfilerChange(opts); !errors.Is(err, ErrInvalidOptions) {
+			t.Fatalf("validateProfilerChange(%#v) = %v, want ErrInvalidOptions", opts, err)
+		}
+	}
+
+	current := pkgmongo.ProfilerSetting{Level: 1, SlowMS: 100}
+	if profilerNeedsChange(current, ProfilerChangeOptions{Level: 1, SlowMillis: &slow}) {
+		t.Fatal("identical setting reported as change")
+	}
+	if !profilerNeedsChange(current, ProfilerChangeOptions{Level: 0}) {
+		t.Fatal("level change not detected")
+	}
+	half := 0.5
+	if !profilerNeedsChange(current, ProfilerChangeOptions{Level: 1, SampleRate: &half}) {
+		t.Fatal("sample rate change not detected")
+	}
+}
@@ -0,0 +1,333 @@
+package mot
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+const (
+	defaultQueryStatsShapes    = 50
+	queryStatsMongosPingWindow = 10 * time.Minute
+	queryStatsHMACKeyBytes     = 32
+)
+
+type queryStatsTarget struct {
+	hotspotTarget
+	Role string
+}
+
+// QueryStats 在副本集的每个 mongod 数据节点或分片集群的活跃 mongos 上聚合 $queryStats（7.1+）：标识符使用本次调用
+// 随机生成的 HMAC key 变换，库名、集合名在本地按 catalog 还原；查询形状与字面量不读取，结果按节点输出。
+func (c *Client) QueryStats(ctx context.Context, opts QueryStatsOptions) (result *QueryStatsResult, err error) {
+	if c != nil && c.session == nil {
+		return withEphemeralCollectorSession(ctx, c, func(session *CollectorSession) (*QueryStatsResult, error) {
+			return session.QueryStats(ctx, opts)
+		})
+	}
+	opts, err = normalizeQueryStatsOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.requireMemberConnectionURI(); err != nil {
+		return nil, err
+	}
+	defer func() { err = mapContextError(err) }()
+
+	cluster, err := c.detectCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clusterType := convertClusterType(cluster.Type)
+	if gate, allowed := diagnosticCapabilityGate("query_stats", clusterType, cluster.MaxWireVersion, true); !allowed {
+		return &QueryStatsResult{ClusterType: clusterType, CollectorStatuses: []CollectorStatus{gate}}, nil
+	}
+	hmacKey := make([]byte, queryStatsHMACKeyBytes)
+	if _, err := rand.Read(hmacKey); err != nil {
+		return nil, err
+	}
+	identifiers, collectorStatuses, collectorErrors := c.queryStatsIdentifiers(ctx, hmacKey, opts)
+
+	var members []hotspotTarget
+	var mongos []string
+	if clusterType == ClusterSharded {
+		var mongosErr error
+		mongos, mongosErr = c.conn.ActiveMongos(ctx, time.Now().Add(-queryStatsMongosPingWindow))
+		if mongosErr != nil {
+			if !isUnauthorizedError(mongosErr) {
+				collectorErrors = append(collectorErrors, mongosErr)
+			}
+			collectorStatuses = append(collectorStatuses, failedCollectorStatus("query_stats", FindingScope{Type: ScopeCluster}, mongosErr))
+		}
+		collectorStatuses = append(collectorStatuses, CollectorStatus{
+			Name: "query_stats", State: CapabilitySkipped, Scope: FindingScope{Type: ScopeCluster}, ReasonCode: "mongos_only",
+			Message: "分片集群经 mongos 路由的查询只记录在 mongos 的 $queryStats 中，不采集 shard 成员",
+		})
+	} else {
+		var targetStatuses []CollectorStatus
+		var targetErrors []error
+		members, targetStatuses, targetErrors = c.discoverHotspotTargets(ctx, cluster.Type)
+		collectorStatuses = append(collectorStatuses, targetStatuses...)
+		collectorErrors = append(collectorErrors, targetErrors...)
+	}
+	targets := queryStatsTargets(clusterType, members, mongos)
+	result = &QueryStatsResult{ClusterType: clusterType}
+	if len(targets) == 0 {
+		result.CollectorStatuses = collectorStatuses
+		sortCollectorStatuses(result.CollectorStatuses)
+		if len(collectorErrors) > 0 {
+			return result, newDiagnosticPartialError("query-stats", result, errors.Join(collectorErrors...))
+		}
+		return result, nil
+	}
+
+	var mu sync.Mutex
+	group, groupCtx := errgroup.WithContext(ctx)
+	limit := semaphore.NewWeighted(int64(opts.NodeConcurrency))
+	for _, target := range targets {
+		if acquireErr := acquireDiagnosticSlot(groupCtx, limit); acquireErr != nil {
+			mu.Lock()
+			collectorErrors = append(collectorErrors, acquireErr)
+			mu.Unlock()
+			break
+		}
+		target := target
+		group.Go(func() error {
+			defer limit.Release(1)
+			release, acquireErr := c.acquireRemoteSlot(groupCtx)
+			if acquireErr != nil {
+				mu.Lock()
+				collectorErrors = append(collectorErrors, acquireErr)
+				mu.Unlock()
+				return nil
+			}
+			defer release()
+			scope := FindingScope{Type: ScopeNode, ReplicaSet: target.ReplicaSet, Shard: target.Shard, Node: target.Address}
+			entries, collectErr := c.collectQueryStats(groupCtx, target.Address, hmacKey)
+			mu.Lock()
+			defer mu.Unlock()
+			if collectErr != nil {
+				if !isUnauthorizedError(collectErr) && !isUnsupportedDiagnosticError(collectErr) {
+					collectorErrors = append(collectorErrors, collectErr)
+				}
+				collectorStatuses = append(collectorStatuses, failedCollectorStatus("query_stats", scope, collectErr))
+				return nil
+			}
+			result.Nodes = append(result.Nodes, buildQueryStatsNode(target, entries, identifiers, opts))
+			collectorStatuses = append(collectorStatuses, CollectorStatus{Name: "query_stats", State: CapabilitySupported, Scope: scope})
+			return nil
+		})
+	}
+	_ = group.Wait()
+	sort.SliceStable(result.Nodes, func(i, j int) bool {
+		left, right := result.Nodes[i], result.Nodes[j]
+		if left.Role != right.Role {
+			return left.Role < right.Role
+		}
+		if left.ReplicaSet != right.ReplicaSet {
+			return left.ReplicaSet < right.ReplicaSet
+		}
+		return left.Address < right.Address
+	})
+	result.Findings = queryStatsFindings(result.Nodes)
+	result.CollectorStatuses = collectorStatuses
+	sanitizeAndSortFindings(result.Findings)
+	sortCollectorStatuses(result.CollectorStatuses)
+	if len(collectorErrors) > 0 {
+		return result, newDiagnosticPartialError("query-stats", result, errors.Join(collectorErrors...))
+	}
+	return result, nil
+}
+
+// queryStatsTargets 选择执行 $queryStats 的节点：分片集群只取 mongos，shard 成员上的统计不包含经路由的查询；
+// 副本集与单机取各数据节点。
+func queryStatsTargets(clusterType ClusterType, members []hotspotTarget, mongos []string) []queryStatsTarget {
+	if clusterType == ClusterSharded {
+		targets := make([]queryStatsTarget, 0, len(mongos))
+		for _, address := range mongos {
+			targets = append(targets, queryStatsTarget{hotspotTarget: hotspotTarget{Address: address}, Role: "mongos"})
+		}
+		return targets
+	}
+	targets := make([]queryStatsTarget, 0, len(members))
+	for _, member := range members {
+		targets = append(targets, queryStatsTarget{hotspotTarget: member, Role: "mongod"})
+	}
+	return targets
+}
+
+func (c *Client) collectQueryStats(ctx context.Context, address string, hmacKey []byte) ([]pkgmongo.QueryStatsEntry, error) {
+	conn, err := c.connectAddress(ctx, address, derivedConnectionOptions{Direct: boolPointer(true)})
+	if err != nil {
+		return nil, err
+	}
+	defer c.closeDerivedConnection(ctx, conn)
+	return conn.QueryStats(ctx, hmacKey, 30*time.Second)
+}
+
+func normalizeQueryStatsOptions(opts QueryStatsOptions) (QueryStatsOptions, error) {
+	if opts.MaxShapes < 0 || opts.NodeConcurrency < 0 {
+		return QueryStatsOptions{}, invalidOptions("max shapes and node concurrency must not be negative")
+	}
+	if opts.MaxShapes == 0 {
+		opts.MaxShapes = defaultQueryStatsShapes
+	}
+	if opts.NodeConcurrency == 0 {
+		opts.NodeConcurrency = defaultOverviewNodeConcurrency
+	}
+	return opts, nil
+}
+
+// queryStatsIdentifierMap 记录 HMAC 变换后的库名、集合名到原名的映射。
+type queryStatsIdentifierMap struct {
+	Databases   map[string]string
+	Collections map[string]map[string]string // 原库名 -> 变换后集合名 -> 原集合名
+}
+
+// queryStatsIdentifiers 用 catalog 中的库名与集合名建立还原表；单库失败只记录状态，对应形状保留变换后的标识。
+func (c *Client) queryStatsIdentifiers(ctx context.Context, hmacKey []byte, opts QueryStatsOptions) (queryStatsIdentifierMap, []CollectorStatus, []error) {
+	identifiers := queryStatsIdentifierMap{Databases: map[string]string{}, Collections: map[string]map[string]string{}}
+	databases, err := c.databaseNames(ctx)
+	if err != nil {
+		status := failedCollectorStatus("query_stats", FindingScope{Type: ScopeCluster}, err)
+		if isUnauthorizedError(err) {
+			return identifiers, []CollectorStatus{status}, nil
+		}
+		return identifiers, []CollectorStatus{status}, []error{err}
+	}
+	var statuses []CollectorStatus
+	var collectorErrors []error
+	for _, database := range databases {
+		if !queryStatsDatabaseSelected(database, opts) {
+			continue
+		}
+		collections, metadataErr := c.collectionMetadata(ctx, database)
+		if metadataErr != nil {
+			if !isUnauthorizedError(metadataErr) {
+				collectorErrors = append(collectorErrors, metadataErr)
+			}
+			statuses = append(statuses, failedCollectorStatus("query_stats", FindingScope{Type: ScopeDatabase, Database: database}, metadataErr))
+			continue
+		}
+		addQueryStatsIdentifiers(&identifiers, hmacKey, database, collections)
+	}
+	return identifiers, statuses, collectorErrors
+}
+
+func addQueryStatsIdentifiers(identifiers *queryStatsIdentifierMap, hmacKey []byte, database string, collections []indexCollectionMetadata) {
+	identifiers.Databases[pkgmongo.QueryStatsIdentifier(hmacKey, database)] = database
+	names := make(map[string]string, len(collections))
+	for _, collection := range collections {
+		names[pkgmongo.QueryStatsIdentifier(hmacKey, collection.Name)] = collection.Name
+	}
+	identifiers.Collections[database] = names
+}
+
+func queryStatsDatabaseSelected(database string, opts QueryStatsOptions) bool {
+	if len(opts.Databases) > 0 {
+		return stringIncluded(opts.Databases, database)
+	}
+	return opts.IncludeSystemDB || !isSystemDatabase(database)
+}
+
+// buildQueryStatsNode 把 $queryStats 条目映射为 slowlog 聚合项；无法还原库名的条目仅在未指定库过滤时保留。
+// queryShapeHash/keyHash 与 profiler 的 queryHash 不对应，单独填入 QueryShapeHash/KeyHash，QueryHash 保持为空。
+func buildQueryStatsNode(target queryStatsTarget, entries []pkgmongo.QueryStatsEntry, identifiers queryStatsIdentifierMap, opts QueryStatsOptions) QueryStatsNode {
+	node := QueryStatsNode{Role: target.Role, ReplicaSet: target.ReplicaSet, Shard: target.Shard, Address: target.Address}
+	for _, entry := range entries {
+		database, resolved := identifiers.Databases[entry.Database]
+		if !resolved {
+			if len(opts.Databases) > 0 {
+				continue
+			}
+			database = entry.Database
+		} else if !queryStatsDatabaseSelected(database, opts) {
+			continue
+		}
+		collection, ok := identifiers.Collections[database][entry.Collection]
+		if !ok {
+			collection = entry.Collection
+		}
+		item := SlowlogSummaryItem{
+			Namespace: database + "." + collection, Operation: entry.Command, QueryShapeHash: entry.QueryShapeHash, KeyHash: entry.KeyHash,
+			Count: entry.ExecCount, TotalMillis: entry.TotalExecMicros / 1000,
+			MaxMillis: entry.MaxExecMicros / 1000, MinMillis: entry.MinExecMicros / 1000,
+			MaxKeysExamined: entry.MaxKeysExamined, MaxDocsExamined: entry.MaxDocsExamined, MaxDocsReturned: entry.MaxDocsReturned,
+			FirstTime: entry.FirstSeen, LastTime: entry.LatestSeen,
+		}
+		if entry.MaxDocsReturned != nil {
+			item.MaxDocs = *entry.MaxDocsReturned
+		}
+		if entry.ExecCount > 0 {
+			item.AvgMillis = float64(entry.TotalExecMicros) / 1000 / float64(entry.ExecCount)
+		}
+		item.WorstDocsToReturned = safeExaminedRatio(entry.MaxDocsExamined, entry.MaxDocsReturned)
+		item.WorstKeysToReturned = safeExaminedRatio(entry.MaxKeysExamined, entry.MaxDocsReturned)
+		node.Total += item.Count
+		node.TotalMillis += item.TotalMillis
+		node.Items = append(node.Items, item)
+	}
+	for i := range node.Items {
+		if node.TotalMillis > 0 {
+			share := float64(node.Items[i].TotalMillis) / float64(node.TotalMillis)
+			node.Items[i].TimeShare = &share
+		}
+	}
+	sort.SliceStable(node.Items, func(i, j int) bool {
+		left, right := node.Items[i], node.Items[j]
+		if left.TotalMillis != right.TotalMillis {
+			return left.TotalMillis > right.TotalMillis
+		}
+		if left.Namespace != right.Namespace {
+			return left.Namespace < right.Namespace
+		}
+		return left.KeyHash < right.KeyHash
+	})
+	if len(node.Items) > opts.MaxShapes {
+		node.Items = node.Items[:opts.MaxShapes]
+	}
+	return node
+}
+
+// queryStatsFindings 标记占节点累计耗时一半以上的查询形状；单个形状主导耗时时优先优化它收益最大。
+func queryStatsFindings(nodes []QueryStatsNode) []DiagnosticFinding {
+	var findings []DiagnosticFinding
+	for _, node := range nodes {
+		for _, item := range node.Items {
+			if item.TimeShare == nil || *item.TimeShare < 0.5 || item.Count < 2 {
+				continue
+			}
+			findings = append(findings, DiagnosticFinding{
+				Code: "query_stats.dominant_shape", Severity: SeverityInfo,
+				Scope:          FindingScope{Type: ScopeNamespace, ReplicaSet: node.ReplicaSet, Shard: node.Shard, Node: node.Address, Namespace: item.Namespace},
+				Summary:        fmt.Sprintf("单个查询形状占 %s 节点累计执行耗时的 %.0f%%", node.Role, *item.TimeShare*100),
+				Evidence:       queryStatsShapeEvidence(item, node.TotalMillis),
+				Recommendation: "优先检查该查询形状的执行计划与索引；queryShapeHash 可在 8.0+ 的慢查询日志中检索样本，它与 profiler 的 queryHash 不同，不能用于 slowlog --hash",
+			})
+		}
+	}
+	return findings
+}
+
+func queryStatsShapeEvidence(item SlowlogSummaryItem, nodeTotalMillis int64) map[string]any {
+	evidence := map[string]any{
+		"keyHash": item.KeyHash, "operation": item.Operation, "execCount": item.Count,
+		"totalMillis": item.TotalMillis, "nodeTotalMillis": nodeTotalMillis,
+	}
+	if item.QueryShapeHash != "" {
+		evidence["queryShapeHash"] = item.QueryShapeHash
+	}
+	return evidence
+}
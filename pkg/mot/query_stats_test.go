@@ -0,0 +1,78 @@
+package mot
+
+import (
+	"testing"
+	"time"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+func TestBuildQueryStatsNodeResolvesIdentifiersAndRanksShapes(t *testing.T) {
+	// 场景：HMAC 变换后的库名、集合名在本地还原，条目映射为 slowlog 聚合项并按累计耗时截取；
+	// 指定库过滤时无法还原的条目被丢弃，系统库默认排除。
+	key := []byte("0123456789abcdef0123456789abcdef")
+	identifiers := queryStatsIdentifierMap{Databases: map[string]string{}, Collections: map[string]map[string]string{}}
+	addQueryStatsIdentifiers(&identifiers, key, "app", []indexCollectionMetadata{{Name: "orders"}, {Name: "users"}})
+	addQueryStatsIdentifiers(&identifiers, key, "admin", []indexCollectionMetadata{{Name: "system.users"}})
+	hash := func(value string) string { return pkgmongo.QueryStatsIdentifier(key, value) }
+	examined, returned := int64(400), int64(4)
+	seen := time.Date(2026, 7, 20, 8, 0, 0, 0, time.UTC)
+	entries := []pkgmongo.QueryStatsEntry{
+		{KeyHash: "K1", QueryShapeHash: "S1", Database: hash("app"), Collection: hash("orders"), Command: "find", ExecCount: 4, TotalExecMicros: 3_000_000, MaxExecMicros: 1_500_000, MinExecMicros: 100_000, MaxDocsExamined: &examined, MaxDocsReturned: &returned, FirstSeen: seen, LatestSeen: seen},
+		{KeyHash: "K2", Database: hash("app"), Collection: hash("users"), Command: "aggregate", ExecCount: 10, TotalExecMicros: 1_000_000},
+		{KeyHash: "K3", Database: hash("admin"), Collection: hash("system.users"), Command: "find", ExecCount: 1, TotalExecMicros: 9_000_000},
+		{KeyHash: "K4", Database: "unknown-db", Collection: "unknown-coll", Command: "distinct", ExecCount: 1, TotalExecMicros: 500_000},
+	}
+	target := queryStatsTarget{hotspotTarget: hotspotTarget{ReplicaSet: "rs0", Address: "n1:27017"}, Role: "mongod"}
+
+	node := buildQueryStatsNode(target, entries, identifiers, QueryStatsOptions{MaxShapes: 2})
+	if node.Role != "mongod" || node.Total != 15 || node.TotalMillis != 4500 || len(node.Items) != 2 {
+		t.Fatalf("node = %#v", node)
+	}
+	first := node.Items[0]
+	if first.Namespace != "app.orders" || first.QueryShapeHash != "S1" || first.KeyHash != "K1" || first.QueryHash != "" || first.Operation != "find" || first.Count != 4 || first.TotalMillis != 3000 || first.MaxMillis != 1500 || first.MinMillis != 100 || first.AvgMillis != 750 {
+		t.Fatalf("first item = %#v", first)
+	}
+	if first.MaxDocs != 4 || first.WorstDocsToReturned == nil || *first.WorstDocsToReturned != 100 || first.TimeShare == nil || *first.TimeShare < 0.66 || !first.LastTime.Equal(seen) {
+		t.Fatalf("first item metrics = %#v", first)
+	}
+	if node.Items[1].Namespace != "app.users" || node.Items[1].KeyHash != "K2" || node.Items[1].QueryShapeHash != "" {
+		t.Fatalf("second item = %#v", node.Items[1])
+	}
+
+	filtered := buildQueryStatsNode(target, entries, identifiers, QueryStatsOptions{Databases: []string{"app"}, MaxShapes: 10})
+	if len(filtered.Items) != 2 {
+		t.Fatalf("filtered items = %#v", filtered.Items)
+	}
+	all := buildQueryStatsNode(target, entries, identifiers, QueryStatsOptions{MaxShapes: 10})
+	if len(all.Items) != 3 || all.Items[2].Namespace != "unknown-db.unknown-coll" {
+		t.Fatalf("unfiltered items = %#v", all.Items)
+	}
+	if findings := queryStatsFindings([]QueryStatsNode{node}); len(findings) != 1 || findings[0].Code != "query_stats.dominant_shape" || findings[0].Scope.Namespace != "app.orders" ||
+		findings[0].Evidence["queryShapeHash"] != "S1" || findings[0].Evidence["queryHash"] != nil || findings[0].Evidence["keyHash"] != "K1" {
+		t.Fatalf("findings = %#v", findings)
+	}
+}
+
+func TestQueryStatsGateRejectsServersBefore71(t *testing.T) {
+	// 场景：$queryStats 需要 7.1（wire 22），更低版本通过 capability gate 返回 unsupported。
+	if status, allowed := diagnosticCapabilityGate("query_stats", ClusterSharded, 21, true); allowed || status.State != CapabilityUnsupported || status.ReasonCode != "unsupported_version" {
+		t.Fatalf("7.0 gate = %#v allowed=%t", status, allowed)
+	}
+	if _, allowed := diagnosticCapabilityGate("query_stats", ClusterReplicaSet, 22, true); !allowed {
+		t.Fatal("7.1 replica set was rejected")
+	}
+}
+
+func TestQueryStatsTargetsUseOnlyMongosInShardedClusters(t *testing.T) {
+	// 场景：分片集群只在 mongos 上采集 $queryStats，副本集采集各数据节点。
+	members := []hotspotTarget{{Address: "shard01-a:27018", ReplicaSet: "shard01", Shard: "shard01"}}
+	sharded := queryStatsTargets(ClusterSharded, members, []string{"router:27017"})
+	if len(sharded) != 1 || sharded[0].Role != "mongos" || sharded[0].Address != "router:27017" {
+		t.Fatalf("sharded targets = %#v", sharded)
+	}
+	replicaSet := queryStatsTargets(ClusterReplicaSet, members, nil)
+	if len(replicaSet) != 1 || replicaSet[0].Role != "mongod" || replicaSet[0].Address != "shard01-a:27018" {
+		t.Fatalf("replica set targets = %#v", replicaSet)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
 )
 
 type ClusterType string
@@ -83,6 +85,8 @@ const (
 	SlowlogSortCount     SlowlogSort = "cnt"
 	SlowlogSortMaxMillis SlowlogSort = "maxMills"
 	SlowlogSortMaxDocs   SlowlogSort = "maxDocs"
+	SlowlogSortP95       SlowlogSort = "p95"
+	SlowlogSortTotal     SlowlogSort = "totalMillis"
 )
 
 type SlowlogOptions struct {
@@ -90,6 +94,15 @@ type SlowlogOptions struct {
 	Sort        SlowlogSort
 	QueryHash   string
 	Concurrency int
+	GetLog      bool // 额外读取各成员 getLog 内存缓冲，补齐未开启 profiler 的 database
+
+	// 以下过滤条件以 $match 下推到 $group 之前；零值表示不过滤。
+	Since      time.Time
+	Until      time.Time
+	Namespaces []string
+	Operations []string
+	AppNames   []string
+	MinMillis  int64
 }
 
 type SlowlogSummaryResult struct {
@@ -111,12 +124,13 @@ type HostSlowlogSummary struct {
 }
 
 type DatabaseSlowlogSummary struct {
-	Database  string               `json:"database"`
-	Total     int64                `json:"total"`
-	FirstTime time.Time            `json:"firstTime"`
-	LastTime  time.Time            `json:"lastTime"`
-	Items     []SlowlogSummaryItem `json:"items"`
-	Findings  []DiagnosticFinding  `json:"findings,omitempty"`
+	Database    string               `json:"database"`
+	Total       int64                `json:"total"`
+	TotalMillis int64                `json:"totalMillis"`
+	FirstTime   time.Time            `json:"firstTime"`
+	LastTime    time.Time            `json:"lastTime"`
+	Items       []SlowlogSummaryItem `json:"items"`
+	Findings    []DiagnosticFinding  `json:"findings,omitempty"`
 }
 
 type SlowlogSummaryItem struct {
@@ -130,6 +144,18 @@ type SlowlogSummaryItem struct {
 	FirstTime time.Time `json:"firstTime"`
 	LastTime  time.Time `json:"lastTime"`
 
+	// QueryShapeHash 与 KeyHash 只由 $queryStats 填充，与 profiler 的 queryHash 不是同一标识，此时 QueryHash 为空。
+	QueryShapeHash string `json:"queryShapeHash,omitempty"`
+	KeyHash        string `json:"keyHash,omitempty"`
+
+	// 耗时分布：7.0+ 使用 $percentile，低版本使用有界 histogram 估算，两者都保留 bucket 供合并；TimeShare 为占该库慢操作总耗时的比例。
+	TotalMillis int64    `json:"totalMillis"`
+	AvgMillis   float64  `json:"avgMillis"`
+	P50Millis   *int64   `json:"p50Millis,omitempty"`
+	P95Millis   *int64   `json:"p95Millis,omitempty"`
+	P99Millis   *int64   `json:"p99Millis,omitempty"`
+	TimeShare   *float64 `json:"timeShare,omitempty"`
+
 	PlanSummary         string   `json:"planSummary,omitempty"`
 	MaxKeysExamined     *int64   `json:"maxKeysExamined,omitempty"`
 	MaxDocsExamined     *int64   `json:"maxDocsExamined,omitempty"`
@@ -141,12 +167,408 @@ type SlowlogSummaryItem struct {
 	AppNames            []string `json:"appNames,omitempty"`
 	ErrorCount          int64    `json:"errorCount,omitempty"`
 	CollectionScanCount int64    `json:"collectionScanCount,omitempty"`
+	SortStageCount      int64    `json:"sortStageCount,omitempty"`
+
+	// millisHistogram 是低版本与日志路径的耗时 bucket 计数，只在进程内用于跨 host 合并 percentile。
+	millisHistogram []pkgmongo.SlowlogMillisBucket
+}
+
+// SlowlogMergeMode 描述 slowlog 结果的合并粒度。
+type SlowlogMergeMode string
+
+const SlowlogMergeCluster SlowlogMergeMode = "cluster"
+
+// MergedSlowlogSummary 是跨 host/shard 折叠同一查询形状后的集群级视图。
+type MergedSlowlogSummary struct {
+	ClusterType       ClusterType         `json:"clusterType"`
+	Merge             SlowlogMergeMode    `json:"merge"`
+	Sort              SlowlogSort         `json:"sort"`
+	Total             int64               `json:"total"`
+	TotalMillis       int64               `json:"totalMillis"`
+	Items             []MergedSlowlogItem `json:"items"`
+	Findings          []DiagnosticFinding `json:"findings,omitempty"`
+	CollectorStatuses []CollectorStatus   `json:"collectorStatuses,omitempty"`
+}
+
+// SlowlogPercentileSource 描述合并视图中 percentile 的来源。
+type SlowlogPercentileSource string
+
+const (
+	// SlowlogPercentileHistogram 表示 p50/p95/p99 取自各来源耗时 bucket 合并后的分布。
+	SlowlogPercentileHistogram SlowlogPercentileSource = "histogram"
+	// SlowlogPercentileHostMax 表示至少一个来源缺少耗时 bucket（例如本地快照），无法还原集群分布，
+	// p50/p95/p99 取各 host 的最大值，HostP95MinMillis 给出各 host p95 的下界。
+	SlowlogPercentileHostMax SlowlogPercentileSource = "host_max"
+)
+
+// MergedSlowlogItem 在聚合字段之外列出贡献该查询形状的副本集（分片）与节点。
+type MergedSlowlogItem struct {
+	SlowlogSummaryItem
+	ReplicaSets      []string                `json:"replicaSets"`
+	Hosts            []string                `json:"hosts"`
+	PercentileSource SlowlogPercentileSource `json:"percentileSource,omitempty"`
+	HostP95MinMillis *int64                  `json:"hostP95MinMillis,omitempty"`
+	// AppNamesTruncated 是合并后超过 maxMergedSlowlogAppNames 而省略的 appName 数量。
+	AppNamesTruncated int `json:"appNamesTruncated,omitempty"`
+}
+
+// SlowlogDigestOptions 在 slowlog 过滤条件之上限制 digest 报告的查询形状数量；Sort 为空时按累计耗时排名。
+type SlowlogDigestOptions struct {
+	SlowlogOptions
+	MaxQueries int // 默认 20
+}
+
+// SlowlogDigestResult 是 pt-query-digest 风格的报告模型：全局时间窗与节点分布，加上按排名的查询形状。
+type SlowlogDigestResult struct {
+	ClusterType       ClusterType          `json:"clusterType"`
+	Since             *time.Time           `json:"since,omitempty"`
+	Until             *time.Time           `json:"until,omitempty"`
+	FirstTime         time.Time            `json:"firstTime"`
+	LastTime          time.Time            `json:"lastTime"`
+	Total             int64                `json:"total"`
+	TotalMillis       int64                `json:"totalMillis"`
+	UniqueShapes      int                  `json:"uniqueShapes"`
+	Hosts             []SlowlogDigestHost  `json:"hosts"`
+	Queries           []SlowlogDigestQuery `json:"queries"`
+	Findings          []DiagnosticFinding  `json:"findings,omitempty"`
+	CollectorStatuses []CollectorStatus    `json:"collectorStatuses,omitempty"`
+}
+
+// SlowlogDigestHost 是单个节点贡献的慢操作次数与累计耗时。
+type SlowlogDigestHost struct {
+	ReplicaSet  string `json:"replicaSet"`
+	Host        string `json:"host"`
+	Count       int64  `json:"count"`
+	TotalMillis int64  `json:"totalMillis"`
+}
+
+// SlowlogDigestQuery 是排名后的查询形状；ExampleCommand 为最新 profile 样本中取值替换为 "?" 的命令。
+type SlowlogDigestQuery struct {
+	Rank int `json:"rank"`
+	MergedSlowlogItem
+	// LatencyHistogram 是按十倍程区间统计的耗时次数；来源缺少 bucket（percentile 为 host_max）时为空。
+	LatencyHistogram []SlowlogLatencyBucket `json:"latencyHistogram,omitempty"`
+	ExampleCommand   string                 `json:"exampleCommand,omitempty"`
+}
+
+// SlowlogLatencyBucket 是 [LowerMillis, UpperMillis) 区间内的慢操作次数；UpperMillis 为 0 表示没有上界。
+type SlowlogLatencyBucket struct {
+	LowerMillis int64 `json:"lowerMillis"`
+	UpperMillis int64 `json:"upperMillis,omitempty"`
+	Count       int64 `json:"count"`
+}
+
+// QueryStatsOptions 控制 $queryStats 采集；Databases 为空时保留所有非系统库的查询形状。
+type QueryStatsOptions struct {
+	Databases       []string
+	IncludeSystemDB bool
+	MaxShapes       int // 每个节点按累计耗时保留前 N 个查询形状，默认 50
+	NodeConcurrency int
+}
+
+// QueryStatsResult 按节点列出 $queryStats 查询形状；Items 与 slowlog 聚合项同构，不依赖 profiler。
+type QueryStatsResult struct {
+	ClusterType       ClusterType         `json:"clusterType"`
+	Nodes             []QueryStatsNode    `json:"nodes"`
+	Findings          []DiagnosticFinding `json:"findings,omitempty"`
+	CollectorStatuses []CollectorStatus   `json:"collectorStatuses,omitempty"`
+}
+
+// QueryStatsNode 是单个 mongos 或 mongod 上的查询形状统计；Role 为 mongos 或 mongod。
+type QueryStatsNode struct {
+	Role        string               `json:"role"`
+	ReplicaSet  string               `json:"replicaSet,omitempty"`
+	Shard       string               `json:"shard,omitempty"`
+	Address     string               `json:"address"`
+	Total       int64                `json:"total"`
+	TotalMillis int64                `json:"totalMillis"`
+	Items       []SlowlogSummaryItem `json:"items"`
+}
+
+// ScatterGatherOptions 控制 mongos 慢日志的广播查询分析；过滤条件与 slowlog 相同，零值表示不过滤。
+type ScatterGatherOptions struct {
+	Databases       []string
+	Namespaces      []string
+	Operations      []string
+	Since           time.Time
+	Until           time.Time
+	MinExecutions   int64   // 可判定路由范围的执行次数达到该值才输出 finding，默认 5
+	BroadcastRatio  float64 // 广播执行占比达到该值才输出 finding，默认 0.5
+	NodeConcurrency int
+}
+
+// ScatterGatherResult 按查询形状汇总 mongos 慢日志中的路由范围，只包含已分片且分布在多个分片上的集合。
+type ScatterGatherResult struct {
+	ClusterType       ClusterType          `json:"clusterType"`
+	Routers           []string             `json:"routers"`
+	Shapes            []ScatterGatherShape `json:"shapes"`
+	Findings          []DiagnosticFinding  `json:"findings,omitempty"`
+	CollectorStatuses []CollectorStatus    `json:"collectorStatuses,omitempty"`
+}
+
+// ScatterGatherShape 是单个查询形状的路由统计：nShards 达到集合所在分片数的执行计为广播，
+// 日志未记录 nShards 的执行计入 Unknown，不参与占比计算。
+type ScatterGatherShape struct {
+	Namespace           string          `json:"namespace"`
+	Operation           string          `json:"operation"`
+	QueryShapeHash      string          `json:"queryShapeHash,omitempty"`
+	PredicateFields     []string        `json:"predicateFields,omitempty"`
+	ShardKey            []IndexKeyField `json:"shardKey"`
+	ShardKeyPrefixUsed  bool            `json:"shardKeyPrefixUsed"`
+	ShardCount          int             `json:"shardCount"`
+	Executions          int64           `json:"executions"`
+	Targeted            int64           `json:"targeted"`
+	Broadcast           int64           `json:"broadcast"`
+	Unknown             int64           `json:"unknown,omitempty"`
+	BroadcastRatio      *float64        `json:"broadcastRatio,omitempty"`
+	MaxShards           int64           `json:"maxShards"`
+	TotalMillis         int64           `json:"totalMillis"`
+	QueryStatsExecCount *int64          `json:"queryStatsExecCount,omitempty"` // 8.0+ 按 queryShapeHash 关联的 mongos 总执行次数，含未达慢日志阈值的执行
+}
+
+// SlowlogSnapshot 是 slowlog --snapshot 写出的离线快照；Summary 只包含查询形状聚合，不含查询取值。
+type SlowlogSnapshot struct {
+	SchemaVersion int                  `json:"schemaVersion"`
+	CollectedAt   time.Time            `json:"collectedAt"`
+	Since         *time.Time           `json:"since,omitempty"`
+	Until         *time.Time           `json:"until,omitempty"`
+	Summary       SlowlogSummaryResult `json:"summary"`
+}
+
+// SlowlogDiffOptions 设置回归判定阈值；零值使用默认值。
+type SlowlogDiffOptions struct {
+	MinCount     int64   // after 窗口中次数低于该值的形状不参与判定，默认 5
+	CountRatio   float64 // 每小时次数增长倍数阈值（时间窗未知时比较原始次数），默认 2
+	LatencyRatio float64 // avg 或 p95 耗时增长倍数阈值，默认 1.5
+	DocsRatio    float64 // maxDocsExamined 增长倍数阈值，默认 2
+}
+
+// SlowlogDiffResult 按 namespace/queryHash/op 比较两个 slowlog 快照；同一形状的计划变化单独报告。
+// BeforeWindowHours/AfterWindowHours 是用于把次数换算为每小时速率的时间窗长度，无法确定时省略。
+type SlowlogDiffResult struct {
+	SchemaVersion     int                 `json:"schemaVersion"`
+	ClusterType       ClusterType         `json:"clusterType"`
+	BeforeCollectedAt time.Time           `json:"beforeCollectedAt"`
+	AfterCollectedAt  time.Time           `json:"afterCollectedAt"`
+	BeforeWindowHours *float64            `json:"beforeWindowHours,omitempty"`
+	AfterWindowHours  *float64            `json:"afterWindowHours,omitempty"`
+	Added             int                 `json:"added"`
+	Removed           int                 `json:"removed"`
+	Regressed         int                 `json:"regressed"`
+	Items             []SlowlogShapeDiff  `json:"items"`
+	Findings          []DiagnosticFinding `json:"findings,omitempty"`
+}
+
+// SlowlogShapeDiff 是单个查询形状的前后对比；State 为 added、removed 或 existing。
+// PlanSummary 是次数最多的计划，主计划在两个快照间变化时 PreviousPlanSummary 记录 before 的主计划。
+type SlowlogShapeDiff struct {
+	Namespace           string            `json:"namespace"`
+	Operation           string            `json:"operation"`
+	QueryHash           string            `json:"queryHash"`
+	PlanSummary         string            `json:"planSummary,omitempty"`
+	PreviousPlanSummary string            `json:"previousPlanSummary,omitempty"`
+	State               string            `json:"state"`
+	Count               SlowlogDelta      `json:"count"`
+	CountPerHour        *SlowlogRateDelta `json:"countPerHour,omitempty"`
+	AvgMillis           SlowlogDelta      `json:"avgMillis"`
+	P95Millis           SlowlogDelta      `json:"p95Millis"`
+	MaxDocsExamined     SlowlogDelta      `json:"maxDocsExamined"`
+	Regressions         []string          `json:"regressions,omitempty"`
+}
+
+// SlowlogDelta 记录指标前后值；任一侧不可用时不计算 Delta，before 为 0 时不计算 Ratio。
+type SlowlogDelta struct {
+	Before *int64   `json:"before,omitempty"`
+	After  *int64   `json:"after,omitempty"`
+	Delta  *int64   `json:"delta,omitempty"`
+	Ratio  *float64 `json:"ratio,omitempty"`
+}
+
+// SlowlogRateDelta 记录按各自时间窗换算的每小时次数；before 为 0 时不计算 Ratio。
+type SlowlogRateDelta struct {
+	Before *float64 `json:"before,omitempty"`
+	After  *float64 `json:"after,omitempty"`
+	Ratio  *float64 `json:"ratio,omitempty"`
+}
+
+// SlowlogAdviseOptions 在 slowlog 过滤条件之上限制参与索引建议的查询形状数量。
+type SlowlogAdviseOptions struct {
+	SlowlogOptions
+	MaxCandidates     int     // 按累计耗时取前 N 个问题查询形状，默认 20
+	DocsExaminedRatio float64 // docsExamined/nreturned 达到该比值视为扫描放大，默认 100
+}
+
+// SlowlogAdviseResult 汇总按 ESR 规则推导的候选索引；每条建议同时以 finding 输出。
+type SlowlogAdviseResult struct {
+	ClusterType       ClusterType         `json:"clusterType"`
+	Suggestions       []IndexSuggestion   `json:"suggestions"`
+	Findings          []DiagnosticFinding `json:"findings,omitempty"`
+	CollectorStatuses []CollectorStatus   `json:"collectorStatuses,omitempty"`
+}
+
+// IndexSuggestion 只包含查询形状的字段名与排序方向，不保留任何查询取值。
+type IndexSuggestion struct {
+	Namespace   string          `json:"namespace"`
+	QueryHash   string          `json:"queryHash"`
+	Operation   string          `json:"operation"`
+	Count       int64           `json:"count"`
+	TotalMillis int64           `json:"totalMillis"`
+	Reasons     []string        `json:"reasons"`
+	Equality    []string        `json:"equality,omitempty"`
+	Sort        []IndexKeyField `json:"sort,omitempty"`
+	Range       []string        `json:"range,omitempty"`
+	ProposedKey []IndexKeyField `json:"proposedKey"`
+	Supersedes  []string        `json:"supersedes,omitempty"`
+}
+
+// PlanCacheOptions 在 slowlog 过滤条件之上选择要检查 plan cache 的查询形状。
+type PlanCacheOptions struct {
+	SlowlogOptions
+	QueryHash       string // 非空时只检查该 queryHash（或 planCacheKey）；无 slowlog 聚合组时按 Namespaces 检查
+	MaxGroups       int    // QueryHash 为空时按累计耗时取前 N 个查询形状，默认 20
+	NodeConcurrency int
+}
+
+// PlanCacheResult 按 slowlog 查询形状汇总各数据节点 $planCacheStats 中的缓存计划。
+type PlanCacheResult struct {
+	ClusterType       ClusterType         `json:"clusterType"`
+	Groups            []PlanCacheGroup    `json:"groups"`
+	Findings          []DiagnosticFinding `json:"findings,omitempty"`
+	CollectorStatuses []CollectorStatus   `json:"collectorStatuses,omitempty"`
+}
+
+// PlanCacheGroup 是单个查询形状在所有节点上的 plan cache 条目；DistinctPlans 按计划签名去重计数。
+type PlanCacheGroup struct {
+	Namespace          string           `json:"namespace"`
+	QueryHash          string           `json:"queryHash"`
+	Operation          string           `json:"operation,omitempty"`
+	SlowlogCount       int64            `json:"slowlogCount"`
+	SlowlogTotalMillis int64            `json:"slowlogTotalMillis"`
+	Entries            []PlanCacheEntry `json:"entries"`
+	DistinctPlans      int              `json:"distinctPlans"`
+}
+
+// PlanCacheEntry 只保留缓存计划的结构摘要，不包含 createdFromQuery 中的查询取值。
+type PlanCacheEntry struct {
+	ReplicaSet     string      `json:"replicaSet,omitempty"`
+	Shard          string      `json:"shard,omitempty"`
+	Host           string      `json:"host"`
+	QueryHash      string      `json:"queryHash,omitempty"`
+	PlanCacheKey   string      `json:"planCacheKey,omitempty"`
+	IsActive       bool        `json:"isActive"`
+	Works          *int64      `json:"works,omitempty"`
+	TimeOfCreation time.Time   `json:"timeOfCreation"`
+	CachedPlan     ExplainPlan `json:"cachedPlan"`
+	PlanSignature  string      `json:"planSignature"`
+}
+
+type ProfilerOptions struct {
+	Databases       []string // 为空时读取每个成员上的全部非系统库
+	IncludeSystemDB bool
+	NodeConcurrency int
+}
+
+// ProfilerChangeOptions 描述跨成员修改 profiler 的目标；Level 为 0 表示关闭。
+type ProfilerChangeOptions struct {
+	ProfilerOptions
+	AllDatabases bool     // 与 Databases 二选一，修改每个成员上的全部非系统库
+	Level        int      // 0 关闭，1 只记录慢操作，2 记录全部操作
+	SlowMillis   *int64   // nil 表示保持不变；mongod 进程级设置
+	SampleRate   *float64 // nil 表示保持不变；mongod 进程级设置，3.6+
+	DryRun       bool     // 只读取当前设置并报告将要做的修改
+}
+
+type ProfilerAction string
+
+const (
+	ProfilerActionStatus  ProfilerAction = "status"
+	ProfilerActionEnable  ProfilerAction = "enable"
+	ProfilerActionDisable ProfilerAction = "disable"
+)
+
+// ProfilerResult 按成员与 database 列出 profiler 设置；变更的逐节点结果记录在 CollectorStatuses 中。
+type ProfilerResult struct {
+	ClusterType       ClusterType         `json:"clusterType"`
+	Action            ProfilerAction      `json:"action"`
+	DryRun            bool                `json:"dryRun,omitempty"`
+	Settings          []ProfilerSetting   `json:"settings"`
+	Findings          []DiagnosticFinding `json:"findings,omitempty"`
+	CollectorStatuses []CollectorStatus   `json:"collectorStatuses,omitempty"`
+}
+
+// ProfilerSetting 是单个成员上单个 database 的 profiler 设置；实际修改后 PreviousLevel 为修改前的 level。
+type ProfilerSetting struct {
+	ReplicaSet    string   `json:"replicaSet,omitempty"`
+	Shard         string   `json:"shard,omitempty"`
+	Host          string   `json:"host"`
+	Database      string   `json:"database"`
+	Level         int      `json:"level"`
+	SlowMillis    int64    `json:"slowMillis"`
+	SampleRate    *float64 `json:"sampleRate,omitempty"`
+	Filter        string   `json:"filter,omitempty"`
+	PreviousLevel *int     `json:"previousLevel,omitempty"`
 }
 
 type SlowlogDetailResult struct {
-	Namespace string   `json:"namespace"`
-	Slowlog   bson.M   `json:"slowlog"`
-	Indexes   []bson.M `json:"indexes"`
+	Namespace string          `json:"namespace"`
+	Slowlog   bson.M          `json:"slowlog"`
+	Indexes   []bson.M        `json:"indexes"`
+	Explain   *SlowlogExplain `json:"explain,omitempty"`
+
+	// orderedSlowlog/orderedIndexes 的嵌套文档保留为 bson.D，只供 explain、advise 与 digest 解析字段顺序。
+	orderedSlowlog bson.M
+	orderedIndexes []bson.M
+}
+
+// ExplainVerbosity 是 slowlog explain 重放的详细程度；executionStats 会真实执行查询，需显式开启。
+type ExplainVerbosity string
+
+const (
+	ExplainQueryPlanner   ExplainVerbosity = "queryPlanner"
+	ExplainExecutionStats ExplainVerbosity = "executionStats"
+)
+
+// SlowlogDetailOptions 控制详情查询的附加采集；零值与 SlowlogDetail 行为一致。
+type SlowlogDetailOptions struct {
+	Explain ExplainVerbosity // 为空时不执行 explain
+}
+
+// SlowlogExplain 是对 profile 中命令形状重新 explain 后的结构化摘要，不包含查询取值。
+type SlowlogExplain struct {
+	Verbosity      ExplainVerbosity    `json:"verbosity"`
+	WinningPlan    ExplainPlan         `json:"winningPlan"`
+	RejectedPlans  []ExplainPlan       `json:"rejectedPlans,omitempty"`
+	ExecutionStats *ExplainStats       `json:"executionStats,omitempty"`
+	Findings       []DiagnosticFinding `json:"findings,omitempty"`
+}
+
+// ExplainPlan 按先序遍历列出 plan 树的 stage，并汇总索引扫描与内存排序。
+type ExplainPlan struct {
+	Stages         []string           `json:"stages"`
+	IndexScans     []ExplainIndexScan `json:"indexScans,omitempty"`
+	InMemorySort   bool               `json:"inMemorySort"`
+	CollectionScan bool               `json:"collectionScan"`
+}
+
+type ExplainIndexScan struct {
+	IndexName string              `json:"indexName"`
+	Key       []IndexKeyField     `json:"key"`
+	Bounds    []ExplainIndexBound `json:"bounds,omitempty"`
+}
+
+// ExplainIndexBound 只描述单个字段的区间数量与类型：point、range 或 full（MinKey 到 MaxKey）。
+type ExplainIndexBound struct {
+	Field     string `json:"field"`
+	Intervals int    `json:"intervals"`
+	Kind      string `json:"kind"`
+}
+
+type ExplainStats struct {
+	NReturned           int64    `json:"nReturned"`
+	ExecutionTimeMillis int64    `json:"executionTimeMillis"`
+	TotalKeysExamined   int64    `json:"totalKeysExamined"`
+	TotalDocsExamined   int64    `json:"totalDocsExamined"`
+	FetchRatio          *float64 `json:"fetchRatio,omitempty"`
 }
 
 type BulkOptions struct {
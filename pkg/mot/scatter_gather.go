@@ -0,0 +1,431 @@
+package mot
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+const (
+	defaultScatterGatherMinExecutions  = 5
+	defaultScatterGatherBroadcastRatio = 0.5
+	// scatterGatherConnectedRouter 表示无法读取 config.mongos 时退回到当前连接的 mongos。
+	scatterGatherConnectedRouter = "connected"
+)
+
+type scatterGatherKey struct {
+	namespace       string
+	operation       string
+	queryShapeHash  string
+	predicateFields string
+}
+
+type scatterGatherGroup struct {
+	key         scatterGatherKey
+	executions  int64
+	totalMillis int64
+	nShards     map[int64]int64
+	unknown     int64
+}
+
+// scatterGatherAggregator 按 namespace、operation、queryShapeHash 与过滤字段合并 mongos 慢日志，
+// 保留 nShards 分布，待读取 routing metadata 后再判定广播与定向执行。
+type scatterGatherAggregator struct {
+	groups map[scatterGatherKey]*scatterGatherGroup
+}
+
+func newScatterGatherAggregator() *scatterGatherAggregator {
+	return &scatterGatherAggregator{groups: make(map[scatterGatherKey]*scatterGatherGroup)}
+}
+
+func (a *scatterGatherAggregator) add(entry pkgmongo.SlowlogEntry) {
+	key := scatterGatherKey{namespace: entry.Ns, operation: entry.Op, queryShapeHash: entry.QueryShapeHash, predicateFields: strings.Join(entry.PredicateFields, ",")}
+	group := a.groups[key]
+	if group == nil {
+		group = &scatterGatherGroup{key: key, nShards: make(map[int64]int64)}
+		a.groups[key] = group
+	}
+	group.executions++
+	group.totalMillis += entry.Millis
+	if entry.NShards == nil {
+		group.unknown++
+		return
+	}
+	group.nShards[*entry.NShards]++
+}
+
+func (a *scatterGatherAggregator) namespaces() []string {
+	seen := make(map[string]struct{})
+	for key := range a.groups {
+		seen[key.namespace] = struct{}{}
+	}
+	result := make([]string, 0, len(seen))
+	for namespace := range seen {
+		result = append(result, namespace)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// ScatterGather 读取每个活跃 mongos 的 getLog 内存缓冲，按查询形状统计 nShards，并与集合分片键及
+// 所在分片数关联，对经常广播到全部分片的形状输出 sharding.scatter_gather_query；8.0+ 额外以
+// $queryStats 补充该形状在 mongos 上的总执行次数。
+func (c *Client) ScatterGather(ctx context.Context, opts ScatterGatherOptions) (result *ScatterGatherResult, err error) {
+	if c != nil && c.session == nil {
+		return withEphemeralCollectorSession(ctx, c, func(session *CollectorSession) (*ScatterGatherResult, error) {
+			return session.ScatterGather(ctx, opts)
+		})
+	}
+	opts, err = normalizeScatterGatherOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.requireMemberConnectionURI(); err != nil {
+		return nil, err
+	}
+	defer func() { err = mapContextError(err) }()
+
+	cluster, err := c.detectCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clusterType := convertClusterType(cluster.Type)
+	if gate, allowed := diagnosticCapabilityGate("scatter_gather", clusterType, cluster.MaxWireVersion, true); !allowed {
+		return &ScatterGatherResult{ClusterType: clusterType, CollectorStatuses: []CollectorStatus{gate}}, nil
+	}
+
+	result = &ScatterGatherResult{ClusterType: clusterType}
+	var collectorErrors []error
+	routers, routerErr := c.conn.ActiveMongos(ctx, time.Now().Add(-queryStatsMongosPingWindow))
+	if routerErr != nil || len(routers) == 0 {
+		if routerErr != nil && !isUnauthorizedError(routerErr) {
+			collectorErrors = append(collectorErrors, routerErr)
+		}
+		routers = []string{scatterGatherConnectedRouter}
+		result.CollectorStatuses = append(result.CollectorStatuses, CollectorStatus{
+			Name: "scatter_gather", State: CapabilitySupported, Scope: FindingScope{Type: ScopeCluster},
+			ReasonCode: "connected_router_only", Message: "未能从 config.mongos 读取活跃 mongos，只分析当前连接的 mongos",
+		})
+	}
+	result.Routers = routers
+
+	aggregator := newScatterGatherAggregator()
+	statuses, routerErrors := c.collectScatterGatherLogs(ctx, routers, opts, aggregator)
+	result.CollectorStatuses = append(result.CollectorStatuses, statuses...)
+	collectorErrors = append(collectorErrors, routerErrors...)
+
+	routing := make(map[string]pkgmongo.IndexRoutingSnapshot)
+	for _, namespace := range aggregator.namespaces() {
+		database, collection, _ := strings.Cut(namespace, ".")
+		snapshot, routingErr := c.scatterGatherRouting(ctx, database, collection)
+		if routingErr != nil {
+			if !isUnauthorizedError(routingErr) {
+				collectorErrors = append(collectorErrors, routingErr)
+			}
+			result.CollectorStatuses = append(result.CollectorStatuses, failedCollectorStatus("scatter_gather", FindingScope{Type: ScopeNamespace, Database: database, Namespace: namespace}, routingErr))
+			if cancelErr := contextError(ctx); cancelErr != nil {
+				break
+			}
+			continue
+		}
+		routing[namespace] = snapshot
+	}
+
+	var execCounts map[string]int64
+	if _, allowed := diagnosticCapabilityGate("query_stats", clusterType, cluster.MaxWireVersion, true); allowed && aggregator.hasQueryShapeHash() {
+		var queryStatsStatuses []CollectorStatus
+		execCounts, queryStatsStatuses, routerErrors = c.scatterGatherQueryStats(ctx, routers, opts)
+		result.CollectorStatuses = append(result.CollectorStatuses, queryStatsStatuses...)
+		collectorErrors = append(collectorErrors, routerErrors...)
+	}
+
+	result.Shapes, result.Findings = buildScatterGatherShapes(aggregator, routing, execCounts, opts)
+	sanitizeAndSortFindings(result.Findings)
+	sortCollectorStatuses(result.CollectorStatuses)
+	if len(collectorErrors) > 0 {
+		return result, newDiagnosticPartialError("scatter-gather", result, errors.Join(collectorErrors...))
+	}
+	return result, nil
+}
+
+func normalizeScatterGatherOptions(opts ScatterGatherOptions) (ScatterGatherOptions, error) {
+	if opts.MinExecutions < 0 || opts.NodeConcurrency < 0 {
+		return ScatterGatherOptions{}, invalidOptions("min executions and node concurrency must not be negative")
+	}
+	if opts.BroadcastRatio < 0 || opts.BroadcastRatio > 1 || math.IsNaN(opts.BroadcastRatio) {
+		return ScatterGatherOptions{}, invalidOptions("broadcast ratio must be between 0 and 1")
+	}
+	if err := validateSlowlogFilter(opts.filter()); err != nil {
+		return ScatterGatherOptions{}, err
+	}
+	if opts.MinExecutions == 0 {
+		opts.MinExecutions = defaultScatterGatherMinExecutions
+	}
+	if opts.BroadcastRatio == 0 {
+		opts.BroadcastRatio = defaultScatterGatherBroadcastRatio
+	}
+	if opts.NodeConcurrency == 0 {
+		opts.NodeConcurrency = defaultOverviewNodeConcurrency
+	}
+	return opts, nil
+}
+
+func (opts ScatterGatherOptions) filter() pkgmongo.SlowlogFilter {
+	return pkgmongo.SlowlogFilter{Since: opts.Since, Until: opts.Until, Namespaces: opts.Namespaces, Operations: opts.Operations}
+}
+
+func (opts ScatterGatherOptions) matches(entry pkgmongo.SlowlogEntry) bool {
+	if !opts.filter().Matches(entry) {
+		return false
+	}
+	if len(opts.Databases) == 0 {
+		return true
+	}
+	database, _, _ := strings.Cut(entry.Ns, ".")
+	return stringIncluded(opts.Databases, database)
+}
+
+func (c *Client) collectScatterGatherLogs(ctx context.Context, routers []string, opts ScatterGatherOptions, aggregator *scatterGatherAggregator) ([]CollectorStatus, []error) {
+	var statuses []CollectorStatus
+	var collectorErrors []error
+	var mu sync.Mutex
+	group, groupCtx := errgroup.WithContext(ctx)
+	limit := semaphore.NewWeighted(int64(opts.NodeConcurrency))
+	for _, router := range routers {
+		if acquireErr := acquireDiagnosticSlot(groupCtx, limit); acquireErr != nil {
+			mu.Lock()
+			collectorErrors = append(collectorErrors, acquireErr)
+			mu.Unlock()
+			break
+		}
+		router := router
+		group.Go(func() error {
+			defer limit.Release(1)
+			scope := FindingScope{Type: ScopeNode, Node: router}
+			var lines []string
+			collectErr := c.withScatterGatherRouter(groupCtx, router, func(conn *pkgmongo.Conn) error {
+				var err error
+				lines, err = conn.GetLog(groupCtx, "global")
+				return err
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			if collectErr != nil {
+				if !isUnauthorizedError(collectErr) {
+					collectorErrors = append(collectorErrors, collectErr)
+				}
+				statuses = append(statuses, failedCollectorStatus("scatter_gather", scope, collectErr))
+				return nil
+			}
+			var entries, routed int64
+			for _, line := range lines {
+				entry, ok := pkgmongo.ParseSlowlogLogLine([]byte(line))
+				if !ok || !opts.matches(entry) {
+					continue
+				}
+				entries++
+				if entry.NShards != nil {
+					routed++
+				}
+				aggregator.add(entry)
+			}
+			statuses = append(statuses, CollectorStatus{
+				Name: "scatter_gather", State: CapabilitySupported, Scope: scope,
+				Message: fmt.Sprintf("getLog 缓冲 %d 行，慢查询 %d 条，其中 %d 条记录了 nShards", len(lines), entries, routed),
+			})
+			return nil
+		})
+	}
+	_ = group.Wait()
+	return statuses, collectorErrors
+}
+
+// withScatterGatherRouter 直连指定 mongos 执行 fn；router 为 connected 时复用当前连接。
+func (c *Client) withScatterGatherRouter(ctx context.Context, router string, fn func(*pkgmongo.Conn) error) error {
+	release, err := c.acquireRemoteSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if router == scatterGatherConnectedRouter {
+		return fn(c.conn)
+	}
+	conn, err := c.connectAddress(ctx, router, derivedConnectionOptions{Direct: boolPointer(true)})
+	if err != nil {
+		return err
+	}
+	defer c.closeDerivedConnection(ctx, conn)
+	return fn(conn)
+}
+
+func (c *Client) scatterGatherRouting(ctx context.Context, database, collection string) (pkgmongo.IndexRoutingSnapshot, error) {
+	release, err := c.acquireRemoteSlot(ctx)
+	if err != nil {
+		return pkgmongo.IndexRoutingSnapshot{}, err
+	}
+	defer release()
+	return c.conn.IndexRouting(ctx, database, collection, indexConsistencyCollectorTimeout)
+}
+
+func (a *scatterGatherAggregator) hasQueryShapeHash() bool {
+	for key := range a.groups {
+		if key.queryShapeHash != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// scatterGatherQueryStats 汇总各 mongos 上 $queryStats 的 execCount，按 queryShapeHash 索引；
+// 只读取 metrics，标识符照常做 HMAC 变换。
+func (c *Client) scatterGatherQueryStats(ctx context.Context, routers []string, opts ScatterGatherOptions) (map[string]int64, []CollectorStatus, []error) {
+	hmacKey := make([]byte, queryStatsHMACKeyBytes)
+	if _, err := rand.Read(hmacKey); err != nil {
+		return nil, nil, []error{err}
+	}
+	counts := make(map[string]int64)
+	var statuses []CollectorStatus
+	var collectorErrors []error
+	var mu sync.Mutex
+	group, groupCtx := errgroup.WithContext(ctx)
+	limit := semaphore.NewWeighted(int64(opts.NodeConcurrency))
+	for _, router := range routers {
+		if acquireErr := acquireDiagnosticSlot(groupCtx, limit); acquireErr != nil {
+			mu.Lock()
+			collectorErrors = append(collectorErrors, acquireErr)
+			mu.Unlock()
+			break
+		}
+		router := router
+		group.Go(func() error {
+			defer limit.Release(1)
+			var entries []pkgmongo.QueryStatsEntry
+			collectErr := c.withScatterGatherRouter(groupCtx, router, func(conn *pkgmongo.Conn) error {
+				var err error
+				entries, err = conn.QueryStats(groupCtx, hmacKey, 30*time.Second)
+				return err
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			if collectErr != nil {
+				if !isUnauthorizedError(collectErr) && !isUnsupportedDiagnosticError(collectErr) {
+					collectorErrors = append(collectorErrors, collectErr)
+				}
+				statuses = append(statuses, failedCollectorStatus("query_stats", FindingScope{Type: ScopeNode, Node: router}, collectErr))
+				return nil
+			}
+			for _, entry := range entries {
+				if entry.QueryShapeHash != "" {
+					counts[entry.QueryShapeHash] += entry.ExecCount
+				}
+			}
+			return nil
+		})
+	}
+	_ = group.Wait()
+	return counts, statuses, collectorErrors
+}
+
+// buildScatterGatherShapes 以集合当前所在分片数判定每次执行是否广播；未分片或只在一个分片上的集合不输出。
+func buildScatterGatherShapes(aggregator *scatterGatherAggregator, routing map[string]pkgmongo.IndexRoutingSnapshot, execCounts map[string]int64, opts ScatterGatherOptions) ([]ScatterGatherShape, []DiagnosticFinding) {
+	var shapes []ScatterGatherShape
+	var findings []DiagnosticFinding
+	for _, group := range aggregator.groups {
+		snapshot, ok := routing[group.key.namespace]
+		if !ok || !snapshot.Sharded || len(snapshot.ExpectedShards) < 2 {
+			continue
+		}
+		shape := ScatterGatherShape{
+			Namespace: group.key.namespace, Operation: group.key.operation, QueryShapeHash: group.key.queryShapeHash,
+			ShardCount: len(snapshot.ExpectedShards), Executions: group.executions, Unknown: group.unknown, TotalMillis: group.totalMillis,
+		}
+		if group.key.predicateFields != "" {
+			shape.PredicateFields = strings.Split(group.key.predicateFields, ",")
+		}
+		for _, field := range snapshot.ShardKey {
+			shape.ShardKey = append(shape.ShardKey, IndexKeyField{Field: field.Key, Order: fmt.Sprint(field.Value)})
+		}
+		if len(shape.ShardKey) > 0 {
+			shape.ShardKeyPrefixUsed = stringIncluded(shape.PredicateFields, shape.ShardKey[0].Field)
+		}
+		for shards, count := range group.nShards {
+			shape.MaxShards = max(shape.MaxShards, shards)
+			if shards >= int64(shape.ShardCount) {
+				shape.Broadcast += count
+			} else {
+				shape.Targeted += count
+			}
+		}
+		if routed := shape.Broadcast + shape.Targeted; routed > 0 {
+			ratio := float64(shape.Broadcast) / float64(routed)
+			shape.BroadcastRatio = &ratio
+		}
+		if count, ok := execCounts[shape.QueryShapeHash]; ok && shape.QueryShapeHash != "" {
+			shape.QueryStatsExecCount = &count
+		}
+		if shape.BroadcastRatio != nil && shape.Broadcast+shape.Targeted >= opts.MinExecutions && *shape.BroadcastRatio >= opts.BroadcastRatio {
+			findings = append(findings, scatterGatherFinding(shape))
+		}
+		shapes = append(shapes, shape)
+	}
+	sort.SliceStable(shapes, func(i, j int) bool {
+		left, right := shapes[i], shapes[j]
+		if left.Broadcast != right.Broadcast {
+			return left.Broadcast > right.Broadcast
+		}
+		if left.Namespace != right.Namespace {
+			return left.Namespace < right.Namespace
+		}
+		if left.Operation != right.Operation {
+			return left.Operation < right.Operation
+		}
+		if left.QueryShapeHash != right.QueryShapeHash {
+			return left.QueryShapeHash < right.QueryShapeHash
+		}
+		return strings.Join(left.PredicateFields, ",") < strings.Join(right.PredicateFields, ",")
+	})
+	return shapes, findings
+}
+
+func scatterGatherFinding(shape ScatterGatherShape) DiagnosticFinding {
+	routed := shape.Broadcast + shape.Targeted
+	shardKey := make([]string, 0, len(shape.ShardKey))
+	for _, field := range shape.ShardKey {
+		shardKey = append(shardKey, field.Field+":"+field.Order)
+	}
+	evidence := map[string]any{
+		"operation": shape.Operation, "queryShapeHash": shape.QueryShapeHash,
+		"predicateFields": strings.Join(shape.PredicateFields, ","), "shardKey": strings.Join(shardKey, ","),
+		"shardKeyPrefixUsed": shape.ShardKeyPrefixUsed, "shardCount": shape.ShardCount, "maxShards": shape.MaxShards,
+		"executions": routed, "broadcast": shape.Broadcast, "targeted": shape.Targeted,
+		"broadcastRatio": *shape.BroadcastRatio, "targetedRatio": float64(shape.Targeted) / float64(routed), "totalMillis": shape.TotalMillis,
+	}
+	if shape.QueryStatsExecCount != nil {
+		evidence["queryStatsExecCount"] = *shape.QueryStatsExecCount
+	}
+	recommendation := "在查询条件中带上分片键前缀字段，使 mongos 能定向到少数分片；若业务查询无法包含分片键，评估 refineCollectionShardKey 或调整分片键"
+	if shape.ShardKeyPrefixUsed {
+		recommendation = "查询虽包含分片键前缀字段，但取值范围仍覆盖全部分片；检查是否使用了 $ne、$nin、$regex 或过宽的范围条件"
+	}
+	return DiagnosticFinding{
+		Code: "sharding.scatter_gather_query", Severity: SeverityWarning,
+		Scope:          FindingScope{Type: ScopeNamespace, Namespace: shape.Namespace},
+		Summary:        fmt.Sprintf("查询形状在 %d 次慢执行中有 %.0f%% 广播到集合所在的全部 %d 个分片", routed, *shape.BroadcastRatio*100, shape.ShardCount),
+		Evidence:       evidence,
+		Recommendation: recommendation,
+	}
+}
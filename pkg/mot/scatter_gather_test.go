@@ -0,0 +1,69 @@
+package mot
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+func TestBuildScatterGatherShapesClassifiesBroadcastByRouting(t *testing.T) {
+	// 场景：nShards 达到集合所在分片数的执行计为广播，缺失 nShards 的执行不参与占比；
+	// 未分片或只在单个分片上的集合不输出，超过阈值的形状输出 sharding.scatter_gather_query。
+	shards := func(value int64) *int64 { return &value }
+	aggregator := newScatterGatherAggregator()
+	for i := 0; i < 6; i++ {
+		aggregator.add(pkgmongo.SlowlogEntry{Ns: "app.orders", Op: "query", PredicateFields: []string{"status"}, NShards: shards(3), Millis: 100})
+	}
+	aggregator.add(pkgmongo.SlowlogEntry{Ns: "app.orders", Op: "query", PredicateFields: []string{"status"}, NShards: shards(1), Millis: 10})
+	aggregator.add(pkgmongo.SlowlogEntry{Ns: "app.orders", Op: "query", PredicateFields: []string{"status"}, Millis: 10})
+	for i := 0; i < 6; i++ {
+		aggregator.add(pkgmongo.SlowlogEntry{Ns: "app.orders", Op: "query", QueryShapeHash: "SHAPE", PredicateFields: []string{"tenant"}, NShards: shards(1), Millis: 50})
+	}
+	aggregator.add(pkgmongo.SlowlogEntry{Ns: "app.local", Op: "query", NShards: shards(1), Millis: 50})
+	aggregator.add(pkgmongo.SlowlogEntry{Ns: "app.single", Op: "query", NShards: shards(1), Millis: 50})
+	if got := aggregator.namespaces(); len(got) != 3 || got[0] != "app.local" {
+		t.Fatalf("namespaces = %v", got)
+	}
+	routing := map[string]pkgmongo.IndexRoutingSnapshot{
+		"app.orders": {Namespace: "app.orders", Sharded: true, ExpectedShards: []string{"s1", "s2", "s3"}, ShardKey: bson.D{{Key: "tenant", Value: "hashed"}}},
+		"app.local":  {Namespace: "app.local"},
+		"app.single": {Namespace: "app.single", Sharded: true, ExpectedShards: []string{"s1"}},
+	}
+	opts, err := normalizeScatterGatherOptions(ScatterGatherOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shapes, findings := buildScatterGatherShapes(aggregator, routing, map[string]int64{"SHAPE": 900}, opts)
+	if len(shapes) != 2 {
+		t.Fatalf("shapes = %#v", shapes)
+	}
+	broadcast := shapes[0]
+	if broadcast.Broadcast != 6 || broadcast.Targeted != 1 || broadcast.Unknown != 1 || broadcast.Executions != 8 || broadcast.MaxShards != 3 || broadcast.ShardCount != 3 {
+		t.Fatalf("broadcast shape = %#v", broadcast)
+	}
+	if broadcast.BroadcastRatio == nil || *broadcast.BroadcastRatio < 0.85 || broadcast.ShardKeyPrefixUsed || len(broadcast.ShardKey) != 1 || broadcast.ShardKey[0].Order != "hashed" {
+		t.Fatalf("broadcast routing = %#v", broadcast)
+	}
+	targeted := shapes[1]
+	if targeted.Broadcast != 0 || targeted.Targeted != 6 || !targeted.ShardKeyPrefixUsed || targeted.QueryStatsExecCount == nil || *targeted.QueryStatsExecCount != 900 {
+		t.Fatalf("targeted shape = %#v", targeted)
+	}
+	if len(findings) != 1 || findings[0].Code != "sharding.scatter_gather_query" || findings[0].Scope.Namespace != "app.orders" || findings[0].Evidence["broadcast"] != int64(6) || findings[0].Evidence["shardKey"] != "tenant:hashed" {
+		t.Fatalf("findings = %#v", findings)
+	}
+}
+
+func TestNormalizeScatterGatherOptionsRejectsInvalidThresholds(t *testing.T) {
+	// 场景：广播占比阈值必须位于 [0,1]，namespace 过滤沿用 slowlog 的 db.collection 校验。
+	for _, opts := range []ScatterGatherOptions{{BroadcastRatio: 1.5}, {MinExecutions: -1}, {Namespaces: []string{"orders"}}} {
+		if _, err := normalizeScatterGatherOptions(opts); err == nil {
+			t.Fatalf("invalid options accepted: %#v", opts)
+		}
+	}
+	if status, allowed := diagnosticCapabilityGate("scatter_gather", ClusterReplicaSet, 25, true); allowed || status.ReasonCode != "unsupported_topology" {
+		t.Fatalf("replica set gate = %#v allowed=%t", status, allowed)
+	}
+}
@@ -371,6 +371,16 @@ func (s *CollectorSession) Capacity(ctx context.Context, opts CapacityOptions) (
 	return s.client.Capacity(ctx, opts)
 }
 
+// CollectionLatency 在当前 session 内采集集合延迟分布。
+func (s *CollectorSession) CollectionLatency(ctx context.Context, opts CollectionLatencyOptions) (result *CollectionLatencyResult, err error) {
+	if err := s.requireOpen(); err != nil {
+		return nil, err
+	}
+	startedAt := time.Now()
+	defer func() { s.recordCapability("collection_latency", time.Since(startedAt), err) }()
+	return s.client.CollectionLatency(ctx, opts)
+}
+
 // SlowlogSummary 在当前 session 内聚合慢日志。
 func (s *CollectorSession) SlowlogSummary(ctx context.Context, opts SlowlogOptions) (result *SlowlogSummaryResult, err error) {
 	if err := s.requireOpen(); err != nil {
@@ -385,13 +395,111 @@ func (s *CollectorSession) SlowlogSummary(ctx context.Context, opts SlowlogOptio
 	return result, err
 }
 
+// SlowlogAdvise 在当前 session 内基于慢日志推导索引建议，详情查询复用 summary 建立的定位索引。
+func (s *CollectorSession) SlowlogAdvise(ctx context.Context, opts SlowlogAdviseOptions) (result *SlowlogAdviseResult, err error) {
+	if err := s.requireOpen(); err != nil {
+		return nil, err
+	}
+	startedAt := time.Now()
+	defer func() { s.recordCapability("slowlog_advise", time.Since(startedAt), err) }()
+	return s.client.SlowlogAdvise(ctx, opts)
+}
+
+// SlowlogDigest 在当前 session 内生成 digest 报告，样本命令查询复用 summary 建立的定位索引。
+func (s *CollectorSession) SlowlogDigest(ctx context.Context, opts SlowlogDigestOptions) (result *SlowlogDigestResult, err error) {
+	if err := s.requireOpen(); err != nil {
+		return nil, err
+	}
+	startedAt := time.Now()
+	defer func() { s.recordCapability("slowlog_digest", time.Since(startedAt), err) }()
+	return s.client.SlowlogDigest(ctx, opts)
+}
+
+// QueryStats 在当前 session 内采集 $queryStats 查询形状统计。
+func (s *CollectorSession) QueryStats(ctx context.Context, opts QueryStatsOptions) (result *QueryStatsResult, err error) {
+	if err := s.requireOpen(); err != nil {
+		return nil, err
+	}
+	startedAt := time.Now()
+	defer func() { s.recordCapability("query_stats", time.Since(startedAt), err) }()
+	return s.client.QueryStats(ctx, opts)
+}
+
+// ScatterGather 在当前 session 内分析 mongos 慢日志中的广播查询。
+func (s *CollectorSession) ScatterGather(ctx context.Context, opts ScatterGatherOptions) (result *ScatterGatherResult, err error) {
+	if err := s.requireOpen(); err != nil {
+		return nil, err
+	}
+	startedAt := time.Now()
+	defer func() { s.recordCapability("scatter_gather", time.Since(startedAt), err) }()
+	return s.client.ScatterGather(ctx, opts)
+}
+
+// PlanCache 在当前 session 内检查 slowlog 查询形状的 plan cache 条目。
+func (s *CollectorSession) PlanCache(ctx context.Context, opts PlanCacheOptions) (result *PlanCacheResult, err error) {
+	if err := s.requireOpen(); err != nil {
+		return nil, err
+	}
+	startedAt := time.Now()
+	defer func() { s.recordCapability("plan_cache", time.Since(startedAt), err) }()
+	return s.client.PlanCache(ctx, opts)
+}
+
+// ProfilerStatus 在当前 session 内读取各成员的 profiler 设置。
+func (s *CollectorSession) ProfilerStatus(ctx context.Context, opts ProfilerOptions) (result *ProfilerResult, err error) {
+	if err := s.requireOpen(); err != nil {
+		return nil, err
+	}
+	startedAt := time.Now()
+	defer func() { s.recordCapability("profiler_status", time.Since(startedAt), err) }()
+	return s.client.ProfilerStatus(ctx, opts)
+}
+
+// IndexSnapshot 在当前 session 内读取所选集合的索引定义快照。
+func (s *CollectorSession) IndexSnapshot(ctx context.Context, opts IndexSnapshotOptions) (result *IndexSnapshot, err error) {
+	if err := s.requireOpen(); err != nil {
+		return nil, err
+	}
+	startedAt := time.Now()
+	defer func() { s.recordCapability("index_snapshot", time.Since(startedAt), err) }()
+	return s.client.IndexSnapshot(ctx, opts)
+}
+
+// IndexLifecycleStatus 在当前 session 内读取 ledger 中已隐藏索引的状态。
+func (s *CollectorSession) IndexLifecycleStatus(ctx context.Context, opts IndexLifecycleStatusOptions) (result *IndexLifecycleResult, err error) {
+	if err := s.requireOpen(); err != nil {
+		return nil, err
+	}
+	startedAt := time.Now()
+	defer func() { s.recordCapability("index_lifecycle", time.Since(startedAt), err) }()
+	return s.client.IndexLifecycleStatus(ctx, opts)
+}
+
+// IndexBuilds 在当前 session 内读取各成员正在进行的索引构建。
+func (s *CollectorSession) IndexBuilds(ctx context.Context, opts IndexBuildsOptions) (result *IndexBuildsResult, err error) {
+	if err := s.requireOpen(); err != nil {
+		return nil, err
+	}
+	startedAt := time.Now()
+	defer func() { s.recordCapability("index_builds", time.Since(startedAt), err) }()
+	return s.client.IndexBuilds(ctx, opts)
+}
+
 // SlowlogDetail 在当前 session 内查询单条慢日志详情。
-func (s *CollectorSession) SlowlogDetail(ctx context.Context, db, queryHash string) (result *SlowlogDetailResult, err error) {
+func (s *CollectorSession) SlowlogDetail(ctx context.Context, db, queryHash string) (*SlowlogDetailResult, error) {
+	return s.SlowlogDetailWithOptions(ctx, db, queryHash, SlowlogDetailOptions{})
+}
+
+// SlowlogDetailWithOptions 在当前 session 内查询慢日志详情并按需重放 explain。
+func (s *CollectorSession) SlowlogDetailWithOptions(ctx context.Context, db, queryHash string, opts SlowlogDetailOptions) (result *SlowlogDetailResult, err error) {
 	if err := s.requireOpen(); err != nil {
 		return nil, err
 	}
 	startedAt := time.Now()
 	defer func() { s.recordCapability("slowlog_detail", time.Since(startedAt), err) }()
+	if err := validateExplainVerbosity(opts.Explain); err != nil {
+		return nil, err
+	}
 	if address := s.slowlogAddress(db, queryHash); address != "" {
 		release, acquireErr := s.acquireRemoteSlot(ctx)
 		if acquireErr != nil {
@@ -405,9 +513,9 @@ func (s *CollectorSession) SlowlogDetail(ctx context.Context, db, queryHash stri
 		if connectErr != nil {
 			return nil, connectErr
 		}
-		return s.slowlogDetailLoader(ctx, conn, db, queryHash)
+		return loadSlowlogDetail(ctx, conn, db, queryHash, opts, s.slowlogDetailLoader)
 	}
-	return s.client.SlowlogDetail(ctx, db, queryHash)
+	return s.client.SlowlogDetailWithOptions(ctx, db, queryHash, opts)
 }
 
 type slowlogLocationKey struct {
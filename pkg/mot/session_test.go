@@ -61,10 +61,44 @@ func TestCollectorSessionCloseIsIdempotentAndRejectsNewCalls(t *testing.T) {
 			return err
 		}},
 		{name: "hotspot", call: func() error { _, err := session.Hotspot(context.Background(), HotspotOptions{}); return err }},
+		{name: "collection latency", call: func() error {
+			_, err := session.CollectionLatency(context.Background(), CollectionLatencyOptions{})
+			return err
+		}},
 		{name: "index audit", call: func() error { _, err := session.IndexAudit(context.Background(), IndexAuditOptions{}); return err }},
 		{name: "capacity", call: func() error { _, err := session.Capacity(context.Background(), CapacityOptions{}); return err }},
 		{name: "slowlog summary", call: func() error { _, err := session.SlowlogSummary(context.Background(), SlowlogOptions{}); return err }},
 		{name: "slowlog detail", call: func() error { _, err := session.SlowlogDetail(context.Background(), "db", "hash"); return err }},
+		{name: "slowlog detail with options", call: func() error {
+			_, err := session.SlowlogDetailWithOptions(context.Background(), "db", "hash", SlowlogDetailOptions{Explain: ExplainQueryPlanner})
+			return err
+		}},
+		{name: "slowlog advise", call: func() error {
+			_, err := session.SlowlogAdvise(context.Background(), SlowlogAdviseOptions{})
+			return err
+		}},
+		{name: "plan cache", call: func() error { _, err := session.PlanCache(context.Background(), PlanCacheOptions{}); return err }},
+		{name: "slowlog digest", call: func() error {
+			_, err := session.SlowlogDigest(context.Background(), SlowlogDigestOptions{})
+			return err
+		}},
+		{name: "query stats", call: func() error { _, err := session.QueryStats(context.Background(), QueryStatsOptions{}); return err }},
+		{name: "scatter gather", call: func() error {
+			_, err := session.ScatterGather(context.Background(), ScatterGatherOptions{})
+			return err
+		}},
+		{name: "profiler status", call: func() error {
+			_, err := session.ProfilerStatus(context.Background(), ProfilerOptions{})
+			return err
+		}},
+		{name: "index snapshot", call: func() error {
+			_, err := session.IndexSnapshot(context.Background(), IndexSnapshotOptions{})
+			return err
+		}},
+		{name: "index lifecycle status", call: func() error {
+			_, err := session.IndexLifecycleStatus(context.Background(), IndexLifecycleStatusOptions{})
+			return err
+		}},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -16,7 +16,14 @@ import (
 	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
 )
 
-const defaultSlowlogConcurrency = 5
+const (
+	defaultSlowlogConcurrency = 5
+	// slowlogPercentileWireVersion 对应 MongoDB 7.0，开始支持 $percentile accumulator。
+	slowlogPercentileWireVersion = 21
+)
+
+// slowlogOperations 是 system.profile 中 op 字段的取值。
+var slowlogOperations = []string{"command", "getmore", "insert", "query", "remove", "update"}
 
 type slowlogDatabaseLoader func(ctx context.Context, addr, db string, sort SlowlogSort) (DatabaseSlowlogSummary, bool, error)
 type slowlogHostLoader func(ctx context.Context, member pkgmongo.RsMember) (HostSlowlogSummary, []CollectorStatus, []error)
@@ -48,6 +55,9 @@ func (c *Client) SlowlogSummary(ctx context.Context, opts SlowlogOptions) (resul
 	if !isValidSlowlogSort(opts.Sort) {
 		return nil, invalidOptions("invalid slowlog sort %q", opts.Sort)
 	}
+	if err := validateSlowlogFilter(opts.filter()); err != nil {
+		return nil, err
+	}
 	capabilityConcurrency := opts.Concurrency
 	if capabilityConcurrency <= 0 {
 		capabilityConcurrency = defaultSlowlogConcurrency
@@ -63,10 +73,17 @@ func (c *Client) SlowlogSummary(ctx context.Context, opts SlowlogOptions) (resul
 		result.CollectorStatuses = []CollectorStatus{gate}
 		return result, nil
 	}
+	if opts.GetLog {
+		if gate, allowed := diagnosticCapabilityGate("slowlog_getlog", result.ClusterType, cluster.MaxWireVersion, true); !allowed {
+			result.CollectorStatuses = append(result.CollectorStatuses, gate)
+			opts.GetLog = false
+		}
+	}
+	nativePercentile := cluster.MaxWireVersion >= slowlogPercentileWireVersion
 	var collectorErrors []error
 	switch cluster.Type {
 	case pkgmongo.ClusterRepl:
-		rs, statuses, collectErrors := c.replicaSetSlowlogSummary(ctx, c.conn, "base", opts, capabilityLimit)
+		rs, statuses, collectErrors := c.replicaSetSlowlogSummary(ctx, c.conn, "base", opts, nativePercentile, capabilityLimit)
 		result.ReplicaSets = append(result.ReplicaSets, rs)
 		result.CollectorStatuses = append(result.CollectorStatuses, statuses...)
 		collectorErrors = append(collectorErrors, collectErrors...)
@@ -97,7 +114,7 @@ func (c *Client) SlowlogSummary(ctx context.Context, opts SlowlogOptions) (resul
 				}
 			}
 			defer c.closeDerivedConnection(ctx, conn)
-			rs, statuses, collectErrors := c.replicaSetSlowlogSummary(ctx, conn, replicaSet, opts, capabilityLimit)
+			rs, statuses, collectErrors := c.replicaSetSlowlogSummary(ctx, conn, replicaSet, opts, nativePercentile, capabilityLimit)
 			if rs.Name == "" {
 				rs.Name = shard.Id
 			}
@@ -167,10 +184,15 @@ func collectSlowlogShards(ctx context.Context, shards []pkgmongo.Shard, concurre
 }
 
 // SlowlogDetail 返回单个 queryHash 的原始慢日志文档和索引信息。
-func (c *Client) SlowlogDetail(ctx context.Context, db, queryHash string) (result *SlowlogDetailResult, err error) {
+func (c *Client) SlowlogDetail(ctx context.Context, db, queryHash string) (*SlowlogDetailResult, error) {
+	return c.SlowlogDetailWithOptions(ctx, db, queryHash, SlowlogDetailOptions{})
+}
+
+// SlowlogDetailWithOptions 在 SlowlogDetail 之上按需重放 explain；explain 失败时返回带详情的部分结果。
+func (c *Client) SlowlogDetailWithOptions(ctx context.Context, db, queryHash string, opts SlowlogDetailOptions) (result *SlowlogDetailResult, err error) {
 	if c != nil && c.session == nil {
 		return withEphemeralCollectorSession(ctx, c, func(session *CollectorSession) (*SlowlogDetailResult, error) {
-			return session.SlowlogDetail(ctx, db, queryHash)
+			return session.SlowlogDetailWithOptions(ctx, db, queryHash, opts)
 		})
 	}
 	defer func() {
@@ -185,8 +207,11 @@ func (c *Client) SlowlogDetail(ctx context.Context, db, queryHash string) (resul
 	if queryHash == "" {
 		return nil, invalidOptions("query hash is required")
 	}
+	if err := validateExplainVerbosity(opts.Explain); err != nil {
+		return nil, err
+	}
 	if strings.TrimSpace(c.uri) == "" {
-		return slowlogDetailFromConnection(ctx, c.conn, db, queryHash)
+		return loadSlowlogDetail(ctx, c.conn, db, queryHash, opts, slowlogDetailFromConnection)
 	}
 
 	summary, err := c.SlowlogSummary(ctx, SlowlogOptions{
@@ -209,7 +234,19 @@ func (c *Client) SlowlogDetail(ctx context.Context, db, queryHash string) (resul
 		return nil, err
 	}
 	defer c.closeDerivedConnection(ctx, conn)
-	return slowlogDetailFromConnection(ctx, conn, db, queryHash)
+	return loadSlowlogDetail(ctx, conn, db, queryHash, opts, slowlogDetailFromConnection)
+}
+
+// loadSlowlogDetail 读取详情后在同一连接上重放 explain，保证计划来自产生慢日志的节点。
+func loadSlowlogDetail(ctx context.Context, conn *pkgmongo.Conn, db, queryHash string, opts SlowlogDetailOptions, load slowlogDetailLoader) (*SlowlogDetailResult, error) {
+	result, err := load(ctx, conn, db, queryHash)
+	if err != nil || opts.Explain == "" {
+		return result, err
+	}
+	if err := attachSlowlogExplain(ctx, conn, db, queryHash, result, opts.Explain); err != nil {
+		return result, newDiagnosticPartialError("slowlog-explain", result, err)
+	}
+	return result, nil
 }
 
 func findSlowlogAddress(summary *SlowlogSummaryResult, db, queryHash string) string {
@@ -234,7 +271,11 @@ func findSlowlogAddress(summary *SlowlogSummaryResult, db, queryHash string) str
 }
 
 func slowlogDetailFromConnection(ctx context.Context, conn *pkgmongo.Conn, db, queryHash string) (*SlowlogDetailResult, error) {
-	slow, err := conn.GetSlowDetail(ctx, db, queryHash)
+	raw, err := conn.GetSlowDetailRaw(ctx, db, queryHash)
+	if err != nil {
+		return nil, err
+	}
+	slow, orderedSlow, err := decodeSlowlogDocument(raw)
 	if err != nil {
 		return nil, err
 	}
@@ -257,18 +298,56 @@ func slowlogDetailFromConnection(ctx context.Context, conn *pkgmongo.Conn, db, q
 		_ = cur.Close(closeCtx)
 	}()
 
-	var indexes []bson.M
-	if err := cur.All(ctx, &indexes); err != nil {
+	var indexes, orderedIndexes []bson.M
+	for cur.Next(ctx) {
+		index, orderedIndex, err := decodeSlowlogDocument(cur.Current)
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, index)
+		orderedIndexes = append(orderedIndexes, orderedIndex)
+	}
+	if err := cur.Err(); err != nil {
 		return nil, err
 	}
 	return &SlowlogDetailResult{
-		Namespace: namespace,
-		Slowlog:   slow,
-		Indexes:   indexes,
+		Namespace:      namespace,
+		Slowlog:        slow,
+		Indexes:        indexes,
+		orderedSlowlog: orderedSlow,
+		orderedIndexes: orderedIndexes,
 	}, nil
 }
 
-func (c *Client) replicaSetSlowlogSummary(ctx context.Context, conn *pkgmongo.Conn, inventoryKey string, opts SlowlogOptions, capabilityLimit *semaphore.Weighted) (ReplicaSetSlowlogSummary, []CollectorStatus, []error) {
+// orderedProfile 返回保留嵌套字段顺序的 profile 文档；手工构造的结果退回公开字段。
+func (r *SlowlogDetailResult) orderedProfile() bson.M {
+	if r.orderedSlowlog != nil {
+		return r.orderedSlowlog
+	}
+	return r.Slowlog
+}
+
+func (r *SlowlogDetailResult) orderedIndexDocuments() []bson.M {
+	if r.orderedIndexes != nil {
+		return r.orderedIndexes
+	}
+	return r.Indexes
+}
+
+// decodeSlowlogDocument 同时返回对外输出用的 bson.M 与保留嵌套字段顺序的解析用文档。
+func decodeSlowlogDocument(raw bson.Raw) (bson.M, bson.M, error) {
+	var document bson.M
+	if err := bson.Unmarshal(raw, &document); err != nil {
+		return nil, nil, err
+	}
+	ordered, err := pkgmongo.DecodeOrderedM(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	return document, ordered, nil
+}
+
+func (c *Client) replicaSetSlowlogSummary(ctx context.Context, conn *pkgmongo.Conn, inventoryKey string, opts SlowlogOptions, nativePercentile bool, capabilityLimit *semaphore.Weighted) (ReplicaSetSlowlogSummary, []CollectorStatus, []error) {
 	inventory, err := c.replicaSetInventory(ctx, conn, inventoryKey)
 	if err != nil {
 		scope := FindingScope{Type: ScopeReplicaSet}
@@ -296,6 +375,9 @@ func (c *Client) replicaSetSlowlogSummary(ctx context.Context, conn *pkgmongo.Co
 		if len(opts.Databases) != 0 && !slices.Contains(opts.Databases, db) {
 			continue
 		}
+		if len(opts.Namespaces) != 0 && !slowlogNamespacesInDatabase(opts.Namespaces, db) {
+			continue
+		}
 		filteredDBs = append(filteredDBs, db)
 	}
 	members := make([]pkgmongo.RsMember, 0, len(inventory.Members))
@@ -320,9 +402,16 @@ func (c *Client) replicaSetSlowlogSummary(ctx context.Context, conn *pkgmongo.Co
 			opts.Sort,
 			opts.Concurrency,
 			func(ctx context.Context, addr, database string, sortValue SlowlogSort) (DatabaseSlowlogSummary, bool, error) {
-				return c.databaseSlowlogSummaryWithLimit(ctx, addr, database, sortValue, capabilityLimit)
+				return c.databaseSlowlogSummaryWithLimit(ctx, addr, database, sortValue, opts.filter(), nativePercentile, capabilityLimit)
 			},
 		)
+		if opts.GetLog {
+			status, getLogErr := c.mergeHostSlowlogGetLog(ctx, &host, inventory.Name, opts, capabilityLimit)
+			hostStatuses = append(hostStatuses, status)
+			if getLogErr != nil && !isUnauthorizedError(getLogErr) && !isUnsupportedDiagnosticError(getLogErr) {
+				hostErrors = append(hostErrors, getLogErr)
+			}
+		}
 		return host, hostStatuses, hostErrors
 	}
 	var hosts []HostSlowlogSummary
@@ -489,10 +578,10 @@ func collectSlowlogDatabaseSummaries(
 }
 
 func (c *Client) databaseSlowlogSummary(ctx context.Context, addr, db string, sort SlowlogSort) (DatabaseSlowlogSummary, bool, error) {
-	return c.databaseSlowlogSummaryWithLimit(ctx, addr, db, sort, nil)
+	return c.databaseSlowlogSummaryWithLimit(ctx, addr, db, sort, pkgmongo.SlowlogFilter{}, false, nil)
 }
 
-func (c *Client) databaseSlowlogSummaryWithLimit(ctx context.Context, addr, db string, sort SlowlogSort, capabilityLimit *semaphore.Weighted) (DatabaseSlowlogSummary, bool, error) {
+func (c *Client) databaseSlowlogSummaryWithLimit(ctx context.Context, addr, db string, sort SlowlogSort, filter pkgmongo.SlowlogFilter, nativePercentile bool, capabilityLimit *semaphore.Weighted) (DatabaseSlowlogSummary, bool, error) {
 	release, err := c.acquireCapabilityRemoteSlot(ctx, capabilityLimit)
 	if err != nil {
 		return DatabaseSlowlogSummary{}, false, err
@@ -515,7 +604,7 @@ func (c *Client) databaseSlowlogSummaryWithLimit(ctx context.Context, addr, db s
 	if len(colls) == 0 {
 		return DatabaseSlowlogSummary{}, false, nil
 	}
-	logs, err := conn.GetSlowLogView(ctx, db, string(sort))
+	logs, err := conn.GetSlowLogViewFiltered(ctx, db, string(sort), filter, nativePercentile)
 	if err != nil {
 		return DatabaseSlowlogSummary{}, false, err
 	}
@@ -523,6 +612,11 @@ func (c *Client) databaseSlowlogSummaryWithLimit(ctx context.Context, addr, db s
 		return DatabaseSlowlogSummary{}, false, nil
 	}
 
+	return summarizeSlowlogViews(db, logs), true, nil
+}
+
+// summarizeSlowlogViews 把单个 database 的聚合视图转换为 summary，并检测同一 query hash 的 plan 变化。
+func summarizeSlowlogViews(db string, logs []*pkgmongo.SlowlogView) DatabaseSlowlogSummary {
 	summary := DatabaseSlowlogSummary{Database: db}
 	plansByQuery := make(map[string]string)
 	for i, log := range logs {
@@ -530,6 +624,7 @@ func (c *Client) databaseSlowlogSummaryWithLimit(ctx context.Context, addr, db s
 		summary.Items = append(summary.Items, item)
 		summary.Findings = append(summary.Findings, findings...)
 		summary.Total += log.Cnt
+		summary.TotalMillis += log.TotalMillis
 		planKey := log.Ns + "\x00" + log.QueryHash
 		if previous, exists := plansByQuery[planKey]; exists && previous != log.PlanSummary {
 			summary.Findings = append(summary.Findings, DiagnosticFinding{Code: "query.plan_changed", Severity: SeverityInfo, Scope: FindingScope{Type: ScopeNamespace, Namespace: log.Ns}, Summary: "同一 query hash 在观察窗口内出现多个 plan summary", Evidence: map[string]any{"queryHash": log.QueryHash}})
@@ -543,7 +638,13 @@ func (c *Client) databaseSlowlogSummaryWithLimit(ctx context.Context, addr, db s
 			summary.LastTime = log.MaxTs
 		}
 	}
-	return summary, true, nil
+	if summary.TotalMillis > 0 {
+		for i := range summary.Items {
+			share := float64(summary.Items[i].TotalMillis) / float64(summary.TotalMillis)
+			summary.Items[i].TimeShare = &share
+		}
+	}
+	return summary
 }
 
 func convertSlowlogView(log pkgmongo.SlowlogView) (SlowlogSummaryItem, []DiagnosticFinding) {
@@ -554,7 +655,11 @@ func convertSlowlogView(log pkgmongo.SlowlogView) (SlowlogSummaryItem, []Diagnos
 		MaxKeysExamined: log.MaxKeysExamined, MaxDocsExamined: log.MaxDocsExamined,
 		MaxDocsReturned: log.MaxDocsReturned, MaxPlanningMicros: log.MaxPlanningMicros,
 		MaxCPUNanos: log.MaxCPUNanos, ErrorCount: log.ErrorCount,
-		CollectionScanCount: log.CollectionScanCount,
+		CollectionScanCount: log.CollectionScanCount, SortStageCount: log.SortStageCount, TotalMillis: log.TotalMillis,
+		P50Millis: log.P50Mills, P95Millis: log.P95Mills, P99Millis: log.P99Mills, millisHistogram: log.MillisHistogram,
+	}
+	if log.Cnt > 0 {
+		item.AvgMillis = float64(log.TotalMillis) / float64(log.Cnt)
 	}
 	for _, appName := range log.AppNames {
 		if appName != "" {
@@ -617,9 +722,42 @@ func safeExaminedRatio(examined, returned *int64) *float64 {
 	return &ratio
 }
 
+func (opts SlowlogOptions) filter() pkgmongo.SlowlogFilter {
+	return pkgmongo.SlowlogFilter{Since: opts.Since, Until: opts.Until, Namespaces: opts.Namespaces, Operations: opts.Operations, AppNames: opts.AppNames, MinMillis: opts.MinMillis}
+}
+
+func validateSlowlogFilter(filter pkgmongo.SlowlogFilter) error {
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Until.After(filter.Since) {
+		return invalidOptions("slowlog until must be after since")
+	}
+	if filter.MinMillis < 0 {
+		return invalidOptions("slowlog min millis must not be negative")
+	}
+	for _, namespace := range filter.Namespaces {
+		if db, collection, found := strings.Cut(namespace, "."); !found || db == "" || collection == "" {
+			return invalidOptions("invalid slowlog namespace %q, expect db.collection", namespace)
+		}
+	}
+	for _, operation := range filter.Operations {
+		if !slices.Contains(slowlogOperations, operation) {
+			return invalidOptions("invalid slowlog operation %q", operation)
+		}
+	}
+	return nil
+}
+
+func slowlogNamespacesInDatabase(namespaces []string, db string) bool {
+	for _, namespace := range namespaces {
+		if strings.HasPrefix(namespace, db+".") {
+			return true
+		}
+	}
+	return false
+}
+
 func isValidSlowlogSort(sort SlowlogSort) bool {
 	switch sort {
-	case SlowlogSortCount, SlowlogSortMaxMillis, SlowlogSortMaxDocs:
+	case SlowlogSortCount, SlowlogSortMaxMillis, SlowlogSortMaxDocs, SlowlogSortP95, SlowlogSortTotal:
 		return true
 	default:
 		return false
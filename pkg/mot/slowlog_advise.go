@@ -0,0 +1,495 @@
+package mot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	drivermongo "go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	defaultSlowlogAdviseCandidates        = 20
+	defaultSlowlogAdviseDocsExaminedRatio = 100
+)
+
+// slowlogEqualityOperators 中的操作符可以像等值条件一样放在索引前缀；其余操作符按范围处理。
+// 多值 $in 单独归类：没有排序时等同等值，有排序时会打乱索引顺序，需按范围放在排序字段之后。
+var slowlogEqualityOperators = map[string]struct{}{"$eq": {}, "$in": {}, "$elemMatch": {}, "$all": {}}
+
+type slowlogPredicateKind int
+
+const (
+	slowlogPredicateEquality slowlogPredicateKind = iota
+	slowlogPredicateIn
+	slowlogPredicateRange
+)
+
+// slowlogQueryShape 是查询条件与排序的字段形状，取值在提取时即被丢弃。
+type slowlogQueryShape struct {
+	equality []string
+	in       []string
+	sort     []IndexKeyField
+	ranges   []string
+}
+
+type slowlogIndexDefinition struct {
+	name        string
+	key         []IndexKeyField
+	replaceable bool
+}
+
+// SlowlogAdvise 对 COLLSCAN、内存排序或扫描放大的慢查询形状按 Equality-Sort-Range 规则推导候选索引，
+// 并与集合现有索引比对：已被覆盖的形状不再建议，前缀被候选 key 包含的普通索引列为可替代。
+func (c *Client) SlowlogAdvise(ctx context.Context, opts SlowlogAdviseOptions) (result *SlowlogAdviseResult, err error) {
+	if c != nil && c.session == nil {
+		return withEphemeralCollectorSession(ctx, c, func(session *CollectorSession) (*SlowlogAdviseResult, error) {
+			return session.SlowlogAdvise(ctx, opts)
+		})
+	}
+	if opts.MaxCandidates < 0 {
+		return nil, invalidOptions("max candidates must not be negative")
+	}
+	if opts.MaxCandidates == 0 {
+		opts.MaxCandidates = defaultSlowlogAdviseCandidates
+	}
+	if opts.DocsExaminedRatio < 0 {
+		return nil, invalidOptions("docs examined ratio must not be negative")
+	}
+	if opts.DocsExaminedRatio == 0 {
+		opts.DocsExaminedRatio = defaultSlowlogAdviseDocsExaminedRatio
+	}
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.requireMemberConnectionURI(); err != nil {
+		return nil, err
+	}
+	defer func() { err = mapContextError(err) }()
+
+	cluster, err := c.detectCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result = &SlowlogAdviseResult{ClusterType: convertClusterType(cluster.Type)}
+	if gate, allowed := diagnosticCapabilityGate("slowlog_advise", result.ClusterType, cluster.MaxWireVersion, true); !allowed {
+		result.CollectorStatuses = []CollectorStatus{gate}
+		return result, nil
+	}
+
+	var collectorErrors []error
+	summary, err := c.session.SlowlogSummary(ctx, opts.SlowlogOptions)
+	if err != nil {
+		if summary == nil || !errors.Is(err, ErrPartialResult) {
+			return nil, err
+		}
+		collectorErrors = append(collectorErrors, err)
+	}
+	result.CollectorStatuses = append(result.CollectorStatuses, summary.CollectorStatuses...)
+	merged, err := MergeSlowlogSummary(summary, SlowlogSortTotal)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := slowlogAdviseCandidates(merged.Items, opts.MaxCandidates, opts.DocsExaminedRatio)
+	for _, item := range candidates {
+		database, _, _ := strings.Cut(item.Namespace, ".")
+		scope := FindingScope{Type: ScopeNamespace, Namespace: item.Namespace}
+		detail, detailErr := c.session.SlowlogDetail(ctx, database, item.QueryHash)
+		if detailErr != nil {
+			if errors.Is(detailErr, drivermongo.ErrNoDocuments) {
+				result.CollectorStatuses = append(result.CollectorStatuses, CollectorStatus{Name: "slowlog_advise", State: CapabilitySkipped, Scope: scope, ReasonCode: "profile_missing", Message: "system.profile 中没有该查询形状的样本，无法提取查询字段"})
+				continue
+			}
+			if cancelErr := contextError(ctx); cancelErr != nil {
+				collectorErrors = append(collectorErrors, cancelErr)
+				break
+			}
+			result.CollectorStatuses = append(result.CollectorStatuses, failedCollectorStatus("slowlog_advise", scope, detailErr))
+			collectorErrors = append(collectorErrors, detailErr)
+			continue
+		}
+		suggestion, finding, ok := adviseSlowlogIndex(item, detail, opts.DocsExaminedRatio)
+		if finding != nil {
+			result.Findings = append(result.Findings, *finding)
+		}
+		if ok {
+			result.Suggestions = append(result.Suggestions, suggestion)
+		}
+	}
+	result.CollectorStatuses = append(result.CollectorStatuses, CollectorStatus{
+		Name: "slowlog_advise", State: CapabilitySupported, Scope: FindingScope{Type: ScopeCluster},
+		ReasonCode: "esr_rule",
+		Message:    fmt.Sprintf("分析 %d 个问题查询形状，生成 %d 条索引建议", len(candidates), len(result.Suggestions)),
+	})
+	sanitizeAndSortFindings(result.Findings)
+	sortCollectorStatuses(result.CollectorStatuses)
+	if len(collectorErrors) > 0 {
+		return result, newDiagnosticPartialError("slowlog-advise", result, errors.Join(collectorErrors...))
+	}
+	return result, nil
+}
+
+// slowlogAdviseCandidates 选出出现 COLLSCAN、内存排序或扫描比达到 docsRatio 的查询形状，按累计耗时截取前 limit 个。
+func slowlogAdviseCandidates(items []MergedSlowlogItem, limit int, docsRatio float64) []MergedSlowlogItem {
+	result := make([]MergedSlowlogItem, 0, min(len(items), limit))
+	for _, item := range items {
+		if len(result) >= limit {
+			break
+		}
+		if item.Operation == "insert" || item.QueryHash == "" || len(slowlogAdviseReasons(item.SlowlogSummaryItem, docsRatio)) == 0 {
+			continue
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+func slowlogAdviseReasons(item SlowlogSummaryItem, docsRatio float64) []string {
+	var reasons []string
+	if item.CollectionScanCount > 0 || strings.Contains(strings.ToUpper(item.PlanSummary), "COLLSCAN") {
+		reasons = append(reasons, "collection_scan")
+	}
+	if item.SortStageCount > 0 {
+		reasons = append(reasons, "blocking_sort")
+	}
+	if item.WorstDocsToReturned != nil && *item.WorstDocsToReturned >= docsRatio {
+		reasons = append(reasons, "docs_examined_high")
+	}
+	return reasons
+}
+
+// adviseSlowlogIndex 从 profile 样本提取查询形状并生成建议；已有索引覆盖时只返回提示 finding。
+func adviseSlowlogIndex(item MergedSlowlogItem, detail *SlowlogDetailResult, docsRatio float64) (IndexSuggestion, *DiagnosticFinding, bool) {
+	if detail == nil {
+		return IndexSuggestion{}, nil, false
+	}
+	shape := slowlogQueryShapeFromProfile(detail.orderedProfile())
+	proposed := shape.esrKey()
+	if len(proposed) == 0 {
+		return IndexSuggestion{}, nil, false
+	}
+	scope := FindingScope{Type: ScopeNamespace, Namespace: item.Namespace}
+	indexes := slowlogIndexDefinitions(detail.orderedIndexDocuments())
+	for _, index := range indexes {
+		if indexKeyCovers(index.key, shape) {
+			return IndexSuggestion{}, &DiagnosticFinding{
+				Code: "query.index_not_selected", Severity: SeverityInfo, Scope: scope,
+				Summary:        "已有索引覆盖该查询形状，但慢查询记录未使用该索引",
+				Evidence:       map[string]any{"queryHash": item.QueryHash, "indexName": index.name, "proposedKey": formatIndexKey(proposed)},
+				Recommendation: "检查索引是否被隐藏、collation 是否一致，或使用 explain 确认计划选择",
+			}, false
+		}
+	}
+	suggestion := IndexSuggestion{
+		Namespace: item.Namespace, QueryHash: item.QueryHash, Operation: item.Operation,
+		Count: item.Count, TotalMillis: item.TotalMillis, Reasons: slowlogAdviseReasons(item.SlowlogSummaryItem, docsRatio),
+		Equality: shape.equality, Sort: shape.sort, Range: shape.ranges, ProposedKey: proposed,
+	}
+	for _, index := range indexes {
+		if index.replaceable && keyPrefix(index.key, proposed) {
+			suggestion.Supersedes = append(suggestion.Supersedes, index.name)
+		}
+	}
+	evidence := map[string]any{
+		"queryHash": item.QueryHash, "count": item.Count, "reasons": suggestion.Reasons,
+		"proposedKey": formatIndexKey(proposed),
+	}
+	recommendation := "评估写入开销后创建候选索引，并用 explain 验证计划"
+	if len(suggestion.Supersedes) > 0 {
+		evidence["supersedes"] = suggestion.Supersedes
+		recommendation += "；新索引生效后可评估删除被其前缀覆盖的索引 " + strings.Join(suggestion.Supersedes, ", ")
+	}
+	return suggestion, &DiagnosticFinding{
+		Code: "query.index_suggestion", Severity: SeverityInfo, Scope: scope,
+		Summary:        "按 Equality-Sort-Range 规则建议索引 " + formatIndexKey(proposed),
+		Evidence:       evidence,
+		Recommendation: recommendation,
+	}, true
+}
+
+// esrKey 依次排列等值字段、排序字段与范围字段；已出现的字段不重复加入。
+func (s slowlogQueryShape) esrKey() []IndexKeyField {
+	equality, sortKey, ranges := s.esrSegments()
+	key := make([]IndexKeyField, 0, len(equality)+len(sortKey)+len(ranges))
+	key = append(key, equality...)
+	key = append(key, sortKey...)
+	return append(key, ranges...)
+}
+
+// esrSegments 返回去重后的 Equality、Sort、Range 三段，字段只保留在首次出现的段中。
+func (s slowlogQueryShape) esrSegments() (equality, sortKey, ranges []IndexKeyField) {
+	seen := make(map[string]struct{})
+	add := func(segment []IndexKeyField, field IndexKeyField) []IndexKeyField {
+		if _, exists := seen[field.Field]; exists {
+			return segment
+		}
+		seen[field.Field] = struct{}{}
+		return append(segment, field)
+	}
+	for _, field := range s.equality {
+		equality = add(equality, IndexKeyField{Field: field, Order: "1"})
+	}
+	for _, field := range s.sort {
+		sortKey = add(sortKey, field)
+	}
+	for _, field := range s.ranges {
+		ranges = add(ranges, IndexKeyField{Field: field, Order: "1"})
+	}
+	return equality, sortKey, ranges
+}
+
+// slowlogQueryShapeFromProfile 兼容 3.4 的 query/$query/$orderby 与 3.6+ 的 command 结构，
+// getmore 使用 originatingCommand；aggregate 只分析开头的 $match 与紧随其后的 $sort。
+func slowlogQueryShapeFromProfile(profile bson.M) slowlogQueryShape {
+	shape := collectSlowlogQueryShape(profile)
+	for _, field := range shape.in {
+		if len(shape.sort) > 0 {
+			shape.ranges = appendUnique(shape.ranges, field)
+		} else {
+			shape.equality = appendUnique(shape.equality, field)
+		}
+	}
+	shape.in = nil
+	return shape
+}
+
+func collectSlowlogQueryShape(profile bson.M) slowlogQueryShape {
+	var shape slowlogQueryShape
+	command := documentElements(profile["originatingCommand"])
+	if command == nil {
+		command = documentElements(profile["command"])
+	}
+	if command == nil {
+		command = documentElements(profile["query"])
+	}
+	if command == nil {
+		return shape
+	}
+	if pipeline, ok := elementValue(command, "pipeline").(bson.A); ok {
+		for i, stage := range pipeline {
+			elements := documentElements(stage)
+			if len(elements) != 1 {
+				break
+			}
+			if i == 0 && elements[0].Key == "$match" {
+				shape.addFilter(documentElements(elements[0].Value))
+				continue
+			}
+			if elements[0].Key == "$sort" {
+				shape.addSort(documentElements(elements[0].Value))
+			}
+			break
+		}
+		return shape
+	}
+	filter, found := bson.D(nil), false
+	for _, name := range []string{"filter", "query", "q", "$query"} {
+		if value := elementValue(command, name); value != nil {
+			filter, found = documentElements(value), true
+			break
+		}
+	}
+	if !found {
+		switch profile["op"] {
+		case "update", "remove":
+			// 3.4 的 update/remove 记录中 query 字段本身就是过滤条件。
+			filter = command
+		}
+	}
+	shape.addFilter(filter)
+	for _, name := range []string{"sort", "$orderby"} {
+		if value := elementValue(command, name); value != nil {
+			shape.addSort(documentElements(value))
+			break
+		}
+	}
+	return shape
+}
+
+func (s *slowlogQueryShape) addFilter(filter bson.D) {
+	for _, element := range filter {
+		if element.Key == "$and" {
+			if clauses, ok := element.Value.(bson.A); ok {
+				for _, clause := range clauses {
+					s.addFilter(documentElements(clause))
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(element.Key, "$") {
+			// $or/$nor/$expr/$text 等无法用单个复合索引表达，跳过。
+			continue
+		}
+		switch slowlogPredicate(element.Value) {
+		case slowlogPredicateEquality:
+			s.equality = appendUnique(s.equality, element.Key)
+		case slowlogPredicateIn:
+			s.in = appendUnique(s.in, element.Key)
+		default:
+			s.ranges = appendUnique(s.ranges, element.Key)
+		}
+	}
+}
+
+func (s *slowlogQueryShape) addSort(sortSpec bson.D) {
+	for _, element := range sortSpec {
+		order, ok := sortDirection(element.Value)
+		if !ok {
+			continue
+		}
+		s.sort = append(s.sort, IndexKeyField{Field: element.Key, Order: order})
+	}
+}
+
+func slowlogPredicate(value any) slowlogPredicateKind {
+	if _, ok := value.(primitive.Regex); ok {
+		return slowlogPredicateRange
+	}
+	elements := documentElements(value)
+	if len(elements) == 0 || !strings.HasPrefix(elements[0].Key, "$") {
+		return slowlogPredicateEquality
+	}
+	kind := slowlogPredicateEquality
+	for _, element := range elements {
+		if _, ok := slowlogEqualityOperators[element.Key]; !ok {
+			return slowlogPredicateRange
+		}
+		if values, ok := element.Value.(bson.A); ok && element.Key == "$in" && len(values) > 1 {
+			kind = slowlogPredicateIn
+		}
+	}
+	return kind
+}
+
+func sortDirection(value any) (string, bool) {
+	var direction float64
+	switch typed := value.(type) {
+	case int32:
+		direction = float64(typed)
+	case int64:
+		direction = float64(typed)
+	case float64:
+		direction = typed
+	default:
+		return "", false
+	}
+	if direction < 0 {
+		return "-1", true
+	}
+	return "1", true
+}
+
+// slowlogIndexDefinitions 解析 listIndexes 结果；unique、partial、sparse、TTL 与 _id 索引不会被建议替代。
+func slowlogIndexDefinitions(indexes []bson.M) []slowlogIndexDefinition {
+	result := make([]slowlogIndexDefinition, 0, len(indexes))
+	for _, index := range indexes {
+		name, _ := index["name"].(string)
+		definition := slowlogIndexDefinition{name: name, replaceable: name != "_id_"}
+		special := false
+		for _, element := range documentElements(index["key"]) {
+			order, ok := sortDirection(element.Value)
+			if !ok {
+				special = true
+				break
+			}
+			definition.key = append(definition.key, IndexKeyField{Field: element.Key, Order: order})
+		}
+		if special || len(definition.key) == 0 {
+			continue
+		}
+		for _, option := range []string{"unique", "sparse", "partialFilterExpression", "expireAfterSeconds"} {
+			if value, exists := index[option]; exists && value != false {
+				definition.replaceable = false
+			}
+		}
+		result = append(result, definition)
+	}
+	return result
+}
+
+// indexKeyCovers 按 ESR 三段判断 existing 的前缀能否服务该查询形状：等值段与范围段字段顺序任意，
+// 排序段必须按顺序出现且整体同向或整体反向。
+func indexKeyCovers(existing []IndexKeyField, shape slowlogQueryShape) bool {
+	equality, sortKey, ranges := shape.esrSegments()
+	if len(existing) < len(equality)+len(sortKey)+len(ranges) {
+		return false
+	}
+	if !sameIndexFieldSet(existing[:len(equality)], equality) {
+		return false
+	}
+	rest := existing[len(equality):]
+	forward, reverse := true, true
+	for i, field := range sortKey {
+		if rest[i].Field != field.Field {
+			return false
+		}
+		if rest[i].Order == field.Order {
+			reverse = false
+		} else {
+			forward = false
+		}
+	}
+	if !forward && !reverse {
+		return false
+	}
+	return sameIndexFieldSet(rest[len(sortKey):len(sortKey)+len(ranges)], ranges)
+}
+
+// sameIndexFieldSet 只比较字段名集合，忽略顺序与方向。
+func sameIndexFieldSet(left, right []IndexKeyField) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	fields := make(map[string]struct{}, len(right))
+	for _, field := range right {
+		fields[field.Field] = struct{}{}
+	}
+	for _, field := range left {
+		if _, ok := fields[field.Field]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func formatIndexKey(key []IndexKeyField) string {
+	parts := make([]string, 0, len(key))
+	for _, field := range key {
+		parts = append(parts, field.Field+": "+field.Order)
+	}
+	return "{ " + strings.Join(parts, ", ") + " }"
+}
+
+func documentElements(value any) bson.D {
+	switch typed := value.(type) {
+	case bson.D:
+		return typed
+	case bson.M:
+		// 无序 map 只出现在调用方手工构造的文档中，按字段名排序保证结果稳定。
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		result := make(bson.D, 0, len(typed))
+		for _, key := range keys {
+			result = append(result, bson.E{Key: key, Value: typed[key]})
+		}
+		return result
+	}
+	return nil
+}
+
+func elementValue(document bson.D, key string) any {
+	for _, element := range document {
+		if element.Key == key {
+			return element.Value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,428 @@
+package mot
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const slowlogSnapshotSchemaVersion = 1
+
+const (
+	defaultSlowlogDiffMinCount     = 5
+	defaultSlowlogDiffCountRatio   = 2
+	defaultSlowlogDiffLatencyRatio = 1.5
+	defaultSlowlogDiffDocsRatio    = 2
+)
+
+// NewSlowlogSnapshot 为 slowlog 概览结果附加 schema 版本、采集时间与时间窗，供离线 DiffSlowlog 使用。
+func NewSlowlogSnapshot(result *SlowlogSummaryResult, opts SlowlogOptions, collectedAt time.Time) SlowlogSnapshot {
+	snapshot := SlowlogSnapshot{SchemaVersion: slowlogSnapshotSchemaVersion, CollectedAt: collectedAt.UTC()}
+	if result != nil {
+		snapshot.Summary = *result
+		sanitizeAndSortFindings(snapshot.Summary.Findings)
+	}
+	if !opts.Since.IsZero() {
+		since := opts.Since.UTC()
+		snapshot.Since = &since
+	}
+	if !opts.Until.IsZero() {
+		until := opts.Until.UTC()
+		snapshot.Until = &until
+	}
+	return snapshot
+}
+
+// DiffSlowlog 离线比较两个 slowlog 快照：先按集群维度合并同一查询形状（ns/queryHash/op，跨计划合并），
+// 再输出新增、消失的形状与次数、avg/p95 耗时、maxDocsExamined 的变化；次数按各自时间窗换算为每小时速率后比较。
+// 超过阈值的回归与主计划变化分别以 finding 输出。不连接 MongoDB。
+func DiffSlowlog(before, after SlowlogSnapshot, opts SlowlogDiffOptions) (*SlowlogDiffResult, error) {
+	opts, err := normalizeSlowlogDiffOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if before.SchemaVersion != slowlogSnapshotSchemaVersion || after.SchemaVersion != slowlogSnapshotSchemaVersion {
+		return nil, invalidOptions("unsupported slowlog snapshot schema version")
+	}
+	if before.Summary.ClusterType != after.Summary.ClusterType {
+		return nil, invalidOptions("slowlog snapshots belong to different cluster types")
+	}
+	if !after.CollectedAt.After(before.CollectedAt) {
+		return nil, invalidOptions("after snapshot must be newer than before snapshot")
+	}
+	beforeItems, err := slowlogItemsByKey(before.Summary)
+	if err != nil {
+		return nil, err
+	}
+	afterItems, err := slowlogItemsByKey(after.Summary)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SlowlogDiffResult{SchemaVersion: slowlogSnapshotSchemaVersion, ClusterType: after.Summary.ClusterType, BeforeCollectedAt: before.CollectedAt, AfterCollectedAt: after.CollectedAt}
+	result.BeforeWindowHours = slowlogSnapshotWindowHours(before, beforeItems)
+	result.AfterWindowHours = slowlogSnapshotWindowHours(after, afterItems)
+	for _, key := range sortedUnionKeys(beforeItems, afterItems) {
+		previous, hadBefore := beforeItems[key]
+		current, hasAfter := afterItems[key]
+		reference := current
+		if !hasAfter {
+			reference = previous
+		}
+		item := SlowlogShapeDiff{
+			Namespace: reference.namespace, Operation: reference.operation, QueryHash: reference.queryHash, PlanSummary: reference.planSummary,
+			State: capacityLifecycleState(hadBefore, hasAfter),
+		}
+		var previousShape, currentShape *slowlogDiffShape
+		if hadBefore {
+			previousShape = &previous
+		}
+		if hasAfter {
+			currentShape = &current
+		}
+		item.Count = slowlogDelta(slowlogMetric(previousShape, slowlogCount), slowlogMetric(currentShape, slowlogCount))
+		item.CountPerHour = slowlogRate(previousShape, currentShape, result.BeforeWindowHours, result.AfterWindowHours)
+		item.AvgMillis = slowlogDelta(slowlogMetric(previousShape, slowlogAvgMillis), slowlogMetric(currentShape, slowlogAvgMillis))
+		item.P95Millis = slowlogDelta(slowlogMetric(previousShape, slowlogP95Millis), slowlogMetric(currentShape, slowlogP95Millis))
+		item.MaxDocsExamined = slowlogDelta(slowlogMetric(previousShape, slowlogMaxDocsExamined), slowlogMetric(currentShape, slowlogMaxDocsExamined))
+
+		switch item.State {
+		case "added":
+			result.Added++
+			if current.count >= opts.MinCount {
+				result.Findings = append(result.Findings, slowlogNewShapeFinding(item))
+			}
+		case "removed":
+			result.Removed++
+		default:
+			if previous.planSummary != current.planSummary {
+				item.PreviousPlanSummary = previous.planSummary
+			}
+			if current.count < opts.MinCount {
+				break
+			}
+			item.Regressions = slowlogRegressions(item, opts)
+			if len(item.Regressions) > 0 {
+				result.Findings = append(result.Findings, slowlogRegressionFinding(item))
+			}
+			if item.PreviousPlanSummary != "" {
+				finding := slowlogPlanChangeFinding(item, previous.plans, current.plans)
+				if finding.Severity == SeverityWarning {
+					item.Regressions = append(item.Regressions, "planSummary")
+				}
+				result.Findings = append(result.Findings, finding)
+			}
+			if len(item.Regressions) > 0 {
+				result.Regressed++
+			}
+		}
+		result.Items = append(result.Items, item)
+	}
+	sort.SliceStable(result.Items, func(i, j int) bool {
+		return slowlogDiffRank(result.Items[i]) < slowlogDiffRank(result.Items[j])
+	})
+	sanitizeAndSortFindings(result.Findings)
+	return result, nil
+}
+
+func normalizeSlowlogDiffOptions(opts SlowlogDiffOptions) (SlowlogDiffOptions, error) {
+	if opts.MinCount < 0 {
+		return opts, invalidOptions("min count must not be negative")
+	}
+	if opts.MinCount == 0 {
+		opts.MinCount = defaultSlowlogDiffMinCount
+	}
+	for _, ratio := range []struct {
+		name     string
+		value    *float64
+		fallback float64
+	}{
+		{name: "count", value: &opts.CountRatio, fallback: defaultSlowlogDiffCountRatio},
+		{name: "latency", value: &opts.LatencyRatio, fallback: defaultSlowlogDiffLatencyRatio},
+		{name: "docs", value: &opts.DocsRatio, fallback: defaultSlowlogDiffDocsRatio},
+	} {
+		if *ratio.value == 0 {
+			*ratio.value = ratio.fallback
+			continue
+		}
+		if *ratio.value <= 1 || math.IsNaN(*ratio.value) || math.IsInf(*ratio.value, 0) {
+			return opts, invalidOptions("%s ratio must be greater than 1", ratio.name)
+		}
+	}
+	return opts, nil
+}
+
+// slowlogDiffShape 是同一 ns/queryHash/op 跨计划合并后的指标；p95 取各计划 p95 的最大值，
+// planSummary 为次数最多的计划，plans 按次数降序列出全部计划。
+type slowlogDiffShape struct {
+	namespace, operation, queryHash string
+	planSummary                     string
+	plans                           []string
+	planCounts                      []int64
+	count, totalMillis              int64
+	p95Millis, maxDocsExamined      *int64
+	firstTime                       time.Time
+}
+
+// slowlogItemsByKey 按 ns/queryHash/op 分组；计划不参与匹配，主计划变化由 DiffSlowlog 单独报告。
+// legacy: 标识本身由计划摘要派生，旧版本上的计划变化会表现为形状新增与消失。
+func slowlogItemsByKey(summary SlowlogSummaryResult) (map[string]slowlogDiffShape, error) {
+	merged, err := MergeSlowlogSummary(&summary, SlowlogSortTotal)
+	if err != nil {
+		return nil, err
+	}
+	items := make(map[string]slowlogDiffShape, len(merged.Items))
+	for _, item := range merged.Items {
+		key := strings.Join([]string{item.Namespace, item.QueryHash, item.Operation}, "\x00")
+		shape, exists := items[key]
+		if !exists {
+			shape = slowlogDiffShape{namespace: item.Namespace, operation: item.Operation, queryHash: item.QueryHash}
+		}
+		shape.plans = append(shape.plans, item.PlanSummary)
+		shape.planCounts = append(shape.planCounts, item.Count)
+		shape.count += item.Count
+		shape.totalMillis += item.TotalMillis
+		shape.p95Millis = maxOptionalInt64(shape.p95Millis, item.P95Millis)
+		shape.maxDocsExamined = maxOptionalInt64(shape.maxDocsExamined, item.MaxDocsExamined)
+		if !item.FirstTime.IsZero() && (shape.firstTime.IsZero() || item.FirstTime.Before(shape.firstTime)) {
+			shape.firstTime = item.FirstTime
+		}
+		items[key] = shape
+	}
+	for key, shape := range items {
+		order := make([]int, len(shape.plans))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			if shape.planCounts[order[i]] != shape.planCounts[order[j]] {
+				return shape.planCounts[order[i]] > shape.planCounts[order[j]]
+			}
+			return shape.plans[order[i]] < shape.plans[order[j]]
+		})
+		plans := make([]string, len(order))
+		counts := make([]int64, len(order))
+		for i, index := range order {
+			plans[i], counts[i] = shape.plans[index], shape.planCounts[index]
+		}
+		shape.plans, shape.planCounts, shape.planSummary = plans, counts, plans[0]
+		items[key] = shape
+	}
+	return items, nil
+}
+
+func maxOptionalInt64(current, value *int64) *int64 {
+	if value == nil || (current != nil && *current >= *value) {
+		return current
+	}
+	copied := *value
+	return &copied
+}
+
+// slowlogSnapshotWindowHours 返回快照时间窗长度：下界取 Since，未设置时取最早的形状 firstTime；
+// 上界取 Until，未设置时取采集时间。无法确定或长度非正时返回 nil，次数按原始值比较。
+func slowlogSnapshotWindowHours(snapshot SlowlogSnapshot, shapes map[string]slowlogDiffShape) *float64 {
+	var start time.Time
+	if snapshot.Since != nil {
+		start = *snapshot.Since
+	} else {
+		for _, shape := range shapes {
+			if !shape.firstTime.IsZero() && (start.IsZero() || shape.firstTime.Before(start)) {
+				start = shape.firstTime
+			}
+		}
+	}
+	end := snapshot.CollectedAt
+	if snapshot.Until != nil {
+		end = *snapshot.Until
+	}
+	if start.IsZero() || !end.After(start) {
+		return nil
+	}
+	hours := end.Sub(start).Hours()
+	return &hours
+}
+
+// slowlogRate 把两侧次数换算为每小时速率；任一快照时间窗未知时返回 nil。
+func slowlogRate(before, after *slowlogDiffShape, beforeHours, afterHours *float64) *SlowlogRateDelta {
+	if beforeHours == nil || afterHours == nil {
+		return nil
+	}
+	result := &SlowlogRateDelta{}
+	if before != nil {
+		rate := float64(before.count) / *beforeHours
+		result.Before = &rate
+	}
+	if after != nil {
+		rate := float64(after.count) / *afterHours
+		result.After = &rate
+	}
+	if result.Before != nil && result.After != nil && *result.Before > 0 {
+		ratio := *result.After / *result.Before
+		result.Ratio = &ratio
+	}
+	return result
+}
+
+type slowlogMetricKind int
+
+const (
+	slowlogCount slowlogMetricKind = iota
+	slowlogAvgMillis
+	slowlogP95Millis
+	slowlogMaxDocsExamined
+)
+
+func slowlogMetric(item *slowlogDiffShape, kind slowlogMetricKind) *int64 {
+	if item == nil {
+		return nil
+	}
+	var value int64
+	switch kind {
+	case slowlogCount:
+		value = item.count
+	case slowlogAvgMillis:
+		if item.count == 0 {
+			return nil
+		}
+		value = int64(math.Round(float64(item.totalMillis) / float64(item.count)))
+	case slowlogP95Millis:
+		if item.p95Millis == nil {
+			return nil
+		}
+		value = *item.p95Millis
+	case slowlogMaxDocsExamined:
+		if item.maxDocsExamined == nil {
+			return nil
+		}
+		value = *item.maxDocsExamined
+	}
+	return &value
+}
+
+func slowlogDelta(before, after *int64) SlowlogDelta {
+	result := SlowlogDelta{Before: before, After: after}
+	if before != nil && after != nil {
+		delta := *after - *before
+		result.Delta = &delta
+		if *before > 0 {
+			ratio := float64(*after) / float64(*before)
+			result.Ratio = &ratio
+		}
+	}
+	return result
+}
+
+func slowlogRegressions(item SlowlogShapeDiff, opts SlowlogDiffOptions) []string {
+	var regressions []string
+	countRatio := item.Count.Ratio
+	if item.CountPerHour != nil {
+		countRatio = item.CountPerHour.Ratio
+	}
+	for _, metric := range []struct {
+		name      string
+		ratio     *float64
+		threshold float64
+	}{
+		{name: "count", ratio: countRatio, threshold: opts.CountRatio},
+		{name: "avgMillis", ratio: item.AvgMillis.Ratio, threshold: opts.LatencyRatio},
+		{name: "p95Millis", ratio: item.P95Millis.Ratio, threshold: opts.LatencyRatio},
+		{name: "maxDocsExamined", ratio: item.MaxDocsExamined.Ratio, threshold: opts.DocsRatio},
+	} {
+		if metric.ratio != nil && *metric.ratio >= metric.threshold {
+			regressions = append(regressions, metric.name)
+		}
+	}
+	return regressions
+}
+
+func slowlogRegressionFinding(item SlowlogShapeDiff) DiagnosticFinding {
+	evidence := map[string]any{
+		"queryHash": item.QueryHash, "operation": item.Operation, "planSummary": item.PlanSummary,
+		"regressions": strings.Join(item.Regressions, ","),
+	}
+	for _, metric := range []struct {
+		name  string
+		delta SlowlogDelta
+	}{
+		{name: "count", delta: item.Count},
+		{name: "avgMillis", delta: item.AvgMillis},
+		{name: "p95Millis", delta: item.P95Millis},
+		{name: "maxDocsExamined", delta: item.MaxDocsExamined},
+	} {
+		if metric.delta.Before != nil {
+			evidence[metric.name+"Before"] = *metric.delta.Before
+		}
+		if metric.delta.After != nil {
+			evidence[metric.name+"After"] = *metric.delta.After
+		}
+	}
+	if item.CountPerHour != nil && item.CountPerHour.Before != nil && item.CountPerHour.After != nil {
+		evidence["countPerHourBefore"] = *item.CountPerHour.Before
+		evidence["countPerHourAfter"] = *item.CountPerHour.After
+	}
+	return DiagnosticFinding{
+		Code: "slowlog.regression", Severity: SeverityWarning,
+		Scope:          FindingScope{Type: ScopeNamespace, Namespace: item.Namespace},
+		Summary:        "查询形状在 after 快照中的次数、耗时或扫描文档数超过回归阈值",
+		Evidence:       evidence,
+		Recommendation: "结合变更窗口检查该查询形状的执行计划、索引与数据量变化，可使用 slowlog --hash 查看最新样本",
+	}
+}
+
+// slowlogPlanChangeFinding 报告主计划变化；after 的主计划出现 COLLSCAN 而 before 没有时视为回归。
+func slowlogPlanChangeFinding(item SlowlogShapeDiff, beforePlans, afterPlans []string) DiagnosticFinding {
+	severity := SeverityInfo
+	summary := "查询形状的主执行计划在两个快照之间发生变化"
+	if slowlogPlanScansCollection(item.PlanSummary) && !slowlogPlanScansCollection(item.PreviousPlanSummary) {
+		severity = SeverityWarning
+		summary = "查询形状的主执行计划由索引扫描退化为集合扫描"
+	}
+	return DiagnosticFinding{
+		Code: "slowlog.plan_changed", Severity: severity,
+		Scope:   FindingScope{Type: ScopeNamespace, Namespace: item.Namespace},
+		Summary: summary,
+		Evidence: map[string]any{
+			"queryHash": item.QueryHash, "operation": item.Operation,
+			"planSummaryBefore": item.PreviousPlanSummary, "planSummaryAfter": item.PlanSummary,
+			"plansBefore": strings.Join(beforePlans, " | "), "plansAfter": strings.Join(afterPlans, " | "),
+		},
+		Recommendation: "检查索引是否被删除、隐藏或重建，以及数据分布变化是否导致计划重新选择，可使用 slowlog --hash --explain 确认当前计划",
+	}
+}
+
+func slowlogPlanScansCollection(planSummary string) bool {
+	return strings.Contains(strings.ToUpper(planSummary), "COLLSCAN")
+}
+
+func slowlogNewShapeFinding(item SlowlogShapeDiff) DiagnosticFinding {
+	evidence := map[string]any{"queryHash": item.QueryHash, "operation": item.Operation, "planSummary": item.PlanSummary}
+	if item.Count.After != nil {
+		evidence["countAfter"] = *item.Count.After
+	}
+	if item.AvgMillis.After != nil {
+		evidence["avgMillisAfter"] = *item.AvgMillis.After
+	}
+	return DiagnosticFinding{
+		Code: "slowlog.new_query_shape", Severity: SeverityInfo,
+		Scope:          FindingScope{Type: ScopeNamespace, Namespace: item.Namespace},
+		Summary:        "after 快照中出现了 before 快照没有的慢查询形状",
+		Evidence:       evidence,
+		Recommendation: "确认该查询形状是否来自新发布的代码路径，并检查其执行计划",
+	}
+}
+
+// slowlogDiffRank 让回归形状排在最前，其后依次为新增、消失和无显著变化的形状。
+func slowlogDiffRank(item SlowlogShapeDiff) int {
+	switch {
+	case len(item.Regressions) > 0:
+		return 0
+	case item.State == "added":
+		return 1
+	case item.State == "removed":
+		return 2
+	default:
+		return 3
+	}
+}
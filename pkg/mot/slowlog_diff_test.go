@@ -0,0 +1,136 @@
+package mot
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func slowlogDiffTestSnapshot(collectedAt time.Time, items ...SlowlogSummaryItem) SlowlogSnapshot {
+	summary := &SlowlogSummaryResult{ClusterType: ClusterSharded, ReplicaSets: []ReplicaSetSlowlogSummary{
+		{Name: "shard01", Hosts: []HostSlowlogSummary{{Address: "n1:27017", Databases: []DatabaseSlowlogSummary{{Database: "app", Items: items}}}}},
+	}}
+	return NewSlowlogSnapshot(summary, SlowlogOptions{}, collectedAt)
+}
+
+func TestDiffSlowlogReportsShapeLifecycleAndRegressions(t *testing.T) {
+	// 测试按查询形状对比两个快照：新增、消失与超过阈值的回归分别标记，回归排在最前并输出 finding。
+	docs, moreDocs := int64(100), int64(5000)
+	p95, slowerP95 := int64(40), int64(45)
+	before := slowlogDiffTestSnapshot(time.Unix(10, 0),
+		SlowlogSummaryItem{Namespace: "app.orders", Operation: "query", QueryHash: "AAAA", PlanSummary: "IXSCAN { status: 1 }", Count: 10, TotalMillis: 300, P95Millis: &p95, MaxDocsExamined: &docs},
+		SlowlogSummaryItem{Namespace: "app.users", Operation: "query", QueryHash: "BBBB", Count: 20, TotalMillis: 400},
+		SlowlogSummaryItem{Namespace: "app.events", Operation: "update", QueryHash: "DDDD", Count: 8, TotalMillis: 800},
+	)
+	after := slowlogDiffTestSnapshot(time.Unix(20, 0),
+		SlowlogSummaryItem{Namespace: "app.orders", Operation: "query", QueryHash: "AAAA", PlanSummary: "IXSCAN { status: 1 }", Count: 12, TotalMillis: 360, P95Millis: &slowerP95, MaxDocsExamined: &moreDocs},
+		SlowlogSummaryItem{Namespace: "app.orders", Operation: "query", QueryHash: "CCCC", PlanSummary: "COLLSCAN", Count: 7, TotalMillis: 700},
+		SlowlogSummaryItem{Namespace: "app.events", Operation: "update", QueryHash: "DDDD", Count: 9, TotalMillis: 900},
+	)
+
+	result, err := DiffSlowlog(before, after, SlowlogDiffOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 1 || result.Removed != 1 || result.Regressed != 1 || len(result.Items) != 4 {
+		t.Fatalf("result = %#v", result)
+	}
+	regressed := result.Items[0]
+	if regressed.QueryHash != "AAAA" || len(regressed.Regressions) != 1 || regressed.Regressions[0] != "maxDocsExamined" {
+		t.Fatalf("regressed = %#v", regressed)
+	}
+	if regressed.Count.Delta == nil || *regressed.Count.Delta != 2 || regressed.AvgMillis.Before == nil || *regressed.AvgMillis.Before != 30 {
+		t.Fatalf("regressed deltas = %#v", regressed)
+	}
+	if result.Items[1].State != "added" || result.Items[1].Count.Before != nil || result.Items[1].Count.Delta != nil {
+		t.Fatalf("added = %#v", result.Items[1])
+	}
+	if result.Items[2].State != "removed" || result.Items[2].QueryHash != "BBBB" || result.Items[3].State != "existing" {
+		t.Fatalf("items = %#v", result.Items)
+	}
+	assertFindingCode(t, result.Findings, "slowlog.regression", SeverityWarning)
+	assertFindingCode(t, result.Findings, "slowlog.new_query_shape", SeverityInfo)
+}
+
+func TestDiffSlowlogRejectsIncompatibleSnapshotsAndThresholds(t *testing.T) {
+	// 测试 schema、集群类型、时间顺序或阈值非法时拒绝比较，避免生成误导性的回归结论。
+	before := slowlogDiffTestSnapshot(time.Unix(10, 0))
+	after := slowlogDiffTestSnapshot(time.Unix(20, 0))
+	if _, err := DiffSlowlog(after, before, SlowlogDiffOptions{}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("reversed snapshots error = %v", err)
+	}
+	if _, err := DiffSlowlog(before, after, SlowlogDiffOptions{LatencyRatio: 0.5}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("ratio error = %v", err)
+	}
+	other := after
+	other.Summary.ClusterType = ClusterReplicaSet
+	if _, err := DiffSlowlog(before, other, SlowlogDiffOptions{}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("cluster type error = %v", err)
+	}
+	legacy := after
+	legacy.SchemaVersion = 0
+	if _, err := DiffSlowlog(before, legacy, SlowlogDiffOptions{}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("schema error = %v", err)
+	}
+}
+
+func TestDiffSlowlogMatchesAcrossPlansAndReportsPlanChange(t *testing.T) {
+	// 测试同一 ns/queryHash/op 的计划变化不会拆成新增与消失，IXSCAN 退化为 COLLSCAN 时输出 plan_changed 回归。
+	before := slowlogDiffTestSnapshot(time.Unix(10, 0),
+		SlowlogSummaryItem{Namespace: "app.orders", Operation: "query", QueryHash: "AAAA", PlanSummary: "IXSCAN { status: 1 }", Count: 10, TotalMillis: 300},
+	)
+	after := slowlogDiffTestSnapshot(time.Unix(20, 0),
+		SlowlogSummaryItem{Namespace: "app.orders", Operation: "query", QueryHash: "AAAA", PlanSummary: "IXSCAN { status: 1 }", Count: 2, TotalMillis: 60},
+		SlowlogSummaryItem{Namespace: "app.orders", Operation: "query", QueryHash: "AAAA", PlanSummary: "COLLSCAN", Count: 9, TotalMillis: 270},
+	)
+
+	result, err := DiffSlowlog(before, after, SlowlogDiffOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 0 || result.Removed != 0 || result.Regressed != 1 || len(result.Items) != 1 {
+		t.Fatalf("result = %#v", result)
+	}
+	item := result.Items[0]
+	if item.State != "existing" || item.PlanSummary != "COLLSCAN" || item.PreviousPlanSummary != "IXSCAN { status: 1 }" {
+		t.Fatalf("item = %#v", item)
+	}
+	if item.Count.After == nil || *item.Count.After != 11 || len(item.Regressions) != 1 || item.Regressions[0] != "planSummary" {
+		t.Fatalf("item = %#v", item)
+	}
+	assertFindingCode(t, result.Findings, "slowlog.plan_changed", SeverityWarning)
+}
+
+func TestDiffSlowlogNormalizesCountsToHourlyRate(t *testing.T) {
+	// 测试次数按各自 Since/Until 时间窗换算为每小时速率后再判定回归，窗口更长的快照不会被误判为次数上涨。
+	end := time.Date(2026, 7, 20, 12, 0, 0, 0, time.UTC)
+	snapshot := func(window time.Duration, count int64, collectedAt time.Time) SlowlogSnapshot {
+		summary := &SlowlogSummaryResult{ClusterType: ClusterReplicaSet, ReplicaSets: []ReplicaSetSlowlogSummary{
+			{Name: "rs0", Hosts: []HostSlowlogSummary{{Address: "n1:27017", Databases: []DatabaseSlowlogSummary{{Database: "app", Items: []SlowlogSummaryItem{
+				{Namespace: "app.orders", Operation: "query", QueryHash: "AAAA", PlanSummary: "COLLSCAN", Count: count, TotalMillis: count * 10},
+			}}}}}},
+		}}
+		return NewSlowlogSnapshot(summary, SlowlogOptions{Since: collectedAt.Add(-window), Until: collectedAt}, collectedAt)
+	}
+
+	result, err := DiffSlowlog(snapshot(time.Hour, 10, end), snapshot(4*time.Hour, 30, end.Add(time.Hour)), SlowlogDiffOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := result.Items[0]
+	if result.AfterWindowHours == nil || *result.AfterWindowHours != 4 || item.CountPerHour == nil || item.CountPerHour.After == nil || *item.CountPerHour.After != 7.5 {
+		t.Fatalf("rate = %#v, windows = %v/%v", item.CountPerHour, result.BeforeWindowHours, result.AfterWindowHours)
+	}
+	if len(item.Regressions) != 0 {
+		t.Fatalf("regressions = %v, want none for a lower hourly rate", item.Regressions)
+	}
+
+	result, err = DiffSlowlog(snapshot(4*time.Hour, 10, end), snapshot(time.Hour, 30, end.Add(time.Hour)), SlowlogDiffOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item := result.Items[0]; len(item.Regressions) != 1 || item.Regressions[0] != "count" || item.CountPerHour.Ratio == nil || *item.CountPerHour.Ratio != 12 {
+		t.Fatalf("item = %#v", item)
+	}
+	assertFindingCode(t, result.Findings, "slowlog.regression", SeverityWarning)
+}
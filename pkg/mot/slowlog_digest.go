@@ -0,0 +1,295 @@
+package mot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	drivermongo "go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	defaultSlowlogDigestQueries = 20
+	maxSlowlogExampleBytes      = 1024
+	slowlogExamplePlaceholder   = "?"
+)
+
+// slowlogExamplePreservedFields 是命令顶层只描述排序、索引提示与分页的字段，样本命令中保留原值；
+// 过滤条件或文档中的同名字段仍是用户数据，照常替换为占位符。
+var slowlogExamplePreservedFields = map[string]struct{}{
+	"sort": {}, "hint": {}, "limit": {}, "skip": {}, "batchSize": {},
+}
+
+// slowlogExamplePreservedStages 是 aggregate pipeline 中取值只描述排序与分页的 stage。
+var slowlogExamplePreservedStages = map[string]struct{}{"$sort": {}, "$limit": {}, "$skip": {}}
+
+// slowlogDigestLatencyBounds 是 digest 延迟分布的十倍程区间下界（毫秒），最后一个区间没有上界。
+var slowlogDigestLatencyBounds = []int64{0, 1, 10, 100, 1000, 10000}
+
+// SlowlogDigest 生成 pt-query-digest 风格的报告：跨节点合并查询形状后按排名截取，
+// 并为每个形状读取最新 profile 样本，输出取值替换为 "?" 的样本命令。
+func (c *Client) SlowlogDigest(ctx context.Context, opts SlowlogDigestOptions) (result *SlowlogDigestResult, err error) {
+	if c != nil && c.session == nil {
+		return withEphemeralCollectorSession(ctx, c, func(session *CollectorSession) (*SlowlogDigestResult, error) {
+			return session.SlowlogDigest(ctx, opts)
+		})
+	}
+	if opts.MaxQueries < 0 {
+		return nil, invalidOptions("max queries must not be negative")
+	}
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.requireMemberConnectionURI(); err != nil {
+		return nil, err
+	}
+	defer func() { err = mapContextError(err) }()
+
+	cluster, err := c.detectCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if gate, allowed := diagnosticCapabilityGate("slowlog_digest", convertClusterType(cluster.Type), cluster.MaxWireVersion, true); !allowed {
+		return &SlowlogDigestResult{ClusterType: convertClusterType(cluster.Type), CollectorStatuses: []CollectorStatus{gate}}, nil
+	}
+
+	var collectorErrors []error
+	summary, err := c.session.SlowlogSummary(ctx, opts.SlowlogOptions)
+	if err != nil {
+		if summary == nil || !errors.Is(err, ErrPartialResult) {
+			return nil, err
+		}
+		collectorErrors = append(collectorErrors, err)
+	}
+	result, err = BuildSlowlogDigest(summary, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	examples := 0
+	for i := range result.Queries {
+		query := &result.Queries[i]
+		database, _, _ := strings.Cut(query.Namespace, ".")
+		detail, detailErr := c.session.SlowlogDetail(ctx, database, query.QueryHash)
+		if detailErr != nil {
+			if errors.Is(detailErr, drivermongo.ErrNoDocuments) {
+				continue
+			}
+			if cancelErr := contextError(ctx); cancelErr != nil {
+				collectorErrors = append(collectorErrors, cancelErr)
+				break
+			}
+			result.CollectorStatuses = append(result.CollectorStatuses, failedCollectorStatus("slowlog_digest", FindingScope{Type: ScopeNamespace, Namespace: query.Namespace}, detailErr))
+			collectorErrors = append(collectorErrors, detailErr)
+			continue
+		}
+		if query.ExampleCommand = slowlogExampleCommand(detail.orderedProfile()); query.ExampleCommand != "" {
+			examples++
+		}
+	}
+	result.CollectorStatuses = append(result.CollectorStatuses, CollectorStatus{
+		Name: "slowlog_digest", State: CapabilitySupported, Scope: FindingScope{Type: ScopeCluster},
+		ReasonCode: "examples_redacted",
+		Message:    fmt.Sprintf("为 %d/%d 个查询形状附带脱敏样本命令", examples, len(result.Queries)),
+	})
+	sortCollectorStatuses(result.CollectorStatuses)
+	if len(collectorErrors) > 0 {
+		return result, newDiagnosticPartialError("slowlog-digest", result, errors.Join(collectorErrors...))
+	}
+	return result, nil
+}
+
+// BuildSlowlogDigest 从 slowlog 概览构建 digest 报告，不读取样本命令；--from-log 离线结果直接使用。
+func BuildSlowlogDigest(summary *SlowlogSummaryResult, opts SlowlogDigestOptions) (*SlowlogDigestResult, error) {
+	if opts.MaxQueries < 0 {
+		return nil, invalidOptions("max queries must not be negative")
+	}
+	if opts.MaxQueries == 0 {
+		opts.MaxQueries = defaultSlowlogDigestQueries
+	}
+	if opts.Sort == "" {
+		opts.Sort = SlowlogSortTotal
+	}
+	merged, err := MergeSlowlogSummary(summary, opts.Sort)
+	if err != nil {
+		return nil, err
+	}
+	result := &SlowlogDigestResult{
+		ClusterType: merged.ClusterType, Total: merged.Total, TotalMillis: merged.TotalMillis, UniqueShapes: len(merged.Items),
+		Findings: merged.Findings, CollectorStatuses: merged.CollectorStatuses,
+	}
+	if !opts.Since.IsZero() {
+		since := opts.Since.UTC()
+		result.Since = &since
+	}
+	if !opts.Until.IsZero() {
+		until := opts.Until.UTC()
+		result.Until = &until
+	}
+	for _, item := range merged.Items {
+		if !item.FirstTime.IsZero() && (result.FirstTime.IsZero() || item.FirstTime.Before(result.FirstTime)) {
+			result.FirstTime = item.FirstTime
+		}
+		if item.LastTime.After(result.LastTime) {
+			result.LastTime = item.LastTime
+		}
+	}
+	for i, item := range merged.Items[:min(len(merged.Items), opts.MaxQueries)] {
+		result.Queries = append(result.Queries, SlowlogDigestQuery{Rank: i + 1, MergedSlowlogItem: item, LatencyHistogram: slowlogDigestLatencyHistogram(item)})
+	}
+	result.Hosts = slowlogDigestHosts(summary)
+	return result, nil
+}
+
+// slowlogDigestLatencyHistogram 把合并后的 1/4 倍频程 bucket 按几何中点归入十倍程区间；
+// percentile 不是由合并 histogram 得出时（例如快照来源缺少 bucket）返回 nil。
+func slowlogDigestLatencyHistogram(item MergedSlowlogItem) []SlowlogLatencyBucket {
+	if item.PercentileSource != SlowlogPercentileHistogram || len(item.millisHistogram) == 0 {
+		return nil
+	}
+	result := make([]SlowlogLatencyBucket, len(slowlogDigestLatencyBounds))
+	for i, lower := range slowlogDigestLatencyBounds {
+		result[i].LowerMillis = lower
+		if i+1 < len(slowlogDigestLatencyBounds) {
+			result[i].UpperMillis = slowlogDigestLatencyBounds[i+1]
+		}
+	}
+	for _, bucket := range item.millisHistogram {
+		estimate := pkgmongo.SlowlogBucketEstimate(bucket.Bucket)
+		index := sort.Search(len(slowlogDigestLatencyBounds), func(i int) bool { return slowlogDigestLatencyBounds[i] > estimate }) - 1
+		result[max(index, 0)].Count += bucket.Count
+	}
+	return result
+}
+
+// slowlogDigestHosts 按节点汇总慢操作次数与累计耗时，耗时最多的节点排在最前。
+func slowlogDigestHosts(summary *SlowlogSummaryResult) []SlowlogDigestHost {
+	if summary == nil {
+		return nil
+	}
+	var hosts []SlowlogDigestHost
+	for _, replicaSet := range summary.ReplicaSets {
+		for _, host := range replicaSet.Hosts {
+			item := SlowlogDigestHost{ReplicaSet: replicaSet.Name, Host: host.Address}
+			for _, database := range host.Databases {
+				for _, summaryItem := range database.Items {
+					item.Count += summaryItem.Count
+					item.TotalMillis += summaryItem.TotalMillis
+				}
+			}
+			if item.Count > 0 {
+				hosts = append(hosts, item)
+			}
+		}
+	}
+	sort.SliceStable(hosts, func(i, j int) bool {
+		if hosts[i].TotalMillis != hosts[j].TotalMillis {
+			return hosts[i].TotalMillis > hosts[j].TotalMillis
+		}
+		if hosts[i].ReplicaSet != hosts[j].ReplicaSet {
+			return hosts[i].ReplicaSet < hosts[j].ReplicaSet
+		}
+		return hosts[i].Host < hosts[j].Host
+	})
+	return hosts
+}
+
+// slowlogExampleCommand 从 profile 样本取出命令（getMore 使用 originatingCommand，3.4 使用 query），
+// 剔除会话与元数据字段，把取值替换为 "?" 后输出 relaxed Extended JSON；超长时截断。
+func slowlogExampleCommand(profile bson.M) string {
+	command := documentElements(profile["originatingCommand"])
+	if command == nil {
+		command = documentElements(profile["command"])
+	}
+	if command == nil {
+		command = documentElements(profile["query"])
+	}
+	if len(command) == 0 {
+		return ""
+	}
+	namespace, _ := profile["ns"].(string)
+	_, collection, _ := strings.Cut(namespace, ".")
+	redacted := make(bson.D, 0, len(command))
+	for i, element := range command {
+		// 会话、事务与 shard 内部参数沿用 explain 重放的剔除规则，comment 属于客户端元数据，样本中同样剔除。
+		if pkgmongo.SlowlogCommandDroppedField(element.Key) || element.Key == "comment" {
+			continue
+		}
+		// 只有首字段取值恰为集合名时才视为命令名保留；3.4 的 query 可能只是过滤条件。
+		if value, ok := element.Value.(string); ok && i == 0 && collection != "" && value == collection {
+			redacted = append(redacted, element)
+			continue
+		}
+		if _, preserved := slowlogExamplePreservedFields[element.Key]; preserved {
+			redacted = append(redacted, element)
+			continue
+		}
+		if element.Key == "pipeline" {
+			redacted = append(redacted, bson.E{Key: element.Key, Value: redactSlowlogExamplePipeline(element.Value)})
+			continue
+		}
+		redacted = append(redacted, bson.E{Key: element.Key, Value: redactSlowlogExampleValue(element.Value)})
+	}
+	payload, err := bson.MarshalExtJSON(redacted, false, false)
+	if err != nil {
+		return ""
+	}
+	if len(payload) > maxSlowlogExampleBytes {
+		// 在 UTF-8 字符边界截断，避免输出半个多字节字符。
+		cut := maxSlowlogExampleBytes
+		for cut > 0 && !utf8.RuneStart(payload[cut]) {
+			cut--
+		}
+		return string(payload[:cut]) + "..."
+	}
+	return string(payload)
+}
+
+// redactSlowlogExamplePipeline 保留 $sort/$limit/$skip stage 的取值，其余 stage 与嵌套 pipeline 照常脱敏。
+func redactSlowlogExamplePipeline(value any) any {
+	stages, ok := value.(bson.A)
+	if !ok {
+		return redactSlowlogExampleValue(value)
+	}
+	redacted := make(bson.A, 0, len(stages))
+	for _, stage := range stages {
+		elements := documentElements(stage)
+		if len(elements) == 1 {
+			if _, preserved := slowlogExamplePreservedStages[elements[0].Key]; preserved {
+				redacted = append(redacted, elements)
+				continue
+			}
+		}
+		redacted = append(redacted, redactSlowlogExampleValue(stage))
+	}
+	return redacted
+}
+
+func redactSlowlogExampleValue(value any) any {
+	if document := documentElements(value); document != nil {
+		redacted := make(bson.D, 0, len(document))
+		for _, element := range document {
+			redacted = append(redacted, bson.E{Key: element.Key, Value: redactSlowlogExampleValue(element.Value)})
+		}
+		return redacted
+	}
+	if values, ok := value.(bson.A); ok {
+		// 文档数组（$or、updates、嵌套 pipeline）逐个保留结构，标量数组折叠为单个占位符。
+		redacted := bson.A{}
+		for _, item := range values {
+			if documentElements(item) != nil {
+				redacted = append(redacted, redactSlowlogExampleValue(item))
+			}
+		}
+		if len(redacted) == 0 && len(values) > 0 {
+			redacted = append(redacted, slowlogExamplePlaceholder)
+		}
+		return redacted
+	}
+	return slowlogExamplePlaceholder
+}
@@ -0,0 +1,135 @@
+package mot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestBuildSlowlogDigestRanksShapesAndHosts(t *testing.T) {
+	// 测试 digest 默认按累计耗时排名并截取，节点分布按耗时降序，观察时间窗覆盖所有查询形状。
+	first, last := time.Date(2026, 7, 20, 8, 0, 0, 0, time.UTC), time.Date(2026, 7, 20, 9, 0, 0, 0, time.UTC)
+	summary := &SlowlogSummaryResult{ClusterType: ClusterSharded, ReplicaSets: []ReplicaSetSlowlogSummary{
+		{Name: "shard01", Hosts: []HostSlowlogSummary{{Address: "n1:27017", Databases: []DatabaseSlowlogSummary{{Database: "app", Items: []SlowlogSummaryItem{
+			{Namespace: "app.orders", Operation: "query", QueryHash: "AAAA", Count: 50, TotalMillis: 500, FirstTime: first, LastTime: first},
+			{Namespace: "app.users", Operation: "query", QueryHash: "BBBB", Count: 2, TotalMillis: 4000, FirstTime: last, LastTime: last},
+		}}}}}},
+		{Name: "shard02", Hosts: []HostSlowlogSummary{{Address: "n2:27017", Databases: []DatabaseSlowlogSummary{{Database: "app", Items: []SlowlogSummaryItem{
+			{Namespace: "app.events", Operation: "update", QueryHash: "CCCC", Count: 1, TotalMillis: 100},
+		}}}}}},
+	}}
+	since := first.Add(-time.Hour)
+	result, err := BuildSlowlogDigest(summary, SlowlogDigestOptions{SlowlogOptions: SlowlogOptions{Since: since}, MaxQueries: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 53 || result.TotalMillis != 4600 || result.UniqueShapes != 3 || len(result.Queries) != 2 {
+		t.Fatalf("result = %#v", result)
+	}
+	if result.Queries[0].Rank != 1 || result.Queries[0].QueryHash != "BBBB" || result.Queries[1].QueryHash != "AAAA" {
+		t.Fatalf("queries = %#v", result.Queries)
+	}
+	if len(result.Hosts) != 2 || result.Hosts[0].Host != "n1:27017" || result.Hosts[0].TotalMillis != 4500 || result.Hosts[1].Count != 1 {
+		t.Fatalf("hosts = %#v", result.Hosts)
+	}
+	if result.Since == nil || !result.Since.Equal(since) || result.Until != nil || !result.FirstTime.Equal(first) || !result.LastTime.Equal(last) {
+		t.Fatalf("window = %v/%v first=%v last=%v", result.Since, result.Until, result.FirstTime, result.LastTime)
+	}
+}
+
+func TestSlowlogExampleCommandRedactsValues(t *testing.T) {
+	// 测试样本命令保留结构、集合名与排序，取值替换为 "?"，会话与 $ 元数据字段被剔除。
+	profile := bson.M{
+		"ns": "app.orders",
+		"command": bson.D{
+			{Key: "find", Value: "orders"},
+			{Key: "filter", Value: bson.D{{Key: "status", Value: "paid"}, {Key: "userId", Value: bson.D{{Key: "$in", Value: bson.A{int32(1), int32(2)}}}}, {Key: "$or", Value: bson.A{bson.D{{Key: "email", Value: "a@example.com"}}}}}},
+			{Key: "sort", Value: bson.D{{Key: "createdAt", Value: int32(-1)}}},
+			{Key: "limit", Value: int32(10)},
+			{Key: "lsid", Value: bson.D{{Key: "id", Value: "session"}}},
+			{Key: "$db", Value: "app"},
+		},
+	}
+	got := slowlogExampleCommand(profile)
+	want := `{"find":"orders","filter":{"status":"?","userId":{"$in":["?"]},"$or":[{"email":"?"}]},"sort":{"createdAt":-1},"limit":10}`
+	if got != want {
+		t.Fatalf("example = %s\nwant %s", got, want)
+	}
+
+	legacy := slowlogExampleCommand(bson.M{"ns": "app.orders", "query": bson.D{{Key: "tenant", Value: "secret"}}})
+	if legacy != `{"tenant":"?"}` {
+		t.Fatalf("legacy example = %s", legacy)
+	}
+	if slowlogExampleCommand(bson.M{"ns": "app.orders"}) != "" {
+		t.Fatal("profile without command produced an example")
+	}
+	large := make(bson.A, 0, 300)
+	for i := 0; i < 300; i++ {
+		large = append(large, bson.D{{Key: "field", Value: i}})
+	}
+	truncated := slowlogExampleCommand(bson.M{"ns": "app.orders", "command": bson.D{{Key: "insert", Value: "orders"}, {Key: "documents", Value: large}}})
+	if !strings.HasSuffix(truncated, "...") || len(truncated) != maxSlowlogExampleBytes+3 {
+		t.Fatalf("truncated length = %d", len(truncated))
+	}
+}
+
+func TestSlowlogExampleCommandPreservesOnlyTopLevelAndStageFields(t *testing.T) {
+	// 测试 sort/limit 等字段只在命令顶层与 aggregate stage 层保留原值，过滤条件与文档中的同名字段照常脱敏。
+	profile := bson.M{
+		"ns": "app.orders",
+		"command": bson.D{
+			{Key: "aggregate", Value: "orders"},
+			{Key: "pipeline", Value: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{{Key: "limit", Value: int32(5000)}, {Key: "skip", Value: "secret"}}}},
+				bson.D{{Key: "$sort", Value: bson.D{{Key: "createdAt", Value: int32(-1)}}}},
+				bson.D{{Key: "$limit", Value: int32(20)}},
+				bson.D{{Key: "$project", Value: bson.D{{Key: "sort", Value: "secret"}}}},
+			}},
+			{Key: "limit", Value: int32(10)},
+		},
+	}
+	want := `{"aggregate":"orders","pipeline":[{"$match":{"limit":"?","skip":"?"}},{"$sort":{"createdAt":-1}},{"$limit":20},{"$project":{"sort":"?"}}],"limit":10}`
+	if got := slowlogExampleCommand(profile); got != want {
+		t.Fatalf("example = %s\nwant %s", got, want)
+	}
+
+	update := slowlogExampleCommand(bson.M{"ns": "app.orders", "command": bson.D{
+		{Key: "update", Value: "orders"},
+		{Key: "updates", Value: bson.A{bson.D{{Key: "q", Value: bson.D{{Key: "hint", Value: "secret"}}}, {Key: "u", Value: bson.D{{Key: "$set", Value: bson.D{{Key: "batchSize", Value: int32(7)}}}}}}}},
+	}})
+	if strings.Contains(update, "secret") || strings.Contains(update, "7") {
+		t.Fatalf("nested preserved-name fields leaked values: %s", update)
+	}
+}
+
+func TestSlowlogExampleCommandTruncatesOnRuneBoundary(t *testing.T) {
+	// 测试超长样本命令在 UTF-8 字符边界截断，不输出半个多字节字符。
+	profile := bson.M{"ns": "app.orders", "command": bson.D{{Key: "find", Value: "orders"}, {Key: strings.Repeat("字", 400), Value: int32(1)}}}
+	truncated := slowlogExampleCommand(profile)
+	if !strings.HasSuffix(truncated, "...") || len(truncated) > maxSlowlogExampleBytes+3 || !utf8.ValidString(truncated) {
+		t.Fatalf("truncated = %q (%d bytes)", truncated, len(truncated))
+	}
+}
+
+func TestSlowlogDigestLatencyHistogramGroupsBucketsByDecade(t *testing.T) {
+	// 测试 digest 延迟分布按十倍程区间累加 bucket 次数，缺少合并 histogram 时不输出分布。
+	item := MergedSlowlogItem{PercentileSource: SlowlogPercentileHistogram}
+	item.millisHistogram = []pkgmongo.SlowlogMillisBucket{{Bucket: 0, Count: 3}, {Bucket: 10, Count: 5}, {Bucket: 14, Count: 2}, {Bucket: 40, Count: 1}, {Bucket: 60, Count: 4}}
+	got := slowlogDigestLatencyHistogram(item)
+	want := []SlowlogLatencyBucket{
+		{LowerMillis: 0, UpperMillis: 1, Count: 3}, {LowerMillis: 1, UpperMillis: 10, Count: 5}, {LowerMillis: 10, UpperMillis: 100, Count: 2},
+		{LowerMillis: 100, UpperMillis: 1000, Count: 0}, {LowerMillis: 1000, UpperMillis: 10000, Count: 1}, {LowerMillis: 10000, Count: 4},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("histogram = %#v\nwant %#v", got, want)
+	}
+	item.PercentileSource = SlowlogPercentileHostMax
+	if got := slowlogDigestLatencyHistogram(item); got != nil {
+		t.Fatalf("host_max histogram = %#v, want nil", got)
+	}
+}
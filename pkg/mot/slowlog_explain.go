@@ -0,0 +1,216 @@
+package mot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+// indexScanStages 是会携带 indexName/keyPattern/indexBounds 的 stage。
+var indexScanStages = map[string]struct{}{"IXSCAN": {}, "COUNT_SCAN": {}, "DISTINCT_SCAN": {}}
+
+// attachSlowlogExplain 在读取详情的同一连接上重放 profile 中的命令形状，结果写入 result.Explain。
+func attachSlowlogExplain(ctx context.Context, conn *pkgmongo.Conn, db, queryHash string, result *SlowlogDetailResult, verbosity ExplainVerbosity) error {
+	command, err := pkgmongo.SlowlogExplainCommand(result.orderedProfile())
+	if err != nil {
+		if errors.Is(err, pkgmongo.ErrSlowlogNotExplainable) {
+			return invalidOptions("slowlog operation %v cannot be explained", result.Slowlog["op"])
+		}
+		return err
+	}
+	output, err := conn.Explain(ctx, db, command, string(verbosity))
+	if err != nil {
+		return err
+	}
+	result.Explain = summarizeSlowlogExplain(output, verbosity, result.Namespace, queryHash)
+	return nil
+}
+
+func validateExplainVerbosity(verbosity ExplainVerbosity) error {
+	switch verbosity {
+	case "", ExplainQueryPlanner, ExplainExecutionStats:
+		return nil
+	default:
+		return invalidOptions("invalid explain verbosity %q", verbosity)
+	}
+}
+
+// summarizeSlowlogExplain 兼容 find 类命令的顶层 queryPlanner、aggregate 的 $cursor 阶段以及 7.0+ SBE 的 queryPlan。
+func summarizeSlowlogExplain(output bson.M, verbosity ExplainVerbosity, namespace, queryHash string) *SlowlogExplain {
+	planner := documentElements(output["queryPlanner"])
+	stats := documentElements(output["executionStats"])
+	if planner == nil {
+		if stages, ok := output["stages"].(bson.A); ok && len(stages) > 0 {
+			cursor := documentElements(elementValue(documentElements(stages[0]), "$cursor"))
+			planner = documentElements(elementValue(cursor, "queryPlanner"))
+			stats = documentElements(elementValue(cursor, "executionStats"))
+		}
+	}
+	explain := &SlowlogExplain{Verbosity: verbosity, WinningPlan: summarizeExplainPlan(explainPlanRoot(elementValue(planner, "winningPlan")))}
+	if rejected, ok := elementValue(planner, "rejectedPlans").(bson.A); ok {
+		for _, plan := range rejected {
+			explain.RejectedPlans = append(explain.RejectedPlans, summarizeExplainPlan(explainPlanRoot(plan)))
+		}
+	}
+	if stats != nil {
+		explain.ExecutionStats = &ExplainStats{
+			NReturned:           explainInt64(elementValue(stats, "nReturned")),
+			ExecutionTimeMillis: explainInt64(elementValue(stats, "executionTimeMillis")),
+			TotalKeysExamined:   explainInt64(elementValue(stats, "totalKeysExamined")),
+			TotalDocsExamined:   explainInt64(elementValue(stats, "totalDocsExamined")),
+		}
+		returned := explain.ExecutionStats.NReturned
+		explain.ExecutionStats.FetchRatio = safeExaminedRatio(&explain.ExecutionStats.TotalDocsExamined, &returned)
+	}
+	explain.Findings = evaluateSlowlogExplain(explain, namespace, queryHash)
+	return explain
+}
+
+func explainPlanRoot(value any) bson.D {
+	plan := documentElements(value)
+	if queryPlan := documentElements(elementValue(plan, "queryPlan")); queryPlan != nil {
+		return queryPlan
+	}
+	return plan
+}
+
+func summarizeExplainPlan(root bson.D) ExplainPlan {
+	plan := ExplainPlan{Stages: []string{}}
+	var walk func(node bson.D)
+	walk = func(node bson.D) {
+		if node == nil {
+			return
+		}
+		stage, _ := elementValue(node, "stage").(string)
+		if stage != "" {
+			plan.Stages = append(plan.Stages, stage)
+		}
+		switch stage {
+		case "COLLSCAN":
+			plan.CollectionScan = true
+		case "SORT":
+			plan.InMemorySort = true
+		}
+		if _, ok := indexScanStages[stage]; ok {
+			plan.IndexScans = append(plan.IndexScans, summarizeExplainIndexScan(node))
+		}
+		walk(documentElements(elementValue(node, "inputStage")))
+		if children, ok := elementValue(node, "inputStages").(bson.A); ok {
+			for _, child := range children {
+				walk(documentElements(child))
+			}
+		}
+	}
+	walk(root)
+	return plan
+}
+
+func summarizeExplainIndexScan(node bson.D) ExplainIndexScan {
+	scan := ExplainIndexScan{}
+	scan.IndexName, _ = elementValue(node, "indexName").(string)
+	for _, element := range documentElements(elementValue(node, "keyPattern")) {
+		scan.Key = append(scan.Key, IndexKeyField{Field: element.Key, Order: fmt.Sprint(element.Value)})
+	}
+	for _, element := range documentElements(elementValue(node, "indexBounds")) {
+		intervals, _ := element.Value.(bson.A)
+		scan.Bounds = append(scan.Bounds, ExplainIndexBound{Field: element.Key, Intervals: len(intervals), Kind: explainBoundKind(intervals)})
+	}
+	return scan
+}
+
+// explainBoundKind 只根据区间字符串的结构分类，不输出区间端点取值。
+func explainBoundKind(intervals bson.A) string {
+	kind := "point"
+	for _, value := range intervals {
+		interval, _ := value.(string)
+		switch {
+		case interval == "[MinKey, MaxKey]" || interval == "[MaxKey, MinKey]":
+			return "full"
+		case !isPointInterval(interval):
+			kind = "range"
+		}
+	}
+	return kind
+}
+
+func isPointInterval(interval string) bool {
+	if len(interval) < 6 || interval[0] != '[' || interval[len(interval)-1] != ']' {
+		return false
+	}
+	body := interval[1 : len(interval)-1]
+	if (len(body)-2)%2 != 0 {
+		return false
+	}
+	half := (len(body) - 2) / 2
+	return body[half:half+2] == ", " && body[:half] == body[half+2:]
+}
+
+func evaluateSlowlogExplain(explain *SlowlogExplain, namespace, queryHash string) []DiagnosticFinding {
+	scope := FindingScope{Type: ScopeNamespace, Namespace: namespace}
+	findings := make([]DiagnosticFinding, 0)
+	winning := explain.WinningPlan
+	if winning.CollectionScan {
+		findings = append(findings, DiagnosticFinding{
+			Code: "query.explain_collection_scan", Severity: SeverityWarning, Scope: scope,
+			Summary:        "当前执行计划仍使用 collection scan",
+			Evidence:       map[string]any{"queryHash": queryHash, "stages": strings.Join(winning.Stages, ">"), "rejectedPlans": len(explain.RejectedPlans)},
+			Recommendation: "使用 slowlog advise 推导候选索引",
+		})
+	}
+	if winning.InMemorySort {
+		findings = append(findings, DiagnosticFinding{
+			Code: "query.explain_in_memory_sort", Severity: SeverityWarning, Scope: scope,
+			Summary:        "当前执行计划包含内存 SORT stage",
+			Evidence:       map[string]any{"queryHash": queryHash, "stages": strings.Join(winning.Stages, ">")},
+			Recommendation: "让排序字段紧随等值字段出现在索引 key 中",
+		})
+	}
+	for _, scan := range winning.IndexScans {
+		if len(scan.Bounds) == 0 {
+			continue
+		}
+		unbounded := true
+		for _, bound := range scan.Bounds {
+			if bound.Kind != "full" {
+				unbounded = false
+				break
+			}
+		}
+		if unbounded {
+			findings = append(findings, DiagnosticFinding{
+				Code: "query.explain_unbounded_index_scan", Severity: SeverityInfo, Scope: scope,
+				Summary:  "索引扫描没有任何字段被条件约束，等价于按索引顺序全量扫描",
+				Evidence: map[string]any{"queryHash": queryHash, "indexName": scan.IndexName},
+			})
+		}
+	}
+	if stats := explain.ExecutionStats; stats != nil {
+		if (stats.FetchRatio != nil && *stats.FetchRatio >= 100) || (stats.NReturned == 0 && stats.TotalDocsExamined >= 100) {
+			findings = append(findings, DiagnosticFinding{
+				Code: "query.explain_fetch_ratio_high", Severity: SeverityWarning, Scope: scope,
+				Summary:  "FETCH 读取的文档数显著高于返回文档数",
+				Evidence: map[string]any{"queryHash": queryHash, "docsExamined": stats.TotalDocsExamined, "nReturned": stats.NReturned},
+			})
+		}
+	}
+	sanitizeAndSortFindings(findings)
+	return findings
+}
+
+func explainInt64(value any) int64 {
+	switch number := value.(type) {
+	case int32:
+		return int64(number)
+	case int64:
+		return number
+	case float64:
+		return int64(number)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,90 @@
+package mot
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"golang.org/x/sync/semaphore"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+// mergeHostSlowlogGetLog 读取单个成员的 getLog 内存缓冲，只补齐 profiler 未覆盖的 database，
+// 避免同一慢操作在 system.profile 与日志中重复计数；返回的状态标明该成员的数据来源。
+func (c *Client) mergeHostSlowlogGetLog(ctx context.Context, host *HostSlowlogSummary, replicaSet string, opts SlowlogOptions, capabilityLimit *semaphore.Weighted) (CollectorStatus, error) {
+	scope := FindingScope{Type: ScopeNode, ReplicaSet: replicaSet, Node: host.Address}
+	fromLog, stats, matched, err := c.hostSlowlogGetLog(ctx, host.Address, opts, capabilityLimit)
+	if err != nil {
+		return failedCollectorStatus("slowlog_getlog", scope, err), err
+	}
+	status := slowlogLogStatus("slowlog_getlog", scope, stats, matched)
+	if status.State != CapabilitySupported {
+		return status, nil
+	}
+	merged := mergeSlowlogGetLogDatabases(host, fromLog)
+	if len(merged) == 0 {
+		status.ReasonCode = "profiler_preferred"
+		status.Message += "；getLog 中的 database 已由 system.profile 覆盖"
+		return status, nil
+	}
+	status.ReasonCode = "source_getlog"
+	status.Message += "；来源 getLog: " + strings.Join(merged, ", ")
+	return status, nil
+}
+
+// hostSlowlogGetLog 返回过滤后的聚合结果、解析计数以及命中过滤条件的条数。
+func (c *Client) hostSlowlogGetLog(ctx context.Context, addr string, opts SlowlogOptions, capabilityLimit *semaphore.Weighted) ([]DatabaseSlowlogSummary, pkgmongo.SlowlogLogStats, int64, error) {
+	var stats pkgmongo.SlowlogLogStats
+	release, err := c.acquireCapabilityRemoteSlot(ctx, capabilityLimit)
+	if err != nil {
+		return nil, stats, 0, err
+	}
+	defer release()
+
+	conn, err := c.connectAddress(ctx, addr, derivedConnectionOptions{Direct: boolPointer(true)})
+	if err != nil {
+		return nil, stats, 0, err
+	}
+	defer c.closeDerivedConnection(ctx, conn)
+	lines, err := conn.GetLog(ctx, "global")
+	if err != nil {
+		return nil, stats, 0, err
+	}
+	aggregator := pkgmongo.NewSlowlogAggregator()
+	filter := opts.filter()
+	var matched int64
+	for _, line := range lines {
+		stats.Lines++
+		entry, ok := pkgmongo.ParseSlowlogLogLine([]byte(line))
+		if !ok {
+			continue
+		}
+		stats.Entries++
+		if filter.Matches(entry) {
+			matched++
+			aggregator.Add(entry)
+		}
+	}
+	return slowlogSummariesFromAggregator(aggregator, opts.Databases, opts.Sort), stats, matched, nil
+}
+
+// mergeSlowlogGetLogDatabases 把 profiler 缺失的 database 并入 host，返回补齐的库名。
+func mergeSlowlogGetLogDatabases(host *HostSlowlogSummary, fromLog []DatabaseSlowlogSummary) []string {
+	covered := make(map[string]struct{}, len(host.Databases))
+	for _, database := range host.Databases {
+		covered[database.Database] = struct{}{}
+	}
+	var merged []string
+	for _, database := range fromLog {
+		if _, exists := covered[database.Database]; exists {
+			continue
+		}
+		host.Databases = append(host.Databases, database)
+		merged = append(merged, database.Database)
+	}
+	if len(merged) > 0 {
+		sort.SliceStable(host.Databases, func(i, j int) bool { return host.Databases[i].Database < host.Databases[j].Database })
+	}
+	return merged
+}
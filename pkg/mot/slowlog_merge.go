@@ -0,0 +1,242 @@
+package mot
+
+import (
+	"encoding/json"
+	"slices"
+	"sort"
+
+	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
+)
+
+// maxMergedSlowlogAppNames 与单 host 视图一致，合并后最多列出 10 个 appName，其余只计数。
+const maxMergedSlowlogAppNames = 10
+
+type slowlogMergeKey struct {
+	namespace   string
+	queryHash   string
+	operation   string
+	planSummary string
+}
+
+type slowlogMergeSlot struct {
+	item MergedSlowlogItem
+	// histogram 合并各来源的耗时 bucket；任一有计数的来源缺少 bucket 时 histogramComplete 为 false。
+	histogram         map[int64]int64
+	histogramComplete bool
+	hostPercentiles   [3]*int64
+	hostP95Min        *int64
+}
+
+// MergeSlowlogSummary 按 namespace/queryHash/op/planSummary 跨 host 与 shard 折叠 slowlog 聚合项。
+// 计数与累计耗时求和，max/min 取极值；各来源都有耗时 bucket 时 percentile 取合并后 histogram 的分位，
+// 否则取各 host percentile 的最大值并标记为 host_max，避免单个慢 host 被平均掉。
+func MergeSlowlogSummary(result *SlowlogSummaryResult, sortValue SlowlogSort) (*MergedSlowlogSummary, error) {
+	if sortValue == "" {
+		sortValue = SlowlogSortCount
+	}
+	if !isValidSlowlogSort(sortValue) {
+		return nil, invalidOptions("invalid slowlog sort %q", sortValue)
+	}
+	merged := &MergedSlowlogSummary{Merge: SlowlogMergeCluster, Sort: sortValue}
+	if result == nil {
+		return merged, nil
+	}
+	merged.ClusterType = result.ClusterType
+	merged.CollectorStatuses = append(merged.CollectorStatuses, result.CollectorStatuses...)
+	merged.Findings = dedupeSlowlogFindings(result.Findings)
+
+	slots := make(map[slowlogMergeKey]*slowlogMergeSlot)
+	for _, replicaSet := range result.ReplicaSets {
+		for _, host := range replicaSet.Hosts {
+			for _, database := range host.Databases {
+				for _, item := range database.Items {
+					key := slowlogMergeKey{namespace: item.Namespace, queryHash: item.QueryHash, operation: item.Operation, planSummary: item.PlanSummary}
+					slot := slots[key]
+					if slot == nil {
+						slot = &slowlogMergeSlot{item: MergedSlowlogItem{SlowlogSummaryItem: SlowlogSummaryItem{
+							Namespace: item.Namespace, Operation: item.Operation, QueryHash: item.QueryHash, PlanSummary: item.PlanSummary,
+							MinMillis: item.MinMillis, FirstTime: item.FirstTime, LastTime: item.LastTime,
+						}}, histogram: make(map[int64]int64), histogramComplete: true}
+						slots[key] = slot
+					}
+					mergeSlowlogItem(slot, item)
+					slot.item.ReplicaSets = appendUnique(slot.item.ReplicaSets, replicaSet.Name)
+					if host.Address != "" {
+						slot.item.Hosts = appendUnique(slot.item.Hosts, host.Address)
+					}
+				}
+			}
+		}
+	}
+	for _, slot := range slots {
+		item := &slot.item
+		finishSlowlogMergePercentiles(slot)
+		if item.Count > 0 {
+			item.AvgMillis = float64(item.TotalMillis) / float64(item.Count)
+		}
+		sort.Strings(item.AppNames)
+		if len(item.AppNames) > maxMergedSlowlogAppNames {
+			item.AppNamesTruncated = len(item.AppNames) - maxMergedSlowlogAppNames
+			item.AppNames = item.AppNames[:maxMergedSlowlogAppNames]
+		}
+		sort.Strings(item.ReplicaSets)
+		sort.Strings(item.Hosts)
+		merged.Total += item.Count
+		merged.TotalMillis += item.TotalMillis
+		merged.Items = append(merged.Items, *item)
+	}
+	for i := range merged.Items {
+		if merged.TotalMillis > 0 {
+			share := float64(merged.Items[i].TotalMillis) / float64(merged.TotalMillis)
+			merged.Items[i].TimeShare = &share
+		}
+	}
+	sortMergedSlowlogItems(merged.Items, sortValue)
+	return merged, nil
+}
+
+func mergeSlowlogItem(slot *slowlogMergeSlot, item SlowlogSummaryItem) {
+	target := &slot.item
+	target.Count += item.Count
+	target.TotalMillis += item.TotalMillis
+	target.MaxMillis = max(target.MaxMillis, item.MaxMillis)
+	target.MinMillis = min(target.MinMillis, item.MinMillis)
+	target.MaxDocs = max(target.MaxDocs, item.MaxDocs)
+	if item.FirstTime.Before(target.FirstTime) {
+		target.FirstTime = item.FirstTime
+	}
+	if item.LastTime.After(target.LastTime) {
+		target.LastTime = item.LastTime
+	}
+	target.MaxKeysExamined = maxOptional(target.MaxKeysExamined, item.MaxKeysExamined)
+	target.MaxDocsExamined = maxOptional(target.MaxDocsExamined, item.MaxDocsExamined)
+	target.MaxDocsReturned = maxOptional(target.MaxDocsReturned, item.MaxDocsReturned)
+	target.MaxPlanningMicros = maxOptional(target.MaxPlanningMicros, item.MaxPlanningMicros)
+	target.MaxCPUNanos = maxOptional(target.MaxCPUNanos, item.MaxCPUNanos)
+	target.WorstDocsToReturned = maxOptional(target.WorstDocsToReturned, item.WorstDocsToReturned)
+	target.WorstKeysToReturned = maxOptional(target.WorstKeysToReturned, item.WorstKeysToReturned)
+	target.ErrorCount += item.ErrorCount
+	target.CollectionScanCount += item.CollectionScanCount
+	target.SortStageCount += item.SortStageCount
+	for _, appName := range item.AppNames {
+		target.AppNames = appendUnique(target.AppNames, appName)
+	}
+	for _, bucket := range item.millisHistogram {
+		slot.histogram[bucket.Bucket] += bucket.Count
+	}
+	if item.Count > 0 && len(item.millisHistogram) == 0 {
+		slot.histogramComplete = false
+	}
+	for i, value := range []*int64{item.P50Millis, item.P95Millis, item.P99Millis} {
+		slot.hostPercentiles[i] = maxOptional(slot.hostPercentiles[i], value)
+	}
+	if item.P95Millis != nil && (slot.hostP95Min == nil || *item.P95Millis < *slot.hostP95Min) {
+		value := *item.P95Millis
+		slot.hostP95Min = &value
+	}
+}
+
+// finishSlowlogMergePercentiles 优先按合并后的 histogram 取分位；来源缺少 bucket 时退化为各 host 最大值。
+func finishSlowlogMergePercentiles(slot *slowlogMergeSlot) {
+	item := &slot.item
+	targets := []**int64{&item.P50Millis, &item.P95Millis, &item.P99Millis}
+	if slot.histogramComplete && len(slot.histogram) > 0 {
+		buckets := make([]pkgmongo.SlowlogMillisBucket, 0, len(slot.histogram))
+		for bucket, count := range slot.histogram {
+			buckets = append(buckets, pkgmongo.SlowlogMillisBucket{Bucket: bucket, Count: count})
+		}
+		sort.Slice(buckets, func(i, j int) bool { return buckets[i].Bucket < buckets[j].Bucket })
+		for i, q := range []float64{0.50, 0.95, 0.99} {
+			*targets[i] = pkgmongo.SlowlogHistogramPercentile(buckets, q, item.MinMillis, item.MaxMillis)
+		}
+		item.PercentileSource = SlowlogPercentileHistogram
+		item.millisHistogram = buckets
+		return
+	}
+	for i, value := range slot.hostPercentiles {
+		*targets[i] = value
+	}
+	if item.P95Millis != nil {
+		item.PercentileSource = SlowlogPercentileHostMax
+		item.HostP95MinMillis = slot.hostP95Min
+	}
+}
+
+func maxOptional[T int64 | float64](current, value *T) *T {
+	if value == nil {
+		return current
+	}
+	if current == nil || *value > *current {
+		next := *value
+		return &next
+	}
+	return current
+}
+
+func appendUnique(values []string, value string) []string {
+	if value == "" || slices.Contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+func sortMergedSlowlogItems(items []MergedSlowlogItem, sortValue SlowlogSort) {
+	sortKey := func(item MergedSlowlogItem) int64 {
+		switch sortValue {
+		case SlowlogSortMaxMillis:
+			return item.MaxMillis
+		case SlowlogSortMaxDocs:
+			return item.MaxDocs
+		case SlowlogSortTotal:
+			return item.TotalMillis
+		case SlowlogSortP95:
+			if item.P95Millis == nil {
+				return 0
+			}
+			return *item.P95Millis
+		default:
+			return item.Count
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		left, right := sortKey(items[i]), sortKey(items[j])
+		if left != right {
+			return left > right
+		}
+		if items[i].Namespace != items[j].Namespace {
+			return items[i].Namespace < items[j].Namespace
+		}
+		if items[i].QueryHash != items[j].QueryHash {
+			return items[i].QueryHash < items[j].QueryHash
+		}
+		if items[i].Operation != items[j].Operation {
+			return items[i].Operation < items[j].Operation
+		}
+		return items[i].PlanSummary < items[j].PlanSummary
+	})
+}
+
+// dedupeSlowlogFindings 折叠不同节点上同一 namespace/queryHash 的重复 finding，保留首个证据。
+func dedupeSlowlogFindings(findings []DiagnosticFinding) []DiagnosticFinding {
+	seen := make(map[string]struct{}, len(findings))
+	result := make([]DiagnosticFinding, 0, len(findings))
+	for _, finding := range findings {
+		identity, ok := finding.Evidence["queryHash"]
+		if !ok {
+			identity = finding.Evidence
+		}
+		// json.Marshal 对 map 按键排序，保证同一证据得到相同的 key。
+		encoded, err := json.Marshal(identity)
+		if err != nil {
+			result = append(result, finding)
+			continue
+		}
+		key := finding.Code + "\x00" + finding.Scope.Namespace + "\x00" + string(encoded)
+		if _, exists := seen[key]; exists {
+			continue
+		}
+		seen[key] = struct{}{}
+		result = append(result, finding)
+	}
+	return result
+}
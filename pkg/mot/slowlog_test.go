@@ -4,12 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"math"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	drivermongo "go.mongodb.org/mongo-driver/mongo"
 
 	pkgmongo "github.com/SisyphusSQ/mongo-overview-tool/v2/pkg/mongo"
@@ -161,3 +167,447 @@ func TestFindSlowlogAddress(t *testing.T) {
 		t.Fatalf("findSlowlogAddress() nil = %q, want empty", got)
 	}
 }
+
+func TestSlowlogSummaryFromLogsMergesFilesAndReportsPartialCoverage(t *testing.T) {
+	// 测试离线解析合并多个日志文件、跳过系统库、host 地址取自启动行，并对缺失文件返回部分覆盖错误。
+	dir := t.TempDir()
+	current := filepath.Join(dir, "mongod.log")
+	rotated := filepath.Join(dir, "mongod.log.1")
+	line := `{"t":{"$date":"2026-07-14T00:00:01Z"},"id":51803,"attr":{"type":"command","ns":"%s","command":{"find":"orders"},"planSummary":"COLLSCAN","docsExamined":1000,"nreturned":0,"queryHash":"ABCD1234","durationMillis":%d}}` + "\n"
+	startup := `{"t":{"$date":"2026-07-14T00:00:00Z"},"s":"I","c":"CONTROL","id":4615611,"ctx":"initandlisten","msg":"MongoDB starting","attr":{"pid":1,"port":27018,"dbPath":"/data/db","host":"db-1"}}` + "\n"
+	if err := os.WriteFile(current, []byte(startup+fmt.Sprintf(line, "app.orders", 30)+fmt.Sprintf(line, "admin.system", 5)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(rotated, []byte(fmt.Sprintf(line, "app.orders", 90)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := SlowlogSummaryFromLogs(context.Background(), SlowlogLogOptions{Paths: []string{current, rotated, filepath.Join(dir, "missing.log")}})
+	if !errors.Is(err, ErrPartialResult) {
+		t.Fatalf("error = %v, want ErrPartialResult", err)
+	}
+	if address := result.ReplicaSets[0].Hosts[0].Address; address != "db-1:27018" {
+		t.Fatalf("host address = %q, want db-1:27018", address)
+	}
+	databases := result.ReplicaSets[0].Hosts[0].Databases
+	if len(databases) != 1 || databases[0].Database != "app" || databases[0].Total != 2 {
+		t.Fatalf("databases = %#v", databases)
+	}
+	item := databases[0].Items[0]
+	if item.Count != 2 || item.MaxMillis != 90 || item.MinMillis != 30 || item.Operation != "query" {
+		t.Fatalf("item = %#v", item)
+	}
+	assertFindingCode(t, result.Findings, "query.collection_scan", SeverityWarning)
+	assertFindingCode(t, result.Findings, "query.zero_return_scan", SeverityWarning)
+	if len(result.CollectorStatuses) != 3 {
+		t.Fatalf("statuses = %#v", result.CollectorStatuses)
+	}
+
+	if _, err := SlowlogSummaryFromLogs(context.Background(), SlowlogLogOptions{}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("empty paths error = %v, want ErrInvalidOptions", err)
+	}
+}
+
+func TestSlowlogLogStatusCountsEntriesAfterFiltering(t *testing.T) {
+	// 测试状态同时报告解析条数与命中过滤条件的条数，全部被过滤时标记为 skipped 而不是 supported。
+	scope := FindingScope{Type: ScopeNode, Node: "mongod.log"}
+	status := slowlogLogStatus("slowlog_log", scope, pkgmongo.SlowlogLogStats{Lines: 10, Entries: 3}, 0)
+	if status.State != CapabilitySkipped || status.ReasonCode != "no_slow_query" || status.Message != "扫描 10 行，解析 3 条慢查询，0 条命中过滤条件" {
+		t.Fatalf("filtered status = %#v", status)
+	}
+	if status := slowlogLogStatus("slowlog_log", scope, pkgmongo.SlowlogLogStats{Lines: 10, Entries: 3}, 2); status.State != CapabilitySupported || status.ReasonCode != "" {
+		t.Fatalf("matched status = %#v", status)
+	}
+}
+
+func TestMergeSlowlogGetLogDatabasesPrefersProfiler(t *testing.T) {
+	// 测试 getLog 只补齐 profiler 未覆盖的库，避免同一慢操作重复计数，并保持库名顺序。
+	host := HostSlowlogSummary{Address: "n1", Databases: []DatabaseSlowlogSummary{{Database: "orders", Total: 7}}}
+	merged := mergeSlowlogGetLogDatabases(&host, []DatabaseSlowlogSummary{
+		{Database: "orders", Total: 3},
+		{Database: "billing", Total: 2},
+	})
+	if !reflect.DeepEqual(merged, []string{"billing"}) {
+		t.Fatalf("merged = %#v, want billing", merged)
+	}
+	if len(host.Databases) != 2 || host.Databases[0].Database != "billing" || host.Databases[1].Total != 7 {
+		t.Fatalf("host databases = %#v", host.Databases)
+	}
+	if merged := mergeSlowlogGetLogDatabases(&host, []DatabaseSlowlogSummary{{Database: "orders", Total: 1}}); len(merged) != 0 {
+		t.Fatalf("covered database merged = %#v", merged)
+	}
+}
+
+func TestValidateSlowlogFilterRejectsInvalidWindowAndDimensions(t *testing.T) {
+	// 测试过滤条件在远程聚合前校验时间窗、namespace、op 和最小耗时。
+	since := time.Date(2026, 7, 14, 0, 0, 0, 0, time.UTC)
+	for _, opts := range []SlowlogOptions{
+		{Since: since, Until: since},
+		{MinMillis: -1},
+		{Namespaces: []string{"orders"}},
+		{Operations: []string{"find"}},
+	} {
+		if err := validateSlowlogFilter(opts.filter()); !errors.Is(err, ErrInvalidOptions) {
+			t.Fatalf("options %#v error = %v, want ErrInvalidOptions", opts, err)
+		}
+	}
+	valid := SlowlogOptions{Since: since, Until: since.Add(time.Hour), Namespaces: []string{"app.orders"}, Operations: []string{"query"}, MinMillis: 100}
+	if err := validateSlowlogFilter(valid.filter()); err != nil {
+		t.Fatalf("valid filter error = %v", err)
+	}
+	if !slowlogNamespacesInDatabase(valid.Namespaces, "app") || slowlogNamespacesInDatabase(valid.Namespaces, "ap") {
+		t.Fatalf("namespace database prefix match is wrong")
+	}
+}
+
+func TestSummarizeSlowlogViewsReportsAverageAndTimeShare(t *testing.T) {
+	// 测试 summary 计算平均耗时、透传 percentile，并给出占库内慢操作总耗时的比例。
+	p95 := int64(280)
+	summary := summarizeSlowlogViews("app", []*pkgmongo.SlowlogView{
+		{Ns: "app.orders", QueryHash: "A", Cnt: 4, TotalMillis: 1200, P95Mills: &p95},
+		{Ns: "app.users", QueryHash: "B", Cnt: 2, TotalMillis: 300},
+	})
+	if summary.TotalMillis != 1500 || len(summary.Items) != 2 {
+		t.Fatalf("summary = %#v", summary)
+	}
+	orders := summary.Items[0]
+	if orders.AvgMillis != 300 || orders.P95Millis == nil || *orders.P95Millis != 280 || orders.TimeShare == nil || *orders.TimeShare != 0.8 {
+		t.Fatalf("orders item = %#v", orders)
+	}
+	if users := summary.Items[1]; users.P95Millis != nil || users.TimeShare == nil || *users.TimeShare != 0.2 {
+		t.Fatalf("users item = %#v", users)
+	}
+	if !isValidSlowlogSort(SlowlogSortP95) || !isValidSlowlogSort(SlowlogSortTotal) {
+		t.Fatalf("p95/totalMillis sort must be valid")
+	}
+}
+
+func TestMergeSlowlogSummaryFoldsSameShapeAcrossShards(t *testing.T) {
+	// 测试集群级合并按查询形状跨 host/shard 求和计数、取极值并列出来源分片；来源只有服务端 percentile 时取各 host 最大值并标记 host_max。
+	first := time.Date(2026, 7, 14, 0, 0, 0, 0, time.UTC)
+	p95A, p95B := int64(100), int64(400)
+	item := func(count, maxMillis, total int64, p95 *int64, at time.Time) SlowlogSummaryItem {
+		return SlowlogSummaryItem{Namespace: "app.orders", Operation: "query", QueryHash: "A", PlanSummary: "COLLSCAN", Count: count, MaxMillis: maxMillis, MinMillis: 10, TotalMillis: total, P95Millis: p95, FirstTime: at, LastTime: at, CollectionScanCount: count}
+	}
+	result := &SlowlogSummaryResult{
+		ClusterType: ClusterSharded,
+		ReplicaSets: []ReplicaSetSlowlogSummary{
+			{Name: "shard-b", Hosts: []HostSlowlogSummary{{Address: "b1", Databases: []DatabaseSlowlogSummary{{Database: "app", Items: []SlowlogSummaryItem{item(1, 900, 400, &p95B, first.Add(time.Hour))}}}}}},
+			{Name: "shard-a", Hosts: []HostSlowlogSummary{
+				{Address: "a1", Databases: []DatabaseSlowlogSummary{{Database: "app", Items: []SlowlogSummaryItem{
+					item(3, 200, 300, &p95A, first),
+					{Namespace: "app.users", Operation: "query", QueryHash: "B", Count: 10, TotalMillis: 300},
+				}}}},
+			}},
+		},
+		Findings: []DiagnosticFinding{
+			{Code: "query.collection_scan", Scope: FindingScope{Type: ScopeNamespace, Namespace: "app.orders"}, Evidence: map[string]any{"queryHash": "A", "count": int64(3)}},
+			{Code: "query.collection_scan", Scope: FindingScope{Type: ScopeNamespace, Namespace: "app.orders"}, Evidence: map[string]any{"queryHash": "A", "count": int64(1)}},
+		},
+	}
+
+	merged, err := MergeSlowlogSummary(result, SlowlogSortTotal)
+	if err != nil {
+		t.Fatalf("MergeSlowlogSummary() error = %v", err)
+	}
+	if merged.Total != 14 || merged.TotalMillis != 1000 || len(merged.Items) != 2 || len(merged.Findings) != 1 {
+		t.Fatalf("merged = %#v", merged)
+	}
+	orders := merged.Items[0]
+	if orders.Namespace != "app.orders" || orders.Count != 4 || orders.MaxMillis != 900 || orders.TotalMillis != 700 || orders.AvgMillis != 175 || orders.CollectionScanCount != 4 {
+		t.Fatalf("orders = %#v", orders)
+	}
+	if orders.P95Millis == nil || *orders.P95Millis != 400 || orders.PercentileSource != SlowlogPercentileHostMax || *orders.HostP95MinMillis != 100 || orders.TimeShare == nil || *orders.TimeShare != 0.7 {
+		t.Fatalf("orders percentile/share = %v/%v", orders.P95Millis, orders.TimeShare)
+	}
+	if !reflect.DeepEqual(orders.ReplicaSets, []string{"shard-a", "shard-b"}) || !reflect.DeepEqual(orders.Hosts, []string{"a1", "b1"}) {
+		t.Fatalf("orders sources = %v/%v", orders.ReplicaSets, orders.Hosts)
+	}
+	if !orders.FirstTime.Equal(first) || !orders.LastTime.Equal(first.Add(time.Hour)) {
+		t.Fatalf("orders time frame = %v~%v", orders.FirstTime, orders.LastTime)
+	}
+	if _, err := MergeSlowlogSummary(result, SlowlogSort("bogus")); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("invalid sort error = %v, want ErrInvalidOptions", err)
+	}
+}
+
+func TestMergeSlowlogSummaryUsesMergedHistogramAndCountsTruncatedAppNames(t *testing.T) {
+	// 测试各来源都有耗时 bucket 时按合并后的 histogram 取分位，单个慢 host 不会被计数加权平均掉；
+	// 合并后超过上限的 appName 只计数，证据 map 相同的 finding 按确定性编码去重。
+	fast := []pkgmongo.SlowlogMillisBucket{{Bucket: 14, Count: 90}}
+	slow := []pkgmongo.SlowlogMillisBucket{{Bucket: 40, Count: 10}}
+	p95Fast, p95Slow := int64(10), int64(1000)
+	var apps []string
+	for i := 0; i < 12; i++ {
+		apps = append(apps, fmt.Sprintf("app-%02d", i))
+	}
+	item := func(count, minMillis, maxMillis int64, p95 *int64, histogram []pkgmongo.SlowlogMillisBucket, appNames []string) SlowlogSummaryItem {
+		return SlowlogSummaryItem{Namespace: "app.orders", Operation: "query", QueryHash: "A", Count: count, MinMillis: minMillis, MaxMillis: maxMillis, TotalMillis: count * maxMillis, P95Millis: p95, AppNames: appNames, millisHistogram: histogram}
+	}
+	evidence := func() map[string]any {
+		return map[string]any{"ratio": 200.0, "count": int64(3), "namespace": "app.orders"}
+	}
+	result := &SlowlogSummaryResult{
+		ReplicaSets: []ReplicaSetSlowlogSummary{{Name: "rs0", Hosts: []HostSlowlogSummary{
+			{Address: "a", Databases: []DatabaseSlowlogSummary{{Database: "app", Items: []SlowlogSummaryItem{item(90, 8, 12, &p95Fast, fast, apps[:8])}}}},
+			{Address: "b", Databases: []DatabaseSlowlogSummary{{Database: "app", Items: []SlowlogSummaryItem{item(10, 900, 1100, &p95Slow, slow, apps[4:])}}}},
+		}}},
+		Findings: []DiagnosticFinding{{Code: "query.docs_examined_high", Evidence: evidence()}, {Code: "query.docs_examined_high", Evidence: evidence()}},
+	}
+	merged, err := MergeSlowlogSummary(result, SlowlogSortCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders := merged.Items[0]
+	if orders.PercentileSource != SlowlogPercentileHistogram || orders.HostP95MinMillis != nil || *orders.P50Millis > 12 || *orders.P95Millis < 900 {
+		t.Fatalf("histogram percentiles = %v/%v source=%s", *orders.P50Millis, *orders.P95Millis, orders.PercentileSource)
+	}
+	if len(orders.AppNames) != maxMergedSlowlogAppNames || orders.AppNamesTruncated != 2 {
+		t.Fatalf("app names = %v truncated=%d", orders.AppNames, orders.AppNamesTruncated)
+	}
+	if len(merged.Findings) != 1 {
+		t.Fatalf("findings = %#v", merged.Findings)
+	}
+}
+
+func TestAdviseSlowlogIndexAppliesESRAndReportsSupersededIndex(t *testing.T) {
+	// 测试候选索引按等值、排序、范围排列，只列出可替代的普通前缀索引，且 finding 不含查询取值。
+	item := MergedSlowlogItem{SlowlogSummaryItem: SlowlogSummaryItem{Namespace: "app.orders", Operation: "query", QueryHash: "A", Count: 5, PlanSummary: "COLLSCAN", CollectionScanCount: 5}}
+	detail := &SlowlogDetailResult{
+		Namespace: "app.orders",
+		Slowlog: bson.M{"op": "query", "command": bson.D{
+			{Key: "find", Value: "orders"},
+			{Key: "filter", Value: bson.D{{Key: "amount", Value: bson.D{{Key: "$gt", Value: 4096}}}, {Key: "status", Value: "secret-status"}}},
+			{Key: "sort", Value: bson.D{{Key: "createdAt", Value: int32(-1)}}},
+		}},
+		Indexes: []bson.M{
+			{"name": "_id_", "key": bson.D{{Key: "_id", Value: int32(1)}}},
+			{"name": "status_1", "key": bson.D{{Key: "status", Value: int32(1)}}},
+			{"name": "status_1_u", "key": bson.D{{Key: "status", Value: int32(1)}}, "unique": true},
+			{"name": "status_text", "key": bson.D{{Key: "status", Value: "text"}}},
+		},
+	}
+
+	suggestion, finding, ok := adviseSlowlogIndex(item, detail, defaultSlowlogAdviseDocsExaminedRatio)
+	if !ok || finding == nil || finding.Code != "query.index_suggestion" {
+		t.Fatalf("adviseSlowlogIndex() = %#v, %#v, %v", suggestion, finding, ok)
+	}
+	wantKey := []IndexKeyField{{Field: "status", Order: "1"}, {Field: "createdAt", Order: "-1"}, {Field: "amount", Order: "1"}}
+	if !reflect.DeepEqual(suggestion.ProposedKey, wantKey) || !reflect.DeepEqual(suggestion.Supersedes, []string{"status_1"}) {
+		t.Fatalf("suggestion = %#v", suggestion)
+	}
+	if !reflect.DeepEqual(suggestion.Reasons, []string{"collection_scan"}) {
+		t.Fatalf("reasons = %v", suggestion.Reasons)
+	}
+	if finding.Evidence["proposedKey"] != "{ status: 1, createdAt: -1, amount: 1 }" {
+		t.Fatalf("evidence = %#v", finding.Evidence)
+	}
+	payload := fmt.Sprint(finding, suggestion)
+	if strings.Contains(payload, "secret-status") || strings.Contains(payload, "4096") {
+		t.Fatalf("advise output leaked query values: %s", payload)
+	}
+
+	detail.Indexes = append(detail.Indexes, bson.M{"name": "cover", "key": bson.D{
+		{Key: "status", Value: int32(1)}, {Key: "createdAt", Value: int32(1)}, {Key: "amount", Value: int32(1)}, {Key: "extra", Value: int32(1)},
+	}})
+	if _, finding, ok := adviseSlowlogIndex(item, detail, defaultSlowlogAdviseDocsExaminedRatio); ok || finding == nil || finding.Code != "query.index_not_selected" {
+		t.Fatalf("covered shape = %#v, %v, want index_not_selected", finding, ok)
+	}
+}
+
+func TestSlowlogQueryShapeTreatsInWithSortAsRange(t *testing.T) {
+	// 测试多值 $in 在有排序时放到排序字段之后，单值 $in 与无排序时的多值 $in 仍按等值处理。
+	command := bson.D{
+		{Key: "find", Value: "orders"},
+		{Key: "filter", Value: bson.D{
+			{Key: "status", Value: bson.D{{Key: "$in", Value: bson.A{"A", "B"}}}},
+			{Key: "region", Value: bson.D{{Key: "$in", Value: bson.A{"cn"}}}},
+			{Key: "tenant", Value: int32(1)},
+		}},
+		{Key: "sort", Value: bson.D{{Key: "createdAt", Value: int32(-1)}}},
+	}
+	shape := slowlogQueryShapeFromProfile(bson.M{"op": "query", "command": command})
+	wantKey := []IndexKeyField{{Field: "region", Order: "1"}, {Field: "tenant", Order: "1"}, {Field: "createdAt", Order: "-1"}, {Field: "status", Order: "1"}}
+	if got := shape.esrKey(); !reflect.DeepEqual(got, wantKey) {
+		t.Fatalf("sorted $in key = %#v, want %#v", got, wantKey)
+	}
+
+	shape = slowlogQueryShapeFromProfile(bson.M{"op": "query", "command": command[:2]})
+	if !reflect.DeepEqual(shape.equality, []string{"region", "tenant", "status"}) || shape.ranges != nil {
+		t.Fatalf("unsorted $in shape = %#v", shape)
+	}
+}
+
+func TestIndexKeyCoversAcceptsEqualityPermutation(t *testing.T) {
+	// 测试等值段与范围段字段顺序任意即可覆盖，排序段必须紧随等值段且整体同向或反向。
+	shape := slowlogQueryShape{
+		equality: []string{"a", "b"},
+		sort:     []IndexKeyField{{Field: "ts", Order: "-1"}, {Field: "_id", Order: "1"}},
+		ranges:   []string{"x", "y"},
+	}
+	key := func(fields ...string) []IndexKeyField {
+		result := make([]IndexKeyField, 0, len(fields)/2)
+		for i := 0; i < len(fields); i += 2 {
+			result = append(result, IndexKeyField{Field: fields[i], Order: fields[i+1]})
+		}
+		return result
+	}
+	for _, tc := range []struct {
+		name string
+		key  []IndexKeyField
+		want bool
+	}{
+		{"exact", key("a", "1", "b", "1", "ts", "-1", "_id", "1", "x", "1", "y", "1"), true},
+		{"permuted equality and range", key("b", "-1", "a", "1", "ts", "-1", "_id", "1", "y", "1", "x", "1", "z", "1"), true},
+		{"reversed sort", key("a", "1", "b", "1", "ts", "1", "_id", "-1", "x", "1", "y", "1"), true},
+		{"mixed sort direction", key("a", "1", "b", "1", "ts", "1", "_id", "1", "x", "1", "y", "1"), false},
+		{"sort before equality", key("a", "1", "ts", "-1", "b", "1", "_id", "1", "x", "1", "y", "1"), false},
+		{"range before sort", key("a", "1", "b", "1", "x", "1", "ts", "-1", "_id", "1", "y", "1"), false},
+		{"too short", key("a", "1", "b", "1", "ts", "-1", "_id", "1", "x", "1"), false},
+	} {
+		if got := indexKeyCovers(tc.key, shape); got != tc.want {
+			t.Fatalf("%s: indexKeyCovers() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSlowlogQueryShapeFromProfileSupportsLegacyAndAggregate(t *testing.T) {
+	// 测试 3.4 update 的 query 过滤、aggregate 的前置 $match/$sort 与 getmore 的 originatingCommand 都能提取字段形状。
+	legacyUpdate := bson.M{"op": "update", "query": bson.D{
+		{Key: "$and", Value: bson.A{bson.D{{Key: "tenant", Value: 1}}, bson.D{{Key: "kind", Value: bson.D{{Key: "$in", Value: bson.A{"a", "b"}}}}}}},
+		{Key: "$or", Value: bson.A{bson.D{{Key: "x", Value: 1}}}},
+		{Key: "name", Value: primitive.Regex{Pattern: "^a"}},
+		{Key: "deletedAt", Value: bson.D{{Key: "$exists", Value: false}}},
+	}}
+	shape := slowlogQueryShapeFromProfile(legacyUpdate)
+	if !reflect.DeepEqual(shape.equality, []string{"tenant", "kind"}) || !reflect.DeepEqual(shape.ranges, []string{"name", "deletedAt"}) || shape.sort != nil {
+		t.Fatalf("legacy update shape = %#v", shape)
+	}
+
+	aggregate := bson.M{"op": "getmore", "originatingCommand": bson.D{
+		{Key: "aggregate", Value: "orders"},
+		{Key: "pipeline", Value: bson.A{
+			bson.D{{Key: "$match", Value: bson.D{{Key: "status", Value: "A"}}}},
+			bson.D{{Key: "$sort", Value: bson.D{{Key: "ts", Value: int32(-1)}, {Key: "_id", Value: int32(1)}}}},
+			bson.D{{Key: "$match", Value: bson.D{{Key: "ignored", Value: 1}}}},
+		}},
+	}}
+	shape = slowlogQueryShapeFromProfile(aggregate)
+	wantKey := []IndexKeyField{{Field: "status", Order: "1"}, {Field: "ts", Order: "-1"}, {Field: "_id", Order: "1"}}
+	if got := shape.esrKey(); !reflect.DeepEqual(got, wantKey) {
+		t.Fatalf("aggregate key = %#v, want %#v", got, wantKey)
+	}
+}
+
+func TestSlowlogAdviseCandidatesSelectsProblemShapes(t *testing.T) {
+	// 测试只选择 COLLSCAN、内存排序或扫描放大的非 insert 形状，并按上限截断。
+	ratio := 500.0
+	items := []MergedSlowlogItem{
+		{SlowlogSummaryItem: SlowlogSummaryItem{Namespace: "app.a", Operation: "query", QueryHash: "A", CollectionScanCount: 1}},
+		{SlowlogSummaryItem: SlowlogSummaryItem{Namespace: "app.b", Operation: "insert", QueryHash: "B", CollectionScanCount: 1}},
+		{SlowlogSummaryItem: SlowlogSummaryItem{Namespace: "app.c", Operation: "query", QueryHash: "C"}},
+		{SlowlogSummaryItem: SlowlogSummaryItem{Namespace: "app.d", Operation: "command", QueryHash: "D", SortStageCount: 2}},
+		{SlowlogSummaryItem: SlowlogSummaryItem{Namespace: "app.e", Operation: "query", QueryHash: "E", WorstDocsToReturned: &ratio}},
+	}
+	got := slowlogAdviseCandidates(items, 2, defaultSlowlogAdviseDocsExaminedRatio)
+	if len(got) != 2 || got[0].QueryHash != "A" || got[1].QueryHash != "D" {
+		t.Fatalf("candidates = %#v", got)
+	}
+	if reasons := slowlogAdviseReasons(items[4].SlowlogSummaryItem, defaultSlowlogAdviseDocsExaminedRatio); !reflect.DeepEqual(reasons, []string{"docs_examined_high"}) {
+		t.Fatalf("reasons = %v", reasons)
+	}
+	if got := slowlogAdviseCandidates(items[4:], 2, 1000); len(got) != 0 {
+		t.Fatalf("candidates with ratio 1000 = %#v, want none", got)
+	}
+}
+
+func TestSummarizeSlowlogExplainReportsPlansBoundsAndFetchRatio(t *testing.T) {
+	// 测试 explain 摘要输出获胜/被拒计划、索引边界类别、内存排序与 FETCH 放大 finding。
+	ixscan := bson.D{
+		{Key: "stage", Value: "IXSCAN"},
+		{Key: "keyPattern", Value: bson.D{{Key: "status", Value: int32(1)}, {Key: "createdAt", Value: int32(-1)}}},
+		{Key: "indexName", Value: "status_1_createdAt_-1"},
+		{Key: "indexBounds", Value: bson.D{
+			{Key: "status", Value: bson.A{`["A", "A"]`}},
+			{Key: "createdAt", Value: bson.A{"[MaxKey, MinKey]"}},
+		}},
+	}
+	output := bson.M{
+		"queryPlanner": bson.D{
+			{Key: "winningPlan", Value: bson.D{{Key: "stage", Value: "SORT"}, {Key: "inputStage", Value: bson.D{{Key: "stage", Value: "FETCH"}, {Key: "inputStage", Value: ixscan}}}}},
+			{Key: "rejectedPlans", Value: bson.A{bson.D{{Key: "stage", Value: "COLLSCAN"}}}},
+		},
+		"executionStats": bson.D{
+			{Key: "nReturned", Value: int32(2)},
+			{Key: "executionTimeMillis", Value: int32(35)},
+			{Key: "totalKeysExamined", Value: int32(500)},
+			{Key: "totalDocsExamined", Value: int32(500)},
+		},
+	}
+
+	explain := summarizeSlowlogExplain(output, ExplainExecutionStats, "app.orders", "ABCD1234")
+	if got := strings.Join(explain.WinningPlan.Stages, ">"); got != "SORT>FETCH>IXSCAN" || !explain.WinningPlan.InMemorySort {
+		t.Fatalf("winning plan = %#v", explain.WinningPlan)
+	}
+	if len(explain.RejectedPlans) != 1 || !explain.RejectedPlans[0].CollectionScan {
+		t.Fatalf("rejected plans = %#v", explain.RejectedPlans)
+	}
+	scans := explain.WinningPlan.IndexScans
+	if len(scans) != 1 || scans[0].IndexName != "status_1_createdAt_-1" || len(scans[0].Key) != 2 || scans[0].Key[1].Order != "-1" {
+		t.Fatalf("index scans = %#v", scans)
+	}
+	if scans[0].Bounds[0].Kind != "point" || scans[0].Bounds[1].Kind != "full" {
+		t.Fatalf("bounds = %#v", scans[0].Bounds)
+	}
+	if explain.ExecutionStats == nil || explain.ExecutionStats.FetchRatio == nil || *explain.ExecutionStats.FetchRatio != 250 {
+		t.Fatalf("execution stats = %#v", explain.ExecutionStats)
+	}
+	codes := make([]string, 0, len(explain.Findings))
+	for _, finding := range explain.Findings {
+		codes = append(codes, finding.Code)
+	}
+	for _, code := range []string{"query.explain_in_memory_sort", "query.explain_fetch_ratio_high"} {
+		if !strings.Contains(strings.Join(codes, ","), code) {
+			t.Fatalf("findings = %v, want %s", codes, code)
+		}
+	}
+	if strings.Contains(fmt.Sprint(explain), `"A"`) {
+		t.Fatalf("explain summary leaked bound values: %#v", explain)
+	}
+}
+
+func TestExplainBoundKindClassifiesIntervals(t *testing.T) {
+	// 测试索引边界只按结构分类为 point、range 或 full。
+	tests := map[string]bson.A{
+		"point": {`[1, 1]`, `["x", "x"]`},
+		"range": {`[1, 1]`, `(5, inf.0]`},
+		"full":  {"[MinKey, MaxKey]"},
+	}
+	for want, intervals := range tests {
+		if got := explainBoundKind(intervals); got != want {
+			t.Fatalf("explainBoundKind(%v) = %q, want %q", intervals, got, want)
+		}
+	}
+}
+
+func TestDecodeSlowlogDocumentKeepsPublicFormNested(t *testing.T) {
+	// 测试公开的 profile 文档保持嵌套 bson.M，解析用副本保留 bson.D 字段顺序。
+	raw, err := bson.Marshal(bson.D{{Key: "ns", Value: "app.orders"}, {Key: "command", Value: bson.D{{Key: "find", Value: "orders"}, {Key: "filter", Value: bson.D{{Key: "a", Value: 1}}}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	document, ordered, err := decodeSlowlogDocument(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := document["command"].(bson.M); !ok {
+		t.Fatalf("public command = %T, want bson.M", document["command"])
+	}
+	if command, ok := ordered["command"].(bson.D); !ok || command[0].Key != "find" {
+		t.Fatalf("ordered command = %#v, want bson.D starting with find", ordered["command"])
+	}
+	detail := &SlowlogDetailResult{Slowlog: document, orderedSlowlog: ordered}
+	if _, ok := detail.orderedProfile()["command"].(bson.D); !ok {
+		t.Fatalf("orderedProfile() did not return the ordered copy")
+	}
+}